@@ -0,0 +1,238 @@
+// Package analysis computes the state of the local variables and of the operand stack at each
+// instruction of a method, as in the ASM analysis package. An Analyzer propagates Frames of
+// abstract values along the control flow until a fixpoint is reached, the values being created
+// and combined by a pluggable Interpreter. The BasicInterpreter, BasicVerifier and
+// SourceInterpreter are provided.
+//
+// The subroutines, used by the JSR and RET instructions of old class files, are not supported.
+package analysis
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+// Interpreter creates the abstract values, and computes the results of the instructions on them.
+// The instruction operations return the pushed value, or nil for the instructions which do not
+// push one, or an error if the instruction cannot be executed with its operands.
+type Interpreter interface {
+	// NewValue returns the value of a type, or nil for the void type. It is used for the
+	// parameters, the return type and the exceptions of the handlers.
+	NewValue(t class.Type) Value
+	// NewEmptyValue returns the value of the local variables which are not initialized, and of
+	// the second slot of the long and double local variables.
+	NewEmptyValue() Value
+	// NewOperation interprets an instruction without operand: ACONST_NULL, ICONST_M1 to DCONST_1,
+	// BIPUSH, SIPUSH, LDC, JSR, GETSTATIC and NEW.
+	NewOperation(instruction class.Instruction) (Value, error)
+	// CopyOperation interprets an instruction which copies a value: the loads and stores of local
+	// variables, DUP, DUP_X1, DUP_X2, DUP2, DUP2_X1, DUP2_X2 and SWAP.
+	CopyOperation(instruction class.Instruction, value Value) (Value, error)
+	// UnaryOperation interprets an instruction with one operand: the negations and conversions,
+	// IINC, the single operand jumps and switches, the returns, PUTSTATIC, GETFIELD, NEWARRAY,
+	// ANEWARRAY, ARRAYLENGTH, ATHROW, CHECKCAST, INSTANCEOF, MONITORENTER and MONITOREXIT.
+	UnaryOperation(instruction class.Instruction, value Value) (Value, error)
+	// BinaryOperation interprets an instruction with two operands: the array loads, the
+	// arithmetic and comparison instructions, the two operand jumps and PUTFIELD.
+	BinaryOperation(instruction class.Instruction, value1 Value, value2 Value) (Value, error)
+	// TernaryOperation interprets an array store instruction.
+	TernaryOperation(instruction class.Instruction, value1 Value, value2 Value, value3 Value) (Value, error)
+	// NaryOperation interprets a method invocation, including the receiver if any, or a
+	// MULTIANEWARRAY instruction.
+	NaryOperation(instruction class.Instruction, values []Value) (Value, error)
+	// ReturnOperation checks a returned value against the value of the return type.
+	ReturnOperation(instruction class.Instruction, value Value, expected Value) error
+	// Merge returns the merge of two values. It must return value1 if the merge does not change
+	// it.
+	Merge(value1 Value, value2 Value) Value
+}
+
+// AnalyzerError is an error of the analysis of an instruction.
+type AnalyzerError struct {
+	// Index is the index of the instruction in the instructions of the method.
+	Index       int
+	Instruction class.Instruction
+	Err         error
+}
+
+func (e *AnalyzerError) Error() string {
+	if opCode := e.Instruction.OpCode(); opCode >= 0 {
+		return fmt.Sprintf("error at instruction %d (%s): %v", e.Index, data.OPCODE_NAMES[opCode], e.Err)
+	}
+	return fmt.Sprintf("error at instruction %d: %v", e.Index, e.Err)
+}
+
+func (e *AnalyzerError) Unwrap() error {
+	return e.Err
+}
+
+// Analyzer computes the frames of the instructions of methods with an interpreter.
+type Analyzer struct {
+	interpreter Interpreter
+}
+
+func NewAnalyzer(interpreter Interpreter) *Analyzer {
+	return &Analyzer{interpreter: interpreter}
+}
+
+// Analyze returns the frames before each instruction of a method of a class, by instruction index
+// in method.Code.Instructions. The frames of the unreachable instructions are nil, and the frame
+// of a pseudo instruction is the one of the next real instruction. The abstract and native
+// methods have no frame.
+func (a *Analyzer) Analyze(owner string, method *class.Method) ([]*Frame, error) {
+	instructions := method.Code.Instructions
+	frames := make([]*Frame, len(instructions))
+	if method.AccessFlags&(data.ACC_ABSTRACT|data.ACC_NATIVE) != 0 || len(instructions) == 0 {
+		return frames, nil
+	}
+	labels := make(map[*class.Label]int)
+	for i, instruction := range instructions {
+		if label, ok := instruction.(*class.Label); ok {
+			labels[label] = i
+		}
+		if opCode := instruction.OpCode(); opCode == data.JSR || opCode == data.JSR_W || opCode == data.RET {
+			return nil, &AnalyzerError{Index: i, Instruction: instruction, Err: errors.New("subroutines are not supported")}
+		}
+	}
+	// handlers are the exception table entries covering each instruction.
+	handlers := make([][]class.Exception, len(instructions))
+	for _, exception := range method.Code.ExceptionTable {
+		for i := labels[exception.Start]; i < labels[exception.End]; i++ {
+			handlers[i] = append(handlers[i], exception)
+		}
+	}
+
+	initial, err := a.initialFrame(owner, method)
+	if err != nil {
+		return nil, &AnalyzerError{Index: 0, Instruction: instructions[0], Err: err}
+	}
+	var queue []int
+	inQueue := make([]bool, len(instructions))
+	merge := func(index int, frame *Frame) error {
+		if index >= len(instructions) {
+			return errors.New("execution can fall off the end of the code")
+		}
+		changed := true
+		if frames[index] == nil {
+			frames[index] = frame.Copy()
+		} else {
+			var err error
+			if changed, err = frames[index].Merge(frame, a.interpreter); err != nil {
+				return err
+			}
+		}
+		if changed && !inQueue[index] {
+			inQueue[index] = true
+			queue = append(queue, index)
+		}
+		return nil
+	}
+	if err := merge(0, initial); err != nil {
+		return nil, err
+	}
+
+	current := NewFrame(0, 0)
+	for len(queue) > 0 {
+		index := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		inQueue[index] = false
+		frame := frames[index]
+		instruction := instructions[index]
+		if err := a.step(index, instruction, frame, current, labels, merge); err != nil {
+			return nil, &AnalyzerError{Index: index, Instruction: instruction, Err: err}
+		}
+		for _, exception := range handlers[index] {
+			catchType := exception.CatchType
+			if catchType == "" {
+				catchType = "java/lang/Throwable"
+			}
+			handler := frame.Copy()
+			handler.ClearStack()
+			if err := handler.Push(a.interpreter.NewValue(class.NewObjectType(catchType))); err != nil {
+				return nil, &AnalyzerError{Index: index, Instruction: instruction, Err: err}
+			}
+			if err := merge(labels[exception.Handler], handler); err != nil {
+				return nil, &AnalyzerError{Index: index, Instruction: instruction, Err: err}
+			}
+		}
+	}
+	return frames, nil
+}
+
+// initialFrame returns the frame at the beginning of a method.
+func (a *Analyzer) initialFrame(owner string, method *class.Method) (*Frame, error) {
+	code := &method.Code
+	frame := NewFrame(int(code.MaxLocal), int(code.MaxStack))
+	methodType := class.NewMethodType(method.Descriptor)
+	frame.SetReturn(a.interpreter.NewValue(methodType.ReturnType()))
+	local := 0
+	set := func(value Value) error {
+		if local >= frame.Locals() {
+			return errors.New("insufficient maximum number of local variables for the parameters")
+		}
+		frame.locals[local] = value
+		local++
+		return nil
+	}
+	if method.AccessFlags&data.ACC_STATIC == 0 {
+		if err := set(a.interpreter.NewValue(class.NewObjectType(owner))); err != nil {
+			return nil, err
+		}
+	}
+	for _, argument := range methodType.ArgumentTypes() {
+		if err := set(a.interpreter.NewValue(argument)); err != nil {
+			return nil, err
+		}
+		if argument.Size() == 2 {
+			if err := set(a.interpreter.NewEmptyValue()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for local < frame.Locals() {
+		frame.locals[local] = a.interpreter.NewEmptyValue()
+		local++
+	}
+	return frame, nil
+}
+
+// step executes an instruction, and merges the resulting frame into the frames of its successors.
+func (a *Analyzer) step(index int, instruction class.Instruction, frame *Frame, current *Frame,
+	labels map[*class.Label]int, merge func(int, *Frame) error) error {
+	if instruction.OpCode() < 0 {
+		return merge(index+1, frame)
+	}
+	if err := current.init(frame).Execute(instruction, a.interpreter); err != nil {
+		return err
+	}
+	var targets []*class.Label
+	next := true
+	switch insn := instruction.(type) {
+	case *class.JumpInstruction:
+		targets = []*class.Label{insn.Label}
+		next = insn.Op != data.GOTO && insn.Op != data.GOTO_W
+	case *class.TableSwitchInstruction:
+		targets = append([]*class.Label{insn.Default}, insn.Labels...)
+		next = false
+	case *class.LookupSwitchInstruction:
+		targets = append([]*class.Label{insn.Default}, insn.Labels...)
+		next = false
+	default:
+		opCode := instruction.OpCode()
+		next = opCode != data.ATHROW && (opCode < data.IRETURN || opCode > data.RETURN)
+	}
+	if next {
+		if err := merge(index+1, current); err != nil {
+			return err
+		}
+	}
+	for _, target := range targets {
+		if err := merge(labels[target], current); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package analysis
+
+import (
+	"os"
+	"testing"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/jasm"
+	"github.com/tk103331/clazz/tools"
+)
+
+const source = `.version 52 0
+.class public super a/T
+.super java/lang/Object
+
+.method public static f (IJ)I
+    iload 0
+    ifeq L1
+    iconst_1
+    istore 0
+    goto L2
+L1:
+    iconst_2
+    istore 0
+L2:
+    aconst_null
+    astore 3
+    iload 0
+    ireturn
+.end method
+
+.method public static bad ()V
+    fconst_1
+    istore 0
+    return
+.end method
+`
+
+func method(t *testing.T, c *class.Class, name string) *class.Method {
+	for i := range c.Methods {
+		if c.Methods[i].Name == name {
+			return &c.Methods[i]
+		}
+	}
+	t.Fatalf("method %s not found", name)
+	return nil
+}
+
+// realIndex returns the index of the n-th real instruction of a method.
+func realIndex(m *class.Method, n int) int {
+	for i, instruction := range m.Code.Instructions {
+		if instruction.OpCode() >= 0 {
+			if n == 0 {
+				return i
+			}
+			n--
+		}
+	}
+	return -1
+}
+
+func TestBasicInterpreter(t *testing.T) {
+	c, err := jasm.Parse([]byte(source))
+	tools.AssertNoErr(t, err)
+	m := method(t, c, "f")
+	frames, err := NewAnalyzer(BasicInterpreter{}).Analyze(c.ThisClass, m)
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "IJ.. ", frames[0].String())
+	// At the ireturn, local 3 is a reference, and the int is on the stack.
+	tools.AssertEqual(t, "IJ.A I", frames[realIndex(m, 10)].String())
+
+	_, err = NewAnalyzer(BasicInterpreter{}).Analyze(c.ThisClass, method(t, c, "bad"))
+	tools.AssertNoErr(t, err)
+	_, err = NewAnalyzer(BasicVerifier{}).Analyze(c.ThisClass, method(t, c, "bad"))
+	tools.AssertEqual(t, "error at instruction 1 (istore): first argument: expected I, but found F", err.Error())
+}
+
+func TestSourceInterpreter(t *testing.T) {
+	c, err := jasm.Parse([]byte(source))
+	tools.AssertNoErr(t, err)
+	m := method(t, c, "f")
+	frames, err := NewAnalyzer(SourceInterpreter{}).Analyze(c.ThisClass, m)
+	tools.AssertNoErr(t, err)
+	// The int local is stored by the two istore instructions.
+	local := frames[realIndex(m, 9)].Local(0).(*SourceValue)
+	tools.AssertEqual(t, 2, len(local.Instructions))
+	tools.AssertEqual(t, true, local.contains(m.Code.Instructions[realIndex(m, 3)]))
+	tools.AssertEqual(t, true, local.contains(m.Code.Instructions[realIndex(m, 6)]))
+	value := frames[realIndex(m, 10)].Stack(0).(*SourceValue)
+	tools.AssertEqual(t, m.Code.Instructions[realIndex(m, 9)], value.Instructions[0])
+	tools.AssertEqual(t, 2, frames[0].Local(1).Size())
+}
+
+func TestAnalyzeHello(t *testing.T) {
+	f, err := os.Open("../class/Hello.class")
+	tools.AssertNoErr(t, err)
+	defer f.Close()
+	reader := class.NewReader(f)
+	tools.AssertNoErr(t, reader.Read())
+	c := reader.Class()
+	for i := range c.Methods {
+		frames, err := NewAnalyzer(BasicVerifier{}).Analyze(c.ThisClass, &c.Methods[i])
+		tools.AssertNoErr(t, err)
+		tools.AssertEqual(t, len(c.Methods[i].Code.Instructions), len(frames))
+	}
+}
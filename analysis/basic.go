@@ -0,0 +1,186 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+// BasicValue is a value of the BasicInterpreter, which only distinguishes the primitive types,
+// the references and the return addresses.
+type BasicValue struct {
+	// Descriptor is the field descriptor of the type of the value, "V" for a return address, or
+	// an empty string for an uninitialized value.
+	Descriptor string
+}
+
+var (
+	UninitializedValue = BasicValue{}
+	IntValue           = BasicValue{Descriptor: "I"}
+	FloatValue         = BasicValue{Descriptor: "F"}
+	LongValue          = BasicValue{Descriptor: "J"}
+	DoubleValue        = BasicValue{Descriptor: "D"}
+	ReferenceValue     = BasicValue{Descriptor: "Ljava/lang/Object;"}
+	ReturnAddressValue = BasicValue{Descriptor: "V"}
+)
+
+func (v BasicValue) Size() int {
+	if v == LongValue || v == DoubleValue {
+		return 2
+	}
+	return 1
+}
+
+// IsReference returns true for the object and array values.
+func (v BasicValue) IsReference() bool {
+	return strings.HasPrefix(v.Descriptor, "L") || strings.HasPrefix(v.Descriptor, "[")
+}
+
+func (v BasicValue) String() string {
+	switch {
+	case v == UninitializedValue:
+		return "."
+	case v == ReturnAddressValue:
+		return "R"
+	case v == ReferenceValue:
+		return "A"
+	}
+	return v.Descriptor
+}
+
+// BasicInterpreter is an Interpreter of BasicValues, which does not check the operands of the
+// instructions.
+type BasicInterpreter struct{}
+
+func (BasicInterpreter) NewValue(t class.Type) Value {
+	switch t.Sort() {
+	case data.TYPE_SORT_VOID:
+		return nil
+	case data.TYPE_SORT_BOOLEAN, data.TYPE_SORT_HAR, data.TYPE_SORT_BYTE, data.TYPE_SORT_SHORT, data.TYPE_SORT_INT:
+		return IntValue
+	case data.TYPE_SORT_FLOAT:
+		return FloatValue
+	case data.TYPE_SORT_LONG:
+		return LongValue
+	case data.TYPE_SORT_DOUBLE:
+		return DoubleValue
+	default:
+		return ReferenceValue
+	}
+}
+
+func (BasicInterpreter) NewEmptyValue() Value {
+	return UninitializedValue
+}
+
+func (i BasicInterpreter) NewOperation(instruction class.Instruction) (Value, error) {
+	switch opCode := instruction.OpCode(); {
+	case opCode == data.ACONST_NULL, opCode == data.NEW:
+		return ReferenceValue, nil
+	case opCode >= data.ICONST_M1 && opCode <= data.ICONST_5, opCode == data.BIPUSH, opCode == data.SIPUSH:
+		return IntValue, nil
+	case opCode == data.LCONST_0, opCode == data.LCONST_1:
+		return LongValue, nil
+	case opCode >= data.FCONST_0 && opCode <= data.FCONST_2:
+		return FloatValue, nil
+	case opCode == data.DCONST_0, opCode == data.DCONST_1:
+		return DoubleValue, nil
+	case opCode == data.LDC:
+		return i.constant(instruction.(*class.LdcInstruction).Value)
+	case opCode == data.JSR, opCode == data.JSR_W:
+		return ReturnAddressValue, nil
+	case opCode == data.GETSTATIC:
+		return i.NewValue(class.NewType(instruction.(*class.FieldInstruction).Descriptor)), nil
+	}
+	return nil, fmt.Errorf("unexpected instruction %s", data.OPCODE_NAMES[instruction.OpCode()])
+}
+
+// constant returns the value of an LDC constant.
+func (i BasicInterpreter) constant(value interface{}) (Value, error) {
+	switch v := value.(type) {
+	case int32:
+		return IntValue, nil
+	case float32:
+		return FloatValue, nil
+	case int64:
+		return LongValue, nil
+	case float64:
+		return DoubleValue, nil
+	case string, class.Type, class.Handle:
+		return ReferenceValue, nil
+	case class.ConstantDynamic:
+		return i.NewValue(class.NewType(v.Descriptor)), nil
+	}
+	return nil, fmt.Errorf("illegal LDC value %v", value)
+}
+
+func (BasicInterpreter) CopyOperation(instruction class.Instruction, value Value) (Value, error) {
+	return value, nil
+}
+
+func (i BasicInterpreter) UnaryOperation(instruction class.Instruction, value Value) (Value, error) {
+	switch opCode := instruction.OpCode(); opCode {
+	case data.INEG, data.IINC, data.L2I, data.F2I, data.D2I, data.I2B, data.I2C, data.I2S, data.ARRAYLENGTH,
+		data.INSTANCEOF:
+		return IntValue, nil
+	case data.FNEG, data.I2F, data.L2F, data.D2F:
+		return FloatValue, nil
+	case data.LNEG, data.I2L, data.F2L, data.D2L:
+		return LongValue, nil
+	case data.DNEG, data.I2D, data.L2D, data.F2D:
+		return DoubleValue, nil
+	case data.GETFIELD:
+		return i.NewValue(class.NewType(instruction.(*class.FieldInstruction).Descriptor)), nil
+	case data.NEWARRAY, data.ANEWARRAY, data.CHECKCAST:
+		return ReferenceValue, nil
+	}
+	return nil, nil
+}
+
+func (BasicInterpreter) BinaryOperation(instruction class.Instruction, value1 Value, value2 Value) (Value, error) {
+	switch opCode := instruction.OpCode(); opCode {
+	case data.IALOAD, data.BALOAD, data.CALOAD, data.SALOAD, data.IADD, data.ISUB, data.IMUL, data.IDIV, data.IREM,
+		data.ISHL, data.ISHR, data.IUSHR, data.IAND, data.IOR, data.IXOR, data.LCMP, data.FCMPL, data.FCMPG,
+		data.DCMPL, data.DCMPG:
+		return IntValue, nil
+	case data.FALOAD, data.FADD, data.FSUB, data.FMUL, data.FDIV, data.FREM:
+		return FloatValue, nil
+	case data.LALOAD, data.LADD, data.LSUB, data.LMUL, data.LDIV, data.LREM, data.LSHL, data.LSHR, data.LUSHR,
+		data.LAND, data.LOR, data.LXOR:
+		return LongValue, nil
+	case data.DALOAD, data.DADD, data.DSUB, data.DMUL, data.DDIV, data.DREM:
+		return DoubleValue, nil
+	case data.AALOAD:
+		return ReferenceValue, nil
+	}
+	return nil, nil
+}
+
+func (BasicInterpreter) TernaryOperation(instruction class.Instruction, value1 Value, value2 Value, value3 Value) (Value, error) {
+	return nil, nil
+}
+
+func (i BasicInterpreter) NaryOperation(instruction class.Instruction, values []Value) (Value, error) {
+	switch insn := instruction.(type) {
+	case *class.MultiANewArrayInstruction:
+		return ReferenceValue, nil
+	case *class.InvokeDynamicInstruction:
+		return i.NewValue(class.NewMethodType(insn.Descriptor).ReturnType()), nil
+	case *class.MethodInstruction:
+		return i.NewValue(class.NewMethodType(insn.Descriptor).ReturnType()), nil
+	}
+	return nil, fmt.Errorf("unexpected instruction %s", data.OPCODE_NAMES[instruction.OpCode()])
+}
+
+func (BasicInterpreter) ReturnOperation(instruction class.Instruction, value Value, expected Value) error {
+	return nil
+}
+
+func (BasicInterpreter) Merge(value1 Value, value2 Value) Value {
+	if value1 != value2 {
+		return UninitializedValue
+	}
+	return value1
+}
@@ -0,0 +1,183 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+// BasicVerifier is a BasicInterpreter which checks that the operands of the instructions have
+// the expected basic types. The references are not distinguished, see the verifier package for
+// a complete verification.
+type BasicVerifier struct {
+	BasicInterpreter
+}
+
+// check returns an error if a value is not the expected one. A nil expected value designates a
+// reference.
+func check(argument string, value Value, expected Value) error {
+	if expected == nil {
+		if basic, ok := value.(BasicValue); ok && basic.IsReference() {
+			return nil
+		}
+		return fmt.Errorf("%s: expected a reference, but found %v", argument, value)
+	}
+	if value != expected {
+		return fmt.Errorf("%s: expected %v, but found %v", argument, expected, value)
+	}
+	return nil
+}
+
+func (v BasicVerifier) CopyOperation(instruction class.Instruction, value Value) (Value, error) {
+	var expected Value
+	switch opCode := instruction.OpCode(); opCode {
+	case data.ILOAD, data.ISTORE:
+		expected = IntValue
+	case data.FLOAD, data.FSTORE:
+		expected = FloatValue
+	case data.LLOAD, data.LSTORE:
+		expected = LongValue
+	case data.DLOAD, data.DSTORE:
+		expected = DoubleValue
+	case data.ALOAD:
+		if err := check("first argument", value, nil); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case data.ASTORE:
+		if value == ReturnAddressValue {
+			return value, nil
+		}
+		if err := check("first argument", value, nil); err != nil {
+			return nil, err
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+	if err := check("first argument", value, expected); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (v BasicVerifier) UnaryOperation(instruction class.Instruction, value Value) (Value, error) {
+	var expected Value
+	switch opCode := instruction.OpCode(); opCode {
+	case data.INEG, data.IINC, data.I2F, data.I2L, data.I2D, data.I2B, data.I2C, data.I2S, data.IFEQ, data.IFNE,
+		data.IFLT, data.IFGE, data.IFGT, data.IFLE, data.TABLESWITCH, data.LOOKUPSWITCH, data.IRETURN,
+		data.NEWARRAY, data.ANEWARRAY:
+		expected = IntValue
+	case data.FNEG, data.F2I, data.F2L, data.F2D, data.FRETURN:
+		expected = FloatValue
+	case data.LNEG, data.L2I, data.L2F, data.L2D, data.LRETURN:
+		expected = LongValue
+	case data.DNEG, data.D2I, data.D2F, data.D2L, data.DRETURN:
+		expected = DoubleValue
+	case data.GETFIELD, data.ARRAYLENGTH, data.ATHROW, data.CHECKCAST, data.INSTANCEOF, data.MONITORENTER,
+		data.MONITOREXIT, data.IFNULL, data.IFNONNULL, data.ARETURN:
+	case data.PUTSTATIC:
+		expected = v.NewValue(class.NewType(instruction.(*class.FieldInstruction).Descriptor))
+	}
+	if err := check("first argument", value, expected); err != nil {
+		return nil, err
+	}
+	return v.BasicInterpreter.UnaryOperation(instruction, value)
+}
+
+func (v BasicVerifier) BinaryOperation(instruction class.Instruction, value1 Value, value2 Value) (Value, error) {
+	var expected1, expected2 Value
+	switch opCode := instruction.OpCode(); opCode {
+	case data.IALOAD, data.BALOAD, data.CALOAD, data.SALOAD, data.FALOAD, data.LALOAD, data.DALOAD, data.AALOAD:
+		expected2 = IntValue
+	case data.IADD, data.ISUB, data.IMUL, data.IDIV, data.IREM, data.ISHL, data.ISHR, data.IUSHR, data.IAND,
+		data.IOR, data.IXOR, data.IF_ICMPEQ, data.IF_ICMPNE, data.IF_ICMPLT, data.IF_ICMPGE, data.IF_ICMPGT,
+		data.IF_ICMPLE:
+		expected1, expected2 = IntValue, IntValue
+	case data.FADD, data.FSUB, data.FMUL, data.FDIV, data.FREM, data.FCMPL, data.FCMPG:
+		expected1, expected2 = FloatValue, FloatValue
+	case data.LADD, data.LSUB, data.LMUL, data.LDIV, data.LREM, data.LAND, data.LOR, data.LXOR, data.LCMP:
+		expected1, expected2 = LongValue, LongValue
+	case data.LSHL, data.LSHR, data.LUSHR:
+		expected1, expected2 = LongValue, IntValue
+	case data.DADD, data.DSUB, data.DMUL, data.DDIV, data.DREM, data.DCMPL, data.DCMPG:
+		expected1, expected2 = DoubleValue, DoubleValue
+	case data.IF_ACMPEQ, data.IF_ACMPNE:
+	case data.PUTFIELD:
+		expected2 = v.NewValue(class.NewType(instruction.(*class.FieldInstruction).Descriptor))
+	}
+	if err := check("first argument", value1, expected1); err != nil {
+		return nil, err
+	}
+	if err := check("second argument", value2, expected2); err != nil {
+		return nil, err
+	}
+	return v.BasicInterpreter.BinaryOperation(instruction, value1, value2)
+}
+
+func (v BasicVerifier) TernaryOperation(instruction class.Instruction, value1 Value, value2 Value, value3 Value) (Value, error) {
+	var expected3 Value
+	switch opCode := instruction.OpCode(); opCode {
+	case data.IASTORE, data.BASTORE, data.CASTORE, data.SASTORE:
+		expected3 = IntValue
+	case data.FASTORE:
+		expected3 = FloatValue
+	case data.LASTORE:
+		expected3 = LongValue
+	case data.DASTORE:
+		expected3 = DoubleValue
+	}
+	if err := check("first argument", value1, nil); err != nil {
+		return nil, err
+	}
+	if err := check("second argument", value2, IntValue); err != nil {
+		return nil, err
+	}
+	if err := check("third argument", value3, expected3); err != nil {
+		return nil, err
+	}
+	return v.BasicInterpreter.TernaryOperation(instruction, value1, value2, value3)
+}
+
+func (v BasicVerifier) NaryOperation(instruction class.Instruction, values []Value) (Value, error) {
+	var expected []Value
+	switch insn := instruction.(type) {
+	case *class.MultiANewArrayInstruction:
+		for range values {
+			expected = append(expected, IntValue)
+		}
+	case *class.InvokeDynamicInstruction:
+		expected = v.argumentValues(insn.Descriptor)
+	case *class.MethodInstruction:
+		if insn.Op != data.INVOKESTATIC {
+			expected = append(expected, nil)
+		}
+		expected = append(expected, v.argumentValues(insn.Descriptor)...)
+	}
+	for i, value := range values {
+		if err := check(fmt.Sprintf("argument %d", i+1), value, expected[i]); err != nil {
+			return nil, err
+		}
+	}
+	return v.BasicInterpreter.NaryOperation(instruction, values)
+}
+
+func (v BasicVerifier) argumentValues(descriptor string) []Value {
+	var values []Value
+	for _, argument := range class.NewMethodType(descriptor).ArgumentTypes() {
+		value := v.NewValue(argument)
+		if value == ReferenceValue {
+			value = nil
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func (v BasicVerifier) ReturnOperation(instruction class.Instruction, value Value, expected Value) error {
+	if value != expected {
+		return fmt.Errorf("incompatible return type: expected %v, but found %v", expected, value)
+	}
+	return nil
+}
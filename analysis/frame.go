@@ -0,0 +1,419 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+// Value is an abstract value of a local variable or of an operand stack slot. The values must be
+// comparable with ==, and the long and double values have a size of 2.
+type Value interface {
+	Size() int
+}
+
+// Frame is the state of the local variables and of the operand stack before an instruction. The
+// long and double local variables use two slots, the second one holding an empty value, while
+// they use a single stack entry.
+type Frame struct {
+	locals      []Value
+	stack       []Value
+	maxStack    int
+	returnValue Value
+}
+
+// NewFrame returns a frame with the given number of local variables and maximum stack size,
+// whose local variables are all nil.
+func NewFrame(numLocals int, maxStack int) *Frame {
+	return &Frame{locals: make([]Value, numLocals), maxStack: maxStack}
+}
+
+// Copy returns a copy of the frame.
+func (f *Frame) Copy() *Frame {
+	return &Frame{locals: append([]Value{}, f.locals...), stack: append([]Value{}, f.stack...),
+		maxStack: f.maxStack, returnValue: f.returnValue}
+}
+
+// init sets the state of the frame to the one of another frame.
+func (f *Frame) init(frame *Frame) *Frame {
+	f.locals = append(f.locals[:0], frame.locals...)
+	f.stack = append(f.stack[:0], frame.stack...)
+	f.maxStack = frame.maxStack
+	f.returnValue = frame.returnValue
+	return f
+}
+
+// Return returns the value of the return type of the method, or nil for a void method.
+func (f *Frame) Return() Value {
+	return f.returnValue
+}
+
+// SetReturn sets the value of the return type of the method.
+func (f *Frame) SetReturn(value Value) {
+	f.returnValue = value
+}
+
+// Locals returns the number of local variables.
+func (f *Frame) Locals() int {
+	return len(f.locals)
+}
+
+// Local returns the value of a local variable.
+func (f *Frame) Local(index int) Value {
+	return f.locals[index]
+}
+
+// SetLocal sets the value of a local variable.
+func (f *Frame) SetLocal(index int, value Value) error {
+	if index < 0 || index >= len(f.locals) {
+		return fmt.Errorf("trying to set an inexistant local variable %d", index)
+	}
+	f.locals[index] = value
+	return nil
+}
+
+// MaxStackSize returns the maximum number of operand stack entries.
+func (f *Frame) MaxStackSize() int {
+	return f.maxStack
+}
+
+// StackSize returns the number of values on the operand stack.
+func (f *Frame) StackSize() int {
+	return len(f.stack)
+}
+
+// Stack returns a value of the operand stack, the bottom of the stack having the index 0.
+func (f *Frame) Stack(index int) Value {
+	return f.stack[index]
+}
+
+// ClearStack removes all the values of the operand stack.
+func (f *Frame) ClearStack() {
+	f.stack = f.stack[:0]
+}
+
+// Push pushes a value on the operand stack.
+func (f *Frame) Push(value Value) error {
+	if len(f.stack) >= f.maxStack {
+		return errors.New("insufficient maximum stack size")
+	}
+	f.stack = append(f.stack, value)
+	return nil
+}
+
+// Pop pops a value from the operand stack.
+func (f *Frame) Pop() (Value, error) {
+	if len(f.stack) == 0 {
+		return nil, errors.New("cannot pop operand off an empty stack")
+	}
+	value := f.stack[len(f.stack)-1]
+	f.stack = f.stack[:len(f.stack)-1]
+	return value, nil
+}
+
+// Merge merges another frame into this frame, and returns true if this frame has changed.
+func (f *Frame) Merge(frame *Frame, interpreter Interpreter) (bool, error) {
+	if len(f.stack) != len(frame.stack) {
+		return false, errors.New("incompatible stack heights")
+	}
+	changed := false
+	for _, values := range [][2][]Value{{f.locals, frame.locals}, {f.stack, frame.stack}} {
+		for i, value := range values[0] {
+			merged := interpreter.Merge(value, values[1][i])
+			if merged != value {
+				values[0][i] = merged
+				changed = true
+			}
+		}
+	}
+	return changed, nil
+}
+
+func (f *Frame) String() string {
+	var s strings.Builder
+	for _, local := range f.locals {
+		fmt.Fprint(&s, local)
+	}
+	s.WriteByte(' ')
+	for _, value := range f.stack {
+		fmt.Fprint(&s, value)
+	}
+	return s.String()
+}
+
+// Execute simulates the execution of an instruction on this frame, with an interpreter.
+func (f *Frame) Execute(instruction class.Instruction, interpreter Interpreter) error {
+	e := &executor{frame: f, interpreter: interpreter}
+	e.execute(instruction)
+	return e.err
+}
+
+// executor executes an instruction, and records the first error of the frame or interpreter
+// operations, after which the operations do nothing.
+type executor struct {
+	frame       *Frame
+	interpreter Interpreter
+	err         error
+}
+
+func (e *executor) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *executor) push(value Value, err error) {
+	e.fail(err)
+	if e.err == nil {
+		e.fail(e.frame.Push(value))
+	}
+}
+
+func (e *executor) pop() Value {
+	if e.err != nil {
+		return nil
+	}
+	value, err := e.frame.Pop()
+	e.fail(err)
+	return value
+}
+
+// popSize pops a value which must have the given size.
+func (e *executor) popSize(size int, opCode int) Value {
+	value := e.pop()
+	if e.err == nil && value.Size() != size {
+		e.fail(fmt.Errorf("illegal use of %s", data.OPCODE_NAMES[opCode]))
+	}
+	return value
+}
+
+func (e *executor) pops(n int) []Value {
+	values := make([]Value, n)
+	for i := n - 1; i >= 0; i-- {
+		values[i] = e.pop()
+	}
+	return values
+}
+
+func (e *executor) local(index int) Value {
+	if e.err == nil && (index < 0 || index >= len(e.frame.locals)) {
+		e.fail(fmt.Errorf("trying to get an inexistant local variable %d", index))
+	}
+	if e.err != nil {
+		return nil
+	}
+	return e.frame.locals[index]
+}
+
+func (e *executor) setLocal(index int, value Value) {
+	if e.err == nil {
+		e.fail(e.frame.SetLocal(index, value))
+	}
+}
+
+func (e *executor) copy(instruction class.Instruction, value Value) Value {
+	if e.err != nil {
+		return nil
+	}
+	value, err := e.interpreter.CopyOperation(instruction, value)
+	e.fail(err)
+	return value
+}
+
+// pushCopies pushes the copies of the values, followed by the values.
+func (e *executor) pushCopies(instruction class.Instruction, copies []Value, values ...Value) {
+	for _, value := range copies {
+		e.push(e.copy(instruction, value), nil)
+	}
+	for _, value := range values {
+		e.push(value, nil)
+	}
+}
+
+func (e *executor) unary(instruction class.Instruction) (Value, error) {
+	value := e.pop()
+	if e.err != nil {
+		return nil, nil
+	}
+	return e.interpreter.UnaryOperation(instruction, value)
+}
+
+func (e *executor) binary(instruction class.Instruction) (Value, error) {
+	value2 := e.pop()
+	value1 := e.pop()
+	if e.err != nil {
+		return nil, nil
+	}
+	return e.interpreter.BinaryOperation(instruction, value1, value2)
+}
+
+func (e *executor) ternary(instruction class.Instruction) (Value, error) {
+	value3 := e.pop()
+	value2 := e.pop()
+	value1 := e.pop()
+	if e.err != nil {
+		return nil, nil
+	}
+	return e.interpreter.TernaryOperation(instruction, value1, value2, value3)
+}
+
+func (e *executor) nary(instruction class.Instruction, n int) (Value, error) {
+	values := e.pops(n)
+	if e.err != nil {
+		return nil, nil
+	}
+	return e.interpreter.NaryOperation(instruction, values)
+}
+
+func (e *executor) execute(instruction class.Instruction) {
+	frame := e.frame
+	opCode := instruction.OpCode()
+	switch {
+	case opCode < 0, opCode == data.NOP, opCode == data.GOTO, opCode == data.GOTO_W, opCode == data.RET:
+	case opCode >= data.ACONST_NULL && opCode <= data.LDC, opCode == data.JSR, opCode == data.JSR_W,
+		opCode == data.GETSTATIC, opCode == data.NEW:
+		e.push(e.interpreter.NewOperation(instruction))
+	case opCode >= data.ILOAD && opCode <= data.ALOAD:
+		e.push(e.copy(instruction, e.local(instruction.(*class.VarInstruction).Var)), nil)
+	case opCode >= data.ISTORE && opCode <= data.ASTORE:
+		index := instruction.(*class.VarInstruction).Var
+		value := e.copy(instruction, e.pop())
+		e.setLocal(index, value)
+		if e.err != nil {
+			return
+		}
+		if value.Size() == 2 {
+			e.setLocal(index+1, e.interpreter.NewEmptyValue())
+		}
+		if index > 0 {
+			if previous := frame.locals[index-1]; previous != nil && previous.Size() == 2 {
+				e.setLocal(index-1, e.interpreter.NewEmptyValue())
+			}
+		}
+	case opCode >= data.IASTORE && opCode <= data.SASTORE:
+		_, err := e.ternary(instruction)
+		e.fail(err)
+	case opCode == data.POP:
+		e.popSize(1, opCode)
+	case opCode == data.POP2:
+		if value := e.pop(); e.err == nil && value.Size() == 1 {
+			e.popSize(1, opCode)
+		}
+	case opCode == data.DUP:
+		value1 := e.popSize(1, opCode)
+		e.pushCopies(instruction, []Value{value1}, value1)
+	case opCode == data.DUP_X1:
+		value1 := e.popSize(1, opCode)
+		value2 := e.popSize(1, opCode)
+		e.pushCopies(instruction, []Value{value1}, value2, value1)
+	case opCode == data.DUP_X2:
+		value1 := e.popSize(1, opCode)
+		if value2 := e.pop(); e.err == nil && value2.Size() == 1 {
+			value3 := e.popSize(1, opCode)
+			e.pushCopies(instruction, []Value{value1}, value3, value2, value1)
+		} else {
+			e.pushCopies(instruction, []Value{value1}, value2, value1)
+		}
+	case opCode == data.DUP2:
+		if value1 := e.pop(); e.err == nil && value1.Size() == 1 {
+			value2 := e.popSize(1, opCode)
+			e.pushCopies(instruction, nil, value2, value1)
+			e.pushCopies(instruction, []Value{value2, value1})
+		} else {
+			e.pushCopies(instruction, nil, value1)
+			e.pushCopies(instruction, []Value{value1})
+		}
+	case opCode == data.DUP2_X1:
+		if value1 := e.pop(); e.err == nil && value1.Size() == 1 {
+			value2 := e.popSize(1, opCode)
+			value3 := e.popSize(1, opCode)
+			e.pushCopies(instruction, []Value{value2, value1}, value3, value2, value1)
+		} else {
+			value2 := e.popSize(1, opCode)
+			e.pushCopies(instruction, []Value{value1}, value2, value1)
+		}
+	case opCode == data.DUP2_X2:
+		value1 := e.pop()
+		if e.err == nil && value1.Size() == 1 {
+			value2 := e.popSize(1, opCode)
+			if value3 := e.pop(); e.err == nil && value3.Size() == 1 {
+				value4 := e.popSize(1, opCode)
+				e.pushCopies(instruction, []Value{value2, value1}, value4, value3, value2, value1)
+			} else {
+				e.pushCopies(instruction, []Value{value2, value1}, value3, value2, value1)
+			}
+		} else if value2 := e.pop(); e.err == nil && value2.Size() == 1 {
+			value3 := e.popSize(1, opCode)
+			e.pushCopies(instruction, []Value{value1}, value3, value2, value1)
+		} else {
+			e.pushCopies(instruction, []Value{value1}, value2, value1)
+		}
+	case opCode == data.SWAP:
+		value2 := e.popSize(1, opCode)
+		value1 := e.popSize(1, opCode)
+		e.pushCopies(instruction, []Value{value2, value1})
+	case opCode >= data.IALOAD && opCode <= data.SALOAD, opCode >= data.IADD && opCode <= data.DREM,
+		opCode >= data.ISHL && opCode <= data.LXOR, opCode >= data.LCMP && opCode <= data.DCMPG:
+		e.push(e.binary(instruction))
+	case opCode >= data.INEG && opCode <= data.DNEG, opCode >= data.I2L && opCode <= data.I2S,
+		opCode == data.GETFIELD, opCode >= data.NEWARRAY && opCode <= data.ARRAYLENGTH,
+		opCode == data.CHECKCAST, opCode == data.INSTANCEOF:
+		e.push(e.unary(instruction))
+	case opCode == data.IINC:
+		index := instruction.(*class.IincInstruction).Var
+		value := e.local(index)
+		if e.err == nil {
+			value, err := e.interpreter.UnaryOperation(instruction, value)
+			e.fail(err)
+			e.setLocal(index, value)
+		}
+	case opCode >= data.IF_ICMPEQ && opCode <= data.IF_ACMPNE, opCode == data.PUTFIELD:
+		_, err := e.binary(instruction)
+		e.fail(err)
+	case opCode >= data.IFEQ && opCode <= data.IFLE, opCode == data.TABLESWITCH, opCode == data.LOOKUPSWITCH,
+		opCode == data.PUTSTATIC, opCode == data.ATHROW, opCode == data.MONITORENTER, opCode == data.MONITOREXIT,
+		opCode == data.IFNULL, opCode == data.IFNONNULL:
+		_, err := e.unary(instruction)
+		e.fail(err)
+	case opCode >= data.IRETURN && opCode <= data.ARETURN:
+		value := e.pop()
+		if e.err != nil {
+			return
+		}
+		_, err := e.interpreter.UnaryOperation(instruction, value)
+		e.fail(err)
+		if e.err == nil {
+			e.fail(e.interpreter.ReturnOperation(instruction, value, frame.returnValue))
+		}
+	case opCode == data.RETURN:
+		if frame.returnValue != nil {
+			e.fail(errors.New("incompatible return type"))
+		}
+	case opCode >= data.INVOKEVIRTUAL && opCode <= data.INVOKEDYNAMIC:
+		var descriptor string
+		switch insn := instruction.(type) {
+		case *class.MethodInstruction:
+			descriptor = insn.Descriptor
+		case *class.InvokeDynamicInstruction:
+			descriptor = insn.Descriptor
+		}
+		methodType := class.NewMethodType(descriptor)
+		n := len(methodType.ArgumentTypes())
+		if opCode != data.INVOKESTATIC && opCode != data.INVOKEDYNAMIC {
+			n++
+		}
+		value, err := e.nary(instruction, n)
+		if methodType.ReturnType().Sort() == data.TYPE_SORT_VOID {
+			e.fail(err)
+		} else {
+			e.push(value, err)
+		}
+	case opCode == data.MULTIANEWARRAY:
+		e.push(e.nary(instruction, instruction.(*class.MultiANewArrayInstruction).NumDimensions))
+	default:
+		e.fail(fmt.Errorf("illegal opcode %d", opCode))
+	}
+}
@@ -0,0 +1,132 @@
+package analysis
+
+import (
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+// SourceValue is a value of the SourceInterpreter, which records the instructions that can
+// produce it.
+type SourceValue struct {
+	size int
+	// Instructions are the instructions that can produce the value, which are empty for the
+	// parameters, the exceptions of the handlers and the uninitialized local variables.
+	Instructions []class.Instruction
+}
+
+func (v *SourceValue) Size() int {
+	return v.size
+}
+
+// contains returns true if the value can be produced by an instruction.
+func (v *SourceValue) contains(instruction class.Instruction) bool {
+	for _, source := range v.Instructions {
+		if source == instruction {
+			return true
+		}
+	}
+	return false
+}
+
+// SourceInterpreter is an Interpreter of SourceValues, which tracks the instructions producing
+// each value. A value loaded from a local variable is produced by the load instruction, and a
+// stored value by the store instruction.
+type SourceInterpreter struct{}
+
+func newSourceValue(size int, instruction class.Instruction) Value {
+	return &SourceValue{size: size, Instructions: []class.Instruction{instruction}}
+}
+
+func (SourceInterpreter) NewValue(t class.Type) Value {
+	if t.Sort() == data.TYPE_SORT_VOID {
+		return nil
+	}
+	return &SourceValue{size: t.Size()}
+}
+
+func (SourceInterpreter) NewEmptyValue() Value {
+	return &SourceValue{size: 1}
+}
+
+func (SourceInterpreter) NewOperation(instruction class.Instruction) (Value, error) {
+	size := 1
+	switch insn := instruction.(type) {
+	case *class.CodeInstruction:
+		if insn.Op == data.LCONST_0 || insn.Op == data.LCONST_1 || insn.Op == data.DCONST_0 || insn.Op == data.DCONST_1 {
+			size = 2
+		}
+	case *class.LdcInstruction:
+		switch v := insn.Value.(type) {
+		case int64, float64:
+			size = 2
+		case class.ConstantDynamic:
+			size = class.NewType(v.Descriptor).Size()
+		}
+	case *class.FieldInstruction:
+		size = class.NewType(insn.Descriptor).Size()
+	}
+	return newSourceValue(size, instruction), nil
+}
+
+func (SourceInterpreter) CopyOperation(instruction class.Instruction, value Value) (Value, error) {
+	return newSourceValue(value.Size(), instruction), nil
+}
+
+func (SourceInterpreter) UnaryOperation(instruction class.Instruction, value Value) (Value, error) {
+	size := 1
+	switch opCode := instruction.OpCode(); opCode {
+	case data.LNEG, data.DNEG, data.I2L, data.I2D, data.L2D, data.F2L, data.F2D, data.D2L:
+		size = 2
+	case data.GETFIELD:
+		size = class.NewType(instruction.(*class.FieldInstruction).Descriptor).Size()
+	}
+	return newSourceValue(size, instruction), nil
+}
+
+func (SourceInterpreter) BinaryOperation(instruction class.Instruction, value1 Value, value2 Value) (Value, error) {
+	size := 1
+	switch opCode := instruction.OpCode(); opCode {
+	case data.LALOAD, data.DALOAD, data.LADD, data.DADD, data.LSUB, data.DSUB, data.LMUL, data.DMUL, data.LDIV,
+		data.DDIV, data.LREM, data.DREM, data.LSHL, data.LSHR, data.LUSHR, data.LAND, data.LOR, data.LXOR:
+		size = 2
+	}
+	return newSourceValue(size, instruction), nil
+}
+
+func (SourceInterpreter) TernaryOperation(instruction class.Instruction, value1 Value, value2 Value, value3 Value) (Value, error) {
+	return newSourceValue(1, instruction), nil
+}
+
+func (SourceInterpreter) NaryOperation(instruction class.Instruction, values []Value) (Value, error) {
+	size := 1
+	switch insn := instruction.(type) {
+	case *class.InvokeDynamicInstruction:
+		size = class.NewMethodType(insn.Descriptor).ReturnType().Size()
+	case *class.MethodInstruction:
+		size = class.NewMethodType(insn.Descriptor).ReturnType().Size()
+	}
+	return newSourceValue(size, instruction), nil
+}
+
+func (SourceInterpreter) ReturnOperation(instruction class.Instruction, value Value, expected Value) error {
+	return nil
+}
+
+func (SourceInterpreter) Merge(value1 Value, value2 Value) Value {
+	source1, source2 := value1.(*SourceValue), value2.(*SourceValue)
+	var added []class.Instruction
+	for _, instruction := range source2.Instructions {
+		if !source1.contains(instruction) {
+			added = append(added, instruction)
+		}
+	}
+	if len(added) == 0 && source1.size == source2.size {
+		return value1
+	}
+	size := source1.size
+	if source2.size < size {
+		size = source2.size
+	}
+	instructions := append(append([]class.Instruction{}, source1.Instructions...), added...)
+	return &SourceValue{size: size, Instructions: instructions}
+}
@@ -0,0 +1,232 @@
+// Package archive reads the classes and resources of JAR and ZIP files.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+)
+
+const versionsDirectory = "META-INF/versions/"
+
+// Archive is an opened JAR or ZIP file. The entries of a multi-release JAR are resolved for
+// the release set with SetRelease: an entry of META-INF/versions/N/ overlays the entry with the
+// same name for the releases greater than or equal to N. By default, only the base entries are
+// visible.
+type Archive struct {
+	reader   *zip.Reader
+	closer   io.Closer
+	manifest *Manifest
+	release  int
+	files    map[string]*zip.File
+	entries  map[string]Entry
+}
+
+// Entry is an entry of an archive. Name is the name of the entry without the version prefix,
+// and Release is the release of the entry, or 0 for base entries.
+type Entry struct {
+	Name    string
+	Release int
+	File    *zip.File
+}
+
+// IsClass returns true if the entry is a class file.
+func (e Entry) IsClass() bool {
+	return strings.HasSuffix(e.Name, ".class") && !e.File.FileInfo().IsDir()
+}
+
+// ClassName returns the internal name of the class of a class entry.
+func (e Entry) ClassName() string {
+	return strings.TrimSuffix(e.Name, ".class")
+}
+
+// Open opens the archive file with the given name.
+func Open(name string) (*Archive, error) {
+	reader, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := newArchive(&reader.Reader)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	archive.closer = reader
+	return archive, nil
+}
+
+// NewArchive reads an archive of the given size.
+func NewArchive(reader io.ReaderAt, size int64) (*Archive, error) {
+	zipReader, err := zip.NewReader(reader, size)
+	if err != nil {
+		return nil, err
+	}
+	return newArchive(zipReader)
+}
+
+// NewArchiveBytes reads an archive from its content, for example a JAR nested in another JAR.
+func NewArchiveBytes(content []byte) (*Archive, error) {
+	return NewArchive(bytes.NewReader(content), int64(len(content)))
+}
+
+func newArchive(reader *zip.Reader) (*Archive, error) {
+	a := &Archive{reader: reader, files: make(map[string]*zip.File)}
+	for _, file := range reader.File {
+		if _, ok := a.files[file.Name]; !ok {
+			a.files[file.Name] = file
+		}
+	}
+	if file, ok := a.files[ManifestName]; ok {
+		content, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		a.manifest, err = ParseManifest(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+	}
+	a.index()
+	return a, nil
+}
+
+// Close closes the archive file, if the archive was opened with Open.
+func (a *Archive) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// Manifest returns the manifest of the archive, or nil if it has no manifest.
+func (a *Archive) Manifest() *Manifest {
+	return a.manifest
+}
+
+// IsMultiRelease returns true if the archive is a multi-release JAR.
+func (a *Archive) IsMultiRelease() bool {
+	return a.manifest != nil && a.manifest.MultiRelease()
+}
+
+// Release returns the release for which the entries are resolved.
+func (a *Archive) Release() int {
+	return a.release
+}
+
+// SetRelease sets the Java release for which the entries are resolved, for example 11. A
+// release of 0 selects the base entries. It must not be called concurrently with other
+// methods.
+func (a *Archive) SetRelease(release int) {
+	a.release = release
+	a.index()
+}
+
+func (a *Archive) index() {
+	a.entries = make(map[string]Entry)
+	multiRelease := a.IsMultiRelease() && a.release > 0
+	for _, file := range a.reader.File {
+		name := file.Name
+		release := 0
+		if strings.HasPrefix(name, versionsDirectory) {
+			if !multiRelease {
+				continue
+			}
+			i := strings.Index(name[len(versionsDirectory):], "/")
+			if i < 0 {
+				continue
+			}
+			version, err := strconv.Atoi(name[len(versionsDirectory) : len(versionsDirectory)+i])
+			if err != nil || version > a.release {
+				continue
+			}
+			release = version
+			name = name[len(versionsDirectory)+i+1:]
+			if len(name) == 0 {
+				continue
+			}
+		}
+		if entry, ok := a.entries[name]; !ok || release > entry.Release {
+			a.entries[name] = Entry{Name: name, Release: release, File: file}
+		}
+	}
+}
+
+// Entries returns the resolved entries of the archive, sorted by name.
+func (a *Archive) Entries() []Entry {
+	entries := make([]Entry, 0, len(a.entries))
+	for _, entry := range a.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// ClassEntries returns the resolved class entries of the archive, sorted by name.
+func (a *Archive) ClassEntries() []Entry {
+	entries := make([]Entry, 0)
+	for _, entry := range a.Entries() {
+		if entry.IsClass() {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Entry returns the resolved entry with the given name.
+func (a *Archive) Entry(name string) (Entry, bool) {
+	entry, ok := a.entries[name]
+	return entry, ok
+}
+
+// ReadFile returns the content of the resolved entry with the given name.
+func (a *Archive) ReadFile(name string) ([]byte, error) {
+	entry, ok := a.entries[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return readFile(entry.File)
+}
+
+// ReadClass parses the class with the given internal name.
+func (a *Archive) ReadClass(internalName string) (*class.Class, error) {
+	content, err := a.ReadFile(internalName + ".class")
+	if err != nil {
+		return nil, err
+	}
+	return readClass(content)
+}
+
+// Read parses the class of a class entry.
+func (e Entry) Read() (*class.Class, error) {
+	content, err := readFile(e.File)
+	if err != nil {
+		return nil, err
+	}
+	return readClass(content)
+}
+
+func readClass(content []byte) (*class.Class, error) {
+	reader := class.NewReader(bytes.NewReader(content))
+	if err := reader.Read(); err != nil {
+		return nil, err
+	}
+	return reader.Class(), nil
+}
+
+func readFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
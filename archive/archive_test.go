@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/tools"
+)
+
+func newTestClass(t *testing.T, name string, version uint32) []byte {
+	writer := class.NewWriter()
+	writer.Visit(version, data.ACC_PUBLIC, name, "", "java/lang/Object", nil)
+	writer.VisitEnd()
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+	return content
+}
+
+func newTestArchive(t *testing.T, entries map[string][]byte) *Archive {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, content := range entries {
+		w, err := writer.Create(name)
+		tools.AssertNoErr(t, err)
+		w.Write(content)
+	}
+	tools.AssertNoErr(t, writer.Close())
+	archive, err := NewArchiveBytes(buffer.Bytes())
+	tools.AssertNoErr(t, err)
+	return archive
+}
+
+func TestParseManifest(t *testing.T) {
+	manifest, err := ParseManifest(strings.NewReader("Manifest-Version: 1.0\r\n" +
+		"Main-Class: a.Main\r\n" +
+		"Class-Path: lib/one.jar lib/tw\r\n" +
+		" o.jar\r\n" +
+		"multi-release: true\r\n" +
+		"\r\n" +
+		"Name: a/Main.class\r\n" +
+		"Sealed: true\r\n"))
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "a.Main", manifest.MainClass())
+	tools.AssertEqual(t, 2, len(manifest.ClassPath()))
+	tools.AssertEqual(t, "lib/two.jar", manifest.ClassPath()[1])
+	tools.AssertEqual(t, true, manifest.MultiRelease())
+	tools.AssertEqual(t, "true", manifest.Entries["a/Main.class"].Get("sealed"))
+
+	_, err = ParseManifest(strings.NewReader("Manifest-Version: 1.0\n\nSealed: true\n"))
+	tools.AssertError(t, err)
+}
+
+func TestMultiRelease(t *testing.T) {
+	archive := newTestArchive(t, map[string][]byte{
+		ManifestName:                         []byte("Manifest-Version: 1.0\nMulti-Release: true\n"),
+		"a/A.class":                          newTestClass(t, "a/A", 52),
+		"a/B.class":                          newTestClass(t, "a/B", 52),
+		"META-INF/versions/9/a/A.class":      newTestClass(t, "a/A", 53),
+		"META-INF/versions/11/a/A.class":     newTestClass(t, "a/A", 55),
+		"META-INF/versions/11/a/C.class":     newTestClass(t, "a/C", 55),
+		"META-INF/versions/17/a/B.class":     newTestClass(t, "a/B", 61),
+		"META-INF/versions/9/module-info.tx": []byte("resource"),
+	})
+	tools.AssertEqual(t, true, archive.IsMultiRelease())
+	tools.AssertEqual(t, 2, len(archive.ClassEntries()))
+	a, err := archive.ReadClass("a/A")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, uint32(52), a.Version)
+
+	archive.SetRelease(11)
+	entries := archive.ClassEntries()
+	tools.AssertEqual(t, 3, len(entries))
+	tools.AssertEqual(t, "a/A", entries[0].ClassName())
+	tools.AssertEqual(t, 11, entries[0].Release)
+	a, err = entries[0].Read()
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, uint32(55), a.Version)
+	b, err := archive.ReadClass("a/B")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, uint32(52), b.Version)
+	_, err = archive.ReadClass("a/D")
+	tools.AssertError(t, err)
+}
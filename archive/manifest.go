@@ -0,0 +1,140 @@
+package archive
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	ManifestName = "META-INF/MANIFEST.MF"
+
+	ManifestVersion = "Manifest-Version"
+	MainClass       = "Main-Class"
+	ClassPath       = "Class-Path"
+	MultiRelease    = "Multi-Release"
+	AutomaticModule = "Automatic-Module-Name"
+)
+
+// Attributes are the attributes of a manifest section. Attribute names are case insensitive.
+type Attributes map[string]string
+
+// Get returns the value of the attribute with the given name, or "" if there is no such
+// attribute.
+func (a Attributes) Get(name string) string {
+	if value, ok := a[name]; ok {
+		return value
+	}
+	for key, value := range a {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Manifest is a parsed JAR manifest, with its main attributes and its per-entry sections
+// indexed by entry name.
+type Manifest struct {
+	Main    Attributes
+	Entries map[string]Attributes
+}
+
+// ParseManifest parses a manifest as specified by the JAR file specification. Continuation
+// lines start with a single space, and sections are separated by empty lines.
+func ParseManifest(reader io.Reader) (*Manifest, error) {
+	manifest := &Manifest{Main: make(Attributes), Entries: make(map[string]Attributes)}
+	scanner := bufio.NewScanner(reader)
+	scanner.Split(scanManifestLines)
+	section := manifest.Main
+	var name string
+	var value strings.Builder
+	flush := func() error {
+		if len(name) == 0 {
+			return nil
+		}
+		if section == nil {
+			if !strings.EqualFold(name, "Name") {
+				return fmt.Errorf("invalid manifest: section does not start with Name: %s", name)
+			}
+			section = make(Attributes)
+			manifest.Entries[value.String()] = section
+		} else {
+			section[name] = value.String()
+		}
+		name = ""
+		value.Reset()
+		return nil
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, " ") {
+			if len(name) == 0 {
+				return nil, fmt.Errorf("invalid manifest: unexpected continuation line %q", line)
+			}
+			value.WriteString(line[1:])
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			section = nil
+			continue
+		}
+		i := strings.Index(line, ": ")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid manifest: invalid header %q", line)
+		}
+		name = line[:i]
+		value.WriteString(line[i+2:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// scanManifestLines splits lines terminated by CR LF, LF or CR.
+func scanManifestLines(content []byte, atEOF bool) (int, []byte, error) {
+	for i, b := range content {
+		if b == '\n' {
+			return i + 1, content[:i], nil
+		}
+		if b == '\r' {
+			if i+1 < len(content) {
+				if content[i+1] == '\n' {
+					return i + 2, content[:i], nil
+				}
+				return i + 1, content[:i], nil
+			}
+			if atEOF {
+				return i + 1, content[:i], nil
+			}
+			return 0, nil, nil
+		}
+	}
+	if atEOF && len(content) > 0 {
+		return len(content), content, nil
+	}
+	return 0, nil, nil
+}
+
+// MainClass returns the Main-Class attribute, in dotted form.
+func (m *Manifest) MainClass() string {
+	return m.Main.Get(MainClass)
+}
+
+// ClassPath returns the relative URLs of the Class-Path attribute.
+func (m *Manifest) ClassPath() []string {
+	return strings.Fields(m.Main.Get(ClassPath))
+}
+
+// MultiRelease returns true if the Multi-Release attribute is true.
+func (m *Manifest) MultiRelease() bool {
+	return strings.EqualFold(strings.TrimSpace(m.Main.Get(MultiRelease)), "true")
+}
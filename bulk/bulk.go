@@ -0,0 +1,239 @@
+// Package bulk parses a stream of class files on a pool of goroutines. The results are
+// delivered in the order of the inputs, or as soon as they are ready, with an error for each
+// input which cannot be read or parsed.
+//
+// The number of inputs which are read, parsed or waiting to be delivered is bounded, so that a
+// slow consumer holds back the reading of the inputs and the memory stays bounded.
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"runtime"
+	"sync"
+
+	"github.com/tk103331/clazz/archive"
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/classpath"
+)
+
+// Input is a class file to parse.
+type Input struct {
+	// Name identifies the input in its result, such as a file path or an archive entry.
+	Name string
+	// Open returns the content of the class file. It is called by the workers.
+	Open func() ([]byte, error)
+}
+
+// Bytes returns an input with the given content.
+func Bytes(name string, content []byte) Input {
+	return Input{Name: name, Open: func() ([]byte, error) {
+		return content, nil
+	}}
+}
+
+// File returns an input which reads a class file.
+func File(path string) Input {
+	return Input{Name: path, Open: func() ([]byte, error) {
+		return ioutil.ReadFile(path)
+	}}
+}
+
+// ArchiveEntry returns an input which reads an entry of an archive. Its name is
+// "archive!/entry".
+func ArchiveEntry(name string, a *archive.Archive, entry string) Input {
+	return Input{Name: name + "!/" + entry, Open: func() ([]byte, error) {
+		return a.ReadFile(entry)
+	}}
+}
+
+// ArchiveClasses returns the inputs of the class entries of an archive.
+func ArchiveClasses(name string, a *archive.Archive) []Input {
+	entries := a.ClassEntries()
+	inputs := make([]Input, len(entries))
+	for i, entry := range entries {
+		inputs[i] = ArchiveEntry(name, a, entry.Name)
+	}
+	return inputs
+}
+
+// SourceClass returns an input which reads a class of a classpath source. Its name is the
+// internal name of the class.
+func SourceClass(source classpath.Source, internalName string) Input {
+	return Input{Name: internalName, Open: func() ([]byte, error) {
+		return source.ReadClass(internalName)
+	}}
+}
+
+// Stream returns a channel which sends the given inputs, and is closed after the last one or
+// when the context is done.
+func Stream(ctx context.Context, inputs []Input) <-chan Input {
+	stream := make(chan Input)
+	go func() {
+		defer close(stream)
+		for _, input := range inputs {
+			select {
+			case stream <- input:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return stream
+}
+
+// Result is the result of the parsing of an input.
+type Result struct {
+	// Index is the position of the input in the stream, from 0.
+	Index int
+	Name  string
+	// Class is the parsed class, or nil if Err is not nil.
+	Class *class.Class
+	Err   error
+}
+
+// Options configure the parsing.
+type Options struct {
+	// Workers is the number of goroutines which parse the inputs. By default, the number of
+	// CPUs.
+	Workers int
+	// Window is the maximum number of inputs which are being read, parsed, or waiting to be
+	// delivered. By default, four times the number of workers. With Ordered, a slow input holds
+	// back the delivery of the next ones, and the reading of the inputs once the window is full.
+	Window int
+	// Ordered delivers the results in the order of the inputs, instead of as soon as they are
+	// ready.
+	Ordered bool
+	// AnnotationsOnly parses the classes with class.Reader.ReadAnnotations instead of Read.
+	AnnotationsOnly bool
+}
+
+// Parse parses the inputs of a stream, and returns the channel of their results. The channel
+// is closed after the result of the last input, or when the context is done. The input stream
+// must be closed by its producer, or the context must be done, for all the goroutines to
+// terminate.
+func Parse(ctx context.Context, inputs <-chan Input, options Options) <-chan Result {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	window := options.Window
+	if window <= 0 {
+		window = 4 * workers
+	}
+	type job struct {
+		index int
+		input Input
+	}
+	slots := make(chan struct{}, window)
+	jobs := make(chan job)
+	done := make(chan Result)
+	results := make(chan Result)
+
+	// The dispatcher takes a slot of the window for each input, which is released when its
+	// result is delivered.
+	go func() {
+		defer close(jobs)
+		for index := 0; ; index++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			var input Input
+			var ok bool
+			select {
+			case input, ok = <-inputs:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job{index, input}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result := parse(j.input, options.AnnotationsOnly)
+				result.Index = j.index
+				select {
+				case done <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	go func() {
+		defer close(results)
+		pending := make(map[int]Result)
+		next := 0
+		deliver := func(result Result) bool {
+			select {
+			case results <- result:
+				<-slots
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for result := range done {
+			if !options.Ordered {
+				if !deliver(result) {
+					return
+				}
+				continue
+			}
+			pending[result.Index] = result
+			for {
+				result, ok := pending[next]
+				if !ok {
+					break
+				}
+				if !deliver(result) {
+					return
+				}
+				delete(pending, next)
+				next++
+			}
+		}
+	}()
+	return results
+}
+
+func parse(input Input, annotationsOnly bool) Result {
+	result := Result{Name: input.Name}
+	content, err := input.Open()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	reader := class.NewReader(bytes.NewReader(content))
+	if annotationsOnly {
+		err = reader.ReadAnnotations()
+	} else {
+		err = reader.Read()
+	}
+	if err != nil {
+		result.Err = err
+	} else {
+		result.Class = reader.Class()
+	}
+	return result
+}
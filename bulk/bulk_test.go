@@ -0,0 +1,70 @@
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/tk103331/clazz/tools"
+)
+
+func inputs(t *testing.T, count int) []Input {
+	content, err := ioutil.ReadFile("../class/Hello.class")
+	tools.AssertNoErr(t, err)
+	inputs := make([]Input, count)
+	for i := range inputs {
+		if i%10 == 3 {
+			inputs[i] = Bytes(fmt.Sprint(i), []byte{0xca, 0xfe})
+		} else {
+			inputs[i] = Bytes(fmt.Sprint(i), content)
+		}
+	}
+	return inputs
+}
+
+func TestParseOrdered(t *testing.T) {
+	ctx := context.Background()
+	index := 0
+	for result := range Parse(ctx, Stream(ctx, inputs(t, 100)), Options{Workers: 4, Window: 8, Ordered: true}) {
+		tools.AssertEqual(t, index, result.Index)
+		tools.AssertEqual(t, fmt.Sprint(index), result.Name)
+		if index%10 == 3 {
+			tools.AssertEqual(t, true, result.Err != nil)
+		} else {
+			tools.AssertNoErr(t, result.Err)
+			tools.AssertEqual(t, "com/example/demo/Hello", result.Class.ThisClass)
+		}
+		index++
+	}
+	tools.AssertEqual(t, 100, index)
+}
+
+func TestParseUnordered(t *testing.T) {
+	ctx := context.Background()
+	var indexes []int
+	errors := 0
+	for result := range Parse(ctx, Stream(ctx, inputs(t, 100)), Options{Workers: 3, AnnotationsOnly: true}) {
+		indexes = append(indexes, result.Index)
+		if result.Err != nil {
+			errors++
+		}
+	}
+	sort.Ints(indexes)
+	tools.AssertEqual(t, 100, len(indexes))
+	tools.AssertEqual(t, 99, indexes[99])
+	tools.AssertEqual(t, 10, errors)
+}
+
+func TestParseCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	results := Parse(ctx, Stream(ctx, inputs(t, 100)), Options{Workers: 2, Window: 2, Ordered: true})
+	<-results
+	cancel()
+	count := 1
+	for range results {
+		count++
+	}
+	tools.AssertEqual(t, true, count < 100)
+}
@@ -0,0 +1,484 @@
+// Package callgraph builds the call graph of the classes of a classpath from their invoke
+// instructions. The targets of the virtual and interface calls are computed with the class
+// hierarchy analysis (CHA), which considers all the subclasses of the receiver type, or with the
+// rapid type analysis (RTA), which only considers the classes instantiated by the methods
+// reachable from the entry points. The invokedynamic instructions of the lambda expressions and
+// method references call their implementation methods. Those of the string concatenations, the
+// record methods and the pattern matching switches have no callees, and those of the other
+// bootstrap methods are unresolved.
+package callgraph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/classpath"
+	"github.com/tk103331/clazz/hierarchy"
+)
+
+// Algorithm is the algorithm used to compute the targets of the virtual calls.
+type Algorithm int
+
+const (
+	// CHA analyzes all the methods of the classpath, and dispatches the virtual calls to all the
+	// subclasses of the receiver type.
+	CHA Algorithm = iota
+	// RTA only analyzes the methods reachable from the entry points, and dispatches the virtual
+	// calls to the classes instantiated by these methods.
+	RTA
+)
+
+func (a Algorithm) String() string {
+	if a == RTA {
+		return "rta"
+	}
+	return "cha"
+}
+
+const (
+	lambdaMetafactory = "java/lang/invoke/LambdaMetafactory"
+	mainDescriptor    = "([Ljava/lang/String;)V"
+)
+
+// jdkBootstraps are the bootstrap methods of the JDK whose call sites do not call the methods of
+// the classpath, as "owner.name".
+var jdkBootstraps = map[string]bool{
+	"java/lang/invoke/StringConcatFactory.makeConcat":              true,
+	"java/lang/invoke/StringConcatFactory.makeConcatWithConstants": true,
+	"java/lang/runtime/ObjectMethods.bootstrap":                    true,
+	"java/lang/runtime/SwitchBootstraps.typeSwitch":                true,
+	"java/lang/runtime/SwitchBootstraps.enumSwitch":                true,
+}
+
+// Method designates a method by the internal name of its class, its name and its descriptor.
+type Method struct {
+	Owner      string
+	Name       string
+	Descriptor string
+}
+
+func (m Method) String() string {
+	return m.Owner + "." + m.Name + m.Descriptor
+}
+
+func less(a Method, b Method) bool {
+	return a.String() < b.String()
+}
+
+// CallSite is an invoke instruction of a method.
+type CallSite struct {
+	Caller Method
+	// Index is the index of the instruction in the instructions of the caller.
+	Index  int
+	OpCode int
+	// Target is the method referenced by the instruction. For an invokedynamic instruction, it
+	// is the implementation method of a lambda expression or method reference, or the name and
+	// descriptor of the call site with the owner of its bootstrap method.
+	Target Method
+	// Callees are the methods of the classpath which can be called, sorted.
+	Callees []Method
+	// Unresolved is the reason why the target could not be resolved in the classpath, or an
+	// empty string.
+	Unresolved string
+}
+
+func (s *CallSite) addCallee(callee Method) bool {
+	i := sort.Search(len(s.Callees), func(i int) bool { return !less(s.Callees[i], callee) })
+	if i < len(s.Callees) && s.Callees[i] == callee {
+		return false
+	}
+	s.Callees = append(s.Callees, Method{})
+	copy(s.Callees[i+1:], s.Callees[i:])
+	s.Callees[i] = callee
+	return true
+}
+
+// Options are the options of Build.
+type Options struct {
+	Algorithm Algorithm
+	// EntryPoints are the roots of the RTA analysis and of the reachability queries. By default,
+	// they are the main methods and the static initializers of the classpath.
+	EntryPoints []Method
+}
+
+// Graph is a call graph, whose nodes are the methods declared in the classpath.
+type Graph struct {
+	Algorithm   Algorithm
+	EntryPoints []Method
+	methods     map[Method]bool
+	sites       map[Method][]*CallSite
+	callers     map[Method][]*CallSite
+}
+
+// Build returns the call graph of all the classes of a classpath.
+func Build(cp *classpath.Classpath, options Options) (*Graph, error) {
+	names, err := cp.ClassNames()
+	if err != nil {
+		return nil, err
+	}
+	b := &builder{
+		classes:      make(map[string]*class.Class),
+		hierarchy:    hierarchy.NewWithLoader(cp),
+		instantiated: make(map[string]bool),
+		graph: &Graph{Algorithm: options.Algorithm, EntryPoints: options.EntryPoints, methods: make(map[Method]bool),
+			sites: make(map[Method][]*CallSite), callers: make(map[Method][]*CallSite)},
+	}
+	for _, name := range names {
+		c, err := cp.Find(name)
+		if err != nil {
+			return nil, err
+		}
+		b.classes[name] = c
+		b.hierarchy.Add(c)
+	}
+	graph := b.graph
+	if graph.EntryPoints == nil {
+		for _, name := range names {
+			for _, method := range b.classes[name].Methods {
+				if method.Name == "<clinit>" || method.Name == "main" && method.Descriptor == mainDescriptor &&
+					method.AccessFlags&(data.ACC_PUBLIC|data.ACC_STATIC) == data.ACC_PUBLIC|data.ACC_STATIC {
+					graph.EntryPoints = append(graph.EntryPoints, Method{name, method.Name, method.Descriptor})
+				}
+			}
+		}
+	}
+	if graph.Algorithm == CHA {
+		for _, name := range names {
+			for _, method := range b.classes[name].Methods {
+				b.reach(Method{name, method.Name, method.Descriptor})
+			}
+		}
+	} else {
+		for _, entryPoint := range graph.EntryPoints {
+			b.reach(entryPoint)
+		}
+	}
+	for len(b.queue) > 0 {
+		method := b.queue[0]
+		b.queue = b.queue[1:]
+		b.analyze(method)
+	}
+	return graph, nil
+}
+
+type builder struct {
+	classes   map[string]*class.Class
+	hierarchy *hierarchy.Hierarchy
+	graph     *Graph
+	// queue are the reached methods which have not been analyzed yet.
+	queue []Method
+	// instantiated are the classes instantiated by the reached methods, for RTA.
+	instantiated map[string]bool
+	// virtualSites are the virtual call sites of the reached methods, for RTA.
+	virtualSites []*CallSite
+}
+
+// declaration returns the declaration of a method in the classpath, or nil.
+func (b *builder) declaration(m Method) *class.Method {
+	c := b.classes[m.Owner]
+	if c == nil {
+		return nil
+	}
+	for i := range c.Methods {
+		if c.Methods[i].Name == m.Name && c.Methods[i].Descriptor == m.Descriptor {
+			return &c.Methods[i]
+		}
+	}
+	return nil
+}
+
+// reach adds a method of the classpath to the graph.
+func (b *builder) reach(m Method) {
+	if b.graph.methods[m] || b.declaration(m) == nil {
+		return
+	}
+	b.graph.methods[m] = true
+	b.queue = append(b.queue, m)
+}
+
+// analyze adds the call sites of a method.
+func (b *builder) analyze(caller Method) {
+	for index, instruction := range b.declaration(caller).Code.Instructions {
+		site := &CallSite{Caller: caller, Index: index, OpCode: instruction.OpCode()}
+		switch insn := instruction.(type) {
+		case *class.TypeInstruction:
+			if insn.Op == data.NEW {
+				b.instantiate(insn.Type)
+			}
+			continue
+		case *class.MethodInstruction:
+			site.Target = Method{insn.Owner, insn.Name, insn.Descriptor}
+			b.call(site, site.OpCode)
+		case *class.InvokeDynamicInstruction:
+			site.Target = Method{insn.BootstrapMethod.Owner, insn.Name, insn.Descriptor}
+			handle, ok := lambdaImplementation(insn)
+			if !ok {
+				if !jdkBootstraps[handle.Owner+"."+handle.Name] {
+					site.Unresolved = "unsupported bootstrap method " + handle.Owner + "." + handle.Name
+				}
+				break
+			}
+			site.Target = Method{handle.Owner, handle.Name, handle.Descriptor}
+			switch handle.Tag {
+			case data.HANDLE_INVOKEVIRTUAL:
+				b.call(site, data.INVOKEVIRTUAL)
+			case data.HANDLE_INVOKEINTERFACE:
+				b.call(site, data.INVOKEINTERFACE)
+			case data.HANDLE_INVOKESTATIC:
+				b.call(site, data.INVOKESTATIC)
+			default:
+				if handle.Tag == data.HANDLE_NEWINVOKESPECIAL {
+					b.instantiate(handle.Owner)
+				}
+				b.call(site, data.INVOKESPECIAL)
+			}
+		default:
+			continue
+		}
+		b.graph.sites[caller] = append(b.graph.sites[caller], site)
+	}
+}
+
+// lambdaImplementation returns the implementation method handle of a lambda expression or method
+// reference, or the bootstrap method handle if the instruction is not a lambda.
+func lambdaImplementation(insn *class.InvokeDynamicInstruction) (class.Handle, bool) {
+	bootstrap := insn.BootstrapMethod
+	if bootstrap.Owner == lambdaMetafactory && len(insn.BootstrapMethodArguments) >= 2 {
+		if handle, ok := insn.BootstrapMethodArguments[1].(class.Handle); ok {
+			return handle, true
+		}
+	}
+	return bootstrap, false
+}
+
+// call resolves the target of a call site, and adds its callees.
+func (b *builder) call(site *CallSite, opCode int) {
+	target, reason := b.resolve(site.Target)
+	site.Unresolved = reason
+	if opCode == data.INVOKEVIRTUAL || opCode == data.INVOKEINTERFACE {
+		if reason == "" && b.isFinal(target) {
+			b.addCallee(site, target)
+			return
+		}
+		for _, receiver := range b.receivers(site.Target.Owner) {
+			b.dispatch(site, receiver)
+		}
+		if b.graph.Algorithm == RTA {
+			b.virtualSites = append(b.virtualSites, site)
+		}
+	} else if reason == "" {
+		b.addCallee(site, target)
+	}
+}
+
+// isFinal returns true if a resolved method cannot be overridden.
+func (b *builder) isFinal(m Method) bool {
+	access := b.declaration(m).AccessFlags
+	return access&(data.ACC_PRIVATE|data.ACC_FINAL|data.ACC_STATIC) != 0 ||
+		b.classes[m.Owner].AccessFlags&data.ACC_FINAL != 0
+}
+
+func (b *builder) addCallee(site *CallSite, callee Method) {
+	if site.addCallee(callee) {
+		b.graph.callers[callee] = append(b.graph.callers[callee], site)
+		b.reach(callee)
+	}
+}
+
+// resolve returns the declaration of a referenced method, searched in its class, its
+// superclasses and its superinterfaces, or the reason why it is not found.
+func (b *builder) resolve(m Method) (Method, string) {
+	reason := "method " + m.String() + " not found"
+	for owner := m.Owner; owner != ""; owner = b.classes[owner].SuperClass {
+		if b.classes[owner] == nil {
+			reason = "class " + owner + " not found"
+			break
+		}
+		if b.declaration(Method{owner, m.Name, m.Descriptor}) != nil {
+			return Method{owner, m.Name, m.Descriptor}, ""
+		}
+	}
+	for _, supertype := range b.hierarchy.AllSupertypes(m.Owner) {
+		if b.declaration(Method{supertype, m.Name, m.Descriptor}) != nil {
+			return Method{supertype, m.Name, m.Descriptor}, ""
+		}
+	}
+	return Method{}, reason
+}
+
+// isConcrete returns true for the classes of the classpath which can be instantiated.
+func (b *builder) isConcrete(name string) bool {
+	c := b.classes[name]
+	return c != nil && c.AccessFlags&(data.ACC_INTERFACE|data.ACC_ABSTRACT) == 0
+}
+
+// receivers returns the possible classes of the receiver of a virtual call.
+func (b *builder) receivers(owner string) []string {
+	var receivers []string
+	for _, name := range append([]string{owner}, b.hierarchy.AllSubtypes(owner)...) {
+		if b.isConcrete(name) && (b.graph.Algorithm == CHA || b.instantiated[name]) {
+			receivers = append(receivers, name)
+		}
+	}
+	return receivers
+}
+
+// instantiate records a class instantiated by a reached method, and dispatches the virtual
+// calls of the reached methods to it.
+func (b *builder) instantiate(name string) {
+	if b.graph.Algorithm != RTA || b.instantiated[name] {
+		return
+	}
+	b.instantiated[name] = true
+	if !b.isConcrete(name) {
+		return
+	}
+	for _, site := range b.virtualSites {
+		if b.hierarchy.IsAssignableFrom(site.Target.Owner, name) {
+			b.dispatch(site, name)
+		}
+	}
+}
+
+// dispatch adds the method selected by a virtual call for a receiver class as a callee: the
+// method declared by the class or its superclasses, or else the maximally specific default
+// method of its superinterfaces.
+func (b *builder) dispatch(site *CallSite, receiver string) {
+	name, descriptor := site.Target.Name, site.Target.Descriptor
+	for owner := receiver; b.classes[owner] != nil; owner = b.classes[owner].SuperClass {
+		if method := b.declaration(Method{owner, name, descriptor}); method != nil &&
+			method.AccessFlags&data.ACC_STATIC == 0 {
+			if method.AccessFlags&data.ACC_ABSTRACT == 0 {
+				b.addCallee(site, Method{owner, name, descriptor})
+			}
+			return
+		}
+	}
+	var candidates []string
+	for _, supertype := range b.hierarchy.AllSupertypes(receiver) {
+		method := b.declaration(Method{supertype, name, descriptor})
+		if method != nil && method.AccessFlags&(data.ACC_STATIC|data.ACC_PRIVATE) == 0 && b.hierarchy.IsInterface(supertype) {
+			candidates = append(candidates, supertype)
+		}
+	}
+	var specific []string
+	for _, candidate := range candidates {
+		overridden := false
+		for _, other := range candidates {
+			overridden = overridden || other != candidate && b.hierarchy.IsAssignableFrom(candidate, other)
+		}
+		if !overridden {
+			specific = append(specific, candidate)
+		}
+	}
+	if len(specific) == 1 && b.declaration(Method{specific[0], name, descriptor}).AccessFlags&data.ACC_ABSTRACT == 0 {
+		b.addCallee(site, Method{specific[0], name, descriptor})
+	}
+}
+
+// Methods returns the methods of the graph, sorted: all the methods of the classpath with CHA,
+// or the methods reachable from the entry points with RTA.
+func (g *Graph) Methods() []Method {
+	methods := make([]Method, 0, len(g.methods))
+	for method := range g.methods {
+		methods = append(methods, method)
+	}
+	sortMethods(methods)
+	return methods
+}
+
+// CallSites returns the call sites of a method, in the instruction order.
+func (g *Graph) CallSites(m Method) []*CallSite {
+	return g.sites[m]
+}
+
+// Callees returns the methods called by a method, sorted.
+func (g *Graph) Callees(m Method) []Method {
+	seen := make(map[Method]bool)
+	for _, site := range g.sites[m] {
+		for _, callee := range site.Callees {
+			seen[callee] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// Callers returns the methods which call a method, sorted.
+func (g *Graph) Callers(m Method) []Method {
+	seen := make(map[Method]bool)
+	for _, site := range g.callers[m] {
+		seen[site.Caller] = true
+	}
+	return sortedKeys(seen)
+}
+
+// Unresolved returns the call sites whose target could not be resolved in the classpath.
+func (g *Graph) Unresolved() []*CallSite {
+	var sites []*CallSite
+	for _, method := range g.Methods() {
+		for _, site := range g.sites[method] {
+			if site.Unresolved != "" {
+				sites = append(sites, site)
+			}
+		}
+	}
+	return sites
+}
+
+// Reachable returns the methods of the graph reachable from some methods, including the methods
+// themselves, sorted. By default, the methods are the entry points of the graph.
+func (g *Graph) Reachable(from ...Method) []Method {
+	if len(from) == 0 {
+		from = g.EntryPoints
+	}
+	seen := make(map[Method]bool)
+	var queue []Method
+	for _, m := range from {
+		if g.methods[m] && !seen[m] {
+			seen[m] = true
+			queue = append(queue, m)
+		}
+	}
+	for len(queue) > 0 {
+		for _, site := range g.sites[queue[0]] {
+			for _, callee := range site.Callees {
+				if !seen[callee] {
+					seen[callee] = true
+					queue = append(queue, callee)
+				}
+			}
+		}
+		queue = queue[1:]
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys(set map[Method]bool) []Method {
+	methods := make([]Method, 0, len(set))
+	for method := range set {
+		methods = append(methods, method)
+	}
+	sortMethods(methods)
+	return methods
+}
+
+func sortMethods(methods []Method) {
+	sort.Slice(methods, func(i, j int) bool { return less(methods[i], methods[j]) })
+}
+
+// ParseMethod parses a method in the format of Method.String, such as
+// "a/Main.main([Ljava/lang/String;)V".
+func ParseMethod(s string) (Method, error) {
+	paren := strings.IndexByte(s, '(')
+	dot := -1
+	if paren > 0 {
+		dot = strings.LastIndexByte(s[:paren], '.')
+	}
+	if dot <= 0 {
+		return Method{}, fmt.Errorf("invalid method %q, expected owner.name(descriptor)", s)
+	}
+	return Method{Owner: s[:dot], Name: s[dot+1 : paren], Descriptor: s[paren:]}, nil
+}
@@ -0,0 +1,136 @@
+package callgraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/internal/testutil"
+	"github.com/tk103331/clazz/tools"
+)
+
+var sources = map[string]string{
+	"a/Shape": `.version 52 0
+.class public interface abstract a/Shape
+.super java/lang/Object
+.method public abstract area ()D
+.end method
+`,
+	"a/Circle": `.version 52 0
+.class public super a/Circle
+.super java/lang/Object
+.implements a/Shape
+.method public <init> ()V
+    aload 0
+    invokespecial java/lang/Object <init> ()V
+    return
+.end method
+.method public area ()D
+    dconst_1
+    dreturn
+.end method
+`,
+	"a/Square": `.version 52 0
+.class public super a/Square
+.super java/lang/Object
+.implements a/Shape
+.method public area ()D
+    dconst_0
+    dreturn
+.end method
+`,
+	"a/Main": `.version 52 0
+.class public super a/Main
+.super java/lang/Object
+.method public static main ([Ljava/lang/String;)V
+    new a/Circle
+    dup
+    invokespecial a/Circle <init> ()V
+    invokeinterface a/Shape area ()D
+    pop2
+    invokedynamic run ()Ljava/lang/Runnable; handle invokestatic java/lang/invoke/LambdaMetafactory metafactory (Ljava/lang/invoke/MethodHandles$Lookup;Ljava/lang/String;Ljava/lang/invoke/MethodType;Ljava/lang/invoke/MethodType;Ljava/lang/invoke/MethodHandle;Ljava/lang/invoke/MethodType;)Ljava/lang/invoke/CallSite; { methodtype ()V handle invokestatic a/Main lambda$main$0 ()V methodtype ()V }
+    pop
+    return
+.end method
+.method private static synthetic lambda$main$0 ()V
+    return
+.end method
+`,
+}
+
+func methodList(methods []Method) string {
+	return strings.Join(methodStrings(methods), " ")
+}
+
+func TestCHA(t *testing.T) {
+	g, err := Build(testutil.Classpath(t, sources), Options{Algorithm: CHA})
+	tools.AssertNoErr(t, err)
+	main := Method{"a/Main", "main", "([Ljava/lang/String;)V"}
+	tools.AssertEqual(t, "a/Main.main([Ljava/lang/String;)V", methodList(g.EntryPoints))
+	tools.AssertEqual(t, "a/Circle.<init>()V a/Circle.area()D a/Main.lambda$main$0()V a/Square.area()D",
+		methodList(g.Callees(main)))
+	tools.AssertEqual(t, "a/Main.main([Ljava/lang/String;)V", methodList(g.Callers(Method{"a/Square", "area", "()D"})))
+	tools.AssertEqual(t, 5, len(g.Reachable()))
+	tools.AssertEqual(t, 6, len(g.Methods()))
+
+	unresolved := g.Unresolved()
+	tools.AssertEqual(t, 1, len(unresolved))
+	tools.AssertEqual(t, "class java/lang/Object not found", unresolved[0].Unresolved)
+	tools.AssertEqual(t, "a/Circle.<init>()V", unresolved[0].Caller.String())
+}
+
+func TestRTA(t *testing.T) {
+	g, err := Build(testutil.Classpath(t, sources), Options{Algorithm: RTA})
+	tools.AssertNoErr(t, err)
+	main := Method{"a/Main", "main", "([Ljava/lang/String;)V"}
+	tools.AssertEqual(t, "a/Circle.<init>()V a/Circle.area()D a/Main.lambda$main$0()V", methodList(g.Callees(main)))
+	tools.AssertEqual(t, "a/Circle.<init>()V a/Circle.area()D a/Main.lambda$main$0()V a/Main.main([Ljava/lang/String;)V",
+		methodList(g.Methods()))
+	tools.AssertEqual(t, 0, len(g.Callers(Method{"a/Square", "area", "()D"})))
+
+	var dot bytes.Buffer
+	tools.AssertNoErr(t, g.WriteDOT(&dot))
+	tools.AssertEqual(t, true, strings.Contains(dot.String(), "  \"a/Main.main([Ljava/lang/String;)V\" [style=bold];\n"))
+	tools.AssertEqual(t, true, strings.Contains(dot.String(), "  \"a/Main.main([Ljava/lang/String;)V\" -> \"a/Circle.area()D\";\n"))
+	tools.AssertEqual(t, true, strings.Contains(dot.String(), "  \"a/Circle.<init>()V\" -> \"java/lang/Object.<init>()V\" [style=dashed];\n"))
+
+	content, err := json.Marshal(g)
+	tools.AssertNoErr(t, err)
+	var decoded graphJSON
+	tools.AssertNoErr(t, json.Unmarshal(content, &decoded))
+	tools.AssertEqual(t, "rta", decoded.Algorithm)
+	tools.AssertEqual(t, 4, len(decoded.CallSites))
+	tools.AssertEqual(t, "invokedynamic", decoded.CallSites[3].Instruction)
+	tools.AssertEqual(t, "a/Main.lambda$main$0()V", decoded.CallSites[3].Callees[0])
+}
+
+func TestBootstrapMethods(t *testing.T) {
+	g, err := Build(testutil.Classpath(t, map[string]string{"a/Concat": `.version 52 0
+.class public super a/Concat
+.super java/lang/Object
+.method public static main ([Ljava/lang/String;)V
+    aload_0
+    invokedynamic makeConcatWithConstants ([Ljava/lang/String;)Ljava/lang/String; handle invokestatic java/lang/invoke/StringConcatFactory makeConcatWithConstants (Ljava/lang/invoke/MethodHandles$Lookup;Ljava/lang/String;Ljava/lang/invoke/MethodType;Ljava/lang/String;[Ljava/lang/Object;)Ljava/lang/invoke/CallSite; { "args: \u0001" }
+    pop
+    invokedynamic run ()V handle invokestatic a/Boot bootstrap (Ljava/lang/invoke/MethodHandles$Lookup;Ljava/lang/String;Ljava/lang/invoke/MethodType;)Ljava/lang/invoke/CallSite; { }
+    return
+.end method
+`}), Options{Algorithm: CHA})
+	tools.AssertNoErr(t, err)
+	sites := g.CallSites(Method{"a/Concat", "main", mainDescriptor})
+	tools.AssertEqual(t, 2, len(sites))
+	tools.AssertEqual(t, "", sites[0].Unresolved)
+	tools.AssertEqual(t, 0, len(sites[0].Callees))
+	unresolved := g.Unresolved()
+	tools.AssertEqual(t, 1, len(unresolved))
+	tools.AssertEqual(t, "unsupported bootstrap method a/Boot.bootstrap", unresolved[0].Unresolved)
+}
+
+func TestParseMethod(t *testing.T) {
+	m, err := ParseMethod("a/b/C.m(Ljava/lang/String;)V")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, Method{"a/b/C", "m", "(Ljava/lang/String;)V"}, m)
+	_, err = ParseMethod("m()V")
+	tools.AssertEqual(t, true, err != nil)
+}
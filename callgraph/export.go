@@ -0,0 +1,85 @@
+package callgraph
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+// WriteDOT writes the graph in the Graphviz DOT language. The entry points are drawn in bold, and
+// the unresolved targets with dashed lines.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph callgraph {")
+	fmt.Fprintln(out, "  node [shape=box];")
+	entryPoints := make(map[Method]bool)
+	for _, entryPoint := range g.EntryPoints {
+		entryPoints[entryPoint] = true
+	}
+	methods := g.Methods()
+	for _, method := range methods {
+		if entryPoints[method] {
+			fmt.Fprintf(out, "  %s [style=bold];\n", strconv.Quote(method.String()))
+		} else {
+			fmt.Fprintf(out, "  %s;\n", strconv.Quote(method.String()))
+		}
+	}
+	for _, method := range methods {
+		for _, callee := range g.Callees(method) {
+			fmt.Fprintf(out, "  %s -> %s;\n", strconv.Quote(method.String()), strconv.Quote(callee.String()))
+		}
+		unresolved := make(map[Method]bool)
+		for _, site := range g.sites[method] {
+			if site.Unresolved != "" && !unresolved[site.Target] {
+				unresolved[site.Target] = true
+				fmt.Fprintf(out, "  %s [style=dashed];\n", strconv.Quote(site.Target.String()))
+				fmt.Fprintf(out, "  %s -> %s [style=dashed];\n", strconv.Quote(method.String()), strconv.Quote(site.Target.String()))
+			}
+		}
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+type graphJSON struct {
+	Algorithm   string     `json:"algorithm"`
+	EntryPoints []string   `json:"entry_points"`
+	Methods     []string   `json:"methods"`
+	CallSites   []siteJSON `json:"call_sites"`
+}
+
+type siteJSON struct {
+	Caller      string   `json:"caller"`
+	Index       int      `json:"index"`
+	Instruction string   `json:"instruction"`
+	Target      string   `json:"target"`
+	Callees     []string `json:"callees,omitempty"`
+	Unresolved  string   `json:"unresolved,omitempty"`
+}
+
+func methodStrings(methods []Method) []string {
+	strings := make([]string, len(methods))
+	for i, method := range methods {
+		strings[i] = method.String()
+	}
+	return strings
+}
+
+// MarshalJSON encodes the graph as a JSON object with its algorithm, entry points, methods and
+// call sites.
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	result := graphJSON{Algorithm: g.Algorithm.String(), EntryPoints: methodStrings(g.EntryPoints),
+		Methods: methodStrings(g.Methods()), CallSites: make([]siteJSON, 0)}
+	for _, method := range g.Methods() {
+		for _, site := range g.sites[method] {
+			result.CallSites = append(result.CallSites, siteJSON{Caller: method.String(), Index: site.Index,
+				Instruction: data.OPCODE_NAMES[site.OpCode], Target: site.Target.String(),
+				Callees: methodStrings(site.Callees), Unresolved: site.Unresolved})
+		}
+	}
+	return json.Marshal(result)
+}
@@ -0,0 +1,291 @@
+// Package cfg builds the control flow graph of the code of a method, made of basic blocks
+// connected by normal and exceptional edges, and computes its dominator trees.
+package cfg
+
+import (
+	"errors"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+// EdgeKind is the kind of an Edge.
+type EdgeKind int
+
+const (
+	// Normal is the kind of the fall through, jump, switch and return edges.
+	Normal EdgeKind = iota
+	// Exceptional is the kind of the edges to exception handlers, and of the edges from the blocks
+	// ending with an ATHROW instruction to the exit block.
+	Exceptional
+)
+
+func (k EdgeKind) String() string {
+	if k == Exceptional {
+		return "exceptional"
+	}
+	return "normal"
+}
+
+// Edge is a control flow edge between two blocks.
+type Edge struct {
+	From *Block
+	To   *Block
+	Kind EdgeKind
+	// CatchType is the internal name of the exceptions caught by the handler of an exceptional
+	// edge, or an empty string for all the exceptions.
+	CatchType string
+}
+
+// Block is a basic block: a sequence of instructions which is only entered by its first
+// instruction and only left by its last one, or by an exception.
+type Block struct {
+	// Index is the index of the block in Graph.Blocks.
+	Index int
+	// Start and End are the indices in MethodCode.Instructions of the first instruction of the
+	// block and of the instruction after its last one. The pseudo instructions before a real
+	// instruction belong to its block.
+	Start int
+	End   int
+	// Instructions are the instructions of the block, which are empty for the entry and exit
+	// blocks.
+	Instructions []class.Instruction
+	Successors   []*Edge
+	Predecessors []*Edge
+}
+
+// Graph is the control flow graph of a method. Its Entry block precedes the first instruction,
+// and its Exit block follows the return instructions and the uncaught exceptions.
+type Graph struct {
+	Method *class.Method
+	Entry  *Block
+	Exit   *Block
+	// Blocks are all the blocks of the graph, from the entry block to the exit block, the other
+	// ones being in the instruction order.
+	Blocks []*Block
+}
+
+// Build returns the control flow graph of a method, which must have code.
+func Build(method *class.Method) (*Graph, error) {
+	instructions := method.Code.Instructions
+	labels := make(map[*class.Label]int)
+	real := false
+	for i, instruction := range instructions {
+		if label, ok := instruction.(*class.Label); ok {
+			labels[label] = i
+		}
+		real = real || instruction.OpCode() >= 0
+	}
+	if !real {
+		return nil, errors.New("method " + method.Name + method.Descriptor + " has no code")
+	}
+	b := &builder{graph: &Graph{Method: method}, labels: labels}
+	if err := b.split(); err != nil {
+		return nil, err
+	}
+	b.link()
+	return b.graph, nil
+}
+
+type builder struct {
+	graph  *Graph
+	labels map[*class.Label]int
+	// blocks are the blocks of the instructions, by instruction index.
+	blocks []*Block
+}
+
+// target returns the index of the instruction designated by a label.
+func (b *builder) target(label *class.Label) (int, error) {
+	index, ok := b.labels[label]
+	if !ok {
+		return 0, errors.New("label not found in method " + b.graph.Method.Name + b.graph.Method.Descriptor)
+	}
+	return index, nil
+}
+
+// split splits the instructions into blocks, which start at the branch targets, at the bounds of
+// the exception ranges, at the exception handlers, and after the instructions which do not fall
+// through to the next one.
+func (b *builder) split() error {
+	code := &b.graph.Method.Code
+	instructions := code.Instructions
+	leaders := make([]bool, len(instructions)+1)
+	leaders[0] = true
+	mark := func(labels ...*class.Label) error {
+		for _, label := range labels {
+			index, err := b.target(label)
+			if err != nil {
+				return err
+			}
+			leaders[index] = true
+		}
+		return nil
+	}
+	for _, exception := range code.ExceptionTable {
+		if err := mark(exception.Start, exception.End, exception.Handler); err != nil {
+			return err
+		}
+	}
+	for i, instruction := range instructions {
+		switch insn := instruction.(type) {
+		case *class.JumpInstruction:
+			if err := mark(insn.Label); err != nil {
+				return err
+			}
+		case *class.TableSwitchInstruction:
+			if err := mark(append([]*class.Label{insn.Default}, insn.Labels...)...); err != nil {
+				return err
+			}
+		case *class.LookupSwitchInstruction:
+			if err := mark(append([]*class.Label{insn.Default}, insn.Labels...)...); err != nil {
+				return err
+			}
+		}
+		if isBranch(instruction) || !fallsThrough(instruction) {
+			leaders[i+1] = true
+		}
+	}
+
+	graph := b.graph
+	graph.Entry = graph.newBlock(0, 0)
+	b.blocks = make([]*Block, len(instructions))
+	start, real := 0, false
+	for i := range instructions {
+		// The pseudo instructions before a leader join the block of the next real instruction.
+		if leaders[i] && real {
+			graph.addBlock(b.blocks, start, i)
+			start, real = i, false
+		}
+		real = real || instructions[i].OpCode() >= 0
+	}
+	if real {
+		graph.addBlock(b.blocks, start, len(instructions))
+	} else {
+		// The trailing pseudo instructions join the last block.
+		last := graph.Blocks[len(graph.Blocks)-1]
+		graph.Blocks = graph.Blocks[:len(graph.Blocks)-1]
+		graph.addBlock(b.blocks, last.Start, len(instructions))
+	}
+	graph.Exit = graph.newBlock(len(instructions), len(instructions))
+	return nil
+}
+
+// newBlock adds a block to the graph.
+func (g *Graph) newBlock(start int, end int) *Block {
+	block := &Block{Index: len(g.Blocks), Start: start, End: end, Instructions: g.Method.Code.Instructions[start:end]}
+	g.Blocks = append(g.Blocks, block)
+	return block
+}
+
+// addBlock adds the block of a range of instructions, and records it for each instruction.
+func (g *Graph) addBlock(blocks []*Block, start int, end int) {
+	block := g.newBlock(start, end)
+	for i := start; i < end; i++ {
+		blocks[i] = block
+	}
+}
+
+// link adds the edges between the blocks.
+func (b *builder) link() {
+	graph := b.graph
+	code := &graph.Method.Code
+	addEdge(graph.Entry, graph.Blocks[1], Normal, "")
+	// The blocks following the JSR instructions, to which the RET instructions return.
+	var returns []*Block
+	for _, block := range graph.Blocks[1 : len(graph.Blocks)-1] {
+		if last := lastInstruction(block); last != nil && isJsr(last.OpCode()) && block.End < len(code.Instructions) {
+			returns = append(returns, b.blocks[block.End])
+		}
+	}
+	for _, block := range graph.Blocks[1 : len(graph.Blocks)-1] {
+		last := lastInstruction(block)
+		switch insn := last.(type) {
+		case *class.JumpInstruction:
+			addEdge(block, b.block(insn.Label), Normal, "")
+		case *class.TableSwitchInstruction:
+			addEdge(block, b.block(insn.Default), Normal, "")
+			for _, label := range insn.Labels {
+				addEdge(block, b.block(label), Normal, "")
+			}
+		case *class.LookupSwitchInstruction:
+			addEdge(block, b.block(insn.Default), Normal, "")
+			for _, label := range insn.Labels {
+				addEdge(block, b.block(label), Normal, "")
+			}
+		}
+		opCode := last.OpCode()
+		switch {
+		case opCode >= data.IRETURN && opCode <= data.RETURN:
+			addEdge(block, graph.Exit, Normal, "")
+		case opCode == data.ATHROW:
+			addEdge(block, graph.Exit, Exceptional, "")
+		case opCode == data.RET:
+			for _, target := range returns {
+				addEdge(block, target, Normal, "")
+			}
+		case fallsThrough(last) && block.End < len(code.Instructions):
+			addEdge(block, b.blocks[block.End], Normal, "")
+		}
+		first := block.Start
+		for first < block.End && code.Instructions[first].OpCode() < 0 {
+			first++
+		}
+		for _, exception := range code.ExceptionTable {
+			if b.labels[exception.Start] <= first && first < b.labels[exception.End] {
+				addEdge(block, b.block(exception.Handler), Exceptional, exception.CatchType)
+			}
+		}
+	}
+}
+
+func (b *builder) block(label *class.Label) *Block {
+	return b.blocks[b.labels[label]]
+}
+
+// addEdge adds an edge between two blocks, unless they are already connected by an identical one.
+func addEdge(from *Block, to *Block, kind EdgeKind, catchType string) {
+	for _, edge := range from.Successors {
+		if edge.To == to && edge.Kind == kind && edge.CatchType == catchType {
+			return
+		}
+	}
+	edge := &Edge{From: from, To: to, Kind: kind, CatchType: catchType}
+	from.Successors = append(from.Successors, edge)
+	to.Predecessors = append(to.Predecessors, edge)
+}
+
+// lastInstruction returns the last real instruction of a block.
+func lastInstruction(block *Block) class.Instruction {
+	for i := len(block.Instructions) - 1; i >= 0; i-- {
+		if block.Instructions[i].OpCode() >= 0 {
+			return block.Instructions[i]
+		}
+	}
+	return nil
+}
+
+func isJsr(opCode int) bool {
+	return opCode == data.JSR || opCode == data.JSR_W
+}
+
+// isBranch returns true for the jump and switch instructions.
+func isBranch(instruction class.Instruction) bool {
+	switch instruction.(type) {
+	case *class.JumpInstruction, *class.TableSwitchInstruction, *class.LookupSwitchInstruction:
+		return true
+	}
+	return false
+}
+
+// fallsThrough returns true if the execution can continue with the next instruction after an
+// instruction, JSR excepted.
+func fallsThrough(instruction class.Instruction) bool {
+	switch opCode := instruction.OpCode(); {
+	case opCode == data.GOTO, opCode == data.GOTO_W, isJsr(opCode), opCode == data.RET, opCode == data.ATHROW,
+		opCode == data.TABLESWITCH, opCode == data.LOOKUPSWITCH:
+		return false
+	case opCode >= data.IRETURN && opCode <= data.RETURN:
+		return false
+	}
+	return true
+}
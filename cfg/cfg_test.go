@@ -0,0 +1,70 @@
+package cfg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/jasm"
+	"github.com/tk103331/clazz/tools"
+)
+
+const source = `.version 52 0
+.class public super a/T
+.super java/lang/Object
+
+.method public static f (I)I
+    iload 0
+    ifeq L1
+    iconst_1
+    istore 0
+    goto L2
+L1:
+    iconst_2
+    istore 0
+L2:
+    iload 0
+    invokestatic a/T g (I)V
+L3:
+    iload 0
+    ireturn
+L4:
+    athrow
+    .catch java/lang/RuntimeException from L2 to L3 using L4
+.end method
+`
+
+func TestBuild(t *testing.T) {
+	c, err := jasm.Parse([]byte(source))
+	tools.AssertNoErr(t, err)
+	g, err := Build(&c.Methods[0])
+	tools.AssertNoErr(t, err)
+	// entry, iload/ifeq, then, else, try, return, handler, exit
+	tools.AssertEqual(t, 8, len(g.Blocks))
+	var edges []string
+	for _, block := range g.Blocks {
+		for _, edge := range block.Successors {
+			edges = append(edges, string(rune('0'+edge.From.Index))+"-"+string(rune('0'+edge.To.Index))+edge.CatchType)
+		}
+	}
+	tools.AssertEqual(t, "0-1 1-3 1-2 2-4 3-4 4-5 4-6java/lang/RuntimeException 5-7 6-7", strings.Join(edges, " "))
+
+	dominators := g.Dominators()
+	tools.AssertEqual(t, 1, dominators.Immediate(g.Blocks[4]).Index)
+	tools.AssertEqual(t, 4, dominators.Immediate(g.Blocks[6]).Index)
+	tools.AssertEqual(t, 3, len(dominators.Children(g.Blocks[1])))
+	tools.AssertEqual(t, true, dominators.Dominates(g.Blocks[1], g.Blocks[5]))
+	tools.AssertEqual(t, false, dominators.Dominates(g.Blocks[2], g.Blocks[4]))
+
+	postDominators := g.PostDominators()
+	tools.AssertEqual(t, 4, postDominators.Immediate(g.Blocks[1]).Index)
+	tools.AssertEqual(t, 7, postDominators.Immediate(g.Blocks[4]).Index)
+	tools.AssertEqual(t, true, postDominators.Dominates(g.Exit, g.Entry))
+
+	var dot bytes.Buffer
+	tools.AssertNoErr(t, g.WriteDOT(&dot))
+	text := dot.String()
+	tools.AssertEqual(t, true, strings.HasPrefix(text, "digraph \"f(I)I\" {\n"))
+	tools.AssertEqual(t, true, strings.Contains(text, "  B2 [label=\"B2\\lICONST_1\\lISTORE 0\\lGOTO L1\\l\"];\n"))
+	tools.AssertEqual(t, true, strings.Contains(text, "  B4 -> B6 [style=dashed, label=\"java/lang/RuntimeException\"];\n"))
+}
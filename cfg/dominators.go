@@ -0,0 +1,121 @@
+package cfg
+
+// DominatorTree is the dominator or post-dominator tree of a Graph. A block A dominates a block B
+// if all the paths from the entry block to B go through A, and A post-dominates B if all the
+// paths from B to the exit block go through A.
+type DominatorTree struct {
+	graph *Graph
+	root  *Block
+	// idoms are the immediate dominators of the blocks, by block index.
+	idoms []*Block
+}
+
+// Root returns the root of the tree: the entry block for the dominators, or the exit block for
+// the post-dominators.
+func (t *DominatorTree) Root() *Block {
+	return t.root
+}
+
+// Immediate returns the immediate dominator of a block, or nil for the root of the tree and for
+// the blocks which are not connected to it.
+func (t *DominatorTree) Immediate(block *Block) *Block {
+	return t.idoms[block.Index]
+}
+
+// Children returns the blocks whose immediate dominator is a block.
+func (t *DominatorTree) Children(block *Block) []*Block {
+	var children []*Block
+	for i, idom := range t.idoms {
+		if idom == block {
+			children = append(children, t.graph.Blocks[i])
+		}
+	}
+	return children
+}
+
+// Dominates returns true if a block dominates another one. A block dominates itself.
+func (t *DominatorTree) Dominates(a *Block, b *Block) bool {
+	for b != nil {
+		if a == b {
+			return true
+		}
+		b = t.idoms[b.Index]
+	}
+	return false
+}
+
+// Dominators returns the dominator tree of the graph.
+func (g *Graph) Dominators() *DominatorTree {
+	return g.dominators(g.Entry, func(b *Block) []*Edge { return b.Successors }, func(e *Edge) *Block { return e.To },
+		func(b *Block) []*Edge { return b.Predecessors }, func(e *Edge) *Block { return e.From })
+}
+
+// PostDominators returns the post-dominator tree of the graph. The blocks from which the exit
+// block cannot be reached, such as infinite loops, have no post-dominator.
+func (g *Graph) PostDominators() *DominatorTree {
+	return g.dominators(g.Exit, func(b *Block) []*Edge { return b.Predecessors }, func(e *Edge) *Block { return e.From },
+		func(b *Block) []*Edge { return b.Successors }, func(e *Edge) *Block { return e.To })
+}
+
+// dominators computes a dominator tree with the algorithm of Cooper, Harvey and Kennedy, in the
+// direction given by the successor and predecessor functions.
+func (g *Graph) dominators(root *Block, successors func(*Block) []*Edge, successor func(*Edge) *Block,
+	predecessors func(*Block) []*Edge, predecessor func(*Edge) *Block) *DominatorTree {
+	// Number the blocks in postorder.
+	order := make([]int, len(g.Blocks))
+	for i := range order {
+		order[i] = -1
+	}
+	var postorder []*Block
+	visited := make([]bool, len(g.Blocks))
+	var visit func(*Block)
+	visit = func(block *Block) {
+		visited[block.Index] = true
+		for _, edge := range successors(block) {
+			if next := successor(edge); !visited[next.Index] {
+				visit(next)
+			}
+		}
+		order[block.Index] = len(postorder)
+		postorder = append(postorder, block)
+	}
+	visit(root)
+
+	idoms := make([]*Block, len(g.Blocks))
+	idoms[root.Index] = root
+	intersect := func(a *Block, b *Block) *Block {
+		for a != b {
+			for order[a.Index] < order[b.Index] {
+				a = idoms[a.Index]
+			}
+			for order[b.Index] < order[a.Index] {
+				b = idoms[b.Index]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(postorder) - 2; i >= 0; i-- {
+			block := postorder[i]
+			var idom *Block
+			for _, edge := range predecessors(block) {
+				previous := predecessor(edge)
+				if idoms[previous.Index] == nil {
+					continue
+				}
+				if idom == nil {
+					idom = previous
+				} else {
+					idom = intersect(previous, idom)
+				}
+			}
+			if idoms[block.Index] != idom {
+				idoms[block.Index] = idom
+				changed = true
+			}
+		}
+	}
+	idoms[root.Index] = nil
+	return &DominatorTree{graph: g, root: root, idoms: idoms}
+}
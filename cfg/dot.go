@@ -0,0 +1,86 @@
+package cfg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+)
+
+// WriteDOT writes the graph in the Graphviz DOT language. Each block is a node labeled with its
+// instructions, in the format of the class.Textifier. The exceptional edges are dashed, and
+// labeled with the caught exception type.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	method := g.Method
+	fmt.Fprintf(out, "digraph %s {\n", dotString(method.Name+method.Descriptor))
+	fmt.Fprintln(out, "  node [shape=box, fontname=\"monospace\"];")
+	texts := g.blockTexts()
+	for _, block := range g.Blocks {
+		switch block {
+		case g.Entry:
+			fmt.Fprintf(out, "  B%d [label=\"entry\", shape=ellipse];\n", block.Index)
+		case g.Exit:
+			fmt.Fprintf(out, "  B%d [label=\"exit\", shape=ellipse];\n", block.Index)
+		default:
+			var label strings.Builder
+			fmt.Fprintf(&label, "B%d\\l", block.Index)
+			for _, line := range texts[block.Index] {
+				label.WriteString(dotEscape(line))
+				label.WriteString("\\l")
+			}
+			fmt.Fprintf(out, "  B%d [label=\"%s\"];\n", block.Index, label.String())
+		}
+	}
+	for _, block := range g.Blocks {
+		for _, edge := range block.Successors {
+			fmt.Fprintf(out, "  B%d -> B%d", edge.From.Index, edge.To.Index)
+			if edge.Kind == Exceptional {
+				switch {
+				case edge.To == g.Exit:
+					fmt.Fprint(out, " [style=dashed]")
+				case edge.CatchType == "":
+					fmt.Fprint(out, " [style=dashed, label=\"any\"]")
+				default:
+					fmt.Fprintf(out, " [style=dashed, label=%s]", dotString(edge.CatchType))
+				}
+			}
+			fmt.Fprintln(out, ";")
+		}
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// blockTexts returns the text lines of the instructions of the blocks, by block index. A single
+// Textifier prints all the blocks, so that the labels have the same names in all of them.
+func (g *Graph) blockTexts() [][]string {
+	textifier := class.NewTextifier()
+	visitor := textifier.VisitMethod(g.Method.AccessFlags, g.Method.Name, g.Method.Descriptor, "", nil)
+	texts := make([][]string, len(g.Blocks))
+	for _, block := range g.Blocks {
+		start := len(textifier.String())
+		for _, instruction := range block.Instructions {
+			instruction.Accept(visitor)
+		}
+		text := strings.TrimRight(textifier.String()[start:], "\n")
+		if text == "" {
+			continue
+		}
+		for _, line := range strings.Split(text, "\n") {
+			texts[block.Index] = append(texts[block.Index], strings.TrimSpace(line))
+		}
+	}
+	return texts
+}
+
+// dotString returns a quoted DOT string.
+func dotString(s string) string {
+	return "\"" + dotEscape(s) + "\""
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(s)
+}
@@ -0,0 +1,275 @@
+package class
+
+import "github.com/tk103331/clazz/class/data"
+
+// Accept makes the given visitor visit this class.
+func (c *Class) Accept(visitor Visitor) {
+	if visitor == nil {
+		return
+	}
+	visitor.Visit(c.Version, c.AccessFlags, c.ThisClass, c.Signature, c.SuperClass, c.Interfaces)
+	if len(c.SourceFile) > 0 || len(c.SourceDebugExtension) > 0 {
+		visitor.VisitSource(c.SourceFile, c.SourceDebugExtension)
+	}
+	if len(c.Module.Name) > 0 {
+		acceptModule(visitor.VisitModule(c.Module.Name, c.Module.AccessFlags, c.Module.Version), c.Module)
+	}
+	if len(c.NestHost) > 0 {
+		visitor.VisitNestHost(c.NestHost)
+	}
+	if len(c.OuterClass.ClassName) > 0 {
+		visitor.VisitOuterClass(c.OuterClass.ClassName, c.OuterClass.MethodName, c.OuterClass.Descriptor)
+	}
+	for _, annotation := range c.RuntimeVisibleAnnotations {
+		acceptAnnotation(visitor.VisitAnnotation(annotation.Descriptor, true), annotation)
+	}
+	for _, annotation := range c.RuntimeInvisibleAnnotations {
+		acceptAnnotation(visitor.VisitAnnotation(annotation.Descriptor, false), annotation)
+	}
+	for _, annotation := range c.RuntimeVisibleTypeAnnotations {
+		acceptAnnotation(visitor.VisitTypeAnnotation(annotation.TypeRef, annotation.TypePath, annotation.Descriptor, true), annotation.Annotation)
+	}
+	for _, annotation := range c.RuntimeInvisibleTypeAnnotations {
+		acceptAnnotation(visitor.VisitTypeAnnotation(annotation.TypeRef, annotation.TypePath, annotation.Descriptor, false), annotation.Annotation)
+	}
+	if c.Deprecated {
+		visitor.VisitAttribute(Attribute{Name: data.DEPRECATED})
+	}
+	for _, attr := range c.Attributes {
+		visitor.VisitAttribute(attr)
+	}
+	for _, member := range c.NestMembers {
+		visitor.VisitNestMember(member)
+	}
+	for _, subclass := range c.PermittedSubclasses {
+		visitor.VisitPermittedSubclass(subclass)
+	}
+	for _, cls := range c.InnerClasses {
+		visitor.VisitInnerClass(cls.Name, cls.OuterName, cls.InnerName, cls.AccessFlags)
+	}
+	for _, component := range c.RecordComponents {
+		acceptRecordComponent(visitor.VisitRecordComponent(component.Name, component.Descriptor, component.Signature), component)
+	}
+	for _, field := range c.Fields {
+		acceptField(visitor.VisitField(field.AccessFlags, field.Name, field.Descriptor, field.Signature, field.ConstantValue), field)
+	}
+	for _, method := range c.Methods {
+		acceptMethod(visitor.VisitMethod(method.AccessFlags, method.Name, method.Descriptor, method.Signature, method.Exceptions), method)
+	}
+	visitor.VisitEnd()
+}
+
+func acceptModule(visitor ModuleVisitor, module Module) {
+	if visitor == nil {
+		return
+	}
+	if len(module.MainClass) > 0 {
+		visitor.VisitMainClass(module.MainClass)
+	}
+	for _, pkg := range module.Packages {
+		visitor.VisitPackage(pkg)
+	}
+	for _, r := range module.Requires {
+		visitor.VisitRequire(r.Name, r.AccessFlags, r.Version)
+	}
+	for _, e := range module.Exports {
+		visitor.VisitExport(e.Name, e.AccessFlags, e.Modules)
+	}
+	for _, o := range module.Opens {
+		visitor.VisitOpen(o.Name, o.AccessFlags, o.Modules)
+	}
+	for _, u := range module.Uses {
+		visitor.VisitUse(u)
+	}
+	for _, p := range module.Provides {
+		visitor.VisitProvide(p.Service, p.Provides)
+	}
+	visitor.VisitEnd()
+}
+
+func acceptAnnotation(visitor AnnotationVisitor, annotation Annotation) {
+	if visitor == nil {
+		return
+	}
+	for _, pair := range annotation.ElementPairs {
+		acceptAnnotationValue(visitor, pair.Name, pair.Value)
+	}
+	visitor.VisitEnd()
+}
+
+func acceptAnnotationValue(visitor AnnotationVisitor, name string, value ElementValue) {
+	if visitor == nil || value == nil {
+		return
+	}
+	switch value.Tag() {
+	case data.ELEMENT_TAG_BOOLEAN:
+		visitor.Visit(name, value.(ElementBooleanValue).Value)
+	case data.ELEMENT_TAG_BYTE:
+		visitor.Visit(name, value.(ElementByteValue).Value)
+	case data.ELEMENT_TAG_CHAR:
+		visitor.Visit(name, value.(ElementCharValue).Value)
+	case data.ELEMENT_TAG_SHORT:
+		visitor.Visit(name, value.(ElementShortValue).Value)
+	case data.ELEMENT_TAG_INTEGER:
+		visitor.Visit(name, value.(ElementIntegerValue).Value)
+	case data.ELEMENT_TAG_LONG:
+		visitor.Visit(name, value.(ElementLongValue).Value)
+	case data.ELEMENT_TAG_FLOAT:
+		visitor.Visit(name, value.(ElementFloatValue).Value)
+	case data.ELEMENT_TAG_DOUBLE:
+		visitor.Visit(name, value.(ElementDoubleValue).Value)
+	case data.ELEMENT_TAG_STRING:
+		visitor.Visit(name, value.(ElementStringValue).Value)
+	case data.ELEMENT_TAG_CLASS:
+		visitor.Visit(name, value.(ElementClassValue).Value)
+	case data.ELEMENT_TAG_ANNOTATION:
+		annotation := value.(ElementAnnotationValue).Value
+		acceptAnnotation(visitor.VisitAnnotation(name, annotation.Descriptor), annotation)
+	case data.ELEMENT_TAG_ENUM:
+		enumValue := value.(ElementEnumValue)
+		visitor.VisitEnum(name, enumValue.TypeName, enumValue.ConstName)
+	case data.ELEMENT_TAG_ARRAY:
+		annotationVisitor := visitor.VisitArray(name)
+		if annotationVisitor == nil {
+			return
+		}
+		for _, elemValue := range value.(ElementArrayValue).Values {
+			acceptAnnotationValue(annotationVisitor, "", elemValue)
+		}
+		annotationVisitor.VisitEnd()
+	}
+}
+
+func acceptField(visitor FieldVisitor, field Field) {
+	if visitor == nil {
+		return
+	}
+	for _, annotation := range field.RuntimeVisibleAnnotations {
+		acceptAnnotation(visitor.VisitAnnotation(annotation.Descriptor, true), annotation)
+	}
+	for _, annotation := range field.RuntimeInvisibleAnnotations {
+		acceptAnnotation(visitor.VisitAnnotation(annotation.Descriptor, false), annotation)
+	}
+	for _, annotation := range field.RuntimeVisibleTypeAnnotations {
+		acceptAnnotation(visitor.VisitTypeAnnotation(annotation.TypeRef, annotation.TypePath, annotation.Descriptor, true), annotation.Annotation)
+	}
+	for _, annotation := range field.RuntimeInvisibleTypeAnnotations {
+		acceptAnnotation(visitor.VisitTypeAnnotation(annotation.TypeRef, annotation.TypePath, annotation.Descriptor, false), annotation.Annotation)
+	}
+	if field.Deprecated {
+		visitor.VisitAttribute(Attribute{Name: data.DEPRECATED})
+	}
+	for _, attribute := range field.Attributes {
+		visitor.VisitAttribute(attribute)
+	}
+	visitor.VisitEnd()
+}
+
+func acceptRecordComponent(visitor RecordComponentVisitor, component RecordComponent) {
+	if visitor == nil {
+		return
+	}
+	for _, annotation := range component.RuntimeVisibleAnnotations {
+		acceptAnnotation(visitor.VisitAnnotation(annotation.Descriptor, true), annotation)
+	}
+	for _, annotation := range component.RuntimeInvisibleAnnotations {
+		acceptAnnotation(visitor.VisitAnnotation(annotation.Descriptor, false), annotation)
+	}
+	for _, annotation := range component.RuntimeVisibleTypeAnnotations {
+		acceptAnnotation(visitor.VisitTypeAnnotation(annotation.TypeRef, annotation.TypePath, annotation.Descriptor, true), annotation.Annotation)
+	}
+	for _, annotation := range component.RuntimeInvisibleTypeAnnotations {
+		acceptAnnotation(visitor.VisitTypeAnnotation(annotation.TypeRef, annotation.TypePath, annotation.Descriptor, false), annotation.Annotation)
+	}
+	for _, attribute := range component.Attributes {
+		visitor.VisitAttribute(attribute)
+	}
+	visitor.VisitEnd()
+}
+
+func acceptMethod(visitor MethodVisitor, method Method) {
+	if visitor == nil {
+		return
+	}
+	for _, parameter := range method.Parameters {
+		visitor.VisitParameter(parameter.ParameterName, parameter.AccessFlags)
+	}
+	if method.AnnotationDefault != nil {
+		annotationVisitor := visitor.VisitAnnotationDefault()
+		if annotationVisitor != nil {
+			acceptAnnotationValue(annotationVisitor, "", method.AnnotationDefault)
+			annotationVisitor.VisitEnd()
+		}
+	}
+	for _, annotation := range method.RuntimeVisibleAnnotations {
+		acceptAnnotation(visitor.VisitAnnotation(annotation.Descriptor, true), annotation)
+	}
+	for _, annotation := range method.RuntimeInvisibleAnnotations {
+		acceptAnnotation(visitor.VisitAnnotation(annotation.Descriptor, false), annotation)
+	}
+	for _, annotation := range method.RuntimeVisibleTypeAnnotations {
+		acceptAnnotation(visitor.VisitTypeAnnotation(annotation.TypeRef, annotation.TypePath, annotation.Descriptor, true), annotation.Annotation)
+	}
+	for _, annotation := range method.RuntimeInvisibleTypeAnnotations {
+		acceptAnnotation(visitor.VisitTypeAnnotation(annotation.TypeRef, annotation.TypePath, annotation.Descriptor, false), annotation.Annotation)
+	}
+	acceptParameterAnnotations(visitor, method.RuntimeVisibleParameterAnnotations, true)
+	acceptParameterAnnotations(visitor, method.RuntimeInvisibleParameterAnnotations, false)
+	if method.Deprecated {
+		visitor.VisitAttribute(Attribute{Name: data.DEPRECATED})
+	}
+	for _, attribute := range method.Attributes {
+		visitor.VisitAttribute(attribute)
+	}
+	code := method.Code
+	if len(code.Instructions) > 0 {
+		visitor.VisitCode()
+		annotations := append(append([]CodeTypeAnnotation(nil), code.RuntimeVisibleTypeAnnotations...), code.RuntimeInvisibleTypeAnnotations...)
+		instructionAnnotations := make(map[Instruction][]CodeTypeAnnotation)
+		for _, annotation := range annotations {
+			if annotation.Instruction != nil {
+				instructionAnnotations[annotation.Instruction] = append(instructionAnnotations[annotation.Instruction], annotation)
+			}
+		}
+		for _, exception := range code.ExceptionTable {
+			visitor.VisitTryCatchBlock(exception.Start, exception.End, exception.Handler, exception.CatchType)
+		}
+		for _, annotation := range annotations {
+			if TypeReferenceSort(annotation.TypeRef) == data.TYPE_REF_EXCEPTION_PARAMETER {
+				acceptAnnotation(visitor.VisitTryCatchAnnotation(annotation.TypeRef, annotation.TypePath, annotation.Descriptor, annotation.Visible), annotation.Annotation)
+			}
+		}
+		for _, instruction := range code.Instructions {
+			instruction.Accept(visitor)
+			for _, annotation := range instructionAnnotations[instruction] {
+				acceptAnnotation(visitor.VisitInstructionAnnotation(annotation.TypeRef, annotation.TypePath, annotation.Descriptor, annotation.Visible), annotation.Annotation)
+			}
+		}
+		for _, variable := range code.LocalVariables {
+			visitor.VisitLocalVariable(variable.Name, variable.Descriptor, variable.Signature, variable.Start, variable.End, variable.Index)
+		}
+		for _, annotation := range annotations {
+			if sort := TypeReferenceSort(annotation.TypeRef); sort == data.TYPE_REF_LOCAL_VARIABLE || sort == data.TYPE_REF_RESOURCE_VARIABLE {
+				acceptAnnotation(visitor.VisitLocalVariableAnnotation(annotation.TypeRef, annotation.TypePath, annotation.Start, annotation.End,
+					annotation.Index, annotation.Descriptor, annotation.Visible), annotation.Annotation)
+			}
+		}
+		for _, attribute := range code.Attributes {
+			visitor.VisitAttribute(attribute)
+		}
+		visitor.VisitMaxs(int(code.MaxStack), int(code.MaxLocal))
+	}
+	visitor.VisitEnd()
+}
+
+func acceptParameterAnnotations(visitor MethodVisitor, parameters []ParameterAnnotation, visible bool) {
+	if len(parameters) == 0 {
+		return
+	}
+	visitor.VisitAnnotableParameterCount(len(parameters), visible)
+	for index, parameter := range parameters {
+		for _, annotation := range parameter.Annotations {
+			acceptAnnotation(visitor.VisitParameterAnnotation(index, annotation.Descriptor, visible), annotation)
+		}
+	}
+}
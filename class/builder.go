@@ -0,0 +1,519 @@
+package class
+
+import "github.com/tk103331/clazz/class/data"
+
+// Builder is a Visitor that builds the Class model of the visited class.
+// The Deprecated attribute visited with VisitAttribute sets the Deprecated flag of the class,
+// field or method, and the attributes visited after VisitCode are the non standard attributes
+// of the Code attribute.
+type Builder struct {
+	class *Class
+}
+
+func NewBuilder() *Builder {
+	return &Builder{class: &Class{}}
+}
+
+// Class returns the class built by this visitor.
+func (b *Builder) Class() *Class {
+	return b.class
+}
+
+func (b *Builder) Visit(version uint32, access uint16, name string, signature string, superName string, interfaces []string) {
+	b.class.Version = version
+	b.class.AccessFlags = access
+	b.class.ThisClass = name
+	b.class.Signature = signature
+	b.class.SuperClass = superName
+	b.class.Interfaces = interfaces
+	// Only records can extend java/lang/Record, and a record without components is only
+	// distinguished from a class by its empty Record attribute.
+	if superName == "java/lang/Record" {
+		b.class.RecordComponents = make([]RecordComponent, 0)
+	}
+}
+
+func (b *Builder) VisitSource(source string, debug string) {
+	b.class.SourceFile = source
+	b.class.SourceDebugExtension = debug
+}
+
+func (b *Builder) VisitModule(name string, access uint16, version string) ModuleVisitor {
+	b.class.Module = Module{Name: name, AccessFlags: access, Version: version}
+	return &moduleBuilder{module: &b.class.Module}
+}
+
+func (b *Builder) VisitNestHost(nestHost string) {
+	b.class.NestHost = nestHost
+}
+
+func (b *Builder) VisitOuterClass(owner string, name string, descriptor string) {
+	b.class.OuterClass = OuterClass{ClassName: owner, MethodName: name, Descriptor: descriptor}
+}
+
+func (b *Builder) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	if visible {
+		return newAnnotationBuilder(&b.class.RuntimeVisibleAnnotations, descriptor, visible)
+	}
+	return newAnnotationBuilder(&b.class.RuntimeInvisibleAnnotations, descriptor, visible)
+}
+
+func (b *Builder) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	if visible {
+		return newTypeAnnotationBuilder(&b.class.RuntimeVisibleTypeAnnotations, typeRef, typePath, descriptor, visible)
+	}
+	return newTypeAnnotationBuilder(&b.class.RuntimeInvisibleTypeAnnotations, typeRef, typePath, descriptor, visible)
+}
+
+func (b *Builder) VisitAttribute(attribute Attribute) {
+	if attribute.Name == data.DEPRECATED {
+		b.class.Deprecated = true
+		return
+	}
+	b.class.Attributes = append(b.class.Attributes, attribute)
+}
+
+func (b *Builder) VisitNestMember(nestMember string) {
+	b.class.NestMembers = append(b.class.NestMembers, nestMember)
+}
+
+func (b *Builder) VisitPermittedSubclass(permittedSubclass string) {
+	b.class.PermittedSubclasses = append(b.class.PermittedSubclasses, permittedSubclass)
+}
+
+func (b *Builder) VisitInnerClass(name string, outerName string, innerName string, access uint16) {
+	b.class.InnerClasses = append(b.class.InnerClasses, InnerClass{Name: name, OuterName: outerName, InnerName: innerName, AccessFlags: access})
+}
+
+func (b *Builder) VisitRecordComponent(name string, descriptor string, signature string) RecordComponentVisitor {
+	b.class.RecordComponents = append(b.class.RecordComponents, RecordComponent{Name: name, Descriptor: descriptor, Signature: signature})
+	return &recordComponentBuilder{class: b.class, index: len(b.class.RecordComponents) - 1}
+}
+
+func (b *Builder) VisitField(access uint16, name string, descriptor string, signature string, value interface{}) FieldVisitor {
+	b.class.Fields = append(b.class.Fields, Field{AccessFlags: access, Name: name, Descriptor: descriptor, Signature: signature, ConstantValue: value})
+	return &fieldBuilder{class: b.class, index: len(b.class.Fields) - 1}
+}
+
+func (b *Builder) VisitMethod(access uint16, name string, descriptor string, signature string, exceptions []string) MethodVisitor {
+	b.class.Methods = append(b.class.Methods, Method{AccessFlags: access, Name: name, Descriptor: descriptor, Signature: signature, Exceptions: exceptions})
+	return &methodBuilder{class: b.class, index: len(b.class.Methods) - 1}
+}
+
+func (b *Builder) VisitEnd() {
+}
+
+type moduleBuilder struct {
+	module *Module
+}
+
+func (m *moduleBuilder) VisitMainClass(mainClass string) {
+	m.module.MainClass = mainClass
+}
+
+func (m *moduleBuilder) VisitPackage(packageName string) {
+	m.module.Packages = append(m.module.Packages, packageName)
+}
+
+func (m *moduleBuilder) VisitRequire(moduleName string, access uint16, version string) {
+	m.module.Requires = append(m.module.Requires, ModuleRequire{Name: moduleName, AccessFlags: access, Version: version})
+}
+
+func (m *moduleBuilder) VisitExport(packageName string, access uint16, modules []string) {
+	m.module.Exports = append(m.module.Exports, ModuleExport{Name: packageName, AccessFlags: access, Modules: modules})
+}
+
+func (m *moduleBuilder) VisitOpen(packageName string, access uint16, modules []string) {
+	m.module.Opens = append(m.module.Opens, ModuleOpen{Name: packageName, AccessFlags: access, Modules: modules})
+}
+
+func (m *moduleBuilder) VisitUse(service string) {
+	m.module.Uses = append(m.module.Uses, service)
+}
+
+func (m *moduleBuilder) VisitProvide(service string, providers []string) {
+	m.module.Provides = append(m.module.Provides, ModuleProvide{Service: service, Provides: providers})
+}
+
+func (m *moduleBuilder) VisitEnd() {
+}
+
+// annotationBuilder builds an annotation, an array of element values, or the default value of
+// an annotation method. The value is stored with the store function when the visit ends.
+type annotationBuilder struct {
+	annotation Annotation
+	values     []ElementValue
+	store      func(b *annotationBuilder)
+}
+
+func newAnnotationBuilder(annotations *[]Annotation, descriptor string, visible bool) *annotationBuilder {
+	return &annotationBuilder{
+		annotation: Annotation{Descriptor: descriptor, Visible: visible},
+		store: func(b *annotationBuilder) {
+			*annotations = append(*annotations, b.annotation)
+		},
+	}
+}
+
+func newTypeAnnotationBuilder(annotations *[]TypeAnnotation, typeRef int, typePath TypePath, descriptor string, visible bool) *annotationBuilder {
+	return &annotationBuilder{
+		annotation: Annotation{Descriptor: descriptor, Visible: visible},
+		store: func(b *annotationBuilder) {
+			*annotations = append(*annotations, TypeAnnotation{TypeRef: typeRef, TypePath: typePath, Annotation: b.annotation})
+		},
+	}
+}
+
+func (a *annotationBuilder) add(name string, value ElementValue) {
+	a.annotation.ElementPairs = append(a.annotation.ElementPairs, ElementPair{Name: name, Value: value})
+	a.values = append(a.values, value)
+}
+
+func (a *annotationBuilder) Visit(name string, value interface{}) {
+	a.add(name, NewElementValue(value))
+}
+
+func (a *annotationBuilder) VisitEnum(name string, descriptor string, value string) {
+	a.add(name, ElementEnumValue{TypeName: descriptor, ConstName: value})
+}
+
+func (a *annotationBuilder) VisitAnnotation(name string, descriptor string) AnnotationVisitor {
+	return &annotationBuilder{
+		annotation: Annotation{Descriptor: descriptor, Visible: a.annotation.Visible},
+		store: func(b *annotationBuilder) {
+			a.add(name, ElementAnnotationValue{Value: b.annotation})
+		},
+	}
+}
+
+func (a *annotationBuilder) VisitArray(name string) AnnotationVisitor {
+	return &annotationBuilder{
+		annotation: Annotation{Visible: a.annotation.Visible},
+		store: func(b *annotationBuilder) {
+			values := b.values
+			if values == nil {
+				values = make([]ElementValue, 0)
+			}
+			a.add(name, ElementArrayValue{Length: uint16(len(values)), Values: values})
+		},
+	}
+}
+
+func (a *annotationBuilder) VisitEnd() {
+	a.store(a)
+}
+
+// NewElementValue returns the ElementValue of a primitive, string or Type annotation value.
+// It returns nil for other values.
+func NewElementValue(value interface{}) ElementValue {
+	switch v := value.(type) {
+	case bool:
+		return ElementBooleanValue{Value: v}
+	case int8:
+		return ElementByteValue{Value: v}
+	case uint16:
+		return ElementCharValue{Value: v}
+	case int16:
+		return ElementShortValue{Value: v}
+	case int32:
+		return ElementIntegerValue{Value: v}
+	case int64:
+		return ElementLongValue{Value: v}
+	case float32:
+		return ElementFloatValue{Value: v}
+	case float64:
+		return ElementDoubleValue{Value: v}
+	case string:
+		return ElementStringValue{Value: v}
+	case Type:
+		return ElementClassValue{Value: v}
+	default:
+		return nil
+	}
+}
+
+type fieldBuilder struct {
+	class *Class
+	index int
+}
+
+func (f *fieldBuilder) field() *Field {
+	return &f.class.Fields[f.index]
+}
+
+func (f *fieldBuilder) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	if visible {
+		return newAnnotationBuilder(&f.field().RuntimeVisibleAnnotations, descriptor, visible)
+	}
+	return newAnnotationBuilder(&f.field().RuntimeInvisibleAnnotations, descriptor, visible)
+}
+
+func (f *fieldBuilder) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	if visible {
+		return newTypeAnnotationBuilder(&f.field().RuntimeVisibleTypeAnnotations, typeRef, typePath, descriptor, visible)
+	}
+	return newTypeAnnotationBuilder(&f.field().RuntimeInvisibleTypeAnnotations, typeRef, typePath, descriptor, visible)
+}
+
+func (f *fieldBuilder) VisitAttribute(attribute Attribute) {
+	if attribute.Name == data.DEPRECATED {
+		f.field().Deprecated = true
+		return
+	}
+	f.field().Attributes = append(f.field().Attributes, attribute)
+}
+
+func (f *fieldBuilder) VisitEnd() {
+}
+
+type recordComponentBuilder struct {
+	class *Class
+	index int
+}
+
+func (r *recordComponentBuilder) component() *RecordComponent {
+	return &r.class.RecordComponents[r.index]
+}
+
+func (r *recordComponentBuilder) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	if visible {
+		return newAnnotationBuilder(&r.component().RuntimeVisibleAnnotations, descriptor, visible)
+	}
+	return newAnnotationBuilder(&r.component().RuntimeInvisibleAnnotations, descriptor, visible)
+}
+
+func (r *recordComponentBuilder) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	if visible {
+		return newTypeAnnotationBuilder(&r.component().RuntimeVisibleTypeAnnotations, typeRef, typePath, descriptor, visible)
+	}
+	return newTypeAnnotationBuilder(&r.component().RuntimeInvisibleTypeAnnotations, typeRef, typePath, descriptor, visible)
+}
+
+func (r *recordComponentBuilder) VisitAttribute(attribute Attribute) {
+	r.component().Attributes = append(r.component().Attributes, attribute)
+}
+
+func (r *recordComponentBuilder) VisitEnd() {
+}
+
+type methodBuilder struct {
+	class  *Class
+	index  int
+	inCode bool
+}
+
+func (m *methodBuilder) method() *Method {
+	return &m.class.Methods[m.index]
+}
+
+func (m *methodBuilder) code() *MethodCode {
+	return &m.class.Methods[m.index].Code
+}
+
+func (m *methodBuilder) add(instruction Instruction) {
+	m.code().Instructions = append(m.code().Instructions, instruction)
+}
+
+func (m *methodBuilder) VisitParameter(name string, access uint16) {
+	m.method().Parameters = append(m.method().Parameters, MethodParameter{ParameterName: name, AccessFlags: access})
+}
+
+func (m *methodBuilder) VisitAnnotationDefault() AnnotationVisitor {
+	return &annotationBuilder{
+		store: func(b *annotationBuilder) {
+			if len(b.values) > 0 {
+				m.method().AnnotationDefault = b.values[0]
+			}
+		},
+	}
+}
+
+func (m *methodBuilder) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	if visible {
+		return newAnnotationBuilder(&m.method().RuntimeVisibleAnnotations, descriptor, visible)
+	}
+	return newAnnotationBuilder(&m.method().RuntimeInvisibleAnnotations, descriptor, visible)
+}
+
+func (m *methodBuilder) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	if visible {
+		return newTypeAnnotationBuilder(&m.method().RuntimeVisibleTypeAnnotations, typeRef, typePath, descriptor, visible)
+	}
+	return newTypeAnnotationBuilder(&m.method().RuntimeInvisibleTypeAnnotations, typeRef, typePath, descriptor, visible)
+}
+
+// codeTypeAnnotation returns a visitor that builds a type annotation of the code, completed
+// with the annotated instruction or variable ranges of the given annotation.
+func (m *methodBuilder) codeTypeAnnotation(annotation CodeTypeAnnotation, typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return &annotationBuilder{
+		annotation: Annotation{Descriptor: descriptor, Visible: visible},
+		store: func(b *annotationBuilder) {
+			annotation.TypeAnnotation = TypeAnnotation{TypeRef: typeRef, TypePath: typePath, Annotation: b.annotation}
+			if visible {
+				m.code().RuntimeVisibleTypeAnnotations = append(m.code().RuntimeVisibleTypeAnnotations, annotation)
+			} else {
+				m.code().RuntimeInvisibleTypeAnnotations = append(m.code().RuntimeInvisibleTypeAnnotations, annotation)
+			}
+		},
+	}
+}
+
+func (m *methodBuilder) VisitAnnotableParameterCount(parameterCount int, visible bool) {
+	parameters := m.parameterAnnotations(visible)
+	for len(*parameters) < parameterCount {
+		*parameters = append(*parameters, ParameterAnnotation{})
+	}
+}
+
+func (m *methodBuilder) parameterAnnotations(visible bool) *[]ParameterAnnotation {
+	if visible {
+		return &m.method().RuntimeVisibleParameterAnnotations
+	}
+	return &m.method().RuntimeInvisibleParameterAnnotations
+}
+
+func (m *methodBuilder) VisitParameterAnnotation(parameterIndex int, descriptor string, visible bool) AnnotationVisitor {
+	return &annotationBuilder{
+		annotation: Annotation{Descriptor: descriptor, Visible: visible},
+		store: func(b *annotationBuilder) {
+			parameters := m.parameterAnnotations(visible)
+			if len(*parameters) <= parameterIndex {
+				count := len(NewMethodType(m.method().Descriptor).ArgumentTypes())
+				if count <= parameterIndex {
+					count = parameterIndex + 1
+				}
+				m.VisitAnnotableParameterCount(count, visible)
+			}
+			parameter := &(*parameters)[parameterIndex]
+			parameter.Annotations = append(parameter.Annotations, b.annotation)
+		},
+	}
+}
+
+func (m *methodBuilder) VisitAttribute(attribute Attribute) {
+	if m.inCode {
+		m.code().Attributes = append(m.code().Attributes, attribute)
+		return
+	}
+	if attribute.Name == data.DEPRECATED {
+		m.method().Deprecated = true
+		return
+	}
+	m.method().Attributes = append(m.method().Attributes, attribute)
+}
+
+func (m *methodBuilder) VisitCode() {
+	m.inCode = true
+}
+
+func (m *methodBuilder) VisitFrame(frameType int, numLocal int, locals []interface{}, numStack int, stacks []interface{}) {
+	frame := &Frame{Type: frameType}
+	if frameType == data.F_CHOP {
+		frame.Locals = make([]interface{}, numLocal)
+	} else if numLocal > 0 {
+		frame.Locals = append([]interface{}{}, locals[:numLocal]...)
+	}
+	if numStack > 0 {
+		frame.Stack = append([]interface{}{}, stacks[:numStack]...)
+	}
+	m.add(frame)
+}
+
+func (m *methodBuilder) VisitInstruction(opCode uint16) {
+	m.add(&CodeInstruction{Op: opCode})
+}
+
+func (m *methodBuilder) VisitIntInstruction(opCode uint16, operand int32) {
+	m.add(&IntInstruction{Op: opCode, Operand: operand})
+}
+
+func (m *methodBuilder) VisitVarInstruction(opCode uint16, variable int) {
+	m.add(&VarInstruction{Op: opCode, Var: variable})
+}
+
+func (m *methodBuilder) VisitTypeInstruction(opCode uint16, typeName string) {
+	m.add(&TypeInstruction{Op: opCode, Type: typeName})
+}
+
+func (m *methodBuilder) VisitFieldInstruction(opCode uint16, owner string, name string, descriptor string) {
+	m.add(&FieldInstruction{Op: opCode, Owner: owner, Name: name, Descriptor: descriptor})
+}
+
+func (m *methodBuilder) VisitMethodInstruction(opCode uint16, owner string, name string, descriptor string, isInterface bool) {
+	m.add(&MethodInstruction{Op: opCode, Owner: owner, Name: name, Descriptor: descriptor, IsInterface: isInterface})
+}
+
+func (m *methodBuilder) VisitInvokeDynamicInstruction(opCode uint16, name string, descriptor string, bootstrapMethodHandle Handle, bootstrapMethodArguments []interface{}) {
+	m.add(&InvokeDynamicInstruction{Name: name, Descriptor: descriptor, BootstrapMethod: bootstrapMethodHandle, BootstrapMethodArguments: bootstrapMethodArguments})
+}
+
+func (m *methodBuilder) VisitJumpInstruction(opCode uint16, label *Label) {
+	m.add(&JumpInstruction{Op: opCode, Label: label})
+}
+
+func (m *methodBuilder) VisitLabel(label *Label) {
+	m.add(label)
+}
+
+func (m *methodBuilder) VisitLdcInstruction(value interface{}) {
+	m.add(&LdcInstruction{Value: value})
+}
+
+func (m *methodBuilder) VisitIincInstruction(variable int, increment int) {
+	m.add(&IincInstruction{Var: variable, Increment: increment})
+}
+
+func (m *methodBuilder) VisitTableSwitchInstruction(min int32, max int32, dflt *Label, labels []*Label) {
+	m.add(&TableSwitchInstruction{Min: min, Max: max, Default: dflt, Labels: labels})
+}
+
+func (m *methodBuilder) VisitLookupSwitchInstruction(dflt *Label, keys []int32, labels []*Label) {
+	m.add(&LookupSwitchInstruction{Default: dflt, Keys: keys, Labels: labels})
+}
+
+func (m *methodBuilder) VisitMultiANewArrayInstruction(descriptor string, numDimensions int) {
+	m.add(&MultiANewArrayInstruction{Descriptor: descriptor, NumDimensions: numDimensions})
+}
+
+// VisitInstructionAnnotation annotates the last visited instruction, ignoring the pseudo
+// instructions visited after it.
+func (m *methodBuilder) VisitInstructionAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	var instruction Instruction
+	instructions := m.code().Instructions
+	for i := len(instructions) - 1; i >= 0; i-- {
+		if instructions[i].OpCode() >= 0 {
+			instruction = instructions[i]
+			break
+		}
+	}
+	return m.codeTypeAnnotation(CodeTypeAnnotation{Instruction: instruction}, typeRef, typePath, descriptor, visible)
+}
+
+func (m *methodBuilder) VisitTryCatchBlock(start *Label, end *Label, handler *Label, typeName string) {
+	m.code().ExceptionTable = append(m.code().ExceptionTable, Exception{Start: start, End: end, Handler: handler, CatchType: typeName})
+}
+
+func (m *methodBuilder) VisitTryCatchAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return m.codeTypeAnnotation(CodeTypeAnnotation{}, typeRef, typePath, descriptor, visible)
+}
+
+func (m *methodBuilder) VisitLocalVariable(name string, descriptor string, signature string, start *Label, end *Label, index int) {
+	m.code().LocalVariables = append(m.code().LocalVariables, LocalVariable{Name: name, Descriptor: descriptor, Signature: signature, Start: start, End: end, Index: index})
+}
+
+func (m *methodBuilder) VisitLocalVariableAnnotation(typeRef int, typePath TypePath, start []*Label, end []*Label, index []int, descriptor string, visible bool) AnnotationVisitor {
+	return m.codeTypeAnnotation(CodeTypeAnnotation{Start: start, End: end, Index: index}, typeRef, typePath, descriptor, visible)
+}
+
+func (m *methodBuilder) VisitLineNumber(line int, start *Label) {
+	m.add(&LineNumber{Line: line, Start: start})
+}
+
+func (m *methodBuilder) VisitMaxs(maxStack int, maxLocals int) {
+	m.code().MaxStack = uint16(maxStack)
+	m.code().MaxLocal = uint16(maxLocals)
+	m.inCode = false
+}
+
+func (m *methodBuilder) VisitEnd() {
+}
@@ -0,0 +1,1231 @@
+package class
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+// CheckError is the panic value of the visitors returned by NewCheckVisitor and
+// NewCheckMethodVisitor when they are called in a wrong order or with invalid arguments.
+type CheckError struct {
+	Context string
+	Message string
+}
+
+func (e *CheckError) Error() string {
+	return e.Context + ": " + e.Message
+}
+
+// CheckClass visits a class with a CheckVisitor, and returns the first error found.
+func CheckClass(c *Class) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			checkErr, ok := r.(*CheckError)
+			if !ok {
+				panic(r)
+			}
+			err = checkErr
+		}
+	}()
+	c.Accept(NewCheckVisitor(nil))
+	return nil
+}
+
+// checkNode tracks the VisitEnd calls of a visitor and of the visitors it returned.
+type checkNode struct {
+	context  string
+	ended    bool
+	children []*checkNode
+}
+
+func (n *checkNode) fail(format string, args ...interface{}) {
+	panic(&CheckError{Context: n.context, Message: fmt.Sprintf(format, args...)})
+}
+
+func (n *checkNode) child(context string) *checkNode {
+	child := &checkNode{context: context}
+	n.children = append(n.children, child)
+	return child
+}
+
+func (n *checkNode) checkNotEnded(method string) {
+	if n.ended {
+		n.fail("%s called after VisitEnd", method)
+	}
+}
+
+func (n *checkNode) end() {
+	n.checkNotEnded("VisitEnd")
+	for _, child := range n.children {
+		if !child.ended {
+			child.fail("VisitEnd not called")
+		}
+	}
+	n.ended = true
+}
+
+// The states of a CheckVisitor, in the order of the Visitor methods.
+const (
+	checkStart = iota
+	checkVisit
+	checkSource
+	checkModule
+	checkNestHost
+	checkOuterClass
+	checkAnnotations
+	checkMembers
+)
+
+// CheckVisitor is a Visitor adapter that checks that its methods are called in the order given
+// by the Visitor documentation, with valid arguments, and forwards them to the next visitor,
+// which may be nil. The errors are reported with a *CheckError panic.
+type CheckVisitor struct {
+	visitor Visitor
+	node    *checkNode
+	state   int
+	access  uint16
+}
+
+func NewCheckVisitor(visitor Visitor) *CheckVisitor {
+	return &CheckVisitor{visitor: visitor, node: &checkNode{context: "class"}}
+}
+
+// order checks that a method of the given state can be called, and moves to this state.
+func (c *CheckVisitor) order(method string, state int, once bool) {
+	c.node.checkNotEnded(method)
+	if c.state == checkStart {
+		c.node.fail("%s called before Visit", method)
+	}
+	if c.state > state || once && c.state == state {
+		c.node.fail("%s called out of order", method)
+	}
+	c.state = state
+}
+
+func (c *CheckVisitor) Visit(version uint32, access uint16, name string, signature string, superName string, interfaces []string) {
+	if c.state != checkStart {
+		c.node.fail("Visit called twice")
+	}
+	c.state = checkVisit
+	c.access = access
+	c.node.context = name
+	if version&0xffff < 45 {
+		c.node.fail("invalid version %d.%d", version&0xffff, version>>16)
+	}
+	checkAccess(c.node, access, data.ACCESS_CLASS)
+	if access&data.ACC_MODULE != 0 {
+		if name != "module-info" {
+			c.node.fail("invalid module class name %q", name)
+		}
+	} else {
+		checkInternalName(c.node, name, "class name")
+	}
+	if access&data.ACC_INTERFACE != 0 {
+		if access&data.ACC_ABSTRACT == 0 || access&(data.ACC_FINAL|data.ACC_ENUM) != 0 {
+			c.node.fail("invalid interface access flags 0x%04x", access)
+		}
+		if superName != "java/lang/Object" {
+			c.node.fail("the super class of an interface must be java/lang/Object")
+		}
+	} else if access&data.ACC_ANNOTATION != 0 {
+		c.node.fail("an annotation type must be an interface")
+	}
+	if superName == "" {
+		if name != "java/lang/Object" && access&data.ACC_MODULE == 0 {
+			c.node.fail("missing super class")
+		}
+	} else {
+		checkInternalName(c.node, superName, "super class")
+	}
+	for _, itf := range interfaces {
+		checkInternalName(c.node, itf, "interface")
+	}
+	if c.visitor != nil {
+		c.visitor.Visit(version, access, name, signature, superName, interfaces)
+	}
+}
+
+func (c *CheckVisitor) VisitSource(source string, debug string) {
+	c.order("VisitSource", checkSource, true)
+	if c.visitor != nil {
+		c.visitor.VisitSource(source, debug)
+	}
+}
+
+func (c *CheckVisitor) VisitModule(name string, access uint16, version string) ModuleVisitor {
+	c.order("VisitModule", checkModule, true)
+	if c.access&data.ACC_MODULE == 0 {
+		c.node.fail("VisitModule called on a class which is not a module")
+	}
+	if name == "" {
+		c.node.fail("empty module name")
+	}
+	checkAccess(c.node, access, data.ACCESS_MODULE)
+	var next ModuleVisitor
+	if c.visitor != nil {
+		next = c.visitor.VisitModule(name, access, version)
+	}
+	return &checkModuleVisitor{visitor: next, node: c.node.child("module " + name)}
+}
+
+func (c *CheckVisitor) VisitNestHost(nestHost string) {
+	c.order("VisitNestHost", checkNestHost, true)
+	checkInternalName(c.node, nestHost, "nest host")
+	if c.visitor != nil {
+		c.visitor.VisitNestHost(nestHost)
+	}
+}
+
+func (c *CheckVisitor) VisitOuterClass(owner string, name string, descriptor string) {
+	c.order("VisitOuterClass", checkOuterClass, true)
+	checkInternalName(c.node, owner, "outer class")
+	if name != "" {
+		checkMethodName(c.node, name)
+		checkMethodDescriptor(c.node, descriptor)
+	}
+	if c.visitor != nil {
+		c.visitor.VisitOuterClass(owner, name, descriptor)
+	}
+}
+
+func (c *CheckVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	c.order("VisitAnnotation", checkAnnotations, false)
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitAnnotation(descriptor, visible)
+	})
+}
+
+func (c *CheckVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	c.order("VisitTypeAnnotation", checkAnnotations, false)
+	checkTypeRef(c.node, typeRef, typePath, data.TYPE_REF_CLASS_TYPE_PARAMETER, data.TYPE_REF_CLASS_TYPE_PARAMETER_BOUND, data.TYPE_REF_CLASS_EXTENDS)
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)
+	})
+}
+
+func (c *CheckVisitor) VisitAttribute(attribute Attribute) {
+	c.order("VisitAttribute", checkAnnotations, false)
+	checkAttribute(c.node, attribute)
+	if c.visitor != nil {
+		c.visitor.VisitAttribute(attribute)
+	}
+}
+
+func (c *CheckVisitor) VisitNestMember(nestMember string) {
+	c.order("VisitNestMember", checkMembers, false)
+	checkInternalName(c.node, nestMember, "nest member")
+	if c.visitor != nil {
+		c.visitor.VisitNestMember(nestMember)
+	}
+}
+
+func (c *CheckVisitor) VisitPermittedSubclass(permittedSubclass string) {
+	c.order("VisitPermittedSubclass", checkMembers, false)
+	checkInternalName(c.node, permittedSubclass, "permitted subclass")
+	if c.visitor != nil {
+		c.visitor.VisitPermittedSubclass(permittedSubclass)
+	}
+}
+
+func (c *CheckVisitor) VisitInnerClass(name string, outerName string, innerName string, access uint16) {
+	c.order("VisitInnerClass", checkMembers, false)
+	checkInternalName(c.node, name, "inner class")
+	if outerName != "" {
+		checkInternalName(c.node, outerName, "outer class")
+	}
+	if innerName != "" {
+		checkUnqualifiedName(c.node, innerName, "inner name")
+	}
+	checkAccess(c.node, access, data.ACCESS_INNER_CLASS)
+	if c.visitor != nil {
+		c.visitor.VisitInnerClass(name, outerName, innerName, access)
+	}
+}
+
+func (c *CheckVisitor) VisitRecordComponent(name string, descriptor string, signature string) RecordComponentVisitor {
+	c.order("VisitRecordComponent", checkMembers, false)
+	node := c.node.child(c.node.context + "." + name)
+	checkUnqualifiedName(node, name, "record component name")
+	checkFieldDescriptor(node, descriptor)
+	var next RecordComponentVisitor
+	if c.visitor != nil {
+		next = c.visitor.VisitRecordComponent(name, descriptor, signature)
+	}
+	return &checkRecordComponentVisitor{visitor: next, node: node}
+}
+
+func (c *CheckVisitor) VisitField(access uint16, name string, descriptor string, signature string, value interface{}) FieldVisitor {
+	c.order("VisitField", checkMembers, false)
+	node := c.node.child(c.node.context + "." + name)
+	checkAccess(node, access, data.ACCESS_FIELD)
+	if access&data.ACC_FINAL != 0 && access&data.ACC_VOLATILE != 0 {
+		node.fail("a field can't be final and volatile")
+	}
+	if c.access&data.ACC_INTERFACE != 0 && access&(data.ACC_PUBLIC|data.ACC_STATIC|data.ACC_FINAL) !=
+		data.ACC_PUBLIC|data.ACC_STATIC|data.ACC_FINAL {
+		node.fail("an interface field must be public, static and final")
+	}
+	checkUnqualifiedName(node, name, "field name")
+	checkFieldDescriptor(node, descriptor)
+	if value != nil {
+		checkConstantValue(node, descriptor, value)
+	}
+	var next FieldVisitor
+	if c.visitor != nil {
+		next = c.visitor.VisitField(access, name, descriptor, signature, value)
+	}
+	return &checkFieldVisitor{visitor: next, node: node}
+}
+
+func (c *CheckVisitor) VisitMethod(access uint16, name string, descriptor string, signature string, exceptions []string) MethodVisitor {
+	c.order("VisitMethod", checkMembers, false)
+	node := c.node.child(c.node.context + "." + name + descriptor)
+	checkAccess(node, access, data.ACCESS_METHOD)
+	if access&data.ACC_ABSTRACT != 0 &&
+		access&(data.ACC_PRIVATE|data.ACC_STATIC|data.ACC_FINAL|data.ACC_SYNCHRONIZED|data.ACC_NATIVE|data.ACC_STRICT) != 0 {
+		node.fail("invalid abstract method access flags 0x%04x", access)
+	}
+	if name == "<clinit>" {
+		if access&data.ACC_STATIC == 0 {
+			node.fail("<clinit> must be static")
+		}
+		if descriptor != "()V" {
+			node.fail("invalid <clinit> descriptor %q", descriptor)
+		}
+	} else {
+		checkMethodName(node, name)
+	}
+	checkMethodDescriptor(node, descriptor)
+	if name == "<init>" && !strings.HasSuffix(descriptor, ")V") {
+		node.fail("a constructor must return void")
+	}
+	for _, exception := range exceptions {
+		checkInternalName(node, exception, "exception")
+	}
+	var next MethodVisitor
+	if c.visitor != nil {
+		next = c.visitor.VisitMethod(access, name, descriptor, signature, exceptions)
+	}
+	return &checkMethodVisitor{visitor: next, node: node, access: access,
+		labels: make(map[*Label]bool)}
+}
+
+func (c *CheckVisitor) VisitEnd() {
+	if c.state == checkStart {
+		c.node.fail("VisitEnd called before Visit")
+	}
+	c.node.end()
+	if c.visitor != nil {
+		c.visitor.VisitEnd()
+	}
+}
+
+// NewCheckMethodVisitor returns a MethodVisitor adapter that checks the calls of the methods
+// of a method with the given access flags, and forwards them to the next visitor, which may be
+// nil. The errors are reported with a *CheckError panic.
+func NewCheckMethodVisitor(visitor MethodVisitor, access uint16) MethodVisitor {
+	return &checkMethodVisitor{visitor: visitor, node: &checkNode{context: "method"}, access: access,
+		labels: make(map[*Label]bool)}
+}
+
+type checkModuleVisitor struct {
+	visitor ModuleVisitor
+	node    *checkNode
+}
+
+func (c *checkModuleVisitor) VisitMainClass(mainClass string) {
+	c.node.checkNotEnded("VisitMainClass")
+	checkInternalName(c.node, mainClass, "main class")
+	if c.visitor != nil {
+		c.visitor.VisitMainClass(mainClass)
+	}
+}
+
+func (c *checkModuleVisitor) VisitPackage(packageName string) {
+	c.node.checkNotEnded("VisitPackage")
+	checkInternalName(c.node, packageName, "package")
+	if c.visitor != nil {
+		c.visitor.VisitPackage(packageName)
+	}
+}
+
+func (c *checkModuleVisitor) VisitRequire(moduleName string, access uint16, version string) {
+	c.node.checkNotEnded("VisitRequire")
+	if moduleName == "" {
+		c.node.fail("empty required module name")
+	}
+	checkAccess(c.node, access, data.ACCESS_MODULE_REQUIRES)
+	if c.visitor != nil {
+		c.visitor.VisitRequire(moduleName, access, version)
+	}
+}
+
+func (c *checkModuleVisitor) VisitExport(packageName string, access uint16, modules []string) {
+	c.node.checkNotEnded("VisitExport")
+	checkInternalName(c.node, packageName, "exported package")
+	checkAccess(c.node, access, data.ACCESS_MODULE_EXPORTS)
+	if c.visitor != nil {
+		c.visitor.VisitExport(packageName, access, modules)
+	}
+}
+
+func (c *checkModuleVisitor) VisitOpen(packageName string, access uint16, modules []string) {
+	c.node.checkNotEnded("VisitOpen")
+	checkInternalName(c.node, packageName, "opened package")
+	checkAccess(c.node, access, data.ACCESS_MODULE_EXPORTS)
+	if c.visitor != nil {
+		c.visitor.VisitOpen(packageName, access, modules)
+	}
+}
+
+func (c *checkModuleVisitor) VisitUse(service string) {
+	c.node.checkNotEnded("VisitUse")
+	checkInternalName(c.node, service, "service")
+	if c.visitor != nil {
+		c.visitor.VisitUse(service)
+	}
+}
+
+func (c *checkModuleVisitor) VisitProvide(service string, providers []string) {
+	c.node.checkNotEnded("VisitProvide")
+	checkInternalName(c.node, service, "service")
+	if len(providers) == 0 {
+		c.node.fail("no providers for service %s", service)
+	}
+	for _, provider := range providers {
+		checkInternalName(c.node, provider, "provider")
+	}
+	if c.visitor != nil {
+		c.visitor.VisitProvide(service, providers)
+	}
+}
+
+func (c *checkModuleVisitor) VisitEnd() {
+	c.node.end()
+	if c.visitor != nil {
+		c.visitor.VisitEnd()
+	}
+}
+
+type checkAnnotationVisitor struct {
+	visitor AnnotationVisitor
+	node    *checkNode
+	// named is true if the values have a name, i.e. if this is not an array or annotation default visitor.
+	named bool
+}
+
+// newCheckAnnotationVisitor checks the descriptor of an annotation, and returns its checking
+// visitor. The next visitor is created with next if hasNext is true.
+func newCheckAnnotationVisitor(parent *checkNode, descriptor string, hasNext bool, next func() AnnotationVisitor) AnnotationVisitor {
+	node := parent.child(parent.context + " @" + descriptor)
+	checkFieldDescriptor(node, descriptor)
+	if descriptor[0] != 'L' {
+		node.fail("invalid annotation descriptor %q", descriptor)
+	}
+	visitor := &checkAnnotationVisitor{node: node, named: true}
+	if hasNext {
+		visitor.visitor = next()
+	}
+	return visitor
+}
+
+func (c *checkAnnotationVisitor) checkName(method string, name string) {
+	c.node.checkNotEnded(method)
+	if c.named && name == "" {
+		c.node.fail("%s called without element name", method)
+	}
+	if !c.named && name != "" {
+		c.node.fail("%s called with an element name in an array", method)
+	}
+}
+
+func (c *checkAnnotationVisitor) Visit(name string, value interface{}) {
+	c.checkName("Visit", name)
+	if NewElementValue(value) == nil {
+		c.node.fail("invalid element value %v (%T)", value, value)
+	}
+	if t, ok := value.(Type); ok && t.Sort() == data.TYPE_SORT_METHOD {
+		c.node.fail("invalid element class value %s", t)
+	}
+	if c.visitor != nil {
+		c.visitor.Visit(name, value)
+	}
+}
+
+func (c *checkAnnotationVisitor) VisitEnum(name string, descriptor string, value string) {
+	c.checkName("VisitEnum", name)
+	checkFieldDescriptor(c.node, descriptor)
+	checkUnqualifiedName(c.node, value, "enum constant")
+	if c.visitor != nil {
+		c.visitor.VisitEnum(name, descriptor, value)
+	}
+}
+
+func (c *checkAnnotationVisitor) VisitAnnotation(name string, descriptor string) AnnotationVisitor {
+	c.checkName("VisitAnnotation", name)
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitAnnotation(name, descriptor)
+	})
+}
+
+func (c *checkAnnotationVisitor) VisitArray(name string) AnnotationVisitor {
+	c.checkName("VisitArray", name)
+	visitor := &checkAnnotationVisitor{node: c.node.child(c.node.context + " " + name + "[]")}
+	if c.visitor != nil {
+		visitor.visitor = c.visitor.VisitArray(name)
+	}
+	return visitor
+}
+
+func (c *checkAnnotationVisitor) VisitEnd() {
+	c.node.end()
+	if c.visitor != nil {
+		c.visitor.VisitEnd()
+	}
+}
+
+type checkFieldVisitor struct {
+	visitor FieldVisitor
+	node    *checkNode
+}
+
+func (c *checkFieldVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	c.node.checkNotEnded("VisitAnnotation")
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitAnnotation(descriptor, visible)
+	})
+}
+
+func (c *checkFieldVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	c.node.checkNotEnded("VisitTypeAnnotation")
+	checkTypeRef(c.node, typeRef, typePath, data.TYPE_REF_FIELD)
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)
+	})
+}
+
+func (c *checkFieldVisitor) VisitAttribute(attribute Attribute) {
+	c.node.checkNotEnded("VisitAttribute")
+	checkAttribute(c.node, attribute)
+	if c.visitor != nil {
+		c.visitor.VisitAttribute(attribute)
+	}
+}
+
+func (c *checkFieldVisitor) VisitEnd() {
+	c.node.end()
+	if c.visitor != nil {
+		c.visitor.VisitEnd()
+	}
+}
+
+type checkRecordComponentVisitor struct {
+	visitor RecordComponentVisitor
+	node    *checkNode
+}
+
+func (c *checkRecordComponentVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	c.node.checkNotEnded("VisitAnnotation")
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitAnnotation(descriptor, visible)
+	})
+}
+
+func (c *checkRecordComponentVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	c.node.checkNotEnded("VisitTypeAnnotation")
+	checkTypeRef(c.node, typeRef, typePath, data.TYPE_REF_FIELD)
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)
+	})
+}
+
+func (c *checkRecordComponentVisitor) VisitAttribute(attribute Attribute) {
+	c.node.checkNotEnded("VisitAttribute")
+	checkAttribute(c.node, attribute)
+	if c.visitor != nil {
+		c.visitor.VisitAttribute(attribute)
+	}
+}
+
+func (c *checkRecordComponentVisitor) VisitEnd() {
+	c.node.end()
+	if c.visitor != nil {
+		c.visitor.VisitEnd()
+	}
+}
+
+// The states of a checkMethodVisitor, in the order of the MethodVisitor methods.
+const (
+	checkParameters = iota
+	checkAnnotationDefault
+	checkMethodAnnotations
+	checkCode
+	checkMaxs
+)
+
+type checkMethodVisitor struct {
+	visitor MethodVisitor
+	node    *checkNode
+	access  uint16
+	state   int
+	// labels maps the visited labels to true, and the labels used but not yet visited to false.
+	labels map[*Label]bool
+	// expandedFrames is 1 if the frames are uncompressed (F_NEW), -1 if they are compressed.
+	expandedFrames int
+}
+
+// order checks that a method of the given state can be called, and moves to this state.
+func (c *checkMethodVisitor) order(method string, state int) {
+	c.node.checkNotEnded(method)
+	if c.state > state {
+		c.node.fail("%s called out of order", method)
+	}
+	c.state = state
+}
+
+// code checks that a code method is called between VisitCode and VisitMaxs.
+func (c *checkMethodVisitor) code(method string) {
+	c.node.checkNotEnded(method)
+	if c.state != checkCode {
+		c.node.fail("%s called outside of the code", method)
+	}
+}
+
+func (c *checkMethodVisitor) opCode(method string, opCode uint16) {
+	c.code(method)
+	if expected := instructionMethod(int(opCode)); expected != method {
+		c.node.fail("invalid opcode %d for %s", opCode, method)
+	}
+}
+
+// use records the use of a label, which must be visited before VisitMaxs.
+func (c *checkMethodVisitor) use(label *Label) {
+	if label == nil {
+		c.node.fail("nil label")
+	}
+	if _, ok := c.labels[label]; !ok {
+		c.labels[label] = false
+	}
+}
+
+// visited checks that a label has already been visited.
+func (c *checkMethodVisitor) visited(label *Label, method string) {
+	if label == nil || !c.labels[label] {
+		c.node.fail("%s called with a label which is not visited yet", method)
+	}
+}
+
+func (c *checkMethodVisitor) VisitParameter(name string, access uint16) {
+	c.order("VisitParameter", checkParameters)
+	if name != "" {
+		checkUnqualifiedName(c.node, name, "parameter name")
+	}
+	checkAccess(c.node, access, data.ACCESS_PARAMETER)
+	if c.visitor != nil {
+		c.visitor.VisitParameter(name, access)
+	}
+}
+
+func (c *checkMethodVisitor) VisitAnnotationDefault() AnnotationVisitor {
+	c.order("VisitAnnotationDefault", checkAnnotationDefault)
+	c.state = checkMethodAnnotations
+	visitor := &checkAnnotationVisitor{node: c.node.child(c.node.context + " default")}
+	if c.visitor != nil {
+		visitor.visitor = c.visitor.VisitAnnotationDefault()
+	}
+	return visitor
+}
+
+func (c *checkMethodVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	c.order("VisitAnnotation", checkMethodAnnotations)
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitAnnotation(descriptor, visible)
+	})
+}
+
+func (c *checkMethodVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	c.order("VisitTypeAnnotation", checkMethodAnnotations)
+	checkTypeRef(c.node, typeRef, typePath, data.TYPE_REF_METHOD_TYPE_PARAMETER, data.TYPE_REF_METHOD_TYPE_PARAMETER_BOUND,
+		data.TYPE_REF_METHOD_RETURN, data.TYPE_REF_METHOD_RECEIVER, data.TYPE_REF_METHOD_FORMAL_PARAMETER, data.TYPE_REF_THROWS)
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)
+	})
+}
+
+func (c *checkMethodVisitor) VisitAnnotableParameterCount(parameterCount int, visible bool) {
+	c.order("VisitAnnotableParameterCount", checkMethodAnnotations)
+	if parameterCount < 0 || parameterCount > 255 {
+		c.node.fail("invalid annotable parameter count %d", parameterCount)
+	}
+	if c.visitor != nil {
+		c.visitor.VisitAnnotableParameterCount(parameterCount, visible)
+	}
+}
+
+func (c *checkMethodVisitor) VisitParameterAnnotation(parameterIndex int, descriptor string, visible bool) AnnotationVisitor {
+	c.order("VisitParameterAnnotation", checkMethodAnnotations)
+	if parameterIndex < 0 || parameterIndex > 255 {
+		c.node.fail("invalid parameter index %d", parameterIndex)
+	}
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitParameterAnnotation(parameterIndex, descriptor, visible)
+	})
+}
+
+// VisitAttribute adds an attribute to the method, or to its code if it is called after VisitCode.
+func (c *checkMethodVisitor) VisitAttribute(attribute Attribute) {
+	c.node.checkNotEnded("VisitAttribute")
+	if c.state != checkCode {
+		c.order("VisitAttribute", checkMethodAnnotations)
+	}
+	checkAttribute(c.node, attribute)
+	if c.visitor != nil {
+		c.visitor.VisitAttribute(attribute)
+	}
+}
+
+func (c *checkMethodVisitor) VisitCode() {
+	c.node.checkNotEnded("VisitCode")
+	if c.state >= checkCode {
+		c.node.fail("VisitCode called twice")
+	}
+	if c.access&(data.ACC_ABSTRACT|data.ACC_NATIVE) != 0 {
+		c.node.fail("abstract and native methods can't have code")
+	}
+	c.state = checkCode
+	if c.visitor != nil {
+		c.visitor.VisitCode()
+	}
+}
+
+func (c *checkMethodVisitor) VisitFrame(frameType int, numLocal int, locals []interface{}, numStack int, stacks []interface{}) {
+	c.code("VisitFrame")
+	expanded := -1
+	if frameType == data.F_NEW {
+		expanded = 1
+	}
+	if c.expandedFrames != 0 && c.expandedFrames != expanded {
+		c.node.fail("expanded and compressed frames can't be mixed")
+	}
+	c.expandedFrames = expanded
+	maxLocal, maxStack := 0, 0
+	switch frameType {
+	case data.F_NEW, data.F_FULL:
+		maxLocal, maxStack = 65535, 65535
+	case data.F_APPEND, data.F_CHOP:
+		maxLocal = 3
+	case data.F_SAME1:
+		maxStack = 1
+	case data.F_SAME:
+	default:
+		c.node.fail("invalid frame type %d", frameType)
+	}
+	if numLocal != len(locals) && frameType != data.F_CHOP || numLocal > maxLocal ||
+		numStack != len(stacks) || numStack > maxStack {
+		c.node.fail("invalid frame sizes %d and %d for frame type %d", numLocal, numStack, frameType)
+	}
+	if frameType == data.F_APPEND && numLocal == 0 || frameType == data.F_CHOP && numLocal == 0 ||
+		frameType == data.F_SAME1 && numStack == 0 {
+		c.node.fail("missing frame values for frame type %d", frameType)
+	}
+	if frameType != data.F_CHOP {
+		for _, values := range [][]interface{}{locals, stacks} {
+			for _, value := range values {
+				c.frameValue(value)
+			}
+		}
+	}
+	if c.visitor != nil {
+		c.visitor.VisitFrame(frameType, numLocal, locals, numStack, stacks)
+	}
+}
+
+func (c *checkMethodVisitor) frameValue(value interface{}) {
+	switch v := value.(type) {
+	case uint8:
+		if v > data.ITEM_UNINITIALIZED_THIS {
+			c.node.fail("invalid frame value %d", v)
+		}
+	case string:
+		checkInternalName(c.node, v, "frame value")
+	case *Label:
+		c.use(v)
+	default:
+		c.node.fail("invalid frame value %v (%T)", value, value)
+	}
+}
+
+func (c *checkMethodVisitor) VisitInstruction(opCode uint16) {
+	c.opCode("VisitInstruction", opCode)
+	if c.visitor != nil {
+		c.visitor.VisitInstruction(opCode)
+	}
+}
+
+func (c *checkMethodVisitor) VisitIntInstruction(opCode uint16, operand int32) {
+	c.opCode("VisitIntInstruction", opCode)
+	switch {
+	case opCode == data.BIPUSH && (operand < -128 || operand > 127),
+		opCode == data.SIPUSH && (operand < -32768 || operand > 32767),
+		opCode == data.NEWARRAY && (operand < data.T_BOOLEAN || operand > data.T_LONG):
+		c.node.fail("invalid operand %d for %s", operand, data.OPCODE_NAMES[opCode])
+	}
+	if c.visitor != nil {
+		c.visitor.VisitIntInstruction(opCode, operand)
+	}
+}
+
+func (c *checkMethodVisitor) VisitVarInstruction(opCode uint16, variable int) {
+	c.opCode("VisitVarInstruction", opCode)
+	checkVariable(c.node, variable)
+	if c.visitor != nil {
+		c.visitor.VisitVarInstruction(opCode, variable)
+	}
+}
+
+func (c *checkMethodVisitor) VisitTypeInstruction(opCode uint16, typeName string) {
+	c.opCode("VisitTypeInstruction", opCode)
+	checkInternalName(c.node, typeName, "type")
+	if opCode == data.NEW && typeName[0] == '[' {
+		c.node.fail("new can't create the array type %s", typeName)
+	}
+	if c.visitor != nil {
+		c.visitor.VisitTypeInstruction(opCode, typeName)
+	}
+}
+
+func (c *checkMethodVisitor) VisitFieldInstruction(opCode uint16, owner string, name string, descriptor string) {
+	c.opCode("VisitFieldInstruction", opCode)
+	checkInternalName(c.node, owner, "field owner")
+	checkUnqualifiedName(c.node, name, "field name")
+	checkFieldDescriptor(c.node, descriptor)
+	if c.visitor != nil {
+		c.visitor.VisitFieldInstruction(opCode, owner, name, descriptor)
+	}
+}
+
+func (c *checkMethodVisitor) VisitMethodInstruction(opCode uint16, owner string, name string, descriptor string, isInterface bool) {
+	c.opCode("VisitMethodInstruction", opCode)
+	checkInternalName(c.node, owner, "method owner")
+	if name != "<init>" || opCode != data.INVOKESPECIAL {
+		checkMethodName(c.node, name)
+	}
+	checkMethodDescriptor(c.node, descriptor)
+	if opCode == data.INVOKEINTERFACE && !isInterface {
+		c.node.fail("invokeinterface must be called on an interface")
+	}
+	if c.visitor != nil {
+		c.visitor.VisitMethodInstruction(opCode, owner, name, descriptor, isInterface)
+	}
+}
+
+func (c *checkMethodVisitor) VisitInvokeDynamicInstruction(opCode uint16, name string, descriptor string, bootstrapMethodHandle Handle, bootstrapMethodArguments []interface{}) {
+	c.opCode("VisitInvokeDynamicInstruction", opCode)
+	checkMethodName(c.node, name)
+	checkMethodDescriptor(c.node, descriptor)
+	checkHandle(c.node, bootstrapMethodHandle)
+	if bootstrapMethodHandle.Tag != data.HANDLE_INVOKESTATIC && bootstrapMethodHandle.Tag != data.HANDLE_NEWINVOKESPECIAL {
+		c.node.fail("invalid bootstrap method handle kind %d", bootstrapMethodHandle.Tag)
+	}
+	for _, argument := range bootstrapMethodArguments {
+		checkConstant(c.node, argument)
+	}
+	if c.visitor != nil {
+		c.visitor.VisitInvokeDynamicInstruction(opCode, name, descriptor, bootstrapMethodHandle, bootstrapMethodArguments)
+	}
+}
+
+func (c *checkMethodVisitor) VisitJumpInstruction(opCode uint16, label *Label) {
+	c.opCode("VisitJumpInstruction", opCode)
+	c.use(label)
+	if c.visitor != nil {
+		c.visitor.VisitJumpInstruction(opCode, label)
+	}
+}
+
+func (c *checkMethodVisitor) VisitLabel(label *Label) {
+	c.code("VisitLabel")
+	if label == nil {
+		c.node.fail("nil label")
+	}
+	if c.labels[label] {
+		c.node.fail("label visited twice")
+	}
+	c.labels[label] = true
+	if c.visitor != nil {
+		c.visitor.VisitLabel(label)
+	}
+}
+
+func (c *checkMethodVisitor) VisitLdcInstruction(value interface{}) {
+	c.code("VisitLdcInstruction")
+	checkConstant(c.node, value)
+	if c.visitor != nil {
+		c.visitor.VisitLdcInstruction(value)
+	}
+}
+
+func (c *checkMethodVisitor) VisitIincInstruction(variable int, increment int) {
+	c.code("VisitIincInstruction")
+	checkVariable(c.node, variable)
+	if increment < -32768 || increment > 32767 {
+		c.node.fail("invalid increment %d", increment)
+	}
+	if c.visitor != nil {
+		c.visitor.VisitIincInstruction(variable, increment)
+	}
+}
+
+func (c *checkMethodVisitor) VisitTableSwitchInstruction(min int32, max int32, dflt *Label, labels []*Label) {
+	c.code("VisitTableSwitchInstruction")
+	if max < min {
+		c.node.fail("max %d must be greater than or equal to min %d", max, min)
+	}
+	if int64(len(labels)) != int64(max)-int64(min)+1 {
+		c.node.fail("expected %d labels instead of %d", int64(max)-int64(min)+1, len(labels))
+	}
+	c.use(dflt)
+	for _, label := range labels {
+		c.use(label)
+	}
+	if c.visitor != nil {
+		c.visitor.VisitTableSwitchInstruction(min, max, dflt, labels)
+	}
+}
+
+func (c *checkMethodVisitor) VisitLookupSwitchInstruction(dflt *Label, keys []int32, labels []*Label) {
+	c.code("VisitLookupSwitchInstruction")
+	if len(keys) != len(labels) {
+		c.node.fail("%d keys for %d labels", len(keys), len(labels))
+	}
+	c.use(dflt)
+	for _, label := range labels {
+		c.use(label)
+	}
+	if c.visitor != nil {
+		c.visitor.VisitLookupSwitchInstruction(dflt, keys, labels)
+	}
+}
+
+func (c *checkMethodVisitor) VisitMultiANewArrayInstruction(descriptor string, numDimensions int) {
+	c.code("VisitMultiANewArrayInstruction")
+	checkFieldDescriptor(c.node, descriptor)
+	if numDimensions < 1 || numDimensions > len(descriptor)-len(strings.TrimLeft(descriptor, "[")) {
+		c.node.fail("invalid number of dimensions %d for %s", numDimensions, descriptor)
+	}
+	if c.visitor != nil {
+		c.visitor.VisitMultiANewArrayInstruction(descriptor, numDimensions)
+	}
+}
+
+func (c *checkMethodVisitor) VisitInstructionAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	c.code("VisitInstructionAnnotation")
+	checkTypeRef(c.node, typeRef, typePath, data.TYPE_REF_INSTANCEOF, data.TYPE_REF_NEW, data.TYPE_REF_CONSTRUCTOR_REFERENCE,
+		data.TYPE_REF_METHOD_REFERENCE, data.TYPE_REF_CAST, data.TYPE_REF_CONSTRUCTOR_INVOCATION_TYPE_ARGUMENT,
+		data.TYPE_REF_METHOD_INVOCATION_TYPE_ARGUMENT, data.TYPE_REF_CONSTRUCTOR_REFERENCE_TYPE_ARGUMENT,
+		data.TYPE_REF_METHOD_REFERENCE_TYPE_ARGUMENT)
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitInstructionAnnotation(typeRef, typePath, descriptor, visible)
+	})
+}
+
+func (c *checkMethodVisitor) VisitTryCatchBlock(start *Label, end *Label, handler *Label, typeName string) {
+	c.code("VisitTryCatchBlock")
+	for _, label := range []*Label{start, end, handler} {
+		if c.labels[label] {
+			c.node.fail("VisitTryCatchBlock called after the visit of its labels")
+		}
+		c.use(label)
+	}
+	if typeName != "" {
+		checkInternalName(c.node, typeName, "exception type")
+	}
+	if c.visitor != nil {
+		c.visitor.VisitTryCatchBlock(start, end, handler, typeName)
+	}
+}
+
+func (c *checkMethodVisitor) VisitTryCatchAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	c.code("VisitTryCatchAnnotation")
+	checkTypeRef(c.node, typeRef, typePath, data.TYPE_REF_EXCEPTION_PARAMETER)
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitTryCatchAnnotation(typeRef, typePath, descriptor, visible)
+	})
+}
+
+func (c *checkMethodVisitor) VisitLocalVariable(name string, descriptor string, signature string, start *Label, end *Label, index int) {
+	c.code("VisitLocalVariable")
+	checkUnqualifiedName(c.node, name, "local variable name")
+	checkFieldDescriptor(c.node, descriptor)
+	checkVariable(c.node, index)
+	c.visited(start, "VisitLocalVariable")
+	c.visited(end, "VisitLocalVariable")
+	if c.visitor != nil {
+		c.visitor.VisitLocalVariable(name, descriptor, signature, start, end, index)
+	}
+}
+
+func (c *checkMethodVisitor) VisitLocalVariableAnnotation(typeRef int, typePath TypePath, start []*Label, end []*Label, index []int, descriptor string, visible bool) AnnotationVisitor {
+	c.code("VisitLocalVariableAnnotation")
+	checkTypeRef(c.node, typeRef, typePath, data.TYPE_REF_LOCAL_VARIABLE, data.TYPE_REF_RESOURCE_VARIABLE)
+	if len(start) == 0 || len(end) != len(start) || len(index) != len(start) {
+		c.node.fail("invalid local variable annotation ranges")
+	}
+	for i := range start {
+		c.visited(start[i], "VisitLocalVariableAnnotation")
+		c.visited(end[i], "VisitLocalVariableAnnotation")
+		checkVariable(c.node, index[i])
+	}
+	return newCheckAnnotationVisitor(c.node, descriptor, c.visitor != nil, func() AnnotationVisitor {
+		return c.visitor.VisitLocalVariableAnnotation(typeRef, typePath, start, end, index, descriptor, visible)
+	})
+}
+
+func (c *checkMethodVisitor) VisitLineNumber(line int, start *Label) {
+	c.code("VisitLineNumber")
+	if line < 0 || line > 65535 {
+		c.node.fail("invalid line number %d", line)
+	}
+	c.visited(start, "VisitLineNumber")
+	if c.visitor != nil {
+		c.visitor.VisitLineNumber(line, start)
+	}
+}
+
+func (c *checkMethodVisitor) VisitMaxs(maxStack int, maxLocals int) {
+	c.code("VisitMaxs")
+	c.state = checkMaxs
+	for _, visited := range c.labels {
+		if !visited {
+			c.node.fail("a label is used but never visited")
+		}
+	}
+	if maxStack < 0 || maxStack > 65535 || maxLocals < 0 || maxLocals > 65535 {
+		c.node.fail("invalid max values %d and %d", maxStack, maxLocals)
+	}
+	if c.visitor != nil {
+		c.visitor.VisitMaxs(maxStack, maxLocals)
+	}
+}
+
+func (c *checkMethodVisitor) VisitEnd() {
+	if c.state == checkCode {
+		c.node.fail("VisitEnd called before VisitMaxs")
+	}
+	c.node.end()
+	if c.visitor != nil {
+		c.visitor.VisitEnd()
+	}
+}
+
+// instructionMethod returns the name of the MethodVisitor method which visits an opcode.
+func instructionMethod(opCode int) string {
+	switch {
+	case opCode == data.BIPUSH, opCode == data.SIPUSH, opCode == data.NEWARRAY:
+		return "VisitIntInstruction"
+	case opCode >= data.ILOAD && opCode <= data.ALOAD, opCode >= data.ISTORE && opCode <= data.ASTORE,
+		opCode == data.RET:
+		return "VisitVarInstruction"
+	case opCode == data.NEW, opCode == data.ANEWARRAY, opCode == data.CHECKCAST, opCode == data.INSTANCEOF:
+		return "VisitTypeInstruction"
+	case opCode >= data.GETSTATIC && opCode <= data.PUTFIELD:
+		return "VisitFieldInstruction"
+	case opCode >= data.INVOKEVIRTUAL && opCode <= data.INVOKEINTERFACE:
+		return "VisitMethodInstruction"
+	case opCode == data.INVOKEDYNAMIC:
+		return "VisitInvokeDynamicInstruction"
+	case opCode >= data.IFEQ && opCode <= data.JSR, opCode == data.IFNULL, opCode == data.IFNONNULL:
+		return "VisitJumpInstruction"
+	case opCode >= data.NOP && opCode <= data.DCONST_1, opCode >= data.IALOAD && opCode <= data.SALOAD,
+		opCode >= data.IASTORE && opCode <= data.SASTORE, opCode >= data.POP && opCode <= data.LXOR,
+		opCode >= data.I2L && opCode <= data.DCMPG, opCode >= data.IRETURN && opCode <= data.RETURN,
+		opCode == data.ARRAYLENGTH, opCode == data.ATHROW, opCode == data.MONITORENTER, opCode == data.MONITOREXIT:
+		return "VisitInstruction"
+	}
+	return ""
+}
+
+func checkAccess(n *checkNode, access uint16, kind int) {
+	for _, name := range data.AccessFlagNames(access, kind) {
+		if strings.HasPrefix(name, "0x") {
+			n.fail("invalid access flags %s", name)
+		}
+	}
+	if bits.OnesCount16(access&(data.ACC_PUBLIC|data.ACC_PRIVATE|data.ACC_PROTECTED)) > 1 {
+		n.fail("public, private and protected are mutually exclusive")
+	}
+	if access&data.ACC_FINAL != 0 && access&data.ACC_ABSTRACT != 0 {
+		n.fail("final and abstract are mutually exclusive")
+	}
+}
+
+func checkAttribute(n *checkNode, attribute Attribute) {
+	if attribute.Name == "" {
+		n.fail("attribute without name")
+	}
+}
+
+// checkTypeRef checks that the sort of a type reference is one of the given sorts, and that the
+// type path is valid.
+func checkTypeRef(n *checkNode, typeRef int, typePath TypePath, sorts ...int) {
+	valid := false
+	for _, sort := range sorts {
+		if TypeReferenceSort(typeRef) == sort {
+			valid = true
+		}
+	}
+	if !valid {
+		n.fail("invalid type reference sort 0x%x", TypeReferenceSort(typeRef))
+	}
+	for _, step := range typePath {
+		if step.Kind > data.TYPE_PATH_TYPE_ARGUMENT || step.Kind != data.TYPE_PATH_TYPE_ARGUMENT && step.TypeArgumentIndex != 0 {
+			n.fail("invalid type path step %v", step)
+		}
+	}
+}
+
+func checkVariable(n *checkNode, variable int) {
+	if variable < 0 || variable > 65535 {
+		n.fail("invalid local variable index %d", variable)
+	}
+}
+
+// checkUnqualifiedName checks a field, method, local variable or inner class name (JVMS 4.2.2).
+func checkUnqualifiedName(n *checkNode, name string, what string) {
+	if name == "" || strings.ContainsAny(name, ".;[/") {
+		n.fail("invalid %s %q", what, name)
+	}
+}
+
+func checkMethodName(n *checkNode, name string) {
+	if name == "<init>" || name == "<clinit>" {
+		return
+	}
+	if strings.ContainsAny(name, "<>") {
+		n.fail("invalid method name %q", name)
+	}
+	checkUnqualifiedName(n, name, "method name")
+}
+
+// checkInternalName checks a class internal name, or an array descriptor.
+func checkInternalName(n *checkNode, name string, what string) {
+	if strings.HasPrefix(name, "[") {
+		checkFieldDescriptor(n, name)
+		return
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == "" || strings.ContainsAny(part, ".;[") {
+			n.fail("invalid %s %q", what, name)
+		}
+	}
+}
+
+func checkFieldDescriptor(n *checkNode, descriptor string) {
+	if fieldDescriptorEnd(descriptor, 0) != len(descriptor) {
+		n.fail("invalid descriptor %q", descriptor)
+	}
+}
+
+func checkMethodDescriptor(n *checkNode, descriptor string) {
+	if !strings.HasPrefix(descriptor, "(") {
+		n.fail("invalid method descriptor %q", descriptor)
+	}
+	i := 1
+	for i < len(descriptor) && descriptor[i] != ')' {
+		if i = fieldDescriptorEnd(descriptor, i); i < 0 {
+			n.fail("invalid method descriptor %q", descriptor)
+		}
+	}
+	if i >= len(descriptor) || descriptor[i+1:] != "V" && fieldDescriptorEnd(descriptor, i+1) != len(descriptor) {
+		n.fail("invalid method descriptor %q", descriptor)
+	}
+}
+
+// fieldDescriptorEnd returns the end of the field descriptor starting at begin, or -1 if it is invalid.
+func fieldDescriptorEnd(descriptor string, begin int) int {
+	i := begin
+	for i < len(descriptor) && descriptor[i] == '[' {
+		i++
+	}
+	if i-begin > 255 || i >= len(descriptor) {
+		return -1
+	}
+	switch descriptor[i] {
+	case 'Z', 'C', 'B', 'S', 'I', 'F', 'J', 'D':
+		return i + 1
+	case 'L':
+		end := strings.IndexByte(descriptor[i:], ';')
+		if end < 2 {
+			return -1
+		}
+		for _, part := range strings.Split(descriptor[i+1:i+end], "/") {
+			if part == "" || strings.ContainsAny(part, ".[") {
+				return -1
+			}
+		}
+		return i + end + 1
+	}
+	return -1
+}
+
+func checkConstantValue(n *checkNode, descriptor string, value interface{}) {
+	ok := false
+	switch value.(type) {
+	case int32:
+		ok = strings.ContainsAny(descriptor, "ZCBSI") && len(descriptor) == 1
+	case int64:
+		ok = descriptor == "J"
+	case float32:
+		ok = descriptor == "F"
+	case float64:
+		ok = descriptor == "D"
+	case string:
+		ok = descriptor == "Ljava/lang/String;"
+	}
+	if !ok {
+		n.fail("invalid constant value %v (%T) for descriptor %s", value, value, descriptor)
+	}
+}
+
+func checkHandle(n *checkNode, handle Handle) {
+	if handle.Tag < data.HANDLE_GETFIELD || handle.Tag > data.HANDLE_INVOKEINTERFACE {
+		n.fail("invalid handle kind %d", handle.Tag)
+	}
+	checkInternalName(n, handle.Owner, "handle owner")
+	if handle.Tag <= data.HANDLE_PUTSTATIC {
+		checkUnqualifiedName(n, handle.Name, "handle field name")
+		checkFieldDescriptor(n, handle.Descriptor)
+		return
+	}
+	if handle.Name != "<init>" || handle.Tag != data.HANDLE_NEWINVOKESPECIAL {
+		checkMethodName(n, handle.Name)
+	}
+	checkMethodDescriptor(n, handle.Descriptor)
+}
+
+// checkConstant checks an ldc or bootstrap method argument constant.
+func checkConstant(n *checkNode, value interface{}) {
+	switch v := value.(type) {
+	case int32, int64, float32, float64, string:
+	case Type:
+		if v.Sort() == data.TYPE_SORT_METHOD {
+			checkMethodDescriptor(n, v.Descriptor())
+		} else {
+			checkInternalName(n, v.InternalName(), "class constant")
+		}
+	case Handle:
+		checkHandle(n, v)
+	case ConstantDynamic:
+		checkUnqualifiedName(n, v.Name, "dynamic constant name")
+		checkFieldDescriptor(n, v.Descriptor)
+		checkHandle(n, v.BootstrapMethod)
+		for _, argument := range v.BootstrapMethodArguments {
+			checkConstant(n, argument)
+		}
+	default:
+		n.fail("invalid constant %v (%T)", value, value)
+	}
+}
@@ -0,0 +1,111 @@
+package class
+
+import (
+	"os"
+	"testing"
+
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/tools"
+)
+
+// checkMessage returns the message of the CheckError raised by f, if any.
+func checkMessage(f func()) (message string) {
+	defer func() {
+		if r := recover(); r != nil {
+			message = r.(*CheckError).Error()
+		}
+	}()
+	f()
+	return ""
+}
+
+func TestCheckClass(t *testing.T) {
+	f, err := os.Open("Hello.class")
+	tools.AssertNoErr(t, err)
+	defer f.Close()
+	reader := NewReader(f)
+	tools.AssertNoErr(t, reader.Read())
+	tools.AssertNoErr(t, CheckClass(reader.Class()))
+
+	writer := NewWriter()
+	reader.Class().Accept(NewCheckVisitor(writer))
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, string(writeClass(t, reader.Class())), string(content))
+
+	c := *reader.Class()
+	c.Methods = append([]Method{}, c.Methods...)
+	c.Methods[0].Descriptor = "(Ljava/lang/String)V"
+	tools.AssertEqual(t, "com/example/demo/Hello.<init>(Ljava/lang/String)V: invalid method descriptor \"(Ljava/lang/String)V\"",
+		CheckClass(&c).Error())
+}
+
+func TestCheckVisitorOrder(t *testing.T) {
+	newVisitor := func() *CheckVisitor {
+		v := NewCheckVisitor(nil)
+		v.Visit(52, data.ACC_PUBLIC|data.ACC_SUPER, "a/A", "", "java/lang/Object", nil)
+		return v
+	}
+	tools.AssertEqual(t, "class: VisitSource called before Visit", checkMessage(func() {
+		NewCheckVisitor(nil).VisitSource("A.java", "")
+	}))
+	tools.AssertEqual(t, "a/A: VisitSource called out of order", checkMessage(func() {
+		v := newVisitor()
+		v.VisitField(data.ACC_PRIVATE, "f", "I", "", nil).VisitEnd()
+		v.VisitSource("A.java", "")
+	}))
+	tools.AssertEqual(t, "a/A: VisitEnd called after VisitEnd", checkMessage(func() {
+		v := newVisitor()
+		v.VisitEnd()
+		v.VisitEnd()
+	}))
+	tools.AssertEqual(t, "a/A.f: VisitEnd not called", checkMessage(func() {
+		v := newVisitor()
+		v.VisitField(data.ACC_PRIVATE, "f", "I", "", nil)
+		v.VisitEnd()
+	}))
+	tools.AssertEqual(t, "a/A.f: public, private and protected are mutually exclusive", checkMessage(func() {
+		newVisitor().VisitField(data.ACC_PUBLIC|data.ACC_PRIVATE, "f", "I", "", nil)
+	}))
+	tools.AssertEqual(t, "a/A.f: invalid descriptor \"Q\"", checkMessage(func() {
+		newVisitor().VisitField(data.ACC_PRIVATE, "f", "Q", "", nil)
+	}))
+	tools.AssertEqual(t, "a/A.m()V: invalid abstract method access flags 0x040a", checkMessage(func() {
+		newVisitor().VisitMethod(data.ACC_ABSTRACT|data.ACC_STATIC|data.ACC_PRIVATE, "m", "()V", "", nil)
+	}))
+}
+
+func TestCheckMethodVisitor(t *testing.T) {
+	tools.AssertEqual(t, "method: invalid opcode 21 for VisitInstruction", checkMessage(func() {
+		m := NewCheckMethodVisitor(nil, data.ACC_STATIC)
+		m.VisitCode()
+		m.VisitInstruction(data.ILOAD)
+	}))
+	tools.AssertEqual(t, "method: VisitInstruction called outside of the code", checkMessage(func() {
+		NewCheckMethodVisitor(nil, data.ACC_STATIC).VisitInstruction(data.RETURN)
+	}))
+	tools.AssertEqual(t, "method: a label is used but never visited", checkMessage(func() {
+		m := NewCheckMethodVisitor(nil, data.ACC_STATIC)
+		m.VisitCode()
+		m.VisitJumpInstruction(data.GOTO, NewLabel())
+		m.VisitMaxs(0, 0)
+	}))
+	tools.AssertEqual(t, "method: label visited twice", checkMessage(func() {
+		m := NewCheckMethodVisitor(nil, data.ACC_STATIC)
+		label := NewLabel()
+		m.VisitCode()
+		m.VisitLabel(label)
+		m.VisitLabel(label)
+	}))
+	tools.AssertEqual(t, "", checkMessage(func() {
+		m := NewCheckMethodVisitor(nil, data.ACC_STATIC)
+		label := NewLabel()
+		m.VisitCode()
+		m.VisitJumpInstruction(data.GOTO, label)
+		m.VisitLabel(label)
+		m.VisitLineNumber(3, label)
+		m.VisitInstruction(data.RETURN)
+		m.VisitMaxs(0, 0)
+		m.VisitEnd()
+	}))
+}
@@ -6,39 +6,47 @@ import (
 )
 
 type Class struct {
-	Version                     uint32
-	AccessFlags                 uint16
-	Signature                   string
-	ThisClass                   string
-	SuperClass                  string
-	Deprecated                  bool
-	Interfaces                  []string
-	Fields                      []Field
-	Methods                     []Method
-	Attributes                  []Attribute
-	SourceFile                  string
-	SourceDebugExtension        string
-	Module                      Module
-	InnerClasses                []InnerClass
-	OuterClass                  OuterClass
-	NestHost                    string
-	RuntimeVisibleAnnotations   []Annotation
-	RuntimeInvisibleAnnotations []Annotation
-	NestMembers                 []string
-	BootstrapMethods            []BootstrapMethod
+	Version                         uint32
+	AccessFlags                     uint16
+	Signature                       string
+	ThisClass                       string
+	SuperClass                      string
+	Deprecated                      bool
+	Interfaces                      []string
+	Fields                          []Field
+	Methods                         []Method
+	Attributes                      []Attribute
+	SourceFile                      string
+	SourceDebugExtension            string
+	Module                          Module
+	InnerClasses                    []InnerClass
+	OuterClass                      OuterClass
+	NestHost                        string
+	RuntimeVisibleAnnotations       []Annotation
+	RuntimeInvisibleAnnotations     []Annotation
+	RuntimeVisibleTypeAnnotations   []TypeAnnotation
+	RuntimeInvisibleTypeAnnotations []TypeAnnotation
+	NestMembers                     []string
+	PermittedSubclasses             []string
+	// RecordComponents are the components of a record class. The Record attribute is written
+	// when they are not nil, even if there are none.
+	RecordComponents []RecordComponent
+	BootstrapMethods []BootstrapMethod
 }
 
 type Field struct {
-	Name                        string
-	AccessFlags                 uint16
-	Descriptor                  string
-	Signature                   string
-	Deprecated                  bool
-	RuntimeVisibleAnnotations   []Annotation
-	RuntimeInvisibleAnnotations []Annotation
-	Attributes                  []Attribute
-	Exceptions                  []string
-	ConstantValue               interface{}
+	Name                            string
+	AccessFlags                     uint16
+	Descriptor                      string
+	Signature                       string
+	Deprecated                      bool
+	RuntimeVisibleAnnotations       []Annotation
+	RuntimeInvisibleAnnotations     []Annotation
+	RuntimeVisibleTypeAnnotations   []TypeAnnotation
+	RuntimeInvisibleTypeAnnotations []TypeAnnotation
+	Attributes                      []Attribute
+	Exceptions                      []string
+	ConstantValue                   interface{}
 }
 
 type Method struct {
@@ -50,6 +58,8 @@ type Method struct {
 	AnnotationDefault                    ElementValue
 	RuntimeVisibleAnnotations            []Annotation
 	RuntimeInvisibleAnnotations          []Annotation
+	RuntimeVisibleTypeAnnotations        []TypeAnnotation
+	RuntimeInvisibleTypeAnnotations      []TypeAnnotation
 	RuntimeVisibleParameterAnnotations   []ParameterAnnotation
 	RuntimeInvisibleParameterAnnotations []ParameterAnnotation
 	Attributes                           []Attribute
@@ -58,6 +68,17 @@ type Method struct {
 	Code                                 MethodCode
 }
 
+type RecordComponent struct {
+	Name                            string
+	Descriptor                      string
+	Signature                       string
+	RuntimeVisibleAnnotations       []Annotation
+	RuntimeInvisibleAnnotations     []Annotation
+	RuntimeVisibleTypeAnnotations   []TypeAnnotation
+	RuntimeInvisibleTypeAnnotations []TypeAnnotation
+	Attributes                      []Attribute
+}
+
 type InnerClass struct {
 	Name        string
 	OuterName   string
@@ -108,9 +129,6 @@ type Attribute struct {
 	Content []byte
 }
 
-type TypePath struct {
-}
-
 type Handle struct {
 	Tag         uint8
 	Owner       string
@@ -124,6 +142,27 @@ type Annotation struct {
 	ElementPairs []ElementPair
 }
 
+// TypeAnnotation is an annotation on the part of a type designated by a type reference,
+// made with the New*Reference functions, and a type path.
+type TypeAnnotation struct {
+	TypeRef  int
+	TypePath TypePath
+	Annotation
+}
+
+// CodeTypeAnnotation is a type annotation on a type used in the code of a method.
+type CodeTypeAnnotation struct {
+	TypeAnnotation
+	// Instruction is the annotated instruction, for the type references from TYPE_REF_INSTANCEOF
+	// to TYPE_REF_METHOD_REFERENCE_TYPE_ARGUMENT.
+	Instruction Instruction
+	// Start, End and Index are the ranges of the annotated variable, for the TYPE_REF_LOCAL_VARIABLE
+	// and TYPE_REF_RESOURCE_VARIABLE type references.
+	Start []*Label
+	End   []*Label
+	Index []int
+}
+
 type ParameterAnnotation struct {
 	Annotations []Annotation
 }
@@ -134,35 +173,57 @@ type MethodParameter struct {
 }
 
 type MethodCode struct {
-	MaxStack         uint16
-	MaxLocal         uint16
-	InstructionCount uint32
-	Instructions     []Instruction
-	ExceptionCount   uint16
-	ExceptionTable   []Exception
-	AttributeCount   uint16
-	Attributes       []Attribute
-}
-
-type Instruction interface {
-	OpCode() uint8
+	MaxStack       uint16
+	MaxLocal       uint16
+	Instructions   []Instruction
+	ExceptionTable []Exception
+	LocalVariables []LocalVariable
+	// The type annotations on the instructions, exception handlers and local variables.
+	RuntimeVisibleTypeAnnotations   []CodeTypeAnnotation
+	RuntimeInvisibleTypeAnnotations []CodeTypeAnnotation
+	Attributes                      []Attribute
+	// offsets are the offsets of the instructions read from a class file, and the code length.
+	offsets []int
+	read    []Instruction
 }
 
-type CodeInstruction struct {
-	opCode uint8
-}
-
-func (c CodeInstruction) OpCode() uint8 {
-	return c.OpCode()
+// Offsets returns the bytecode offsets of the instructions, a pseudo instruction having the
+// offset of the instruction that follows it, followed by the length of the code. For the code
+// read from a class file, as long as its instructions are unchanged, these are the offsets of
+// the class file. Otherwise they are the offsets at which the Writer encodes the instructions,
+// as returned by InstructionOffsets.
+func (c *MethodCode) Offsets() []int {
+	if c.offsets != nil && len(c.read) == len(c.Instructions) {
+		unchanged := true
+		for i, instruction := range c.Instructions {
+			if instruction != c.read[i] {
+				unchanged = false
+				break
+			}
+		}
+		if unchanged {
+			return append([]int(nil), c.offsets...)
+		}
+	}
+	return InstructionOffsets(append(c.Instructions[:len(c.Instructions):len(c.Instructions)], NewLabel()))
 }
 
 type Exception struct {
-	StartPC   uint32
-	EndPC     uint32
-	HandlerPC uint32
+	Start     *Label
+	End       *Label
+	Handler   *Label
 	CatchType string
 }
 
+type LocalVariable struct {
+	Name       string
+	Descriptor string
+	Signature  string
+	Start      *Label
+	End        *Label
+	Index      int
+}
+
 type ConstantReference struct {
 	Tag         uint8
 	Owner       string
@@ -201,3 +262,97 @@ type Type struct {
 	begin int
 	end   int
 }
+
+// NewType returns the Type corresponding to the given field or method descriptor.
+func NewType(descriptor string) Type {
+	if len(descriptor) == 0 {
+		return Type{sort: data.TYPE_SORT_VOID, value: descriptor}
+	}
+	sort := data.TYPE_SORT_OBJECT
+	switch descriptor[0] {
+	case 'V':
+		sort = data.TYPE_SORT_VOID
+	case 'Z':
+		sort = data.TYPE_SORT_BOOLEAN
+	case 'C':
+		sort = data.TYPE_SORT_HAR
+	case 'B':
+		sort = data.TYPE_SORT_BYTE
+	case 'S':
+		sort = data.TYPE_SORT_SHORT
+	case 'I':
+		sort = data.TYPE_SORT_INT
+	case 'F':
+		sort = data.TYPE_SORT_FLOAT
+	case 'J':
+		sort = data.TYPE_SORT_LONG
+	case 'D':
+		sort = data.TYPE_SORT_DOUBLE
+	case '[':
+		sort = data.TYPE_SORT_ARRAY
+	case '(':
+		sort = data.TYPE_SORT_METHOD
+	case 'L':
+		return NewObjectType(descriptor[1 : len(descriptor)-1])
+	}
+	return Type{sort: sort, value: descriptor, begin: 0, end: len(descriptor)}
+}
+
+// Sort returns the sort of this type, one of the data.TYPE_SORT_* constants.
+func (t Type) Sort() int {
+	return t.sort
+}
+
+// Descriptor returns the field or method descriptor of this type.
+func (t Type) Descriptor() string {
+	if t.sort == data.TYPE_SORT_INTERNAL {
+		return "L" + t.value[t.begin:t.end] + ";"
+	}
+	return t.value[t.begin:t.end]
+}
+
+// InternalName returns the internal name of the class corresponding to this object or array
+// type. For other types it returns the descriptor.
+func (t Type) InternalName() string {
+	return t.value[t.begin:t.end]
+}
+
+func (t Type) String() string {
+	return t.Descriptor()
+}
+
+// ArgumentTypes returns the argument types of this method type.
+func (t Type) ArgumentTypes() []Type {
+	descriptor := t.Descriptor()
+	types := make([]Type, 0)
+	for i := 1; i < len(descriptor) && descriptor[i] != ')'; {
+		end := i
+		for descriptor[end] == '[' {
+			end++
+		}
+		if descriptor[end] == 'L' {
+			end = strings.IndexByte(descriptor[end:], ';') + end
+		}
+		types = append(types, NewType(descriptor[i:end+1]))
+		i = end + 1
+	}
+	return types
+}
+
+// ReturnType returns the return type of this method type.
+func (t Type) ReturnType() Type {
+	descriptor := t.Descriptor()
+	return NewType(descriptor[strings.IndexByte(descriptor, ')')+1:])
+}
+
+// Size returns the number of local variable or operand stack slots used by values of this type.
+func (t Type) Size() int {
+	switch t.sort {
+	case data.TYPE_SORT_VOID:
+		return 0
+	case data.TYPE_SORT_LONG, data.TYPE_SORT_DOUBLE:
+		return 2
+	default:
+		return 1
+	}
+}
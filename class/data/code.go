@@ -0,0 +1,183 @@
+package data
+
+import "encoding/binary"
+
+// CodeData is the content of a Code attribute.
+type CodeData struct {
+	MaxStack             uint16
+	MaxLocals            uint16
+	CodeLength           uint32
+	Code                 []byte
+	ExceptionTableLength uint16
+	ExceptionTable       []ExceptionData
+	AttributesCount      uint16
+	Attributes           []AttributeData
+}
+
+type ExceptionData struct {
+	StartPC   uint16
+	EndPC     uint16
+	HandlerPC uint16
+	CatchType uint16
+}
+
+// InstructionData is a decoded bytecode instruction. Index is the constant pool index or the
+// local variable index used by the instruction, Value is its signed immediate operand or branch
+// offset, and Count is the dimension count of MULTIANEWARRAY or the argument count of
+// INVOKEINTERFACE. The switch instructions store their default offset in Value, and their keys
+// and offsets in Keys and Offsets.
+type InstructionData struct {
+	Offset  int
+	OpCode  uint8
+	Wide    bool
+	Index   uint16
+	Value   int32
+	Count   uint8
+	Low     int32
+	High    int32
+	Keys    []int32
+	Offsets []int32
+	Length  int
+}
+
+// Code returns the content of a Code attribute.
+func (v AttributeValue) Code() CodeData {
+	reader := v.Reader()
+	code := CodeData{}
+	code.MaxStack = reader.ReadUint16()
+	code.MaxLocals = reader.ReadUint16()
+	code.CodeLength = reader.ReadUint32()
+	code.Code = reader.ReadBytes(code.CodeLength)
+	code.ExceptionTableLength = reader.ReadUint16()
+	code.ExceptionTable = make([]ExceptionData, code.ExceptionTableLength)
+	for i := range code.ExceptionTable {
+		code.ExceptionTable[i] = ExceptionData{
+			StartPC:   reader.ReadUint16(),
+			EndPC:     reader.ReadUint16(),
+			HandlerPC: reader.ReadUint16(),
+			CatchType: reader.ReadUint16(),
+		}
+	}
+	code.AttributesCount = reader.ReadUint16()
+	code.Attributes = make([]AttributeData, code.AttributesCount)
+	for i := range code.Attributes {
+		attribute := AttributeData{NameIndex: reader.ReadUint16(), Length: reader.ReadUint32()}
+		attribute.Value = reader.ReadBytes(attribute.Length)
+		code.Attributes[i] = attribute
+	}
+	return code
+}
+
+// Instructions decodes the bytecode instructions of a method.
+func (c CodeData) Instructions() []InstructionData {
+	code := c.Code
+	instructions := make([]InstructionData, 0)
+	for offset := 0; offset < len(code); {
+		instruction := decodeInstruction(code, offset)
+		instructions = append(instructions, instruction)
+		offset += instruction.Length
+	}
+	return instructions
+}
+
+func decodeInstruction(code []byte, offset int) InstructionData {
+	opCode := code[offset]
+	instruction := InstructionData{Offset: offset, OpCode: opCode, Length: 1}
+	switch {
+	case opCode == BIPUSH:
+		instruction.Value = int32(int8(code[offset+1]))
+		instruction.Length = 2
+	case opCode == SIPUSH:
+		instruction.Value = int32(readInt16(code, offset+1))
+		instruction.Length = 3
+	case opCode == NEWARRAY:
+		instruction.Value = int32(code[offset+1])
+		instruction.Length = 2
+	case opCode == LDC:
+		instruction.Index = uint16(code[offset+1])
+		instruction.Length = 2
+	case opCode == LDC_W || opCode == LDC2_W:
+		instruction.Index = readUint16(code, offset+1)
+		instruction.Length = 3
+	case opCode >= ILOAD && opCode <= ALOAD, opCode >= ISTORE && opCode <= ASTORE, opCode == RET:
+		instruction.Index = uint16(code[offset+1])
+		instruction.Length = 2
+	case opCode >= ILOAD_0 && opCode < IALOAD:
+		instruction.Index = uint16(opCode-ILOAD_0) % 4
+	case opCode >= ISTORE_0 && opCode < IASTORE:
+		instruction.Index = uint16(opCode-ISTORE_0) % 4
+	case opCode == IINC:
+		instruction.Index = uint16(code[offset+1])
+		instruction.Value = int32(int8(code[offset+2]))
+		instruction.Length = 3
+	case opCode >= IFEQ && opCode <= JSR, opCode == IFNULL, opCode == IFNONNULL:
+		instruction.Value = int32(readInt16(code, offset+1))
+		instruction.Length = 3
+	case opCode == GOTO_W || opCode == JSR_W:
+		instruction.Value = readInt32(code, offset+1)
+		instruction.Length = 5
+	case opCode == TABLESWITCH:
+		pos := offset + 4 - offset&3
+		instruction.Value = readInt32(code, pos)
+		instruction.Low = readInt32(code, pos+4)
+		instruction.High = readInt32(code, pos+8)
+		pos += 12
+		count := int(instruction.High - instruction.Low + 1)
+		instruction.Offsets = make([]int32, count)
+		for i := 0; i < count; i++ {
+			instruction.Offsets[i] = readInt32(code, pos)
+			pos += 4
+		}
+		instruction.Length = pos - offset
+	case opCode == LOOKUPSWITCH:
+		pos := offset + 4 - offset&3
+		instruction.Value = readInt32(code, pos)
+		count := int(readInt32(code, pos+4))
+		pos += 8
+		instruction.Keys = make([]int32, count)
+		instruction.Offsets = make([]int32, count)
+		for i := 0; i < count; i++ {
+			instruction.Keys[i] = readInt32(code, pos)
+			instruction.Offsets[i] = readInt32(code, pos+4)
+			pos += 8
+		}
+		instruction.Length = pos - offset
+	case opCode >= GETSTATIC && opCode <= INVOKESTATIC, opCode == NEW, opCode == ANEWARRAY,
+		opCode == CHECKCAST, opCode == INSTANCEOF:
+		instruction.Index = readUint16(code, offset+1)
+		instruction.Length = 3
+	case opCode == INVOKEINTERFACE:
+		instruction.Index = readUint16(code, offset+1)
+		instruction.Count = code[offset+3]
+		instruction.Length = 5
+	case opCode == INVOKEDYNAMIC:
+		instruction.Index = readUint16(code, offset+1)
+		instruction.Length = 5
+	case opCode == MULTIANEWARRAY:
+		instruction.Index = readUint16(code, offset+1)
+		instruction.Count = code[offset+3]
+		instruction.Length = 4
+	case opCode == WIDE:
+		instruction.OpCode = code[offset+1]
+		instruction.Wide = true
+		instruction.Index = readUint16(code, offset+2)
+		instruction.Length = 4
+		if instruction.OpCode == IINC {
+			instruction.Value = int32(readInt16(code, offset+4))
+			instruction.Length = 6
+		}
+	}
+	return instruction
+}
+
+func readUint16(code []byte, offset int) uint16 {
+	return binary.BigEndian.Uint16(code[offset:])
+}
+
+func readInt16(code []byte, offset int) int16 {
+	return int16(binary.BigEndian.Uint16(code[offset:]))
+}
+
+func readInt32(code []byte, offset int) int32 {
+	return int32(binary.BigEndian.Uint32(code[offset:]))
+}
@@ -30,7 +30,7 @@ const MODULE_PACKAGES = "ModulePackages"
 const MODULE_MAIN_CLASS = "ModuleMainClass"
 const NEST_HOST = "NestHost"
 const NEST_MEMBERS = "NestMembers"
-const PERMITTED_SUBTYPES = "PermittedSubtypes"
+const PERMITTED_SUBCLASSES = "PermittedSubclasses"
 const RECORD = "Record"
 
 const (
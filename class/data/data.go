@@ -44,6 +44,22 @@ type ClassData struct {
 	Attributes      []AttributeData
 }
 
+// Accept makes the given visitor visit this class data.
+func (d *ClassData) Accept(visitor Visitor) {
+	if visitor != nil {
+		visitor.VisitStart()
+		visitor.VisitMagicNumber(d.MagicNumber)
+		visitor.VisitVersion(d.MinorVersion, d.MajorVersion)
+		visitor.VisitConstants(d.ConstantPool)
+		visitor.Visit(d.ThisClass, d.SuperClass, d.AccessFlags)
+		visitor.VisitInterfaces(d.Interfaces)
+		visitor.VisitFields(d.Fields)
+		visitor.VisitMethods(d.Methods)
+		visitor.VisitAttributes(d.Attributes)
+		visitor.VisitEnd()
+	}
+}
+
 type ConstantReferenceData interface {
 	ConstantData
 	OwnerIndex() uint16
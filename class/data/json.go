@@ -0,0 +1,561 @@
+package data
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tk103331/clazz/common"
+)
+
+// The kinds of elements with access flags, which give the names of their flags.
+const (
+	ACCESS_CLASS = iota
+	ACCESS_FIELD
+	ACCESS_METHOD
+	ACCESS_INNER_CLASS
+	ACCESS_PARAMETER
+	ACCESS_MODULE
+	ACCESS_MODULE_REQUIRES
+	ACCESS_MODULE_EXPORTS
+)
+
+type accessFlagName struct {
+	mask uint16
+	name string
+}
+
+var accessFlagNames = map[int][]accessFlagName{
+	ACCESS_CLASS: {{ACC_PUBLIC, "ACC_PUBLIC"}, {ACC_FINAL, "ACC_FINAL"}, {ACC_SUPER, "ACC_SUPER"},
+		{ACC_INTERFACE, "ACC_INTERFACE"}, {ACC_ABSTRACT, "ACC_ABSTRACT"}, {ACC_SYNTHETIC, "ACC_SYNTHETIC"},
+		{ACC_ANNOTATION, "ACC_ANNOTATION"}, {ACC_ENUM, "ACC_ENUM"}, {ACC_MODULE, "ACC_MODULE"}},
+	ACCESS_FIELD: {{ACC_PUBLIC, "ACC_PUBLIC"}, {ACC_PRIVATE, "ACC_PRIVATE"}, {ACC_PROTECTED, "ACC_PROTECTED"},
+		{ACC_STATIC, "ACC_STATIC"}, {ACC_FINAL, "ACC_FINAL"}, {ACC_VOLATILE, "ACC_VOLATILE"},
+		{ACC_TRANSIENT, "ACC_TRANSIENT"}, {ACC_SYNTHETIC, "ACC_SYNTHETIC"}, {ACC_ENUM, "ACC_ENUM"}},
+	ACCESS_METHOD: {{ACC_PUBLIC, "ACC_PUBLIC"}, {ACC_PRIVATE, "ACC_PRIVATE"}, {ACC_PROTECTED, "ACC_PROTECTED"},
+		{ACC_STATIC, "ACC_STATIC"}, {ACC_FINAL, "ACC_FINAL"}, {ACC_SYNCHRONIZED, "ACC_SYNCHRONIZED"},
+		{ACC_BRIDGE, "ACC_BRIDGE"}, {ACC_VARARGS, "ACC_VARARGS"}, {ACC_NATIVE, "ACC_NATIVE"},
+		{ACC_ABSTRACT, "ACC_ABSTRACT"}, {ACC_STRICT, "ACC_STRICT"}, {ACC_SYNTHETIC, "ACC_SYNTHETIC"}},
+	ACCESS_INNER_CLASS: {{ACC_PUBLIC, "ACC_PUBLIC"}, {ACC_PRIVATE, "ACC_PRIVATE"},
+		{ACC_PROTECTED, "ACC_PROTECTED"}, {ACC_STATIC, "ACC_STATIC"}, {ACC_FINAL, "ACC_FINAL"},
+		{ACC_INTERFACE, "ACC_INTERFACE"}, {ACC_ABSTRACT, "ACC_ABSTRACT"}, {ACC_SYNTHETIC, "ACC_SYNTHETIC"},
+		{ACC_ANNOTATION, "ACC_ANNOTATION"}, {ACC_ENUM, "ACC_ENUM"}},
+	ACCESS_PARAMETER: {{ACC_FINAL, "ACC_FINAL"}, {ACC_SYNTHETIC, "ACC_SYNTHETIC"}, {ACC_MANDATED, "ACC_MANDATED"}},
+	ACCESS_MODULE:    {{ACC_OPEN, "ACC_OPEN"}, {ACC_SYNTHETIC, "ACC_SYNTHETIC"}, {ACC_MANDATED, "ACC_MANDATED"}},
+	ACCESS_MODULE_REQUIRES: {{ACC_TRANSITIVE, "ACC_TRANSITIVE"}, {ACC_STATIC_PHASE, "ACC_STATIC_PHASE"},
+		{ACC_SYNTHETIC, "ACC_SYNTHETIC"}, {ACC_MANDATED, "ACC_MANDATED"}},
+	ACCESS_MODULE_EXPORTS: {{ACC_SYNTHETIC, "ACC_SYNTHETIC"}, {ACC_MANDATED, "ACC_MANDATED"}},
+}
+
+// AccessFlagNames returns the ACC_* names of the access flags of a kind of element, one of the
+// ACCESS_* constants. The flags without name for this kind are returned in hexadecimal.
+func AccessFlagNames(access uint16, kind int) []string {
+	names := make([]string, 0)
+	for _, flag := range accessFlagNames[kind] {
+		if access&flag.mask != 0 {
+			names = append(names, flag.name)
+			access &^= flag.mask
+		}
+	}
+	for mask := uint16(1); mask != 0; mask <<= 1 {
+		if access&mask != 0 {
+			names = append(names, fmt.Sprintf("0x%04x", mask))
+		}
+	}
+	return names
+}
+
+// ParseAccessFlags returns the access flags with the given names, as returned by
+// AccessFlagNames.
+func ParseAccessFlags(names []string, kind int) (uint16, error) {
+	access := uint16(0)
+next:
+	for _, name := range names {
+		for _, flag := range accessFlagNames[kind] {
+			if flag.name == name {
+				access |= flag.mask
+				continue next
+			}
+		}
+		value, err := strconv.ParseUint(name, 0, 16)
+		if err != nil || !strings.HasPrefix(name, "0x") {
+			return 0, fmt.Errorf("unknown access flag %q", name)
+		}
+		access |= uint16(value)
+	}
+	return access, nil
+}
+
+var constantTagNames = map[uint8]string{
+	TAG_CONSTANT_UTF8:                "Utf8",
+	TAG_CONSTANT_INTEGER:             "Integer",
+	TAG_CONSTANT_FLOAT:               "Float",
+	TAG_CONSTANT_LONG:                "Long",
+	TAG_CONSTANT_DOUBLE:              "Double",
+	TAG_CONSTANT_CLASS:               "Class",
+	TAG_CONSTANT_STRING:              "String",
+	TAG_CONSTANT_FIELDREF:            "Fieldref",
+	TAG_CONSTANT_METHODREF:           "Methodref",
+	TAG_CONSTANT_INTERFACE_METHODREF: "InterfaceMethodref",
+	TAG_CONSTANT_NAME_AND_TYPE:       "NameAndType",
+	TAG_CONSTANT_METHOD_HANDLE:       "MethodHandle",
+	TAG_CONSTANT_METHOD_TYPE:         "MethodType",
+	TAG_CONSTANT_DYNAMIC:             "Dynamic",
+	TAG_CONSTANT_INVOKE_DYNAMIC:      "InvokeDynamic",
+	TAG_CONSTANT_MODULE:              "Module",
+	TAG_CONSTANT_PACKAGE:             "Package",
+}
+
+// ConstantTagName returns the name of a constant pool tag, as in the CONSTANT_Utf8_info
+// structure names without the prefix and suffix ("Utf8", "Methodref"...).
+func ConstantTagName(tag uint8) string {
+	return constantTagNames[tag]
+}
+
+type classJSON struct {
+	Magic           uint32          `json:"magic"`
+	MinorVersion    uint16          `json:"minor_version"`
+	MajorVersion    uint16          `json:"major_version"`
+	ConstantPool    []*constantJSON `json:"constant_pool"`
+	AccessFlags     *uint16         `json:"access_flags"`
+	AccessFlagNames []string        `json:"access_flag_names"`
+	ThisClass       uint16          `json:"this_class"`
+	SuperClass      uint16          `json:"super_class"`
+	Interfaces      []uint16        `json:"interfaces"`
+	Fields          []memberJSON    `json:"fields"`
+	Methods         []memberJSON    `json:"methods"`
+	Attributes      []attributeJSON `json:"attributes"`
+}
+
+// constantJSON is a constant pool entry. Tag is the discriminator of the entry, which gives the
+// fields that are set.
+type constantJSON struct {
+	Tag                      string          `json:"tag"`
+	Value                    json.RawMessage `json:"value,omitempty"`
+	Bytes                    string          `json:"bytes,omitempty"`
+	NameIndex                *uint16         `json:"name_index,omitempty"`
+	StringIndex              *uint16         `json:"string_index,omitempty"`
+	ClassIndex               *uint16         `json:"class_index,omitempty"`
+	NameAndTypeIndex         *uint16         `json:"name_and_type_index,omitempty"`
+	DescriptorIndex          *uint16         `json:"descriptor_index,omitempty"`
+	ReferenceKind            *uint8          `json:"reference_kind,omitempty"`
+	ReferenceIndex           *uint16         `json:"reference_index,omitempty"`
+	BootstrapMethodAttrIndex *uint16         `json:"bootstrap_method_attr_index,omitempty"`
+}
+
+type memberJSON struct {
+	AccessFlags     *uint16         `json:"access_flags"`
+	AccessFlagNames []string        `json:"access_flag_names"`
+	NameIndex       uint16          `json:"name_index"`
+	DescriptorIndex uint16          `json:"descriptor_index"`
+	Attributes      []attributeJSON `json:"attributes"`
+}
+
+// attributeJSON is an attribute, with its content in hexadecimal in Info, or decoded in Code for
+// the Code attributes. Name is only informative.
+type attributeJSON struct {
+	NameIndex uint16    `json:"name_index"`
+	Name      string    `json:"name,omitempty"`
+	Info      *string   `json:"info,omitempty"`
+	Code      *codeJSON `json:"code,omitempty"`
+}
+
+type codeJSON struct {
+	MaxStack       uint16          `json:"max_stack"`
+	MaxLocals      uint16          `json:"max_locals"`
+	Code           string          `json:"code"`
+	ExceptionTable []exceptionJSON `json:"exception_table"`
+	Attributes     []attributeJSON `json:"attributes"`
+}
+
+type exceptionJSON struct {
+	StartPC   uint16 `json:"start_pc"`
+	EndPC     uint16 `json:"end_pc"`
+	HandlerPC uint16 `json:"handler_pc"`
+	CatchType uint16 `json:"catch_type"`
+}
+
+// MarshalJSON encodes the class data in JSON. The constant pool entries are encoded at their
+// index, with null for the unused entries, and the access flags are encoded both as a number and
+// as names.
+func (d ClassData) MarshalJSON() ([]byte, error) {
+	class := classJSON{
+		Magic:           d.MagicNumber,
+		MinorVersion:    d.MinorVersion,
+		MajorVersion:    d.MajorVersion,
+		ConstantPool:    make([]*constantJSON, len(d.ConstantPool)),
+		AccessFlags:     &d.AccessFlags,
+		AccessFlagNames: AccessFlagNames(d.AccessFlags, ACCESS_CLASS),
+		ThisClass:       d.ThisClass,
+		SuperClass:      d.SuperClass,
+		Interfaces:      make([]uint16, len(d.Interfaces)),
+	}
+	for i, constant := range d.ConstantPool {
+		if constant != nil {
+			class.ConstantPool[i] = encodeConstant(constant)
+		}
+	}
+	for i, itf := range d.Interfaces {
+		class.Interfaces[i] = itf.Index
+	}
+	class.Fields = make([]memberJSON, len(d.Fields))
+	for i, field := range d.Fields {
+		class.Fields[i] = d.encodeMember(field.AccessFlags, field.NameIndex, field.DescriptorIndex, field.Attributes, ACCESS_FIELD)
+	}
+	class.Methods = make([]memberJSON, len(d.Methods))
+	for i, method := range d.Methods {
+		class.Methods[i] = d.encodeMember(method.AccessFlags, method.NameIndex, method.DescriptorIndex, method.Attributes, ACCESS_METHOD)
+	}
+	class.Attributes = d.encodeAttributes(d.Attributes)
+	return json.Marshal(class)
+}
+
+// UnmarshalJSON decodes class data encoded by MarshalJSON. The counts and lengths are computed
+// from the decoded values. The access flags are decoded from their names if their numeric value
+// is missing.
+func (d *ClassData) UnmarshalJSON(b []byte) error {
+	class := classJSON{}
+	if err := json.Unmarshal(b, &class); err != nil {
+		return err
+	}
+	result := ClassData{
+		MagicNumber:  class.Magic,
+		MinorVersion: class.MinorVersion,
+		MajorVersion: class.MajorVersion,
+		ThisClass:    class.ThisClass,
+		SuperClass:   class.SuperClass,
+	}
+	if result.MagicNumber == 0 {
+		result.MagicNumber = MAGIC_NUMBER
+	}
+	var err error
+	if result.AccessFlags, err = decodeAccessFlags(class.AccessFlags, class.AccessFlagNames, ACCESS_CLASS); err != nil {
+		return err
+	}
+	result.ConstantPool = make([]ConstantData, len(class.ConstantPool))
+	for i, constant := range class.ConstantPool {
+		if constant == nil {
+			continue
+		}
+		if result.ConstantPool[i], err = decodeConstant(constant); err != nil {
+			return fmt.Errorf("constant #%d: %v", i, err)
+		}
+	}
+	if len(result.ConstantPool) == 0 {
+		result.ConstantPool = make([]ConstantData, 1)
+	}
+	result.ConstantCount = uint16(len(result.ConstantPool))
+	result.Interfaces = make([]InterfaceData, len(class.Interfaces))
+	for i, index := range class.Interfaces {
+		result.Interfaces[i] = InterfaceData{Index: index}
+	}
+	result.InterfacesCount = uint16(len(result.Interfaces))
+	result.Fields = make([]FieldData, len(class.Fields))
+	for i, member := range class.Fields {
+		field := FieldData{NameIndex: member.NameIndex, DescriptorIndex: member.DescriptorIndex}
+		if field.AccessFlags, err = decodeAccessFlags(member.AccessFlags, member.AccessFlagNames, ACCESS_FIELD); err != nil {
+			return err
+		}
+		if field.Attributes, err = decodeAttributes(member.Attributes); err != nil {
+			return err
+		}
+		field.AttributesCount = uint16(len(field.Attributes))
+		result.Fields[i] = field
+	}
+	result.FieldsCount = uint16(len(result.Fields))
+	result.Methods = make([]MethodData, len(class.Methods))
+	for i, member := range class.Methods {
+		method := MethodData{NameIndex: member.NameIndex, DescriptorIndex: member.DescriptorIndex}
+		if method.AccessFlags, err = decodeAccessFlags(member.AccessFlags, member.AccessFlagNames, ACCESS_METHOD); err != nil {
+			return err
+		}
+		if method.Attributes, err = decodeAttributes(member.Attributes); err != nil {
+			return err
+		}
+		method.AttributesCount = uint16(len(method.Attributes))
+		result.Methods[i] = method
+	}
+	result.MethodsCount = uint16(len(result.Methods))
+	if result.Attributes, err = decodeAttributes(class.Attributes); err != nil {
+		return err
+	}
+	result.AttributesCount = uint16(len(result.Attributes))
+	*d = result
+	return nil
+}
+
+func decodeAccessFlags(access *uint16, names []string, kind int) (uint16, error) {
+	if access != nil {
+		return *access, nil
+	}
+	return ParseAccessFlags(names, kind)
+}
+
+func (d ClassData) encodeMember(access uint16, nameIndex uint16, descriptorIndex uint16, attributes []AttributeData, kind int) memberJSON {
+	return memberJSON{
+		AccessFlags:     &access,
+		AccessFlagNames: AccessFlagNames(access, kind),
+		NameIndex:       nameIndex,
+		DescriptorIndex: descriptorIndex,
+		Attributes:      d.encodeAttributes(attributes),
+	}
+}
+
+func (d ClassData) encodeAttributes(attributes []AttributeData) []attributeJSON {
+	result := make([]attributeJSON, len(attributes))
+	for i, attribute := range attributes {
+		result[i] = attributeJSON{NameIndex: attribute.NameIndex}
+		if int(attribute.NameIndex) < len(d.ConstantPool) {
+			if name, ok := d.ConstantPool[attribute.NameIndex].(ConstantUTF8Data); ok {
+				result[i].Name = name.UTF8Value
+			}
+		}
+		if result[i].Name == CODE {
+			code := attribute.Value.Code()
+			result[i].Code = &codeJSON{
+				MaxStack:       code.MaxStack,
+				MaxLocals:      code.MaxLocals,
+				Code:           hex.EncodeToString(code.Code),
+				ExceptionTable: make([]exceptionJSON, len(code.ExceptionTable)),
+				Attributes:     d.encodeAttributes(code.Attributes),
+			}
+			for j, exception := range code.ExceptionTable {
+				result[i].Code.ExceptionTable[j] = exceptionJSON(exception)
+			}
+		} else {
+			info := hex.EncodeToString(attribute.Value)
+			result[i].Info = &info
+		}
+	}
+	return result
+}
+
+func decodeAttributes(attributes []attributeJSON) ([]AttributeData, error) {
+	result := make([]AttributeData, len(attributes))
+	for i, attribute := range attributes {
+		var value []byte
+		var err error
+		switch {
+		case attribute.Code != nil:
+			code := CodeData{MaxStack: attribute.Code.MaxStack, MaxLocals: attribute.Code.MaxLocals}
+			if code.Code, err = hex.DecodeString(attribute.Code.Code); err != nil {
+				return nil, fmt.Errorf("attribute %s: %v", attribute.Name, err)
+			}
+			code.ExceptionTable = make([]ExceptionData, len(attribute.Code.ExceptionTable))
+			for j, exception := range attribute.Code.ExceptionTable {
+				code.ExceptionTable[j] = ExceptionData(exception)
+			}
+			if code.Attributes, err = decodeAttributes(attribute.Code.Attributes); err != nil {
+				return nil, err
+			}
+			value = code.Value()
+		case attribute.Info != nil:
+			if value, err = hex.DecodeString(*attribute.Info); err != nil {
+				return nil, fmt.Errorf("attribute %s: %v", attribute.Name, err)
+			}
+		}
+		result[i] = AttributeData{NameIndex: attribute.NameIndex, Length: uint32(len(value)), Value: value}
+	}
+	return result, nil
+}
+
+// Value returns the content of a Code attribute with this code.
+func (c CodeData) Value() AttributeValue {
+	var buffer bytes.Buffer
+	writer := common.NewWriter(&buffer)
+	writer.WriteUint16(c.MaxStack)
+	writer.WriteUint16(c.MaxLocals)
+	writer.WriteUint32(uint32(len(c.Code)))
+	writer.WriteBytes(c.Code)
+	writer.WriteUint16(uint16(len(c.ExceptionTable)))
+	for _, exception := range c.ExceptionTable {
+		writer.WriteUint16(exception.StartPC)
+		writer.WriteUint16(exception.EndPC)
+		writer.WriteUint16(exception.HandlerPC)
+		writer.WriteUint16(exception.CatchType)
+	}
+	writer.WriteUint16(uint16(len(c.Attributes)))
+	for _, attribute := range c.Attributes {
+		writer.WriteUint16(attribute.NameIndex)
+		writer.WriteUint32(uint32(len(attribute.Value)))
+		writer.WriteBytes(attribute.Value)
+	}
+	writer.Flush()
+	return buffer.Bytes()
+}
+
+func encodeConstant(constant ConstantData) *constantJSON {
+	result := &constantJSON{Tag: ConstantTagName(constant.Tag())}
+	switch c := constant.(type) {
+	case ConstantUTF8Data:
+		if utf8.ValidString(c.UTF8Value) {
+			result.Value, _ = json.Marshal(c.UTF8Value)
+		} else {
+			result.Bytes = hex.EncodeToString([]byte(c.UTF8Value))
+		}
+	case ConstantIntegerData:
+		result.Value = json.RawMessage(strconv.Itoa(int(c.IntegerValue)))
+	case ConstantFloatData:
+		result.Value = encodeFloat(float64(c.FloatValue), 32)
+	case ConstantLongData:
+		result.Value = json.RawMessage(strconv.Quote(strconv.FormatInt(c.LongValue, 10)))
+	case ConstantDoubleData:
+		result.Value = encodeFloat(c.DoubleValue, 64)
+	case ConstantClassData:
+		result.NameIndex = &c.NameIndex
+	case ConstantStringData:
+		result.StringIndex = &c.ValueIndex
+	case ConstantFieldRefData:
+		result.ClassIndex, result.NameAndTypeIndex = &c.ClassIndex, &c.NameAndTypeIndex
+	case ConstantMethodRefData:
+		result.ClassIndex, result.NameAndTypeIndex = &c.ClassIndex, &c.NameAndTypeIndex
+	case ConstantInterfaceMethodRefData:
+		result.ClassIndex, result.NameAndTypeIndex = &c.ClassIndex, &c.NameAndTypeIndex
+	case ConstantNameAndTypeData:
+		result.NameIndex, result.DescriptorIndex = &c.NameIndex, &c.DescriptorIndex
+	case ConstantMethodHandleData:
+		result.ReferenceKind, result.ReferenceIndex = &c.ReferenceKind, &c.ReferenceIndex
+	case ConstantMethodTypeData:
+		result.DescriptorIndex = &c.DescriptorIndex
+	case ConstantDynamicData:
+		result.BootstrapMethodAttrIndex, result.NameAndTypeIndex = &c.BootstrapMethodIndex, &c.NameAndTypeIndex
+	case ConstantInvokeDynamicData:
+		result.BootstrapMethodAttrIndex, result.NameAndTypeIndex = &c.BootstrapMethodIndex, &c.NameAndTypeIndex
+	case ConstantModuleData:
+		result.NameIndex = &c.NameIndex
+	case ConstantPackageData:
+		result.NameIndex = &c.NameIndex
+	}
+	return result
+}
+
+func decodeConstant(constant *constantJSON) (ConstantData, error) {
+	var err error
+	u2 := func(value *uint16, name string) uint16 {
+		if value == nil {
+			if err == nil {
+				err = fmt.Errorf("missing %s in %s constant", name, constant.Tag)
+			}
+			return 0
+		}
+		return *value
+	}
+	var result ConstantData
+	switch constant.Tag {
+	case "Utf8":
+		var value string
+		if len(constant.Bytes) > 0 {
+			b, err := hex.DecodeString(constant.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			value = string(b)
+		} else if err := json.Unmarshal(constant.Value, &value); err != nil {
+			return nil, err
+		}
+		result = ConstantUTF8Data{Length: uint16(len(value)), UTF8Value: value}
+	case "Integer":
+		var value int32
+		if err := json.Unmarshal(constant.Value, &value); err != nil {
+			return nil, err
+		}
+		result = ConstantIntegerData{IntegerValue: value}
+	case "Float":
+		value, err := decodeFloat(constant.Value, 32)
+		if err != nil {
+			return nil, err
+		}
+		result = ConstantFloatData{FloatValue: float32(value)}
+	case "Long":
+		var text string
+		if err := json.Unmarshal(constant.Value, &text); err != nil {
+			text = string(constant.Value)
+		}
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		result = ConstantLongData{LongValue: value}
+	case "Double":
+		value, err := decodeFloat(constant.Value, 64)
+		if err != nil {
+			return nil, err
+		}
+		result = ConstantDoubleData{DoubleValue: value}
+	case "Class":
+		result = ConstantClassData{NameIndex: u2(constant.NameIndex, "name_index")}
+	case "String":
+		result = ConstantStringData{ValueIndex: u2(constant.StringIndex, "string_index")}
+	case "Fieldref":
+		result = ConstantFieldRefData{ClassIndex: u2(constant.ClassIndex, "class_index"),
+			NameAndTypeIndex: u2(constant.NameAndTypeIndex, "name_and_type_index")}
+	case "Methodref":
+		result = ConstantMethodRefData{ClassIndex: u2(constant.ClassIndex, "class_index"),
+			NameAndTypeIndex: u2(constant.NameAndTypeIndex, "name_and_type_index")}
+	case "InterfaceMethodref":
+		result = ConstantInterfaceMethodRefData{ClassIndex: u2(constant.ClassIndex, "class_index"),
+			NameAndTypeIndex: u2(constant.NameAndTypeIndex, "name_and_type_index")}
+	case "NameAndType":
+		result = ConstantNameAndTypeData{NameIndex: u2(constant.NameIndex, "name_index"),
+			DescriptorIndex: u2(constant.DescriptorIndex, "descriptor_index")}
+	case "MethodHandle":
+		if constant.ReferenceKind == nil {
+			return nil, fmt.Errorf("missing reference_kind in MethodHandle constant")
+		}
+		result = ConstantMethodHandleData{ReferenceKind: *constant.ReferenceKind,
+			ReferenceIndex: u2(constant.ReferenceIndex, "reference_index")}
+	case "MethodType":
+		result = ConstantMethodTypeData{DescriptorIndex: u2(constant.DescriptorIndex, "descriptor_index")}
+	case "Dynamic":
+		result = ConstantDynamicData{BootstrapMethodIndex: u2(constant.BootstrapMethodAttrIndex, "bootstrap_method_attr_index"),
+			NameAndTypeIndex: u2(constant.NameAndTypeIndex, "name_and_type_index")}
+	case "InvokeDynamic":
+		result = ConstantInvokeDynamicData{BootstrapMethodIndex: u2(constant.BootstrapMethodAttrIndex, "bootstrap_method_attr_index"),
+			NameAndTypeIndex: u2(constant.NameAndTypeIndex, "name_and_type_index")}
+	case "Module":
+		result = ConstantModuleData{NameIndex: u2(constant.NameIndex, "name_index")}
+	case "Package":
+		result = ConstantPackageData{NameIndex: u2(constant.NameIndex, "name_index")}
+	default:
+		return nil, fmt.Errorf("unknown constant tag %q", constant.Tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// encodeFloat encodes a finite value as a JSON number, and the other values as the "NaN",
+// "Infinity" and "-Infinity" strings.
+func encodeFloat(value float64, bitSize int) json.RawMessage {
+	switch {
+	case math.IsNaN(value):
+		return json.RawMessage(`"NaN"`)
+	case math.IsInf(value, 1):
+		return json.RawMessage(`"Infinity"`)
+	case math.IsInf(value, -1):
+		return json.RawMessage(`"-Infinity"`)
+	}
+	return json.RawMessage(strconv.FormatFloat(value, 'g', -1, bitSize))
+}
+
+func decodeFloat(value json.RawMessage, bitSize int) (float64, error) {
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		text = string(value)
+	}
+	switch text {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	return strconv.ParseFloat(text, bitSize)
+}
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/tools"
+)
+
+func TestJSON(t *testing.T) {
+	content, err := ioutil.ReadFile("../Hello.class")
+	tools.AssertNoErr(t, err)
+	reader := NewReader(bytes.NewReader(content))
+	tools.AssertNoErr(t, reader.Read())
+
+	b, err := json.Marshal(reader.Data())
+	tools.AssertNoErr(t, err)
+	decoded := ClassData{}
+	tools.AssertNoErr(t, json.Unmarshal(b, &decoded))
+
+	var buffer bytes.Buffer
+	writer := NewWriter(&buffer)
+	decoded.Accept(writer)
+	tools.AssertEqual(t, buffer.String(), string(content))
+}
+
+func TestAccessFlagNames(t *testing.T) {
+	names := AccessFlagNames(ACC_PUBLIC|ACC_STATIC|ACC_VARARGS, ACCESS_METHOD)
+	tools.AssertEqual(t, strings.Join(names, " "), "ACC_PUBLIC ACC_STATIC ACC_VARARGS")
+	names = AccessFlagNames(ACC_PUBLIC|ACC_NATIVE, ACCESS_FIELD)
+	tools.AssertEqual(t, strings.Join(names, " "), "ACC_PUBLIC 0x0100")
+	access, err := ParseAccessFlags(names, ACCESS_FIELD)
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, access, uint16(ACC_PUBLIC|ACC_NATIVE))
+}
@@ -37,7 +37,7 @@ const ACC_STATIC_PHASE uint16 = 0x0040 // module requires
 const ACC_VARARGS uint16 = 0x0080      // method
 const ACC_TRANSIENT uint16 = 0x0080    // field
 const ACC_NATIVE uint16 = 0x0100       // method
-const ACC_constERFACE uint16 = 0x0200  // class
+const ACC_INTERFACE uint16 = 0x0200    // class
 const ACC_ABSTRACT uint16 = 0x0400     // class, method
 const ACC_STRICT uint16 = 0x0800       // method
 const ACC_SYNTHETIC uint16 = 0x1000    // class, field, method, parameter, module *
@@ -49,166 +49,211 @@ const ACC_MODULE uint16 = 0x8000       // class
 // The JVM opcode values (with the MethodVisitor method name used to visit them in comment, and
 // where '-' means 'same method name as on the previous line').
 // See https://docs.oracle.com/javase/specs/jvms/se9/html/jvms-6.html.
-const NOP = 0                 // visitInsn
-const ACONST_NULL = 1         // -
-const ICONST_M1 = 2           // -
-const ICONST_0 = 3            // -
-const ICONST_1 = 4            // -
-const ICONST_2 = 5            // -
-const ICONST_3 = 6            // -
-const ICONST_4 = 7            // -
-const ICONST_5 = 8            // -
-const LCONST_0 = 9            // -
-const LCONST_1 = 10           // -
-const FCONST_0 = 11           // -
-const FCONST_1 = 12           // -
-const FCONST_2 = 13           // -
-const DCONST_0 = 14           // -
-const DCONST_1 = 15           // -
-const BIPUSH = 16             // visitconstInsn
-const SIPUSH = 17             // -
-const LDC = 18                // visitLdcInsn
-const ILOAD = 21              // visitVarInsn
-const LLOAD = 22              // -
-const FLOAD = 23              // -
-const DLOAD = 24              // -
-const ALOAD = 25              // -
-const IALOAD = 46             // visitInsn
-const LALOAD = 47             // -
-const FALOAD = 48             // -
-const DALOAD = 49             // -
-const AALOAD = 50             // -
-const BALOAD = 51             // -
-const CALOAD = 52             // -
-const SALOAD = 53             // -
-const ISTORE = 54             // visitVarInsn
-const LSTORE = 55             // -
-const FSTORE = 56             // -
-const DSTORE = 57             // -
-const ASTORE = 58             // -
-const IASTORE = 79            // visitInsn
-const LASTORE = 80            // -
-const FASTORE = 81            // -
-const DASTORE = 82            // -
-const AASTORE = 83            // -
-const BASTORE = 84            // -
-const CASTORE = 85            // -
-const SASTORE = 86            // -
-const POP = 87                // -
-const POP2 = 88               // -
-const DUP = 89                // -
-const DUP_X1 = 90             // -
-const DUP_X2 = 91             // -
-const DUP2 = 92               // -
-const DUP2_X1 = 93            // -
-const DUP2_X2 = 94            // -
-const SWAP = 95               // -
-const IADD = 96               // -
-const LADD = 97               // -
-const FADD = 98               // -
-const DADD = 99               // -
-const ISUB = 100              // -
-const LSUB = 101              // -
-const FSUB = 102              // -
-const DSUB = 103              // -
-const IMUL = 104              // -
-const LMUL = 105              // -
-const FMUL = 106              // -
-const DMUL = 107              // -
-const IDIV = 108              // -
-const LDIV = 109              // -
-const FDIV = 110              // -
-const DDIV = 111              // -
-const IREM = 112              // -
-const LREM = 113              // -
-const FREM = 114              // -
-const DREM = 115              // -
-const INEG = 116              // -
-const LNEG = 117              // -
-const FNEG = 118              // -
-const DNEG = 119              // -
-const ISHL = 120              // -
-const LSHL = 121              // -
-const ISHR = 122              // -
-const LSHR = 123              // -
-const IUSHR = 124             // -
-const LUSHR = 125             // -
-const IAND = 126              // -
-const LAND = 127              // -
-const IOR = 128               // -
-const LOR = 129               // -
-const IXOR = 130              // -
-const LXOR = 131              // -
-const IINC = 132              // visitIincInsn
-const I2L = 133               // visitInsn
-const I2F = 134               // -
-const I2D = 135               // -
-const L2I = 136               // -
-const L2F = 137               // -
-const L2D = 138               // -
-const F2I = 139               // -
-const F2L = 140               // -
-const F2D = 141               // -
-const D2I = 142               // -
-const D2L = 143               // -
-const D2F = 144               // -
-const I2B = 145               // -
-const I2C = 146               // -
-const I2S = 147               // -
-const LCMP = 148              // -
-const FCMPL = 149             // -
-const FCMPG = 150             // -
-const DCMPL = 151             // -
-const DCMPG = 152             // -
-const IFEQ = 153              // visitJumpInsn
-const IFNE = 154              // -
-const IFLT = 155              // -
-const IFGE = 156              // -
-const IFGT = 157              // -
-const IFLE = 158              // -
-const IF_ICMPEQ = 159         // -
-const IF_ICMPNE = 160         // -
-const IF_ICMPLT = 161         // -
-const IF_ICMPGE = 162         // -
-const IF_ICMPGT = 163         // -
-const IF_ICMPLE = 164         // -
-const IF_ACMPEQ = 165         // -
-const IF_ACMPNE = 166         // -
-const GOTO = 167              // -
-const JSR = 168               // -
-const RET = 169               // visitVarInsn
-const TABLESWITCH = 170       // visiTableSwitchInsn
-const LOOKUPSWITCH = 171      // visitLookupSwitch
-const IRETURN = 172           // visitInsn
-const LRETURN = 173           // -
-const FRETURN = 174           // -
-const DRETURN = 175           // -
-const ARETURN = 176           // -
-const RETURN = 177            // -
-const GETSTATIC = 178         // visitFieldInsn
-const PUTSTATIC = 179         // -
-const GETFIELD = 180          // -
-const PUTFIELD = 181          // -
-const INVOKEVIRTUAL = 182     // visitMethodInsn
-const INVOKESPECIAL = 183     // -
-const INVOKESTATIC = 184      // -
-const INVOKEconstERFACE = 185 // -
-const INVOKEDYNAMIC = 186     // visitInvokeDynamicInsn
-const NEW = 187               // visitTypeInsn
-const NEWARRAY = 188          // visitconstInsn
-const ANEWARRAY = 189         // visitTypeInsn
-const ARRAYLENGTH = 190       // visitInsn
-const ATHROW = 191            // -
-const CHECKCAST = 192         // visitTypeInsn
-const INSTANCEOF = 193        // -
-const MONITORENTER = 194      // visitInsn
-const MONITOREXIT = 195       // -
-const MULTIANEWARRAY = 197    // visitMultiANewArrayInsn
-const IFNULL = 198            // visitJumpInsn
-const IFNONNULL = 199         // -
+const NOP = 0               // visitInsn
+const ACONST_NULL = 1       // -
+const ICONST_M1 = 2         // -
+const ICONST_0 = 3          // -
+const ICONST_1 = 4          // -
+const ICONST_2 = 5          // -
+const ICONST_3 = 6          // -
+const ICONST_4 = 7          // -
+const ICONST_5 = 8          // -
+const LCONST_0 = 9          // -
+const LCONST_1 = 10         // -
+const FCONST_0 = 11         // -
+const FCONST_1 = 12         // -
+const FCONST_2 = 13         // -
+const DCONST_0 = 14         // -
+const DCONST_1 = 15         // -
+const BIPUSH = 16           // visitIntInsn
+const SIPUSH = 17           // -
+const LDC = 18              // visitLdcInsn
+const LDC_W = 19            // -
+const LDC2_W = 20           // -
+const ILOAD = 21            // visitVarInsn
+const LLOAD = 22            // -
+const FLOAD = 23            // -
+const DLOAD = 24            // -
+const ALOAD = 25            // -
+const ILOAD_0 = 26          // visitVarInsn, ILOAD_0 to ALOAD_3 have an implicit index
+const IALOAD = 46           // visitInsn
+const LALOAD = 47           // -
+const FALOAD = 48           // -
+const DALOAD = 49           // -
+const AALOAD = 50           // -
+const BALOAD = 51           // -
+const CALOAD = 52           // -
+const SALOAD = 53           // -
+const ISTORE = 54           // visitVarInsn
+const LSTORE = 55           // -
+const FSTORE = 56           // -
+const DSTORE = 57           // -
+const ASTORE = 58           // -
+const ISTORE_0 = 59         // visitVarInsn, ISTORE_0 to ASTORE_3 have an implicit index
+const IASTORE = 79          // visitInsn
+const LASTORE = 80          // -
+const FASTORE = 81          // -
+const DASTORE = 82          // -
+const AASTORE = 83          // -
+const BASTORE = 84          // -
+const CASTORE = 85          // -
+const SASTORE = 86          // -
+const POP = 87              // -
+const POP2 = 88             // -
+const DUP = 89              // -
+const DUP_X1 = 90           // -
+const DUP_X2 = 91           // -
+const DUP2 = 92             // -
+const DUP2_X1 = 93          // -
+const DUP2_X2 = 94          // -
+const SWAP = 95             // -
+const IADD = 96             // -
+const LADD = 97             // -
+const FADD = 98             // -
+const DADD = 99             // -
+const ISUB = 100            // -
+const LSUB = 101            // -
+const FSUB = 102            // -
+const DSUB = 103            // -
+const IMUL = 104            // -
+const LMUL = 105            // -
+const FMUL = 106            // -
+const DMUL = 107            // -
+const IDIV = 108            // -
+const LDIV = 109            // -
+const FDIV = 110            // -
+const DDIV = 111            // -
+const IREM = 112            // -
+const LREM = 113            // -
+const FREM = 114            // -
+const DREM = 115            // -
+const INEG = 116            // -
+const LNEG = 117            // -
+const FNEG = 118            // -
+const DNEG = 119            // -
+const ISHL = 120            // -
+const LSHL = 121            // -
+const ISHR = 122            // -
+const LSHR = 123            // -
+const IUSHR = 124           // -
+const LUSHR = 125           // -
+const IAND = 126            // -
+const LAND = 127            // -
+const IOR = 128             // -
+const LOR = 129             // -
+const IXOR = 130            // -
+const LXOR = 131            // -
+const IINC = 132            // visitIincInsn
+const I2L = 133             // visitInsn
+const I2F = 134             // -
+const I2D = 135             // -
+const L2I = 136             // -
+const L2F = 137             // -
+const L2D = 138             // -
+const F2I = 139             // -
+const F2L = 140             // -
+const F2D = 141             // -
+const D2I = 142             // -
+const D2L = 143             // -
+const D2F = 144             // -
+const I2B = 145             // -
+const I2C = 146             // -
+const I2S = 147             // -
+const LCMP = 148            // -
+const FCMPL = 149           // -
+const FCMPG = 150           // -
+const DCMPL = 151           // -
+const DCMPG = 152           // -
+const IFEQ = 153            // visitJumpInsn
+const IFNE = 154            // -
+const IFLT = 155            // -
+const IFGE = 156            // -
+const IFGT = 157            // -
+const IFLE = 158            // -
+const IF_ICMPEQ = 159       // -
+const IF_ICMPNE = 160       // -
+const IF_ICMPLT = 161       // -
+const IF_ICMPGE = 162       // -
+const IF_ICMPGT = 163       // -
+const IF_ICMPLE = 164       // -
+const IF_ACMPEQ = 165       // -
+const IF_ACMPNE = 166       // -
+const GOTO = 167            // -
+const JSR = 168             // -
+const RET = 169             // visitVarInsn
+const TABLESWITCH = 170     // visiTableSwitchInsn
+const LOOKUPSWITCH = 171    // visitLookupSwitch
+const IRETURN = 172         // visitInsn
+const LRETURN = 173         // -
+const FRETURN = 174         // -
+const DRETURN = 175         // -
+const ARETURN = 176         // -
+const RETURN = 177          // -
+const GETSTATIC = 178       // visitFieldInsn
+const PUTSTATIC = 179       // -
+const GETFIELD = 180        // -
+const PUTFIELD = 181        // -
+const INVOKEVIRTUAL = 182   // visitMethodInsn
+const INVOKESPECIAL = 183   // -
+const INVOKESTATIC = 184    // -
+const INVOKEINTERFACE = 185 // -
+const INVOKEDYNAMIC = 186   // visitInvokeDynamicInsn
+const NEW = 187             // visitTypeInsn
+const NEWARRAY = 188        // visitIntInsn
+const ANEWARRAY = 189       // visitTypeInsn
+const ARRAYLENGTH = 190     // visitInsn
+const ATHROW = 191          // -
+const CHECKCAST = 192       // visitTypeInsn
+const INSTANCEOF = 193      // -
+const MONITORENTER = 194    // visitInsn
+const MONITOREXIT = 195     // -
+const WIDE = 196            // prefix of the wide forms of visitVarInsn and visitIincInsn
+const MULTIANEWARRAY = 197  // visitMultiANewArrayInsn
+const IFNULL = 198          // visitJumpInsn
+const IFNONNULL = 199       // -
+const GOTO_W = 200          // visitJumpInsn, with a 32 bits offset
+const JSR_W = 201           // -
 
+// The stack map frame types, used in MethodVisitor.VisitFrame. F_NEW designates an expanded
+// frame, the other values designate the compressed frame types of the StackMapTable attribute.
+const F_NEW = -1
+const F_FULL = 0
+const F_APPEND = 1
+const F_CHOP = 2
+const F_SAME = 3
+const F_SAME1 = 4
+
+// The verification types of the primitive values in stack map frames. Reference types are
+// represented by their internal name, and uninitialized types by the Label of their NEW
+// instruction.
+const (
+	ITEM_TOP uint8 = iota
+	ITEM_INTEGER
+	ITEM_FLOAT
+	ITEM_DOUBLE
+	ITEM_LONG
+	ITEM_NULL
+	ITEM_UNINITIALIZED_THIS
+	ITEM_OBJECT
+	ITEM_UNINITIALIZED
+)
+
+// The array types of the NEWARRAY instruction.
+const (
+	T_BOOLEAN = 4
+	T_CHAR    = 5
+	T_FLOAT   = 6
+	T_DOUBLE  = 7
+	T_BYTE    = 8
+	T_SHORT   = 9
+	T_INT     = 10
+	T_LONG    = 11
+)
+
+// The reference kinds of method handles, defined in
+// https://docs.oracle.com/javase/specs/jvms/se9/html/jvms-5.html#jvms-5.4.3.5-220.
 const (
-	HANDLE_GETFIELD uint8 = iota
+	HANDLE_GETFIELD uint8 = iota + 1
 	HANDLE_GETSTATIC
 	HANDLE_PUTFIELD
 	HANDLE_PUTSTATIC
@@ -234,3 +279,39 @@ const (
 	ELEMENT_TAG_ANNOTATION uint8 = '@'
 	ELEMENT_TAG_ARRAY      uint8 = '['
 )
+
+// The target types of the type annotations, defined in
+// https://docs.oracle.com/javase/specs/jvms/se11/html/jvms-4.html#jvms-4.7.20-400. They are the
+// sorts of the type references of the visitors.
+const (
+	TYPE_REF_CLASS_TYPE_PARAMETER                 = 0x00
+	TYPE_REF_METHOD_TYPE_PARAMETER                = 0x01
+	TYPE_REF_CLASS_EXTENDS                        = 0x10
+	TYPE_REF_CLASS_TYPE_PARAMETER_BOUND           = 0x11
+	TYPE_REF_METHOD_TYPE_PARAMETER_BOUND          = 0x12
+	TYPE_REF_FIELD                                = 0x13
+	TYPE_REF_METHOD_RETURN                        = 0x14
+	TYPE_REF_METHOD_RECEIVER                      = 0x15
+	TYPE_REF_METHOD_FORMAL_PARAMETER              = 0x16
+	TYPE_REF_THROWS                               = 0x17
+	TYPE_REF_LOCAL_VARIABLE                       = 0x40
+	TYPE_REF_RESOURCE_VARIABLE                    = 0x41
+	TYPE_REF_EXCEPTION_PARAMETER                  = 0x42
+	TYPE_REF_INSTANCEOF                           = 0x43
+	TYPE_REF_NEW                                  = 0x44
+	TYPE_REF_CONSTRUCTOR_REFERENCE                = 0x45
+	TYPE_REF_METHOD_REFERENCE                     = 0x46
+	TYPE_REF_CAST                                 = 0x47
+	TYPE_REF_CONSTRUCTOR_INVOCATION_TYPE_ARGUMENT = 0x48
+	TYPE_REF_METHOD_INVOCATION_TYPE_ARGUMENT      = 0x49
+	TYPE_REF_CONSTRUCTOR_REFERENCE_TYPE_ARGUMENT  = 0x4A
+	TYPE_REF_METHOD_REFERENCE_TYPE_ARGUMENT       = 0x4B
+)
+
+// The kinds of the type path steps of the type annotations.
+const (
+	TYPE_PATH_ARRAY_ELEMENT uint8 = iota
+	TYPE_PATH_INNER_TYPE
+	TYPE_PATH_WILDCARD_BOUND
+	TYPE_PATH_TYPE_ARGUMENT
+)
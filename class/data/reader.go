@@ -1,6 +1,7 @@
 package data
 
 import (
+	"fmt"
 	"github.com/tk103331/clazz/common"
 	"io"
 )
@@ -8,6 +9,7 @@ import (
 type Reader struct {
 	reader *common.DataReader
 	data   *ClassData
+	err    error
 }
 
 func NewReader(reader io.Reader) *Reader {
@@ -15,22 +17,14 @@ func NewReader(reader io.Reader) *Reader {
 }
 
 func (r *Reader) Accept(visitor Visitor) {
-	if visitor != nil {
-		visitor.VisitStart()
-		visitor.VisitMagicNumber(r.data.MagicNumber)
-		visitor.VisitVersion(r.data.MinorVersion, r.data.MajorVersion)
-		visitor.VisitConstants(r.data.ConstantPool)
-		visitor.Visit(r.data.ThisClass, r.data.SuperClass, r.data.AccessFlags)
-		visitor.VisitInterfaces(r.data.Interfaces)
-		visitor.VisitFields(r.data.Fields)
-		visitor.VisitMethods(r.data.Methods)
-		visitor.VisitAttributes(r.data.Attributes)
-		visitor.VisitEnd()
-	}
+	r.data.Accept(visitor)
 }
 
 func (r *Reader) Read() error {
 	r.data.MagicNumber = r.readU4()
+	if r.err == nil && r.data.MagicNumber != MAGIC_NUMBER {
+		return fmt.Errorf("invalid magic number %#x", r.data.MagicNumber)
+	}
 	r.data.MinorVersion = r.readU2()
 	r.data.MajorVersion = r.readU2()
 
@@ -53,7 +47,18 @@ func (r *Reader) Read() error {
 	r.data.AttributesCount = r.readU2()
 	r.data.Attributes = r.readAttributes(r.data.AttributesCount)
 
-	return nil
+	return r.err
+}
+
+// Data returns the class data read by Read.
+func (r *Reader) Data() *ClassData {
+	return r.data
+}
+
+func (r *Reader) check(err error) {
+	if err != nil && r.err == nil {
+		r.err = err
+	}
 }
 
 func (r *Reader) readConstantPool(count uint16) []ConstantData {
@@ -75,9 +80,11 @@ func (r *Reader) readConstantPool(count uint16) []ConstantData {
 		case TAG_CONSTANT_LONG:
 			long := r.readInt64()
 			pool[i] = ConstantLongData{LongValue: long}
+			i++
 		case TAG_CONSTANT_DOUBLE:
 			double := r.readFloat64()
 			pool[i] = ConstantDoubleData{DoubleValue: double}
+			i++
 		case TAG_CONSTANT_CLASS:
 			index := r.readU2()
 			pool[i] = ConstantClassData{NameIndex: index}
@@ -118,41 +125,48 @@ func (r *Reader) readConstantPool(count uint16) []ConstantData {
 }
 
 func (r *Reader) readU1() uint8 {
-	v, _ := r.reader.ReadUint8()
+	v, err := r.reader.ReadUint8()
+	r.check(err)
 	return v
 }
 func (r *Reader) readU2() uint16 {
-	v, _ := r.reader.ReadUint16()
+	v, err := r.reader.ReadUint16()
+	r.check(err)
 	return v
 }
 func (r *Reader) readU4() uint32 {
-	v, _ := r.reader.ReadUint32()
+	v, err := r.reader.ReadUint32()
+	r.check(err)
 	return v
 }
 func (r *Reader) readInt32() int32 {
-	v, _ := r.reader.ReadInt32()
+	v, err := r.reader.ReadInt32()
+	r.check(err)
 	return v
 }
 func (r *Reader) readInt64() int64 {
-	v, _ := r.reader.ReadInt64()
+	v, err := r.reader.ReadInt64()
+	r.check(err)
 	return v
 }
 func (r *Reader) readFloat32() float32 {
-	v, _ := r.reader.ReadFloat32()
+	v, err := r.reader.ReadFloat32()
+	r.check(err)
 	return v
 }
 func (r *Reader) readFloat64() float64 {
-	v, _ := r.reader.ReadFloat64()
+	v, err := r.reader.ReadFloat64()
+	r.check(err)
 	return v
 }
 
 func (r *Reader) readUTF8(length int) string {
-	str, _ := r.reader.ReadString(length)
-	return str
+	return string(r.readBytes(length))
 }
 
 func (r *Reader) readBytes(length int) []byte {
-	bytes, _ := r.reader.ReadBytes(length)
+	bytes, err := r.reader.ReadBytes(uint32(length))
+	r.check(err)
 	return bytes
 }
 
@@ -32,7 +32,7 @@ func (w Writer) VisitConstants(constants []ConstantData) {
 	count := len(constants)
 	writer.WriteUint16(uint16(count))
 	for i, data := range constants {
-		if i == 0 {
+		if i == 0 || data == nil {
 			continue
 		}
 		tag := data.Tag()
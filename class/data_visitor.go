@@ -9,6 +9,8 @@ type ResolveDataVisitor struct {
 	class                 *Class
 	constantDynamicValues map[uint16]ConstantDynamic
 	BootstrapMethods      []BootstrapMethod
+	// annotationsOnly only resolves the class, field and method names and their annotations.
+	annotationsOnly bool
 }
 
 func (r *ResolveDataVisitor) Class() Class {
@@ -16,179 +18,17 @@ func (r *ResolveDataVisitor) Class() Class {
 }
 
 func (r *ResolveDataVisitor) Accept(visitor Visitor) {
-	if visitor != nil {
-		class := r.class
-		visitor.Visit(class.Version, class.AccessFlags, class.ThisClass, class.SuperClass, class.Signature, class.Interfaces)
-		visitor.VisitSource(class.SourceFile, class.SourceDebugExtension)
-
-		module := class.Module
-		moduleVisitor := visitor.VisitModule(module.Name, module.AccessFlags, module.Version)
-		r.acceptModule(moduleVisitor, module)
-
-		visitor.VisitNestHost(class.NestHost)
-		visitor.VisitOuterClass(class.OuterClass.ClassName, class.OuterClass.MethodName, class.OuterClass.Descriptor)
-
-		for _, annotation := range class.RuntimeVisibleAnnotations {
-			annotationVisitor := visitor.VisitAnnotation(annotation.Descriptor, annotation.Visible)
-			r.acceptAnnotation(annotationVisitor, annotation)
-		}
-		for _, annotation := range class.RuntimeInvisibleAnnotations {
-			annotationVisitor := visitor.VisitAnnotation(annotation.Descriptor, annotation.Visible)
-			r.acceptAnnotation(annotationVisitor, annotation)
-		}
-		// TODO RuntimeVisibleTypeAnnotations
-		// TODO RuntimeInvisibleTypeAnnotations
-
-		for _, attr := range class.Attributes {
-			visitor.VisitAttribute(attr)
-		}
-		for _, member := range class.NestMembers {
-			visitor.VisitNestMember(member)
-		}
-		for _, cls := range class.InnerClasses {
-			visitor.VisitInnerClass(cls.Name, cls.OuterName, cls.InnerName, cls.AccessFlags)
-		}
-		for _, field := range class.Fields {
-			fieldVisitor := visitor.VisitField(field.AccessFlags, field.Name, field.Descriptor, field.Signature, field.ConstantValue)
-			r.acceptField(fieldVisitor, field)
-		}
-		for _, method := range class.Methods {
-			methodVisitor := visitor.VisitMethod(method.AccessFlags, method.Name, method.Descriptor, method.Signature, method.Exceptions)
-			r.acceptMethod(methodVisitor, method)
-		}
-
-		visitor.VisitEnd()
-	}
-}
-
-func (r *ResolveDataVisitor) acceptModule(visitor ModuleVisitor, module Module) {
-	if visitor != nil {
-		visitor.VisitMainClass(module.MainClass)
-		for _, pkg := range module.Packages {
-			visitor.VisitPackage(pkg)
-		}
-		for _, r := range module.Requires {
-			visitor.VisitRequire(r.Name, r.AccessFlags, r.Version)
-		}
-		for _, e := range module.Exports {
-			visitor.VisitExport(e.Name, e.AccessFlags, e.Modules)
-		}
-		for _, o := range module.Opens {
-			visitor.VisitOpen(o.Name, o.AccessFlags, o.Modules)
-		}
-		for _, u := range module.Uses {
-			visitor.VisitUse(u)
-		}
-		for _, p := range module.Provides {
-			visitor.VisitProvide(p.Service, p.Provides)
-		}
-		visitor.VisitEnd()
-	}
-}
-func (r *ResolveDataVisitor) acceptAnnotation(visitor AnnotationVisitor, annotation Annotation) {
-	for _, pair := range annotation.ElementPairs {
-		r.acceptAnnotationValue(visitor, pair.Name, pair.Value)
-	}
-	visitor.VisitEnd()
-}
-
-func (r *ResolveDataVisitor) acceptAnnotationValue(visitor AnnotationVisitor, name string, value ElementValue) {
-	switch value.Tag() {
-	case data.ELEMENT_TAG_BOOLEAN:
-		visitor.Visit(name, value.(ElementBooleanValue).Value)
-	case data.ELEMENT_TAG_BYTE:
-		visitor.Visit(name, value.(ElementByteValue).Value)
-	case data.ELEMENT_TAG_CHAR:
-		visitor.Visit(name, value.(ElementCharValue).Value)
-	case data.ELEMENT_TAG_SHORT:
-		visitor.Visit(name, value.(ElementShortValue).Value)
-	case data.ELEMENT_TAG_INTEGER:
-		visitor.Visit(name, value.(ElementIntegerValue).Value)
-	case data.ELEMENT_TAG_LONG:
-		visitor.Visit(name, value.(ElementLongValue).Value)
-	case data.ELEMENT_TAG_FLOAT:
-		visitor.Visit(name, value.(ElementFloatValue).Value)
-	case data.ELEMENT_TAG_DOUBLE:
-		visitor.Visit(name, value.(ElementDoubleValue).Value)
-	case data.ELEMENT_TAG_STRING:
-		visitor.Visit(name, value.(ElementStringValue).Value)
-	case data.ELEMENT_TAG_CLASS:
-		visitor.Visit(name, value.(ElementClassValue).Value)
-	case data.ELEMENT_TAG_ANNOTATION:
-		annotation := value.(ElementAnnotationValue).Value
-		annotationVisitor := visitor.VisitAnnotation(name, annotation.Descriptor)
-		r.acceptAnnotation(annotationVisitor, annotation)
-	case data.ELEMENT_TAG_ENUM:
-		enumValue := value.(ElementEnumValue)
-		visitor.VisitEnum(name, enumValue.TypeName, enumValue.ConstName)
-	case data.ELEMENT_TAG_ARRAY:
-		annotationVisitor := visitor.VisitArray(name)
-		for _, elemValue := range value.(ElementArrayValue).Values {
-			r.acceptAnnotationValue(annotationVisitor, "", elemValue)
-		}
-	}
-}
-
-func (r *ResolveDataVisitor) acceptField(visitor FieldVisitor, field Field) {
-	for _, annotation := range field.RuntimeVisibleAnnotations {
-		annotationVisitor := visitor.VisitAnnotation(annotation.Descriptor, annotation.Visible)
-		r.acceptAnnotation(annotationVisitor, annotation)
-	}
-	for _, annotation := range field.RuntimeInvisibleAnnotations {
-		annotationVisitor := visitor.VisitAnnotation(annotation.Descriptor, annotation.Visible)
-		r.acceptAnnotation(annotationVisitor, annotation)
-	}
-	// TODO RuntimeVisibleTypeAnnotations
-	// TODO RuntimeInvisibleTypeAnnotations
-	for _, attribute := range field.Attributes {
-		visitor.VisitAttribute(attribute)
-	}
-	visitor.VisitEnd()
-}
-func (r *ResolveDataVisitor) acceptMethod(visitor MethodVisitor, method Method) {
-	for _, parameter := range method.Parameters {
-		visitor.VisitParameter(parameter.ParameterName, parameter.AccessFlags)
-	}
-	annotationDefaultVisitor := visitor.VisitAnnotationDefault()
-	r.acceptAnnotationValue(annotationDefaultVisitor, "", method.AnnotationDefault)
-
-	for _, annotation := range method.RuntimeVisibleAnnotations {
-		annotationVisitor := visitor.VisitAnnotation(annotation.Descriptor, annotation.Visible)
-		r.acceptAnnotation(annotationVisitor, annotation)
-	}
-	for _, annotation := range method.RuntimeInvisibleAnnotations {
-		annotationVisitor := visitor.VisitAnnotation(annotation.Descriptor, annotation.Visible)
-		r.acceptAnnotation(annotationVisitor, annotation)
-	}
-	// TODO RuntimeVisibleTypeAnnotations
-	// TODO RuntimeInvisibleTypeAnnotations
-	for index, parameter := range method.RuntimeVisibleParameterAnnotations {
-		count := len(method.RuntimeVisibleParameterAnnotations)
-		visitor.VisitAnnotableParameterCount(count, true)
-		for _, annotation := range parameter.Annotations {
-			annotationVisitor := visitor.VisitParameterAnnotation(index, annotation.Descriptor, annotation.Visible)
-			r.acceptAnnotation(annotationVisitor, annotation)
-		}
-	}
-	for index, parameter := range method.RuntimeInvisibleParameterAnnotations {
-		count := len(method.RuntimeVisibleParameterAnnotations)
-		visitor.VisitAnnotableParameterCount(count, true)
-		for _, annotation := range parameter.Annotations {
-			annotationVisitor := visitor.VisitParameterAnnotation(index, annotation.Descriptor, annotation.Visible)
-			r.acceptAnnotation(annotationVisitor, annotation)
-		}
-	}
-	for _, attribute := range method.Attributes {
-		visitor.VisitAttribute(attribute)
-	}
-	// TODO VisitCode
-	visitor.VisitEnd()
+	r.class.Accept(visitor)
 }
 
 func (r *ResolveDataVisitor) VisitEnd() {
 	r.class = &Class{}
 	r.constantDynamicValues = make(map[uint16]ConstantDynamic)
-	r.resolveAll()
+	if r.annotationsOnly {
+		r.resolveAnnotations()
+	} else {
+		r.resolveAll()
+	}
 }
 
 func (r *ResolveDataVisitor) resolveInteger(index uint16) int32 {
@@ -208,6 +48,9 @@ func (r *ResolveDataVisitor) resolveDouble(index uint16) float64 {
 	return doubleData.DoubleValue
 }
 func (r *ResolveDataVisitor) resolveClassName(index uint16) string {
+	if index == 0 {
+		return ""
+	}
 	classData := r.Data().ConstantPool[index].(data.ConstantClassData)
 	return r.resolveUTF8(classData.NameIndex)
 }
@@ -216,6 +59,9 @@ func (r *ResolveDataVisitor) resolveString(index uint16) string {
 	return r.resolveUTF8(strData.ValueIndex)
 }
 func (r *ResolveDataVisitor) resolveUTF8(index uint16) string {
+	if index == 0 {
+		return ""
+	}
 	utf8Data := r.Data().ConstantPool[index].(data.ConstantUTF8Data)
 	return utf8Data.UTF8Value
 }
@@ -225,15 +71,25 @@ func (r *ResolveDataVisitor) resolveNameAndType(index uint16) (string, string) {
 }
 func (r *ResolveDataVisitor) resolveReference(index uint16) ConstantReference {
 	referenceData := r.Data().ConstantPool[index].(data.ConstantReferenceData)
-	owner := r.resolveUTF8(referenceData.OwnerIndex())
+	owner := r.resolveClassName(referenceData.OwnerIndex())
 	name, descriptor := r.resolveNameAndType(referenceData.DescriptorIndex())
 	isInterface := referenceData.Tag() == data.TAG_CONSTANT_INTERFACE_METHODREF
 	return ConstantReference{Tag: referenceData.Tag(), Owner: owner, Name: name, Descriptor: descriptor, IsInterface: isInterface}
 }
 
-func (r ResolveDataVisitor) resolveAll() {
+func (r *ResolveDataVisitor) resolveModuleName(index uint16) string {
+	moduleData := r.Data().ConstantPool[index].(data.ConstantModuleData)
+	return r.resolveUTF8(moduleData.NameIndex)
+}
+func (r *ResolveDataVisitor) resolvePackageName(index uint16) string {
+	packageData := r.Data().ConstantPool[index].(data.ConstantPackageData)
+	return r.resolveUTF8(packageData.NameIndex)
+}
+
+func (r *ResolveDataVisitor) resolveAll() {
 	classData := r.Data()
 	class := r.class
+	class.AccessFlags = classData.AccessFlags
 	class.ThisClass = r.resolveClassName(classData.ThisClass)
 	class.SuperClass = r.resolveClassName(classData.SuperClass)
 	interfaces := make([]string, classData.InterfacesCount)
@@ -242,7 +98,14 @@ func (r ResolveDataVisitor) resolveAll() {
 	}
 	class.Interfaces = interfaces
 
-	class.Version = uint32(classData.MinorVersion) << 16 & uint32(classData.MajorVersion)
+	class.Version = uint32(classData.MinorVersion)<<16 | uint32(classData.MajorVersion)
+
+	// The bootstrap methods are needed to resolve the dynamic constants of fields and methods.
+	for _, attr := range classData.Attributes {
+		if r.resolveUTF8(attr.NameIndex) == data.BOOTSTRAP_METHODS {
+			class.BootstrapMethods = r.resolveBootstrapMethods(attr.Value)
+		}
+	}
 
 	var module Module
 	var moduleMainClass string
@@ -258,27 +121,29 @@ func (r ResolveDataVisitor) resolveAll() {
 		case data.ENCLOSING_METHOD:
 			class.OuterClass = r.resolveOuterClass(attr.Value)
 		case data.NEST_HOST:
-			class.NestHost = r.resolveUTF8(attr.Value.Uint16())
+			class.NestHost = r.resolveClassName(attr.Value.Uint16())
 		case data.NEST_MEMBERS:
 			class.NestMembers = r.resolveNestMembers(attr.Value)
-		case data.PERMITTED_SUBTYPES:
+		case data.PERMITTED_SUBCLASSES:
+			class.PermittedSubclasses = r.resolveNestMembers(attr.Value)
 		case data.SIGNATURE:
 			class.Signature = r.resolveUTF8(attr.Value.Uint16())
 		case data.RUNTIME_VISIBLE_ANNOTATIONS:
 			class.RuntimeVisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, true)
 		case data.RUNTIME_VISIBLE_TYPE_ANNOTATIONS:
-			// TODO
+			class.RuntimeVisibleTypeAnnotations = r.resolveRuntimeTypeAnnotations(attr.Value, true)
 		case data.DEPRECATED:
 			class.Deprecated = true
 		case data.SYNTHETIC:
 			class.AccessFlags |= data.ACC_SYNTHETIC
 		case data.SOURCE_DEBUG_EXTENSION:
-			class.SourceDebugExtension = r.resolveUTF8(attr.Value.Uint16())
+			class.SourceDebugExtension = string(attr.Value)
 		case data.RUNTIME_INVISIBLE_ANNOTATIONS:
-			class.RuntimeVisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, false)
+			class.RuntimeInvisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, false)
 		case data.RUNTIME_INVISIBLE_TYPE_ANNOTATIONS:
-			// TODO
+			class.RuntimeInvisibleTypeAnnotations = r.resolveRuntimeTypeAnnotations(attr.Value, false)
 		case data.RECORD:
+			class.RecordComponents = r.resolveRecordComponents(attr.Value)
 		case data.MODULE:
 			module = r.resolveModuleAttributes(attr.Value)
 		case data.MODULE_MAIN_CLASS:
@@ -286,7 +151,6 @@ func (r ResolveDataVisitor) resolveAll() {
 		case data.MODULE_PACKAGES:
 			modulePackages = r.resolveModulePackages(attr.Value)
 		case data.BOOTSTRAP_METHODS:
-			class.BootstrapMethods = r.resolveBootstrapMethods(attr.Value)
 		default:
 			attributes = append(attributes, Attribute{Name: name, Content: attr.Value})
 		}
@@ -299,11 +163,76 @@ func (r ResolveDataVisitor) resolveAll() {
 		class.Module = module
 	}
 	class.Attributes = attributes
+
+	class.Fields = make([]Field, len(classData.Fields))
+	for i, fieldData := range classData.Fields {
+		class.Fields[i] = r.resolveField(fieldData)
+	}
+	class.Methods = make([]Method, len(classData.Methods))
+	for i, methodData := range classData.Methods {
+		class.Methods[i] = r.resolveMethod(methodData)
+	}
+}
+
+// resolveAnnotations resolves the access flags, names and supertypes of the class, the access
+// flags, names and descriptors of its fields and methods, their annotations and the default
+// values of the annotation elements, but not the other attributes.
+func (r *ResolveDataVisitor) resolveAnnotations() {
+	classData := r.Data()
+	class := r.class
+	class.AccessFlags = classData.AccessFlags
+	class.ThisClass = r.resolveClassName(classData.ThisClass)
+	class.SuperClass = r.resolveClassName(classData.SuperClass)
+	class.Interfaces = make([]string, classData.InterfacesCount)
+	for i := uint16(0); i < classData.InterfacesCount; i++ {
+		class.Interfaces[i] = r.resolveClassName(classData.Interfaces[i].Index)
+	}
+	class.Version = uint32(classData.MinorVersion)<<16 | uint32(classData.MajorVersion)
+	for _, attr := range classData.Attributes {
+		switch r.resolveUTF8(attr.NameIndex) {
+		case data.RUNTIME_VISIBLE_ANNOTATIONS:
+			class.RuntimeVisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, true)
+		case data.RUNTIME_INVISIBLE_ANNOTATIONS:
+			class.RuntimeInvisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, false)
+		}
+	}
+	class.Fields = make([]Field, len(classData.Fields))
+	for i, fieldData := range classData.Fields {
+		field := &class.Fields[i]
+		field.AccessFlags = fieldData.AccessFlags
+		field.Name = r.resolveUTF8(fieldData.NameIndex)
+		field.Descriptor = r.resolveUTF8(fieldData.DescriptorIndex)
+		for _, attr := range fieldData.Attributes {
+			switch r.resolveUTF8(attr.NameIndex) {
+			case data.RUNTIME_VISIBLE_ANNOTATIONS:
+				field.RuntimeVisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, true)
+			case data.RUNTIME_INVISIBLE_ANNOTATIONS:
+				field.RuntimeInvisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, false)
+			}
+		}
+	}
+	class.Methods = make([]Method, len(classData.Methods))
+	for i, methodData := range classData.Methods {
+		method := &class.Methods[i]
+		method.AccessFlags = methodData.AccessFlags
+		method.Name = r.resolveUTF8(methodData.NameIndex)
+		method.Descriptor = r.resolveUTF8(methodData.DescriptorIndex)
+		for _, attr := range methodData.Attributes {
+			switch r.resolveUTF8(attr.NameIndex) {
+			case data.ANNOTATION_DEFAULT:
+				method.AnnotationDefault = r.readElementValue(attr.Value.Reader())
+			case data.RUNTIME_VISIBLE_ANNOTATIONS:
+				method.RuntimeVisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, true)
+			case data.RUNTIME_INVISIBLE_ANNOTATIONS:
+				method.RuntimeInvisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, false)
+			}
+		}
+	}
 }
 
 func (r *ResolveDataVisitor) resolveConstantValue(constIndex uint16) interface{} {
 	pool := r.Data().ConstantPool
-	if constIndex < 0 || int(constIndex) > len(pool) {
+	if constIndex == 0 || int(constIndex) >= len(pool) {
 		return nil
 	}
 	constantData := pool[constIndex]
@@ -334,23 +263,18 @@ func (r *ResolveDataVisitor) resolveConstantValue(constIndex uint16) interface{}
 	case data.TAG_CONSTANT_METHOD_HANDLE:
 		methodHandleData := constantData.(data.ConstantMethodHandleData)
 		reference := r.resolveReference(methodHandleData.ReferenceIndex)
-		return Handle{Tag: methodHandleData.Tag(), Owner: reference.Owner, Name: reference.Name, Descriptor: reference.Descriptor}
+		return Handle{Tag: methodHandleData.ReferenceKind, Owner: reference.Owner, Name: reference.Name, Descriptor: reference.Descriptor, IsInterface: reference.IsInterface}
 	case data.TAG_CONSTANT_METHOD_TYPE:
 		methodTypeData := constantData.(data.ConstantMethodTypeData)
 		descriptor := r.resolveUTF8(methodTypeData.DescriptorIndex)
 		return NewMethodType(descriptor)
 	case data.TAG_CONSTANT_DYNAMIC:
-		dynamicData := constantData.(data.ConstantDynamicData)
-		return dynamicData
+		return r.resolveConstantDynamic(constIndex)
 	default:
 		return nil
 	}
 }
 
-func (r ResolveDataVisitor) resolveConstantDynamicHandle() {
-
-}
-
 func (r *ResolveDataVisitor) resolveField(fieldData data.FieldData) Field {
 	field := Field{}
 
@@ -375,11 +299,11 @@ func (r *ResolveDataVisitor) resolveField(fieldData data.FieldData) Field {
 		case data.RUNTIME_VISIBLE_ANNOTATIONS:
 			field.RuntimeVisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, true)
 		case data.RUNTIME_VISIBLE_TYPE_ANNOTATIONS:
-			// TODO
+			field.RuntimeVisibleTypeAnnotations = r.resolveRuntimeTypeAnnotations(attr.Value, true)
 		case data.RUNTIME_INVISIBLE_ANNOTATIONS:
 			field.RuntimeInvisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, false)
 		case data.RUNTIME_INVISIBLE_TYPE_ANNOTATIONS:
-			// TODO
+			field.RuntimeInvisibleTypeAnnotations = r.resolveRuntimeTypeAnnotations(attr.Value, false)
 		default:
 			attributes = append(attributes, Attribute{Name: name, Content: attr.Value})
 		}
@@ -398,6 +322,8 @@ func (r *ResolveDataVisitor) resolveMethod(methodData data.MethodData) Method {
 		switch name {
 		case data.CODE:
 			method.Code = r.resolveMethodCode(attr.Value)
+		case data.SIGNATURE:
+			method.Signature = r.resolveUTF8(attr.Value.Uint16())
 		case data.EXCEPTIONS:
 			method.Exceptions = r.resolveMethodExceptions(attr.Value)
 		case data.DEPRECATED:
@@ -409,15 +335,15 @@ func (r *ResolveDataVisitor) resolveMethod(methodData data.MethodData) Method {
 		case data.RUNTIME_VISIBLE_ANNOTATIONS:
 			method.RuntimeVisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, true)
 		case data.RUNTIME_VISIBLE_TYPE_ANNOTATIONS:
-			// TODO
+			method.RuntimeVisibleTypeAnnotations = r.resolveRuntimeTypeAnnotations(attr.Value, true)
 		case data.RUNTIME_INVISIBLE_ANNOTATIONS:
 			method.RuntimeInvisibleAnnotations = r.resolveRuntimeAnnotations(attr.Value, false)
 		case data.RUNTIME_INVISIBLE_TYPE_ANNOTATIONS:
-			// TODO
+			method.RuntimeInvisibleTypeAnnotations = r.resolveRuntimeTypeAnnotations(attr.Value, false)
 		case data.RUNTIME_VISIBLE_PARAMETER_ANNOTATIONS:
 			method.RuntimeVisibleParameterAnnotations = r.resolveRuntimeParameterAnnotations(attr.Value, true)
 		case data.RUNTIME_INVISIBLE_PARAMETER_ANNOTATIONS:
-			method.RuntimeInvisibleParameterAnnotations = r.resolveRuntimeParameterAnnotations(attr.Value, true)
+			method.RuntimeInvisibleParameterAnnotations = r.resolveRuntimeParameterAnnotations(attr.Value, false)
 		case data.METHOD_PARAMETERS:
 			method.Parameters = r.resolveMethodParameter(attr.Value)
 		default:
@@ -428,6 +354,39 @@ func (r *ResolveDataVisitor) resolveMethod(methodData data.MethodData) Method {
 	return method
 }
 
+func (r *ResolveDataVisitor) resolveRecordComponents(attrValue data.AttributeValue) []RecordComponent {
+	reader := attrValue.Reader()
+	count := reader.ReadUint16()
+	components := make([]RecordComponent, count)
+	for i := uint16(0); i < count; i++ {
+		component := &components[i]
+		component.Name = r.resolveUTF8(reader.ReadUint16())
+		component.Descriptor = r.resolveUTF8(reader.ReadUint16())
+		attributes := make([]Attribute, 0)
+		attributeCount := reader.ReadUint16()
+		for j := uint16(0); j < attributeCount; j++ {
+			name := r.resolveUTF8(reader.ReadUint16())
+			value := data.AttributeValue(reader.ReadBytes(reader.ReadUint32()))
+			switch name {
+			case data.SIGNATURE:
+				component.Signature = r.resolveUTF8(value.Uint16())
+			case data.RUNTIME_VISIBLE_ANNOTATIONS:
+				component.RuntimeVisibleAnnotations = r.resolveRuntimeAnnotations(value, true)
+			case data.RUNTIME_INVISIBLE_ANNOTATIONS:
+				component.RuntimeInvisibleAnnotations = r.resolveRuntimeAnnotations(value, false)
+			case data.RUNTIME_VISIBLE_TYPE_ANNOTATIONS:
+				component.RuntimeVisibleTypeAnnotations = r.resolveRuntimeTypeAnnotations(value, true)
+			case data.RUNTIME_INVISIBLE_TYPE_ANNOTATIONS:
+				component.RuntimeInvisibleTypeAnnotations = r.resolveRuntimeTypeAnnotations(value, false)
+			default:
+				attributes = append(attributes, Attribute{Name: name, Content: value})
+			}
+		}
+		component.Attributes = attributes
+	}
+	return components
+}
+
 func (r *ResolveDataVisitor) resolveInnerClasses(attrValue data.AttributeValue) []InnerClass {
 	reader := attrValue.Reader()
 	count := reader.ReadUint16()
@@ -438,7 +397,6 @@ func (r *ResolveDataVisitor) resolveInnerClasses(attrValue data.AttributeValue)
 		innerClassIndex := reader.ReadUint16()
 		accessFlags := reader.ReadUint16()
 		innerClasses[i] = InnerClass{r.resolveClassName(currentClassIndex), r.resolveClassName(outerClassIndex), r.resolveUTF8(innerClassIndex), accessFlags}
-
 	}
 	return innerClasses
 }
@@ -446,7 +404,10 @@ func (r *ResolveDataVisitor) resolveInnerClasses(attrValue data.AttributeValue)
 func (r *ResolveDataVisitor) resolveOuterClass(attrValue data.AttributeValue) OuterClass {
 	reader := attrValue.Reader()
 	className := r.resolveClassName(reader.ReadUint16())
-	methodName, descriptor := r.resolveNameAndType(reader.ReadUint16())
+	var methodName, descriptor string
+	if methodIndex := reader.ReadUint16(); methodIndex != 0 {
+		methodName, descriptor = r.resolveNameAndType(methodIndex)
+	}
 	return OuterClass{ClassName: className, MethodName: methodName, Descriptor: descriptor}
 }
 
@@ -455,21 +416,21 @@ func (r *ResolveDataVisitor) resolveModulePackages(attrValue data.AttributeValue
 	packageCount := reader.ReadUint16()
 	packages := make([]string, packageCount)
 	for i := uint16(0); i < packageCount; i++ {
-		packages[i] = r.resolveUTF8(reader.ReadUint16())
+		packages[i] = r.resolvePackageName(reader.ReadUint16())
 	}
 	return packages
 }
 
 func (r *ResolveDataVisitor) resolveModuleAttributes(attrValue data.AttributeValue) Module {
 	reader := attrValue.Reader()
-	moduleName := r.resolveClassName(reader.ReadUint16())
+	moduleName := r.resolveModuleName(reader.ReadUint16())
 	accessFlags := reader.ReadUint16()
 	version := r.resolveUTF8(reader.ReadUint16())
 
 	requireCount := reader.ReadUint16()
 	requires := make([]ModuleRequire, requireCount)
 	for i := uint16(0); i < requireCount; i++ {
-		name := r.resolveUTF8(reader.ReadUint16())
+		name := r.resolveModuleName(reader.ReadUint16())
 		access := reader.ReadUint16()
 		version := r.resolveUTF8(reader.ReadUint16())
 		requires[i] = ModuleRequire{Name: name, AccessFlags: access, Version: version}
@@ -478,14 +439,14 @@ func (r *ResolveDataVisitor) resolveModuleAttributes(attrValue data.AttributeVal
 	exportCount := reader.ReadUint16()
 	exports := make([]ModuleExport, exportCount)
 	for i := uint16(0); i < exportCount; i++ {
-		pkgName := r.resolveUTF8(reader.ReadUint16())
+		pkgName := r.resolvePackageName(reader.ReadUint16())
 		access := reader.ReadUint16()
 		exportToCount := reader.ReadUint16()
 		var exportTos []string
 		if exportToCount != 0 {
 			exportTos = make([]string, exportToCount)
 			for j := uint16(0); j < exportToCount; j++ {
-				exportTos[j] = r.resolveUTF8(reader.ReadUint16())
+				exportTos[j] = r.resolveModuleName(reader.ReadUint16())
 			}
 		}
 		exports[i] = ModuleExport{Name: pkgName, AccessFlags: access, Modules: exportTos}
@@ -494,14 +455,14 @@ func (r *ResolveDataVisitor) resolveModuleAttributes(attrValue data.AttributeVal
 	openCount := reader.ReadUint16()
 	opens := make([]ModuleOpen, openCount)
 	for i := uint16(0); i < openCount; i++ {
-		pkgName := r.resolveUTF8(reader.ReadUint16())
+		pkgName := r.resolvePackageName(reader.ReadUint16())
 		access := reader.ReadUint16()
 		openToCount := reader.ReadUint16()
 		var openTos []string
 		if openToCount != 0 {
 			openTos = make([]string, openToCount)
 			for j := uint16(0); j < openToCount; j++ {
-				openTos[j] = r.resolveUTF8(reader.ReadUint16())
+				openTos[j] = r.resolveModuleName(reader.ReadUint16())
 			}
 		}
 		opens[i] = ModuleOpen{Name: pkgName, AccessFlags: access, Modules: openTos}
@@ -516,7 +477,7 @@ func (r *ResolveDataVisitor) resolveModuleAttributes(attrValue data.AttributeVal
 	provideCount := reader.ReadUint16()
 	provides := make([]ModuleProvide, provideCount)
 	for i := uint16(0); i < provideCount; i++ {
-		service := r.resolveUTF8(reader.ReadUint16())
+		service := r.resolveClassName(reader.ReadUint16())
 		provideWithCount := reader.ReadUint16()
 		provideWiths := make([]string, provideWithCount)
 		for j := uint16(0); j < provideWithCount; j++ {
@@ -539,6 +500,82 @@ func (r *ResolveDataVisitor) resolveRuntimeAnnotations(attrValue data.AttributeV
 	}
 	return annotations
 }
+
+func (r *ResolveDataVisitor) resolveRuntimeTypeAnnotations(attrValue data.AttributeValue, visible bool) []TypeAnnotation {
+	reader := attrValue.Reader()
+	annotationCount := reader.ReadUint16()
+	annotations := make([]TypeAnnotation, annotationCount)
+	for i := uint16(0); i < annotationCount; i++ {
+		annotations[i] = r.readTypeAnnotation(reader, visible, nil, nil).TypeAnnotation
+	}
+	return annotations
+}
+
+func (r *ResolveDataVisitor) resolveCodeTypeAnnotations(attrValue data.AttributeValue, visible bool, label func(int) *Label, instruction func(int) Instruction) []CodeTypeAnnotation {
+	reader := attrValue.Reader()
+	annotationCount := reader.ReadUint16()
+	annotations := make([]CodeTypeAnnotation, annotationCount)
+	for i := uint16(0); i < annotationCount; i++ {
+		annotations[i] = r.readTypeAnnotation(reader, visible, label, instruction)
+	}
+	return annotations
+}
+
+// readTypeAnnotation reads a type annotation. The labels of the variable ranges and the
+// annotated instruction of the annotations of the code are returned by label and instruction,
+// which are nil outside of the code.
+func (r *ResolveDataVisitor) readTypeAnnotation(reader *data.AttributeValueReader, visible bool, label func(int) *Label, instruction func(int) Instruction) CodeTypeAnnotation {
+	annotation := CodeTypeAnnotation{}
+	sort := int(reader.ReadUint8())
+	typeRef := sort << 24
+	switch sort {
+	case data.TYPE_REF_CLASS_TYPE_PARAMETER, data.TYPE_REF_METHOD_TYPE_PARAMETER, data.TYPE_REF_METHOD_FORMAL_PARAMETER:
+		typeRef |= int(reader.ReadUint8()) << 16
+	case data.TYPE_REF_CLASS_EXTENDS, data.TYPE_REF_THROWS, data.TYPE_REF_EXCEPTION_PARAMETER:
+		typeRef |= int(reader.ReadUint16()) << 8
+	case data.TYPE_REF_CLASS_TYPE_PARAMETER_BOUND, data.TYPE_REF_METHOD_TYPE_PARAMETER_BOUND:
+		parameterIndex := int(reader.ReadUint8())
+		boundIndex := int(reader.ReadUint8())
+		typeRef |= parameterIndex<<16 | boundIndex<<8
+	case data.TYPE_REF_LOCAL_VARIABLE, data.TYPE_REF_RESOURCE_VARIABLE:
+		count := reader.ReadUint16()
+		for i := uint16(0); i < count; i++ {
+			start := int(reader.ReadUint16())
+			length := int(reader.ReadUint16())
+			index := int(reader.ReadUint16())
+			if label != nil {
+				annotation.Start = append(annotation.Start, label(start))
+				annotation.End = append(annotation.End, label(start+length))
+				annotation.Index = append(annotation.Index, index)
+			}
+		}
+	case data.TYPE_REF_INSTANCEOF, data.TYPE_REF_NEW, data.TYPE_REF_CONSTRUCTOR_REFERENCE, data.TYPE_REF_METHOD_REFERENCE:
+		offset := int(reader.ReadUint16())
+		if instruction != nil {
+			annotation.Instruction = instruction(offset)
+		}
+	case data.TYPE_REF_CAST, data.TYPE_REF_CONSTRUCTOR_INVOCATION_TYPE_ARGUMENT, data.TYPE_REF_METHOD_INVOCATION_TYPE_ARGUMENT,
+		data.TYPE_REF_CONSTRUCTOR_REFERENCE_TYPE_ARGUMENT, data.TYPE_REF_METHOD_REFERENCE_TYPE_ARGUMENT:
+		offset := int(reader.ReadUint16())
+		if instruction != nil {
+			annotation.Instruction = instruction(offset)
+		}
+		typeRef |= int(reader.ReadUint8())
+	}
+	annotation.TypeRef = typeRef
+	pathLength := reader.ReadUint8()
+	if pathLength > 0 {
+		annotation.TypePath = make(TypePath, pathLength)
+		for i := range annotation.TypePath {
+			annotation.TypePath[i].Kind = reader.ReadUint8()
+			annotation.TypePath[i].TypeArgumentIndex = reader.ReadUint8()
+		}
+	}
+	annotation.Annotation = r.readAnnotation(reader)
+	annotation.Visible = visible
+	return annotation
+}
+
 func (r *ResolveDataVisitor) resolveRuntimeParameterAnnotations(attrValue data.AttributeValue, visible bool) []ParameterAnnotation {
 	reader := attrValue.Reader()
 	parameterCount := reader.ReadUint8()
@@ -575,7 +612,7 @@ func (r *ResolveDataVisitor) readElementValue(reader *data.AttributeValueReader)
 	tag := reader.ReadUint8()
 	switch tag {
 	case data.ELEMENT_TAG_BOOLEAN:
-		return ElementBooleanValue{Value: r.resolveInteger(reader.ReadUint16()) == 0}
+		return ElementBooleanValue{Value: r.resolveInteger(reader.ReadUint16()) != 0}
 	case data.ELEMENT_TAG_BYTE:
 		return ElementByteValue{Value: int8(r.resolveInteger(reader.ReadUint16()))}
 	case data.ELEMENT_TAG_CHAR:
@@ -593,7 +630,7 @@ func (r *ResolveDataVisitor) readElementValue(reader *data.AttributeValueReader)
 	case data.ELEMENT_TAG_STRING:
 		return ElementStringValue{Value: r.resolveUTF8(reader.ReadUint16())}
 	case data.ELEMENT_TAG_CLASS:
-		return ElementClassValue{Value: r.resolveClassName(reader.ReadUint16())}
+		return ElementClassValue{Value: NewType(r.resolveUTF8(reader.ReadUint16()))}
 	case data.ELEMENT_TAG_ANNOTATION:
 		return ElementAnnotationValue{Value: r.readAnnotation(reader)}
 	case data.ELEMENT_TAG_ENUM:
@@ -635,35 +672,263 @@ func (r *ResolveDataVisitor) resolveMethodExceptions(attrValue data.AttributeVal
 }
 
 func (r *ResolveDataVisitor) resolveMethodCode(attrValue data.AttributeValue) MethodCode {
-	reader := attrValue.Reader()
+	codeData := attrValue.Code()
+	labels := make(map[int]*Label)
+	label := func(offset int) *Label {
+		if l, ok := labels[offset]; ok {
+			return l
+		}
+		l := NewLabel()
+		l.resolve(offset)
+		labels[offset] = l
+		return l
+	}
+
+	exceptions := make([]Exception, codeData.ExceptionTableLength)
+	for i, exceptionData := range codeData.ExceptionTable {
+		exceptions[i] = Exception{
+			Start:     label(int(exceptionData.StartPC)),
+			End:       label(int(exceptionData.EndPC)),
+			Handler:   label(int(exceptionData.HandlerPC)),
+			CatchType: r.resolveClassName(exceptionData.CatchType),
+		}
+	}
 
-	maxStack := reader.ReadUint16()
-	maxLocal := reader.ReadUint16()
-	instructionCount := reader.ReadUint32()
-	instructions := make([]Instruction, instructionCount)
-	for i := uint32(0); i < instructionCount; i++ {
-		opCode := reader.ReadUint8()
-		instructions[i] = CodeInstruction{opCode: opCode}
+	lineNumbers := make(map[int][]*LineNumber)
+	frames := make(map[int]*Frame)
+	localVariables := make([]LocalVariable, 0)
+	attributes := make([]Attribute, 0)
+	for _, attr := range codeData.Attributes {
+		name := r.resolveUTF8(attr.NameIndex)
+		switch name {
+		case data.LINE_NUMBER_TABLE:
+			reader := attr.Value.Reader()
+			count := reader.ReadUint16()
+			for i := uint16(0); i < count; i++ {
+				offset := int(reader.ReadUint16())
+				line := int(reader.ReadUint16())
+				lineNumbers[offset] = append(lineNumbers[offset], &LineNumber{Line: line, Start: label(offset)})
+			}
+		case data.LOCAL_VARIABLE_TABLE:
+			reader := attr.Value.Reader()
+			count := reader.ReadUint16()
+			for i := uint16(0); i < count; i++ {
+				start := int(reader.ReadUint16())
+				length := int(reader.ReadUint16())
+				name := r.resolveUTF8(reader.ReadUint16())
+				descriptor := r.resolveUTF8(reader.ReadUint16())
+				index := int(reader.ReadUint16())
+				localVariables = append(localVariables, LocalVariable{Name: name, Descriptor: descriptor, Start: label(start), End: label(start + length), Index: index})
+			}
+		case data.LOCAL_VARIABLE_TYPE_TABLE, data.RUNTIME_VISIBLE_TYPE_ANNOTATIONS, data.RUNTIME_INVISIBLE_TYPE_ANNOTATIONS:
+		case data.STACK_MAP_TABLE:
+			r.resolveStackMapTable(attr.Value, frames, label)
+		default:
+			attributes = append(attributes, Attribute{Name: name, Content: attr.Value})
+		}
 	}
-	exceptionCount := reader.ReadUint16()
-	exceptions := make([]Exception, exceptionCount)
-	for i := uint16(0); i < exceptionCount; i++ {
-		start := reader.ReadUint32()
-		end := reader.ReadUint32()
-		handler := reader.ReadUint32()
-		className := r.resolveClassName(reader.ReadUint16())
-		exceptions[i] = Exception{StartPC: start, EndPC: end, HandlerPC: handler, CatchType: className}
-	}
-	attributeCount := reader.ReadUint16()
-	attributes := make([]Attribute, attributeCount)
-	for i := uint16(0); i < attributeCount; i++ {
-		name := r.resolveUTF8(reader.ReadUint16())
-		length := reader.ReadUint32()
-		bytes := reader.ReadBytes(length)
-		attributes[i] = Attribute{Name: name, Content: bytes}
+	// The signatures of the local variables are merged after all the variables have been read,
+	// since the LocalVariableTypeTable can precede the LocalVariableTable.
+	for _, attr := range codeData.Attributes {
+		if r.resolveUTF8(attr.NameIndex) != data.LOCAL_VARIABLE_TYPE_TABLE {
+			continue
+		}
+		reader := attr.Value.Reader()
+		count := reader.ReadUint16()
+		for i := uint16(0); i < count; i++ {
+			start := int(reader.ReadUint16())
+			length := int(reader.ReadUint16())
+			reader.ReadUint16()
+			signature := r.resolveUTF8(reader.ReadUint16())
+			index := int(reader.ReadUint16())
+			for j := range localVariables {
+				variable := &localVariables[j]
+				if variable.Start.Offset == start && variable.End.Offset == start+length && variable.Index == index {
+					variable.Signature = signature
+				}
+			}
+		}
+	}
+
+	instructionsData := codeData.Instructions()
+	codeInstructions := make([]Instruction, len(instructionsData))
+	for i, instructionData := range instructionsData {
+		codeInstructions[i] = r.resolveInstruction(instructionData, label)
 	}
 
-	return MethodCode{MaxStack: maxStack, MaxLocal: maxLocal, InstructionCount: instructionCount, Instructions: instructions}
+	// The type annotations are resolved once the instructions are known, and before the labels
+	// of their variable ranges are inserted in the instructions.
+	offsetInstructions := make(map[int]Instruction, len(codeInstructions))
+	for i, instructionData := range instructionsData {
+		offsetInstructions[instructionData.Offset] = codeInstructions[i]
+	}
+	instruction := func(offset int) Instruction {
+		return offsetInstructions[offset]
+	}
+	var visibleTypeAnnotations, invisibleTypeAnnotations []CodeTypeAnnotation
+	for _, attr := range codeData.Attributes {
+		switch r.resolveUTF8(attr.NameIndex) {
+		case data.RUNTIME_VISIBLE_TYPE_ANNOTATIONS:
+			visibleTypeAnnotations = r.resolveCodeTypeAnnotations(attr.Value, true, label, instruction)
+		case data.RUNTIME_INVISIBLE_TYPE_ANNOTATIONS:
+			invisibleTypeAnnotations = r.resolveCodeTypeAnnotations(attr.Value, false, label, instruction)
+		}
+	}
+
+	instructions := make([]Instruction, 0, len(codeInstructions))
+	offsets := make([]int, 0, len(codeInstructions)+1)
+	appendPseudoInstructions := func(offset int) {
+		if l, ok := labels[offset]; ok {
+			instructions = append(instructions, l)
+		}
+		for _, lineNumber := range lineNumbers[offset] {
+			instructions = append(instructions, lineNumber)
+		}
+		if frame, ok := frames[offset]; ok {
+			instructions = append(instructions, frame)
+		}
+		for len(offsets) < len(instructions) {
+			offsets = append(offsets, offset)
+		}
+	}
+	for i, instruction := range codeInstructions {
+		appendPseudoInstructions(instructionsData[i].Offset)
+		instructions = append(instructions, instruction)
+		offsets = append(offsets, instructionsData[i].Offset)
+	}
+	appendPseudoInstructions(int(codeData.CodeLength))
+	offsets = append(offsets, int(codeData.CodeLength))
+
+	return MethodCode{
+		MaxStack:                        codeData.MaxStack,
+		MaxLocal:                        codeData.MaxLocals,
+		Instructions:                    instructions,
+		ExceptionTable:                  exceptions,
+		LocalVariables:                  localVariables,
+		RuntimeVisibleTypeAnnotations:   visibleTypeAnnotations,
+		RuntimeInvisibleTypeAnnotations: invisibleTypeAnnotations,
+		Attributes:                      attributes,
+		offsets:                         offsets,
+		read:                            append([]Instruction(nil), instructions...),
+	}
+}
+
+func (r *ResolveDataVisitor) resolveInstruction(instructionData data.InstructionData, label func(int) *Label) Instruction {
+	opCode := instructionData.OpCode
+	offset := instructionData.Offset
+	switch {
+	case opCode == data.BIPUSH || opCode == data.SIPUSH || opCode == data.NEWARRAY:
+		return &IntInstruction{Op: uint16(opCode), Operand: instructionData.Value}
+	case opCode == data.LDC || opCode == data.LDC_W || opCode == data.LDC2_W:
+		return &LdcInstruction{Value: r.resolveConstantValue(instructionData.Index)}
+	case opCode >= data.ILOAD && opCode <= data.ALOAD, opCode >= data.ISTORE && opCode <= data.ASTORE, opCode == data.RET:
+		return &VarInstruction{Op: uint16(opCode), Var: int(instructionData.Index)}
+	case opCode >= data.ILOAD_0 && opCode < data.IALOAD:
+		return &VarInstruction{Op: uint16(data.ILOAD + (opCode-data.ILOAD_0)/4), Var: int(instructionData.Index)}
+	case opCode >= data.ISTORE_0 && opCode < data.IASTORE:
+		return &VarInstruction{Op: uint16(data.ISTORE + (opCode-data.ISTORE_0)/4), Var: int(instructionData.Index)}
+	case opCode == data.IINC:
+		return &IincInstruction{Var: int(instructionData.Index), Increment: int(instructionData.Value)}
+	case opCode >= data.IFEQ && opCode <= data.JSR, opCode == data.IFNULL, opCode == data.IFNONNULL:
+		return &JumpInstruction{Op: uint16(opCode), Label: label(offset + int(instructionData.Value))}
+	case opCode == data.GOTO_W:
+		return &JumpInstruction{Op: data.GOTO, Label: label(offset + int(instructionData.Value))}
+	case opCode == data.JSR_W:
+		return &JumpInstruction{Op: data.JSR, Label: label(offset + int(instructionData.Value))}
+	case opCode == data.TABLESWITCH:
+		labels := make([]*Label, len(instructionData.Offsets))
+		for i, target := range instructionData.Offsets {
+			labels[i] = label(offset + int(target))
+		}
+		return &TableSwitchInstruction{Min: instructionData.Low, Max: instructionData.High, Default: label(offset + int(instructionData.Value)), Labels: labels}
+	case opCode == data.LOOKUPSWITCH:
+		labels := make([]*Label, len(instructionData.Offsets))
+		for i, target := range instructionData.Offsets {
+			labels[i] = label(offset + int(target))
+		}
+		return &LookupSwitchInstruction{Default: label(offset + int(instructionData.Value)), Keys: instructionData.Keys, Labels: labels}
+	case opCode >= data.GETSTATIC && opCode <= data.PUTFIELD:
+		reference := r.resolveReference(instructionData.Index)
+		return &FieldInstruction{Op: uint16(opCode), Owner: reference.Owner, Name: reference.Name, Descriptor: reference.Descriptor}
+	case opCode >= data.INVOKEVIRTUAL && opCode <= data.INVOKEINTERFACE:
+		reference := r.resolveReference(instructionData.Index)
+		return &MethodInstruction{Op: uint16(opCode), Owner: reference.Owner, Name: reference.Name, Descriptor: reference.Descriptor, IsInterface: reference.IsInterface}
+	case opCode == data.INVOKEDYNAMIC:
+		invokeDynamicData := r.Data().ConstantPool[instructionData.Index].(data.ConstantInvokeDynamicData)
+		name, descriptor := r.resolveNameAndType(invokeDynamicData.NameAndTypeIndex)
+		bootstrapMethod := r.class.BootstrapMethods[invokeDynamicData.BootstrapMethodIndex]
+		return &InvokeDynamicInstruction{Name: name, Descriptor: descriptor, BootstrapMethod: bootstrapMethod.Handle, BootstrapMethodArguments: bootstrapMethod.Arguments}
+	case opCode == data.NEW || opCode == data.ANEWARRAY || opCode == data.CHECKCAST || opCode == data.INSTANCEOF:
+		return &TypeInstruction{Op: uint16(opCode), Type: r.resolveClassName(instructionData.Index)}
+	case opCode == data.MULTIANEWARRAY:
+		return &MultiANewArrayInstruction{Descriptor: r.resolveClassName(instructionData.Index), NumDimensions: int(instructionData.Count)}
+	default:
+		return &CodeInstruction{Op: uint16(opCode)}
+	}
+}
+
+func (r *ResolveDataVisitor) resolveStackMapTable(attrValue data.AttributeValue, frames map[int]*Frame, label func(int) *Label) {
+	reader := attrValue.Reader()
+	count := reader.ReadUint16()
+	offset := -1
+	for i := uint16(0); i < count; i++ {
+		frameType := reader.ReadUint8()
+		frame := &Frame{}
+		var delta int
+		switch {
+		case frameType < 64:
+			frame.Type = data.F_SAME
+			delta = int(frameType)
+		case frameType < 128:
+			frame.Type = data.F_SAME1
+			delta = int(frameType - 64)
+			frame.Stack = []interface{}{r.resolveVerificationType(reader, label)}
+		case frameType == 247:
+			frame.Type = data.F_SAME1
+			delta = int(reader.ReadUint16())
+			frame.Stack = []interface{}{r.resolveVerificationType(reader, label)}
+		case frameType >= 248 && frameType < 251:
+			frame.Type = data.F_CHOP
+			delta = int(reader.ReadUint16())
+			frame.Locals = make([]interface{}, 251-int(frameType))
+		case frameType == 251:
+			frame.Type = data.F_SAME
+			delta = int(reader.ReadUint16())
+		case frameType >= 252 && frameType < 255:
+			frame.Type = data.F_APPEND
+			delta = int(reader.ReadUint16())
+			frame.Locals = make([]interface{}, int(frameType)-251)
+			for j := range frame.Locals {
+				frame.Locals[j] = r.resolveVerificationType(reader, label)
+			}
+		default:
+			frame.Type = data.F_FULL
+			delta = int(reader.ReadUint16())
+			frame.Locals = make([]interface{}, reader.ReadUint16())
+			for j := range frame.Locals {
+				frame.Locals[j] = r.resolveVerificationType(reader, label)
+			}
+			frame.Stack = make([]interface{}, reader.ReadUint16())
+			for j := range frame.Stack {
+				frame.Stack[j] = r.resolveVerificationType(reader, label)
+			}
+		}
+		offset += delta + 1
+		frames[offset] = frame
+		label(offset)
+	}
+}
+
+func (r *ResolveDataVisitor) resolveVerificationType(reader *data.AttributeValueReader, label func(int) *Label) interface{} {
+	tag := reader.ReadUint8()
+	switch tag {
+	case data.ITEM_OBJECT:
+		return r.resolveClassName(reader.ReadUint16())
+	case data.ITEM_UNINITIALIZED:
+		return label(int(reader.ReadUint16()))
+	default:
+		return tag
+	}
 }
 
 func (r *ResolveDataVisitor) resolveNestMembers(attrValue data.AttributeValue) []string {
@@ -671,7 +936,7 @@ func (r *ResolveDataVisitor) resolveNestMembers(attrValue data.AttributeValue) [
 	nestMemberCount := reader.ReadUint16()
 	nestMembers := make([]string, nestMemberCount)
 	for i := uint16(0); i < nestMemberCount; i++ {
-		member := r.resolveUTF8(reader.ReadUint16())
+		member := r.resolveClassName(reader.ReadUint16())
 		nestMembers[i] = member
 	}
 	return nestMembers
@@ -682,14 +947,17 @@ func (r *ResolveDataVisitor) resolveConstantDynamic(index uint16) ConstantDynami
 	}
 	dynamicData := r.Data().ConstantPool[index].(data.ConstantDynamicData)
 	name, descriptor := r.resolveNameAndType(dynamicData.NameAndTypeIndex)
-	bootstrapMethod := r.Class().BootstrapMethods[dynamicData.BootstrapMethodIndex]
-	return ConstantDynamic{Name: name, Descriptor: descriptor, BootstrapMethod: bootstrapMethod.Handle, BootstrapMethodArguments: bootstrapMethod.Arguments}
+	bootstrapMethod := r.class.BootstrapMethods[dynamicData.BootstrapMethodIndex]
+	constantDynamic := ConstantDynamic{Name: name, Descriptor: descriptor, BootstrapMethod: bootstrapMethod.Handle, BootstrapMethodArguments: bootstrapMethod.Arguments}
+	r.constantDynamicValues[index] = constantDynamic
+	return constantDynamic
 }
 
 func (r *ResolveDataVisitor) resolveBootstrapMethods(attrValue data.AttributeValue) []BootstrapMethod {
 	reader := attrValue.Reader()
 	methodCount := reader.ReadUint16()
 	methods := make([]BootstrapMethod, methodCount)
+	r.class.BootstrapMethods = methods
 	for i := uint16(0); i < methodCount; i++ {
 		handle := r.resolveConstantValue(reader.ReadUint16()).(Handle)
 		argCount := reader.ReadUint16()
@@ -0,0 +1,361 @@
+package class
+
+import (
+	"fmt"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+// frameComputer computes the stack map frames and the max stack and max locals values of a
+// method, with a data flow analysis of its instructions.
+type frameComputer struct {
+	method       *Method
+	simulator    *Simulator
+	instructions []Instruction
+	labels       map[*Label]int
+	states       []*FrameState
+	maxStack     int
+	maxLocals    int
+}
+
+// ComputeMaxs computes the max stack and max locals values of a method of the given class.
+func ComputeMaxs(owner string, method *Method) error {
+	return computeFrames(owner, method, false, nil)
+}
+
+// ComputeFrames computes the stack map frames and the max stack and max locals values of a
+// method of the given class, replacing its frames. The reference types are merged with
+// commonSuperClass, which returns the internal name of the common super class of two classes, or
+// to java/lang/Object if it is nil. The JSR and RET instructions and unreachable code are not
+// supported.
+func ComputeFrames(owner string, method *Method, commonSuperClass func(type1 string, type2 string) string) error {
+	return computeFrames(owner, method, true, commonSuperClass)
+}
+
+func computeFrames(owner string, method *Method, frames bool, commonSuperClass func(string, string) string) (err error) {
+	if len(method.Code.Instructions) == 0 {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s.%s%s: %v", owner, method.Name, method.Descriptor, r)
+		}
+	}()
+	c := newFrameComputer(owner, method)
+	c.simulator.CommonSuperClass = commonSuperClass
+	c.prepare(frames)
+	c.analyze()
+	method.Code.MaxStack = uint16(c.maxStack)
+	method.Code.MaxLocal = uint16(c.maxLocals)
+	if frames {
+		method.Code.Instructions = c.frames()
+	}
+	return nil
+}
+
+func newFrameComputer(owner string, method *Method) *frameComputer {
+	return &frameComputer{method: method, simulator: NewSimulator(owner, method), labels: make(map[*Label]int)}
+}
+
+// execute simulates the execution of an instruction on a state.
+func (c *frameComputer) execute(instruction Instruction, state *FrameState) {
+	if err := c.simulator.Execute(instruction, state); err != nil {
+		panic(err)
+	}
+}
+
+// prepare copies the instructions of the method, without its frames if they are computed, and
+// with a label before each NEW instruction, which designates its uninitialized type.
+func (c *frameComputer) prepare(frames bool) {
+	var previous *Label
+	for _, instruction := range c.method.Code.Instructions {
+		switch insn := instruction.(type) {
+		case *Frame:
+			if frames {
+				continue
+			}
+		case *Label:
+			previous = insn
+		case *LineNumber:
+		case *TypeInstruction:
+			if insn.Op == data.NEW {
+				if previous == nil {
+					previous = NewLabel()
+					c.instructions = append(c.instructions, previous)
+				}
+				c.simulator.NewTypes[previous] = insn.Type
+				c.simulator.NewLabels[insn] = previous
+			}
+			previous = nil
+		default:
+			previous = nil
+		}
+		c.instructions = append(c.instructions, instruction)
+	}
+	for i, instruction := range c.instructions {
+		if label, ok := instruction.(*Label); ok {
+			c.labels[label] = i
+		}
+	}
+}
+
+func (c *frameComputer) index(label *Label) int {
+	index, ok := c.labels[label]
+	if !ok {
+		panic("label not visited")
+	}
+	return index
+}
+
+func (c *frameComputer) analyze() {
+	c.states = make([]*FrameState, len(c.instructions))
+	initial := c.simulator.InitialState()
+	c.maxLocals = len(initial.Locals)
+	queue := []int{0}
+	c.states[0] = initial
+	merge := func(index int, state *FrameState) {
+		if c.merge(index, state) {
+			queue = append(queue, index)
+		}
+	}
+	for len(queue) > 0 {
+		index := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		before := c.states[index]
+		instruction := c.instructions[index]
+		if instruction.OpCode() < 0 {
+			if index+1 < len(c.instructions) {
+				merge(index+1, before)
+			}
+			continue
+		}
+		after := before.Copy()
+		c.execute(instruction, after)
+		if len(after.Stack) > c.maxStack {
+			c.maxStack = len(after.Stack)
+		}
+		if len(after.Locals) > c.maxLocals {
+			c.maxLocals = len(after.Locals)
+		}
+		for _, exception := range c.method.Code.ExceptionTable {
+			if index < c.index(exception.Start) || index >= c.index(exception.End) {
+				continue
+			}
+			catchType := exception.CatchType
+			if catchType == "" {
+				catchType = "java/lang/Throwable"
+			}
+			handler := c.index(exception.Handler)
+			merge(handler, &FrameState{Locals: before.Locals, Stack: []interface{}{catchType}})
+			merge(handler, &FrameState{Locals: after.Locals, Stack: []interface{}{catchType}})
+			if c.maxStack < 1 {
+				c.maxStack = 1
+			}
+		}
+		targets, next := Successors(instruction)
+		for _, target := range targets {
+			merge(c.index(target), after)
+		}
+		if next {
+			if index+1 >= len(c.instructions) {
+				panic("execution falls off the end of the code")
+			}
+			merge(index+1, after)
+		}
+	}
+}
+
+// merge merges a state into the state of an instruction, and returns whether the latter changed.
+func (c *frameComputer) merge(index int, state *FrameState) bool {
+	current := c.states[index]
+	if current == nil {
+		c.states[index] = state.Copy()
+		return true
+	}
+	if len(current.Stack) != len(state.Stack) {
+		panic(fmt.Sprintf("inconsistent stack heights %d and %d", len(current.Stack), len(state.Stack)))
+	}
+	changed := false
+	for i, value := range state.Stack {
+		if merged := c.simulator.Merge(current.Stack[i], value); merged != current.Stack[i] {
+			current.Stack[i] = merged
+			changed = true
+		}
+	}
+	for i := range current.Locals {
+		value := interface{}(data.ITEM_TOP)
+		if i < len(state.Locals) {
+			value = state.Locals[i]
+		}
+		if merged := c.simulator.Merge(current.Locals[i], value); merged != current.Locals[i] {
+			current.Locals[i] = merged
+			changed = true
+		}
+	}
+	return changed
+}
+
+// frames returns the instructions with a frame before each instruction that is the target of a
+// jump or of an exception handler.
+func (c *frameComputer) frames() []Instruction {
+	targets := make(map[*Label]bool)
+	for _, instruction := range c.instructions {
+		labels, _ := Successors(instruction)
+		for _, label := range labels {
+			targets[label] = true
+		}
+	}
+	for _, exception := range c.method.Code.ExceptionTable {
+		targets[exception.Handler] = true
+	}
+	previous := compactFrameValues(c.states[0].Locals, true)
+	instructions := make([]Instruction, 0, len(c.instructions))
+	target := false
+	for i, instruction := range c.instructions {
+		if label, ok := instruction.(*Label); ok && targets[label] {
+			target = true
+		}
+		if instruction.OpCode() >= 0 {
+			if c.states[i] == nil {
+				panic(fmt.Sprintf("unreachable instruction %s", data.OPCODE_NAMES[instruction.OpCode()]))
+			}
+			if target {
+				frame := newFrame(previous, c.states[i])
+				previous = compactFrameValues(c.states[i].Locals, true)
+				instructions = append(instructions, frame)
+			}
+			target = false
+		}
+		instructions = append(instructions, instruction)
+	}
+	return instructions
+}
+
+// compactFrameValues returns the values of a frame with one value for the long and double
+// values, and without the trailing top values of the locals.
+func compactFrameValues(values []interface{}, locals bool) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for i := 0; i < len(values); i++ {
+		result = append(result, values[i])
+		if values[i] == data.ITEM_LONG || values[i] == data.ITEM_DOUBLE {
+			i++
+		}
+	}
+	for locals && len(result) > 0 && result[len(result)-1] == data.ITEM_TOP {
+		result = result[:len(result)-1]
+	}
+	return result
+}
+
+// newFrame returns the frame for a state, in the most compact form relative to the locals of the
+// previous frame.
+func newFrame(previous []interface{}, state *FrameState) *Frame {
+	locals := compactFrameValues(state.Locals, true)
+	stack := compactFrameValues(state.Stack, false)
+	common := 0
+	for common < len(locals) && common < len(previous) && locals[common] == previous[common] {
+		common++
+	}
+	switch {
+	case common == len(locals) && common == len(previous) && len(stack) == 0:
+		return &Frame{Type: data.F_SAME}
+	case common == len(locals) && common == len(previous) && len(stack) == 1:
+		return &Frame{Type: data.F_SAME1, Stack: stack}
+	case len(stack) == 0 && common == len(previous) && len(locals)-common <= 3:
+		return &Frame{Type: data.F_APPEND, Locals: locals[common:]}
+	case len(stack) == 0 && common == len(locals) && len(previous)-common <= 3:
+		return &Frame{Type: data.F_CHOP, Locals: make([]interface{}, len(previous)-common)}
+	}
+	return &Frame{Type: data.F_FULL, Locals: locals, Stack: stack}
+}
+
+// longJumpFrames returns the instructions of a method with a frame after each conditional jump
+// encoded with a goto_w, whose opposite condition jumps to the instruction after the goto_w.
+// The frames are computed with a linear simulation of the instructions from the previous frame,
+// and the other frames of the method are rewritten as full frames, since a compressed frame is
+// relative to the previous one. The instructions are returned unchanged if the method has no
+// frames.
+func longJumpFrames(owner string, method *Method, instructions []Instruction, longJumps map[*JumpInstruction]bool) []Instruction {
+	hasFrames := false
+	for _, instruction := range instructions {
+		if _, ok := instruction.(*Frame); ok {
+			hasFrames = true
+			break
+		}
+	}
+	if !hasFrames {
+		return instructions
+	}
+	m := *method
+	m.Code.Instructions = instructions
+	c := newFrameComputer(owner, &m)
+	c.prepare(false)
+	state := c.simulator.InitialState()
+	previous := compactFrameValues(state.Locals, true)
+	result := make([]Instruction, 0, len(c.instructions))
+	for i, instruction := range c.instructions {
+		if frame, ok := instruction.(*Frame); ok {
+			locals := previous
+			switch frame.Type {
+			case data.F_SAME, data.F_SAME1:
+			case data.F_APPEND:
+				locals = append(locals[:len(locals):len(locals)], frame.Locals...)
+			case data.F_CHOP:
+				locals = locals[:len(locals)-len(frame.Locals)]
+			default:
+				locals = frame.Locals
+			}
+			previous = locals
+			state = &FrameState{Locals: expandFrameValues(locals), Stack: expandFrameValues(frame.Stack)}
+			result = append(result, &Frame{Type: data.F_FULL, Locals: locals, Stack: frame.Stack})
+			continue
+		}
+		result = append(result, instruction)
+		if instruction.OpCode() < 0 {
+			continue
+		}
+		jump, ok := instruction.(*JumpInstruction)
+		if ok && longJumps[jump] && jump.Op != data.GOTO && jump.Op != data.JSR && !hasNextFrame(c.instructions[i+1:]) {
+			if state == nil {
+				panic("cannot compute the frame after a long jump in unreachable code")
+			}
+			c.execute(jump, state)
+			result = append(result, &Frame{Type: data.F_FULL,
+				Locals: compactFrameValues(state.Locals, true), Stack: compactFrameValues(state.Stack, false)})
+			continue
+		}
+		if op := instruction.OpCode(); op == data.JSR || op == data.RET {
+			state = nil
+		} else if _, next := Successors(instruction); !next {
+			state = nil
+		} else if state != nil {
+			c.execute(instruction, state)
+		}
+	}
+	return result
+}
+
+// hasNextFrame returns true if a frame precedes the next instruction.
+func hasNextFrame(instructions []Instruction) bool {
+	for _, instruction := range instructions {
+		if _, ok := instruction.(*Frame); ok {
+			return true
+		} else if instruction.OpCode() >= 0 {
+			return false
+		}
+	}
+	return false
+}
+
+// expandFrameValues returns the values of a frame with two slots for the long and double
+// values.
+func expandFrameValues(values []interface{}) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+		if value == data.ITEM_LONG || value == data.ITEM_DOUBLE {
+			result = append(result, data.ITEM_TOP)
+		}
+	}
+	return result
+}
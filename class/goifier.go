@@ -0,0 +1,646 @@
+package class
+
+import (
+	"fmt"
+	"go/format"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+type accessName struct {
+	mask uint16
+	name string
+}
+
+var classAccessNames = []accessName{{data.ACC_PUBLIC, "ACC_PUBLIC"}, {data.ACC_PRIVATE, "ACC_PRIVATE"},
+	{data.ACC_PROTECTED, "ACC_PROTECTED"}, {data.ACC_STATIC, "ACC_STATIC"}, {data.ACC_FINAL, "ACC_FINAL"},
+	{data.ACC_SUPER, "ACC_SUPER"}, {data.ACC_INTERFACE, "ACC_INTERFACE"}, {data.ACC_ABSTRACT, "ACC_ABSTRACT"},
+	{data.ACC_SYNTHETIC, "ACC_SYNTHETIC"}, {data.ACC_ANNOTATION, "ACC_ANNOTATION"}, {data.ACC_ENUM, "ACC_ENUM"},
+	{data.ACC_MODULE, "ACC_MODULE"}}
+
+var fieldAccessNames = []accessName{{data.ACC_PUBLIC, "ACC_PUBLIC"}, {data.ACC_PRIVATE, "ACC_PRIVATE"},
+	{data.ACC_PROTECTED, "ACC_PROTECTED"}, {data.ACC_STATIC, "ACC_STATIC"}, {data.ACC_FINAL, "ACC_FINAL"},
+	{data.ACC_VOLATILE, "ACC_VOLATILE"}, {data.ACC_TRANSIENT, "ACC_TRANSIENT"}, {data.ACC_SYNTHETIC, "ACC_SYNTHETIC"},
+	{data.ACC_ENUM, "ACC_ENUM"}, {data.ACC_MANDATED, "ACC_MANDATED"}}
+
+var methodAccessNames = []accessName{{data.ACC_PUBLIC, "ACC_PUBLIC"}, {data.ACC_PRIVATE, "ACC_PRIVATE"},
+	{data.ACC_PROTECTED, "ACC_PROTECTED"}, {data.ACC_STATIC, "ACC_STATIC"}, {data.ACC_FINAL, "ACC_FINAL"},
+	{data.ACC_SYNCHRONIZED, "ACC_SYNCHRONIZED"}, {data.ACC_BRIDGE, "ACC_BRIDGE"}, {data.ACC_VARARGS, "ACC_VARARGS"},
+	{data.ACC_NATIVE, "ACC_NATIVE"}, {data.ACC_ABSTRACT, "ACC_ABSTRACT"}, {data.ACC_STRICT, "ACC_STRICT"},
+	{data.ACC_SYNTHETIC, "ACC_SYNTHETIC"}, {data.ACC_MANDATED, "ACC_MANDATED"}}
+
+var moduleAccessNames = []accessName{{data.ACC_OPEN, "ACC_OPEN"}, {data.ACC_STATIC_PHASE, "ACC_STATIC_PHASE"},
+	{data.ACC_SYNTHETIC, "ACC_SYNTHETIC"}, {data.ACC_MANDATED, "ACC_MANDATED"}}
+
+var requireAccessNames = []accessName{{data.ACC_TRANSITIVE, "ACC_TRANSITIVE"},
+	{data.ACC_STATIC_PHASE, "ACC_STATIC_PHASE"}, {data.ACC_SYNTHETIC, "ACC_SYNTHETIC"},
+	{data.ACC_MANDATED, "ACC_MANDATED"}}
+
+var frameNames = map[int]string{data.F_NEW: "F_NEW", data.F_FULL: "F_FULL", data.F_APPEND: "F_APPEND",
+	data.F_CHOP: "F_CHOP", data.F_SAME: "F_SAME", data.F_SAME1: "F_SAME1"}
+
+var itemNames = []string{"ITEM_TOP", "ITEM_INTEGER", "ITEM_FLOAT", "ITEM_DOUBLE", "ITEM_LONG", "ITEM_NULL",
+	"ITEM_UNINITIALIZED_THIS"}
+
+var handleNames = []string{"0", "HANDLE_GETFIELD", "HANDLE_GETSTATIC", "HANDLE_PUTFIELD", "HANDLE_PUTSTATIC",
+	"HANDLE_INVOKEVIRTUAL", "HANDLE_INVOKESTATIC", "HANDLE_INVOKESPECIAL", "HANDLE_NEWINVOKESPECIAL",
+	"HANDLE_INVOKEINTERFACE"}
+
+var typePathNames = []string{"TYPE_PATH_ARRAY_ELEMENT", "TYPE_PATH_INNER_TYPE", "TYPE_PATH_WILDCARD_BOUND",
+	"TYPE_PATH_TYPE_ARGUMENT"}
+
+// Goifier is a Visitor that generates the Go source of a function rebuilding the visited class
+// with a Writer. The generated function returns the bytes of the class and an error:
+//
+//	func Dump() ([]byte, error) {
+//		writer := class.NewWriter()
+//		writer.Visit(52, data.ACC_PUBLIC|data.ACC_SUPER, "a/A", "", "java/lang/Object", nil)
+//		...
+//		writer.VisitEnd()
+//		return writer.Bytes()
+//	}
+//
+// If the package is "main", a main function writing the class to the standard output is
+// generated too.
+type Goifier struct {
+	code        strings.Builder
+	pkg         string
+	function    string
+	imports     map[string]bool
+	labelNames  map[*Label]string
+	annotations int
+}
+
+func NewGoifier(pkg string, function string) *Goifier {
+	return &Goifier{pkg: pkg, function: function, imports: make(map[string]bool), labelNames: make(map[*Label]string)}
+}
+
+// Source returns the formatted Go source generated so far. It is complete once VisitEnd has
+// been called.
+func (g *Goifier) Source() ([]byte, error) {
+	var source strings.Builder
+	source.WriteString("// Code generated by goifier. DO NOT EDIT.\n\n")
+	source.WriteString("package " + g.pkg + "\n\nimport (\n")
+	if g.pkg == "main" {
+		source.WriteString("\"fmt\"\n")
+	}
+	if g.imports["math"] {
+		source.WriteString("\"math\"\n")
+	}
+	if g.pkg == "main" {
+		source.WriteString("\"os\"\n")
+	}
+	source.WriteString("\n\"github.com/tk103331/clazz/class\"\n")
+	if g.imports["data"] {
+		source.WriteString("\"github.com/tk103331/clazz/class/data\"\n")
+	}
+	source.WriteString(")\n\n")
+	if g.pkg == "main" {
+		fmt.Fprintf(&source, "func main() {\ncontent, err := %s()\nif err != nil {\n"+
+			"fmt.Fprintln(os.Stderr, err)\nos.Exit(1)\n}\nos.Stdout.Write(content)\n}\n\n", g.function)
+	}
+	source.WriteString(g.code.String())
+	return format.Source([]byte(source.String()))
+}
+
+func (g *Goifier) println(format string, args ...interface{}) {
+	fmt.Fprintf(&g.code, format, args...)
+	g.code.WriteByte('\n')
+}
+
+// call generates a method call on a visitor, with the given Go expressions as arguments.
+func (g *Goifier) call(receiver string, method string, args ...string) {
+	g.println("%s.%s(%s)", receiver, method, strings.Join(args, ", "))
+}
+
+func (g *Goifier) Visit(version uint32, access uint16, name string, signature string, superName string, interfaces []string) {
+	g.println("func %s() ([]byte, error) {", g.function)
+	g.println("writer := class.NewWriter()")
+	g.call("writer", "Visit", strconv.FormatUint(uint64(version), 10), g.access(access, classAccessNames),
+		strconv.Quote(name), strconv.Quote(signature), strconv.Quote(superName), stringsValue(interfaces))
+}
+
+func (g *Goifier) VisitSource(source string, debug string) {
+	g.call("writer", "VisitSource", strconv.Quote(source), strconv.Quote(debug))
+}
+
+func (g *Goifier) VisitModule(name string, access uint16, version string) ModuleVisitor {
+	g.println("{")
+	g.println("module := writer.VisitModule(%s, %s, %s)", strconv.Quote(name), g.access(access, moduleAccessNames),
+		strconv.Quote(version))
+	return &goModuleVisitor{g: g}
+}
+
+func (g *Goifier) VisitNestHost(nestHost string) {
+	g.call("writer", "VisitNestHost", strconv.Quote(nestHost))
+}
+
+func (g *Goifier) VisitOuterClass(owner string, name string, descriptor string) {
+	g.call("writer", "VisitOuterClass", strconv.Quote(owner), strconv.Quote(name), strconv.Quote(descriptor))
+}
+
+func (g *Goifier) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return g.annotation("writer", "VisitAnnotation", strconv.Quote(descriptor), strconv.FormatBool(visible))
+}
+
+func (g *Goifier) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return g.annotation("writer", "VisitTypeAnnotation", typeRefValue(typeRef), g.typePath(typePath), strconv.Quote(descriptor), strconv.FormatBool(visible))
+}
+
+func (g *Goifier) VisitAttribute(attribute Attribute) {
+	g.call("writer", "VisitAttribute", attributeValue(attribute))
+}
+
+func (g *Goifier) VisitNestMember(nestMember string) {
+	g.call("writer", "VisitNestMember", strconv.Quote(nestMember))
+}
+
+func (g *Goifier) VisitPermittedSubclass(permittedSubclass string) {
+	g.call("writer", "VisitPermittedSubclass", strconv.Quote(permittedSubclass))
+}
+
+func (g *Goifier) VisitInnerClass(name string, outerName string, innerName string, access uint16) {
+	g.call("writer", "VisitInnerClass", strconv.Quote(name), strconv.Quote(outerName), strconv.Quote(innerName),
+		g.access(access, classAccessNames))
+}
+
+func (g *Goifier) VisitRecordComponent(name string, descriptor string, signature string) RecordComponentVisitor {
+	g.println("{")
+	g.println("recordComponent := writer.VisitRecordComponent(%s, %s, %s)", strconv.Quote(name),
+		strconv.Quote(descriptor), strconv.Quote(signature))
+	return &goFieldVisitor{g: g, name: "recordComponent"}
+}
+
+func (g *Goifier) VisitField(access uint16, name string, descriptor string, signature string, value interface{}) FieldVisitor {
+	g.println("{")
+	g.println("field := writer.VisitField(%s, %s, %s, %s, %s)", g.access(access, fieldAccessNames),
+		strconv.Quote(name), strconv.Quote(descriptor), strconv.Quote(signature), g.value(value))
+	return &goFieldVisitor{g: g, name: "field"}
+}
+
+func (g *Goifier) VisitMethod(access uint16, name string, descriptor string, signature string, exceptions []string) MethodVisitor {
+	g.labelNames = make(map[*Label]string)
+	g.println("{")
+	g.println("method := writer.VisitMethod(%s, %s, %s, %s, %s)", g.access(access, methodAccessNames),
+		strconv.Quote(name), strconv.Quote(descriptor), strconv.Quote(signature), stringsValue(exceptions))
+	return &goMethodVisitor{g: g}
+}
+
+func (g *Goifier) VisitEnd() {
+	g.println("writer.VisitEnd()")
+	g.println("return writer.Bytes()")
+	g.println("}")
+}
+
+// annotation opens a block declaring the visitor of an annotation.
+func (g *Goifier) annotation(receiver string, method string, args ...string) AnnotationVisitor {
+	name := "annotation" + strconv.Itoa(g.annotations)
+	g.annotations++
+	g.println("{")
+	g.println("%s := %s.%s(%s)", name, receiver, method, strings.Join(args, ", "))
+	return &goAnnotationVisitor{g: g, name: name}
+}
+
+// access returns an expression of access flags, made of the data.ACC_* constants of a kind of
+// element, and of a hexadecimal value for the unknown flags.
+func (g *Goifier) access(access uint16, names []accessName) string {
+	if access == 0 {
+		return "0"
+	}
+	parts := make([]string, 0)
+	for _, name := range names {
+		if access&name.mask != 0 {
+			parts = append(parts, "data."+name.name)
+			access &^= name.mask
+		}
+	}
+	if len(parts) > 0 {
+		g.imports["data"] = true
+	}
+	if access != 0 {
+		parts = append(parts, fmt.Sprintf("0x%04x", access))
+	}
+	return strings.Join(parts, "|")
+}
+
+func (g *Goifier) opCode(opCode uint16) string {
+	if int(opCode) >= len(data.OPCODE_NAMES) {
+		return strconv.Itoa(int(opCode))
+	}
+	g.imports["data"] = true
+	return "data." + strings.ToUpper(data.OPCODE_NAMES[opCode])
+}
+
+// value returns the Go expression of a constant, with the type of the visitor arguments.
+func (g *Goifier) value(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int8:
+		return fmt.Sprintf("int8(%d)", v)
+	case int16:
+		return fmt.Sprintf("int16(%d)", v)
+	case uint16:
+		return fmt.Sprintf("uint16(%d)", v)
+	case int32:
+		return fmt.Sprintf("int32(%d)", v)
+	case int64:
+		return fmt.Sprintf("int64(%d)", v)
+	case int:
+		return strconv.Itoa(v)
+	case uint8:
+		return fmt.Sprintf("uint8(%d)", v)
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) || (v == 0 && math.Signbit(float64(v))) {
+			g.imports["math"] = true
+			return fmt.Sprintf("math.Float32frombits(0x%08x)", math.Float32bits(v))
+		}
+		return "float32(" + strconv.FormatFloat(float64(v), 'g', -1, 32) + ")"
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) || (v == 0 && math.Signbit(v)) {
+			g.imports["math"] = true
+			return fmt.Sprintf("math.Float64frombits(0x%016x)", math.Float64bits(v))
+		}
+		return "float64(" + strconv.FormatFloat(v, 'g', -1, 64) + ")"
+	case Type:
+		switch v.Sort() {
+		case data.TYPE_SORT_INTERNAL:
+			return "class.NewObjectType(" + strconv.Quote(v.InternalName()) + ")"
+		case data.TYPE_SORT_METHOD:
+			return "class.NewMethodType(" + strconv.Quote(v.Descriptor()) + ")"
+		default:
+			return "class.NewType(" + strconv.Quote(v.Descriptor()) + ")"
+		}
+	case Handle:
+		return g.handle(v)
+	case ConstantDynamic:
+		return fmt.Sprintf("class.ConstantDynamic{Name: %s, Descriptor: %s, BootstrapMethod: %s, "+
+			"BootstrapMethodArguments: %s}", strconv.Quote(v.Name), strconv.Quote(v.Descriptor),
+			g.handle(v.BootstrapMethod), g.values(v.BootstrapMethodArguments))
+	case *Label:
+		return g.label(v)
+	default:
+		return fmt.Sprintf("%#v", v)
+	}
+}
+
+func (g *Goifier) values(values []interface{}) string {
+	if values == nil {
+		return "nil"
+	}
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = g.value(value)
+	}
+	return "[]interface{}{" + strings.Join(texts, ", ") + "}"
+}
+
+func (g *Goifier) handle(handle Handle) string {
+	tag := strconv.Itoa(int(handle.Tag))
+	if handle.Tag > 0 && int(handle.Tag) < len(handleNames) {
+		g.imports["data"] = true
+		tag = "data." + handleNames[handle.Tag]
+	}
+	return fmt.Sprintf("class.Handle{Tag: %s, Owner: %s, Name: %s, Descriptor: %s, IsInterface: %t}", tag,
+		strconv.Quote(handle.Owner), strconv.Quote(handle.Name), strconv.Quote(handle.Descriptor), handle.IsInterface)
+}
+
+// label returns the variable of a label, declared before the current statement when the label
+// is first used.
+func (g *Goifier) label(label *Label) string {
+	if label == nil {
+		return "nil"
+	}
+	name, ok := g.labelNames[label]
+	if !ok {
+		name = "label" + strconv.Itoa(len(g.labelNames))
+		g.labelNames[label] = name
+		g.println("%s := class.NewLabel()", name)
+	}
+	return name
+}
+
+func (g *Goifier) labels(labels []*Label) string {
+	names := make([]string, len(labels))
+	for i, label := range labels {
+		names[i] = g.label(label)
+	}
+	return "[]*class.Label{" + strings.Join(names, ", ") + "}"
+}
+
+// typePath returns the expression of a type path.
+func (g *Goifier) typePath(typePath TypePath) string {
+	if len(typePath) == 0 {
+		return "nil"
+	}
+	g.imports["data"] = true
+	steps := make([]string, len(typePath))
+	for i, step := range typePath {
+		kind := strconv.Itoa(int(step.Kind))
+		if int(step.Kind) < len(typePathNames) {
+			kind = "data." + typePathNames[step.Kind]
+		}
+		steps[i] = fmt.Sprintf("{Kind: %s, TypeArgumentIndex: %d}", kind, step.TypeArgumentIndex)
+	}
+	return "class.TypePath{" + strings.Join(steps, ", ") + "}"
+}
+
+// frameValues returns the expression of the locals or stack of a frame.
+func (g *Goifier) frameValues(values []interface{}) string {
+	if values == nil {
+		return "nil"
+	}
+	texts := make([]string, len(values))
+	for i, value := range values {
+		if item, ok := value.(uint8); ok && int(item) < len(itemNames) {
+			g.imports["data"] = true
+			texts[i] = "data." + itemNames[item]
+		} else {
+			texts[i] = g.value(value)
+		}
+	}
+	return "[]interface{}{" + strings.Join(texts, ", ") + "}"
+}
+
+func stringsValue(values []string) string {
+	if values == nil {
+		return "nil"
+	}
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = strconv.Quote(value)
+	}
+	return "[]string{" + strings.Join(texts, ", ") + "}"
+}
+
+func int32sValue(values []int32) string {
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = strconv.Itoa(int(value))
+	}
+	return "[]int32{" + strings.Join(texts, ", ") + "}"
+}
+
+func typeRefValue(typeRef int) string {
+	return fmt.Sprintf("0x%08x", typeRef)
+}
+
+func attributeValue(attribute Attribute) string {
+	content := "nil"
+	if attribute.Content != nil {
+		bytes := make([]string, len(attribute.Content))
+		for i, b := range attribute.Content {
+			bytes[i] = fmt.Sprintf("0x%02x", b)
+		}
+		content = "[]byte{" + strings.Join(bytes, ", ") + "}"
+	}
+	return fmt.Sprintf("class.Attribute{Name: %s, Content: %s}", strconv.Quote(attribute.Name), content)
+}
+
+type goModuleVisitor struct {
+	g *Goifier
+}
+
+func (m *goModuleVisitor) VisitMainClass(mainClass string) {
+	m.g.call("module", "VisitMainClass", strconv.Quote(mainClass))
+}
+
+func (m *goModuleVisitor) VisitPackage(packageName string) {
+	m.g.call("module", "VisitPackage", strconv.Quote(packageName))
+}
+
+func (m *goModuleVisitor) VisitRequire(moduleName string, access uint16, version string) {
+	m.g.call("module", "VisitRequire", strconv.Quote(moduleName), m.g.access(access, requireAccessNames),
+		strconv.Quote(version))
+}
+
+func (m *goModuleVisitor) VisitExport(packageName string, access uint16, modules []string) {
+	m.g.call("module", "VisitExport", strconv.Quote(packageName), m.g.access(access, moduleAccessNames),
+		stringsValue(modules))
+}
+
+func (m *goModuleVisitor) VisitOpen(packageName string, access uint16, modules []string) {
+	m.g.call("module", "VisitOpen", strconv.Quote(packageName), m.g.access(access, moduleAccessNames),
+		stringsValue(modules))
+}
+
+func (m *goModuleVisitor) VisitUse(service string) {
+	m.g.call("module", "VisitUse", strconv.Quote(service))
+}
+
+func (m *goModuleVisitor) VisitProvide(service string, providers []string) {
+	m.g.call("module", "VisitProvide", strconv.Quote(service), stringsValue(providers))
+}
+
+func (m *goModuleVisitor) VisitEnd() {
+	m.g.call("module", "VisitEnd")
+	m.g.println("}")
+}
+
+// goFieldVisitor generates the calls of a field or record component visitor.
+type goFieldVisitor struct {
+	g    *Goifier
+	name string
+}
+
+func (f *goFieldVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return f.g.annotation(f.name, "VisitAnnotation", strconv.Quote(descriptor), strconv.FormatBool(visible))
+}
+
+func (f *goFieldVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return f.g.annotation(f.name, "VisitTypeAnnotation", typeRefValue(typeRef), f.g.typePath(typePath), strconv.Quote(descriptor), strconv.FormatBool(visible))
+}
+
+func (f *goFieldVisitor) VisitAttribute(attribute Attribute) {
+	f.g.call(f.name, "VisitAttribute", attributeValue(attribute))
+}
+
+func (f *goFieldVisitor) VisitEnd() {
+	f.g.call(f.name, "VisitEnd")
+	f.g.println("}")
+}
+
+type goAnnotationVisitor struct {
+	g    *Goifier
+	name string
+}
+
+func (a *goAnnotationVisitor) Visit(name string, value interface{}) {
+	a.g.call(a.name, "Visit", strconv.Quote(name), a.g.value(value))
+}
+
+func (a *goAnnotationVisitor) VisitEnum(name string, descriptor string, value string) {
+	a.g.call(a.name, "VisitEnum", strconv.Quote(name), strconv.Quote(descriptor), strconv.Quote(value))
+}
+
+func (a *goAnnotationVisitor) VisitAnnotation(name string, descriptor string) AnnotationVisitor {
+	return a.g.annotation(a.name, "VisitAnnotation", strconv.Quote(name), strconv.Quote(descriptor))
+}
+
+func (a *goAnnotationVisitor) VisitArray(name string) AnnotationVisitor {
+	return a.g.annotation(a.name, "VisitArray", strconv.Quote(name))
+}
+
+func (a *goAnnotationVisitor) VisitEnd() {
+	a.g.call(a.name, "VisitEnd")
+	a.g.println("}")
+}
+
+type goMethodVisitor struct {
+	g *Goifier
+}
+
+func (m *goMethodVisitor) VisitParameter(name string, access uint16) {
+	m.g.call("method", "VisitParameter", strconv.Quote(name), m.g.access(access, methodAccessNames))
+}
+
+func (m *goMethodVisitor) VisitAnnotationDefault() AnnotationVisitor {
+	return m.g.annotation("method", "VisitAnnotationDefault")
+}
+
+func (m *goMethodVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return m.g.annotation("method", "VisitAnnotation", strconv.Quote(descriptor), strconv.FormatBool(visible))
+}
+
+func (m *goMethodVisitor) VisitAnnotableParameterCount(parameterCount int, visible bool) {
+	m.g.call("method", "VisitAnnotableParameterCount", strconv.Itoa(parameterCount), strconv.FormatBool(visible))
+}
+
+func (m *goMethodVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return m.g.annotation("method", "VisitTypeAnnotation", typeRefValue(typeRef), m.g.typePath(typePath), strconv.Quote(descriptor), strconv.FormatBool(visible))
+}
+
+func (m *goMethodVisitor) VisitParameterAnnotation(parameterIndex int, descriptor string, visible bool) AnnotationVisitor {
+	return m.g.annotation("method", "VisitParameterAnnotation", strconv.Itoa(parameterIndex),
+		strconv.Quote(descriptor), strconv.FormatBool(visible))
+}
+
+func (m *goMethodVisitor) VisitAttribute(attribute Attribute) {
+	m.g.call("method", "VisitAttribute", attributeValue(attribute))
+}
+
+func (m *goMethodVisitor) VisitCode() {
+	m.g.call("method", "VisitCode")
+}
+
+func (m *goMethodVisitor) VisitFrame(frameType int, numLocal int, locals []interface{}, numStack int, stacks []interface{}) {
+	name := strconv.Itoa(frameType)
+	if frameName, ok := frameNames[frameType]; ok {
+		m.g.imports["data"] = true
+		name = "data." + frameName
+	}
+	localsValue := m.g.frameValues(locals)
+	stackValue := m.g.frameValues(stacks)
+	m.g.call("method", "VisitFrame", name, strconv.Itoa(numLocal), localsValue, strconv.Itoa(numStack), stackValue)
+}
+
+func (m *goMethodVisitor) VisitInstruction(opCode uint16) {
+	m.g.call("method", "VisitInstruction", m.g.opCode(opCode))
+}
+
+func (m *goMethodVisitor) VisitIntInstruction(opCode uint16, operand int32) {
+	m.g.call("method", "VisitIntInstruction", m.g.opCode(opCode), strconv.Itoa(int(operand)))
+}
+
+func (m *goMethodVisitor) VisitVarInstruction(opCode uint16, variable int) {
+	m.g.call("method", "VisitVarInstruction", m.g.opCode(opCode), strconv.Itoa(variable))
+}
+
+func (m *goMethodVisitor) VisitTypeInstruction(opCode uint16, typeName string) {
+	m.g.call("method", "VisitTypeInstruction", m.g.opCode(opCode), strconv.Quote(typeName))
+}
+
+func (m *goMethodVisitor) VisitFieldInstruction(opCode uint16, owner string, name string, descriptor string) {
+	m.g.call("method", "VisitFieldInstruction", m.g.opCode(opCode), strconv.Quote(owner), strconv.Quote(name),
+		strconv.Quote(descriptor))
+}
+
+func (m *goMethodVisitor) VisitMethodInstruction(opCode uint16, owner string, name string, descriptor string, isInterface bool) {
+	m.g.call("method", "VisitMethodInstruction", m.g.opCode(opCode), strconv.Quote(owner), strconv.Quote(name),
+		strconv.Quote(descriptor), strconv.FormatBool(isInterface))
+}
+
+func (m *goMethodVisitor) VisitInvokeDynamicInstruction(opCode uint16, name string, descriptor string, bootstrapMethodHandle Handle, bootstrapMethodArguments []interface{}) {
+	m.g.call("method", "VisitInvokeDynamicInstruction", m.g.opCode(opCode), strconv.Quote(name),
+		strconv.Quote(descriptor), m.g.handle(bootstrapMethodHandle), m.g.values(bootstrapMethodArguments))
+}
+
+func (m *goMethodVisitor) VisitJumpInstruction(opCode uint16, label *Label) {
+	m.g.call("method", "VisitJumpInstruction", m.g.opCode(opCode), m.g.label(label))
+}
+
+func (m *goMethodVisitor) VisitLabel(label *Label) {
+	m.g.call("method", "VisitLabel", m.g.label(label))
+}
+
+func (m *goMethodVisitor) VisitLdcInstruction(value interface{}) {
+	m.g.call("method", "VisitLdcInstruction", m.g.value(value))
+}
+
+func (m *goMethodVisitor) VisitIincInstruction(variable int, increment int) {
+	m.g.call("method", "VisitIincInstruction", strconv.Itoa(variable), strconv.Itoa(increment))
+}
+
+func (m *goMethodVisitor) VisitTableSwitchInstruction(min int32, max int32, dflt *Label, labels []*Label) {
+	dfltValue := m.g.label(dflt)
+	m.g.call("method", "VisitTableSwitchInstruction", strconv.Itoa(int(min)), strconv.Itoa(int(max)), dfltValue,
+		m.g.labels(labels))
+}
+
+func (m *goMethodVisitor) VisitLookupSwitchInstruction(dflt *Label, keys []int32, labels []*Label) {
+	dfltValue := m.g.label(dflt)
+	m.g.call("method", "VisitLookupSwitchInstruction", dfltValue, int32sValue(keys), m.g.labels(labels))
+}
+
+func (m *goMethodVisitor) VisitMultiANewArrayInstruction(descriptor string, numDimensions int) {
+	m.g.call("method", "VisitMultiANewArrayInstruction", strconv.Quote(descriptor), strconv.Itoa(numDimensions))
+}
+
+func (m *goMethodVisitor) VisitInstructionAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return m.g.annotation("method", "VisitInstructionAnnotation", typeRefValue(typeRef), m.g.typePath(typePath), strconv.Quote(descriptor), strconv.FormatBool(visible))
+}
+
+func (m *goMethodVisitor) VisitTryCatchBlock(start *Label, end *Label, handler *Label, typeName string) {
+	startValue, endValue, handlerValue := m.g.label(start), m.g.label(end), m.g.label(handler)
+	m.g.call("method", "VisitTryCatchBlock", startValue, endValue, handlerValue, strconv.Quote(typeName))
+}
+
+func (m *goMethodVisitor) VisitTryCatchAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return m.g.annotation("method", "VisitTryCatchAnnotation", typeRefValue(typeRef), m.g.typePath(typePath), strconv.Quote(descriptor), strconv.FormatBool(visible))
+}
+
+func (m *goMethodVisitor) VisitLocalVariable(name string, descriptor string, signature string, start *Label, end *Label, index int) {
+	startValue, endValue := m.g.label(start), m.g.label(end)
+	m.g.call("method", "VisitLocalVariable", strconv.Quote(name), strconv.Quote(descriptor), strconv.Quote(signature),
+		startValue, endValue, strconv.Itoa(index))
+}
+
+func (m *goMethodVisitor) VisitLocalVariableAnnotation(typeRef int, typePath TypePath, start []*Label, end []*Label, index []int, descriptor string, visible bool) AnnotationVisitor {
+	startValue, endValue := m.g.labels(start), m.g.labels(end)
+	indexes := make([]string, len(index))
+	for i, variable := range index {
+		indexes[i] = strconv.Itoa(variable)
+	}
+	return m.g.annotation("method", "VisitLocalVariableAnnotation", typeRefValue(typeRef), m.g.typePath(typePath),
+		startValue, endValue, "[]int{"+strings.Join(indexes, ", ")+"}", strconv.Quote(descriptor), strconv.FormatBool(visible))
+}
+
+func (m *goMethodVisitor) VisitLineNumber(line int, start *Label) {
+	m.g.call("method", "VisitLineNumber", strconv.Itoa(line), m.g.label(start))
+}
+
+func (m *goMethodVisitor) VisitMaxs(maxStack int, maxLocals int) {
+	m.g.call("method", "VisitMaxs", strconv.Itoa(maxStack), strconv.Itoa(maxLocals))
+}
+
+func (m *goMethodVisitor) VisitEnd() {
+	m.g.call("method", "VisitEnd")
+	m.g.println("}")
+}
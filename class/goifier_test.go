@@ -0,0 +1,91 @@
+package class
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/tools"
+)
+
+func goifyHello(t *testing.T, pkg string) (*Reader, []byte) {
+	t.Helper()
+	f, err := os.Open("Hello.class")
+	tools.AssertNoErr(t, err)
+	defer f.Close()
+	reader := NewReader(f)
+	tools.AssertNoErr(t, reader.Read())
+
+	goifier := NewGoifier(pkg, "Hello")
+	reader.Accept(goifier)
+	source, err := goifier.Source()
+	tools.AssertNoErr(t, err)
+	return reader, source
+}
+
+func TestGoifier(t *testing.T) {
+	_, source := goifyHello(t, "fixtures")
+	text := string(source)
+	for _, line := range []string{
+		"package fixtures",
+		"func Hello() ([]byte, error) {",
+		`	writer.Visit(52, data.ACC_PUBLIC|data.ACC_SUPER, "com/example/demo/Hello", "", "java/lang/Object", []string{})`,
+		`		field := writer.VisitField(data.ACC_PRIVATE, "x", "I", "", nil)`,
+		`		method := writer.VisitMethod(0, "<init>", "()V", "", nil)`,
+		"		label0 := class.NewLabel()",
+		"		method.VisitLineNumber(11, label0)",
+		"		method.VisitIntInstruction(data.SIPUSH, 233)",
+		`		method.VisitFieldInstruction(data.PUTFIELD, "com/example/demo/Hello", "x", "I")`,
+		"	return writer.Bytes()",
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, text)
+		}
+	}
+	if strings.Contains(text, "func main()") {
+		t.Errorf("unexpected main function in:\n%s", text)
+	}
+}
+
+func TestGoifierRun(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	root, err := filepath.Abs("..")
+	tools.AssertNoErr(t, err)
+	dir, err := ioutil.TempDir("", "goifier")
+	tools.AssertNoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	reader, source := goifyHello(t, "main")
+	goMod := fmt.Sprintf("module goifier\n\ngo 1.13\n\nrequire github.com/tk103331/clazz v0.0.0\n\n"+
+		"replace github.com/tk103331/clazz => %s\n", root)
+	tools.AssertNoErr(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644))
+	tools.AssertNoErr(t, ioutil.WriteFile(filepath.Join(dir, "main.go"), source, 0644))
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(goTool, "run", ".")
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("go run: %v\n%s", err, stderr.String())
+	}
+	if !bytes.Equal(writeClass(t, reader.Class()), stdout.Bytes()) {
+		t.Errorf("generated program wrote a different class")
+	}
+
+	generated := NewReader(bytes.NewReader(stdout.Bytes()))
+	tools.AssertNoErr(t, generated.Read())
+	var out bytes.Buffer
+	generated.Class().Accept(NewTraceVisitor(nil, &out))
+	if text := out.String(); text != helloTrace {
+		t.Errorf("unexpected trace of the generated class:\n%s", text)
+	}
+}
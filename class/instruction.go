@@ -0,0 +1,233 @@
+package class
+
+import "github.com/tk103331/clazz/class/data"
+
+// An Instruction is an element of the instruction list of a method. Labels, line numbers and
+// stack map frames are pseudo instructions, whose OpCode is -1.
+type Instruction interface {
+	OpCode() int
+	Accept(visitor MethodVisitor)
+}
+
+// CodeInstruction is an instruction without operand.
+type CodeInstruction struct {
+	Op uint16
+}
+
+func (c *CodeInstruction) OpCode() int {
+	return int(c.Op)
+}
+
+func (c *CodeInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitInstruction(c.Op)
+}
+
+// IntInstruction is a BIPUSH, SIPUSH or NEWARRAY instruction.
+type IntInstruction struct {
+	Op      uint16
+	Operand int32
+}
+
+func (i *IntInstruction) OpCode() int {
+	return int(i.Op)
+}
+
+func (i *IntInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitIntInstruction(i.Op, i.Operand)
+}
+
+// VarInstruction is an instruction that loads or stores a local variable, or a RET instruction.
+type VarInstruction struct {
+	Op  uint16
+	Var int
+}
+
+func (v *VarInstruction) OpCode() int {
+	return int(v.Op)
+}
+
+func (v *VarInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitVarInstruction(v.Op, v.Var)
+}
+
+// TypeInstruction is a NEW, ANEWARRAY, CHECKCAST or INSTANCEOF instruction.
+type TypeInstruction struct {
+	Op   uint16
+	Type string
+}
+
+func (t *TypeInstruction) OpCode() int {
+	return int(t.Op)
+}
+
+func (t *TypeInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitTypeInstruction(t.Op, t.Type)
+}
+
+type FieldInstruction struct {
+	Op         uint16
+	Owner      string
+	Name       string
+	Descriptor string
+}
+
+func (f *FieldInstruction) OpCode() int {
+	return int(f.Op)
+}
+
+func (f *FieldInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitFieldInstruction(f.Op, f.Owner, f.Name, f.Descriptor)
+}
+
+type MethodInstruction struct {
+	Op          uint16
+	Owner       string
+	Name        string
+	Descriptor  string
+	IsInterface bool
+}
+
+func (m *MethodInstruction) OpCode() int {
+	return int(m.Op)
+}
+
+func (m *MethodInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitMethodInstruction(m.Op, m.Owner, m.Name, m.Descriptor, m.IsInterface)
+}
+
+type InvokeDynamicInstruction struct {
+	Name                     string
+	Descriptor               string
+	BootstrapMethod          Handle
+	BootstrapMethodArguments []interface{}
+}
+
+func (i *InvokeDynamicInstruction) OpCode() int {
+	return data.INVOKEDYNAMIC
+}
+
+func (i *InvokeDynamicInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitInvokeDynamicInstruction(data.INVOKEDYNAMIC, i.Name, i.Descriptor, i.BootstrapMethod, i.BootstrapMethodArguments)
+}
+
+// JumpInstruction is a conditional branch, GOTO or JSR instruction.
+type JumpInstruction struct {
+	Op    uint16
+	Label *Label
+}
+
+func (j *JumpInstruction) OpCode() int {
+	return int(j.Op)
+}
+
+func (j *JumpInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitJumpInstruction(j.Op, j.Label)
+}
+
+// LdcInstruction loads a constant, which can be an int32, a float32, an int64, a float64, a
+// string, a Type, a Handle or a ConstantDynamic.
+type LdcInstruction struct {
+	Value interface{}
+}
+
+func (l *LdcInstruction) OpCode() int {
+	return data.LDC
+}
+
+func (l *LdcInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitLdcInstruction(l.Value)
+}
+
+type IincInstruction struct {
+	Var       int
+	Increment int
+}
+
+func (i *IincInstruction) OpCode() int {
+	return data.IINC
+}
+
+func (i *IincInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitIincInstruction(i.Var, i.Increment)
+}
+
+type TableSwitchInstruction struct {
+	Min     int32
+	Max     int32
+	Default *Label
+	Labels  []*Label
+}
+
+func (t *TableSwitchInstruction) OpCode() int {
+	return data.TABLESWITCH
+}
+
+func (t *TableSwitchInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitTableSwitchInstruction(t.Min, t.Max, t.Default, t.Labels)
+}
+
+type LookupSwitchInstruction struct {
+	Default *Label
+	Keys    []int32
+	Labels  []*Label
+}
+
+func (l *LookupSwitchInstruction) OpCode() int {
+	return data.LOOKUPSWITCH
+}
+
+func (l *LookupSwitchInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitLookupSwitchInstruction(l.Default, l.Keys, l.Labels)
+}
+
+type MultiANewArrayInstruction struct {
+	Descriptor    string
+	NumDimensions int
+}
+
+func (m *MultiANewArrayInstruction) OpCode() int {
+	return data.MULTIANEWARRAY
+}
+
+func (m *MultiANewArrayInstruction) Accept(visitor MethodVisitor) {
+	visitor.VisitMultiANewArrayInstruction(m.Descriptor, m.NumDimensions)
+}
+
+func (l *Label) OpCode() int {
+	return -1
+}
+
+func (l *Label) Accept(visitor MethodVisitor) {
+	visitor.VisitLabel(l)
+}
+
+// LineNumber is a pseudo instruction that designates the source line of the instructions that
+// follow the Start label.
+type LineNumber struct {
+	Line  int
+	Start *Label
+}
+
+func (l *LineNumber) OpCode() int {
+	return -1
+}
+
+func (l *LineNumber) Accept(visitor MethodVisitor) {
+	visitor.VisitLineNumber(l.Line, l.Start)
+}
+
+// Frame is a pseudo instruction that gives the stack map frame of the instruction that follows
+// it. Type is one of the data.F_* frame types.
+type Frame struct {
+	Type   int
+	Locals []interface{}
+	Stack  []interface{}
+}
+
+func (f *Frame) OpCode() int {
+	return -1
+}
+
+func (f *Frame) Accept(visitor MethodVisitor) {
+	visitor.VisitFrame(f.Type, len(f.Locals), f.Locals, len(f.Stack), f.Stack)
+}
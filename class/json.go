@@ -0,0 +1,593 @@
+package class
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+var jsonFrameTypeNames = map[int]string{data.F_NEW: "new", data.F_FULL: "full", data.F_APPEND: "append",
+	data.F_CHOP: "chop", data.F_SAME: "same", data.F_SAME1: "same1"}
+
+type classJSON struct {
+	MajorVersion         uint16                `json:"major_version"`
+	MinorVersion         uint16                `json:"minor_version"`
+	AccessFlags          uint16                `json:"access_flags"`
+	AccessFlagNames      []string              `json:"access_flag_names"`
+	Name                 string                `json:"name"`
+	Signature            string                `json:"signature,omitempty"`
+	SuperName            string                `json:"super_name,omitempty"`
+	Interfaces           []string              `json:"interfaces"`
+	Deprecated           bool                  `json:"deprecated,omitempty"`
+	SourceFile           string                `json:"source_file,omitempty"`
+	SourceDebugExtension string                `json:"source_debug_extension,omitempty"`
+	Module               *moduleJSON           `json:"module,omitempty"`
+	OuterClass           *outerClassJSON       `json:"outer_class,omitempty"`
+	NestHost             string                `json:"nest_host,omitempty"`
+	NestMembers          []string              `json:"nest_members,omitempty"`
+	PermittedSubclasses  []string              `json:"permitted_subclasses,omitempty"`
+	InnerClasses         []innerClassJSON      `json:"inner_classes,omitempty"`
+	Annotations          []annotationJSON      `json:"annotations,omitempty"`
+	TypeAnnotations      []typeAnnotationJSON  `json:"type_annotations,omitempty"`
+	RecordComponents     []recordComponentJSON `json:"record_components,omitempty"`
+	Fields               []fieldJSON           `json:"fields"`
+	Methods              []methodJSON          `json:"methods"`
+	Attributes           []attributeJSON       `json:"attributes,omitempty"`
+}
+
+type recordComponentJSON struct {
+	Name            string               `json:"name"`
+	Descriptor      string               `json:"descriptor"`
+	Signature       string               `json:"signature,omitempty"`
+	Annotations     []annotationJSON     `json:"annotations,omitempty"`
+	TypeAnnotations []typeAnnotationJSON `json:"type_annotations,omitempty"`
+	Attributes      []attributeJSON      `json:"attributes,omitempty"`
+}
+
+type outerClassJSON struct {
+	Owner      string `json:"owner"`
+	Name       string `json:"name,omitempty"`
+	Descriptor string `json:"descriptor,omitempty"`
+}
+
+type innerClassJSON struct {
+	Name            string   `json:"name"`
+	OuterName       string   `json:"outer_name,omitempty"`
+	InnerName       string   `json:"inner_name,omitempty"`
+	AccessFlags     uint16   `json:"access_flags"`
+	AccessFlagNames []string `json:"access_flag_names"`
+}
+
+type moduleJSON struct {
+	Name            string              `json:"name"`
+	AccessFlags     uint16              `json:"access_flags"`
+	AccessFlagNames []string            `json:"access_flag_names"`
+	Version         string              `json:"version,omitempty"`
+	MainClass       string              `json:"main_class,omitempty"`
+	Packages        []string            `json:"packages,omitempty"`
+	Requires        []moduleLinkJSON    `json:"requires,omitempty"`
+	Exports         []moduleLinkJSON    `json:"exports,omitempty"`
+	Opens           []moduleLinkJSON    `json:"opens,omitempty"`
+	Uses            []string            `json:"uses,omitempty"`
+	Provides        []moduleProvideJSON `json:"provides,omitempty"`
+}
+
+// moduleLinkJSON is a required, exported or opened element of a module.
+type moduleLinkJSON struct {
+	Name            string   `json:"name"`
+	AccessFlags     uint16   `json:"access_flags"`
+	AccessFlagNames []string `json:"access_flag_names"`
+	Version         string   `json:"version,omitempty"`
+	Modules         []string `json:"modules,omitempty"`
+}
+
+type moduleProvideJSON struct {
+	Service  string   `json:"service"`
+	Provides []string `json:"provides"`
+}
+
+type fieldJSON struct {
+	AccessFlags     uint16               `json:"access_flags"`
+	AccessFlagNames []string             `json:"access_flag_names"`
+	Name            string               `json:"name"`
+	Descriptor      string               `json:"descriptor"`
+	Signature       string               `json:"signature,omitempty"`
+	Deprecated      bool                 `json:"deprecated,omitempty"`
+	Value           interface{}          `json:"value,omitempty"`
+	Annotations     []annotationJSON     `json:"annotations,omitempty"`
+	TypeAnnotations []typeAnnotationJSON `json:"type_annotations,omitempty"`
+	Attributes      []attributeJSON      `json:"attributes,omitempty"`
+}
+
+type methodJSON struct {
+	AccessFlags          uint16               `json:"access_flags"`
+	AccessFlagNames      []string             `json:"access_flag_names"`
+	Name                 string               `json:"name"`
+	Descriptor           string               `json:"descriptor"`
+	Signature            string               `json:"signature,omitempty"`
+	Deprecated           bool                 `json:"deprecated,omitempty"`
+	Exceptions           []string             `json:"exceptions,omitempty"`
+	Parameters           []parameterJSON      `json:"parameters,omitempty"`
+	AnnotationDefault    interface{}          `json:"annotation_default,omitempty"`
+	Annotations          []annotationJSON     `json:"annotations,omitempty"`
+	ParameterAnnotations [][]annotationJSON   `json:"parameter_annotations,omitempty"`
+	TypeAnnotations      []typeAnnotationJSON `json:"type_annotations,omitempty"`
+	Code                 *codeJSON            `json:"code,omitempty"`
+	Attributes           []attributeJSON      `json:"attributes,omitempty"`
+}
+
+type parameterJSON struct {
+	Name            string   `json:"name,omitempty"`
+	AccessFlags     uint16   `json:"access_flags"`
+	AccessFlagNames []string `json:"access_flag_names"`
+}
+
+type codeJSON struct {
+	MaxStack        uint16                   `json:"max_stack"`
+	MaxLocals       uint16                   `json:"max_locals"`
+	Instructions    []map[string]interface{} `json:"instructions"`
+	ExceptionTable  []exceptionJSON          `json:"exception_table,omitempty"`
+	LocalVariables  []localVariableJSON      `json:"local_variables,omitempty"`
+	TypeAnnotations []typeAnnotationJSON     `json:"type_annotations,omitempty"`
+	Attributes      []attributeJSON          `json:"attributes,omitempty"`
+}
+
+type exceptionJSON struct {
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Handler   string `json:"handler"`
+	CatchType string `json:"catch_type,omitempty"`
+}
+
+type localVariableJSON struct {
+	Name       string `json:"name"`
+	Descriptor string `json:"descriptor"`
+	Signature  string `json:"signature,omitempty"`
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	Index      int    `json:"index"`
+}
+
+type annotationJSON struct {
+	Descriptor string                 `json:"descriptor"`
+	Visible    bool                   `json:"visible"`
+	Values     map[string]interface{} `json:"values,omitempty"`
+}
+
+// typeAnnotationJSON is a type annotation, with its type path in the format of TypePath.String.
+// The annotations of the code have the index of their instruction in the instruction list, or
+// the ranges of their local variable.
+type typeAnnotationJSON struct {
+	TypeRef  int    `json:"type_ref"`
+	TypePath string `json:"type_path,omitempty"`
+	annotationJSON
+	Instruction *int     `json:"instruction,omitempty"`
+	Start       []string `json:"start,omitempty"`
+	End         []string `json:"end,omitempty"`
+	Index       []int    `json:"index,omitempty"`
+}
+
+// attributeJSON is a non standard attribute, with its content in hexadecimal.
+type attributeJSON struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// MarshalJSON encodes the class in JSON, with names and descriptors instead of constant pool
+// indexes. The access flags are encoded both as a number and as names. The annotation element
+// values are tagged with their element_value tag ("s", "e", "[", ...), and the constants with
+// their type ("int", "long", "string", "type", "handle", ...). In the instruction lists the
+// labels are named L0, L1... in each method.
+func (c Class) MarshalJSON() ([]byte, error) {
+	class := classJSON{
+		MajorVersion:         uint16(c.Version & 0xffff),
+		MinorVersion:         uint16(c.Version >> 16),
+		AccessFlags:          c.AccessFlags,
+		AccessFlagNames:      data.AccessFlagNames(c.AccessFlags, data.ACCESS_CLASS),
+		Name:                 c.ThisClass,
+		Signature:            c.Signature,
+		SuperName:            c.SuperClass,
+		Interfaces:           append(make([]string, 0), c.Interfaces...),
+		Deprecated:           c.Deprecated,
+		SourceFile:           c.SourceFile,
+		SourceDebugExtension: c.SourceDebugExtension,
+		NestHost:             c.NestHost,
+		NestMembers:          c.NestMembers,
+		PermittedSubclasses:  c.PermittedSubclasses,
+		Annotations:          annotationsJSON(c.RuntimeVisibleAnnotations, c.RuntimeInvisibleAnnotations),
+		TypeAnnotations:      typeAnnotationsJSON(c.RuntimeVisibleTypeAnnotations, c.RuntimeInvisibleTypeAnnotations),
+		Fields:               make([]fieldJSON, len(c.Fields)),
+		Methods:              make([]methodJSON, len(c.Methods)),
+		Attributes:           attributesJSON(c.Attributes),
+	}
+	if c.Module.Name != "" {
+		class.Module = moduleToJSON(c.Module)
+	}
+	if c.OuterClass.ClassName != "" {
+		class.OuterClass = &outerClassJSON{c.OuterClass.ClassName, c.OuterClass.MethodName, c.OuterClass.Descriptor}
+	}
+	for _, inner := range c.InnerClasses {
+		class.InnerClasses = append(class.InnerClasses, innerClassJSON{inner.Name, inner.OuterName, inner.InnerName,
+			inner.AccessFlags, data.AccessFlagNames(inner.AccessFlags, data.ACCESS_INNER_CLASS)})
+	}
+	for _, component := range c.RecordComponents {
+		class.RecordComponents = append(class.RecordComponents, recordComponentJSON{
+			Name:            component.Name,
+			Descriptor:      component.Descriptor,
+			Signature:       component.Signature,
+			Annotations:     annotationsJSON(component.RuntimeVisibleAnnotations, component.RuntimeInvisibleAnnotations),
+			TypeAnnotations: typeAnnotationsJSON(component.RuntimeVisibleTypeAnnotations, component.RuntimeInvisibleTypeAnnotations),
+			Attributes:      attributesJSON(component.Attributes),
+		})
+	}
+	for i, field := range c.Fields {
+		class.Fields[i] = fieldJSON{
+			AccessFlags:     field.AccessFlags,
+			AccessFlagNames: data.AccessFlagNames(field.AccessFlags, data.ACCESS_FIELD),
+			Name:            field.Name,
+			Descriptor:      field.Descriptor,
+			Signature:       field.Signature,
+			Deprecated:      field.Deprecated,
+			Annotations:     annotationsJSON(field.RuntimeVisibleAnnotations, field.RuntimeInvisibleAnnotations),
+			TypeAnnotations: typeAnnotationsJSON(field.RuntimeVisibleTypeAnnotations, field.RuntimeInvisibleTypeAnnotations),
+			Attributes:      attributesJSON(field.Attributes),
+		}
+		if field.ConstantValue != nil {
+			class.Fields[i].Value = constantJSON(field.ConstantValue, nil)
+		}
+	}
+	for i, method := range c.Methods {
+		class.Methods[i] = methodToJSON(method)
+	}
+	return json.Marshal(class)
+}
+
+func moduleToJSON(module Module) *moduleJSON {
+	result := &moduleJSON{
+		Name:            module.Name,
+		AccessFlags:     module.AccessFlags,
+		AccessFlagNames: data.AccessFlagNames(module.AccessFlags, data.ACCESS_MODULE),
+		Version:         module.Version,
+		MainClass:       module.MainClass,
+		Packages:        module.Packages,
+		Uses:            module.Uses,
+	}
+	for _, require := range module.Requires {
+		result.Requires = append(result.Requires, moduleLinkJSON{Name: require.Name, AccessFlags: require.AccessFlags,
+			AccessFlagNames: data.AccessFlagNames(require.AccessFlags, data.ACCESS_MODULE_REQUIRES), Version: require.Version})
+	}
+	for _, export := range module.Exports {
+		result.Exports = append(result.Exports, moduleLinkJSON{Name: export.Name, AccessFlags: export.AccessFlags,
+			AccessFlagNames: data.AccessFlagNames(export.AccessFlags, data.ACCESS_MODULE_EXPORTS), Modules: export.Modules})
+	}
+	for _, open := range module.Opens {
+		result.Opens = append(result.Opens, moduleLinkJSON{Name: open.Name, AccessFlags: open.AccessFlags,
+			AccessFlagNames: data.AccessFlagNames(open.AccessFlags, data.ACCESS_MODULE_EXPORTS), Modules: open.Modules})
+	}
+	for _, provide := range module.Provides {
+		result.Provides = append(result.Provides, moduleProvideJSON{provide.Service, provide.Provides})
+	}
+	return result
+}
+
+func methodToJSON(method Method) methodJSON {
+	result := methodJSON{
+		AccessFlags:     method.AccessFlags,
+		AccessFlagNames: data.AccessFlagNames(method.AccessFlags, data.ACCESS_METHOD),
+		Name:            method.Name,
+		Descriptor:      method.Descriptor,
+		Signature:       method.Signature,
+		Deprecated:      method.Deprecated,
+		Exceptions:      method.Exceptions,
+		Annotations:     annotationsJSON(method.RuntimeVisibleAnnotations, method.RuntimeInvisibleAnnotations),
+		TypeAnnotations: typeAnnotationsJSON(method.RuntimeVisibleTypeAnnotations, method.RuntimeInvisibleTypeAnnotations),
+		Attributes:      attributesJSON(method.Attributes),
+	}
+	for _, parameter := range method.Parameters {
+		result.Parameters = append(result.Parameters, parameterJSON{parameter.ParameterName, parameter.AccessFlags,
+			data.AccessFlagNames(parameter.AccessFlags, data.ACCESS_PARAMETER)})
+	}
+	if method.AnnotationDefault != nil {
+		result.AnnotationDefault = elementValueJSON(method.AnnotationDefault)
+	}
+	visible, invisible := method.RuntimeVisibleParameterAnnotations, method.RuntimeInvisibleParameterAnnotations
+	for i := 0; i < len(visible) || i < len(invisible); i++ {
+		var annotations []annotationJSON
+		if i < len(visible) {
+			annotations = append(annotations, annotationsJSON(visible[i].Annotations, nil)...)
+		}
+		if i < len(invisible) {
+			annotations = append(annotations, annotationsJSON(nil, invisible[i].Annotations)...)
+		}
+		result.ParameterAnnotations = append(result.ParameterAnnotations, annotations)
+	}
+	if len(method.Code.Instructions) > 0 {
+		result.Code = codeToJSON(method.Code)
+	}
+	return result
+}
+
+func codeToJSON(code MethodCode) *codeJSON {
+	labelNames := make(map[*Label]string)
+	for _, instruction := range code.Instructions {
+		if label, ok := instruction.(*Label); ok {
+			labelNames[label] = "L" + strconv.Itoa(len(labelNames))
+		}
+	}
+	labelName := func(label *Label) string {
+		if label == nil {
+			return ""
+		}
+		name, ok := labelNames[label]
+		if !ok {
+			name = "L" + strconv.Itoa(len(labelNames))
+			labelNames[label] = name
+		}
+		return name
+	}
+	labelsNames := func(labels []*Label) []string {
+		names := make([]string, len(labels))
+		for i, label := range labels {
+			names[i] = labelName(label)
+		}
+		return names
+	}
+	result := &codeJSON{
+		MaxStack:     code.MaxStack,
+		MaxLocals:    code.MaxLocal,
+		Instructions: make([]map[string]interface{}, len(code.Instructions)),
+		Attributes:   attributesJSON(code.Attributes),
+	}
+	for i, instruction := range code.Instructions {
+		value := make(map[string]interface{})
+		if instruction.OpCode() >= 0 {
+			value["op"] = data.OPCODE_NAMES[instruction.OpCode()]
+		}
+		switch insn := instruction.(type) {
+		case *IntInstruction:
+			value["operand"] = insn.Operand
+		case *VarInstruction:
+			value["var"] = insn.Var
+		case *TypeInstruction:
+			value["type"] = insn.Type
+		case *FieldInstruction:
+			value["owner"], value["name"], value["descriptor"] = insn.Owner, insn.Name, insn.Descriptor
+		case *MethodInstruction:
+			value["owner"], value["name"], value["descriptor"] = insn.Owner, insn.Name, insn.Descriptor
+			value["interface"] = insn.IsInterface
+		case *InvokeDynamicInstruction:
+			value["name"], value["descriptor"] = insn.Name, insn.Descriptor
+			value["bootstrap_method"] = handleJSON(insn.BootstrapMethod)
+			value["bootstrap_arguments"] = constantsJSON(insn.BootstrapMethodArguments, labelName)
+		case *JumpInstruction:
+			value["label"] = labelName(insn.Label)
+		case *LdcInstruction:
+			value["value"] = constantJSON(insn.Value, labelName)
+		case *IincInstruction:
+			value["var"], value["increment"] = insn.Var, insn.Increment
+		case *TableSwitchInstruction:
+			value["min"], value["max"] = insn.Min, insn.Max
+			value["default"], value["labels"] = labelName(insn.Default), labelsNames(insn.Labels)
+		case *LookupSwitchInstruction:
+			value["keys"] = append(make([]int32, 0), insn.Keys...)
+			value["default"], value["labels"] = labelName(insn.Default), labelsNames(insn.Labels)
+		case *MultiANewArrayInstruction:
+			value["descriptor"], value["dimensions"] = insn.Descriptor, insn.NumDimensions
+		case *Label:
+			value["label"] = labelName(insn)
+		case *LineNumber:
+			value["line"], value["start"] = insn.Line, labelName(insn.Start)
+		case *Frame:
+			value["frame"] = jsonFrameTypeNames[insn.Type]
+			value["locals"] = frameItemsJSON(insn.Locals, labelName)
+			value["stack"] = frameItemsJSON(insn.Stack, labelName)
+		}
+		result.Instructions[i] = value
+	}
+	for _, exception := range code.ExceptionTable {
+		result.ExceptionTable = append(result.ExceptionTable, exceptionJSON{labelName(exception.Start),
+			labelName(exception.End), labelName(exception.Handler), exception.CatchType})
+	}
+	for _, local := range code.LocalVariables {
+		result.LocalVariables = append(result.LocalVariables, localVariableJSON{local.Name, local.Descriptor,
+			local.Signature, labelName(local.Start), labelName(local.End), local.Index})
+	}
+	indexes := make(map[Instruction]int)
+	for i, instruction := range code.Instructions {
+		indexes[instruction] = i
+	}
+	for _, annotation := range append(append([]CodeTypeAnnotation(nil), code.RuntimeVisibleTypeAnnotations...), code.RuntimeInvisibleTypeAnnotations...) {
+		value := typeAnnotationToJSON(annotation.TypeAnnotation)
+		if index, ok := indexes[annotation.Instruction]; ok {
+			value.Instruction = &index
+		}
+		if len(annotation.Start) > 0 {
+			value.Start, value.End, value.Index = labelsNames(annotation.Start), labelsNames(annotation.End), annotation.Index
+		}
+		result.TypeAnnotations = append(result.TypeAnnotations, value)
+	}
+	return result
+}
+
+// frameItemsJSON encodes the verification types of a frame: the primitive types by their name
+// ("integer", "top"...), the object types as {"object": internalName} and the uninitialized types
+// as {"uninitialized": label}.
+func frameItemsJSON(items []interface{}, labelName func(*Label) string) []interface{} {
+	result := make([]interface{}, len(items))
+	for i, item := range items {
+		switch v := item.(type) {
+		case uint8:
+			if int(v) < len(frameTypeNames) {
+				result[i] = strings.ToLower(frameTypeNames[v])
+			} else {
+				result[i] = v
+			}
+		case string:
+			result[i] = map[string]interface{}{"object": v}
+		case *Label:
+			result[i] = map[string]interface{}{"uninitialized": labelName(v)}
+		}
+	}
+	return result
+}
+
+func annotationsJSON(visible []Annotation, invisible []Annotation) []annotationJSON {
+	var result []annotationJSON
+	for _, annotation := range visible {
+		annotation.Visible = true
+		result = append(result, annotationToJSON(annotation))
+	}
+	for _, annotation := range invisible {
+		annotation.Visible = false
+		result = append(result, annotationToJSON(annotation))
+	}
+	return result
+}
+
+func typeAnnotationsJSON(visible []TypeAnnotation, invisible []TypeAnnotation) []typeAnnotationJSON {
+	var result []typeAnnotationJSON
+	for _, annotation := range visible {
+		annotation.Visible = true
+		result = append(result, typeAnnotationToJSON(annotation))
+	}
+	for _, annotation := range invisible {
+		annotation.Visible = false
+		result = append(result, typeAnnotationToJSON(annotation))
+	}
+	return result
+}
+
+func typeAnnotationToJSON(annotation TypeAnnotation) typeAnnotationJSON {
+	return typeAnnotationJSON{TypeRef: annotation.TypeRef, TypePath: annotation.TypePath.String(),
+		annotationJSON: annotationToJSON(annotation.Annotation)}
+}
+
+func annotationToJSON(annotation Annotation) annotationJSON {
+	result := annotationJSON{Descriptor: annotation.Descriptor, Visible: annotation.Visible}
+	if len(annotation.ElementPairs) > 0 {
+		result.Values = make(map[string]interface{})
+		for _, pair := range annotation.ElementPairs {
+			result.Values[pair.Name] = elementValueJSON(pair.Value)
+		}
+	}
+	return result
+}
+
+// elementValueJSON encodes an annotation element value as an object whose "tag" is the
+// element_value tag of the value.
+func elementValueJSON(value ElementValue) map[string]interface{} {
+	result := map[string]interface{}{"tag": string(rune(value.Tag()))}
+	switch v := value.(type) {
+	case ElementBooleanValue:
+		result["value"] = v.Value
+	case ElementByteValue:
+		result["value"] = v.Value
+	case ElementCharValue:
+		result["value"] = string(rune(v.Value))
+	case ElementShortValue:
+		result["value"] = v.Value
+	case ElementIntegerValue:
+		result["value"] = v.Value
+	case ElementLongValue:
+		result["value"] = strconv.FormatInt(v.Value, 10)
+	case ElementFloatValue:
+		result["value"] = floatJSON(float64(v.Value), 32)
+	case ElementDoubleValue:
+		result["value"] = floatJSON(v.Value, 64)
+	case ElementStringValue:
+		result["value"] = v.Value
+	case ElementEnumValue:
+		result["type_name"], result["const_name"] = v.TypeName, v.ConstName
+	case ElementClassValue:
+		if t, ok := v.Value.(Type); ok {
+			result["value"] = t.Descriptor()
+		} else {
+			result["value"] = v.Value
+		}
+	case ElementAnnotationValue:
+		result["value"] = annotationToJSON(v.Value)
+	case ElementArrayValue:
+		values := make([]interface{}, len(v.Values))
+		for i, element := range v.Values {
+			values[i] = elementValueJSON(element)
+		}
+		result["values"] = values
+	}
+	return result
+}
+
+func constantsJSON(values []interface{}, labelName func(*Label) string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = constantJSON(value, labelName)
+	}
+	return result
+}
+
+// constantJSON encodes an ldc, field or bootstrap method argument constant as an object whose
+// "type" is the type of the constant.
+func constantJSON(value interface{}, labelName func(*Label) string) map[string]interface{} {
+	switch v := value.(type) {
+	case int32:
+		return map[string]interface{}{"type": "int", "value": v}
+	case int64:
+		return map[string]interface{}{"type": "long", "value": strconv.FormatInt(v, 10)}
+	case float32:
+		return map[string]interface{}{"type": "float", "value": floatJSON(float64(v), 32)}
+	case float64:
+		return map[string]interface{}{"type": "double", "value": floatJSON(v, 64)}
+	case string:
+		return map[string]interface{}{"type": "string", "value": v}
+	case Type:
+		if v.Sort() == data.TYPE_SORT_METHOD {
+			return map[string]interface{}{"type": "method_type", "value": v.Descriptor()}
+		}
+		return map[string]interface{}{"type": "type", "value": v.Descriptor()}
+	case Handle:
+		return handleJSON(v)
+	case ConstantDynamic:
+		return map[string]interface{}{"type": "dynamic", "name": v.Name, "descriptor": v.Descriptor,
+			"bootstrap_method":    handleJSON(v.BootstrapMethod),
+			"bootstrap_arguments": constantsJSON(v.BootstrapMethodArguments, labelName)}
+	case *Label:
+		if labelName != nil {
+			return map[string]interface{}{"type": "label", "value": labelName(v)}
+		}
+	}
+	return map[string]interface{}{"type": "unknown", "value": value}
+}
+
+func handleJSON(handle Handle) map[string]interface{} {
+	kind := ""
+	if int(handle.Tag) < len(handleTagNames) {
+		kind = strings.ToLower(handleTagNames[handle.Tag])
+	}
+	return map[string]interface{}{"type": "handle", "kind": kind, "owner": handle.Owner, "name": handle.Name,
+		"descriptor": handle.Descriptor, "interface": handle.IsInterface}
+}
+
+// floatJSON returns a finite value as is, and the other values as the "NaN", "Infinity" and
+// "-Infinity" strings, which JSON numbers can't represent.
+func floatJSON(value float64, bitSize int) interface{} {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Infinity"
+	case math.IsInf(value, -1):
+		return "-Infinity"
+	}
+	return json.Number(strconv.FormatFloat(value, 'g', -1, bitSize))
+}
+
+func attributesJSON(attributes []Attribute) []attributeJSON {
+	var result []attributeJSON
+	for _, attribute := range attributes {
+		result = append(result, attributeJSON{attribute.Name, hex.EncodeToString(attribute.Content)})
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+package class
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/tools"
+)
+
+func TestClassJSON(t *testing.T) {
+	f, err := os.Open("Hello.class")
+	tools.AssertNoErr(t, err)
+	defer f.Close()
+	reader := NewReader(f)
+	tools.AssertNoErr(t, reader.Read())
+
+	b, err := json.Marshal(reader.Class())
+	tools.AssertNoErr(t, err)
+	text := string(b)
+	for _, value := range []string{
+		`"name":"com/example/demo/Hello"`,
+		`"access_flags":33,"access_flag_names":["ACC_PUBLIC","ACC_SUPER"]`,
+		`{"label":"L0"},{"line":11,"start":"L0"},{"op":"aload","var":0}`,
+		`{"op":"sipush","operand":233}`,
+	} {
+		if !strings.Contains(text, value) {
+			t.Errorf("missing %q in:\n%s", value, text)
+		}
+	}
+}
+
+func TestClassJSONValues(t *testing.T) {
+	writer := NewWriter()
+	writer.Visit(52, data.ACC_PUBLIC|data.ACC_SUPER, "a/A", "", "java/lang/Object", nil)
+	annotation := writer.VisitAnnotation("La/Ann;", false)
+	annotation.Visit("value", "x")
+	annotation.VisitEnum("e", "La/E;", "ONE")
+	annotation.VisitEnd()
+	writer.VisitField(data.ACC_PRIVATE|data.ACC_STATIC|data.ACC_FINAL, "F", "J", "", int64(5)).VisitEnd()
+	writer.VisitEnd()
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+	reader := NewReader(bytes.NewReader(content))
+	tools.AssertNoErr(t, reader.Read())
+
+	b, err := json.Marshal(reader.Class())
+	tools.AssertNoErr(t, err)
+	if text := string(b); text != `{"major_version":52,"minor_version":0,"access_flags":33,"access_flag_names":["ACC_PUBLIC","ACC_SUPER"],"name":"a/A","super_name":"java/lang/Object","interfaces":[],"annotations":[{"descriptor":"La/Ann;","visible":false,"values":{"e":{"const_name":"ONE","tag":"e","type_name":"La/E;"},"value":{"tag":"s","value":"x"}}}],"fields":[{"access_flags":26,"access_flag_names":["ACC_PRIVATE","ACC_STATIC","ACC_FINAL"],"name":"F","descriptor":"J","value":{"type":"long","value":"5"}}],"methods":[]}` {
+		t.Errorf("unexpected JSON:\n%s", text)
+	}
+}
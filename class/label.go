@@ -0,0 +1,26 @@
+package class
+
+// A Label designates a position in the bytecode of a method. Labels are used
+// for jump, goto and switch instructions, for try catch blocks, and for the
+// ranges of local variables and line numbers.
+// A Label designates the instruction that is visited just after it.
+type Label struct {
+	// Offset is the bytecode offset of this label, valid once the label has
+	// been resolved (when the method has been read or written).
+	Offset   int
+	resolved bool
+}
+
+func NewLabel() *Label {
+	return &Label{}
+}
+
+// Resolved returns true if the bytecode offset of this label is known.
+func (l *Label) Resolved() bool {
+	return l.resolved
+}
+
+func (l *Label) resolve(offset int) {
+	l.Offset = offset
+	l.resolved = true
+}
@@ -1,6 +1,7 @@
 package class
 
 import (
+	"fmt"
 	"github.com/tk103331/clazz/class/data"
 	"io"
 )
@@ -14,13 +15,40 @@ func NewReader(reader io.Reader) *Reader {
 	return &Reader{reader: data.NewReader(reader)}
 }
 
-func (r *Reader) Read() {
-	r.reader.Read()
+// Read reads and resolves the class. It returns an error if the class is malformed.
+func (r *Reader) Read() error {
+	return r.read(&ResolveDataVisitor{})
+}
+
+// ReadAnnotations reads the class, and only resolves its access flags, names and supertypes,
+// the access flags, names and descriptors of its fields and methods, and their annotations. It
+// is faster than Read when the code and the other attributes are not needed.
+func (r *Reader) ReadAnnotations() error {
+	return r.read(&ResolveDataVisitor{annotationsOnly: true})
+}
+
+func (r *Reader) read(resolver *ResolveDataVisitor) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("malformed class: %v", e)
+		}
+	}()
+	if err := r.reader.Read(); err != nil {
+		return err
+	}
+	r.reader.Accept(resolver)
+	r.class = resolver.class
+	return nil
+}
+
+// Class returns the class resolved by Read.
+func (r *Reader) Class() *Class {
+	return r.class
 }
 
 func (r *Reader) Accept(visitor Visitor) {
-	if visitor == nil {
+	if visitor == nil || r.class == nil {
 		return
 	}
-	r.reader.Accept(&ResolveDataVisitor{visitor: visitor})
+	r.class.Accept(visitor)
 }
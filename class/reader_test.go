@@ -1,6 +1,9 @@
 package class
 
 import (
+	"bytes"
+	"fmt"
+	"github.com/tk103331/clazz/class/data"
 	"github.com/tk103331/clazz/common"
 	"github.com/tk103331/clazz/tools"
 	"os"
@@ -23,3 +26,39 @@ func TestReadClass(t *testing.T) {
 
 	reader.Accept(PrintVisitor{})
 }
+
+func TestReadAnnotations(t *testing.T) {
+	f, err := os.Open("Hello.class")
+	tools.AssertNoErr(t, err)
+	defer f.Close()
+
+	reader := NewReader(f)
+	tools.AssertNoErr(t, reader.ReadAnnotations())
+	c := reader.Class()
+	tools.AssertEqual(t, "com/example/demo/Hello", c.ThisClass)
+	tools.AssertEqual(t, "java/lang/Object", c.SuperClass)
+	tools.AssertEqual(t, "method1", c.Methods[1].Name)
+	tools.AssertEqual(t, 0, len(c.Methods[1].Code.Instructions))
+}
+
+func TestReadTruncated(t *testing.T) {
+	reader := NewReader(bytes.NewReader([]byte{0xca, 0xfe, 0xba, 0xbe, 0, 0, 0, 52, 0, 10}))
+	tools.AssertEqual(t, true, reader.Read() != nil)
+}
+
+func TestCodeOffsets(t *testing.T) {
+	f, err := os.Open("Hello.class")
+	tools.AssertNoErr(t, err)
+	defer f.Close()
+	reader := NewReader(f)
+	tools.AssertNoErr(t, reader.Read())
+
+	code := &reader.Class().Methods[0].Code
+	tools.AssertEqual(t, "[0 0 0 1 4 4 4 5 8 11 11 11 12 13 16 16 16 17]", fmt.Sprint(code.Offsets()))
+	for i, instruction := range code.Instructions {
+		if insn, ok := instruction.(*IntInstruction); ok && insn.Op == data.SIPUSH {
+			code.Instructions[i] = &IntInstruction{Op: data.BIPUSH, Operand: 5}
+		}
+	}
+	tools.AssertEqual(t, "[0 0 0 1 4 4 4 5 7 10 10 10 11 12 15 15 15 16]", fmt.Sprint(code.Offsets()))
+}
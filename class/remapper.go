@@ -0,0 +1,357 @@
+package class
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+// A Remapper renames types, fields and methods.
+// Types are designated by their internal name. The owner of a method or field is the internal
+// name of the class that declares it, and is empty for invokedynamic call sites and dynamic
+// constants.
+type Remapper interface {
+	MapType(internalName string) string
+	MapMethodName(owner string, name string, descriptor string) string
+	MapFieldName(owner string, name string, descriptor string) string
+	MapDescriptor(descriptor string) string
+	MapSignature(signature string) string
+}
+
+// A StringRemapper is a Remapper which also renames string constants, for example the class
+// names used with reflection.
+type StringRemapper interface {
+	Remapper
+	MapString(value string) string
+}
+
+// A PackageRemapper is a Remapper which also renames packages, given by their internal name
+// such as "com/example". The packages of the modules are renamed with MapPackageName when the
+// Remapper is a PackageRemapper, and with the mapping of a package-info class otherwise.
+type PackageRemapper interface {
+	Remapper
+	MapPackageName(packageName string) string
+}
+
+// SimpleRemapper is a Remapper based on a fixed mapping. The keys of the mapping are internal
+// names for types, "owner.name" for fields, and "owner.name" followed by the descriptor for
+// methods. Names which are not in the mapping are left unchanged.
+// A package is renamed if all the classes of the mapping in this package are moved to the
+// same package.
+type SimpleRemapper struct {
+	mapping     map[string]string
+	packagesSet sync.Once
+	packages    map[string]string
+}
+
+func NewSimpleRemapper(mapping map[string]string) *SimpleRemapper {
+	return &SimpleRemapper{mapping: mapping}
+}
+
+func (r *SimpleRemapper) MapType(internalName string) string {
+	if strings.HasPrefix(internalName, "[") {
+		return r.MapDescriptor(internalName)
+	}
+	if newName, ok := r.mapping[internalName]; ok {
+		return newName
+	}
+	return internalName
+}
+
+func (r *SimpleRemapper) MapMethodName(owner string, name string, descriptor string) string {
+	if newName, ok := r.mapping[owner+"."+name+descriptor]; ok {
+		return newName
+	}
+	return name
+}
+
+func (r *SimpleRemapper) MapFieldName(owner string, name string, descriptor string) string {
+	if newName, ok := r.mapping[owner+"."+name]; ok {
+		return newName
+	}
+	return name
+}
+
+func (r *SimpleRemapper) MapPackageName(packageName string) string {
+	r.packagesSet.Do(r.computePackages)
+	if newName, ok := r.packages[packageName]; ok {
+		return newName
+	}
+	return packageName
+}
+
+// computePackages computes the new names of the packages from the class names of the mapping.
+// The packages whose classes are moved to different packages are not renamed.
+func (r *SimpleRemapper) computePackages() {
+	r.packages = make(map[string]string)
+	conflicts := make(map[string]bool)
+	for name, newName := range r.mapping {
+		if strings.ContainsAny(name, ".[") {
+			continue
+		}
+		packageName, newPackageName := packageOf(name), packageOf(newName)
+		if previous, ok := r.packages[packageName]; ok && previous != newPackageName {
+			conflicts[packageName] = true
+		}
+		r.packages[packageName] = newPackageName
+	}
+	for packageName := range conflicts {
+		delete(r.packages, packageName)
+	}
+}
+
+// packageOf returns the internal name of the package of a class.
+func packageOf(internalName string) string {
+	if index := strings.LastIndexByte(internalName, '/'); index >= 0 {
+		return internalName[:index]
+	}
+	return ""
+}
+
+func (r *SimpleRemapper) MapDescriptor(descriptor string) string {
+	return RemapDescriptor(r.MapType, descriptor)
+}
+
+func (r *SimpleRemapper) MapSignature(signature string) string {
+	return RemapSignature(r.MapType, signature)
+}
+
+// RemapDescriptor renames the class types of a field or method descriptor with mapType.
+func RemapDescriptor(mapType func(internalName string) string, descriptor string) string {
+	if !strings.Contains(descriptor, "L") {
+		return descriptor
+	}
+	var builder strings.Builder
+	for i := 0; i < len(descriptor); i++ {
+		c := descriptor[i]
+		builder.WriteByte(c)
+		if c == 'L' {
+			end := strings.IndexByte(descriptor[i:], ';') + i
+			builder.WriteString(mapType(descriptor[i+1 : end]))
+			builder.WriteByte(';')
+			i = end
+		}
+	}
+	return builder.String()
+}
+
+// RemapSignature renames the class types of a class, method or field generic signature with
+// mapType. Inner class names are renamed with the mapping of their binary name.
+func RemapSignature(mapType func(internalName string) string, signature string) string {
+	if len(signature) == 0 {
+		return signature
+	}
+	s := &signatureRemapper{signature: signature, mapType: mapType}
+	s.remap()
+	return s.builder.String()
+}
+
+type signatureRemapper struct {
+	signature string
+	pos       int
+	mapType   func(string) string
+	builder   strings.Builder
+}
+
+func (s *signatureRemapper) remap() {
+	if s.signature[0] == '<' {
+		s.copy(1)
+		for s.pos < len(s.signature) && s.signature[s.pos] != '>' {
+			end := strings.IndexByte(s.signature[s.pos:], ':') + s.pos
+			s.copy(end - s.pos)
+			for s.pos < len(s.signature) && s.signature[s.pos] == ':' {
+				s.copy(1)
+				if s.pos < len(s.signature) && s.signature[s.pos] != ':' && s.signature[s.pos] != '>' {
+					s.remapType()
+				}
+			}
+		}
+		s.copy(1)
+	}
+	for s.pos < len(s.signature) {
+		switch s.signature[s.pos] {
+		case 'L', 'T', '[':
+			s.remapType()
+		default:
+			s.copy(1)
+		}
+	}
+}
+
+func (s *signatureRemapper) remapType() {
+	switch s.signature[s.pos] {
+	case 'L':
+		s.remapClassType()
+	case 'T':
+		end := strings.IndexByte(s.signature[s.pos:], ';') + s.pos
+		s.copy(end - s.pos + 1)
+	case '[':
+		s.copy(1)
+		s.remapType()
+	default:
+		s.copy(1)
+	}
+}
+
+func (s *signatureRemapper) remapClassType() {
+	s.copy(1)
+	name := s.readName()
+	mappedName := s.mapType(name)
+	s.builder.WriteString(mappedName)
+	for s.pos < len(s.signature) {
+		switch s.signature[s.pos] {
+		case '<':
+			s.copy(1)
+			for s.signature[s.pos] != '>' {
+				switch s.signature[s.pos] {
+				case '*':
+					s.copy(1)
+				case '+', '-':
+					s.copy(1)
+					s.remapType()
+				default:
+					s.remapType()
+				}
+			}
+			s.copy(1)
+		case '.':
+			s.copy(1)
+			innerName := s.readName()
+			name = name + "$" + innerName
+			mappedInnerName := s.mapType(name)
+			if strings.HasPrefix(mappedInnerName, mappedName+"$") {
+				s.builder.WriteString(mappedInnerName[len(mappedName)+1:])
+			} else if index := strings.LastIndexByte(mappedInnerName, '$'); index >= 0 {
+				s.builder.WriteString(mappedInnerName[index+1:])
+			} else {
+				s.builder.WriteString(innerName)
+			}
+			mappedName = mappedInnerName
+		case ';':
+			s.copy(1)
+			return
+		default:
+			return
+		}
+	}
+}
+
+func (s *signatureRemapper) readName() string {
+	start := s.pos
+	for s.pos < len(s.signature) {
+		c := s.signature[s.pos]
+		if c == '<' || c == '.' || c == ';' {
+			break
+		}
+		s.pos++
+	}
+	return s.signature[start:s.pos]
+}
+
+func (s *signatureRemapper) copy(length int) {
+	if s.pos+length > len(s.signature) {
+		length = len(s.signature) - s.pos
+	}
+	s.builder.WriteString(s.signature[s.pos : s.pos+length])
+	s.pos += length
+}
+
+// RemapValue renames the types, names and descriptors contained in a constant value, which can
+// be a Type, a Handle or a ConstantDynamic. Strings are renamed if the remapper is a
+// StringRemapper. Other values are returned unchanged.
+func RemapValue(remapper Remapper, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if stringRemapper, ok := remapper.(StringRemapper); ok {
+			return stringRemapper.MapString(v)
+		}
+		return v
+	case Type:
+		switch v.Sort() {
+		case data.TYPE_SORT_METHOD:
+			return NewMethodType(remapper.MapDescriptor(v.Descriptor()))
+		case data.TYPE_SORT_ARRAY, data.TYPE_SORT_INTERNAL:
+			return NewObjectType(remapType(remapper, v.InternalName()))
+		default:
+			return v
+		}
+	case Handle:
+		return remapHandle(remapper, v)
+	case ConstantDynamic:
+		arguments := make([]interface{}, len(v.BootstrapMethodArguments))
+		for i, argument := range v.BootstrapMethodArguments {
+			arguments[i] = RemapValue(remapper, argument)
+		}
+		return ConstantDynamic{
+			Name:                     remapper.MapMethodName("", v.Name, v.Descriptor),
+			Descriptor:               remapper.MapDescriptor(v.Descriptor),
+			BootstrapMethod:          remapHandle(remapper, v.BootstrapMethod),
+			BootstrapMethodArguments: arguments,
+		}
+	default:
+		return value
+	}
+}
+
+func remapHandle(remapper Remapper, handle Handle) Handle {
+	var name string
+	if handle.Tag <= data.HANDLE_PUTSTATIC {
+		name = remapper.MapFieldName(handle.Owner, handle.Name, handle.Descriptor)
+	} else {
+		name = remapper.MapMethodName(handle.Owner, handle.Name, handle.Descriptor)
+	}
+	return Handle{
+		Tag:         handle.Tag,
+		Owner:       remapType(remapper, handle.Owner),
+		Name:        name,
+		Descriptor:  remapper.MapDescriptor(handle.Descriptor),
+		IsInterface: handle.IsInterface,
+	}
+}
+
+// remapType renames an internal name, which can also be the descriptor of an array type.
+func remapType(remapper Remapper, internalName string) string {
+	if len(internalName) == 0 {
+		return internalName
+	}
+	if strings.HasPrefix(internalName, "[") {
+		return remapper.MapDescriptor(internalName)
+	}
+	return remapper.MapType(internalName)
+}
+
+func remapTypes(remapper Remapper, internalNames []string) []string {
+	if internalNames == nil {
+		return nil
+	}
+	newNames := make([]string, len(internalNames))
+	for i, name := range internalNames {
+		newNames[i] = remapType(remapper, name)
+	}
+	return newNames
+}
+
+// remapPackage renames a package with MapPackageName if the remapper is a PackageRemapper, or
+// else with the mapping of the package-info class of this package.
+func remapPackage(remapper Remapper, packageName string) string {
+	if packageRemapper, ok := remapper.(PackageRemapper); ok {
+		return packageRemapper.MapPackageName(packageName)
+	}
+	newName := remapper.MapType(packageName + "/package-info")
+	if index := strings.LastIndexByte(newName, '/'); index >= 0 {
+		return newName[:index]
+	}
+	return packageName
+}
+
+// remapInnerName renames the simple name of an inner class after its binary name.
+func remapInnerName(name string, newName string, innerName string) string {
+	if len(innerName) == 0 || name == newName {
+		return innerName
+	}
+	if index := strings.LastIndexByte(newName, '$'); index >= 0 {
+		return newName[index+1:]
+	}
+	return innerName
+}
@@ -0,0 +1,147 @@
+package class
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tk103331/clazz/tools"
+)
+
+func newTestRemapper() *SimpleRemapper {
+	return NewSimpleRemapper(map[string]string{
+		"com/example/demo/Hello":               "a/A",
+		"com/example/demo/Hello$User":          "a/A$B",
+		"com/example/demo/Hello.method3(I)I":   "m",
+		"com/example/demo/Hello$User.name(I)V": "n",
+		".run()Lcom/example/demo/Hello;":       "go",
+		"com/example/demo/Hello$User$Address":  "a/A$B$C",
+	})
+}
+
+func TestRemapDescriptor(t *testing.T) {
+	remapper := newTestRemapper()
+	tools.AssertEqual(t, "(ILa/A;[La/A$B;)La/A;", remapper.MapDescriptor("(ILcom/example/demo/Hello;[Lcom/example/demo/Hello$User;)Lcom/example/demo/Hello;"))
+	tools.AssertEqual(t, "(IJ)V", remapper.MapDescriptor("(IJ)V"))
+	tools.AssertEqual(t, "[[La/A;", remapper.MapType("[[Lcom/example/demo/Hello;"))
+}
+
+func TestRemapSignature(t *testing.T) {
+	remapper := newTestRemapper()
+	tools.AssertEqual(t, "<T:La/A;>Ljava/util/List<TT;>;",
+		remapper.MapSignature("<T:Lcom/example/demo/Hello;>Ljava/util/List<TT;>;"))
+	tools.AssertEqual(t, "<K::Ljava/lang/Comparable<-TK;>;>(Ljava/util/Map<TK;+La/A;>;)La/A<TK;>.B.C;^TE;",
+		remapper.MapSignature("<K::Ljava/lang/Comparable<-TK;>;>(Ljava/util/Map<TK;+Lcom/example/demo/Hello;>;)Lcom/example/demo/Hello<TK;>.User.Address;^TE;"))
+	tools.AssertEqual(t, "Ljava/util/List<[La/A;>;", remapper.MapSignature("Ljava/util/List<[Lcom/example/demo/Hello;>;"))
+}
+
+type recordingVisitor struct {
+	Visitor
+	name      string
+	superName string
+	method    *recordingMethodVisitor
+}
+
+func (v *recordingVisitor) Visit(version uint32, access uint16, name string, signature string, superName string, interfaces []string) {
+	v.name = name
+	v.superName = superName
+}
+
+func (v *recordingVisitor) VisitMethod(access uint16, name string, descriptor string, signature string, exceptions []string) MethodVisitor {
+	v.method = &recordingMethodVisitor{name: name + descriptor}
+	return v.method
+}
+
+type recordingMethodVisitor struct {
+	MethodVisitor
+	name   string
+	values []interface{}
+}
+
+func (v *recordingMethodVisitor) VisitMethodInstruction(opCode uint16, owner string, name string, descriptor string, isInterface bool) {
+	v.values = append(v.values, owner+"."+name+descriptor)
+}
+
+func (v *recordingMethodVisitor) VisitLdcInstruction(value interface{}) {
+	v.values = append(v.values, value)
+}
+
+func (v *recordingMethodVisitor) VisitInvokeDynamicInstruction(opCode uint16, name string, descriptor string, bootstrapMethodHandle Handle, bootstrapMethodArguments []interface{}) {
+	v.values = append(v.values, name+descriptor, bootstrapMethodArguments[0])
+}
+
+func TestRemappingVisitor(t *testing.T) {
+	recorder := &recordingVisitor{}
+	visitor := NewRemappingVisitor(recorder, newTestRemapper())
+	visitor.Visit(0, 0, "com/example/demo/Hello", "", "java/lang/Object", nil)
+	tools.AssertEqual(t, "a/A", recorder.name)
+	tools.AssertEqual(t, "java/lang/Object", recorder.superName)
+
+	methodVisitor := visitor.VisitMethod(0, "method3", "(I)I", "", nil)
+	tools.AssertEqual(t, "m(I)I", recorder.method.name)
+	methodVisitor.VisitMethodInstruction(0, "com/example/demo/Hello$User", "name", "(I)V", false)
+	methodVisitor.VisitLdcInstruction(NewObjectType("com/example/demo/Hello"))
+	methodVisitor.VisitLdcInstruction("com/example/demo/Hello")
+	methodVisitor.VisitInvokeDynamicInstruction(0, "run", "()Lcom/example/demo/Hello;", Handle{}, []interface{}{
+		Handle{Tag: 6, Owner: "com/example/demo/Hello", Name: "method3", Descriptor: "(I)I"},
+	})
+
+	values := recorder.method.values
+	tools.AssertEqual(t, "a/A$B.n(I)V", values[0])
+	tools.AssertEqual(t, "La/A;", values[1].(Type).Descriptor())
+	tools.AssertEqual(t, "com/example/demo/Hello", values[2])
+	tools.AssertEqual(t, "go()La/A;", values[3])
+	tools.AssertEqual(t, Handle{Tag: 6, Owner: "a/A", Name: "m", Descriptor: "(I)I"}, values[4])
+}
+
+func TestRemapModulePackages(t *testing.T) {
+	remapper := newTestRemapper()
+	tools.AssertEqual(t, "a", remapper.MapPackageName("com/example/demo"))
+	tools.AssertEqual(t, "com/example", remapper.MapPackageName("com/example"))
+
+	c := &Class{Version: 53, AccessFlags: 0x8000, ThisClass: "module-info", Module: Module{
+		Name:     "demo",
+		Packages: []string{"com/example/demo", "com/example/other"},
+		Exports:  []ModuleExport{{Name: "com/example/demo"}},
+		Opens:    []ModuleOpen{{Name: "com/example/demo"}},
+	}}
+	builder := NewBuilder()
+	c.Accept(NewRemappingVisitor(builder, remapper))
+	module := builder.Class().Module
+	tools.AssertEqual(t, "[a com/example/other]", fmt.Sprint(module.Packages))
+	tools.AssertEqual(t, "a", module.Exports[0].Name)
+	tools.AssertEqual(t, "a", module.Opens[0].Name)
+}
+
+func TestRemapRecord(t *testing.T) {
+	remapper := NewSimpleRemapper(map[string]string{
+		"com/example/Point":   "a/P",
+		"com/example/Shape":   "a/S",
+		"com/example/Point.x": "f",
+	})
+	c := &Class{Version: 61, ThisClass: "com/example/Point", SuperClass: "java/lang/Record",
+		PermittedSubclasses: []string{"com/example/Shape"},
+		RecordComponents: []RecordComponent{{Name: "x", Descriptor: "I"},
+			{Name: "shape", Descriptor: "Lcom/example/Shape;", Signature: "Ljava/util/List<Lcom/example/Shape;>;"}}}
+	builder := NewBuilder()
+	c.Accept(NewRemappingVisitor(builder, remapper))
+	class := builder.Class()
+	tools.AssertEqual(t, "[a/S]", fmt.Sprint(class.PermittedSubclasses))
+	tools.AssertEqual(t, 2, len(class.RecordComponents))
+	tools.AssertEqual(t, "f I", class.RecordComponents[0].Name+" "+class.RecordComponents[0].Descriptor)
+	tools.AssertEqual(t, "La/S;", class.RecordComponents[1].Descriptor)
+	tools.AssertEqual(t, "Ljava/util/List<La/S;>;", class.RecordComponents[1].Signature)
+}
+
+func TestRemapTypeAnnotations(t *testing.T) {
+	builder := NewBuilder()
+	visitTypeAnnotations(NewRemappingVisitor(builder, NewSimpleRemapper(map[string]string{"a/NonNull": "b/N"})))
+	class := builder.Class()
+	tools.AssertEqual(t, "Lb/N;", class.Fields[0].RuntimeVisibleTypeAnnotations[0].Descriptor)
+	tools.AssertEqual(t, "Lb/N;", class.Methods[0].RuntimeVisibleTypeAnnotations[0].Descriptor)
+	code := class.Methods[0].Code
+	tools.AssertEqual(t, "Lb/N;", code.RuntimeVisibleTypeAnnotations[1].Descriptor)
+	if instruction, ok := code.RuntimeVisibleTypeAnnotations[1].Instruction.(*TypeInstruction); !ok || instruction.Type != "java/lang/String" {
+		t.Errorf("unexpected annotated instruction %v", code.RuntimeVisibleTypeAnnotations[1].Instruction)
+	}
+	tools.AssertEqual(t, "[0]", fmt.Sprint(code.RuntimeInvisibleTypeAnnotations[0].Index))
+}
@@ -0,0 +1,363 @@
+package class
+
+// RemappingVisitor is a Visitor adapter that renames the types, fields and methods of the
+// visited class with a Remapper, and forwards the renamed class to the next visitor.
+// The names of the annotation elements are not renamed: the Remapper needs the descriptor of
+// their annotation method, which the element values do not give, like for empty arrays.
+type RemappingVisitor struct {
+	visitor   Visitor
+	remapper  Remapper
+	className string
+}
+
+func NewRemappingVisitor(visitor Visitor, remapper Remapper) *RemappingVisitor {
+	return &RemappingVisitor{visitor: visitor, remapper: remapper}
+}
+
+func (r *RemappingVisitor) Visit(version uint32, access uint16, name string, signature string, superName string, interfaces []string) {
+	r.className = name
+	r.visitor.Visit(version, access, r.remapper.MapType(name), r.remapper.MapSignature(signature),
+		remapType(r.remapper, superName), remapTypes(r.remapper, interfaces))
+}
+
+func (r *RemappingVisitor) VisitSource(source string, debug string) {
+	r.visitor.VisitSource(source, debug)
+}
+
+func (r *RemappingVisitor) VisitModule(name string, access uint16, version string) ModuleVisitor {
+	moduleVisitor := r.visitor.VisitModule(name, access, version)
+	if moduleVisitor == nil {
+		return nil
+	}
+	return &remappingModuleVisitor{visitor: moduleVisitor, remapper: r.remapper}
+}
+
+func (r *RemappingVisitor) VisitNestHost(nestHost string) {
+	r.visitor.VisitNestHost(remapType(r.remapper, nestHost))
+}
+
+func (r *RemappingVisitor) VisitOuterClass(owner string, name string, descriptor string) {
+	if len(name) > 0 {
+		name = r.remapper.MapMethodName(owner, name, descriptor)
+	}
+	r.visitor.VisitOuterClass(remapType(r.remapper, owner), name, r.remapper.MapDescriptor(descriptor))
+}
+
+func (r *RemappingVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitAnnotation(r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *RemappingVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitTypeAnnotation(typeRef, typePath, r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *RemappingVisitor) VisitAttribute(attribute Attribute) {
+	r.visitor.VisitAttribute(attribute)
+}
+
+func (r *RemappingVisitor) VisitNestMember(nestMember string) {
+	r.visitor.VisitNestMember(remapType(r.remapper, nestMember))
+}
+
+func (r *RemappingVisitor) VisitPermittedSubclass(permittedSubclass string) {
+	r.visitor.VisitPermittedSubclass(remapType(r.remapper, permittedSubclass))
+}
+
+func (r *RemappingVisitor) VisitInnerClass(name string, outerName string, innerName string, access uint16) {
+	newName := remapType(r.remapper, name)
+	r.visitor.VisitInnerClass(newName, remapType(r.remapper, outerName), remapInnerName(name, newName, innerName), access)
+}
+
+// VisitRecordComponent renames a record component like the field of the same name.
+func (r *RemappingVisitor) VisitRecordComponent(name string, descriptor string, signature string) RecordComponentVisitor {
+	componentVisitor := r.visitor.VisitRecordComponent(r.remapper.MapFieldName(r.className, name, descriptor),
+		r.remapper.MapDescriptor(descriptor), r.remapper.MapSignature(signature))
+	if componentVisitor == nil {
+		return nil
+	}
+	return &remappingRecordComponentVisitor{visitor: componentVisitor, remapper: r.remapper}
+}
+
+func (r *RemappingVisitor) VisitField(access uint16, name string, descriptor string, signature string, value interface{}) FieldVisitor {
+	fieldVisitor := r.visitor.VisitField(access, r.remapper.MapFieldName(r.className, name, descriptor),
+		r.remapper.MapDescriptor(descriptor), r.remapper.MapSignature(signature), RemapValue(r.remapper, value))
+	if fieldVisitor == nil {
+		return nil
+	}
+	return &remappingFieldVisitor{visitor: fieldVisitor, remapper: r.remapper}
+}
+
+func (r *RemappingVisitor) VisitMethod(access uint16, name string, descriptor string, signature string, exceptions []string) MethodVisitor {
+	methodVisitor := r.visitor.VisitMethod(access, r.remapper.MapMethodName(r.className, name, descriptor),
+		r.remapper.MapDescriptor(descriptor), r.remapper.MapSignature(signature), remapTypes(r.remapper, exceptions))
+	if methodVisitor == nil {
+		return nil
+	}
+	return &remappingMethodVisitor{visitor: methodVisitor, remapper: r.remapper}
+}
+
+func (r *RemappingVisitor) VisitEnd() {
+	r.visitor.VisitEnd()
+}
+
+type remappingModuleVisitor struct {
+	visitor  ModuleVisitor
+	remapper Remapper
+}
+
+func (r *remappingModuleVisitor) VisitMainClass(mainClass string) {
+	r.visitor.VisitMainClass(remapType(r.remapper, mainClass))
+}
+
+func (r *remappingModuleVisitor) VisitPackage(packageName string) {
+	r.visitor.VisitPackage(remapPackage(r.remapper, packageName))
+}
+
+func (r *remappingModuleVisitor) VisitRequire(moduleName string, access uint16, version string) {
+	r.visitor.VisitRequire(moduleName, access, version)
+}
+
+func (r *remappingModuleVisitor) VisitExport(packageName string, access uint16, modules []string) {
+	r.visitor.VisitExport(remapPackage(r.remapper, packageName), access, modules)
+}
+
+func (r *remappingModuleVisitor) VisitOpen(packageName string, access uint16, modules []string) {
+	r.visitor.VisitOpen(remapPackage(r.remapper, packageName), access, modules)
+}
+
+func (r *remappingModuleVisitor) VisitUse(service string) {
+	r.visitor.VisitUse(remapType(r.remapper, service))
+}
+
+func (r *remappingModuleVisitor) VisitProvide(service string, providers []string) {
+	r.visitor.VisitProvide(remapType(r.remapper, service), remapTypes(r.remapper, providers))
+}
+
+func (r *remappingModuleVisitor) VisitEnd() {
+	r.visitor.VisitEnd()
+}
+
+type remappingAnnotationVisitor struct {
+	visitor  AnnotationVisitor
+	remapper Remapper
+}
+
+func newRemappingAnnotationVisitor(visitor AnnotationVisitor, remapper Remapper) AnnotationVisitor {
+	if visitor == nil {
+		return nil
+	}
+	return &remappingAnnotationVisitor{visitor: visitor, remapper: remapper}
+}
+
+func (r *remappingAnnotationVisitor) Visit(name string, value interface{}) {
+	r.visitor.Visit(name, RemapValue(r.remapper, value))
+}
+
+func (r *remappingAnnotationVisitor) VisitEnum(name string, descriptor string, value string) {
+	r.visitor.VisitEnum(name, r.remapper.MapDescriptor(descriptor), value)
+}
+
+func (r *remappingAnnotationVisitor) VisitAnnotation(name string, descriptor string) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitAnnotation(name, r.remapper.MapDescriptor(descriptor)), r.remapper)
+}
+
+func (r *remappingAnnotationVisitor) VisitArray(name string) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitArray(name), r.remapper)
+}
+
+func (r *remappingAnnotationVisitor) VisitEnd() {
+	r.visitor.VisitEnd()
+}
+
+type remappingFieldVisitor struct {
+	visitor  FieldVisitor
+	remapper Remapper
+}
+
+func (r *remappingFieldVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitAnnotation(r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *remappingFieldVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitTypeAnnotation(typeRef, typePath, r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *remappingFieldVisitor) VisitAttribute(attribute Attribute) {
+	r.visitor.VisitAttribute(attribute)
+}
+
+func (r *remappingFieldVisitor) VisitEnd() {
+	r.visitor.VisitEnd()
+}
+
+type remappingRecordComponentVisitor struct {
+	visitor  RecordComponentVisitor
+	remapper Remapper
+}
+
+func (r *remappingRecordComponentVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitAnnotation(r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *remappingRecordComponentVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitTypeAnnotation(typeRef, typePath, r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *remappingRecordComponentVisitor) VisitAttribute(attribute Attribute) {
+	r.visitor.VisitAttribute(attribute)
+}
+
+func (r *remappingRecordComponentVisitor) VisitEnd() {
+	r.visitor.VisitEnd()
+}
+
+type remappingMethodVisitor struct {
+	visitor  MethodVisitor
+	remapper Remapper
+}
+
+func (r *remappingMethodVisitor) VisitParameter(name string, access uint16) {
+	r.visitor.VisitParameter(name, access)
+}
+
+func (r *remappingMethodVisitor) VisitAnnotationDefault() AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitAnnotationDefault(), r.remapper)
+}
+
+func (r *remappingMethodVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitAnnotation(r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *remappingMethodVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitTypeAnnotation(typeRef, typePath, r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *remappingMethodVisitor) VisitAnnotableParameterCount(parameterCount int, visible bool) {
+	r.visitor.VisitAnnotableParameterCount(parameterCount, visible)
+}
+
+func (r *remappingMethodVisitor) VisitParameterAnnotation(parameterIndex int, descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitParameterAnnotation(parameterIndex, r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *remappingMethodVisitor) VisitAttribute(attribute Attribute) {
+	r.visitor.VisitAttribute(attribute)
+}
+
+func (r *remappingMethodVisitor) VisitCode() {
+	r.visitor.VisitCode()
+}
+
+func (r *remappingMethodVisitor) VisitFrame(frameType int, numLocal int, locals []interface{}, numStack int, stacks []interface{}) {
+	r.visitor.VisitFrame(frameType, numLocal, r.remapFrameTypes(locals), numStack, r.remapFrameTypes(stacks))
+}
+
+func (r *remappingMethodVisitor) remapFrameTypes(types []interface{}) []interface{} {
+	if types == nil {
+		return nil
+	}
+	newTypes := make([]interface{}, len(types))
+	for i, t := range types {
+		if name, ok := t.(string); ok {
+			newTypes[i] = remapType(r.remapper, name)
+		} else {
+			newTypes[i] = t
+		}
+	}
+	return newTypes
+}
+
+func (r *remappingMethodVisitor) VisitInstruction(opCode uint16) {
+	r.visitor.VisitInstruction(opCode)
+}
+
+func (r *remappingMethodVisitor) VisitIntInstruction(opCode uint16, operand int32) {
+	r.visitor.VisitIntInstruction(opCode, operand)
+}
+
+func (r *remappingMethodVisitor) VisitVarInstruction(opCode uint16, variable int) {
+	r.visitor.VisitVarInstruction(opCode, variable)
+}
+
+func (r *remappingMethodVisitor) VisitTypeInstruction(opCode uint16, typeName string) {
+	r.visitor.VisitTypeInstruction(opCode, remapType(r.remapper, typeName))
+}
+
+func (r *remappingMethodVisitor) VisitFieldInstruction(opCode uint16, owner string, name string, descriptor string) {
+	r.visitor.VisitFieldInstruction(opCode, remapType(r.remapper, owner), r.remapper.MapFieldName(owner, name, descriptor),
+		r.remapper.MapDescriptor(descriptor))
+}
+
+func (r *remappingMethodVisitor) VisitMethodInstruction(opCode uint16, owner string, name string, descriptor string, isInterface bool) {
+	r.visitor.VisitMethodInstruction(opCode, remapType(r.remapper, owner), r.remapper.MapMethodName(owner, name, descriptor),
+		r.remapper.MapDescriptor(descriptor), isInterface)
+}
+
+func (r *remappingMethodVisitor) VisitInvokeDynamicInstruction(opCode uint16, name string, descriptor string, bootstrapMethodHandle Handle, bootstrapMethodArguments []interface{}) {
+	arguments := make([]interface{}, len(bootstrapMethodArguments))
+	for i, argument := range bootstrapMethodArguments {
+		arguments[i] = RemapValue(r.remapper, argument)
+	}
+	r.visitor.VisitInvokeDynamicInstruction(opCode, r.remapper.MapMethodName("", name, descriptor), r.remapper.MapDescriptor(descriptor),
+		remapHandle(r.remapper, bootstrapMethodHandle), arguments)
+}
+
+func (r *remappingMethodVisitor) VisitJumpInstruction(opCode uint16, label *Label) {
+	r.visitor.VisitJumpInstruction(opCode, label)
+}
+
+func (r *remappingMethodVisitor) VisitLabel(label *Label) {
+	r.visitor.VisitLabel(label)
+}
+
+func (r *remappingMethodVisitor) VisitLdcInstruction(value interface{}) {
+	r.visitor.VisitLdcInstruction(RemapValue(r.remapper, value))
+}
+
+func (r *remappingMethodVisitor) VisitIincInstruction(variable int, increment int) {
+	r.visitor.VisitIincInstruction(variable, increment)
+}
+
+func (r *remappingMethodVisitor) VisitTableSwitchInstruction(min int32, max int32, dflt *Label, labels []*Label) {
+	r.visitor.VisitTableSwitchInstruction(min, max, dflt, labels)
+}
+
+func (r *remappingMethodVisitor) VisitLookupSwitchInstruction(dflt *Label, keys []int32, labels []*Label) {
+	r.visitor.VisitLookupSwitchInstruction(dflt, keys, labels)
+}
+
+func (r *remappingMethodVisitor) VisitMultiANewArrayInstruction(descriptor string, numDimensions int) {
+	r.visitor.VisitMultiANewArrayInstruction(r.remapper.MapDescriptor(descriptor), numDimensions)
+}
+
+func (r *remappingMethodVisitor) VisitInstructionAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitInstructionAnnotation(typeRef, typePath, r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *remappingMethodVisitor) VisitTryCatchBlock(start *Label, end *Label, handler *Label, typeName string) {
+	r.visitor.VisitTryCatchBlock(start, end, handler, remapType(r.remapper, typeName))
+}
+
+func (r *remappingMethodVisitor) VisitTryCatchAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitTryCatchAnnotation(typeRef, typePath, r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *remappingMethodVisitor) VisitLocalVariable(name string, descriptor string, signature string, start *Label, end *Label, index int) {
+	r.visitor.VisitLocalVariable(name, r.remapper.MapDescriptor(descriptor), r.remapper.MapSignature(signature), start, end, index)
+}
+
+func (r *remappingMethodVisitor) VisitLocalVariableAnnotation(typeRef int, typePath TypePath, start []*Label, end []*Label, index []int, descriptor string, visible bool) AnnotationVisitor {
+	return newRemappingAnnotationVisitor(r.visitor.VisitLocalVariableAnnotation(typeRef, typePath, start, end, index, r.remapper.MapDescriptor(descriptor), visible), r.remapper)
+}
+
+func (r *remappingMethodVisitor) VisitLineNumber(line int, start *Label) {
+	r.visitor.VisitLineNumber(line, start)
+}
+
+func (r *remappingMethodVisitor) VisitMaxs(maxStack int, maxLocals int) {
+	r.visitor.VisitMaxs(maxStack, maxLocals)
+}
+
+func (r *remappingMethodVisitor) VisitEnd() {
+	r.visitor.VisitEnd()
+}
@@ -0,0 +1,816 @@
+package class
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+// FrameState is the state of the local variables and of the operand stack before an
+// instruction. The values are data.ITEM_* tags, the internal names or array descriptors of
+// reference types, or the label of the NEW instruction of uninitialized types. The long and
+// double values use two slots, the second one being data.ITEM_TOP.
+type FrameState struct {
+	Locals []interface{}
+	Stack  []interface{}
+}
+
+func (s *FrameState) Copy() *FrameState {
+	return &FrameState{Locals: append([]interface{}{}, s.Locals...), Stack: append([]interface{}{}, s.Stack...)}
+}
+
+// Replace replaces all the occurrences of a value in the locals and on the stack.
+func (s *FrameState) Replace(from interface{}, to interface{}) {
+	for _, values := range [][]interface{}{s.Locals, s.Stack} {
+		for i, value := range values {
+			if value == from {
+				values[i] = to
+			}
+		}
+	}
+}
+
+// SimulationError is an error found by a Simulator in an instruction. Expected and Actual are
+// set for the type errors.
+type SimulationError struct {
+	Message  string
+	Expected string
+	Actual   string
+}
+
+func (e *SimulationError) Error() string {
+	if e.Expected != "" {
+		return fmt.Sprintf("%s (expected %s, found %s)", e.Message, e.Expected, e.Actual)
+	}
+	return e.Message
+}
+
+// Simulator simulates the execution of the instructions of a method on frame states. It is
+// used to compute the stack map frames, and by the verifier, which also checks the types of
+// the values used by each instruction.
+type Simulator struct {
+	// Owner and SuperClass are the internal names of the class of the method and of its super
+	// class.
+	Owner      string
+	SuperClass string
+	Method     *Method
+	// NewTypes gives the type created by the NEW instruction designated by each label, and
+	// NewLabels gives the label which designates each NEW instruction.
+	NewTypes  map[*Label]string
+	NewLabels map[Instruction]*Label
+	// Check enables the checks of the instructions, against the types of their operands, the
+	// max stack and max locals of the method, its return type and its class.
+	Check bool
+	// IsAssignable returns whether a class can be assigned to another one. All the classes are
+	// assignable to each other if it is nil.
+	IsAssignable func(from string, to string) bool
+	// CommonSuperClass returns the internal name of the common super class of two classes. The
+	// classes are merged to java/lang/Object if it is nil.
+	CommonSuperClass func(type1 string, type2 string) string
+}
+
+// NewSimulator returns a simulator of the instructions of a method of the given class, without
+// checks.
+func NewSimulator(owner string, method *Method) *Simulator {
+	return &Simulator{Owner: owner, Method: method, NewTypes: make(map[*Label]string),
+		NewLabels: make(map[Instruction]*Label)}
+}
+
+// InitialState returns the state at the beginning of the method.
+func (s *Simulator) InitialState() *FrameState {
+	state := &FrameState{}
+	if s.Method.AccessFlags&data.ACC_STATIC == 0 {
+		if s.Method.Name == "<init>" && s.Owner != "java/lang/Object" {
+			state.Locals = append(state.Locals, data.ITEM_UNINITIALIZED_THIS)
+		} else {
+			state.Locals = append(state.Locals, s.Owner)
+		}
+	}
+	for _, argument := range NewMethodType(s.Method.Descriptor).ArgumentTypes() {
+		state.Locals = append(state.Locals, frameValues(argument.Descriptor())...)
+	}
+	return state
+}
+
+// Successors returns the jump targets of an instruction, and whether the execution can
+// continue with the next instruction. A JSR instruction jumps to its subroutine, and the
+// successors of a RET instruction are not known.
+func Successors(instruction Instruction) ([]*Label, bool) {
+	switch insn := instruction.(type) {
+	case *JumpInstruction:
+		return []*Label{insn.Label}, insn.Op != data.GOTO && insn.Op != data.JSR
+	case *TableSwitchInstruction:
+		return append([]*Label{insn.Default}, insn.Labels...), false
+	case *LookupSwitchInstruction:
+		return append([]*Label{insn.Default}, insn.Labels...), false
+	case *VarInstruction:
+		if insn.Op == data.RET {
+			return nil, false
+		}
+	case *CodeInstruction:
+		if insn.Op == data.ATHROW || (insn.Op >= data.IRETURN && insn.Op <= data.RETURN) {
+			return nil, false
+		}
+	}
+	return nil, true
+}
+
+// Execute simulates the execution of an instruction on a state. The JSR and RET instructions
+// are not supported.
+func (s *Simulator) Execute(instruction Instruction, state *FrameState) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*SimulationError)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	s.execute(instruction, state)
+	return nil
+}
+
+// ValueString returns the text of a value, as in the verifier errors.
+func (s *Simulator) ValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case *Label:
+		return "uninitialized " + s.NewTypes[v]
+	}
+	switch value {
+	case data.ITEM_TOP:
+		return "top"
+	case data.ITEM_INTEGER:
+		return "int"
+	case data.ITEM_FLOAT:
+		return "float"
+	case data.ITEM_LONG:
+		return "long"
+	case data.ITEM_DOUBLE:
+		return "double"
+	case data.ITEM_NULL:
+		return "null"
+	case data.ITEM_UNINITIALIZED_THIS:
+		return "uninitializedThis"
+	}
+	return fmt.Sprint(value)
+}
+
+// Assignable returns true if a value of type from can be used where a value of type to is
+// expected. As in the JVM verifier, any reference is assignable to an interface, if
+// IsAssignable says so.
+func (s *Simulator) Assignable(from interface{}, to interface{}) bool {
+	if from == to || to == data.ITEM_TOP {
+		return true
+	}
+	toType, ok := to.(string)
+	if !ok {
+		return false
+	}
+	if from == data.ITEM_NULL {
+		return true
+	}
+	fromType, ok := from.(string)
+	return ok && s.assignableType(fromType, toType)
+}
+
+func (s *Simulator) assignableType(from string, to string) bool {
+	if from == to || to == "java/lang/Object" {
+		return true
+	}
+	if strings.HasPrefix(from, "[") {
+		if strings.HasPrefix(to, "[") {
+			fromElement, toElement := elementType(from[1:]), elementType(to[1:])
+			return fromElement != "" && toElement != "" && s.assignableType(fromElement, toElement)
+		}
+		return to == "java/lang/Cloneable" || to == "java/io/Serializable"
+	}
+	if strings.HasPrefix(to, "[") {
+		return false
+	}
+	return s.IsAssignable == nil || s.IsAssignable(from, to)
+}
+
+// Merge returns the most specific value which can be assigned from two values.
+func (s *Simulator) Merge(value1 interface{}, value2 interface{}) interface{} {
+	if value1 == value2 {
+		return value1
+	}
+	if !isReferenceValue(value1) || !isReferenceValue(value2) {
+		return data.ITEM_TOP
+	}
+	if value1 == data.ITEM_NULL {
+		return value2
+	}
+	if value2 == data.ITEM_NULL {
+		return value1
+	}
+	return s.mergeTypes(value1.(string), value2.(string))
+}
+
+func (s *Simulator) mergeTypes(type1 string, type2 string) string {
+	if type1 == type2 {
+		return type1
+	}
+	if strings.HasPrefix(type1, "[") && strings.HasPrefix(type2, "[") {
+		element1, element2 := elementType(type1[1:]), elementType(type2[1:])
+		if element1 != "" && element2 != "" {
+			return "[" + typeDescriptor(s.mergeTypes(element1, element2))
+		}
+		return "java/lang/Object"
+	}
+	if strings.HasPrefix(type1, "[") || strings.HasPrefix(type2, "[") || s.CommonSuperClass == nil {
+		return "java/lang/Object"
+	}
+	return s.CommonSuperClass(type1, type2)
+}
+
+// isReferenceValue returns true for the initialized references, including null.
+func isReferenceValue(value interface{}) bool {
+	_, ok := value.(string)
+	return ok || value == data.ITEM_NULL
+}
+
+// isUninitializedValue returns true for the uninitialized references.
+func isUninitializedValue(value interface{}) bool {
+	_, ok := value.(*Label)
+	return ok || value == data.ITEM_UNINITIALIZED_THIS
+}
+
+// isCategory1Value returns true for the values which use one slot.
+func isCategory1Value(value interface{}) bool {
+	return value != data.ITEM_TOP && value != data.ITEM_LONG && value != data.ITEM_DOUBLE
+}
+
+// elementType returns the internal name or array descriptor of a reference field descriptor,
+// or an empty string for a primitive descriptor.
+func elementType(descriptor string) string {
+	switch descriptor[0] {
+	case 'L':
+		return descriptor[1 : len(descriptor)-1]
+	case '[':
+		return descriptor
+	default:
+		return ""
+	}
+}
+
+// typeDescriptor returns the field descriptor of an internal name or array descriptor.
+func typeDescriptor(name string) string {
+	if strings.HasPrefix(name, "[") {
+		return name
+	}
+	return "L" + name + ";"
+}
+
+// frameValues returns the values of a field descriptor in a frame.
+func frameValues(descriptor string) []interface{} {
+	switch descriptor[0] {
+	case 'Z', 'B', 'C', 'S', 'I':
+		return []interface{}{data.ITEM_INTEGER}
+	case 'F':
+		return []interface{}{data.ITEM_FLOAT}
+	case 'J':
+		return []interface{}{data.ITEM_LONG, data.ITEM_TOP}
+	case 'D':
+		return []interface{}{data.ITEM_DOUBLE, data.ITEM_TOP}
+	case 'V':
+		return nil
+	case 'L':
+		return []interface{}{descriptor[1 : len(descriptor)-1]}
+	default:
+		return []interface{}{descriptor}
+	}
+}
+
+// slots returns the slots of a value of a primitive type.
+func slots(value interface{}) []interface{} {
+	if value == data.ITEM_LONG || value == data.ITEM_DOUBLE {
+		return []interface{}{value, data.ITEM_TOP}
+	}
+	return []interface{}{value}
+}
+
+func (s *Simulator) fail(format string, args ...interface{}) {
+	panic(&SimulationError{Message: fmt.Sprintf(format, args...)})
+}
+
+func (s *Simulator) mismatch(message string, expected string, actual interface{}) {
+	if s.Check {
+		panic(&SimulationError{Message: message, Expected: expected, Actual: s.ValueString(actual)})
+	}
+}
+
+func (s *Simulator) push(state *FrameState, values ...interface{}) {
+	if s.Check && len(state.Stack)+len(values) > int(s.Method.Code.MaxStack) {
+		s.fail("operand stack overflow, max stack is %d", s.Method.Code.MaxStack)
+	}
+	state.Stack = append(state.Stack, values...)
+}
+
+func (s *Simulator) pop(state *FrameState) interface{} {
+	if len(state.Stack) == 0 {
+		s.fail("operand stack underflow")
+	}
+	value := state.Stack[len(state.Stack)-1]
+	state.Stack = state.Stack[:len(state.Stack)-1]
+	return value
+}
+
+// popValue pops a value of the given type, or of a type assignable to it.
+func (s *Simulator) popValue(state *FrameState, expected interface{}) interface{} {
+	if expected == data.ITEM_LONG || expected == data.ITEM_DOUBLE {
+		if second := s.pop(state); second != data.ITEM_TOP {
+			s.mismatch("bad type on operand stack", s.ValueString(expected), second)
+		}
+	}
+	value := s.pop(state)
+	if !s.Assignable(value, expected) {
+		s.mismatch("bad type on operand stack", s.ValueString(expected), value)
+	}
+	return value
+}
+
+// popDescriptor pops a value of the type of a field descriptor.
+func (s *Simulator) popDescriptor(state *FrameState, descriptor string) {
+	s.popValue(state, frameValues(descriptor)[0])
+}
+
+// popArguments pops the arguments of a method descriptor.
+func (s *Simulator) popArguments(state *FrameState, descriptor string) {
+	arguments := NewMethodType(descriptor).ArgumentTypes()
+	for i := len(arguments) - 1; i >= 0; i-- {
+		s.popDescriptor(state, arguments[i].Descriptor())
+	}
+}
+
+// popReference pops an initialized reference or null.
+func (s *Simulator) popReference(state *FrameState) interface{} {
+	value := s.pop(state)
+	if !isReferenceValue(value) {
+		s.mismatch("bad type on operand stack", "reference", value)
+	}
+	return value
+}
+
+// popArray pops null or an array of one of the given types. An empty descriptor accepts the
+// arrays of references.
+func (s *Simulator) popArray(state *FrameState, descriptors ...string) interface{} {
+	value := s.pop(state)
+	if value == data.ITEM_NULL {
+		return value
+	}
+	if array, ok := value.(string); ok && strings.HasPrefix(array, "[") {
+		for _, descriptor := range descriptors {
+			if descriptor == array || descriptor == "" && elementType(array[1:]) != "" {
+				return value
+			}
+		}
+	}
+	expected := strings.Join(descriptors, " or ")
+	if expected == "" {
+		expected = "array of references"
+	}
+	if s.Check {
+		panic(&SimulationError{Message: "bad array type on operand stack", Expected: expected, Actual: s.ValueString(value)})
+	}
+	return value
+}
+
+func (s *Simulator) popCategory1(state *FrameState) interface{} {
+	value := s.pop(state)
+	if !isCategory1Value(value) {
+		s.mismatch("bad type on operand stack", "category 1 value", value)
+	}
+	return value
+}
+
+// popWords pops a category 2 value, or two category 1 values, as two slots.
+func (s *Simulator) popWords(state *FrameState) []interface{} {
+	value := s.pop(state)
+	if value == data.ITEM_TOP {
+		under := s.pop(state)
+		if under != data.ITEM_LONG && under != data.ITEM_DOUBLE {
+			s.mismatch("bad type on operand stack", "category 2 value", under)
+		}
+		return []interface{}{under, value}
+	}
+	if !isCategory1Value(value) {
+		s.mismatch("bad type on operand stack", "category 1 value", value)
+	}
+	return []interface{}{s.popCategory1(state), value}
+}
+
+// local checks the index of a local variable when checking, or adds the missing local
+// variables otherwise.
+func (s *Simulator) local(state *FrameState, index int, size int) {
+	if s.Check {
+		if index < 0 || index+size > int(s.Method.Code.MaxLocal) {
+			s.fail("local variable %d out of range, max locals is %d", index, s.Method.Code.MaxLocal)
+		}
+	}
+	for len(state.Locals) < index+size {
+		state.Locals = append(state.Locals, data.ITEM_TOP)
+	}
+}
+
+// load pushes a local variable of the given type.
+func (s *Simulator) load(state *FrameState, index int, expected interface{}) {
+	size := len(slots(expected))
+	s.local(state, index, size)
+	value := state.Locals[index]
+	if _, ok := expected.(string); ok {
+		if !isReferenceValue(value) && !isUninitializedValue(value) {
+			s.mismatch(fmt.Sprintf("bad type in local variable %d", index), "reference", value)
+		}
+	} else if value != expected || size == 2 && state.Locals[index+1] != data.ITEM_TOP {
+		s.mismatch(fmt.Sprintf("bad type in local variable %d", index), s.ValueString(expected), value)
+	}
+	s.push(state, state.Locals[index:index+size]...)
+}
+
+// store stores values in local variables.
+func (s *Simulator) store(state *FrameState, index int, values ...interface{}) {
+	s.local(state, index, len(values))
+	if index > 0 {
+		if previous := state.Locals[index-1]; previous == data.ITEM_LONG || previous == data.ITEM_DOUBLE {
+			state.Locals[index-1] = data.ITEM_TOP
+		}
+	}
+	copy(state.Locals[index:], values)
+}
+
+var newArrayDescriptors = map[int32]string{data.T_BOOLEAN: "Z", data.T_CHAR: "C", data.T_FLOAT: "F",
+	data.T_DOUBLE: "D", data.T_BYTE: "B", data.T_SHORT: "S", data.T_INT: "I", data.T_LONG: "J"}
+
+// arrayDescriptors gives the array types accepted by the array load and store instructions
+// other than AALOAD and AASTORE.
+var arrayDescriptors = map[int]string{
+	data.IALOAD: "[I", data.LALOAD: "[J", data.FALOAD: "[F", data.DALOAD: "[D", data.BALOAD: "[B",
+	data.CALOAD: "[C", data.SALOAD: "[S", data.IASTORE: "[I", data.LASTORE: "[J", data.FASTORE: "[F",
+	data.DASTORE: "[D", data.BASTORE: "[B", data.CASTORE: "[C", data.SASTORE: "[S",
+}
+
+// conversions gives the operand and result types of the conversion instructions.
+var conversions = map[int][2]uint8{
+	data.I2L: {data.ITEM_INTEGER, data.ITEM_LONG}, data.I2F: {data.ITEM_INTEGER, data.ITEM_FLOAT},
+	data.I2D: {data.ITEM_INTEGER, data.ITEM_DOUBLE}, data.L2I: {data.ITEM_LONG, data.ITEM_INTEGER},
+	data.L2F: {data.ITEM_LONG, data.ITEM_FLOAT}, data.L2D: {data.ITEM_LONG, data.ITEM_DOUBLE},
+	data.F2I: {data.ITEM_FLOAT, data.ITEM_INTEGER}, data.F2L: {data.ITEM_FLOAT, data.ITEM_LONG},
+	data.F2D: {data.ITEM_FLOAT, data.ITEM_DOUBLE}, data.D2I: {data.ITEM_DOUBLE, data.ITEM_INTEGER},
+	data.D2L: {data.ITEM_DOUBLE, data.ITEM_LONG}, data.D2F: {data.ITEM_DOUBLE, data.ITEM_FLOAT},
+	data.I2B: {data.ITEM_INTEGER, data.ITEM_INTEGER}, data.I2C: {data.ITEM_INTEGER, data.ITEM_INTEGER},
+	data.I2S: {data.ITEM_INTEGER, data.ITEM_INTEGER},
+}
+
+// arithmeticTypes gives the operand types of the arithmetic instructions, by opcode modulo 4.
+var arithmeticTypes = []uint8{data.ITEM_INTEGER, data.ITEM_LONG, data.ITEM_FLOAT, data.ITEM_DOUBLE}
+
+// execute simulates the execution of an instruction on a state.
+func (s *Simulator) execute(instruction Instruction, state *FrameState) {
+	switch insn := instruction.(type) {
+	case *CodeInstruction:
+		s.executeCode(int(insn.Op), state)
+	case *IntInstruction:
+		if insn.Op == data.NEWARRAY {
+			descriptor, ok := newArrayDescriptors[insn.Operand]
+			if !ok && s.Check {
+				s.fail("invalid array type %d", insn.Operand)
+			}
+			s.popValue(state, data.ITEM_INTEGER)
+			s.push(state, "["+descriptor)
+		} else {
+			s.push(state, data.ITEM_INTEGER)
+		}
+	case *VarInstruction:
+		s.executeVar(insn, state)
+	case *IincInstruction:
+		s.local(state, insn.Var, 1)
+		if value := state.Locals[insn.Var]; value != data.ITEM_INTEGER {
+			s.mismatch(fmt.Sprintf("bad type in local variable %d", insn.Var), "int", value)
+		}
+		s.store(state, insn.Var, data.ITEM_INTEGER)
+	case *TypeInstruction:
+		switch insn.Op {
+		case data.NEW:
+			if s.Check && strings.HasPrefix(insn.Type, "[") {
+				s.fail("new can't create an array")
+			}
+			label := s.NewLabels[insn]
+			state.Replace(label, data.ITEM_TOP)
+			s.push(state, label)
+		case data.ANEWARRAY:
+			s.popValue(state, data.ITEM_INTEGER)
+			s.push(state, "["+typeDescriptor(insn.Type))
+		case data.CHECKCAST:
+			s.popReference(state)
+			s.push(state, insn.Type)
+		case data.INSTANCEOF:
+			s.popReference(state)
+			s.push(state, data.ITEM_INTEGER)
+		}
+	case *FieldInstruction:
+		s.executeField(insn, state)
+	case *MethodInstruction:
+		s.executeMethod(insn, state)
+	case *InvokeDynamicInstruction:
+		s.popArguments(state, insn.Descriptor)
+		s.push(state, frameValues(NewMethodType(insn.Descriptor).ReturnType().Descriptor())...)
+	case *JumpInstruction:
+		switch {
+		case insn.Op >= data.IFEQ && insn.Op <= data.IFLE:
+			s.popValue(state, data.ITEM_INTEGER)
+		case insn.Op >= data.IF_ICMPEQ && insn.Op <= data.IF_ICMPLE:
+			s.popValue(state, data.ITEM_INTEGER)
+			s.popValue(state, data.ITEM_INTEGER)
+		case insn.Op == data.IF_ACMPEQ || insn.Op == data.IF_ACMPNE:
+			s.popReference(state)
+			s.popReference(state)
+		case insn.Op == data.IFNULL || insn.Op == data.IFNONNULL:
+			s.popReference(state)
+		case insn.Op == data.JSR:
+			s.fail("jsr instructions are not supported")
+		}
+	case *LdcInstruction:
+		switch v := insn.Value.(type) {
+		case int32:
+			s.push(state, data.ITEM_INTEGER)
+		case float32:
+			s.push(state, data.ITEM_FLOAT)
+		case int64:
+			s.push(state, data.ITEM_LONG, data.ITEM_TOP)
+		case float64:
+			s.push(state, data.ITEM_DOUBLE, data.ITEM_TOP)
+		case string:
+			s.push(state, "java/lang/String")
+		case Type:
+			if v.Sort() == data.TYPE_SORT_METHOD {
+				s.push(state, "java/lang/invoke/MethodType")
+			} else {
+				s.push(state, "java/lang/Class")
+			}
+		case Handle:
+			s.push(state, "java/lang/invoke/MethodHandle")
+		case ConstantDynamic:
+			s.push(state, frameValues(v.Descriptor)...)
+		default:
+			s.fail("invalid constant %v", insn.Value)
+		}
+	case *TableSwitchInstruction, *LookupSwitchInstruction:
+		s.popValue(state, data.ITEM_INTEGER)
+	case *MultiANewArrayInstruction:
+		if s.Check && (insn.NumDimensions < 1 || !strings.HasPrefix(insn.Descriptor, strings.Repeat("[", insn.NumDimensions))) {
+			s.fail("invalid number of dimensions %d", insn.NumDimensions)
+		}
+		for i := 0; i < insn.NumDimensions; i++ {
+			s.popValue(state, data.ITEM_INTEGER)
+		}
+		s.push(state, insn.Descriptor)
+	default:
+		s.fail("unexpected instruction %T", instruction)
+	}
+}
+
+func (s *Simulator) executeCode(op int, state *FrameState) {
+	switch {
+	case op == data.NOP:
+	case op == data.ACONST_NULL:
+		s.push(state, data.ITEM_NULL)
+	case op >= data.ICONST_M1 && op <= data.ICONST_5:
+		s.push(state, data.ITEM_INTEGER)
+	case op == data.LCONST_0 || op == data.LCONST_1:
+		s.push(state, data.ITEM_LONG, data.ITEM_TOP)
+	case op >= data.FCONST_0 && op <= data.FCONST_2:
+		s.push(state, data.ITEM_FLOAT)
+	case op == data.DCONST_0 || op == data.DCONST_1:
+		s.push(state, data.ITEM_DOUBLE, data.ITEM_TOP)
+	case op >= data.ILOAD_0 && op < data.IALOAD:
+		kind := (op - data.ILOAD_0) / 4
+		s.executeVar(&VarInstruction{Op: uint16(data.ILOAD + kind), Var: (op - data.ILOAD_0) % 4}, state)
+	case op >= data.ISTORE_0 && op < data.IASTORE:
+		kind := (op - data.ISTORE_0) / 4
+		s.executeVar(&VarInstruction{Op: uint16(data.ISTORE + kind), Var: (op - data.ISTORE_0) % 4}, state)
+	case op >= data.IALOAD && op <= data.SALOAD:
+		s.popValue(state, data.ITEM_INTEGER)
+		if op == data.AALOAD {
+			array, ok := s.popArray(state, "").(string)
+			if ok && strings.HasPrefix(array, "[") {
+				s.push(state, frameValues(array[1:])...)
+			} else {
+				s.push(state, data.ITEM_NULL)
+			}
+		} else if op == data.BALOAD {
+			s.popArray(state, "[B", "[Z")
+			s.push(state, data.ITEM_INTEGER)
+		} else {
+			s.popArray(state, arrayDescriptors[op])
+			s.push(state, frameValues(arrayDescriptors[op][1:])...)
+		}
+	case op >= data.IASTORE && op <= data.SASTORE:
+		switch op {
+		case data.AASTORE:
+			s.popReference(state)
+			s.popValue(state, data.ITEM_INTEGER)
+			s.popArray(state, "")
+		case data.BASTORE:
+			s.popValue(state, data.ITEM_INTEGER)
+			s.popValue(state, data.ITEM_INTEGER)
+			s.popArray(state, "[B", "[Z")
+		default:
+			s.popValue(state, frameValues(arrayDescriptors[op][1:])[0])
+			s.popValue(state, data.ITEM_INTEGER)
+			s.popArray(state, arrayDescriptors[op])
+		}
+	case op == data.POP:
+		s.popCategory1(state)
+	case op == data.POP2:
+		s.popWords(state)
+	case op == data.DUP:
+		v := s.popCategory1(state)
+		s.push(state, v, v)
+	case op == data.DUP_X1:
+		v1, v2 := s.popCategory1(state), s.popCategory1(state)
+		s.push(state, v1, v2, v1)
+	case op == data.DUP_X2:
+		v1, w := s.popCategory1(state), s.popWords(state)
+		s.push(state, v1, w[0], w[1], v1)
+	case op == data.DUP2:
+		w := s.popWords(state)
+		s.push(state, w[0], w[1], w[0], w[1])
+	case op == data.DUP2_X1:
+		w, v := s.popWords(state), s.popCategory1(state)
+		s.push(state, w[0], w[1], v, w[0], w[1])
+	case op == data.DUP2_X2:
+		w1, w2 := s.popWords(state), s.popWords(state)
+		s.push(state, w1[0], w1[1], w2[0], w2[1], w1[0], w1[1])
+	case op == data.SWAP:
+		v1, v2 := s.popCategory1(state), s.popCategory1(state)
+		s.push(state, v1, v2)
+	case op >= data.IADD && op <= data.DREM:
+		t := arithmeticTypes[(op-data.IADD)%4]
+		s.popValue(state, t)
+		s.popValue(state, t)
+		s.push(state, slots(t)...)
+	case op >= data.INEG && op <= data.DNEG:
+		t := arithmeticTypes[(op-data.INEG)%4]
+		s.popValue(state, t)
+		s.push(state, slots(t)...)
+	case op >= data.ISHL && op <= data.LXOR:
+		t := arithmeticTypes[(op-data.ISHL)%2]
+		if op <= data.LUSHR {
+			s.popValue(state, data.ITEM_INTEGER)
+		} else {
+			s.popValue(state, t)
+		}
+		s.popValue(state, t)
+		s.push(state, slots(t)...)
+	case op >= data.I2L && op <= data.I2S:
+		conversion := conversions[op]
+		s.popValue(state, conversion[0])
+		s.push(state, slots(conversion[1])...)
+	case op == data.LCMP:
+		s.popValue(state, data.ITEM_LONG)
+		s.popValue(state, data.ITEM_LONG)
+		s.push(state, data.ITEM_INTEGER)
+	case op == data.FCMPL || op == data.FCMPG:
+		s.popValue(state, data.ITEM_FLOAT)
+		s.popValue(state, data.ITEM_FLOAT)
+		s.push(state, data.ITEM_INTEGER)
+	case op == data.DCMPL || op == data.DCMPG:
+		s.popValue(state, data.ITEM_DOUBLE)
+		s.popValue(state, data.ITEM_DOUBLE)
+		s.push(state, data.ITEM_INTEGER)
+	case op >= data.IRETURN && op <= data.RETURN:
+		s.executeReturn(op, state)
+	case op == data.ARRAYLENGTH:
+		array := s.pop(state)
+		if name, ok := array.(string); array != data.ITEM_NULL && (!ok || !strings.HasPrefix(name, "[")) {
+			s.mismatch("bad type on operand stack", "array", array)
+		}
+		s.push(state, data.ITEM_INTEGER)
+	case op == data.ATHROW:
+		s.popValue(state, "java/lang/Throwable")
+	case op == data.MONITORENTER || op == data.MONITOREXIT:
+		s.popReference(state)
+	default:
+		s.fail("invalid opcode %d", op)
+	}
+}
+
+func (s *Simulator) executeReturn(op int, state *FrameState) {
+	returnType := NewMethodType(s.Method.Descriptor).ReturnType().Descriptor()
+	if op == data.RETURN {
+		if !s.Check {
+			return
+		}
+		if returnType != "V" {
+			s.fail("return in a method returning %s", returnType)
+		}
+		for _, value := range state.Locals {
+			if value == data.ITEM_UNINITIALIZED_THIS {
+				s.fail("constructor returns before the call of a super or this constructor")
+			}
+		}
+		return
+	}
+	// The return instructions are ordered as the arithmetic types, followed by ARETURN.
+	var expected interface{} = "java/lang/Object"
+	if op < data.ARETURN {
+		expected = arithmeticTypes[op-data.IRETURN]
+	}
+	if s.Check {
+		values := frameValues(returnType)
+		if len(values) == 0 || !sameKind(values[0], expected) {
+			s.fail("bad return instruction for the return type %s", returnType)
+		}
+		expected = values[0]
+	}
+	s.popValue(state, expected)
+}
+
+// sameKind returns true if two values are both references, or the same primitive value.
+func sameKind(value1 interface{}, value2 interface{}) bool {
+	_, reference1 := value1.(string)
+	_, reference2 := value2.(string)
+	return reference1 && reference2 || value1 == value2
+}
+
+func (s *Simulator) executeVar(insn *VarInstruction, state *FrameState) {
+	switch op := int(insn.Op); {
+	case op >= data.ILOAD && op <= data.ALOAD:
+		var expected interface{} = "java/lang/Object"
+		if op < data.ALOAD {
+			expected = arithmeticTypes[op-data.ILOAD]
+		}
+		s.load(state, insn.Var, expected)
+	case op >= data.ISTORE && op < data.ASTORE:
+		t := arithmeticTypes[op-data.ISTORE]
+		s.popValue(state, t)
+		s.store(state, insn.Var, slots(t)...)
+	case op == data.ASTORE:
+		value := s.pop(state)
+		if !isReferenceValue(value) && !isUninitializedValue(value) {
+			s.mismatch("bad type on operand stack", "reference", value)
+		}
+		s.store(state, insn.Var, value)
+	case op == data.RET:
+		s.fail("ret instructions are not supported")
+	}
+}
+
+func (s *Simulator) executeField(insn *FieldInstruction, state *FrameState) {
+	switch insn.Op {
+	case data.GETSTATIC:
+		s.push(state, frameValues(insn.Descriptor)...)
+	case data.PUTSTATIC:
+		s.popDescriptor(state, insn.Descriptor)
+	case data.GETFIELD:
+		s.popValue(state, insn.Owner)
+		s.push(state, frameValues(insn.Descriptor)...)
+	case data.PUTFIELD:
+		s.popDescriptor(state, insn.Descriptor)
+		if receiver := s.pop(state); receiver == data.ITEM_UNINITIALIZED_THIS {
+			if s.Check && insn.Owner != s.Owner {
+				s.fail("putfield on uninitializedThis must assign a field of %s", s.Owner)
+			}
+		} else if !s.Assignable(receiver, insn.Owner) {
+			s.mismatch("bad type on operand stack", insn.Owner, receiver)
+		}
+	}
+}
+
+func (s *Simulator) executeMethod(insn *MethodInstruction, state *FrameState) {
+	s.popArguments(state, insn.Descriptor)
+	if insn.Name == "<init>" {
+		if s.Check && insn.Op != data.INVOKESPECIAL {
+			s.fail("constructors must be invoked with invokespecial")
+		}
+		receiver := s.pop(state)
+		if receiver == data.ITEM_UNINITIALIZED_THIS {
+			if s.Check && insn.Owner != s.Owner && insn.Owner != s.SuperClass {
+				s.fail("bad constructor call on uninitializedThis, expected %s or %s", s.Owner, s.SuperClass)
+			}
+			state.Replace(receiver, s.Owner)
+		} else if label, ok := receiver.(*Label); ok {
+			if insn.Owner != s.NewTypes[label] {
+				s.mismatch("bad constructor call", s.ValueString(receiver), insn.Owner)
+			}
+			state.Replace(receiver, s.NewTypes[label])
+		} else {
+			s.mismatch("bad type on operand stack", "uninitialized value", receiver)
+		}
+	} else if insn.Op != data.INVOKESTATIC {
+		owner := insn.Owner
+		if insn.Op == data.INVOKESPECIAL && s.Check {
+			if !s.assignableType(s.Owner, insn.Owner) {
+				s.fail("invokespecial on %s, which is not a super type of %s", insn.Owner, s.Owner)
+			}
+			owner = s.Owner
+		}
+		s.popValue(state, owner)
+	}
+	s.push(state, frameValues(NewMethodType(insn.Descriptor).ReturnType().Descriptor())...)
+}
@@ -0,0 +1,43 @@
+package class
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/tools"
+)
+
+func TestSimulator(t *testing.T) {
+	method := &Method{AccessFlags: data.ACC_STATIC, Name: "m", Descriptor: "(J[Ljava/lang/String;)I",
+		Code: MethodCode{MaxStack: 3, MaxLocal: 3}}
+	simulator := NewSimulator("a/A", method)
+	state := simulator.InitialState()
+	tools.AssertEqual(t, "[4 0 [Ljava/lang/String;]", fmt.Sprint(state.Locals))
+	for _, instruction := range []Instruction{
+		&VarInstruction{Op: data.ALOAD, Var: 2},
+		&CodeInstruction{Op: data.ICONST_0},
+		&CodeInstruction{Op: data.AALOAD},
+		&VarInstruction{Op: data.LLOAD, Var: 0},
+	} {
+		tools.AssertNoErr(t, simulator.Execute(instruction, state))
+	}
+	tools.AssertEqual(t, "[java/lang/String 4 0]", fmt.Sprint(state.Stack))
+
+	// Without checks, only the stack heights matter.
+	tools.AssertNoErr(t, simulator.Execute(&CodeInstruction{Op: data.IADD}, state.Copy()))
+	simulator.Check = true
+	err := simulator.Execute(&CodeInstruction{Op: data.IADD}, state.Copy())
+	tools.AssertEqual(t, "bad type on operand stack (expected int, found top)", err.Error())
+	err = simulator.Execute(&CodeInstruction{Op: data.ICONST_0}, state.Copy())
+	tools.AssertEqual(t, "operand stack overflow, max stack is 3", err.Error())
+	tools.AssertEqual(t, "operand stack underflow", simulator.Execute(&CodeInstruction{Op: data.POP}, &FrameState{}).Error())
+	tools.AssertNoErr(t, simulator.Execute(&CodeInstruction{Op: data.POP2}, state))
+	tools.AssertNoErr(t, simulator.Execute(&CodeInstruction{Op: data.IRETURN}, &FrameState{Stack: []interface{}{data.ITEM_INTEGER}}))
+	err = simulator.Execute(&CodeInstruction{Op: data.ARETURN}, state)
+	tools.AssertEqual(t, "bad return instruction for the return type I", err.Error())
+
+	tools.AssertEqual(t, "[Ljava/lang/Object;", simulator.Merge("[La/B;", "[Ljava/lang/String;"))
+	tools.AssertEqual(t, "java/lang/Object", simulator.Merge("[I", "[J"))
+	tools.AssertEqual(t, data.ITEM_TOP, simulator.Merge(data.ITEM_INTEGER, "a/B"))
+}
@@ -0,0 +1,199 @@
+package class
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+// symbolTable builds the constant pool and the bootstrap methods of a class. Equal constants
+// and bootstrap methods are added only once.
+type symbolTable struct {
+	constants        []data.ConstantData
+	indexes          map[string]uint16
+	bootstrapMethods [][]uint16
+	bootstrapIndexes map[string]uint16
+}
+
+func newSymbolTable() *symbolTable {
+	return &symbolTable{
+		constants:        []data.ConstantData{nil},
+		indexes:          make(map[string]uint16),
+		bootstrapIndexes: make(map[string]uint16),
+	}
+}
+
+func (s *symbolTable) add(key string, constant data.ConstantData) uint16 {
+	if index, ok := s.indexes[key]; ok {
+		return index
+	}
+	if len(s.constants) >= math.MaxUint16 {
+		panic("too many constants")
+	}
+	index := uint16(len(s.constants))
+	s.constants = append(s.constants, constant)
+	tag := constant.Tag()
+	if tag == data.TAG_CONSTANT_LONG || tag == data.TAG_CONSTANT_DOUBLE {
+		s.constants = append(s.constants, nil)
+	}
+	s.indexes[key] = index
+	return index
+}
+
+func (s *symbolTable) addUTF8(value string) uint16 {
+	return s.add("1:"+value, data.ConstantUTF8Data{Length: uint16(len(value)), UTF8Value: value})
+}
+
+func (s *symbolTable) addInteger(value int32) uint16 {
+	return s.add(fmt.Sprintf("3:%d", value), data.ConstantIntegerData{IntegerValue: value})
+}
+
+func (s *symbolTable) addFloat(value float32) uint16 {
+	return s.add(fmt.Sprintf("4:%d", math.Float32bits(value)), data.ConstantFloatData{FloatValue: value})
+}
+
+func (s *symbolTable) addLong(value int64) uint16 {
+	return s.add(fmt.Sprintf("5:%d", value), data.ConstantLongData{LongValue: value})
+}
+
+func (s *symbolTable) addDouble(value float64) uint16 {
+	return s.add(fmt.Sprintf("6:%d", math.Float64bits(value)), data.ConstantDoubleData{DoubleValue: value})
+}
+
+// addClass adds a CONSTANT_Class, or returns 0 if the name is empty.
+func (s *symbolTable) addClass(internalName string) uint16 {
+	if len(internalName) == 0 {
+		return 0
+	}
+	nameIndex := s.addUTF8(internalName)
+	return s.add("7:"+internalName, data.ConstantClassData{NameIndex: nameIndex})
+}
+
+func (s *symbolTable) addString(value string) uint16 {
+	valueIndex := s.addUTF8(value)
+	return s.add("8:"+value, data.ConstantStringData{ValueIndex: valueIndex})
+}
+
+func (s *symbolTable) addNameAndType(name string, descriptor string) uint16 {
+	nameIndex := s.addUTF8(name)
+	descriptorIndex := s.addUTF8(descriptor)
+	return s.add("12:"+name+" "+descriptor, data.ConstantNameAndTypeData{NameIndex: nameIndex, DescriptorIndex: descriptorIndex})
+}
+
+func (s *symbolTable) addFieldRef(owner string, name string, descriptor string) uint16 {
+	classIndex := s.addClass(owner)
+	nameAndTypeIndex := s.addNameAndType(name, descriptor)
+	return s.add("9:"+owner+" "+name+" "+descriptor, data.ConstantFieldRefData{ClassIndex: classIndex, NameAndTypeIndex: nameAndTypeIndex})
+}
+
+func (s *symbolTable) addMethodRef(owner string, name string, descriptor string, isInterface bool) uint16 {
+	classIndex := s.addClass(owner)
+	nameAndTypeIndex := s.addNameAndType(name, descriptor)
+	if isInterface {
+		return s.add("11:"+owner+" "+name+" "+descriptor, data.ConstantInterfaceMethodRefData{ClassIndex: classIndex, NameAndTypeIndex: nameAndTypeIndex})
+	}
+	return s.add("10:"+owner+" "+name+" "+descriptor, data.ConstantMethodRefData{ClassIndex: classIndex, NameAndTypeIndex: nameAndTypeIndex})
+}
+
+func (s *symbolTable) addMethodHandle(handle Handle) uint16 {
+	var referenceIndex uint16
+	if handle.Tag <= data.HANDLE_PUTSTATIC {
+		referenceIndex = s.addFieldRef(handle.Owner, handle.Name, handle.Descriptor)
+	} else {
+		referenceIndex = s.addMethodRef(handle.Owner, handle.Name, handle.Descriptor, handle.IsInterface)
+	}
+	key := fmt.Sprintf("15:%d %d", handle.Tag, referenceIndex)
+	return s.add(key, data.ConstantMethodHandleData{ReferenceKind: handle.Tag, ReferenceIndex: referenceIndex})
+}
+
+func (s *symbolTable) addMethodType(descriptor string) uint16 {
+	descriptorIndex := s.addUTF8(descriptor)
+	return s.add("16:"+descriptor, data.ConstantMethodTypeData{DescriptorIndex: descriptorIndex})
+}
+
+func (s *symbolTable) addConstantDynamic(constantDynamic ConstantDynamic) uint16 {
+	bootstrapMethodIndex := s.addBootstrapMethod(constantDynamic.BootstrapMethod, constantDynamic.BootstrapMethodArguments)
+	nameAndTypeIndex := s.addNameAndType(constantDynamic.Name, constantDynamic.Descriptor)
+	key := fmt.Sprintf("17:%d %d", bootstrapMethodIndex, nameAndTypeIndex)
+	return s.add(key, data.ConstantDynamicData{BootstrapMethodIndex: bootstrapMethodIndex, NameAndTypeIndex: nameAndTypeIndex})
+}
+
+func (s *symbolTable) addInvokeDynamic(name string, descriptor string, handle Handle, arguments []interface{}) uint16 {
+	bootstrapMethodIndex := s.addBootstrapMethod(handle, arguments)
+	nameAndTypeIndex := s.addNameAndType(name, descriptor)
+	key := fmt.Sprintf("18:%d %d", bootstrapMethodIndex, nameAndTypeIndex)
+	return s.add(key, data.ConstantInvokeDynamicData{BootstrapMethodIndex: bootstrapMethodIndex, NameAndTypeIndex: nameAndTypeIndex})
+}
+
+func (s *symbolTable) addModule(name string) uint16 {
+	nameIndex := s.addUTF8(name)
+	return s.add("19:"+name, data.ConstantModuleData{NameIndex: nameIndex})
+}
+
+func (s *symbolTable) addPackage(name string) uint16 {
+	nameIndex := s.addUTF8(name)
+	return s.add("20:"+name, data.ConstantPackageData{NameIndex: nameIndex})
+}
+
+// addConstant adds a loadable constant, which can be an int32, a float32, an int64, a float64,
+// a string, a Type, a Handle or a ConstantDynamic.
+func (s *symbolTable) addConstant(value interface{}) uint16 {
+	switch v := value.(type) {
+	case int32:
+		return s.addInteger(v)
+	case int:
+		return s.addInteger(int32(v))
+	case int8:
+		return s.addInteger(int32(v))
+	case int16:
+		return s.addInteger(int32(v))
+	case uint16:
+		return s.addInteger(int32(v))
+	case bool:
+		if v {
+			return s.addInteger(1)
+		}
+		return s.addInteger(0)
+	case float32:
+		return s.addFloat(v)
+	case int64:
+		return s.addLong(v)
+	case float64:
+		return s.addDouble(v)
+	case string:
+		return s.addString(v)
+	case Type:
+		if v.Sort() == data.TYPE_SORT_METHOD {
+			return s.addMethodType(v.Descriptor())
+		}
+		return s.addClass(v.InternalName())
+	case Handle:
+		return s.addMethodHandle(v)
+	case ConstantDynamic:
+		return s.addConstantDynamic(v)
+	default:
+		panic(fmt.Sprintf("invalid constant %v", value))
+	}
+}
+
+func (s *symbolTable) addBootstrapMethod(handle Handle, arguments []interface{}) uint16 {
+	bootstrapMethod := make([]uint16, 0, len(arguments)+1)
+	bootstrapMethod = append(bootstrapMethod, s.addMethodHandle(handle))
+	for _, argument := range arguments {
+		bootstrapMethod = append(bootstrapMethod, s.addConstant(argument))
+	}
+	keys := make([]string, len(bootstrapMethod))
+	for i, index := range bootstrapMethod {
+		keys[i] = fmt.Sprint(index)
+	}
+	key := strings.Join(keys, " ")
+	if index, ok := s.bootstrapIndexes[key]; ok {
+		return index
+	}
+	index := uint16(len(s.bootstrapMethods))
+	s.bootstrapMethods = append(s.bootstrapMethods, bootstrapMethod)
+	s.bootstrapIndexes[key] = index
+	return index
+}
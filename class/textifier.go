@@ -0,0 +1,742 @@
+package class
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+const (
+	textTab  = "  "
+	textTab2 = "    "
+	textTab3 = "      "
+	textLTab = "   "
+)
+
+var frameTypeNames = []string{"TOP", "INTEGER", "FLOAT", "DOUBLE", "LONG", "NULL", "UNINITIALIZED_THIS"}
+
+var arrayTypeNames = map[int32]string{data.T_BOOLEAN: "T_BOOLEAN", data.T_CHAR: "T_CHAR", data.T_FLOAT: "T_FLOAT",
+	data.T_DOUBLE: "T_DOUBLE", data.T_BYTE: "T_BYTE", data.T_SHORT: "T_SHORT", data.T_INT: "T_INT", data.T_LONG: "T_LONG"}
+
+var handleTagNames = []string{"", "GETFIELD", "GETSTATIC", "PUTFIELD", "PUTSTATIC", "INVOKEVIRTUAL", "INVOKESTATIC",
+	"INVOKESPECIAL", "NEWINVOKESPECIAL", "INVOKEINTERFACE"}
+
+// Textifier is a Visitor that records the events it receives as indented text, one line per
+// event, in a format close to the one of the ASM Textifier. The field, method, annotation and
+// module visitors it returns write to the same text, so the visit order is preserved.
+type Textifier struct {
+	text       strings.Builder
+	labelNames map[*Label]string
+}
+
+func NewTextifier() *Textifier {
+	return &Textifier{labelNames: make(map[*Label]string)}
+}
+
+// String returns the text recorded so far.
+func (t *Textifier) String() string {
+	return t.text.String()
+}
+
+func (t *Textifier) print(s ...string) {
+	for _, part := range s {
+		t.text.WriteString(part)
+	}
+}
+
+func (t *Textifier) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&t.text, format, args...)
+}
+
+func (t *Textifier) Visit(version uint32, access uint16, name string, signature string, superName string, interfaces []string) {
+	major := version & 0xffff
+	minor := version >> 16
+	t.printf("// class version %d.%d (%d)\n", major, minor, version)
+	t.printf("// access flags 0x%X\n", access)
+	t.printSignature("", signature)
+	switch {
+	case access&data.ACC_ANNOTATION != 0:
+		t.print(accessText(access&^(data.ACC_INTERFACE|data.ACC_ABSTRACT|data.ACC_SUPER), accessClass), "@interface ")
+	case access&data.ACC_INTERFACE != 0:
+		t.print(accessText(access&^(data.ACC_INTERFACE|data.ACC_ABSTRACT|data.ACC_SUPER), accessClass), "interface ")
+	case access&data.ACC_MODULE != 0:
+		t.print(accessText(access&^data.ACC_MODULE, accessClass))
+	case access&data.ACC_ENUM != 0:
+		t.print(accessText(access&^(data.ACC_ENUM|data.ACC_SUPER), accessClass), "enum ")
+	default:
+		t.print(accessText(access&^data.ACC_SUPER, accessClass), "class ")
+	}
+	t.print(name)
+	if len(superName) > 0 && superName != "java/lang/Object" {
+		t.print(" extends ", superName)
+	}
+	if len(interfaces) > 0 {
+		t.print(" implements ", strings.Join(interfaces, " "))
+	}
+	t.print(" {\n\n")
+}
+
+func (t *Textifier) VisitSource(source string, debug string) {
+	if len(source) > 0 {
+		t.print(textTab, "// compiled from: ", source, "\n")
+	}
+	if len(debug) > 0 {
+		t.print(textTab, "// debug info: ", debug, "\n")
+	}
+}
+
+func (t *Textifier) VisitModule(name string, access uint16, version string) ModuleVisitor {
+	t.print(textTab, accessText(access, accessModule), "module ", name)
+	if len(version) > 0 {
+		t.print("@", version)
+	}
+	t.print(" { // access flags ", fmt.Sprintf("0x%X", access), "\n")
+	return &textModuleVisitor{t: t}
+}
+
+func (t *Textifier) VisitNestHost(nestHost string) {
+	t.print(textTab, "NESTHOST ", nestHost, "\n")
+}
+
+func (t *Textifier) VisitOuterClass(owner string, name string, descriptor string) {
+	t.print(textTab, "OUTERCLASS ", owner)
+	if len(name) > 0 {
+		t.print(" ", name, " ", descriptor)
+	}
+	t.print("\n")
+}
+
+func (t *Textifier) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return t.annotation(textTab, descriptor, visible, "")
+}
+
+func (t *Textifier) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return t.typeAnnotation(textTab, "", typeRef, typePath, descriptor, visible, "")
+}
+
+func (t *Textifier) VisitAttribute(attribute Attribute) {
+	t.printAttribute(textTab, attribute)
+}
+
+func (t *Textifier) VisitNestMember(nestMember string) {
+	t.print(textTab, "NESTMEMBER ", nestMember, "\n")
+}
+
+func (t *Textifier) VisitPermittedSubclass(permittedSubclass string) {
+	t.print(textTab, "PERMITTEDSUBCLASS ", permittedSubclass, "\n")
+}
+
+func (t *Textifier) VisitInnerClass(name string, outerName string, innerName string, access uint16) {
+	t.printf("%s// access flags 0x%X\n", textTab, access&^data.ACC_SUPER)
+	t.print(textTab, accessText(access, accessInnerClass), "INNERCLASS ", name, " ", textName(outerName), " ",
+		textName(innerName), "\n")
+}
+
+func (t *Textifier) VisitRecordComponent(name string, descriptor string, signature string) RecordComponentVisitor {
+	t.print("\n")
+	t.printSignature(textTab, signature)
+	t.print(textTab, "RECORDCOMPONENT ", descriptor, " ", name, "\n")
+	return &textFieldVisitor{t: t}
+}
+
+func (t *Textifier) VisitField(access uint16, name string, descriptor string, signature string, value interface{}) FieldVisitor {
+	t.print("\n")
+	t.printf("%s// access flags 0x%X\n", textTab, access)
+	t.printSignature(textTab, signature)
+	t.print(textTab, accessText(access, accessField), descriptor, " ", name)
+	if value != nil {
+		t.print(" = ", constantText(value, t.labelName))
+	}
+	t.print("\n")
+	return &textFieldVisitor{t: t}
+}
+
+func (t *Textifier) VisitMethod(access uint16, name string, descriptor string, signature string, exceptions []string) MethodVisitor {
+	t.labelNames = make(map[*Label]string)
+	t.print("\n")
+	t.printf("%s// access flags 0x%X\n", textTab, access)
+	t.printSignature(textTab, signature)
+	t.print(textTab, accessText(access, accessMethod), name, descriptor)
+	if len(exceptions) > 0 {
+		t.print(" throws ", strings.Join(exceptions, " "))
+	}
+	t.print("\n")
+	return &textMethodVisitor{t: t}
+}
+
+func (t *Textifier) VisitEnd() {
+	t.print("}\n")
+}
+
+func (t *Textifier) printSignature(indent string, signature string) {
+	if len(signature) > 0 {
+		t.print(indent, "// signature ", signature, "\n")
+	}
+}
+
+func (t *Textifier) printAttribute(indent string, attribute Attribute) {
+	t.printf("%sATTRIBUTE %s : %d bytes\n", indent, attribute.Name, len(attribute.Content))
+}
+
+// annotation starts an annotation at the beginning of a line, and returns the visitor of its
+// values that closes it.
+func (t *Textifier) annotation(indent string, descriptor string, visible bool, comment string) AnnotationVisitor {
+	t.print(indent, "@", descriptor, "(")
+	end := ")"
+	switch {
+	case !visible && len(comment) > 0:
+		end += " // invisible, " + comment
+	case !visible:
+		end += " // invisible"
+	case len(comment) > 0:
+		end += " // " + comment
+	}
+	return &textAnnotationVisitor{t: t, end: end + "\n"}
+}
+
+// typeAnnotation starts a type annotation at the beginning of a line, after a prefix, and
+// returns the visitor of its values that closes it with its type reference and path, followed
+// by a suffix.
+func (t *Textifier) typeAnnotation(indent string, prefix string, typeRef int, typePath TypePath, descriptor string, visible bool, suffix string) AnnotationVisitor {
+	t.print(indent, prefix, "@", descriptor, "(")
+	end := ") : " + typeRefText(typeRef) + ", "
+	if len(typePath) > 0 {
+		end += typePath.String()
+	} else {
+		end += "null"
+	}
+	end += suffix
+	if !visible {
+		end += " // invisible"
+	}
+	return &textAnnotationVisitor{t: t, end: end + "\n"}
+}
+
+// labelName returns the name of a label, assigned in the order the labels are first printed.
+func (t *Textifier) labelName(label *Label) string {
+	if label == nil {
+		return "null"
+	}
+	name, ok := t.labelNames[label]
+	if !ok {
+		name = "L" + strconv.Itoa(len(t.labelNames))
+		t.labelNames[label] = name
+	}
+	return name
+}
+
+type textModuleVisitor struct {
+	t *Textifier
+}
+
+func (m *textModuleVisitor) VisitMainClass(mainClass string) {
+	m.t.print(textTab2, "// main class ", mainClass, "\n")
+}
+
+func (m *textModuleVisitor) VisitPackage(packageName string) {
+	m.t.print(textTab2, "// package ", packageName, "\n")
+}
+
+func (m *textModuleVisitor) VisitRequire(moduleName string, access uint16, version string) {
+	m.t.print(textTab2, "requires ", accessText(access, accessModule), moduleName)
+	if len(version) > 0 {
+		m.t.print("; // version ", version)
+	} else {
+		m.t.print(";")
+	}
+	m.t.print("\n")
+}
+
+func (m *textModuleVisitor) VisitExport(packageName string, access uint16, modules []string) {
+	m.printTargets("exports ", packageName, access, " to", modules)
+}
+
+func (m *textModuleVisitor) VisitOpen(packageName string, access uint16, modules []string) {
+	m.printTargets("opens ", packageName, access, " to", modules)
+}
+
+func (m *textModuleVisitor) VisitUse(service string) {
+	m.t.print(textTab2, "uses ", service, ";\n")
+}
+
+func (m *textModuleVisitor) VisitProvide(service string, providers []string) {
+	m.printTargets("provides ", service, 0, " with", providers)
+}
+
+func (m *textModuleVisitor) VisitEnd() {
+	m.t.print(textTab, "}\n")
+}
+
+func (m *textModuleVisitor) printTargets(keyword string, name string, access uint16, separator string, targets []string) {
+	m.t.print(textTab2, keyword, accessText(access, accessModule), name)
+	if len(targets) > 0 {
+		m.t.print(separator, "\n", textTab3, strings.Join(targets, ",\n"+textTab3))
+	}
+	m.t.print(";\n")
+}
+
+// textFieldVisitor prints the annotations and attributes of a field or record component.
+type textFieldVisitor struct {
+	t *Textifier
+}
+
+func (f *textFieldVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return f.t.annotation(textTab, descriptor, visible, "")
+}
+
+func (f *textFieldVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return f.t.typeAnnotation(textTab, "", typeRef, typePath, descriptor, visible, "")
+}
+
+func (f *textFieldVisitor) VisitAttribute(attribute Attribute) {
+	f.t.printAttribute(textTab, attribute)
+}
+
+func (f *textFieldVisitor) VisitEnd() {
+}
+
+type textAnnotationVisitor struct {
+	t     *Textifier
+	count int
+	end   string
+}
+
+func (a *textAnnotationVisitor) name(name string) {
+	if a.count > 0 {
+		a.t.print(", ")
+	}
+	a.count++
+	if len(name) > 0 {
+		a.t.print(name, "=")
+	}
+}
+
+func (a *textAnnotationVisitor) Visit(name string, value interface{}) {
+	a.name(name)
+	a.t.print(annotationValueText(value))
+}
+
+func (a *textAnnotationVisitor) VisitEnum(name string, descriptor string, value string) {
+	a.name(name)
+	a.t.print(descriptor, ".", value)
+}
+
+func (a *textAnnotationVisitor) VisitAnnotation(name string, descriptor string) AnnotationVisitor {
+	a.name(name)
+	a.t.print("@", descriptor, "(")
+	return &textAnnotationVisitor{t: a.t, end: ")"}
+}
+
+func (a *textAnnotationVisitor) VisitArray(name string) AnnotationVisitor {
+	a.name(name)
+	a.t.print("{")
+	return &textAnnotationVisitor{t: a.t, end: "}"}
+}
+
+func (a *textAnnotationVisitor) VisitEnd() {
+	a.t.print(a.end)
+}
+
+type textMethodVisitor struct {
+	t *Textifier
+}
+
+func (m *textMethodVisitor) VisitParameter(name string, access uint16) {
+	m.t.print(textTab2, "// parameter ", accessText(access, accessParameter), textName(name), "\n")
+}
+
+func (m *textMethodVisitor) VisitAnnotationDefault() AnnotationVisitor {
+	m.t.print(textTab2, "default=")
+	return &textAnnotationVisitor{t: m.t, end: "\n"}
+}
+
+func (m *textMethodVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return m.t.annotation(textTab, descriptor, visible, "")
+}
+
+func (m *textMethodVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return m.t.typeAnnotation(textTab, "", typeRef, typePath, descriptor, visible, "")
+}
+
+func (m *textMethodVisitor) VisitAnnotableParameterCount(parameterCount int, visible bool) {
+	kind := "visible"
+	if !visible {
+		kind = "invisible"
+	}
+	m.t.printf("%s// annotable parameter count: %d (%s)\n", textTab2, parameterCount, kind)
+}
+
+func (m *textMethodVisitor) VisitParameterAnnotation(parameterIndex int, descriptor string, visible bool) AnnotationVisitor {
+	return m.t.annotation(textTab2, descriptor, visible, "parameter "+strconv.Itoa(parameterIndex))
+}
+
+func (m *textMethodVisitor) VisitAttribute(attribute Attribute) {
+	m.t.printAttribute(textTab, attribute)
+}
+
+func (m *textMethodVisitor) VisitCode() {
+}
+
+func (m *textMethodVisitor) VisitFrame(frameType int, numLocal int, locals []interface{}, numStack int, stacks []interface{}) {
+	m.t.print(textLTab, "FRAME ")
+	switch frameType {
+	case data.F_NEW, data.F_FULL:
+		if frameType == data.F_NEW {
+			m.t.print("NEW")
+		} else {
+			m.t.print("FULL")
+		}
+		m.t.print(" [", m.frameTypes(locals, numLocal), "] [", m.frameTypes(stacks, numStack), "]")
+	case data.F_APPEND:
+		m.t.print("APPEND [", m.frameTypes(locals, numLocal), "]")
+	case data.F_CHOP:
+		m.t.print("CHOP ", strconv.Itoa(numLocal))
+	case data.F_SAME:
+		m.t.print("SAME")
+	case data.F_SAME1:
+		m.t.print("SAME1 ", m.frameTypes(stacks, 1))
+	}
+	m.t.print("\n")
+}
+
+func (m *textMethodVisitor) frameTypes(types []interface{}, count int) string {
+	if count > len(types) {
+		count = len(types)
+	}
+	texts := make([]string, count)
+	for i, value := range types[:count] {
+		switch v := value.(type) {
+		case string:
+			texts[i] = v
+		case *Label:
+			texts[i] = m.t.labelName(v)
+		case uint8:
+			texts[i] = frameTypeName(int(v))
+		case int:
+			texts[i] = frameTypeName(v)
+		default:
+			texts[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+func frameTypeName(item int) string {
+	if item >= 0 && item < len(frameTypeNames) {
+		return frameTypeNames[item]
+	}
+	return strconv.Itoa(item)
+}
+
+func (m *textMethodVisitor) instruction(opCode uint16, operands ...string) {
+	m.t.print(textTab2, opCodeText(opCode))
+	for _, operand := range operands {
+		m.t.print(" ", operand)
+	}
+	m.t.print("\n")
+}
+
+func (m *textMethodVisitor) VisitInstruction(opCode uint16) {
+	m.instruction(opCode)
+}
+
+func (m *textMethodVisitor) VisitIntInstruction(opCode uint16, operand int32) {
+	if opCode == data.NEWARRAY {
+		if name, ok := arrayTypeNames[operand]; ok {
+			m.instruction(opCode, name)
+			return
+		}
+	}
+	m.instruction(opCode, strconv.Itoa(int(operand)))
+}
+
+func (m *textMethodVisitor) VisitVarInstruction(opCode uint16, variable int) {
+	m.instruction(opCode, strconv.Itoa(variable))
+}
+
+func (m *textMethodVisitor) VisitTypeInstruction(opCode uint16, typeName string) {
+	m.instruction(opCode, typeName)
+}
+
+func (m *textMethodVisitor) VisitFieldInstruction(opCode uint16, owner string, name string, descriptor string) {
+	m.instruction(opCode, owner+"."+name, ":", descriptor)
+}
+
+func (m *textMethodVisitor) VisitMethodInstruction(opCode uint16, owner string, name string, descriptor string, isInterface bool) {
+	if isInterface && opCode != data.INVOKEINTERFACE {
+		m.instruction(opCode, owner+"."+name, descriptor, "(itf)")
+	} else {
+		m.instruction(opCode, owner+"."+name, descriptor)
+	}
+}
+
+func (m *textMethodVisitor) VisitInvokeDynamicInstruction(opCode uint16, name string, descriptor string, bootstrapMethodHandle Handle, bootstrapMethodArguments []interface{}) {
+	t := m.t
+	t.print(textTab2, opCodeText(opCode), " ", name, descriptor, " [\n")
+	t.print(textTab3, handleText(bootstrapMethodHandle), "\n")
+	t.print(textTab3, "// arguments:")
+	if len(bootstrapMethodArguments) == 0 {
+		t.print(" none")
+	}
+	t.print("\n")
+	for i, argument := range bootstrapMethodArguments {
+		t.print(textTab3, constantText(argument, t.labelName))
+		if i < len(bootstrapMethodArguments)-1 {
+			t.print(",")
+		}
+		t.print("\n")
+	}
+	t.print(textTab2, "]\n")
+}
+
+func (m *textMethodVisitor) VisitJumpInstruction(opCode uint16, label *Label) {
+	m.instruction(opCode, m.t.labelName(label))
+}
+
+func (m *textMethodVisitor) VisitLabel(label *Label) {
+	m.t.print(textLTab, m.t.labelName(label), "\n")
+}
+
+func (m *textMethodVisitor) VisitLdcInstruction(value interface{}) {
+	m.instruction(data.LDC, constantText(value, m.t.labelName))
+}
+
+func (m *textMethodVisitor) VisitIincInstruction(variable int, increment int) {
+	m.instruction(data.IINC, strconv.Itoa(variable), strconv.Itoa(increment))
+}
+
+func (m *textMethodVisitor) VisitTableSwitchInstruction(min int32, max int32, dflt *Label, labels []*Label) {
+	m.instruction(data.TABLESWITCH)
+	for i, label := range labels {
+		m.t.printf("%s%d: %s\n", textTab3, min+int32(i), m.t.labelName(label))
+	}
+	m.t.print(textTab3, "default: ", m.t.labelName(dflt), "\n")
+}
+
+func (m *textMethodVisitor) VisitLookupSwitchInstruction(dflt *Label, keys []int32, labels []*Label) {
+	m.instruction(data.LOOKUPSWITCH)
+	for i, label := range labels {
+		m.t.printf("%s%d: %s\n", textTab3, keys[i], m.t.labelName(label))
+	}
+	m.t.print(textTab3, "default: ", m.t.labelName(dflt), "\n")
+}
+
+func (m *textMethodVisitor) VisitMultiANewArrayInstruction(descriptor string, numDimensions int) {
+	m.instruction(data.MULTIANEWARRAY, descriptor, strconv.Itoa(numDimensions))
+}
+
+func (m *textMethodVisitor) VisitInstructionAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return m.t.typeAnnotation(textTab2, "", typeRef, typePath, descriptor, visible, "")
+}
+
+func (m *textMethodVisitor) VisitTryCatchBlock(start *Label, end *Label, handler *Label, typeName string) {
+	m.t.print(textTab2, "TRYCATCHBLOCK ", m.t.labelName(start), " ", m.t.labelName(end), " ",
+		m.t.labelName(handler), " ", textName(typeName), "\n")
+}
+
+func (m *textMethodVisitor) VisitTryCatchAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	return m.t.typeAnnotation(textTab2, "TRYCATCHBLOCK ", typeRef, typePath, descriptor, visible, "")
+}
+
+func (m *textMethodVisitor) VisitLocalVariable(name string, descriptor string, signature string, start *Label, end *Label, index int) {
+	m.t.print(textTab2, "LOCALVARIABLE ", name, " ", descriptor, " ", m.t.labelName(start), " ",
+		m.t.labelName(end), " ", strconv.Itoa(index), "\n")
+	m.t.printSignature(textTab2, signature)
+}
+
+func (m *textMethodVisitor) VisitLocalVariableAnnotation(typeRef int, typePath TypePath, start []*Label, end []*Label, index []int, descriptor string, visible bool) AnnotationVisitor {
+	ranges := " ["
+	for i := range start {
+		ranges += " " + m.t.labelName(start[i]) + " - " + m.t.labelName(end[i]) + " - " + strconv.Itoa(index[i])
+	}
+	return m.t.typeAnnotation(textTab2, "LOCALVARIABLE ", typeRef, typePath, descriptor, visible, ranges+" ]")
+}
+
+func (m *textMethodVisitor) VisitLineNumber(line int, start *Label) {
+	m.t.print(textTab2, "LINENUMBER ", strconv.Itoa(line), " ", m.t.labelName(start), "\n")
+}
+
+func (m *textMethodVisitor) VisitMaxs(maxStack int, maxLocals int) {
+	m.t.print(textTab2, "MAXSTACK = ", strconv.Itoa(maxStack), "\n")
+	m.t.print(textTab2, "MAXLOCALS = ", strconv.Itoa(maxLocals), "\n")
+}
+
+func (m *textMethodVisitor) VisitEnd() {
+}
+
+const (
+	accessClass = iota
+	accessInnerClass
+	accessField
+	accessMethod
+	accessParameter
+	accessModule
+)
+
+// accessText returns the modifiers of an access flags value, each followed by a space.
+func accessText(access uint16, kind int) string {
+	var builder strings.Builder
+	add := func(mask uint16, name string) {
+		if access&mask != 0 {
+			builder.WriteString(name + " ")
+		}
+	}
+	add(data.ACC_PUBLIC, "public")
+	add(data.ACC_PRIVATE, "private")
+	add(data.ACC_PROTECTED, "protected")
+	add(data.ACC_FINAL, "final")
+	add(data.ACC_STATIC, "static")
+	switch kind {
+	case accessMethod:
+		add(data.ACC_SYNCHRONIZED, "synchronized")
+		add(data.ACC_BRIDGE, "bridge")
+		add(data.ACC_VARARGS, "varargs")
+		add(data.ACC_NATIVE, "native")
+	case accessField:
+		add(data.ACC_VOLATILE, "volatile")
+		add(data.ACC_TRANSIENT, "transient")
+	case accessModule:
+		add(data.ACC_TRANSITIVE, "transitive")
+		add(data.ACC_STATIC_PHASE, "static")
+	}
+	if kind != accessParameter && kind != accessModule {
+		add(data.ACC_ABSTRACT, "abstract")
+		add(data.ACC_STRICT, "strictfp")
+	}
+	add(data.ACC_SYNTHETIC, "synthetic")
+	add(data.ACC_MANDATED, "mandated")
+	if kind == accessClass || kind == accessInnerClass || kind == accessField {
+		add(data.ACC_ENUM, "enum")
+	}
+	return builder.String()
+}
+
+func opCodeText(opCode uint16) string {
+	if int(opCode) < len(data.OPCODE_NAMES) {
+		return strings.ToUpper(data.OPCODE_NAMES[opCode])
+	}
+	return strconv.Itoa(int(opCode))
+}
+
+func textName(name string) string {
+	if len(name) == 0 {
+		return "null"
+	}
+	return name
+}
+
+func handleText(handle Handle) string {
+	tag := strconv.Itoa(int(handle.Tag))
+	if int(handle.Tag) < len(handleTagNames) {
+		tag = handleTagNames[handle.Tag]
+	}
+	text := tag + " " + handle.Owner + "." + handle.Name + handle.Descriptor
+	if handle.IsInterface {
+		text += " itf"
+	}
+	return text
+}
+
+// typeRefText returns the text of a type reference, its sort followed by its target information.
+func typeRefText(typeRef int) string {
+	parameter := strconv.Itoa(typeRef >> 16 & 0xff)
+	switch sort := TypeReferenceSort(typeRef); sort {
+	case data.TYPE_REF_CLASS_TYPE_PARAMETER:
+		return "CLASS_TYPE_PARAMETER " + parameter
+	case data.TYPE_REF_METHOD_TYPE_PARAMETER:
+		return "METHOD_TYPE_PARAMETER " + parameter
+	case data.TYPE_REF_CLASS_EXTENDS:
+		return "CLASS_EXTENDS " + strconv.Itoa(int(int16(typeRef>>8)))
+	case data.TYPE_REF_CLASS_TYPE_PARAMETER_BOUND:
+		return "CLASS_TYPE_PARAMETER_BOUND " + parameter + ", " + strconv.Itoa(typeRef>>8&0xff)
+	case data.TYPE_REF_METHOD_TYPE_PARAMETER_BOUND:
+		return "METHOD_TYPE_PARAMETER_BOUND " + parameter + ", " + strconv.Itoa(typeRef>>8&0xff)
+	case data.TYPE_REF_FIELD:
+		return "FIELD"
+	case data.TYPE_REF_METHOD_RETURN:
+		return "METHOD_RETURN"
+	case data.TYPE_REF_METHOD_RECEIVER:
+		return "METHOD_RECEIVER"
+	case data.TYPE_REF_METHOD_FORMAL_PARAMETER:
+		return "METHOD_FORMAL_PARAMETER " + parameter
+	case data.TYPE_REF_THROWS:
+		return "THROWS " + strconv.Itoa(typeRef>>8&0xffff)
+	case data.TYPE_REF_LOCAL_VARIABLE:
+		return "LOCAL_VARIABLE"
+	case data.TYPE_REF_RESOURCE_VARIABLE:
+		return "RESOURCE_VARIABLE"
+	case data.TYPE_REF_EXCEPTION_PARAMETER:
+		return "EXCEPTION_PARAMETER " + strconv.Itoa(typeRef>>8&0xffff)
+	case data.TYPE_REF_INSTANCEOF:
+		return "INSTANCEOF"
+	case data.TYPE_REF_NEW:
+		return "NEW"
+	case data.TYPE_REF_CONSTRUCTOR_REFERENCE:
+		return "CONSTRUCTOR_REFERENCE"
+	case data.TYPE_REF_METHOD_REFERENCE:
+		return "METHOD_REFERENCE"
+	case data.TYPE_REF_CAST:
+		return "CAST " + strconv.Itoa(typeRef&0xff)
+	case data.TYPE_REF_CONSTRUCTOR_INVOCATION_TYPE_ARGUMENT:
+		return "CONSTRUCTOR_INVOCATION_TYPE_ARGUMENT " + strconv.Itoa(typeRef&0xff)
+	case data.TYPE_REF_METHOD_INVOCATION_TYPE_ARGUMENT:
+		return "METHOD_INVOCATION_TYPE_ARGUMENT " + strconv.Itoa(typeRef&0xff)
+	case data.TYPE_REF_CONSTRUCTOR_REFERENCE_TYPE_ARGUMENT:
+		return "CONSTRUCTOR_REFERENCE_TYPE_ARGUMENT " + strconv.Itoa(typeRef&0xff)
+	case data.TYPE_REF_METHOD_REFERENCE_TYPE_ARGUMENT:
+		return "METHOD_REFERENCE_TYPE_ARGUMENT " + strconv.Itoa(typeRef&0xff)
+	default:
+		return fmt.Sprintf("0x%X", sort)
+	}
+}
+
+// constantText returns the text of an ldc, bootstrap method argument or field constant.
+func constantText(value interface{}, labelName func(*Label) string) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case int64:
+		return strconv.FormatInt(v, 10) + "L"
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32) + "F"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64) + "D"
+	case Type:
+		if v.Sort() == data.TYPE_SORT_METHOD {
+			return v.Descriptor()
+		}
+		return v.Descriptor() + ".class"
+	case Handle:
+		return handleText(v)
+	case ConstantDynamic:
+		arguments := make([]string, len(v.BootstrapMethodArguments))
+		for i, argument := range v.BootstrapMethodArguments {
+			arguments[i] = constantText(argument, labelName)
+		}
+		return v.Name + " : " + v.Descriptor + " " + handleText(v.BootstrapMethod) + " [" +
+			strings.Join(arguments, ", ") + "]"
+	case *Label:
+		return labelName(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func annotationValueText(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case int8:
+		return "(byte)" + strconv.Itoa(int(v))
+	case int16:
+		return "(short)" + strconv.Itoa(int(v))
+	case uint16:
+		return "(char)" + strconv.Itoa(int(v))
+	case int32:
+		return strconv.Itoa(int(v))
+	default:
+		return constantText(value, nil)
+	}
+}
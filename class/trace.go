@@ -0,0 +1,554 @@
+package class
+
+import (
+	"io"
+)
+
+// TraceVisitor is a Visitor adapter that records the visited class with a Textifier, and
+// forwards it unchanged to the next visitor, which may be nil. The text is written to the
+// writer, if any, when the class has been visited.
+type TraceVisitor struct {
+	visitor   Visitor
+	textifier *Textifier
+	writer    io.Writer
+}
+
+func NewTraceVisitor(visitor Visitor, writer io.Writer) *TraceVisitor {
+	return &TraceVisitor{visitor: visitor, textifier: NewTextifier(), writer: writer}
+}
+
+// Textifier returns the Textifier recording the events of this visitor.
+func (t *TraceVisitor) Textifier() *Textifier {
+	return t.textifier
+}
+
+func (t *TraceVisitor) Visit(version uint32, access uint16, name string, signature string, superName string, interfaces []string) {
+	t.textifier.Visit(version, access, name, signature, superName, interfaces)
+	if t.visitor != nil {
+		t.visitor.Visit(version, access, name, signature, superName, interfaces)
+	}
+}
+
+func (t *TraceVisitor) VisitSource(source string, debug string) {
+	t.textifier.VisitSource(source, debug)
+	if t.visitor != nil {
+		t.visitor.VisitSource(source, debug)
+	}
+}
+
+func (t *TraceVisitor) VisitModule(name string, access uint16, version string) ModuleVisitor {
+	var next ModuleVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitModule(name, access, version)
+	}
+	return &traceModuleVisitor{visitor: next, text: t.textifier.VisitModule(name, access, version)}
+}
+
+func (t *TraceVisitor) VisitNestHost(nestHost string) {
+	t.textifier.VisitNestHost(nestHost)
+	if t.visitor != nil {
+		t.visitor.VisitNestHost(nestHost)
+	}
+}
+
+func (t *TraceVisitor) VisitOuterClass(owner string, name string, descriptor string) {
+	t.textifier.VisitOuterClass(owner, name, descriptor)
+	if t.visitor != nil {
+		t.visitor.VisitOuterClass(owner, name, descriptor)
+	}
+}
+
+func (t *TraceVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitAnnotation(descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.textifier.VisitAnnotation(descriptor, visible)}
+}
+
+func (t *TraceVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.textifier.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)}
+}
+
+func (t *TraceVisitor) VisitAttribute(attribute Attribute) {
+	t.textifier.VisitAttribute(attribute)
+	if t.visitor != nil {
+		t.visitor.VisitAttribute(attribute)
+	}
+}
+
+func (t *TraceVisitor) VisitNestMember(nestMember string) {
+	t.textifier.VisitNestMember(nestMember)
+	if t.visitor != nil {
+		t.visitor.VisitNestMember(nestMember)
+	}
+}
+
+func (t *TraceVisitor) VisitPermittedSubclass(permittedSubclass string) {
+	t.textifier.VisitPermittedSubclass(permittedSubclass)
+	if t.visitor != nil {
+		t.visitor.VisitPermittedSubclass(permittedSubclass)
+	}
+}
+
+func (t *TraceVisitor) VisitInnerClass(name string, outerName string, innerName string, access uint16) {
+	t.textifier.VisitInnerClass(name, outerName, innerName, access)
+	if t.visitor != nil {
+		t.visitor.VisitInnerClass(name, outerName, innerName, access)
+	}
+}
+
+func (t *TraceVisitor) VisitRecordComponent(name string, descriptor string, signature string) RecordComponentVisitor {
+	var next RecordComponentVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitRecordComponent(name, descriptor, signature)
+	}
+	return &traceRecordComponentVisitor{visitor: next, text: t.textifier.VisitRecordComponent(name, descriptor, signature)}
+}
+
+func (t *TraceVisitor) VisitField(access uint16, name string, descriptor string, signature string, value interface{}) FieldVisitor {
+	var next FieldVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitField(access, name, descriptor, signature, value)
+	}
+	return &traceFieldVisitor{visitor: next, text: t.textifier.VisitField(access, name, descriptor, signature, value)}
+}
+
+func (t *TraceVisitor) VisitMethod(access uint16, name string, descriptor string, signature string, exceptions []string) MethodVisitor {
+	var next MethodVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitMethod(access, name, descriptor, signature, exceptions)
+	}
+	return &traceMethodVisitor{visitor: next, text: t.textifier.VisitMethod(access, name, descriptor, signature, exceptions)}
+}
+
+func (t *TraceVisitor) VisitEnd() {
+	t.textifier.VisitEnd()
+	if t.writer != nil {
+		io.WriteString(t.writer, t.textifier.String())
+	}
+	if t.visitor != nil {
+		t.visitor.VisitEnd()
+	}
+}
+
+// NewTraceMethodVisitor returns a MethodVisitor adapter that records the visited method with a
+// Textifier, forwards it to the next visitor, which may be nil, and writes the text to the
+// writer when the method has been visited.
+func NewTraceMethodVisitor(visitor MethodVisitor, writer io.Writer) MethodVisitor {
+	textifier := NewTextifier()
+	return &traceMethodVisitor{visitor: visitor, text: &textMethodVisitor{t: textifier}, textifier: textifier,
+		writer: writer}
+}
+
+type traceModuleVisitor struct {
+	visitor ModuleVisitor
+	text    ModuleVisitor
+}
+
+func (t *traceModuleVisitor) VisitMainClass(mainClass string) {
+	t.text.VisitMainClass(mainClass)
+	if t.visitor != nil {
+		t.visitor.VisitMainClass(mainClass)
+	}
+}
+
+func (t *traceModuleVisitor) VisitPackage(packageName string) {
+	t.text.VisitPackage(packageName)
+	if t.visitor != nil {
+		t.visitor.VisitPackage(packageName)
+	}
+}
+
+func (t *traceModuleVisitor) VisitRequire(moduleName string, access uint16, version string) {
+	t.text.VisitRequire(moduleName, access, version)
+	if t.visitor != nil {
+		t.visitor.VisitRequire(moduleName, access, version)
+	}
+}
+
+func (t *traceModuleVisitor) VisitExport(packageName string, access uint16, modules []string) {
+	t.text.VisitExport(packageName, access, modules)
+	if t.visitor != nil {
+		t.visitor.VisitExport(packageName, access, modules)
+	}
+}
+
+func (t *traceModuleVisitor) VisitOpen(packageName string, access uint16, modules []string) {
+	t.text.VisitOpen(packageName, access, modules)
+	if t.visitor != nil {
+		t.visitor.VisitOpen(packageName, access, modules)
+	}
+}
+
+func (t *traceModuleVisitor) VisitUse(service string) {
+	t.text.VisitUse(service)
+	if t.visitor != nil {
+		t.visitor.VisitUse(service)
+	}
+}
+
+func (t *traceModuleVisitor) VisitProvide(service string, providers []string) {
+	t.text.VisitProvide(service, providers)
+	if t.visitor != nil {
+		t.visitor.VisitProvide(service, providers)
+	}
+}
+
+func (t *traceModuleVisitor) VisitEnd() {
+	t.text.VisitEnd()
+	if t.visitor != nil {
+		t.visitor.VisitEnd()
+	}
+}
+
+type traceAnnotationVisitor struct {
+	visitor AnnotationVisitor
+	text    AnnotationVisitor
+}
+
+func (t *traceAnnotationVisitor) Visit(name string, value interface{}) {
+	t.text.Visit(name, value)
+	if t.visitor != nil {
+		t.visitor.Visit(name, value)
+	}
+}
+
+func (t *traceAnnotationVisitor) VisitEnum(name string, descriptor string, value string) {
+	t.text.VisitEnum(name, descriptor, value)
+	if t.visitor != nil {
+		t.visitor.VisitEnum(name, descriptor, value)
+	}
+}
+
+func (t *traceAnnotationVisitor) VisitAnnotation(name string, descriptor string) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitAnnotation(name, descriptor)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitAnnotation(name, descriptor)}
+}
+
+func (t *traceAnnotationVisitor) VisitArray(name string) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitArray(name)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitArray(name)}
+}
+
+func (t *traceAnnotationVisitor) VisitEnd() {
+	t.text.VisitEnd()
+	if t.visitor != nil {
+		t.visitor.VisitEnd()
+	}
+}
+
+type traceFieldVisitor struct {
+	visitor FieldVisitor
+	text    FieldVisitor
+}
+
+func (t *traceFieldVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitAnnotation(descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitAnnotation(descriptor, visible)}
+}
+
+func (t *traceFieldVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)}
+}
+
+func (t *traceFieldVisitor) VisitAttribute(attribute Attribute) {
+	t.text.VisitAttribute(attribute)
+	if t.visitor != nil {
+		t.visitor.VisitAttribute(attribute)
+	}
+}
+
+func (t *traceFieldVisitor) VisitEnd() {
+	t.text.VisitEnd()
+	if t.visitor != nil {
+		t.visitor.VisitEnd()
+	}
+}
+
+type traceRecordComponentVisitor struct {
+	visitor RecordComponentVisitor
+	text    RecordComponentVisitor
+}
+
+func (t *traceRecordComponentVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitAnnotation(descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitAnnotation(descriptor, visible)}
+}
+
+func (t *traceRecordComponentVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)}
+}
+
+func (t *traceRecordComponentVisitor) VisitAttribute(attribute Attribute) {
+	t.text.VisitAttribute(attribute)
+	if t.visitor != nil {
+		t.visitor.VisitAttribute(attribute)
+	}
+}
+
+func (t *traceRecordComponentVisitor) VisitEnd() {
+	t.text.VisitEnd()
+	if t.visitor != nil {
+		t.visitor.VisitEnd()
+	}
+}
+
+type traceMethodVisitor struct {
+	visitor MethodVisitor
+	text    MethodVisitor
+	// textifier and writer are only set for the methods traced on their own.
+	textifier *Textifier
+	writer    io.Writer
+}
+
+func (t *traceMethodVisitor) VisitParameter(name string, access uint16) {
+	t.text.VisitParameter(name, access)
+	if t.visitor != nil {
+		t.visitor.VisitParameter(name, access)
+	}
+}
+
+func (t *traceMethodVisitor) VisitAnnotationDefault() AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitAnnotationDefault()
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitAnnotationDefault()}
+}
+
+func (t *traceMethodVisitor) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitAnnotation(descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitAnnotation(descriptor, visible)}
+}
+
+func (t *traceMethodVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitTypeAnnotation(typeRef, typePath, descriptor, visible)}
+}
+
+func (t *traceMethodVisitor) VisitAnnotableParameterCount(parameterCount int, visible bool) {
+	t.text.VisitAnnotableParameterCount(parameterCount, visible)
+	if t.visitor != nil {
+		t.visitor.VisitAnnotableParameterCount(parameterCount, visible)
+	}
+}
+
+func (t *traceMethodVisitor) VisitParameterAnnotation(parameterIndex int, descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitParameterAnnotation(parameterIndex, descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitParameterAnnotation(parameterIndex, descriptor, visible)}
+}
+
+func (t *traceMethodVisitor) VisitAttribute(attribute Attribute) {
+	t.text.VisitAttribute(attribute)
+	if t.visitor != nil {
+		t.visitor.VisitAttribute(attribute)
+	}
+}
+
+func (t *traceMethodVisitor) VisitCode() {
+	t.text.VisitCode()
+	if t.visitor != nil {
+		t.visitor.VisitCode()
+	}
+}
+
+func (t *traceMethodVisitor) VisitFrame(frameType int, numLocal int, locals []interface{}, numStack int, stacks []interface{}) {
+	t.text.VisitFrame(frameType, numLocal, locals, numStack, stacks)
+	if t.visitor != nil {
+		t.visitor.VisitFrame(frameType, numLocal, locals, numStack, stacks)
+	}
+}
+
+func (t *traceMethodVisitor) VisitInstruction(opCode uint16) {
+	t.text.VisitInstruction(opCode)
+	if t.visitor != nil {
+		t.visitor.VisitInstruction(opCode)
+	}
+}
+
+func (t *traceMethodVisitor) VisitIntInstruction(opCode uint16, operand int32) {
+	t.text.VisitIntInstruction(opCode, operand)
+	if t.visitor != nil {
+		t.visitor.VisitIntInstruction(opCode, operand)
+	}
+}
+
+func (t *traceMethodVisitor) VisitVarInstruction(opCode uint16, variable int) {
+	t.text.VisitVarInstruction(opCode, variable)
+	if t.visitor != nil {
+		t.visitor.VisitVarInstruction(opCode, variable)
+	}
+}
+
+func (t *traceMethodVisitor) VisitTypeInstruction(opCode uint16, typeName string) {
+	t.text.VisitTypeInstruction(opCode, typeName)
+	if t.visitor != nil {
+		t.visitor.VisitTypeInstruction(opCode, typeName)
+	}
+}
+
+func (t *traceMethodVisitor) VisitFieldInstruction(opCode uint16, owner string, name string, descriptor string) {
+	t.text.VisitFieldInstruction(opCode, owner, name, descriptor)
+	if t.visitor != nil {
+		t.visitor.VisitFieldInstruction(opCode, owner, name, descriptor)
+	}
+}
+
+func (t *traceMethodVisitor) VisitMethodInstruction(opCode uint16, owner string, name string, descriptor string, isInterface bool) {
+	t.text.VisitMethodInstruction(opCode, owner, name, descriptor, isInterface)
+	if t.visitor != nil {
+		t.visitor.VisitMethodInstruction(opCode, owner, name, descriptor, isInterface)
+	}
+}
+
+func (t *traceMethodVisitor) VisitInvokeDynamicInstruction(opCode uint16, name string, descriptor string, bootstrapMethodHandle Handle, bootstrapMethodArguments []interface{}) {
+	t.text.VisitInvokeDynamicInstruction(opCode, name, descriptor, bootstrapMethodHandle, bootstrapMethodArguments)
+	if t.visitor != nil {
+		t.visitor.VisitInvokeDynamicInstruction(opCode, name, descriptor, bootstrapMethodHandle, bootstrapMethodArguments)
+	}
+}
+
+func (t *traceMethodVisitor) VisitJumpInstruction(opCode uint16, label *Label) {
+	t.text.VisitJumpInstruction(opCode, label)
+	if t.visitor != nil {
+		t.visitor.VisitJumpInstruction(opCode, label)
+	}
+}
+
+func (t *traceMethodVisitor) VisitLabel(label *Label) {
+	t.text.VisitLabel(label)
+	if t.visitor != nil {
+		t.visitor.VisitLabel(label)
+	}
+}
+
+func (t *traceMethodVisitor) VisitLdcInstruction(value interface{}) {
+	t.text.VisitLdcInstruction(value)
+	if t.visitor != nil {
+		t.visitor.VisitLdcInstruction(value)
+	}
+}
+
+func (t *traceMethodVisitor) VisitIincInstruction(variable int, increment int) {
+	t.text.VisitIincInstruction(variable, increment)
+	if t.visitor != nil {
+		t.visitor.VisitIincInstruction(variable, increment)
+	}
+}
+
+func (t *traceMethodVisitor) VisitTableSwitchInstruction(min int32, max int32, dflt *Label, labels []*Label) {
+	t.text.VisitTableSwitchInstruction(min, max, dflt, labels)
+	if t.visitor != nil {
+		t.visitor.VisitTableSwitchInstruction(min, max, dflt, labels)
+	}
+}
+
+func (t *traceMethodVisitor) VisitLookupSwitchInstruction(dflt *Label, keys []int32, labels []*Label) {
+	t.text.VisitLookupSwitchInstruction(dflt, keys, labels)
+	if t.visitor != nil {
+		t.visitor.VisitLookupSwitchInstruction(dflt, keys, labels)
+	}
+}
+
+func (t *traceMethodVisitor) VisitMultiANewArrayInstruction(descriptor string, numDimensions int) {
+	t.text.VisitMultiANewArrayInstruction(descriptor, numDimensions)
+	if t.visitor != nil {
+		t.visitor.VisitMultiANewArrayInstruction(descriptor, numDimensions)
+	}
+}
+
+func (t *traceMethodVisitor) VisitInstructionAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitInstructionAnnotation(typeRef, typePath, descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitInstructionAnnotation(typeRef, typePath, descriptor, visible)}
+}
+
+func (t *traceMethodVisitor) VisitTryCatchBlock(start *Label, end *Label, handler *Label, typeName string) {
+	t.text.VisitTryCatchBlock(start, end, handler, typeName)
+	if t.visitor != nil {
+		t.visitor.VisitTryCatchBlock(start, end, handler, typeName)
+	}
+}
+
+func (t *traceMethodVisitor) VisitTryCatchAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitTryCatchAnnotation(typeRef, typePath, descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitTryCatchAnnotation(typeRef, typePath, descriptor, visible)}
+}
+
+func (t *traceMethodVisitor) VisitLocalVariable(name string, descriptor string, signature string, start *Label, end *Label, index int) {
+	t.text.VisitLocalVariable(name, descriptor, signature, start, end, index)
+	if t.visitor != nil {
+		t.visitor.VisitLocalVariable(name, descriptor, signature, start, end, index)
+	}
+}
+
+func (t *traceMethodVisitor) VisitLocalVariableAnnotation(typeRef int, typePath TypePath, start []*Label, end []*Label, index []int, descriptor string, visible bool) AnnotationVisitor {
+	var next AnnotationVisitor
+	if t.visitor != nil {
+		next = t.visitor.VisitLocalVariableAnnotation(typeRef, typePath, start, end, index, descriptor, visible)
+	}
+	return &traceAnnotationVisitor{visitor: next, text: t.text.VisitLocalVariableAnnotation(typeRef, typePath, start, end, index, descriptor, visible)}
+}
+
+func (t *traceMethodVisitor) VisitLineNumber(line int, start *Label) {
+	t.text.VisitLineNumber(line, start)
+	if t.visitor != nil {
+		t.visitor.VisitLineNumber(line, start)
+	}
+}
+
+func (t *traceMethodVisitor) VisitMaxs(maxStack int, maxLocals int) {
+	t.text.VisitMaxs(maxStack, maxLocals)
+	if t.visitor != nil {
+		t.visitor.VisitMaxs(maxStack, maxLocals)
+	}
+}
+
+func (t *traceMethodVisitor) VisitEnd() {
+	t.text.VisitEnd()
+	if t.textifier != nil && t.writer != nil {
+		io.WriteString(t.writer, t.textifier.String())
+	}
+	if t.visitor != nil {
+		t.visitor.VisitEnd()
+	}
+}
@@ -0,0 +1,193 @@
+package class
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/tools"
+)
+
+const helloTrace = `// class version 52.0 (52)
+// access flags 0x21
+public class com/example/demo/Hello {
+
+  // compiled from: Hello.java
+  // access flags 0x9
+  public static INNERCLASS com/example/demo/Hello$User com/example/demo/Hello User
+
+  // access flags 0x2
+  private I x
+
+  // access flags 0x2
+  private I y
+
+  // access flags 0x1
+  public Ljava/lang/String; name
+
+  // access flags 0x0
+  <init>()V
+   L0
+    LINENUMBER 11 L0
+    ALOAD 0
+    INVOKESPECIAL java/lang/Object.<init> ()V
+   L1
+    LINENUMBER 5 L1
+    ALOAD 0
+    SIPUSH 233
+    PUTFIELD com/example/demo/Hello.x : I
+   L2
+    LINENUMBER 12 L2
+    ALOAD 0
+    ICONST_1
+    PUTFIELD com/example/demo/Hello.y : I
+   L3
+    LINENUMBER 13 L3
+    RETURN
+    MAXSTACK = 2
+    MAXLOCALS = 1
+
+  // access flags 0x1
+  public method1()V
+   L0
+    LINENUMBER 17 L0
+    RETURN
+    MAXSTACK = 0
+    MAXLOCALS = 1
+
+  // access flags 0x1
+  public method2()I
+   L0
+    LINENUMBER 20 L0
+    ICONST_0
+    IRETURN
+    MAXSTACK = 1
+    MAXLOCALS = 1
+
+  // access flags 0x1
+  public method3(I)I
+   L0
+    LINENUMBER 24 L0
+    ILOAD 1
+    ICONST_1
+    IADD
+    IRETURN
+    MAXSTACK = 2
+    MAXLOCALS = 2
+
+  // access flags 0x1
+  public method4()I
+   L0
+    LINENUMBER 28 L0
+    ALOAD 0
+    GETFIELD com/example/demo/Hello.x : I
+    IRETURN
+    MAXSTACK = 1
+    MAXLOCALS = 1
+}
+`
+
+func TestTraceVisitor(t *testing.T) {
+	f, err := os.Open("Hello.class")
+	tools.AssertNoErr(t, err)
+	defer f.Close()
+	reader := NewReader(f)
+	tools.AssertNoErr(t, reader.Read())
+
+	var out bytes.Buffer
+	writer := NewWriter()
+	reader.Class().Accept(NewTraceVisitor(writer, &out))
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, string(writeClass(t, reader.Class())), string(content))
+
+	if text := out.String(); text != helloTrace {
+		t.Errorf("unexpected trace:\n%s", text)
+	}
+}
+
+func TestTextifier(t *testing.T) {
+	textifier := NewTextifier()
+	textifier.Visit(52, data.ACC_PUBLIC|data.ACC_SUPER, "a/A", "", "java/lang/Object", nil)
+	annotation := textifier.VisitAnnotation("La/Ann;", false)
+	annotation.Visit("value", "x")
+	array := annotation.VisitArray("ints")
+	array.Visit("", int32(1))
+	array.Visit("", int32(2))
+	array.VisitEnd()
+	annotation.VisitEnum("e", "La/E;", "ONE")
+	annotation.VisitEnd()
+	textifier.VisitField(data.ACC_PRIVATE|data.ACC_STATIC|data.ACC_FINAL, "F", "J", "", int64(5)).VisitEnd()
+
+	var out bytes.Buffer
+	method := NewTraceMethodVisitor(textifier.VisitMethod(data.ACC_PUBLIC|data.ACC_STATIC, "m", "(I)I", "", nil), &out)
+	method.VisitCode()
+	label, dflt := NewLabel(), NewLabel()
+	method.VisitVarInstruction(data.ILOAD, 0)
+	method.VisitTableSwitchInstruction(0, 0, dflt, []*Label{label})
+	method.VisitLabel(label)
+	method.VisitFrame(data.F_SAME, 0, nil, 0, nil)
+	method.VisitIntInstruction(data.NEWARRAY, data.T_INT)
+	method.VisitInstruction(data.ARRAYLENGTH)
+	method.VisitInstruction(data.IRETURN)
+	method.VisitLabel(dflt)
+	method.VisitFrame(data.F_FULL, 1, []interface{}{data.ITEM_INTEGER}, 0, nil)
+	method.VisitLdcInstruction(int64(3))
+	method.VisitInstruction(data.L2I)
+	method.VisitInstruction(data.IRETURN)
+	method.VisitMaxs(2, 1)
+	method.VisitEnd()
+	textifier.VisitEnd()
+
+	methodText := "    ILOAD 0\n" +
+		"    TABLESWITCH\n" +
+		"      0: L0\n" +
+		"      default: L1\n" +
+		"   L0\n" +
+		"   FRAME SAME\n" +
+		"    NEWARRAY T_INT\n" +
+		"    ARRAYLENGTH\n" +
+		"    IRETURN\n" +
+		"   L1\n" +
+		"   FRAME FULL [INTEGER] []\n" +
+		"    LDC 3L\n" +
+		"    L2I\n" +
+		"    IRETURN\n" +
+		"    MAXSTACK = 2\n" +
+		"    MAXLOCALS = 1\n"
+	tools.AssertEqual(t, methodText, out.String())
+	tools.AssertEqual(t, "// class version 52.0 (52)\n"+
+		"// access flags 0x21\n"+
+		"public class a/A {\n"+
+		"\n"+
+		"  @La/Ann;(value=\"x\", ints={1, 2}, e=La/E;.ONE) // invisible\n"+
+		"\n"+
+		"  // access flags 0x1A\n"+
+		"  private final static J F = 5L\n"+
+		"\n"+
+		"  // access flags 0x9\n"+
+		"  public static m(I)I\n"+
+		methodText+
+		"}\n", textifier.String())
+}
+
+func TestTextifyTypeAnnotations(t *testing.T) {
+	textifier := NewTextifier()
+	visitTypeAnnotations(textifier)
+	text := textifier.String()
+	for _, line := range []string{
+		"  @La/Super;() : CLASS_EXTENDS -1, null\n",
+		"  @La/Element;(value=\"x\") : CLASS_EXTENDS 0, 0; // invisible\n",
+		"  @La/NonNull;() : FIELD, [\n",
+		"  @La/Checked;() : THROWS 0, null // invisible\n",
+		"    TRYCATCHBLOCK @La/Caught;() : EXCEPTION_PARAMETER 0, null\n",
+		"    CHECKCAST java/lang/String\n    @La/NonNull;() : CAST 0, null\n",
+		"    LOCALVARIABLE @La/Local;() : LOCAL_VARIABLE, null [ L0 - L2 - 0 ] // invisible\n",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("%q not found in:\n%s", line, text)
+		}
+	}
+}
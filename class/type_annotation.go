@@ -0,0 +1,101 @@
+package class
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+// The type references of the type annotations are ints, as in ASM. Their sort, one of the
+// data.TYPE_REF_* constants, is stored in the most significant byte, and the target
+// information in the other bytes:
+//   - the type parameter index in the second byte, for the *_TYPE_PARAMETER sorts,
+//   - the type parameter index in the second byte and the bound index in the third byte, for
+//     the *_TYPE_PARAMETER_BOUND sorts,
+//   - the interface index, or -1 for the super class, in the second and third bytes for
+//     CLASS_EXTENDS,
+//   - the parameter index in the second byte for METHOD_FORMAL_PARAMETER,
+//   - the exception index in the second and third bytes for THROWS,
+//   - the try catch block index in the second and third bytes for EXCEPTION_PARAMETER,
+//   - the type argument index in the last byte for CAST and the *_TYPE_ARGUMENT sorts.
+// The other sorts have no target information in the type reference.
+
+// NewTypeReference returns a type reference of a sort without target information.
+func NewTypeReference(sort int) int {
+	return sort << 24
+}
+
+// NewTypeParameterReference returns a CLASS_TYPE_PARAMETER or METHOD_TYPE_PARAMETER type reference.
+func NewTypeParameterReference(sort int, parameterIndex int) int {
+	return sort<<24 | parameterIndex<<16
+}
+
+// NewTypeParameterBoundReference returns a CLASS_TYPE_PARAMETER_BOUND or
+// METHOD_TYPE_PARAMETER_BOUND type reference.
+func NewTypeParameterBoundReference(sort int, parameterIndex int, boundIndex int) int {
+	return sort<<24 | parameterIndex<<16 | boundIndex<<8
+}
+
+// NewSuperTypeReference returns a CLASS_EXTENDS type reference, for the interface of the given
+// index, or for the super class if the index is -1.
+func NewSuperTypeReference(interfaceIndex int) int {
+	return data.TYPE_REF_CLASS_EXTENDS<<24 | (interfaceIndex&0xffff)<<8
+}
+
+// NewFormalParameterReference returns a METHOD_FORMAL_PARAMETER type reference.
+func NewFormalParameterReference(parameterIndex int) int {
+	return data.TYPE_REF_METHOD_FORMAL_PARAMETER<<24 | parameterIndex<<16
+}
+
+// NewExceptionReference returns a THROWS type reference.
+func NewExceptionReference(exceptionIndex int) int {
+	return data.TYPE_REF_THROWS<<24 | exceptionIndex<<8
+}
+
+// NewTryCatchReference returns an EXCEPTION_PARAMETER type reference, for the try catch block of
+// the given index in the exception table of the method.
+func NewTryCatchReference(tryCatchBlockIndex int) int {
+	return data.TYPE_REF_EXCEPTION_PARAMETER<<24 | tryCatchBlockIndex<<8
+}
+
+// NewTypeArgumentReference returns a CAST or *_TYPE_ARGUMENT type reference.
+func NewTypeArgumentReference(sort int, argumentIndex int) int {
+	return sort<<24 | argumentIndex
+}
+
+// TypeReferenceSort returns the sort of a type reference, one of the data.TYPE_REF_* constants.
+func TypeReferenceSort(typeRef int) int {
+	return int(uint32(typeRef) >> 24)
+}
+
+// TypePath is the path to the annotated part of the type designated by a type reference. It is
+// made of the steps from the outermost type, and is empty for the type itself.
+type TypePath []TypePathStep
+
+type TypePathStep struct {
+	// Kind is one of the data.TYPE_PATH_* constants.
+	Kind uint8
+	// TypeArgumentIndex is the index of the type argument of a TYPE_PATH_TYPE_ARGUMENT step.
+	TypeArgumentIndex uint8
+}
+
+// String returns the path in the format of ASM, with '[' for an array element, '.' for an
+// inner type, '*' for a wildcard bound, and the type argument index followed by ';' for a
+// type argument.
+func (p TypePath) String() string {
+	var builder strings.Builder
+	for _, step := range p {
+		switch step.Kind {
+		case data.TYPE_PATH_ARRAY_ELEMENT:
+			builder.WriteByte('[')
+		case data.TYPE_PATH_INNER_TYPE:
+			builder.WriteByte('.')
+		case data.TYPE_PATH_WILDCARD_BOUND:
+			builder.WriteByte('*')
+		default:
+			builder.WriteString(strconv.Itoa(int(step.TypeArgumentIndex)) + ";")
+		}
+	}
+	return builder.String()
+}
@@ -4,7 +4,7 @@ import "fmt"
 
 // A visitor to visit a Java class.
 // The methods of this class must be called in the following order:
-// visit [ visitSource ] [ visitModule ][ visitNestHost ][ visitPermittedSubtype ][ visitOuterClass ] ( visitAnnotation | visitTypeAnnotation | visitAttribute )* ( visitNestMember | visitInnerClass | visitField | visitMethod )* visitEnd.
+// visit [ visitSource ] [ visitModule ][ visitNestHost ][ visitOuterClass ] ( visitAnnotation | visitTypeAnnotation | visitAttribute )* ( visitNestMember | visitPermittedSubclass | visitInnerClass | visitRecordComponent | visitField | visitMethod )* visitEnd.
 type Visitor interface {
 	Visit(version uint32, access uint16, name string, signature string, superName string, interfaces []string)
 	VisitSource(source string, debug string)
@@ -12,13 +12,14 @@ type Visitor interface {
 	VisitNestHost(nestHost string)
 	VisitOuterClass(owner string, name string, descriptor string)
 	VisitAnnotation(descriptor string, visible bool) AnnotationVisitor
-	//VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor
+	VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor
 	VisitAttribute(attribute Attribute)
 	VisitNestMember(nestMember string)
+	VisitPermittedSubclass(permittedSubclass string)
 	VisitInnerClass(name string, outerName string, innerName string, access uint16)
+	VisitRecordComponent(name string, descriptor string, signature string) RecordComponentVisitor
 	VisitField(access uint16, name string, descriptor string, signature string, value interface{}) FieldVisitor
 	VisitMethod(access uint16, name string, descriptor string, signature string, exceptions []string) MethodVisitor
-	//VisitRecordComponentExperimental(access uint16, name string, descriptor string, signature string)
 	VisitEnd()
 }
 
@@ -38,12 +39,12 @@ type ModuleVisitor interface {
 
 // A visitor to visit a Java method.
 // The methods of this class must be called in the following order:
-// ( visitParameter )* [ visitAnnotationDefault ] ( visitAnnotation | visitAnnotableParameterCount | visitParameterAnnotation visitTypeAnnotation | visitAttribute )* [ visitCode ( visitFrame | visit<i>X</i>Insn | visitLabel | visitInsnAnnotation | visitTryCatchBlock | visitTryCatchAnnotation | visitLocalVariable | visitLocalVariableAnnotation | visitLineNumber )* visitMaxs ] visitEnd. In addition, the visit<i>X</i>Insn and visitLabel methods must be called in the sequential order of the bytecode instructions of the visited code, visitInsnAnnotation must be called after the annotated instruction, visitTryCatchBlock must be called before the labels passed as arguments have been visited, visitTryCatchBlockAnnotation must be called after the corresponding try catch block has been visited, and the visitLocalVariable, visitLocalVariableAnnotation and visitLineNumber methods must be called after the labels passed as arguments have been visited.
+// ( visitParameter )* [ visitAnnotationDefault ] ( visitAnnotation | visitAnnotableParameterCount | visitParameterAnnotation | visitTypeAnnotation | visitAttribute )* [ visitCode ( visitFrame | visit<i>X</i>Instruction | visitLabel | visitInstructionAnnotation | visitTryCatchBlock | visitTryCatchAnnotation | visitLocalVariable | visitLocalVariableAnnotation | visitLineNumber )* visitMaxs ] visitEnd. In addition, the visit<i>X</i>Instruction and visitLabel methods must be called in the sequential order of the bytecode instructions of the visited code, visitInstructionAnnotation must be called after the annotated instruction, visitTryCatchBlock must be called before the labels passed as arguments have been visited, visitTryCatchAnnotation must be called after the corresponding try catch block has been visited, and the visitLocalVariable, visitLocalVariableAnnotation and visitLineNumber methods must be called after the labels passed as arguments have been visited.
 type MethodVisitor interface {
 	VisitParameter(name string, access uint16)
 	VisitAnnotationDefault() AnnotationVisitor
 	VisitAnnotation(descriptor string, visible bool) AnnotationVisitor
-	//VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor
+	VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor
 	VisitAnnotableParameterCount(parameterCount int, visible bool)
 	VisitParameterAnnotation(parameterIndex int, descriptor string, visible bool) AnnotationVisitor
 	VisitAttribute(attribute Attribute)
@@ -56,6 +57,20 @@ type MethodVisitor interface {
 	VisitFieldInstruction(opCode uint16, owner string, name string, descriptor string)
 	VisitMethodInstruction(opCode uint16, owner string, name string, descriptor string, isInterface bool)
 	VisitInvokeDynamicInstruction(opCode uint16, name string, descriptor string, bootstrapMethodHandle Handle, bootstrapMethodArguments []interface{})
+	VisitJumpInstruction(opCode uint16, label *Label)
+	VisitLabel(label *Label)
+	VisitLdcInstruction(value interface{})
+	VisitIincInstruction(variable int, increment int)
+	VisitTableSwitchInstruction(min int32, max int32, dflt *Label, labels []*Label)
+	VisitLookupSwitchInstruction(dflt *Label, keys []int32, labels []*Label)
+	VisitMultiANewArrayInstruction(descriptor string, numDimensions int)
+	VisitInstructionAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor
+	VisitTryCatchBlock(start *Label, end *Label, handler *Label, typeName string)
+	VisitTryCatchAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor
+	VisitLocalVariable(name string, descriptor string, signature string, start *Label, end *Label, index int)
+	VisitLocalVariableAnnotation(typeRef int, typePath TypePath, start []*Label, end []*Label, index []int, descriptor string, visible bool) AnnotationVisitor
+	VisitLineNumber(line int, start *Label)
+	VisitMaxs(maxStack int, maxLocals int)
 	VisitEnd()
 }
 
@@ -64,7 +79,17 @@ type MethodVisitor interface {
 // ( visitAnnotation | visitTypeAnnotation | visitAttribute )* visitEnd.
 type FieldVisitor interface {
 	VisitAnnotation(descriptor string, visible bool) AnnotationVisitor
-	//VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor
+	VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor
+	VisitAttribute(attribute Attribute)
+	VisitEnd()
+}
+
+// A visitor to visit a record component.
+// The methods of this class must be called in the following order:
+// ( visitAnnotation | visitTypeAnnotation | visitAttribute )* visitEnd.
+type RecordComponentVisitor interface {
+	VisitAnnotation(descriptor string, visible bool) AnnotationVisitor
+	VisitTypeAnnotation(typeRef int, typePath TypePath, descriptor string, visible bool) AnnotationVisitor
 	VisitAttribute(attribute Attribute)
 	VisitEnd()
 }
@@ -85,9 +110,9 @@ type PrintVisitor struct {
 
 func (p PrintVisitor) Visit(version uint32, access uint16, name string, signature string, superName string, interfaces []string) {
 	fmt.Printf("class %s \n", name)
-	fmt.Printf("\tminor verison: %d \n", version>>16)
-	fmt.Printf("\tmajor verison: %d \n", version&0x00ff)
-	fmt.Printf("\tflags: %b \n", access)
+	fmt.Printf("\tminor version: %d \n", version>>16)
+	fmt.Printf("\tmajor version: %d \n", version&0xffff)
+	fmt.Printf("\tflags: (0x%04x) \n", access)
 	fmt.Printf("\tsuper: %s \n", superName)
 	fmt.Printf("\tinterfaces: %s \n", interfaces)
 
@@ -125,10 +150,18 @@ func (p PrintVisitor) VisitTypeAnnotation(typeRef int, typePath TypePath, descri
 func (p PrintVisitor) VisitNestMember(nestMember string) {
 	fmt.Printf("NestMember: %v\n", nestMember)
 }
+func (p PrintVisitor) VisitPermittedSubclass(permittedSubclass string) {
+	fmt.Printf("PermittedSubclass: %v\n", permittedSubclass)
+}
 func (p PrintVisitor) VisitAttribute(attribute Attribute) {
 	fmt.Printf("Attribute: %v\n", attribute)
 }
 
+func (p PrintVisitor) VisitRecordComponent(name string, descriptor string, signature string) RecordComponentVisitor {
+	fmt.Printf("RecordComponent: %v\n", name)
+	return nil
+}
+
 func (p PrintVisitor) VisitField(access uint16, name string, descriptor string, signature string, value interface{}) FieldVisitor {
 	fmt.Printf("Field: %v\n", name)
 	return nil
@@ -1,5 +1,1037 @@
 package class
 
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+// The flags of a Writer, to compute values of the methods instead of writing them as visited.
+const (
+	// COMPUTE_MAXS computes the max stack and max locals values of the methods.
+	COMPUTE_MAXS = 1 << iota
+	// COMPUTE_FRAMES computes the stack map frames of the methods, and their max stack and max
+	// locals values. The visited frames are ignored.
+	COMPUTE_FRAMES
+)
+
+// Writer is a Visitor that generates the bytes of the visited class. The class data is
+// encoded when VisitEnd is called, and can then be retrieved with Data, Bytes or Write.
+// The stack map frames and the max stack and max locals values of the methods are written
+// as visited, unless Flags asks to compute them.
 type Writer struct {
-	class *Class
+	Builder
+	// Flags is a combination of the COMPUTE_* flags.
+	Flags int
+	// CommonSuperClass returns the internal name of the common super class of two classes. It is
+	// used to merge types when computing frames, and defaults to java/lang/Object when nil.
+	CommonSuperClass func(type1 string, type2 string) string
+	data             *data.ClassData
+	err              error
+}
+
+func NewWriter() *Writer {
+	return &Writer{Builder: Builder{class: &Class{}}}
+}
+
+func (w *Writer) VisitEnd() {
+	if w.Flags&(COMPUTE_MAXS|COMPUTE_FRAMES) != 0 {
+		for i := range w.class.Methods {
+			var err error
+			if w.Flags&COMPUTE_FRAMES != 0 {
+				err = ComputeFrames(w.class.ThisClass, &w.class.Methods[i], w.CommonSuperClass)
+			} else {
+				err = ComputeMaxs(w.class.ThisClass, &w.class.Methods[i])
+			}
+			if err != nil {
+				w.err = err
+				return
+			}
+		}
+	}
+	w.data, w.err = Encode(w.class)
+}
+
+// Data returns the class data of the visited class.
+func (w *Writer) Data() (*data.ClassData, error) {
+	if w.data == nil && w.err == nil {
+		return nil, errors.New("class not visited")
+	}
+	return w.data, w.err
+}
+
+// Bytes returns the bytes of the visited class.
+func (w *Writer) Bytes() ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := w.Write(&buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Write writes the bytes of the visited class to the given writer.
+func (w *Writer) Write(writer io.Writer) error {
+	classData, err := w.Data()
+	if err != nil {
+		return err
+	}
+	classData.Accept(data.NewWriter(writer))
+	return nil
+}
+
+// Encode returns the class data of the given class.
+func Encode(class *Class) (classData *data.ClassData, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("cannot encode class %s: %v", class.ThisClass, e)
+		}
+	}()
+	encoder := &classEncoder{class: class, symbols: newSymbolTable()}
+	return encoder.encode(), nil
+}
+
+type classEncoder struct {
+	class   *Class
+	symbols *symbolTable
+}
+
+func (e *classEncoder) encode() *data.ClassData {
+	class := e.class
+	symbols := e.symbols
+	classData := &data.ClassData{MagicNumber: data.MAGIC_NUMBER}
+	classData.MinorVersion = uint16(class.Version >> 16)
+	classData.MajorVersion = uint16(class.Version)
+	classData.AccessFlags = class.AccessFlags
+	classData.ThisClass = symbols.addClass(class.ThisClass)
+	classData.SuperClass = symbols.addClass(class.SuperClass)
+	classData.Interfaces = make([]data.InterfaceData, len(class.Interfaces))
+	for i, name := range class.Interfaces {
+		classData.Interfaces[i] = data.InterfaceData{Index: symbols.addClass(name)}
+	}
+	classData.InterfacesCount = uint16(len(classData.Interfaces))
+
+	classData.Fields = make([]data.FieldData, len(class.Fields))
+	for i, field := range class.Fields {
+		classData.Fields[i] = e.encodeField(field)
+	}
+	classData.FieldsCount = uint16(len(classData.Fields))
+	classData.Methods = make([]data.MethodData, len(class.Methods))
+	for i, method := range class.Methods {
+		classData.Methods[i] = e.encodeMethod(method)
+	}
+	classData.MethodsCount = uint16(len(classData.Methods))
+	classData.Attributes = e.encodeClassAttributes()
+	classData.AttributesCount = uint16(len(classData.Attributes))
+
+	classData.ConstantPool = symbols.constants
+	classData.ConstantCount = uint16(len(symbols.constants))
+	return classData
+}
+
+func (e *classEncoder) attribute(name string, value []byte) data.AttributeData {
+	return data.AttributeData{NameIndex: e.symbols.addUTF8(name), Length: uint32(len(value)), Value: value}
+}
+
+func (e *classEncoder) indexAttribute(name string, index uint16) data.AttributeData {
+	vector := &byteVector{}
+	vector.putUint16(index)
+	return e.attribute(name, vector.bytes)
+}
+
+func (e *classEncoder) classesAttribute(name string, classes []string) data.AttributeData {
+	vector := &byteVector{}
+	vector.putUint16(uint16(len(classes)))
+	for _, class := range classes {
+		vector.putUint16(e.symbols.addClass(class))
+	}
+	return e.attribute(name, vector.bytes)
+}
+
+func (e *classEncoder) encodeClassAttributes() []data.AttributeData {
+	class := e.class
+	symbols := e.symbols
+	attributes := make([]data.AttributeData, 0)
+	if len(class.SourceFile) > 0 {
+		attributes = append(attributes, e.indexAttribute(data.SOURCE_FILE, symbols.addUTF8(class.SourceFile)))
+	}
+	if len(class.SourceDebugExtension) > 0 {
+		attributes = append(attributes, e.attribute(data.SOURCE_DEBUG_EXTENSION, []byte(class.SourceDebugExtension)))
+	}
+	if len(class.Signature) > 0 {
+		attributes = append(attributes, e.indexAttribute(data.SIGNATURE, symbols.addUTF8(class.Signature)))
+	}
+	if len(class.OuterClass.ClassName) > 0 {
+		vector := &byteVector{}
+		vector.putUint16(symbols.addClass(class.OuterClass.ClassName))
+		if len(class.OuterClass.MethodName) > 0 {
+			vector.putUint16(symbols.addNameAndType(class.OuterClass.MethodName, class.OuterClass.Descriptor))
+		} else {
+			vector.putUint16(0)
+		}
+		attributes = append(attributes, e.attribute(data.ENCLOSING_METHOD, vector.bytes))
+	}
+	if len(class.NestHost) > 0 {
+		attributes = append(attributes, e.indexAttribute(data.NEST_HOST, symbols.addClass(class.NestHost)))
+	}
+	if len(class.NestMembers) > 0 {
+		attributes = append(attributes, e.classesAttribute(data.NEST_MEMBERS, class.NestMembers))
+	}
+	if len(class.PermittedSubclasses) > 0 {
+		attributes = append(attributes, e.classesAttribute(data.PERMITTED_SUBCLASSES, class.PermittedSubclasses))
+	}
+	if class.RecordComponents != nil {
+		attributes = append(attributes, e.encodeRecord(class.RecordComponents))
+	}
+	if len(class.InnerClasses) > 0 {
+		vector := &byteVector{}
+		vector.putUint16(uint16(len(class.InnerClasses)))
+		for _, innerClass := range class.InnerClasses {
+			vector.putUint16(symbols.addClass(innerClass.Name))
+			vector.putUint16(symbols.addClass(innerClass.OuterName))
+			if len(innerClass.InnerName) > 0 {
+				vector.putUint16(symbols.addUTF8(innerClass.InnerName))
+			} else {
+				vector.putUint16(0)
+			}
+			vector.putUint16(innerClass.AccessFlags)
+		}
+		attributes = append(attributes, e.attribute(data.INNER_CLASSES, vector.bytes))
+	}
+	if len(class.Module.Name) > 0 {
+		attributes = append(attributes, e.encodeModule(class.Module)...)
+	}
+	attributes = append(attributes, e.encodeAnnotations(class.RuntimeVisibleAnnotations, class.RuntimeInvisibleAnnotations)...)
+	attributes = append(attributes, e.encodeTypeAnnotations(class.RuntimeVisibleTypeAnnotations, class.RuntimeInvisibleTypeAnnotations)...)
+	if class.Deprecated {
+		attributes = append(attributes, e.attribute(data.DEPRECATED, nil))
+	}
+	for _, attribute := range class.Attributes {
+		attributes = append(attributes, e.attribute(attribute.Name, attribute.Content))
+	}
+	// The bootstrap methods are written last, since they can be added by any other attribute.
+	if len(symbols.bootstrapMethods) > 0 {
+		vector := &byteVector{}
+		vector.putUint16(uint16(len(symbols.bootstrapMethods)))
+		for _, bootstrapMethod := range symbols.bootstrapMethods {
+			vector.putUint16(bootstrapMethod[0])
+			vector.putUint16(uint16(len(bootstrapMethod) - 1))
+			for _, argument := range bootstrapMethod[1:] {
+				vector.putUint16(argument)
+			}
+		}
+		attributes = append(attributes, e.attribute(data.BOOTSTRAP_METHODS, vector.bytes))
+	}
+	return attributes
+}
+
+func (e *classEncoder) encodeRecord(components []RecordComponent) data.AttributeData {
+	symbols := e.symbols
+	vector := &byteVector{}
+	vector.putUint16(uint16(len(components)))
+	for _, component := range components {
+		vector.putUint16(symbols.addUTF8(component.Name))
+		vector.putUint16(symbols.addUTF8(component.Descriptor))
+		attributes := make([]data.AttributeData, 0)
+		if len(component.Signature) > 0 {
+			attributes = append(attributes, e.indexAttribute(data.SIGNATURE, symbols.addUTF8(component.Signature)))
+		}
+		attributes = append(attributes, e.encodeAnnotations(component.RuntimeVisibleAnnotations, component.RuntimeInvisibleAnnotations)...)
+		attributes = append(attributes, e.encodeTypeAnnotations(component.RuntimeVisibleTypeAnnotations, component.RuntimeInvisibleTypeAnnotations)...)
+		for _, attribute := range component.Attributes {
+			attributes = append(attributes, e.attribute(attribute.Name, attribute.Content))
+		}
+		putAttributes(vector, attributes)
+	}
+	return e.attribute(data.RECORD, vector.bytes)
+}
+
+func (e *classEncoder) encodeModule(module Module) []data.AttributeData {
+	symbols := e.symbols
+	vector := &byteVector{}
+	vector.putUint16(symbols.addModule(module.Name))
+	vector.putUint16(module.AccessFlags)
+	vector.putUint16(e.optionalUTF8(module.Version))
+	vector.putUint16(uint16(len(module.Requires)))
+	for _, require := range module.Requires {
+		vector.putUint16(symbols.addModule(require.Name))
+		vector.putUint16(require.AccessFlags)
+		vector.putUint16(e.optionalUTF8(require.Version))
+	}
+	putPackages := func(name string, access uint16, modules []string) {
+		vector.putUint16(symbols.addPackage(name))
+		vector.putUint16(access)
+		vector.putUint16(uint16(len(modules)))
+		for _, module := range modules {
+			vector.putUint16(symbols.addModule(module))
+		}
+	}
+	vector.putUint16(uint16(len(module.Exports)))
+	for _, export := range module.Exports {
+		putPackages(export.Name, export.AccessFlags, export.Modules)
+	}
+	vector.putUint16(uint16(len(module.Opens)))
+	for _, open := range module.Opens {
+		putPackages(open.Name, open.AccessFlags, open.Modules)
+	}
+	vector.putUint16(uint16(len(module.Uses)))
+	for _, use := range module.Uses {
+		vector.putUint16(symbols.addClass(use))
+	}
+	vector.putUint16(uint16(len(module.Provides)))
+	for _, provide := range module.Provides {
+		vector.putUint16(symbols.addClass(provide.Service))
+		vector.putUint16(uint16(len(provide.Provides)))
+		for _, provider := range provide.Provides {
+			vector.putUint16(symbols.addClass(provider))
+		}
+	}
+	attributes := []data.AttributeData{e.attribute(data.MODULE, vector.bytes)}
+	if len(module.Packages) > 0 {
+		packages := &byteVector{}
+		packages.putUint16(uint16(len(module.Packages)))
+		for _, pkg := range module.Packages {
+			packages.putUint16(symbols.addPackage(pkg))
+		}
+		attributes = append(attributes, e.attribute(data.MODULE_PACKAGES, packages.bytes))
+	}
+	if len(module.MainClass) > 0 {
+		attributes = append(attributes, e.indexAttribute(data.MODULE_MAIN_CLASS, symbols.addClass(module.MainClass)))
+	}
+	return attributes
+}
+
+func (e *classEncoder) optionalUTF8(value string) uint16 {
+	if len(value) == 0 {
+		return 0
+	}
+	return e.symbols.addUTF8(value)
+}
+
+func (e *classEncoder) encodeField(field Field) data.FieldData {
+	symbols := e.symbols
+	fieldData := data.FieldData{AccessFlags: field.AccessFlags}
+	fieldData.NameIndex = symbols.addUTF8(field.Name)
+	fieldData.DescriptorIndex = symbols.addUTF8(field.Descriptor)
+	attributes := make([]data.AttributeData, 0)
+	if field.ConstantValue != nil {
+		attributes = append(attributes, e.indexAttribute(data.CONSTANT_VALUE, symbols.addConstant(field.ConstantValue)))
+	}
+	if len(field.Signature) > 0 {
+		attributes = append(attributes, e.indexAttribute(data.SIGNATURE, symbols.addUTF8(field.Signature)))
+	}
+	attributes = append(attributes, e.encodeAnnotations(field.RuntimeVisibleAnnotations, field.RuntimeInvisibleAnnotations)...)
+	attributes = append(attributes, e.encodeTypeAnnotations(field.RuntimeVisibleTypeAnnotations, field.RuntimeInvisibleTypeAnnotations)...)
+	if field.Deprecated {
+		attributes = append(attributes, e.attribute(data.DEPRECATED, nil))
+	}
+	for _, attribute := range field.Attributes {
+		attributes = append(attributes, e.attribute(attribute.Name, attribute.Content))
+	}
+	fieldData.Attributes = attributes
+	fieldData.AttributesCount = uint16(len(attributes))
+	return fieldData
+}
+
+func (e *classEncoder) encodeMethod(method Method) data.MethodData {
+	symbols := e.symbols
+	methodData := data.MethodData{AccessFlags: method.AccessFlags}
+	methodData.NameIndex = symbols.addUTF8(method.Name)
+	methodData.DescriptorIndex = symbols.addUTF8(method.Descriptor)
+	attributes := make([]data.AttributeData, 0)
+	if len(method.Code.Instructions) > 0 {
+		attributes = append(attributes, e.attribute(data.CODE, e.encodeCode(&method)))
+	}
+	if len(method.Exceptions) > 0 {
+		attributes = append(attributes, e.classesAttribute(data.EXCEPTIONS, method.Exceptions))
+	}
+	if len(method.Signature) > 0 {
+		attributes = append(attributes, e.indexAttribute(data.SIGNATURE, symbols.addUTF8(method.Signature)))
+	}
+	if method.AnnotationDefault != nil {
+		vector := &byteVector{}
+		e.putElementValue(vector, method.AnnotationDefault)
+		attributes = append(attributes, e.attribute(data.ANNOTATION_DEFAULT, vector.bytes))
+	}
+	attributes = append(attributes, e.encodeAnnotations(method.RuntimeVisibleAnnotations, method.RuntimeInvisibleAnnotations)...)
+	attributes = append(attributes, e.encodeTypeAnnotations(method.RuntimeVisibleTypeAnnotations, method.RuntimeInvisibleTypeAnnotations)...)
+	if len(method.RuntimeVisibleParameterAnnotations) > 0 {
+		attributes = append(attributes, e.encodeParameterAnnotations(data.RUNTIME_VISIBLE_PARAMETER_ANNOTATIONS, method.RuntimeVisibleParameterAnnotations))
+	}
+	if len(method.RuntimeInvisibleParameterAnnotations) > 0 {
+		attributes = append(attributes, e.encodeParameterAnnotations(data.RUNTIME_INVISIBLE_PARAMETER_ANNOTATIONS, method.RuntimeInvisibleParameterAnnotations))
+	}
+	if len(method.Parameters) > 0 {
+		vector := &byteVector{}
+		vector.putUint8(uint8(len(method.Parameters)))
+		for _, parameter := range method.Parameters {
+			vector.putUint16(e.optionalUTF8(parameter.ParameterName))
+			vector.putUint16(parameter.AccessFlags)
+		}
+		attributes = append(attributes, e.attribute(data.METHOD_PARAMETERS, vector.bytes))
+	}
+	if method.Deprecated {
+		attributes = append(attributes, e.attribute(data.DEPRECATED, nil))
+	}
+	for _, attribute := range method.Attributes {
+		attributes = append(attributes, e.attribute(attribute.Name, attribute.Content))
+	}
+	methodData.Attributes = attributes
+	methodData.AttributesCount = uint16(len(attributes))
+	return methodData
+}
+
+func (e *classEncoder) encodeAnnotations(visibleAnnotations []Annotation, invisibleAnnotations []Annotation) []data.AttributeData {
+	attributes := make([]data.AttributeData, 0)
+	if len(visibleAnnotations) > 0 {
+		vector := &byteVector{}
+		e.putAnnotations(vector, visibleAnnotations)
+		attributes = append(attributes, e.attribute(data.RUNTIME_VISIBLE_ANNOTATIONS, vector.bytes))
+	}
+	if len(invisibleAnnotations) > 0 {
+		vector := &byteVector{}
+		e.putAnnotations(vector, invisibleAnnotations)
+		attributes = append(attributes, e.attribute(data.RUNTIME_INVISIBLE_ANNOTATIONS, vector.bytes))
+	}
+	return attributes
+}
+
+func (e *classEncoder) encodeTypeAnnotations(visibleAnnotations []TypeAnnotation, invisibleAnnotations []TypeAnnotation) []data.AttributeData {
+	attributes := make([]data.AttributeData, 0)
+	if len(visibleAnnotations) > 0 {
+		attributes = append(attributes, e.typeAnnotationsAttribute(data.RUNTIME_VISIBLE_TYPE_ANNOTATIONS, visibleAnnotations))
+	}
+	if len(invisibleAnnotations) > 0 {
+		attributes = append(attributes, e.typeAnnotationsAttribute(data.RUNTIME_INVISIBLE_TYPE_ANNOTATIONS, invisibleAnnotations))
+	}
+	return attributes
+}
+
+func (e *classEncoder) typeAnnotationsAttribute(name string, annotations []TypeAnnotation) data.AttributeData {
+	vector := &byteVector{}
+	vector.putUint16(uint16(len(annotations)))
+	for _, annotation := range annotations {
+		e.putTypeAnnotation(vector, CodeTypeAnnotation{TypeAnnotation: annotation}, nil)
+	}
+	return e.attribute(name, vector.bytes)
+}
+
+func (e *classEncoder) codeTypeAnnotationsAttribute(name string, annotations []CodeTypeAnnotation, offsets map[Instruction]int) data.AttributeData {
+	vector := &byteVector{}
+	vector.putUint16(uint16(len(annotations)))
+	for _, annotation := range annotations {
+		e.putTypeAnnotation(vector, annotation, offsets)
+	}
+	return e.attribute(name, vector.bytes)
+}
+
+// putTypeAnnotation writes a type annotation. The offsets of the instructions are only given
+// for the annotations of the code, which are the only ones whose sort is TYPE_REF_LOCAL_VARIABLE
+// or above.
+func (e *classEncoder) putTypeAnnotation(vector *byteVector, annotation CodeTypeAnnotation, offsets map[Instruction]int) {
+	typeRef := annotation.TypeRef
+	sort := TypeReferenceSort(typeRef)
+	if (sort >= data.TYPE_REF_LOCAL_VARIABLE) != (offsets != nil) {
+		panic(fmt.Sprintf("type reference sort 0x%X not allowed here", sort))
+	}
+	instructionOffset := func() uint16 {
+		offset, ok := offsets[annotation.Instruction]
+		if !ok {
+			panic("annotated instruction not found")
+		}
+		return uint16(offset)
+	}
+	vector.putUint8(uint8(sort))
+	switch sort {
+	case data.TYPE_REF_CLASS_TYPE_PARAMETER, data.TYPE_REF_METHOD_TYPE_PARAMETER, data.TYPE_REF_METHOD_FORMAL_PARAMETER:
+		vector.putUint8(uint8(typeRef >> 16))
+	case data.TYPE_REF_CLASS_EXTENDS, data.TYPE_REF_THROWS, data.TYPE_REF_EXCEPTION_PARAMETER:
+		vector.putUint16(uint16(typeRef >> 8))
+	case data.TYPE_REF_CLASS_TYPE_PARAMETER_BOUND, data.TYPE_REF_METHOD_TYPE_PARAMETER_BOUND:
+		vector.putUint8(uint8(typeRef >> 16))
+		vector.putUint8(uint8(typeRef >> 8))
+	case data.TYPE_REF_FIELD, data.TYPE_REF_METHOD_RETURN, data.TYPE_REF_METHOD_RECEIVER:
+	case data.TYPE_REF_LOCAL_VARIABLE, data.TYPE_REF_RESOURCE_VARIABLE:
+		if len(annotation.End) != len(annotation.Start) || len(annotation.Index) != len(annotation.Start) {
+			panic("invalid local variable annotation ranges")
+		}
+		vector.putUint16(uint16(len(annotation.Start)))
+		for i, start := range annotation.Start {
+			vector.putUint16(uint16(labelOffset(start)))
+			vector.putUint16(uint16(labelOffset(annotation.End[i]) - labelOffset(start)))
+			vector.putUint16(uint16(annotation.Index[i]))
+		}
+	case data.TYPE_REF_INSTANCEOF, data.TYPE_REF_NEW, data.TYPE_REF_CONSTRUCTOR_REFERENCE, data.TYPE_REF_METHOD_REFERENCE:
+		vector.putUint16(instructionOffset())
+	case data.TYPE_REF_CAST, data.TYPE_REF_CONSTRUCTOR_INVOCATION_TYPE_ARGUMENT, data.TYPE_REF_METHOD_INVOCATION_TYPE_ARGUMENT,
+		data.TYPE_REF_CONSTRUCTOR_REFERENCE_TYPE_ARGUMENT, data.TYPE_REF_METHOD_REFERENCE_TYPE_ARGUMENT:
+		vector.putUint16(instructionOffset())
+		vector.putUint8(uint8(typeRef))
+	default:
+		panic(fmt.Sprintf("invalid type reference sort 0x%X", sort))
+	}
+	vector.putUint8(uint8(len(annotation.TypePath)))
+	for _, step := range annotation.TypePath {
+		vector.putUint8(step.Kind)
+		vector.putUint8(step.TypeArgumentIndex)
+	}
+	e.putAnnotation(vector, annotation.Annotation)
+}
+
+func (e *classEncoder) encodeParameterAnnotations(name string, parameters []ParameterAnnotation) data.AttributeData {
+	vector := &byteVector{}
+	vector.putUint8(uint8(len(parameters)))
+	for _, parameter := range parameters {
+		e.putAnnotations(vector, parameter.Annotations)
+	}
+	return e.attribute(name, vector.bytes)
+}
+
+func (e *classEncoder) putAnnotations(vector *byteVector, annotations []Annotation) {
+	vector.putUint16(uint16(len(annotations)))
+	for _, annotation := range annotations {
+		e.putAnnotation(vector, annotation)
+	}
+}
+
+func (e *classEncoder) putAnnotation(vector *byteVector, annotation Annotation) {
+	vector.putUint16(e.symbols.addUTF8(annotation.Descriptor))
+	vector.putUint16(uint16(len(annotation.ElementPairs)))
+	for _, pair := range annotation.ElementPairs {
+		vector.putUint16(e.symbols.addUTF8(pair.Name))
+		e.putElementValue(vector, pair.Value)
+	}
+}
+
+func (e *classEncoder) putElementValue(vector *byteVector, value ElementValue) {
+	symbols := e.symbols
+	vector.putUint8(value.Tag())
+	switch v := value.(type) {
+	case ElementBooleanValue:
+		vector.putUint16(symbols.addConstant(v.Value))
+	case ElementByteValue:
+		vector.putUint16(symbols.addConstant(v.Value))
+	case ElementCharValue:
+		vector.putUint16(symbols.addConstant(v.Value))
+	case ElementShortValue:
+		vector.putUint16(symbols.addConstant(v.Value))
+	case ElementIntegerValue:
+		vector.putUint16(symbols.addInteger(v.Value))
+	case ElementLongValue:
+		vector.putUint16(symbols.addLong(v.Value))
+	case ElementFloatValue:
+		vector.putUint16(symbols.addFloat(v.Value))
+	case ElementDoubleValue:
+		vector.putUint16(symbols.addDouble(v.Value))
+	case ElementStringValue:
+		vector.putUint16(symbols.addUTF8(v.Value))
+	case ElementEnumValue:
+		vector.putUint16(symbols.addUTF8(v.TypeName))
+		vector.putUint16(symbols.addUTF8(v.ConstName))
+	case ElementClassValue:
+		if t, ok := v.Value.(Type); ok {
+			vector.putUint16(symbols.addUTF8(t.Descriptor()))
+		} else {
+			vector.putUint16(symbols.addUTF8(fmt.Sprint(v.Value)))
+		}
+	case ElementAnnotationValue:
+		e.putAnnotation(vector, v.Value)
+	case ElementArrayValue:
+		vector.putUint16(uint16(len(v.Values)))
+		for _, elementValue := range v.Values {
+			e.putElementValue(vector, elementValue)
+		}
+	}
+}
+
+func (e *classEncoder) encodeCode(method *Method) []byte {
+	code := method.Code
+	encoder := &codeEncoder{symbols: e.symbols}
+	instructions := code.Instructions
+	if encoder.resizeJumps(instructions) {
+		instructions = longJumpFrames(e.class.ThisClass, method, instructions, encoder.longJumps)
+	}
+	bytecode := encoder.encode(instructions)
+
+	vector := &byteVector{}
+	vector.putUint16(code.MaxStack)
+	vector.putUint16(code.MaxLocal)
+	vector.putUint32(uint32(len(bytecode)))
+	vector.putBytes(bytecode)
+	vector.putUint16(uint16(len(code.ExceptionTable)))
+	for _, exception := range code.ExceptionTable {
+		vector.putUint16(uint16(labelOffset(exception.Start)))
+		vector.putUint16(uint16(labelOffset(exception.End)))
+		vector.putUint16(uint16(labelOffset(exception.Handler)))
+		vector.putUint16(e.symbols.addClass(exception.CatchType))
+	}
+
+	attributes := make([]data.AttributeData, 0)
+	if len(encoder.frames) > 0 {
+		attributes = append(attributes, e.attribute(data.STACK_MAP_TABLE, encoder.encodeFrames()))
+	}
+	if len(encoder.lineNumbers) > 0 {
+		lines := &byteVector{}
+		lines.putUint16(uint16(len(encoder.lineNumbers)))
+		for _, lineNumber := range encoder.lineNumbers {
+			lines.putUint16(uint16(labelOffset(lineNumber.Start)))
+			lines.putUint16(uint16(lineNumber.Line))
+		}
+		attributes = append(attributes, e.attribute(data.LINE_NUMBER_TABLE, lines.bytes))
+	}
+	if len(code.LocalVariables) > 0 {
+		attributes = append(attributes, e.encodeLocalVariables(data.LOCAL_VARIABLE_TABLE, code.LocalVariables, false))
+		signatures := make([]LocalVariable, 0)
+		for _, variable := range code.LocalVariables {
+			if len(variable.Signature) > 0 {
+				signatures = append(signatures, variable)
+			}
+		}
+		if len(signatures) > 0 {
+			attributes = append(attributes, e.encodeLocalVariables(data.LOCAL_VARIABLE_TYPE_TABLE, signatures, true))
+		}
+	}
+	if len(code.RuntimeVisibleTypeAnnotations) > 0 || len(code.RuntimeInvisibleTypeAnnotations) > 0 {
+		offsets := make(map[Instruction]int, len(instructions))
+		for i, offset := range encoder.offsets(instructions) {
+			if instructions[i].OpCode() >= 0 {
+				offsets[instructions[i]] = offset
+			}
+		}
+		if len(code.RuntimeVisibleTypeAnnotations) > 0 {
+			attributes = append(attributes, e.codeTypeAnnotationsAttribute(data.RUNTIME_VISIBLE_TYPE_ANNOTATIONS, code.RuntimeVisibleTypeAnnotations, offsets))
+		}
+		if len(code.RuntimeInvisibleTypeAnnotations) > 0 {
+			attributes = append(attributes, e.codeTypeAnnotationsAttribute(data.RUNTIME_INVISIBLE_TYPE_ANNOTATIONS, code.RuntimeInvisibleTypeAnnotations, offsets))
+		}
+	}
+	for _, attribute := range code.Attributes {
+		attributes = append(attributes, e.attribute(attribute.Name, attribute.Content))
+	}
+	putAttributes(vector, attributes)
+	return vector.bytes
+}
+
+// putAttributes writes the attributes nested in the Code and Record attributes.
+func putAttributes(vector *byteVector, attributes []data.AttributeData) {
+	vector.putUint16(uint16(len(attributes)))
+	for _, attribute := range attributes {
+		vector.putUint16(attribute.NameIndex)
+		vector.putUint32(attribute.Length)
+		vector.putBytes(attribute.Value)
+	}
+}
+
+func (e *classEncoder) encodeLocalVariables(name string, variables []LocalVariable, signature bool) data.AttributeData {
+	vector := &byteVector{}
+	vector.putUint16(uint16(len(variables)))
+	for _, variable := range variables {
+		start := labelOffset(variable.Start)
+		vector.putUint16(uint16(start))
+		vector.putUint16(uint16(labelOffset(variable.End) - start))
+		vector.putUint16(e.symbols.addUTF8(variable.Name))
+		if signature {
+			vector.putUint16(e.symbols.addUTF8(variable.Signature))
+		} else {
+			vector.putUint16(e.symbols.addUTF8(variable.Descriptor))
+		}
+		vector.putUint16(uint16(variable.Index))
+	}
+	return e.attribute(name, vector.bytes)
+}
+
+func labelOffset(label *Label) int {
+	if label == nil || !label.resolved {
+		panic("label not visited")
+	}
+	return label.Offset
+}
+
+// codeEncoder encodes the instructions of a method. The offsets of the labels are computed in
+// a first pass, and the instructions are written in a second pass.
+type codeEncoder struct {
+	symbols *symbolTable
+	// longJumps are the jumps whose offset does not fit in 16 bits.
+	longJumps    map[*JumpInstruction]bool
+	frames       []*Frame
+	frameOffsets []int
+	lineNumbers  []*LineNumber
+}
+
+func (c *codeEncoder) encode(instructions []Instruction) []byte {
+	for _, instruction := range instructions {
+		if label, ok := instruction.(*Label); ok {
+			label.resolved = false
+		}
+	}
+	offset := 0
+	for _, instruction := range instructions {
+		switch insn := instruction.(type) {
+		case *Label:
+			insn.resolve(offset)
+		case *Frame:
+			if n := len(c.frameOffsets); n == 0 || c.frameOffsets[n-1] != offset {
+				c.frames = append(c.frames, insn)
+				c.frameOffsets = append(c.frameOffsets, offset)
+			}
+		case *LineNumber:
+			c.lineNumbers = append(c.lineNumbers, insn)
+		default:
+			offset += c.size(instruction, offset)
+		}
+	}
+	if offset == 0 || offset > math.MaxUint16 {
+		panic(fmt.Sprintf("invalid code length %d", offset))
+	}
+	vector := &byteVector{}
+	for _, instruction := range instructions {
+		if instruction.OpCode() >= 0 {
+			c.put(vector, instruction)
+		}
+	}
+	return vector.bytes
+}
+
+// InstructionOffsets returns the bytecode offsets at which the Writer encodes the instructions of
+// a method, a pseudo instruction having the offset of the instruction that follows it. The ldc
+// instructions are sized as in a class whose constant pool only contains their constants, and
+// the jumps whose offset does not fit in 16 bits as goto_w instructions. MethodCode.Offsets
+// returns the offsets of the class file for the code which has been read.
+func InstructionOffsets(instructions []Instruction) []int {
+	encoder := &codeEncoder{symbols: newSymbolTable()}
+	encoder.resizeJumps(instructions)
+	return encoder.offsets(instructions)
+}
+
+func (c *codeEncoder) offsets(instructions []Instruction) []int {
+	offsets := make([]int, len(instructions))
+	offset := 0
+	for i, instruction := range instructions {
+		offsets[i] = offset
+		if instruction.OpCode() >= 0 {
+			offset += c.size(instruction, offset)
+		}
+	}
+	return offsets
+}
+
+// resizeJumps finds the jumps whose offset does not fit in 16 bits, and returns true if there
+// are some. Like in ASM, the goto and jsr instructions are then encoded as goto_w and jsr_w,
+// and the conditional jumps with the opposite condition, which jumps over a goto_w to the
+// original target. Resizing a jump moves the following instructions, so this is repeated until
+// no other jump needs to be resized.
+func (c *codeEncoder) resizeJumps(instructions []Instruction) bool {
+	c.longJumps = make(map[*JumpInstruction]bool)
+	for {
+		offsets := c.offsets(instructions)
+		labels := make(map[*Label]int)
+		for i, instruction := range instructions {
+			if label, ok := instruction.(*Label); ok {
+				labels[label] = offsets[i]
+			}
+		}
+		resized := false
+		for i, instruction := range instructions {
+			jump, ok := instruction.(*JumpInstruction)
+			if !ok || c.longJumps[jump] {
+				continue
+			}
+			if target, ok := labels[jump.Label]; ok && (target-offsets[i] < math.MinInt16 || target-offsets[i] > math.MaxInt16) {
+				c.longJumps[jump] = true
+				resized = true
+			}
+		}
+		if !resized {
+			return len(c.longJumps) > 0
+		}
+	}
+}
+
+// oppositeJump returns the conditional jump opcode with the opposite condition.
+func oppositeJump(opCode uint16) uint16 {
+	if opCode == data.IFNULL || opCode == data.IFNONNULL {
+		return opCode ^ 1
+	}
+	return ((opCode - data.IFEQ) ^ 1) + data.IFEQ
+}
+
+func (c *codeEncoder) isWideConstant(value interface{}) bool {
+	switch v := value.(type) {
+	case int64, float64:
+		return true
+	case ConstantDynamic:
+		return v.Descriptor == "J" || v.Descriptor == "D"
+	default:
+		return false
+	}
+}
+
+func switchPadding(offset int) int {
+	return 3 - offset&3
+}
+
+// size returns the size of an instruction written at the given offset.
+func (c *codeEncoder) size(instruction Instruction, offset int) int {
+	switch insn := instruction.(type) {
+	case *CodeInstruction:
+		return 1
+	case *IntInstruction:
+		if insn.Op == data.SIPUSH {
+			return 3
+		}
+		return 2
+	case *VarInstruction:
+		if insn.Var < 4 && insn.Op != data.RET {
+			return 1
+		} else if insn.Var <= math.MaxUint8 {
+			return 2
+		}
+		return 4
+	case *JumpInstruction:
+		if !c.longJumps[insn] {
+			return 3
+		} else if insn.Op == data.GOTO || insn.Op == data.JSR {
+			return 5
+		}
+		return 8
+	case *TypeInstruction, *FieldInstruction:
+		return 3
+	case *MethodInstruction:
+		if insn.Op == data.INVOKEINTERFACE {
+			return 5
+		}
+		return 3
+	case *InvokeDynamicInstruction:
+		return 5
+	case *LdcInstruction:
+		index := c.symbols.addConstant(insn.Value)
+		if index <= math.MaxUint8 && !c.isWideConstant(insn.Value) {
+			return 2
+		}
+		return 3
+	case *IincInstruction:
+		if insn.Var <= math.MaxUint8 && insn.Increment >= math.MinInt8 && insn.Increment <= math.MaxInt8 {
+			return 3
+		}
+		return 6
+	case *TableSwitchInstruction:
+		return 1 + switchPadding(offset) + 12 + 4*len(insn.Labels)
+	case *LookupSwitchInstruction:
+		return 1 + switchPadding(offset) + 8 + 8*len(insn.Labels)
+	case *MultiANewArrayInstruction:
+		return 4
+	default:
+		panic(fmt.Sprintf("unknown instruction %T", instruction))
+	}
+}
+
+func (c *codeEncoder) put(vector *byteVector, instruction Instruction) {
+	symbols := c.symbols
+	offset := len(vector.bytes)
+	switch insn := instruction.(type) {
+	case *CodeInstruction:
+		vector.putUint8(uint8(insn.Op))
+	case *IntInstruction:
+		vector.putUint8(uint8(insn.Op))
+		if insn.Op == data.SIPUSH {
+			vector.putUint16(uint16(insn.Operand))
+		} else {
+			vector.putUint8(uint8(insn.Operand))
+		}
+	case *VarInstruction:
+		if insn.Var < 4 && insn.Op != data.RET {
+			if insn.Op < data.ISTORE {
+				vector.putUint8(uint8(data.ILOAD_0 + (int(insn.Op)-data.ILOAD)*4 + insn.Var))
+			} else {
+				vector.putUint8(uint8(data.ISTORE_0 + (int(insn.Op)-data.ISTORE)*4 + insn.Var))
+			}
+		} else if insn.Var <= math.MaxUint8 {
+			vector.putUint8(uint8(insn.Op))
+			vector.putUint8(uint8(insn.Var))
+		} else {
+			vector.putUint8(data.WIDE)
+			vector.putUint8(uint8(insn.Op))
+			vector.putUint16(uint16(insn.Var))
+		}
+	case *TypeInstruction:
+		vector.putUint8(uint8(insn.Op))
+		vector.putUint16(symbols.addClass(insn.Type))
+	case *FieldInstruction:
+		vector.putUint8(uint8(insn.Op))
+		vector.putUint16(symbols.addFieldRef(insn.Owner, insn.Name, insn.Descriptor))
+	case *MethodInstruction:
+		vector.putUint8(uint8(insn.Op))
+		vector.putUint16(symbols.addMethodRef(insn.Owner, insn.Name, insn.Descriptor, insn.IsInterface))
+		if insn.Op == data.INVOKEINTERFACE {
+			argumentsSize := 1
+			for _, argumentType := range NewMethodType(insn.Descriptor).ArgumentTypes() {
+				argumentsSize += argumentType.Size()
+			}
+			vector.putUint8(uint8(argumentsSize))
+			vector.putUint8(0)
+		}
+	case *InvokeDynamicInstruction:
+		vector.putUint8(data.INVOKEDYNAMIC)
+		vector.putUint16(symbols.addInvokeDynamic(insn.Name, insn.Descriptor, insn.BootstrapMethod, insn.BootstrapMethodArguments))
+		vector.putUint16(0)
+	case *JumpInstruction:
+		jump := labelOffset(insn.Label) - offset
+		if c.longJumps[insn] {
+			switch insn.Op {
+			case data.GOTO:
+				vector.putUint8(data.GOTO_W)
+			case data.JSR:
+				vector.putUint8(data.JSR_W)
+			default:
+				vector.putUint8(uint8(oppositeJump(insn.Op)))
+				vector.putUint16(8)
+				vector.putUint8(data.GOTO_W)
+				jump -= 3
+			}
+			vector.putUint32(uint32(jump))
+			break
+		}
+		if jump < math.MinInt16 || jump > math.MaxInt16 {
+			panic(fmt.Sprintf("jump offset %d too large", jump))
+		}
+		vector.putUint8(uint8(insn.Op))
+		vector.putUint16(uint16(jump))
+	case *LdcInstruction:
+		index := symbols.addConstant(insn.Value)
+		if c.isWideConstant(insn.Value) {
+			vector.putUint8(data.LDC2_W)
+			vector.putUint16(index)
+		} else if index <= math.MaxUint8 {
+			vector.putUint8(data.LDC)
+			vector.putUint8(uint8(index))
+		} else {
+			vector.putUint8(data.LDC_W)
+			vector.putUint16(index)
+		}
+	case *IincInstruction:
+		if insn.Var <= math.MaxUint8 && insn.Increment >= math.MinInt8 && insn.Increment <= math.MaxInt8 {
+			vector.putUint8(data.IINC)
+			vector.putUint8(uint8(insn.Var))
+			vector.putUint8(uint8(insn.Increment))
+		} else {
+			vector.putUint8(data.WIDE)
+			vector.putUint8(data.IINC)
+			vector.putUint16(uint16(insn.Var))
+			vector.putUint16(uint16(insn.Increment))
+		}
+	case *TableSwitchInstruction:
+		vector.putUint8(data.TABLESWITCH)
+		vector.putBytes(make([]byte, switchPadding(offset)))
+		vector.putUint32(uint32(labelOffset(insn.Default) - offset))
+		vector.putUint32(uint32(insn.Min))
+		vector.putUint32(uint32(insn.Max))
+		for _, label := range insn.Labels {
+			vector.putUint32(uint32(labelOffset(label) - offset))
+		}
+	case *LookupSwitchInstruction:
+		vector.putUint8(data.LOOKUPSWITCH)
+		vector.putBytes(make([]byte, switchPadding(offset)))
+		vector.putUint32(uint32(labelOffset(insn.Default) - offset))
+		vector.putUint32(uint32(len(insn.Labels)))
+		for i, label := range insn.Labels {
+			vector.putUint32(uint32(insn.Keys[i]))
+			vector.putUint32(uint32(labelOffset(label) - offset))
+		}
+	case *MultiANewArrayInstruction:
+		vector.putUint8(data.MULTIANEWARRAY)
+		vector.putUint16(symbols.addClass(insn.Descriptor))
+		vector.putUint8(uint8(insn.NumDimensions))
+	}
+}
+
+func (c *codeEncoder) encodeFrames() []byte {
+	vector := &byteVector{}
+	vector.putUint16(uint16(len(c.frames)))
+	previousOffset := -1
+	for i, frame := range c.frames {
+		delta := c.frameOffsets[i] - previousOffset - 1
+		previousOffset = c.frameOffsets[i]
+		switch frame.Type {
+		case data.F_SAME:
+			if delta < 64 {
+				vector.putUint8(uint8(delta))
+			} else {
+				vector.putUint8(251)
+				vector.putUint16(uint16(delta))
+			}
+		case data.F_SAME1:
+			if delta < 64 {
+				vector.putUint8(uint8(64 + delta))
+			} else {
+				vector.putUint8(247)
+				vector.putUint16(uint16(delta))
+			}
+			c.putVerificationType(vector, frame.Stack[0])
+		case data.F_CHOP:
+			vector.putUint8(uint8(251 - len(frame.Locals)))
+			vector.putUint16(uint16(delta))
+		case data.F_APPEND:
+			vector.putUint8(uint8(251 + len(frame.Locals)))
+			vector.putUint16(uint16(delta))
+			for _, local := range frame.Locals {
+				c.putVerificationType(vector, local)
+			}
+		default:
+			vector.putUint8(255)
+			vector.putUint16(uint16(delta))
+			vector.putUint16(uint16(len(frame.Locals)))
+			for _, local := range frame.Locals {
+				c.putVerificationType(vector, local)
+			}
+			vector.putUint16(uint16(len(frame.Stack)))
+			for _, stack := range frame.Stack {
+				c.putVerificationType(vector, stack)
+			}
+		}
+	}
+	return vector.bytes
+}
+
+func (c *codeEncoder) putVerificationType(vector *byteVector, verificationType interface{}) {
+	switch v := verificationType.(type) {
+	case uint8:
+		vector.putUint8(v)
+	case int:
+		vector.putUint8(uint8(v))
+	case string:
+		vector.putUint8(data.ITEM_OBJECT)
+		vector.putUint16(c.symbols.addClass(v))
+	case *Label:
+		vector.putUint8(data.ITEM_UNINITIALIZED)
+		vector.putUint16(uint16(labelOffset(v)))
+	default:
+		vector.putUint8(data.ITEM_TOP)
+	}
+}
+
+// byteVector is a growable byte slice with big endian put methods.
+type byteVector struct {
+	bytes []byte
+}
+
+func (v *byteVector) putUint8(value uint8) {
+	v.bytes = append(v.bytes, value)
+}
+
+func (v *byteVector) putUint16(value uint16) {
+	v.bytes = append(v.bytes, 0, 0)
+	binary.BigEndian.PutUint16(v.bytes[len(v.bytes)-2:], value)
+}
+
+func (v *byteVector) putUint32(value uint32) {
+	v.bytes = append(v.bytes, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(v.bytes[len(v.bytes)-4:], value)
+}
+
+func (v *byteVector) putBytes(value []byte) {
+	v.bytes = append(v.bytes, value...)
 }
@@ -0,0 +1,339 @@
+package class
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/tools"
+)
+
+func readClass(t *testing.T, content []byte) *Class {
+	t.Helper()
+	reader := NewReader(bytes.NewReader(content))
+	tools.AssertNoErr(t, reader.Read())
+	return reader.Class()
+}
+
+func writeClass(t *testing.T, class *Class) []byte {
+	t.Helper()
+	writer := NewWriter()
+	class.Accept(writer)
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+	return content
+}
+
+func TestWriteClass(t *testing.T) {
+	f, err := os.Open("Hello.class")
+	tools.AssertNoErr(t, err)
+	defer f.Close()
+	reader := NewReader(f)
+	tools.AssertNoErr(t, reader.Read())
+	hello := reader.Class()
+
+	content := writeClass(t, hello)
+	class := readClass(t, content)
+	tools.AssertEqual(t, hello.ThisClass, class.ThisClass)
+	tools.AssertEqual(t, hello.SuperClass, class.SuperClass)
+	tools.AssertEqual(t, hello.Version, class.Version)
+	tools.AssertEqual(t, hello.SourceFile, class.SourceFile)
+	tools.AssertEqual(t, len(hello.Fields), len(class.Fields))
+	tools.AssertEqual(t, len(hello.Methods), len(class.Methods))
+	for i, method := range hello.Methods {
+		tools.AssertEqual(t, method.Name+method.Descriptor, class.Methods[i].Name+class.Methods[i].Descriptor)
+		tools.AssertEqual(t, len(method.Code.Instructions), len(class.Methods[i].Code.Instructions))
+		tools.AssertEqual(t, method.Code.MaxStack, class.Methods[i].Code.MaxStack)
+	}
+	// Writing the class again must produce the same bytes.
+	tools.AssertEqual(t, string(content), string(writeClass(t, class)))
+}
+
+func TestWriteCode(t *testing.T) {
+	writer := NewWriter()
+	writer.Visit(52, data.ACC_PUBLIC, "a/A", "", "java/lang/Object", nil)
+	method := writer.VisitMethod(data.ACC_PUBLIC|data.ACC_STATIC, "m", "(I)J", "", nil)
+	method.VisitCode()
+	one, two, dflt := NewLabel(), NewLabel(), NewLabel()
+	method.VisitVarInstruction(data.ILOAD, 0)
+	method.VisitTableSwitchInstruction(1, 2, dflt, []*Label{one, two})
+	method.VisitLabel(one)
+	method.VisitFrame(data.F_SAME, 0, nil, 0, nil)
+	method.VisitLdcInstruction(int64(1) << 40)
+	method.VisitInstruction(data.LRETURN)
+	method.VisitLabel(two)
+	method.VisitFrame(data.F_SAME, 0, nil, 0, nil)
+	method.VisitIincInstruction(300, 1000)
+	method.VisitLabel(dflt)
+	method.VisitFrame(data.F_SAME, 0, nil, 0, nil)
+	method.VisitInstruction(data.LCONST_0)
+	method.VisitInstruction(data.LRETURN)
+	method.VisitMaxs(2, 301)
+	method.VisitEnd()
+	writer.VisitEnd()
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+
+	code := readClass(t, content).Methods[0].Code
+	tools.AssertEqual(t, 2, int(code.MaxStack))
+	var opCodes []int
+	var frames int
+	for _, instruction := range code.Instructions {
+		if instruction.OpCode() >= 0 {
+			opCodes = append(opCodes, instruction.OpCode())
+		} else if _, ok := instruction.(*Frame); ok {
+			frames++
+		}
+	}
+	tools.AssertEqual(t, 3, frames)
+	tools.AssertEqual(t, 7, len(opCodes))
+	tools.AssertEqual(t, data.LDC, opCodes[2])
+	iinc := &IincInstruction{}
+	for _, instruction := range code.Instructions {
+		switch insn := instruction.(type) {
+		case *LdcInstruction:
+			tools.AssertEqual(t, int64(1)<<40, insn.Value)
+		case *IincInstruction:
+			iinc = insn
+		}
+	}
+	tools.AssertEqual(t, 300, iinc.Var)
+	tools.AssertEqual(t, 1000, iinc.Increment)
+}
+
+func TestComputeFrames(t *testing.T) {
+	writer := NewWriter()
+	writer.Flags = COMPUTE_FRAMES
+	writer.Visit(52, data.ACC_PUBLIC, "a/A", "", "java/lang/Object", nil)
+	method := writer.VisitMethod(data.ACC_PUBLIC|data.ACC_STATIC, "m", "(Ljava/lang/String;)Ljava/lang/Object;", "", nil)
+	method.VisitCode()
+	nonNull, join, start, end, handler := NewLabel(), NewLabel(), NewLabel(), NewLabel(), NewLabel()
+	method.VisitTryCatchBlock(start, end, handler, "java/lang/Exception")
+	method.VisitVarInstruction(data.ALOAD, 0)
+	method.VisitJumpInstruction(data.IFNONNULL, nonNull)
+	method.VisitTypeInstruction(data.NEW, "java/lang/StringBuilder")
+	method.VisitInstruction(data.DUP)
+	method.VisitMethodInstruction(data.INVOKESPECIAL, "java/lang/StringBuilder", "<init>", "()V", false)
+	method.VisitVarInstruction(data.ASTORE, 1)
+	method.VisitJumpInstruction(data.GOTO, join)
+	method.VisitLabel(nonNull)
+	method.VisitVarInstruction(data.ALOAD, 0)
+	method.VisitVarInstruction(data.ASTORE, 1)
+	method.VisitLabel(join)
+	method.VisitLabel(start)
+	method.VisitVarInstruction(data.ALOAD, 1)
+	method.VisitInstruction(data.ARETURN)
+	method.VisitLabel(end)
+	method.VisitLabel(handler)
+	method.VisitVarInstruction(data.ASTORE, 2)
+	method.VisitInstruction(data.ACONST_NULL)
+	method.VisitInstruction(data.ARETURN)
+	method.VisitMaxs(0, 0)
+	method.VisitEnd()
+	writer.VisitEnd()
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+
+	code := readClass(t, content).Methods[0].Code
+	tools.AssertEqual(t, 2, int(code.MaxStack))
+	tools.AssertEqual(t, 3, int(code.MaxLocal))
+	var frames []*Frame
+	for _, instruction := range code.Instructions {
+		if frame, ok := instruction.(*Frame); ok {
+			frames = append(frames, frame)
+		}
+	}
+	tools.AssertEqual(t, 3, len(frames))
+	tools.AssertEqual(t, data.F_SAME, frames[0].Type)
+	tools.AssertEqual(t, data.F_APPEND, frames[1].Type)
+	tools.AssertEqual(t, "java/lang/Object", frames[1].Locals[0])
+	tools.AssertEqual(t, data.F_SAME1, frames[2].Type)
+	tools.AssertEqual(t, "java/lang/Exception", frames[2].Stack[0])
+}
+
+func TestWriteLongJumps(t *testing.T) {
+	writer := NewWriter()
+	writer.Flags = COMPUTE_FRAMES
+	writer.Visit(52, data.ACC_PUBLIC, "a/A", "", "java/lang/Object", nil)
+	method := writer.VisitMethod(data.ACC_PUBLIC|data.ACC_STATIC, "m", "(IJ)I", "", nil)
+	method.VisitCode()
+	loop, end := NewLabel(), NewLabel()
+	method.VisitLabel(loop)
+	method.VisitVarInstruction(data.ILOAD, 0)
+	method.VisitJumpInstruction(data.IFEQ, end)
+	method.VisitIincInstruction(0, -1)
+	for i := 0; i < 33000; i++ {
+		method.VisitInstruction(data.NOP)
+	}
+	method.VisitJumpInstruction(data.GOTO, loop)
+	method.VisitLabel(end)
+	method.VisitInstruction(data.ICONST_0)
+	method.VisitInstruction(data.IRETURN)
+	method.VisitMaxs(0, 0)
+	method.VisitEnd()
+	writer.VisitEnd()
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+
+	c := readClass(t, content)
+	var jumps []*JumpInstruction
+	frames := make(map[int]*Frame)
+	offset := 0
+	for _, instruction := range c.Methods[0].Code.Instructions {
+		switch insn := instruction.(type) {
+		case *Label:
+			offset = insn.Offset
+		case *Frame:
+			frames[offset] = insn
+		case *JumpInstruction:
+			jumps = append(jumps, insn)
+		}
+	}
+	// ifeq is encoded as "ifne +8; goto_w end", and the backward goto as a goto_w.
+	tools.AssertEqual(t, 3, len(jumps))
+	tools.AssertEqual(t, data.IFNE, int(jumps[0].Op))
+	tools.AssertEqual(t, 9, jumps[0].Label.Offset)
+	tools.AssertEqual(t, data.GOTO, int(jumps[1].Op))
+	tools.AssertEqual(t, 9+3+33000+5, jumps[1].Label.Offset)
+	tools.AssertEqual(t, 0, jumps[2].Label.Offset)
+	tools.AssertEqual(t, 3, len(frames))
+	tools.AssertEqual(t, data.F_FULL, frames[9].Type)
+	tools.AssertEqual(t, 2, len(frames[9].Locals))
+	tools.AssertEqual(t, data.ITEM_LONG, frames[9].Locals[1])
+	tools.AssertEqual(t, 0, len(frames[9].Stack))
+
+	// The class read back is written with the same long jumps.
+	tools.AssertEqual(t, string(content), string(writeClass(t, c)))
+}
+
+func TestWriteRecord(t *testing.T) {
+	writer := NewWriter()
+	writer.Visit(61, data.ACC_PUBLIC|data.ACC_FINAL|data.ACC_SUPER, "a/Point", "", "java/lang/Record", []string{"a/Shape"})
+	component := writer.VisitRecordComponent("x", "I", "")
+	component.VisitAnnotation("La/NonNegative;", true).VisitEnd()
+	component.VisitEnd()
+	component = writer.VisitRecordComponent("tags", "Ljava/util/List;", "Ljava/util/List<Ljava/lang/String;>;")
+	component.VisitAttribute(Attribute{Name: "Custom", Content: []byte{1}})
+	component.VisitEnd()
+	writer.VisitEnd()
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+
+	class := readClass(t, content)
+	tools.AssertEqual(t, 2, len(class.RecordComponents))
+	x, tags := class.RecordComponents[0], class.RecordComponents[1]
+	tools.AssertEqual(t, "x I", x.Name+" "+x.Descriptor)
+	tools.AssertEqual(t, 1, len(x.RuntimeVisibleAnnotations))
+	tools.AssertEqual(t, "La/NonNegative;", x.RuntimeVisibleAnnotations[0].Descriptor)
+	tools.AssertEqual(t, "Ljava/util/List<Ljava/lang/String;>;", tags.Signature)
+	tools.AssertEqual(t, "[{Custom [1]}]", fmt.Sprint(tags.Attributes))
+	tools.AssertEqual(t, string(content), string(writeClass(t, class)))
+
+	// A record without components keeps its empty Record attribute.
+	writer = NewWriter()
+	writer.Visit(61, data.ACC_FINAL|data.ACC_SUPER, "a/Empty", "", "java/lang/Record", nil)
+	writer.VisitEnd()
+	content, err = writer.Bytes()
+	tools.AssertNoErr(t, err)
+	class = readClass(t, content)
+	if class.RecordComponents == nil {
+		t.Errorf("the Record attribute of a/Empty is missing")
+	}
+	tools.AssertEqual(t, 0, len(class.RecordComponents))
+}
+
+func TestWritePermittedSubclasses(t *testing.T) {
+	writer := NewWriter()
+	writer.Visit(61, data.ACC_PUBLIC|data.ACC_ABSTRACT|data.ACC_INTERFACE, "a/Shape", "", "java/lang/Object", nil)
+	writer.VisitPermittedSubclass("a/Point")
+	writer.VisitPermittedSubclass("a/Circle")
+	writer.VisitEnd()
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+
+	class := readClass(t, content)
+	tools.AssertEqual(t, "[a/Point a/Circle]", fmt.Sprint(class.PermittedSubclasses))
+	tools.AssertEqual(t, 0, len(class.Attributes))
+	if class.RecordComponents != nil {
+		t.Errorf("unexpected Record attribute in a/Shape")
+	}
+}
+
+// visitTypeAnnotations visits a class with type annotations on its super types, a field, a
+// method and the code of the method.
+func visitTypeAnnotations(visitor Visitor) {
+	visitor.Visit(52, data.ACC_PUBLIC|data.ACC_SUPER, "a/A", "", "java/lang/Object", []string{"java/util/List"})
+	visitor.VisitTypeAnnotation(NewSuperTypeReference(-1), nil, "La/Super;", true).VisitEnd()
+	annotation := visitor.VisitTypeAnnotation(NewSuperTypeReference(0), TypePath{{Kind: data.TYPE_PATH_TYPE_ARGUMENT}}, "La/Element;", false)
+	annotation.Visit("value", "x")
+	annotation.VisitEnd()
+	field := visitor.VisitField(data.ACC_PRIVATE, "f", "[Ljava/lang/String;", "", nil)
+	field.VisitTypeAnnotation(NewTypeReference(data.TYPE_REF_FIELD), TypePath{{Kind: data.TYPE_PATH_ARRAY_ELEMENT}}, "La/NonNull;", true).VisitEnd()
+	field.VisitEnd()
+
+	method := visitor.VisitMethod(data.ACC_STATIC, "m", "(Ljava/lang/Object;)Ljava/lang/String;", "", []string{"java/io/IOException"})
+	method.VisitTypeAnnotation(NewTypeReference(data.TYPE_REF_METHOD_RETURN), nil, "La/NonNull;", true).VisitEnd()
+	method.VisitTypeAnnotation(NewExceptionReference(0), nil, "La/Checked;", false).VisitEnd()
+	method.VisitCode()
+	start, end, handler := NewLabel(), NewLabel(), NewLabel()
+	method.VisitTryCatchBlock(start, end, handler, "java/lang/RuntimeException")
+	method.VisitTryCatchAnnotation(NewTryCatchReference(0), nil, "La/Caught;", true).VisitEnd()
+	method.VisitLabel(start)
+	method.VisitVarInstruction(data.ALOAD, 0)
+	method.VisitTypeInstruction(data.CHECKCAST, "java/lang/String")
+	method.VisitInstructionAnnotation(NewTypeArgumentReference(data.TYPE_REF_CAST, 0), nil, "La/NonNull;", true).VisitEnd()
+	method.VisitLabel(end)
+	method.VisitInstruction(data.ARETURN)
+	method.VisitLabel(handler)
+	method.VisitFrame(data.F_SAME1, 0, nil, 1, []interface{}{"java/lang/RuntimeException"})
+	method.VisitInstruction(data.ACONST_NULL)
+	method.VisitInstruction(data.ARETURN)
+	method.VisitLocalVariable("o", "Ljava/lang/Object;", "", start, handler, 0)
+	method.VisitLocalVariableAnnotation(NewTypeReference(data.TYPE_REF_LOCAL_VARIABLE), nil, []*Label{start}, []*Label{handler}, []int{0}, "La/Local;", false).VisitEnd()
+	method.VisitMaxs(1, 1)
+	method.VisitEnd()
+	visitor.VisitEnd()
+}
+
+func TestWriteTypeAnnotations(t *testing.T) {
+	writer := NewWriter()
+	visitTypeAnnotations(NewCheckVisitor(writer))
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+
+	class := readClass(t, content)
+	tools.AssertEqual(t, 1, len(class.RuntimeVisibleTypeAnnotations))
+	tools.AssertEqual(t, NewSuperTypeReference(-1), class.RuntimeVisibleTypeAnnotations[0].TypeRef)
+	tools.AssertEqual(t, 1, len(class.RuntimeInvisibleTypeAnnotations))
+	element := class.RuntimeInvisibleTypeAnnotations[0]
+	tools.AssertEqual(t, NewSuperTypeReference(0), element.TypeRef)
+	tools.AssertEqual(t, "0;", element.TypePath.String())
+	tools.AssertEqual(t, "La/Element;", element.Descriptor)
+	tools.AssertEqual(t, 1, len(element.ElementPairs))
+	tools.AssertEqual(t, "[", class.Fields[0].RuntimeVisibleTypeAnnotations[0].TypePath.String())
+	method := class.Methods[0]
+	tools.AssertEqual(t, NewTypeReference(data.TYPE_REF_METHOD_RETURN), method.RuntimeVisibleTypeAnnotations[0].TypeRef)
+	tools.AssertEqual(t, NewExceptionReference(0), method.RuntimeInvisibleTypeAnnotations[0].TypeRef)
+
+	code := method.Code
+	tools.AssertEqual(t, 2, len(code.RuntimeVisibleTypeAnnotations))
+	caught, cast := code.RuntimeVisibleTypeAnnotations[0], code.RuntimeVisibleTypeAnnotations[1]
+	tools.AssertEqual(t, NewTryCatchReference(0), caught.TypeRef)
+	tools.AssertEqual(t, "La/Caught;", caught.Descriptor)
+	tools.AssertEqual(t, NewTypeArgumentReference(data.TYPE_REF_CAST, 0), cast.TypeRef)
+	if instruction, ok := cast.Instruction.(*TypeInstruction); !ok || instruction.Op != data.CHECKCAST {
+		t.Errorf("unexpected annotated instruction %v", cast.Instruction)
+	}
+	tools.AssertEqual(t, 1, len(code.RuntimeInvisibleTypeAnnotations))
+	local := code.RuntimeInvisibleTypeAnnotations[0]
+	tools.AssertEqual(t, "[0] [5] [0]", fmt.Sprint([]int{local.Start[0].Offset}, []int{local.End[0].Offset}, local.Index))
+	tools.AssertEqual(t, string(content), string(writeClass(t, class)))
+
+	// The type annotations of the code are only written in the code.
+	class.RuntimeVisibleTypeAnnotations[0].TypeRef = NewTypeReference(data.TYPE_REF_CAST)
+	if _, err := Encode(class); err == nil {
+		t.Errorf("a class type annotation with a CAST type reference is encoded")
+	}
+}
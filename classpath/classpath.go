@@ -0,0 +1,288 @@
+// Package classpath locates classes by internal name in an ordered list of directories, JAR
+// files and in-memory classes.
+package classpath
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tk103331/clazz/archive"
+	"github.com/tk103331/clazz/class"
+)
+
+// ErrNotFound is returned when a class is not in the classpath.
+var ErrNotFound = errors.New("class not found")
+
+// Classpath is an ordered list of sources. When several sources contain a class, the first one
+// wins. The classes found are parsed once and cached. A Classpath is safe for concurrent use,
+// except the methods which add sources.
+type Classpath struct {
+	sources []Source
+	release int
+	jars    map[string]bool
+	mutex   sync.Mutex
+	cache   map[string]*result
+}
+
+type result struct {
+	class *class.Class
+	err   error
+}
+
+func New() *Classpath {
+	return &Classpath{jars: make(map[string]bool), cache: make(map[string]*result)}
+}
+
+// Parse returns a classpath made of the directories and JAR files of a list separated by
+// os.PathListSeparator, as with the -classpath option of the java command.
+func Parse(list string) (*Classpath, error) {
+	c := New()
+	for _, path := range filepath.SplitList(list) {
+		if err := c.AddPath(path); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// SetRelease sets the Java release for which the entries of multi-release JARs are resolved.
+func (c *Classpath) SetRelease(release int) {
+	c.release = release
+	for _, source := range c.sources {
+		if archiveSource, ok := source.(*ArchiveSource); ok {
+			archiveSource.Archive.SetRelease(release)
+		}
+	}
+	c.cache = make(map[string]*result)
+}
+
+// Add appends a source to the classpath.
+func (c *Classpath) Add(source Source) {
+	c.sources = append(c.sources, source)
+}
+
+// Sources returns the sources of the classpath, in order.
+func (c *Classpath) Sources() []Source {
+	return c.sources
+}
+
+// AddPath appends a directory or a JAR file to the classpath.
+func (c *Classpath) AddPath(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		c.Add(NewDirectorySource(path))
+		return nil
+	}
+	return c.AddJar(path)
+}
+
+// AddJar appends a JAR file to the classpath, followed by the JAR files and directories of the
+// Class-Path attribute of its manifest, transitively. The Class-Path entries which do not
+// exist are ignored, as the JVM does.
+func (c *Classpath) AddJar(path string) error {
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if c.jars[absolutePath] {
+		return nil
+	}
+	c.jars[absolutePath] = true
+	a, err := archive.Open(path)
+	if err != nil {
+		return err
+	}
+	return c.addArchive(path, a, filepath.Dir(absolutePath))
+}
+
+// AddJarBytes appends a JAR file held in memory to the classpath. The Class-Path attribute of
+// its manifest is ignored, since it has no location.
+func (c *Classpath) AddJarBytes(name string, content []byte) error {
+	a, err := archive.NewArchiveBytes(content)
+	if err != nil {
+		return err
+	}
+	return c.addArchive(name, a, "")
+}
+
+// AddNestedJar appends a JAR file contained in another JAR file to the classpath, as used by
+// executable JARs. The name of the source is the path of the outer JAR, followed by "!/" and
+// the name of the entry.
+func (c *Classpath) AddNestedJar(path string, entryName string) error {
+	outer, err := archive.Open(path)
+	if err != nil {
+		return err
+	}
+	defer outer.Close()
+	content, err := outer.ReadFile(entryName)
+	if err != nil {
+		return err
+	}
+	return c.AddJarBytes(path+"!/"+entryName, content)
+}
+
+// AddNestedJars appends the JAR files contained in the given directory of another JAR file,
+// for example "BOOT-INF/lib/", in name order.
+func (c *Classpath) AddNestedJars(path string, dir string) error {
+	outer, err := archive.Open(path)
+	if err != nil {
+		return err
+	}
+	defer outer.Close()
+	for _, entry := range outer.Entries() {
+		if !strings.HasPrefix(entry.Name, dir) || !strings.HasSuffix(entry.Name, ".jar") {
+			continue
+		}
+		content, err := outer.ReadFile(entry.Name)
+		if err != nil {
+			return err
+		}
+		if err := c.AddJarBytes(path+"!/"+entry.Name, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Classpath) addArchive(name string, a *archive.Archive, dir string) error {
+	a.SetRelease(c.release)
+	c.Add(NewArchiveSource(name, a))
+	if a.Manifest() == nil || len(dir) == 0 {
+		return nil
+	}
+	for _, classPath := range a.Manifest().ClassPath() {
+		path, err := resolveClassPath(dir, classPath)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			c.Add(NewDirectorySource(path))
+		} else if err := c.AddJar(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveClassPath resolves a relative URL of a Class-Path attribute against the directory of
+// its JAR file.
+func resolveClassPath(dir string, classPath string) (string, error) {
+	u, err := url.Parse(classPath)
+	if err != nil {
+		return "", err
+	}
+	if len(u.Scheme) > 0 && u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported class path URL %s", classPath)
+	}
+	path := filepath.FromSlash(u.Path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
+// FindBytes returns the content of the class file of the given class, from the first source
+// which contains it.
+func (c *Classpath) FindBytes(internalName string) ([]byte, error) {
+	for _, source := range c.sources {
+		content, err := source.ReadClass(internalName)
+		if err == nil {
+			return content, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", internalName, ErrNotFound)
+}
+
+// Find returns the class with the given internal name. The error wraps ErrNotFound if the
+// class is not in the classpath.
+func (c *Classpath) Find(internalName string) (*class.Class, error) {
+	c.mutex.Lock()
+	r, ok := c.cache[internalName]
+	c.mutex.Unlock()
+	if ok {
+		return r.class, r.err
+	}
+	r = &result{}
+	content, err := c.FindBytes(internalName)
+	if err != nil {
+		r.err = err
+	} else {
+		reader := class.NewReader(bytes.NewReader(content))
+		if err := reader.Read(); err != nil {
+			r.err = fmt.Errorf("%s: %v", internalName, err)
+		} else {
+			r.class = reader.Class()
+		}
+	}
+	c.mutex.Lock()
+	c.cache[internalName] = r
+	c.mutex.Unlock()
+	return r.class, r.err
+}
+
+// ClassNames returns the internal names of all the classes of the classpath, sorted.
+func (c *Classpath) ClassNames() ([]string, error) {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, source := range c.sources {
+		sourceNames, err := source.ClassNames()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range sourceNames {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Packages returns the internal names of the classes of the classpath, grouped by package.
+// The package of the classes of the unnamed package is "".
+func (c *Classpath) Packages() (map[string][]string, error) {
+	names, err := c.ClassNames()
+	if err != nil {
+		return nil, err
+	}
+	packages := make(map[string][]string)
+	for _, name := range names {
+		pkg := ""
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			pkg = name[:i]
+		}
+		packages[pkg] = append(packages[pkg], name)
+	}
+	return packages, nil
+}
+
+// Close closes the sources of the classpath.
+func (c *Classpath) Close() error {
+	var err error
+	for _, source := range c.sources {
+		if e := source.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
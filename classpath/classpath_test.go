@@ -0,0 +1,95 @@
+package classpath
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/tools"
+)
+
+func newTestClass(t *testing.T, name string, superName string) []byte {
+	writer := class.NewWriter()
+	writer.Visit(52, data.ACC_PUBLIC, name, "", superName, nil)
+	writer.VisitEnd()
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+	return content
+}
+
+func newTestJar(t *testing.T, entries map[string][]byte) []byte {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, content := range entries {
+		w, err := writer.Create(name)
+		tools.AssertNoErr(t, err)
+		w.Write(content)
+	}
+	tools.AssertNoErr(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func TestClasspath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "classpath")
+	tools.AssertNoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	classes := filepath.Join(dir, "classes")
+	tools.AssertNoErr(t, os.MkdirAll(filepath.Join(classes, "a"), 0755))
+	tools.AssertNoErr(t, ioutil.WriteFile(filepath.Join(classes, "a", "A.class"), newTestClass(t, "a/A", "java/lang/Object"), 0644))
+	// A file with the name of a package does not hide the classes of this package in other sources.
+	tools.AssertNoErr(t, ioutil.WriteFile(filepath.Join(classes, "b"), nil, 0644))
+	_, err = NewDirectorySource(classes).ReadClass("b/B")
+	tools.AssertEqual(t, true, os.IsNotExist(err))
+	tools.AssertNoErr(t, os.MkdirAll(filepath.Join(dir, "lib"), 0755))
+	tools.AssertNoErr(t, ioutil.WriteFile(filepath.Join(dir, "lib", "dep.jar"), newTestJar(t, map[string][]byte{
+		"META-INF/MANIFEST.MF": []byte("Manifest-Version: 1.0\nClass-Path: ../main.jar\n"),
+		"b/B.class":            newTestClass(t, "b/B", "java/lang/Object"),
+	}), 0644))
+	nested := newTestJar(t, map[string][]byte{"c/C.class": newTestClass(t, "c/C", "b/B")})
+	tools.AssertNoErr(t, ioutil.WriteFile(filepath.Join(dir, "main.jar"), newTestJar(t, map[string][]byte{
+		"META-INF/MANIFEST.MF": []byte("Manifest-Version: 1.0\nClass-Path: lib/dep.jar missing.jar\n"),
+		"a/A.class":            newTestClass(t, "a/A", "b/B"),
+		"lib/nested.jar":       nested,
+	}), 0644))
+
+	c, err := Parse(classes + string(os.PathListSeparator) + filepath.Join(dir, "main.jar"))
+	tools.AssertNoErr(t, err)
+	defer c.Close()
+	tools.AssertNoErr(t, c.AddNestedJars(filepath.Join(dir, "main.jar"), "lib/"))
+	memory := NewMemorySource()
+	memory.Add("Gen", newTestClass(t, "Gen", "c/C"))
+	c.Add(memory)
+	tools.AssertEqual(t, 5, len(c.Sources()))
+
+	// The directory comes first.
+	a, err := c.Find("a/A")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "java/lang/Object", a.SuperClass)
+	cached, _ := c.Find("a/A")
+	tools.AssertEqual(t, a, cached)
+	b, err := c.Find("b/B")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "b/B", b.ThisClass)
+	nestedClass, err := c.Find("c/C")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "b/B", nestedClass.SuperClass)
+	_, err = c.Find("Gen")
+	tools.AssertNoErr(t, err)
+	_, err = c.Find("d/D")
+	tools.AssertEqual(t, true, errors.Is(err, ErrNotFound))
+
+	names, err := c.ClassNames()
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, 4, len(names))
+	packages, err := c.Packages()
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "Gen", packages[""][0])
+	tools.AssertEqual(t, 1, len(packages["a"]))
+}
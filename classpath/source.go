@@ -0,0 +1,137 @@
+package classpath
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/tk103331/clazz/archive"
+)
+
+// Source is an element of a classpath, which contains class files designated by the internal
+// names of their classes.
+type Source interface {
+	// ReadClass returns the content of the class file of the given class, or an error
+	// satisfying os.IsNotExist if the source does not contain this class.
+	ReadClass(internalName string) ([]byte, error)
+	// ClassNames returns the internal names of the classes of the source.
+	ClassNames() ([]string, error)
+	Close() error
+}
+
+// DirectorySource is a directory containing class files in package subdirectories.
+type DirectorySource struct {
+	Dir string
+}
+
+func NewDirectorySource(dir string) *DirectorySource {
+	return &DirectorySource{Dir: dir}
+}
+
+func (s *DirectorySource) ReadClass(internalName string) ([]byte, error) {
+	path := filepath.Join(s.Dir, filepath.FromSlash(internalName)+".class")
+	content, err := ioutil.ReadFile(path)
+	if errors.Is(err, syscall.ENOTDIR) {
+		// A package directory of the class is a file.
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return content, err
+}
+
+func (s *DirectorySource) ClassNames() ([]string, error) {
+	names := make([]string, 0)
+	err := filepath.Walk(s.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".class") {
+			return nil
+		}
+		name, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, strings.TrimSuffix(filepath.ToSlash(name), ".class"))
+		return nil
+	})
+	return names, err
+}
+
+func (s *DirectorySource) Close() error {
+	return nil
+}
+
+// ArchiveSource is a JAR file. Name is the path of the file, or a descriptive name for the
+// archives which are not files, such as nested JARs.
+type ArchiveSource struct {
+	Name    string
+	Archive *archive.Archive
+}
+
+func NewArchiveSource(name string, a *archive.Archive) *ArchiveSource {
+	return &ArchiveSource{Name: name, Archive: a}
+}
+
+func (s *ArchiveSource) ReadClass(internalName string) ([]byte, error) {
+	return s.Archive.ReadFile(internalName + ".class")
+}
+
+func (s *ArchiveSource) ClassNames() ([]string, error) {
+	entries := s.Archive.ClassEntries()
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.ClassName()
+	}
+	return names, nil
+}
+
+func (s *ArchiveSource) Close() error {
+	return s.Archive.Close()
+}
+
+// MemorySource contains class files held in memory, for example generated classes.
+type MemorySource struct {
+	mutex   sync.RWMutex
+	classes map[string][]byte
+}
+
+func NewMemorySource() *MemorySource {
+	return &MemorySource{classes: make(map[string][]byte)}
+}
+
+// Add adds or replaces the class file of the given class.
+func (s *MemorySource) Add(internalName string, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.classes[internalName] = content
+}
+
+func (s *MemorySource) ReadClass(internalName string) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	content, ok := s.classes[internalName]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: internalName, Err: os.ErrNotExist}
+	}
+	return content, nil
+}
+
+func (s *MemorySource) ClassNames() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	names := make([]string, 0, len(s.classes))
+	for name := range s.classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *MemorySource) Close() error {
+	return nil
+}
@@ -0,0 +1,75 @@
+// Command callgraph prints the call graph of the classes of a classpath in the DOT or JSON
+// format.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tk103331/clazz/callgraph"
+	"github.com/tk103331/clazz/classpath"
+)
+
+func main() {
+	algorithm := flag.String("algorithm", "cha", "algorithm of the virtual calls: cha or rta")
+	format := flag.String("format", "dot", "output format: dot or json")
+	entryPoints := flag.String("entry", "", "comma separated entry points such as a/Main.main([Ljava/lang/String;)V "+
+		"(default: the main methods and static initializers)")
+	reachable := flag.Bool("reachable", false, "only print the methods reachable from the entry points")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: callgraph [-algorithm cha|rta] [-format dot|json] [-entry methods] [-reachable] classpath")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || (*algorithm != "cha" && *algorithm != "rta") || (*format != "dot" && *format != "json") {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *algorithm, *format, *entryPoints, *reachable); err != nil {
+		fmt.Fprintf(os.Stderr, "callgraph: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, algorithm string, format string, entryPoints string, reachable bool) error {
+	options := callgraph.Options{}
+	if algorithm == "rta" {
+		options.Algorithm = callgraph.RTA
+	}
+	if entryPoints != "" {
+		for _, entryPoint := range strings.Split(entryPoints, ",") {
+			method, err := callgraph.ParseMethod(entryPoint)
+			if err != nil {
+				return err
+			}
+			options.EntryPoints = append(options.EntryPoints, method)
+		}
+	}
+	cp, err := classpath.Parse(path)
+	if err != nil {
+		return err
+	}
+	defer cp.Close()
+	graph, err := callgraph.Build(cp, options)
+	if err != nil {
+		return err
+	}
+	if reachable {
+		for _, method := range graph.Reachable() {
+			fmt.Println(method)
+		}
+		return nil
+	}
+	if format == "json" {
+		content, err := json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(string(content))
+		return err
+	}
+	return graph.WriteDOT(os.Stdout)
+}
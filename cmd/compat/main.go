@@ -0,0 +1,62 @@
+// Command compat reports the incompatible changes between two versions of a library, as text
+// or JSON. It exits with status 3 if the changes break the compatibility given by -fail.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tk103331/clazz/classpath"
+	"github.com/tk103331/clazz/compat"
+)
+
+func main() {
+	format := flag.String("format", "text", "output format: text or json")
+	fail := flag.String("fail", "none", "fail on the changes which break the compatibility: binary, source or none")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: compat [-format text|json] [-fail binary|source|none] old new")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 || (*format != "text" && *format != "json") ||
+		(*fail != "binary" && *fail != "source" && *fail != "none") {
+		flag.Usage()
+		os.Exit(2)
+	}
+	report, err := run(flag.Arg(0), flag.Arg(1), *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "compat: %v\n", err)
+		os.Exit(1)
+	}
+	if *fail == "binary" && report.Breaking(compat.Binary) || *fail == "source" && report.Breaking(compat.Source) {
+		os.Exit(3)
+	}
+}
+
+func run(oldPath string, newPath string, format string) (*compat.Report, error) {
+	oldClasspath, err := classpath.Parse(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	defer oldClasspath.Close()
+	newClasspath, err := classpath.Parse(newPath)
+	if err != nil {
+		return nil, err
+	}
+	defer newClasspath.Close()
+	report, err := compat.Compare(oldClasspath, newClasspath)
+	if err != nil {
+		return nil, err
+	}
+	if format == "json" {
+		content, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		_, err = fmt.Println(string(content))
+		return report, err
+	}
+	return report, report.WriteText(os.Stdout)
+}
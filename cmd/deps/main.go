@@ -0,0 +1,59 @@
+// Command deps prints the dependencies of the classes of a classpath at the class, package,
+// archive or module level, in the text format of jdeps or in the DOT format.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tk103331/clazz/classpath"
+	"github.com/tk103331/clazz/deps"
+)
+
+var levels = map[string]deps.Level{
+	"class":   deps.Class,
+	"package": deps.Package,
+	"archive": deps.Archive,
+	"module":  deps.Module,
+}
+
+func main() {
+	level := flag.String("level", "package", "level of the dependencies: class, package, archive or module")
+	format := flag.String("format", "report", "output format: report or dot")
+	excludeJDK := flag.Bool("exclude-jdk", false, "exclude the dependencies on the JDK classes")
+	excludeSamePackage := flag.Bool("exclude-same-package", false, "exclude the dependencies within a package")
+	excludeSameArchive := flag.Bool("exclude-same-archive", false, "exclude the dependencies within an archive")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: deps [-level class|package|archive|module] [-format report|dot] "+
+			"[-exclude-jdk] [-exclude-same-package] [-exclude-same-archive] classpath")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if _, ok := levels[*level]; !ok || flag.NArg() != 1 || (*format != "report" && *format != "dot") {
+		flag.Usage()
+		os.Exit(2)
+	}
+	options := deps.Options{ExcludeJDK: *excludeJDK, ExcludeSamePackage: *excludeSamePackage,
+		ExcludeSameArchive: *excludeSameArchive}
+	if err := run(flag.Arg(0), levels[*level], *format, options); err != nil {
+		fmt.Fprintf(os.Stderr, "deps: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, level deps.Level, format string, options deps.Options) error {
+	cp, err := classpath.Parse(path)
+	if err != nil {
+		return err
+	}
+	defer cp.Close()
+	graph, err := deps.Analyze(cp, options)
+	if err != nil {
+		return err
+	}
+	if format == "dot" {
+		return graph.WriteDOT(os.Stdout, level)
+	}
+	return graph.WriteReport(os.Stdout, level)
+}
@@ -0,0 +1,49 @@
+// Command goifier prints the Go source of a program rebuilding a class file with the clazz
+// writer API.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tk103331/clazz/class"
+)
+
+func main() {
+	pkg := flag.String("package", "main", "package of the generated source")
+	function := flag.String("func", "dump", "name of the generated function")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: goifier [-package name] [-func name] classfile")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *pkg, *function); err != nil {
+		fmt.Fprintf(os.Stderr, "goifier: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, pkg string, function string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := class.NewReader(f)
+	if err := reader.Read(); err != nil {
+		return err
+	}
+	goifier := class.NewGoifier(pkg, function)
+	reader.Accept(goifier)
+	source, err := goifier.Source()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(source)
+	return err
+}
@@ -0,0 +1,48 @@
+// Command instrument inserts coverage probes in the classes of a JAR, and writes the
+// instrumented JAR and the JSON layout of the probes, with the ID of each class. The layout is
+// printed to the standard output unless a layout file is given.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/tk103331/clazz/coverage"
+)
+
+func main() {
+	runtime := flag.String("runtime", "", "internal name of the class whose getProbes method returns the probe arrays")
+	layoutPath := flag.String("layout", "", "file of the JSON layout of the probes")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: instrument [-runtime class] [-layout file] input.jar output.jar")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), flag.Arg(1), *runtime, *layoutPath); err != nil {
+		fmt.Fprintf(os.Stderr, "instrument: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(input string, output string, runtime string, layoutPath string) error {
+	layout, err := coverage.InstrumentFile(input, output, &coverage.Options{Runtime: runtime})
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(layout, "", "  ")
+	if err != nil {
+		return err
+	}
+	if layoutPath != "" {
+		return ioutil.WriteFile(layoutPath, append(content, '\n'), 0644)
+	}
+	_, err = fmt.Println(string(content))
+	return err
+}
@@ -0,0 +1,72 @@
+// Command jasm assembles class files from jasm source files, or disassembles class files with -d.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/jasm"
+)
+
+func main() {
+	disassemble := flag.Bool("d", false, "disassemble class files to the standard output")
+	output := flag.String("o", "", "output directory of the assembled class files (default: current directory)")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: jasm [-o dir] file.j... | jasm -d classfile...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	status := 0
+	for _, path := range flag.Args() {
+		var err error
+		if *disassemble {
+			err = disassembleFile(path)
+		} else {
+			err = assembleFile(path, *output)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "jasm: %s: %v\n", path, err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+func disassembleFile(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return jasm.Disassemble(os.Stdout, content)
+}
+
+// assembleFile writes the class of a source file in the output directory, in the directories of
+// its package.
+func assembleFile(path string, output string) error {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	content, err := jasm.Assemble(source)
+	if err != nil {
+		return err
+	}
+	reader := class.NewReader(bytes.NewReader(content))
+	if err := reader.Read(); err != nil {
+		return err
+	}
+	target := filepath.Join(output, filepath.FromSlash(reader.Class().ThisClass+".class"))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(target, content, 0644)
+}
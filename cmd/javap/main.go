@@ -0,0 +1,33 @@
+// Command javap prints class files in the format of "javap -v -c -p".
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tk103331/clazz/javap"
+)
+
+func main() {
+	flag.Bool("v", true, "print additional information (always enabled)")
+	flag.Bool("c", true, "disassemble the code (always enabled)")
+	flag.Bool("p", true, "show all classes and members (always enabled)")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: javap [-v] [-c] [-p] classfile...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	status := 0
+	for _, path := range flag.Args() {
+		if err := javap.DisassembleFile(os.Stdout, path); err != nil {
+			fmt.Fprintf(os.Stderr, "javap: %s: %v\n", path, err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
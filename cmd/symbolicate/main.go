@@ -0,0 +1,48 @@
+// Command symbolicate annotates the frames of a Java stack trace with the methods and bytecode
+// ranges of their lines in the classes of a classpath, and reports the frames which do not
+// match the classes. The stack trace is read from a file, or from the standard input.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tk103331/clazz/classpath"
+	"github.com/tk103331/clazz/stacktrace"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: symbolicate classpath [trace]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 && flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), flag.Arg(1)); err != nil {
+		fmt.Fprintf(os.Stderr, "symbolicate: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, tracePath string) error {
+	cp, err := classpath.Parse(path)
+	if err != nil {
+		return err
+	}
+	defer cp.Close()
+	var trace io.Reader = os.Stdin
+	if tracePath != "" {
+		file, err := os.Open(tracePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		trace = file
+	}
+	return stacktrace.Annotate(cp, trace, os.Stdout)
+}
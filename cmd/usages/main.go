@@ -0,0 +1,61 @@
+// Command usages prints the usages of a method, a field, a class or a string literal in the
+// classes of a classpath, as text or JSON. The query is method:owner.name(descriptor),
+// field:owner.name[:descriptor], new:owner or string:literal.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tk103331/clazz/classpath"
+	"github.com/tk103331/clazz/usage"
+)
+
+func main() {
+	format := flag.String("format", "text", "output format: text or json")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: usages [-format text|json] query classpath")
+		fmt.Fprintln(flag.CommandLine.Output(), "query: method:owner.name(descriptor), field:owner.name[:descriptor], new:owner or string:literal")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 || (*format != "text" && *format != "json") {
+		flag.Usage()
+		os.Exit(2)
+	}
+	query, err := usage.ParseQuery(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "usages: %v\n", err)
+		os.Exit(2)
+	}
+	if err := run(query, flag.Arg(1), *format); err != nil {
+		fmt.Fprintf(os.Stderr, "usages: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(query usage.Query, path string, format string) error {
+	cp, err := classpath.Parse(path)
+	if err != nil {
+		return err
+	}
+	defer cp.Close()
+	usages, err := usage.Search(cp, query)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		content, err := json.MarshalIndent(usages, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(string(content))
+		return err
+	}
+	for _, u := range usages {
+		fmt.Println(u)
+	}
+	return nil
+}
@@ -22,13 +22,12 @@ func (dr *DataReader) ReadByte() (byte, error) {
 
 func (dr *DataReader) ReadBytes(length uint32) ([]byte, error) {
 	bytes := make([]byte, length)
-	_, err := dr.r.Read(bytes)
+	_, err := io.ReadFull(dr.r, bytes)
 	return bytes, err
 }
 
-func (dr *DataReader) ReadRune() (rune, error) {
-	r, _, err := dr.r.ReadRune()
-	return r, err
+func (dr *DataReader) ReadRune() (rune, int, error) {
+	return dr.r.ReadRune()
 }
 
 // Read reads a byte.
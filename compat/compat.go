@@ -0,0 +1,470 @@
+// Package compat compares two versions of a library, in the manner of japicmp, and reports the
+// changes of their public API which break the binary or source compatibility of their clients.
+//
+// The API of a library are its public classes, and their public and protected fields and
+// methods, except the protected members of the final classes. The additions are compatible,
+// except the abstract methods added to the interfaces and abstract classes, which break the
+// source compatibility of their implementations. The members moved to a superclass are not
+// removed.
+package compat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/classpath"
+	"github.com/tk103331/clazz/hierarchy"
+)
+
+// Severity is the kind of compatibility broken by a change.
+type Severity int
+
+const (
+	// Binary changes break the clients compiled against the old version, which fail to link or
+	// behave differently with the new version. They generally break the source compatibility
+	// too.
+	Binary Severity = iota
+	// Source changes only break the compilation of the clients against the new version.
+	Source
+)
+
+func (s Severity) String() string {
+	if s == Binary {
+		return "binary"
+	}
+	return "source"
+}
+
+// MarshalText encodes the severity as "binary" or "source".
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Kind is the kind of a change.
+type Kind string
+
+const (
+	ClassRemoved             Kind = "class_removed"
+	ClassVisibilityReduced   Kind = "class_visibility_reduced"
+	ClassKindChanged         Kind = "class_kind_changed"
+	ClassNowFinal            Kind = "class_now_final"
+	ClassNowAbstract         Kind = "class_now_abstract"
+	SuperclassRemoved        Kind = "superclass_removed"
+	InterfaceRemoved         Kind = "interface_removed"
+	FieldRemoved             Kind = "field_removed"
+	FieldTypeChanged         Kind = "field_type_changed"
+	FieldVisibilityReduced   Kind = "field_visibility_reduced"
+	FieldNowFinal            Kind = "field_now_final"
+	FieldStaticChanged       Kind = "field_static_changed"
+	ConstantValueChanged     Kind = "constant_value_changed"
+	MethodRemoved            Kind = "method_removed"
+	MethodReturnTypeChanged  Kind = "method_return_type_changed"
+	MethodVisibilityReduced  Kind = "method_visibility_reduced"
+	MethodNowFinal           Kind = "method_now_final"
+	MethodNowAbstract        Kind = "method_now_abstract"
+	MethodStaticChanged      Kind = "method_static_changed"
+	MethodExceptionAdded     Kind = "method_exception_added"
+	AbstractMethodAdded      Kind = "abstract_method_added"
+	AnnotationElementRemoved Kind = "annotation_element_removed"
+	AnnotationDefaultRemoved Kind = "annotation_default_removed"
+)
+
+// Change is an incompatible change of a class or of one of its members.
+type Change struct {
+	// Class is the internal name of the class.
+	Class string `json:"class"`
+	// Member is the name and descriptor of the field ("name:descriptor") or method
+	// ("name(arguments)return"), or an empty string for a change of the class itself.
+	Member   string   `json:"member,omitempty"`
+	Kind     Kind     `json:"kind"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (c Change) String() string {
+	name := c.Class
+	if c.Member != "" {
+		name += "." + c.Member
+	}
+	return fmt.Sprintf("[%s] %s: %s", c.Severity, name, c.Message)
+}
+
+// Report is the list of the incompatible changes between two versions of a library.
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// Breaking returns true if the report contains a change of the given severity, or of a more
+// severe one.
+func (r *Report) Breaking(severity Severity) bool {
+	for _, change := range r.Changes {
+		if change.Severity <= severity {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the changes, one per line.
+func (r *Report) WriteText(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	for _, change := range r.Changes {
+		fmt.Fprintln(out, change)
+	}
+	return out.Flush()
+}
+
+// version is one of the compared versions of the library.
+type version struct {
+	classes   map[string]*class.Class
+	hierarchy *hierarchy.Hierarchy
+}
+
+func load(cp *classpath.Classpath) (*version, error) {
+	names, err := cp.ClassNames()
+	if err != nil {
+		return nil, err
+	}
+	v := &version{classes: make(map[string]*class.Class), hierarchy: hierarchy.NewWithLoader(cp)}
+	for _, name := range names {
+		if name == "module-info" || name == "package-info" || strings.HasSuffix(name, "/package-info") {
+			continue
+		}
+		c, err := cp.Find(name)
+		if err != nil {
+			return nil, err
+		}
+		v.classes[name] = c
+		v.hierarchy.Add(c)
+	}
+	return v, nil
+}
+
+// Compare returns the incompatible changes between the old and the new version of a library,
+// sorted by class and member.
+func Compare(oldClasspath *classpath.Classpath, newClasspath *classpath.Classpath) (*Report, error) {
+	oldVersion, err := load(oldClasspath)
+	if err != nil {
+		return nil, err
+	}
+	newVersion, err := load(newClasspath)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(oldVersion.classes))
+	for name := range oldVersion.classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c := &comparator{old: oldVersion, new: newVersion, report: &Report{Changes: make([]Change, 0)}}
+	for _, name := range names {
+		c.compareClass(oldVersion.classes[name], newVersion.classes[name])
+	}
+	sort.SliceStable(c.report.Changes, func(i, j int) bool {
+		a, b := c.report.Changes[i], c.report.Changes[j]
+		if a.Class != b.Class {
+			return a.Class < b.Class
+		}
+		return a.Member < b.Member
+	})
+	return c.report, nil
+}
+
+type comparator struct {
+	old    *version
+	new    *version
+	report *Report
+}
+
+func (c *comparator) add(className string, member string, kind Kind, severity Severity, format string, args ...interface{}) {
+	c.report.Changes = append(c.report.Changes, Change{Class: className, Member: member, Kind: kind,
+		Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+func isPublic(accessFlags uint16) bool {
+	return accessFlags&data.ACC_PUBLIC != 0
+}
+
+// isVisible returns true for the public and protected members.
+func isVisible(accessFlags uint16) bool {
+	return accessFlags&(data.ACC_PUBLIC|data.ACC_PROTECTED) != 0
+}
+
+// visibilityText returns the visibility of a member which is neither public nor protected.
+func visibilityText(accessFlags uint16) string {
+	if accessFlags&data.ACC_PRIVATE != 0 {
+		return "private"
+	}
+	return "package-private"
+}
+
+// isAPI returns true for the public members, and for the protected members of the classes which
+// can be extended.
+func isAPI(owner *class.Class, accessFlags uint16) bool {
+	return isPublic(accessFlags) ||
+		accessFlags&data.ACC_PROTECTED != 0 && owner.AccessFlags&data.ACC_FINAL == 0
+}
+
+func classKind(accessFlags uint16) string {
+	switch {
+	case accessFlags&data.ACC_ANNOTATION != 0:
+		return "annotation"
+	case accessFlags&data.ACC_INTERFACE != 0:
+		return "interface"
+	case accessFlags&data.ACC_ENUM != 0:
+		return "enum"
+	default:
+		return "class"
+	}
+}
+
+func added(oldFlags uint16, newFlags uint16, flag uint16) bool {
+	return oldFlags&flag == 0 && newFlags&flag != 0
+}
+
+func (c *comparator) compareClass(oldClass *class.Class, newClass *class.Class) {
+	name := oldClass.ThisClass
+	if !isPublic(oldClass.AccessFlags) {
+		return
+	}
+	if newClass == nil {
+		c.add(name, "", ClassRemoved, Binary, "class removed")
+		return
+	}
+	if !isPublic(newClass.AccessFlags) {
+		c.add(name, "", ClassVisibilityReduced, Binary, "class is no longer public")
+		return
+	}
+	oldFlags, newFlags := oldClass.AccessFlags, newClass.AccessFlags
+	if (oldFlags^newFlags)&data.ACC_INTERFACE != 0 {
+		c.add(name, "", ClassKindChanged, Binary, "%s changed to %s", classKind(oldFlags), classKind(newFlags))
+	}
+	if newFlags&data.ACC_INTERFACE == 0 {
+		if added(oldFlags, newFlags, data.ACC_FINAL) {
+			c.add(name, "", ClassNowFinal, Binary, "class is now final")
+		}
+		if added(oldFlags, newFlags, data.ACC_ABSTRACT) && oldFlags&data.ACC_INTERFACE == 0 {
+			c.add(name, "", ClassNowAbstract, Binary, "class is now abstract")
+		}
+	}
+	newSupertypes := make(map[string]bool)
+	for _, supertype := range c.new.hierarchy.AllSupertypes(name) {
+		newSupertypes[supertype] = true
+	}
+	for _, supertype := range c.old.hierarchy.AllSupertypes(name) {
+		if !newSupertypes[supertype] {
+			if c.old.hierarchy.IsInterface(supertype) {
+				c.add(name, "", InterfaceRemoved, Binary, "interface %s removed", supertype)
+			} else {
+				c.add(name, "", SuperclassRemoved, Binary, "superclass %s removed", supertype)
+			}
+		}
+	}
+	c.compareFields(oldClass, newClass)
+	c.compareMethods(oldClass, newClass)
+}
+
+func fieldName(field *class.Field) string {
+	return field.Name + ":" + field.Descriptor
+}
+
+func (c *comparator) compareFields(oldClass *class.Class, newClass *class.Class) {
+	name := oldClass.ThisClass
+	for i := range oldClass.Fields {
+		oldField := &oldClass.Fields[i]
+		if !isAPI(oldClass, oldField.AccessFlags) || oldField.AccessFlags&data.ACC_SYNTHETIC != 0 {
+			continue
+		}
+		member := fieldName(oldField)
+		var newField *class.Field
+		for j := range newClass.Fields {
+			if newClass.Fields[j].Name == oldField.Name {
+				newField = &newClass.Fields[j]
+			}
+		}
+		if newField == nil || !isAPI(newClass, newField.AccessFlags) {
+			if c.inherited(newClass, func(owner *class.Class) bool {
+				for _, field := range owner.Fields {
+					if field.Name == oldField.Name && field.Descriptor == oldField.Descriptor && isAPI(owner, field.AccessFlags) {
+						return true
+					}
+				}
+				return false
+			}) {
+				continue
+			}
+			if newField == nil {
+				c.add(name, member, FieldRemoved, Binary, "field removed")
+			} else if !isVisible(newField.AccessFlags) {
+				c.add(name, member, FieldVisibilityReduced, Binary, "field is now %s", visibilityText(newField.AccessFlags))
+			}
+			continue
+		}
+		if newField.Descriptor != oldField.Descriptor {
+			c.add(name, member, FieldTypeChanged, Binary, "field type changed to %s", newField.Descriptor)
+			continue
+		}
+		oldFlags, newFlags := oldField.AccessFlags, newField.AccessFlags
+		if isPublic(oldFlags) && !isPublic(newFlags) {
+			c.add(name, member, FieldVisibilityReduced, Binary, "field is no longer public")
+		}
+		if added(oldFlags, newFlags, data.ACC_FINAL) {
+			c.add(name, member, FieldNowFinal, Binary, "field is now final")
+		}
+		if (oldFlags^newFlags)&data.ACC_STATIC != 0 {
+			c.add(name, member, FieldStaticChanged, Binary, "field is %s", staticText(newFlags))
+		}
+		if !sameConstant(oldField.ConstantValue, newField.ConstantValue) {
+			c.add(name, member, ConstantValueChanged, Binary,
+				"constant value changed from %s to %s, the compiled clients keep the old value",
+				constantText(oldField.ConstantValue), constantText(newField.ConstantValue))
+		}
+	}
+}
+
+func staticText(accessFlags uint16) string {
+	if accessFlags&data.ACC_STATIC != 0 {
+		return "now static"
+	}
+	return "no longer static"
+}
+
+// sameConstant returns true if two constant values are equal. The floating-point values are
+// compared by their bits, so that NaN equals NaN, and 0.0 differs from -0.0.
+func sameConstant(a interface{}, b interface{}) bool {
+	switch a := a.(type) {
+	case float32:
+		b, ok := b.(float32)
+		return ok && math.Float32bits(a) == math.Float32bits(b)
+	case float64:
+		b, ok := b.(float64)
+		return ok && math.Float64bits(a) == math.Float64bits(b)
+	}
+	return a == b
+}
+
+func constantText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "none"
+	case string:
+		return fmt.Sprintf("%q", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func methodName(method *class.Method) string {
+	return method.Name + method.Descriptor
+}
+
+// argumentsDescriptor returns the descriptor of a method without its return type.
+func argumentsDescriptor(descriptor string) string {
+	return descriptor[:strings.LastIndexByte(descriptor, ')')+1]
+}
+
+func findMethod(c *class.Class, name string, descriptor string) *class.Method {
+	for i := range c.Methods {
+		if c.Methods[i].Name == name && c.Methods[i].Descriptor == descriptor {
+			return &c.Methods[i]
+		}
+	}
+	return nil
+}
+
+func (c *comparator) compareMethods(oldClass *class.Class, newClass *class.Class) {
+	name := oldClass.ThisClass
+	annotation := oldClass.AccessFlags&data.ACC_ANNOTATION != 0
+	for i := range oldClass.Methods {
+		oldMethod := &oldClass.Methods[i]
+		if !isAPI(oldClass, oldMethod.AccessFlags) || oldMethod.Name == "<clinit>" ||
+			oldMethod.AccessFlags&(data.ACC_SYNTHETIC|data.ACC_BRIDGE) != 0 {
+			continue
+		}
+		member := methodName(oldMethod)
+		newMethod := findMethod(newClass, oldMethod.Name, oldMethod.Descriptor)
+		if newMethod == nil || !isAPI(newClass, newMethod.AccessFlags) {
+			if oldMethod.Name != "<init>" && c.inherited(newClass, func(owner *class.Class) bool {
+				method := findMethod(owner, oldMethod.Name, oldMethod.Descriptor)
+				return method != nil && isAPI(owner, method.AccessFlags)
+			}) {
+				continue
+			}
+			if newMethod != nil {
+				if !isVisible(newMethod.AccessFlags) {
+					c.add(name, member, MethodVisibilityReduced, Binary, "method is now %s", visibilityText(newMethod.AccessFlags))
+				}
+				continue
+			}
+			for j := range newClass.Methods {
+				method := &newClass.Methods[j]
+				if method.Name == oldMethod.Name && isAPI(newClass, method.AccessFlags) &&
+					argumentsDescriptor(method.Descriptor) == argumentsDescriptor(oldMethod.Descriptor) {
+					newMethod = method
+				}
+			}
+			if newMethod != nil {
+				c.add(name, member, MethodReturnTypeChanged, Binary, "return type changed to %s",
+					class.NewMethodType(newMethod.Descriptor).ReturnType().Descriptor())
+				continue
+			}
+			if annotation {
+				c.add(name, member, AnnotationElementRemoved, Binary, "annotation element removed")
+			} else {
+				c.add(name, member, MethodRemoved, Binary, "method removed")
+			}
+			continue
+		}
+		oldFlags, newFlags := oldMethod.AccessFlags, newMethod.AccessFlags
+		if isPublic(oldFlags) && !isPublic(newFlags) {
+			c.add(name, member, MethodVisibilityReduced, Binary, "method is no longer public")
+		}
+		if added(oldFlags, newFlags, data.ACC_FINAL) && newClass.AccessFlags&data.ACC_FINAL == 0 {
+			c.add(name, member, MethodNowFinal, Binary, "method is now final")
+		}
+		if added(oldFlags, newFlags, data.ACC_ABSTRACT) {
+			c.add(name, member, MethodNowAbstract, Binary, "method is now abstract")
+		}
+		if (oldFlags^newFlags)&data.ACC_STATIC != 0 {
+			c.add(name, member, MethodStaticChanged, Binary, "method is %s", staticText(newFlags))
+		}
+		oldExceptions := make(map[string]bool)
+		for _, exception := range oldMethod.Exceptions {
+			oldExceptions[exception] = true
+		}
+		for _, exception := range newMethod.Exceptions {
+			if !oldExceptions[exception] {
+				c.add(name, member, MethodExceptionAdded, Source, "exception %s added", exception)
+			}
+		}
+		if oldMethod.AnnotationDefault != nil && newMethod.AnnotationDefault == nil {
+			c.add(name, member, AnnotationDefaultRemoved, Source, "annotation element default value removed")
+		}
+	}
+	if newClass.AccessFlags&(data.ACC_ABSTRACT|data.ACC_INTERFACE) == 0 || newClass.AccessFlags&data.ACC_FINAL != 0 {
+		return
+	}
+	for i := range newClass.Methods {
+		newMethod := &newClass.Methods[i]
+		if newMethod.AccessFlags&data.ACC_ABSTRACT != 0 && isAPI(newClass, newMethod.AccessFlags) &&
+			findMethod(oldClass, newMethod.Name, newMethod.Descriptor) == nil && !annotation {
+			c.add(name, methodName(newMethod), AbstractMethodAdded, Source, "abstract method added")
+		}
+	}
+}
+
+// inherited returns true if a supertype of a class in the new version matches a predicate.
+func (c *comparator) inherited(newClass *class.Class, match func(owner *class.Class) bool) bool {
+	for _, supertype := range c.new.hierarchy.AllSupertypes(newClass.ThisClass) {
+		if owner := c.new.classes[supertype]; owner != nil && match(owner) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package compat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/internal/testutil"
+	"github.com/tk103331/clazz/tools"
+)
+
+var oldSources = map[string]string{
+	"a/Removed": `.class public super a/Removed
+.super java/lang/Object
+`,
+	"a/Base": `.class public super a/Base
+.super java/lang/Object
+`,
+	"a/Iface": `.class public interface abstract a/Iface
+.super java/lang/Object
+`,
+	"a/Api": `.class public super a/Api
+.super a/Base
+.implements a/Iface
+.field public static final LIMIT I = 5
+.field public static final NAN D = NaNd
+.field public static final ZERO F = 0.0f
+.field public x I
+.field public y I
+.field public z I
+.method public hidden ()V
+    return
+.end method
+.method public m ()V
+    return
+.end method
+.method public r ()I
+    iconst_0
+    ireturn
+.end method
+.method public s ()V
+    return
+.end method
+.method public e ()V
+    return
+.end method
+.method public moved ()V
+    return
+.end method
+.method private internal ()V
+    return
+.end method
+`,
+	"a/Ann": `.class public interface abstract annotation a/Ann
+.super java/lang/Object
+.implements java/lang/annotation/Annotation
+.method public abstract value ()I
+.end method
+.method public abstract name ()Ljava/lang/String;
+    .annotationdefault s "x"
+.end method
+`,
+	"a/Service": `.class public interface abstract a/Service
+.super java/lang/Object
+.method public abstract run ()V
+.end method
+`,
+}
+
+var newSources = map[string]string{
+	"a/Base": `.class public super a/Base
+.super java/lang/Object
+.method public moved ()V
+    return
+.end method
+`,
+	"a/Iface": `.class public super a/Iface
+.super java/lang/Object
+`,
+	"a/Api": `.class public super a/Api
+.super a/Base
+.field public static final LIMIT I = 6
+.field public static final NAN D = NaNd
+.field public static final ZERO F = -0.0f
+.field public x J
+.field private z I
+.method hidden ()V
+    return
+.end method
+.method public static m ()V
+    return
+.end method
+.method public r ()J
+    lconst_0
+    lreturn
+.end method
+.method protected s ()V
+    return
+.end method
+.method public e ()V
+    .throws java/io/IOException
+    return
+.end method
+`,
+	"a/Ann": `.class public interface abstract annotation a/Ann
+.super java/lang/Object
+.implements java/lang/annotation/Annotation
+.method public abstract name ()Ljava/lang/String;
+.end method
+`,
+	"a/Service": `.class public interface abstract a/Service
+.super java/lang/Object
+.method public abstract run ()V
+.end method
+.method public abstract stop ()V
+.end method
+`,
+}
+
+func TestCompare(t *testing.T) {
+	report, err := Compare(testutil.Classpath(t, oldSources), testutil.Classpath(t, newSources))
+	tools.AssertNoErr(t, err)
+	var text strings.Builder
+	tools.AssertNoErr(t, report.WriteText(&text))
+	tools.AssertEqual(t, `[source] a/Ann.name()Ljava/lang/String;: annotation element default value removed
+[binary] a/Ann.value()I: annotation element removed
+[binary] a/Api: interface a/Iface removed
+[binary] a/Api.LIMIT:I: constant value changed from 5 to 6, the compiled clients keep the old value
+[binary] a/Api.ZERO:F: constant value changed from 0 to -0, the compiled clients keep the old value
+[source] a/Api.e()V: exception java/io/IOException added
+[binary] a/Api.hidden()V: method is now package-private
+[binary] a/Api.m()V: method is now static
+[binary] a/Api.r()I: return type changed to J
+[binary] a/Api.s()V: method is no longer public
+[binary] a/Api.x:I: field type changed to J
+[binary] a/Api.y:I: field removed
+[binary] a/Api.z:I: field is now private
+[binary] a/Iface: interface changed to class
+[binary] a/Removed: class removed
+[source] a/Service.stop()V: abstract method added
+`, text.String())
+	tools.AssertEqual(t, true, report.Breaking(Binary))
+
+	content, err := json.Marshal(report.Changes[1])
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, `{"class":"a/Ann","member":"value()I","kind":"annotation_element_removed",`+
+		`"severity":"binary","message":"annotation element removed"}`, string(content))
+
+	report, err = Compare(testutil.Classpath(t, oldSources), testutil.Classpath(t, oldSources))
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, 0, len(report.Changes))
+	tools.AssertEqual(t, false, report.Breaking(Source))
+}
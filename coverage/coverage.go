@@ -0,0 +1,477 @@
+// Package coverage instruments classes for code coverage, like the offline instrumentation of
+// JaCoCo, without a JVM agent. Probes are inserted at the edges of the control flow of the
+// methods: each probe sets an element of a boolean array of the class when it is executed.
+//
+// The probe array is created by a synthetic static method of the class, which every
+// instrumented method calls first, and is stored in a synthetic static field. The layout of
+// the probes, with the ID of each class, is returned to interpret the arrays once the
+// instrumented code has run.
+//
+// The stack map frames of the methods are kept, with the probe array as a new local variable
+// after the arguments, and are rewritten as full frames: the instrumented classes are valid
+// without a frame computation, and thus without the class hierarchy.
+package coverage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc64"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+const (
+	// DataField is the name of the synthetic static field of the probe array.
+	DataField = "$clazzData"
+	// InitMethod is the name of the synthetic static method which returns the probe array, and
+	// creates it on its first call.
+	InitMethod = "$clazzInit"
+	// RuntimeMethod is the name of the static method of Options.Runtime which returns the
+	// probe array of a class.
+	RuntimeMethod = "getProbes"
+	// RuntimeDescriptor is the descriptor of RuntimeMethod, whose arguments are the ID, the
+	// internal name and the probe count of a class.
+	RuntimeDescriptor = "(JLjava/lang/String;I)[Z"
+)
+
+// Options are the options of the instrumentation.
+type Options struct {
+	// Runtime is the internal name of a class with a RuntimeMethod static method, which returns
+	// the probe arrays of the instrumented classes, for example to register them and dump them
+	// at the end of the execution. The classes create their own probe array if it is empty.
+	Runtime string
+}
+
+// Layout is the layout of the probes of the instrumented classes.
+type Layout struct {
+	Classes []ClassLayout `json:"classes"`
+}
+
+// ClassLayout is the layout of the probe array of a class.
+type ClassLayout struct {
+	// Name is the internal name of the class.
+	Name string `json:"name"`
+	// ID is the CRC64 checksum of the original bytes of the class, computed like the class IDs
+	// of JaCoCo.
+	ID         uint64         `json:"id,string"`
+	ProbeCount int            `json:"probeCount"`
+	Methods    []MethodLayout `json:"methods,omitempty"`
+}
+
+// MethodLayout is the range of the probes of a method in the probe array of its class.
+type MethodLayout struct {
+	Name       string `json:"name"`
+	Descriptor string `json:"descriptor"`
+	FirstProbe int    `json:"firstProbe"`
+	ProbeCount int    `json:"probeCount"`
+	// Lines are the source lines of the probes, or 0 for the probes without line number.
+	Lines []int `json:"lines,omitempty"`
+}
+
+// ErrInstrumented is returned for a class which is already instrumented.
+var ErrInstrumented = errors.New("class already instrumented")
+
+var crcTable = crc64.MakeTable(crc64.ISO)
+
+// ClassID returns the ID of the bytes of a class, the CRC64 checksum used by JaCoCo, which has
+// no initial and final complement, unlike crc64.Checksum.
+func ClassID(content []byte) uint64 {
+	var sum uint64
+	for _, b := range content {
+		sum = crcTable[byte(sum)^b] ^ (sum >> 8)
+	}
+	return sum
+}
+
+// InstrumentClass instruments the bytes of a class, and returns the instrumented bytes with the
+// layout of their probes. The interfaces, the modules and the classes without code are not
+// instrumented, and are returned unchanged with a layout without probes. The methods which use
+// JSR and RET instructions are not instrumented.
+func InstrumentClass(content []byte, options *Options) ([]byte, *ClassLayout, error) {
+	reader := class.NewReader(bytes.NewReader(content))
+	if err := reader.Read(); err != nil {
+		return nil, nil, err
+	}
+	c := reader.Class()
+	layout := &ClassLayout{Name: c.ThisClass, ID: ClassID(content)}
+	if c.AccessFlags&(data.ACC_INTERFACE|data.ACC_MODULE) != 0 {
+		return content, layout, nil
+	}
+	for _, field := range c.Fields {
+		if field.Name == DataField {
+			return nil, nil, ErrInstrumented
+		}
+	}
+	if options == nil {
+		options = &Options{}
+	}
+	in := &instrumenter{owner: c.ThisClass, frames: c.Version&0xffff >= 50}
+	for i := range c.Methods {
+		method, err := in.instrumentMethod(&c.Methods[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s.%s%s: %v", c.ThisClass, c.Methods[i].Name, c.Methods[i].Descriptor, err)
+		}
+		if method.ProbeCount > 0 {
+			layout.Methods = append(layout.Methods, method)
+		}
+	}
+	layout.ProbeCount = in.probeCount
+	if layout.ProbeCount == 0 {
+		return content, layout, nil
+	}
+	c.Fields = append(c.Fields, class.Field{Name: DataField, Descriptor: "[Z",
+		AccessFlags: data.ACC_PRIVATE | data.ACC_STATIC | data.ACC_TRANSIENT | data.ACC_SYNTHETIC})
+	init, err := in.initMethod(layout, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.Methods = append(c.Methods, *init)
+	writer := class.NewWriter()
+	c.Accept(writer)
+	instrumented, err := writer.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	return instrumented, layout, nil
+}
+
+// instrumenter inserts the probes in the methods of a class.
+type instrumenter struct {
+	owner string
+	// frames is true if the class has a version of 50 or more, with stack map frames.
+	frames     bool
+	probeCount int
+}
+
+// initMethod returns the method which returns the probe array of the class, and creates it on
+// its first call.
+func (in *instrumenter) initMethod(layout *ClassLayout, options *Options) (*class.Method, error) {
+	method := &class.Method{Name: InitMethod, Descriptor: "()[Z",
+		AccessFlags: data.ACC_PRIVATE | data.ACC_STATIC | data.ACC_SYNTHETIC}
+	initialized := class.NewLabel()
+	instructions := []class.Instruction{
+		&class.FieldInstruction{Op: data.GETSTATIC, Owner: in.owner, Name: DataField, Descriptor: "[Z"},
+		&class.CodeInstruction{Op: data.DUP},
+		&class.JumpInstruction{Op: data.IFNONNULL, Label: initialized},
+		&class.CodeInstruction{Op: data.POP},
+	}
+	if options.Runtime != "" {
+		instructions = append(instructions,
+			&class.LdcInstruction{Value: int64(layout.ID)},
+			&class.LdcInstruction{Value: in.owner},
+			pushInt(layout.ProbeCount),
+			&class.MethodInstruction{Op: data.INVOKESTATIC, Owner: options.Runtime, Name: RuntimeMethod, Descriptor: RuntimeDescriptor})
+	} else {
+		instructions = append(instructions, pushInt(layout.ProbeCount), &class.IntInstruction{Op: data.NEWARRAY, Operand: data.T_BOOLEAN})
+	}
+	instructions = append(instructions,
+		&class.CodeInstruction{Op: data.DUP},
+		&class.FieldInstruction{Op: data.PUTSTATIC, Owner: in.owner, Name: DataField, Descriptor: "[Z"},
+		initialized,
+		&class.CodeInstruction{Op: data.ARETURN})
+	method.Code.Instructions = instructions
+	var err error
+	if in.frames {
+		err = class.ComputeFrames(in.owner, method, nil)
+	} else {
+		err = class.ComputeMaxs(in.owner, method)
+	}
+	return method, err
+}
+
+// instrumentMethod inserts the probes of a method: before the return and athrow instructions,
+// before the goto instructions, on the edges of the conditional jumps and of the switches, and
+// before the jump targets reached from the previous instruction. The probes of the jump and
+// switch edges are in trampolines at the end of the method, which jump to the original
+// targets, with their frames.
+func (in *instrumenter) instrumentMethod(method *class.Method) (MethodLayout, error) {
+	layout := MethodLayout{Name: method.Name, Descriptor: method.Descriptor, FirstProbe: in.probeCount}
+	code := &method.Code
+	if len(code.Instructions) == 0 || hasSubroutines(code.Instructions) {
+		return layout, nil
+	}
+	probeLocal := argumentsSize(method)
+	frames, labelFrames, err := in.expandFrames(method, probeLocal)
+	if err != nil {
+		return layout, err
+	}
+	line := 0
+	probe := func() []class.Instruction {
+		layout.Lines = append(layout.Lines, line)
+		layout.ProbeCount++
+		in.probeCount++
+		return []class.Instruction{
+			&class.VarInstruction{Op: data.ALOAD, Var: probeLocal},
+			pushInt(in.probeCount - 1),
+			&class.CodeInstruction{Op: data.ICONST_1},
+			&class.CodeInstruction{Op: data.BASTORE},
+		}
+	}
+	var trampolines []class.Instruction
+	trampoline := func(target *class.Label) (*class.Label, error) {
+		label := class.NewLabel()
+		trampolines = append(trampolines, label)
+		if frame := labelFrames[target]; frame != nil {
+			trampolines = append(trampolines, copyFrame(frame))
+		} else if in.frames && len(frames) > 0 {
+			return nil, errors.New("missing frame at a jump target")
+		}
+		trampolines = append(trampolines, probe()...)
+		trampolines = append(trampolines, &class.JumpInstruction{Op: data.GOTO, Label: target})
+		return label, nil
+	}
+
+	targets := jumpTargets(code.Instructions)
+	instructions := []class.Instruction{
+		&class.MethodInstruction{Op: data.INVOKESTATIC, Owner: in.owner, Name: InitMethod, Descriptor: "()[Z"},
+		&class.VarInstruction{Op: data.ASTORE, Var: probeLocal},
+	}
+	// pending is true if the previous instructions fall through to the next one, and are not
+	// covered by a probe yet.
+	pending := false
+	for _, instruction := range code.Instructions {
+		switch insn := instruction.(type) {
+		case *class.Label:
+			if targets[insn] && pending {
+				instructions = append(instructions, probe()...)
+				pending = false
+			}
+		case *class.LineNumber:
+			line = insn.Line
+		case *class.Frame:
+			instruction = frames[insn]
+		case *class.VarInstruction:
+			if insn.Var >= probeLocal {
+				insn.Var++
+			}
+			pending = true
+		case *class.IincInstruction:
+			if insn.Var >= probeLocal {
+				insn.Var++
+			}
+			pending = true
+		case *class.JumpInstruction:
+			if insn.Op == data.GOTO || insn.Op == data.GOTO_W {
+				instructions = append(instructions, probe()...)
+				pending = false
+				break
+			}
+			if insn.Label, err = trampoline(insn.Label); err != nil {
+				return layout, err
+			}
+			pending = true
+		case *class.TableSwitchInstruction:
+			if err := switchTrampolines(&insn.Default, insn.Labels, trampoline); err != nil {
+				return layout, err
+			}
+			pending = false
+		case *class.LookupSwitchInstruction:
+			if err := switchTrampolines(&insn.Default, insn.Labels, trampoline); err != nil {
+				return layout, err
+			}
+			pending = false
+		default:
+			if op := instruction.OpCode(); op >= data.IRETURN && op <= data.RETURN || op == data.ATHROW {
+				instructions = append(instructions, probe()...)
+				pending = false
+			} else {
+				pending = true
+			}
+		}
+		instructions = append(instructions, instruction)
+	}
+	code.Instructions = append(instructions, trampolines...)
+	for i := range code.LocalVariables {
+		if code.LocalVariables[i].Index >= probeLocal {
+			code.LocalVariables[i].Index++
+		}
+	}
+	// A probe pushes three values on the operand stack.
+	code.MaxStack += 3
+	if int(code.MaxLocal) < probeLocal {
+		code.MaxLocal = uint16(probeLocal)
+	}
+	code.MaxLocal++
+	return layout, nil
+}
+
+// switchTrampolines replaces the targets of a switch with trampolines, one per distinct target.
+func switchTrampolines(defaultLabel **class.Label, labels []*class.Label, trampoline func(*class.Label) (*class.Label, error)) error {
+	trampolines := make(map[*class.Label]*class.Label)
+	replace := func(label *class.Label) (*class.Label, error) {
+		if t, ok := trampolines[label]; ok {
+			return t, nil
+		}
+		t, err := trampoline(label)
+		trampolines[label] = t
+		return t, err
+	}
+	var err error
+	if *defaultLabel, err = replace(*defaultLabel); err != nil {
+		return err
+	}
+	for i := range labels {
+		if labels[i], err = replace(labels[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandFrames returns the frames of a method as full frames, with the probe array inserted in
+// the locals at probeLocal, and the frames of the labels which precede them.
+func (in *instrumenter) expandFrames(method *class.Method, probeLocal int) (map[*class.Frame]*class.Frame, map[*class.Label]*class.Frame, error) {
+	frames := make(map[*class.Frame]*class.Frame)
+	labelFrames := make(map[*class.Label]*class.Frame)
+	locals := in.initialLocals(method)
+	var labels []*class.Label
+	for _, instruction := range method.Code.Instructions {
+		switch insn := instruction.(type) {
+		case *class.Label:
+			labels = append(labels, insn)
+			continue
+		case *class.LineNumber:
+			continue
+		case *class.Frame:
+			switch insn.Type {
+			case data.F_SAME, data.F_SAME1:
+			case data.F_APPEND:
+				locals = append(locals[:len(locals):len(locals)], insn.Locals...)
+			case data.F_CHOP:
+				if len(insn.Locals) > len(locals) {
+					return nil, nil, errors.New("invalid chop frame")
+				}
+				locals = locals[:len(locals)-len(insn.Locals)]
+			default:
+				locals = insn.Locals
+			}
+			expanded, ok := insertLocal(locals, probeLocal, "[Z")
+			if !ok {
+				return nil, nil, errors.New("an argument slot is overwritten by a long or double value")
+			}
+			frame := &class.Frame{Type: data.F_FULL, Locals: expanded, Stack: append([]interface{}{}, insn.Stack...)}
+			frames[insn] = frame
+			for _, label := range labels {
+				labelFrames[label] = frame
+			}
+		}
+		labels = labels[:0]
+	}
+	return frames, labelFrames, nil
+}
+
+// initialLocals returns the locals of the implicit frame at the beginning of a method, with one
+// value for the long and double arguments.
+func (in *instrumenter) initialLocals(method *class.Method) []interface{} {
+	var locals []interface{}
+	if method.AccessFlags&data.ACC_STATIC == 0 {
+		if method.Name == "<init>" && in.owner != "java/lang/Object" {
+			locals = append(locals, data.ITEM_UNINITIALIZED_THIS)
+		} else {
+			locals = append(locals, in.owner)
+		}
+	}
+	for _, argument := range class.NewMethodType(method.Descriptor).ArgumentTypes() {
+		switch argument.Sort() {
+		case data.TYPE_SORT_BOOLEAN, data.TYPE_SORT_HAR, data.TYPE_SORT_BYTE, data.TYPE_SORT_SHORT, data.TYPE_SORT_INT:
+			locals = append(locals, data.ITEM_INTEGER)
+		case data.TYPE_SORT_FLOAT:
+			locals = append(locals, data.ITEM_FLOAT)
+		case data.TYPE_SORT_LONG:
+			locals = append(locals, data.ITEM_LONG)
+		case data.TYPE_SORT_DOUBLE:
+			locals = append(locals, data.ITEM_DOUBLE)
+		default:
+			locals = append(locals, argument.InternalName())
+		}
+	}
+	return locals
+}
+
+// insertLocal inserts a value at the given slot of the locals of a frame, which have one value
+// for the long and double values, and pads them with top values if they are shorter. It
+// returns false if a long or double value uses the slot.
+func insertLocal(locals []interface{}, slot int, value interface{}) ([]interface{}, bool) {
+	result := make([]interface{}, 0, len(locals)+1)
+	i, size := 0, 0
+	for ; i < len(locals) && size < slot; i++ {
+		result = append(result, locals[i])
+		size++
+		if locals[i] == data.ITEM_LONG || locals[i] == data.ITEM_DOUBLE {
+			size++
+		}
+	}
+	if size > slot {
+		return nil, false
+	}
+	for ; size < slot; size++ {
+		result = append(result, data.ITEM_TOP)
+	}
+	result = append(result, value)
+	return append(result, locals[i:]...), true
+}
+
+func copyFrame(frame *class.Frame) *class.Frame {
+	return &class.Frame{Type: frame.Type, Locals: append([]interface{}{}, frame.Locals...),
+		Stack: append([]interface{}{}, frame.Stack...)}
+}
+
+// argumentsSize returns the number of local variable slots of the receiver and of the
+// arguments of a method.
+func argumentsSize(method *class.Method) int {
+	size := 0
+	if method.AccessFlags&data.ACC_STATIC == 0 {
+		size++
+	}
+	for _, argument := range class.NewMethodType(method.Descriptor).ArgumentTypes() {
+		size += argument.Size()
+	}
+	return size
+}
+
+// jumpTargets returns the labels which are the targets of jumps or switches.
+func jumpTargets(instructions []class.Instruction) map[*class.Label]bool {
+	targets := make(map[*class.Label]bool)
+	for _, instruction := range instructions {
+		switch insn := instruction.(type) {
+		case *class.JumpInstruction:
+			targets[insn.Label] = true
+		case *class.TableSwitchInstruction:
+			targets[insn.Default] = true
+			for _, label := range insn.Labels {
+				targets[label] = true
+			}
+		case *class.LookupSwitchInstruction:
+			targets[insn.Default] = true
+			for _, label := range insn.Labels {
+				targets[label] = true
+			}
+		}
+	}
+	return targets
+}
+
+func hasSubroutines(instructions []class.Instruction) bool {
+	for _, instruction := range instructions {
+		if op := instruction.OpCode(); op == data.JSR || op == data.JSR_W || op == data.RET {
+			return true
+		}
+	}
+	return false
+}
+
+// pushInt returns the instruction which pushes an int constant.
+func pushInt(value int) class.Instruction {
+	switch {
+	case value >= -1 && value <= 5:
+		return &class.CodeInstruction{Op: uint16(data.ICONST_0 + value)}
+	case value >= -128 && value <= 127:
+		return &class.IntInstruction{Op: data.BIPUSH, Operand: int32(value)}
+	case value >= -32768 && value <= 32767:
+		return &class.IntInstruction{Op: data.SIPUSH, Operand: int32(value)}
+	default:
+		return &class.LdcInstruction{Value: int32(value)}
+	}
+}
@@ -0,0 +1,217 @@
+package coverage
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/classpath"
+	"github.com/tk103331/clazz/internal/testutil"
+	"github.com/tk103331/clazz/interp"
+	"github.com/tk103331/clazz/tools"
+	"github.com/tk103331/clazz/verifier"
+)
+
+const covSource = `.class public super a/Cov
+.super java/lang/Object
+.method public static sign (I)I
+L0:
+    .line 3
+    iload_0
+    ifle L1
+L3:
+    .line 4
+    iconst_1
+    ireturn
+L1:
+    .line 5
+    iload_0
+    ifge L2
+    iconst_m1
+    ireturn
+L2:
+    .line 6
+    iconst_0
+    ireturn
+.end method
+.method public total (J[I)J
+    lload_1
+    lstore 5
+    iconst_0
+    istore 4
+L0:
+    iload 4
+    aload_3
+    arraylength
+    if_icmpge L1
+    lload 5
+    aload_3
+    iload 4
+    iaload
+    i2l
+    ladd
+    lstore 5
+    iinc 4 1
+    goto L0
+L1:
+    lload 5
+    lreturn
+.end method
+.method public static pick (I)I
+    iload_0
+    tableswitch 0 1
+        L0
+        L0
+        default : L1
+L0:
+    iconst_1
+    ireturn
+L1:
+    iconst_0
+    ireturn
+.end method
+`
+
+const runtimeSource = `.class public super a/Runtime
+.super java/lang/Object
+.field public static last [Z
+.method public static getProbes (JLjava/lang/String;I)[Z
+    iload_3
+    newarray boolean
+    dup
+    putstatic a/Runtime last [Z
+    areturn
+.end method
+`
+
+func instrument(t *testing.T, options *Options) ([]byte, *ClassLayout) {
+	content := testutil.Assemble(t, covSource)
+	instrumented, layout, err := InstrumentClass(content, options)
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, ClassID(content), layout.ID)
+	return instrumented, layout
+}
+
+func newInterpreter(t *testing.T, instrumented []byte) *interp.Interpreter {
+	source := classpath.NewMemorySource()
+	source.Add("a/Cov", instrumented)
+	source.Add("a/Runtime", testutil.Assemble(t, runtimeSource))
+	cp := classpath.New()
+	cp.Add(source)
+	return interp.New(cp)
+}
+
+// probes returns the indexes of the probes which have been executed.
+func probes(t *testing.T, in *interp.Interpreter) []int {
+	value, err := in.StaticField("a/Cov", DataField)
+	tools.AssertNoErr(t, err)
+	var executed []int
+	for i, element := range value.(*interp.Array).Elements {
+		if element != int32(0) {
+			executed = append(executed, i)
+		}
+	}
+	return executed
+}
+
+func TestClassID(t *testing.T) {
+	tools.AssertEqual(t, uint64(0), ClassID(nil))
+	tools.AssertEqual(t, uint64(0xd800000000000000), ClassID([]byte{0x80}))
+}
+
+func TestInstrumentClass(t *testing.T) {
+	instrumented, layout := instrument(t, nil)
+	tools.AssertEqual(t, "a/Cov", layout.Name)
+	tools.AssertEqual(t, 13, layout.ProbeCount)
+	tools.AssertEqual(t, 3, len(layout.Methods))
+	sign := layout.Methods[0]
+	tools.AssertEqual(t, "sign", sign.Name)
+	tools.AssertEqual(t, 0, sign.FirstProbe)
+	tools.AssertEqual(t, 5, sign.ProbeCount)
+	tools.AssertEqual(t, "[3 4 5 5 6]", fmt.Sprint(sign.Lines))
+	tools.AssertEqual(t, "(J[I)J", layout.Methods[1].Descriptor)
+	tools.AssertEqual(t, 5, layout.Methods[1].FirstProbe)
+	tools.AssertEqual(t, "[0 0 0 0]", fmt.Sprint(layout.Methods[1].Lines))
+	tools.AssertEqual(t, 9, layout.Methods[2].FirstProbe)
+
+	reader := class.NewReader(bytes.NewReader(instrumented))
+	tools.AssertNoErr(t, reader.Read())
+	c := reader.Class()
+	tools.AssertEqual(t, DataField, c.Fields[len(c.Fields)-1].Name)
+	tools.AssertEqual(t, InitMethod, c.Methods[len(c.Methods)-1].Name)
+	tools.AssertEqual(t, 0, len(verifier.New(nil).Verify(c)))
+
+	_, _, err := InstrumentClass(instrumented, nil)
+	tools.AssertEqual(t, ErrInstrumented, err)
+}
+
+func TestProbes(t *testing.T) {
+	instrumented, _ := instrument(t, nil)
+	for _, test := range []struct {
+		value  int32
+		result int32
+		probes string
+	}{{5, 1, "[1]"}, {-5, -1, "[0 3]"}, {0, 0, "[0 2 4]"}} {
+		in := newInterpreter(t, instrumented)
+		result, err := in.Invoke("a/Cov", "sign", "(I)I", test.value)
+		tools.AssertNoErr(t, err)
+		tools.AssertEqual(t, test.result, result)
+		tools.AssertEqual(t, test.probes, fmt.Sprint(probes(t, in)))
+	}
+
+	in := newInterpreter(t, instrumented)
+	array := &interp.Array{Descriptor: "[I", Elements: []interp.Value{int32(1), int32(2), int32(3)}}
+	result, err := in.Invoke("a/Cov", "total", "(J[I)J", in.NewObject("a/Cov"), int64(10), array)
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, int64(16), result)
+	tools.AssertEqual(t, "[5 6 7 8]", fmt.Sprint(probes(t, in)))
+
+	in = newInterpreter(t, instrumented)
+	for _, value := range []int32{1, 7} {
+		_, err = in.Invoke("a/Cov", "pick", "(I)I", value)
+		tools.AssertNoErr(t, err)
+	}
+	tools.AssertEqual(t, "[9 10 11 12]", fmt.Sprint(probes(t, in)))
+}
+
+func TestRuntime(t *testing.T) {
+	instrumented, _ := instrument(t, &Options{Runtime: "a/Runtime"})
+	in := newInterpreter(t, instrumented)
+	_, err := in.Invoke("a/Cov", "pick", "(I)I", int32(0))
+	tools.AssertNoErr(t, err)
+	last, err := in.StaticField("a/Runtime", "last")
+	tools.AssertNoErr(t, err)
+	probes, err := in.StaticField("a/Cov", DataField)
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, last, probes)
+	tools.AssertEqual(t, 13, len(last.(*interp.Array).Elements))
+}
+
+func TestInstrumentJAR(t *testing.T) {
+	var input bytes.Buffer
+	zipWriter := zip.NewWriter(&input)
+	for name, content := range map[string][]byte{
+		"a/Cov.class":          testutil.Assemble(t, covSource),
+		"a/Runtime.class":      testutil.Assemble(t, runtimeSource),
+		"META-INF/SIGNER.SF":   []byte("Signature-Version: 1.0\n"),
+		"META-INF/MANIFEST.MF": []byte("Manifest-Version: 1.0\n"),
+	} {
+		entry, err := zipWriter.Create(name)
+		tools.AssertNoErr(t, err)
+		_, err = entry.Write(content)
+		tools.AssertNoErr(t, err)
+	}
+	tools.AssertNoErr(t, zipWriter.Close())
+	reader, err := zip.NewReader(bytes.NewReader(input.Bytes()), int64(input.Len()))
+	tools.AssertNoErr(t, err)
+
+	var output bytes.Buffer
+	layout, err := InstrumentJAR(reader, &output, nil)
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, 2, len(layout.Classes))
+	result, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, 3, len(result.File))
+}
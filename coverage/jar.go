@@ -0,0 +1,88 @@
+package coverage
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// InstrumentJAR copies the JAR read by reader to writer, instrumenting its classes, and returns
+// the layout of the classes which have probes.
+// The signature files are not copied, since they do not match the instrumented classes.
+func InstrumentJAR(reader *zip.Reader, writer io.Writer, options *Options) (*Layout, error) {
+	layout := &Layout{}
+	zipWriter := zip.NewWriter(writer)
+	for _, file := range reader.File {
+		if isSignatureFile(file.Name) {
+			continue
+		}
+		content, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(file.Name, ".class") && !file.FileInfo().IsDir() {
+			instrumented, classLayout, err := InstrumentClass(content, options)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", file.Name, err)
+			}
+			content = instrumented
+			if classLayout.ProbeCount > 0 {
+				layout.Classes = append(layout.Classes, *classLayout)
+			}
+		}
+
+		header := file.FileHeader
+		entry, err := zipWriter.CreateHeader(&header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := entry.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	return layout, zipWriter.Close()
+}
+
+// InstrumentFile instruments the JAR file input, writes the result to the JAR file output, and
+// returns the layout of the classes which have probes.
+func InstrumentFile(input string, output string, options *Options) (*Layout, error) {
+	reader, err := zip.OpenReader(input)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	f, err := os.Create(output)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := InstrumentJAR(&reader.Reader, f, options)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return layout, f.Close()
+}
+
+func readFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+func isSignatureFile(name string) bool {
+	if !strings.HasPrefix(name, "META-INF/") || strings.Count(name, "/") != 1 {
+		return false
+	}
+	for _, suffix := range []string{".SF", ".DSA", ".RSA", ".EC"} {
+		if strings.HasSuffix(strings.ToUpper(name), suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+// Package deps analyzes the static dependencies of the classes of a classpath, in the manner of
+// the jdeps tool. The class dependencies are aggregated at the package, archive and module
+// levels, and reported in the DOT format or in the text format of "jdeps -verbose".
+//
+// The archive of a class is the base name of the directory or JAR file of the classpath which
+// contains it, and its module is the one declared by the module-info class of this archive, or
+// the archive name itself. The JDK classes, which are not in the classpath, are in the "JDK"
+// archive and module, and the other missing classes are "not found".
+package deps
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/classpath"
+)
+
+const (
+	// JDK is the archive and module of the JDK classes which are not in the classpath.
+	JDK = "JDK"
+	// NotFound is the archive and module of the other classes which are not in the classpath.
+	NotFound = "not found"
+)
+
+var jdkPackages = []string{"java/", "javax/", "jdk/", "sun/", "com/sun/"}
+
+// Level is the granularity of the dependencies.
+type Level int
+
+const (
+	Class Level = iota
+	Package
+	Archive
+	Module
+)
+
+// Options are the filters of the dependencies.
+type Options struct {
+	// ExcludeJDK excludes the dependencies on the JDK classes.
+	ExcludeJDK bool
+	// ExcludeSamePackage excludes the dependencies between the classes of a package.
+	ExcludeSamePackage bool
+	// ExcludeSameArchive excludes the dependencies between the classes of an archive.
+	ExcludeSameArchive bool
+}
+
+// Dependency is a reference from a class of the classpath to another class, designated by
+// their internal names.
+type Dependency struct {
+	From string
+	To   string
+}
+
+// Edge is a dependency between two nodes of a level, which aggregates Count class dependencies.
+type Edge struct {
+	From  string
+	To    string
+	Count int
+}
+
+// Graph is the dependency graph of the classes of a classpath.
+type Graph struct {
+	// Dependencies are the class dependencies, sorted.
+	Dependencies []Dependency
+	// archives are the archives of the classes of the classpath.
+	archives map[string]string
+	// modules are the modules of the archives.
+	modules map[string]string
+}
+
+// IsJDK returns true for the classes of the JDK packages: java, javax, jdk, sun and com.sun.
+func IsJDK(internalName string) bool {
+	for _, prefix := range jdkPackages {
+		if strings.HasPrefix(internalName, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Analyze returns the dependencies of the classes of a classpath. When several sources contain
+// a class, the first one wins.
+func Analyze(cp *classpath.Classpath, options Options) (*Graph, error) {
+	g := &Graph{archives: make(map[string]string), modules: make(map[string]string)}
+	var names []string
+	for _, source := range cp.Sources() {
+		archive := archiveName(source)
+		g.modules[archive] = archive
+		sourceNames, err := source.ClassNames()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range sourceNames {
+			if name == "module-info" {
+				module, err := readModule(source)
+				if err != nil {
+					return nil, err
+				}
+				if module != "" {
+					g.modules[archive] = module
+				}
+				continue
+			}
+			if _, ok := g.archives[name]; !ok {
+				g.archives[name] = archive
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c, err := cp.Find(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, reference := range References(c) {
+			if options.ExcludeJDK && IsJDK(reference) ||
+				options.ExcludeSamePackage && packageName(name) == packageName(reference) ||
+				options.ExcludeSameArchive && g.Archive(name) == g.Archive(reference) {
+				continue
+			}
+			g.Dependencies = append(g.Dependencies, Dependency{From: name, To: reference})
+		}
+	}
+	return g, nil
+}
+
+func archiveName(source classpath.Source) string {
+	switch s := source.(type) {
+	case *classpath.ArchiveSource:
+		return filepath.Base(s.Name)
+	case *classpath.DirectorySource:
+		return filepath.Base(s.Dir)
+	case *classpath.MemorySource:
+		return "memory"
+	default:
+		return fmt.Sprintf("%T", source)
+	}
+}
+
+// readModule returns the name of the module declared by the module-info class of a source.
+func readModule(source classpath.Source) (string, error) {
+	content, err := source.ReadClass("module-info")
+	if err != nil {
+		return "", err
+	}
+	reader := class.NewReader(bytes.NewReader(content))
+	if err := reader.Read(); err != nil {
+		return "", fmt.Errorf("module-info: %v", err)
+	}
+	return reader.Class().Module.Name, nil
+}
+
+func packageName(internalName string) string {
+	if index := strings.LastIndexByte(internalName, '/'); index >= 0 {
+		return internalName[:index]
+	}
+	return ""
+}
+
+// Archive returns the archive of a class.
+func (g *Graph) Archive(internalName string) string {
+	if archive, ok := g.archives[internalName]; ok {
+		return archive
+	}
+	if IsJDK(internalName) {
+		return JDK
+	}
+	return NotFound
+}
+
+// Module returns the module of a class.
+func (g *Graph) Module(internalName string) string {
+	archive := g.Archive(internalName)
+	if module, ok := g.modules[archive]; ok {
+		return module
+	}
+	return archive
+}
+
+// Node returns the name of the node of a class at a level: the class or package name with dots,
+// "<unnamed>" for the unnamed package, or the archive or module name.
+func (g *Graph) Node(internalName string, level Level) string {
+	switch level {
+	case Package:
+		if name := packageName(internalName); name != "" {
+			return strings.ReplaceAll(name, "/", ".")
+		}
+		return "<unnamed>"
+	case Archive:
+		return g.Archive(internalName)
+	case Module:
+		return g.Module(internalName)
+	default:
+		return strings.ReplaceAll(internalName, "/", ".")
+	}
+}
+
+// Edges returns the dependencies between the nodes of a level, sorted.
+func (g *Graph) Edges(level Level) []Edge {
+	counts := make(map[Edge]int)
+	for _, dependency := range g.Dependencies {
+		counts[Edge{From: g.Node(dependency.From, level), To: g.Node(dependency.To, level)}]++
+	}
+	edges := make([]Edge, 0, len(counts))
+	for edge, count := range counts {
+		edge.Count = count
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// WriteDOT writes the dependencies between the nodes of a level in the Graphviz DOT language.
+func (g *Graph) WriteDOT(w io.Writer, level Level) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph dependencies {")
+	for _, edge := range g.Edges(level) {
+		fmt.Fprintf(out, "  %s -> %s;\n", strconv.Quote(edge.From), strconv.Quote(edge.To))
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// WriteReport writes the dependencies in the text format of jdeps. For each archive, or module
+// at the module level, the archives or modules it depends on are listed, followed at the class
+// and package levels by the dependencies of its classes or packages, with the archive of their
+// targets, as with "jdeps -verbose:class" and "jdeps -verbose:package".
+func (g *Graph) WriteReport(w io.Writer, level Level) error {
+	out := bufio.NewWriter(w)
+	type detail struct {
+		from, to, archive string
+	}
+	summaryLevel := Archive
+	if level == Module {
+		summaryLevel = Module
+	}
+	summaries := make(map[string]map[string]bool)
+	details := make(map[string]map[detail]bool)
+	for _, dependency := range g.Dependencies {
+		archive := g.Node(dependency.From, summaryLevel)
+		if summaries[archive] == nil {
+			summaries[archive] = make(map[string]bool)
+			details[archive] = make(map[detail]bool)
+		}
+		summaries[archive][g.Node(dependency.To, summaryLevel)] = true
+		if level == Class || level == Package {
+			details[archive][detail{g.Node(dependency.From, level), g.Node(dependency.To, level), g.Archive(dependency.To)}] = true
+		}
+	}
+	archives := make([]string, 0, len(summaries))
+	for archive := range summaries {
+		archives = append(archives, archive)
+	}
+	sort.Strings(archives)
+	for _, archive := range archives {
+		for _, target := range sortedKeys(summaries[archive]) {
+			fmt.Fprintf(out, "%s -> %s\n", archive, target)
+		}
+		lines := make([]detail, 0, len(details[archive]))
+		for line := range details[archive] {
+			lines = append(lines, line)
+		}
+		sort.Slice(lines, func(i, j int) bool {
+			if lines[i].from != lines[j].from {
+				return lines[i].from < lines[j].from
+			}
+			return lines[i].to < lines[j].to
+		})
+		for _, line := range lines {
+			fmt.Fprintf(out, "   %-50s -> %-50s %s\n", line.from, line.to, line.archive)
+		}
+	}
+	return out.Flush()
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
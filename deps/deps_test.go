@@ -0,0 +1,76 @@
+package deps
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/internal/testutil"
+	"github.com/tk103331/clazz/tools"
+)
+
+var sources = map[string]string{
+	"a/A": `.version 52 0
+.class public super a/A
+.super java/lang/Object
+.field private b [Lb/B;
+.method public run (La/Helper;)Ljava/lang/String;
+    new c/C
+    invokestatic b/B make ()Ljava/util/List;
+    pop
+    ldc "x"
+    areturn
+.end method
+`,
+	"a/Helper": `.version 52 0
+.class public super a/Helper
+.super java/lang/Object
+`,
+	"b/B": `.version 52 0
+.class public super b/B
+.super java/lang/Object
+.method public static make ()Ljava/util/List;
+    aconst_null
+    areturn
+.end method
+`,
+}
+
+func TestReferences(t *testing.T) {
+	c, err := testutil.Classpath(t, sources).Find("a/A")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "a/Helper b/B c/C java/lang/Object java/lang/String java/util/List",
+		strings.Join(References(c), " "))
+}
+
+func TestAnalyze(t *testing.T) {
+	g, err := Analyze(testutil.Classpath(t, sources), Options{})
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, 9, len(g.Dependencies))
+	tools.AssertEqual(t, "memory", g.Archive("a/A"))
+	tools.AssertEqual(t, JDK, g.Module("java/util/List"))
+	tools.AssertEqual(t, NotFound, g.Archive("c/C"))
+
+	edges := g.Edges(Package)
+	tools.AssertEqual(t, 7, len(edges))
+	tools.AssertEqual(t, Edge{From: "a", To: "a", Count: 1}, edges[0])
+	tools.AssertEqual(t, Edge{From: "a", To: "java.lang", Count: 3}, edges[3])
+
+	g, err = Analyze(testutil.Classpath(t, sources), Options{ExcludeJDK: true, ExcludeSamePackage: true})
+	tools.AssertNoErr(t, err)
+	edges = g.Edges(Archive)
+	tools.AssertEqual(t, 2, len(edges))
+	tools.AssertEqual(t, Edge{From: "memory", To: "memory", Count: 1}, edges[0])
+	tools.AssertEqual(t, Edge{From: "memory", To: NotFound, Count: 1}, edges[1])
+
+	var dot bytes.Buffer
+	tools.AssertNoErr(t, g.WriteDOT(&dot, Package))
+	tools.AssertEqual(t, "digraph dependencies {\n  \"a\" -> \"b\";\n  \"a\" -> \"c\";\n}\n", dot.String())
+
+	var report bytes.Buffer
+	tools.AssertNoErr(t, g.WriteReport(&report, Class))
+	tools.AssertEqual(t, "memory -> memory\nmemory -> not found\n"+
+		"   a.A                                                -> b.B                                                memory\n"+
+		"   a.A                                                -> c.C                                                not found\n",
+		report.String())
+}
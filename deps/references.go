@@ -0,0 +1,178 @@
+package deps
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+)
+
+// References returns the internal names of the classes referenced by a class, sorted, excluding
+// the class itself: its supertypes, the types of its signatures and descriptors, annotations,
+// constants, nest and inner classes, and the classes referenced by the instructions, exception
+// handlers, local variables and stack map frames of its methods. The array types are replaced
+// by their element types.
+func References(c *class.Class) []string {
+	r := &referenceCollector{names: make(map[string]bool)}
+	r.MapType(c.SuperClass)
+	r.types(c.Interfaces)
+	r.MapSignature(c.Signature)
+	r.MapType(c.OuterClass.ClassName)
+	r.MapDescriptor(c.OuterClass.Descriptor)
+	r.MapType(c.NestHost)
+	r.types(c.NestMembers)
+	for _, innerClass := range c.InnerClasses {
+		r.MapType(innerClass.Name)
+		r.MapType(innerClass.OuterName)
+	}
+	r.annotations(c.RuntimeVisibleAnnotations)
+	r.annotations(c.RuntimeInvisibleAnnotations)
+	r.MapType(c.Module.MainClass)
+	r.types(c.Module.Uses)
+	for _, provide := range c.Module.Provides {
+		r.MapType(provide.Service)
+		r.types(provide.Provides)
+	}
+	for _, field := range c.Fields {
+		r.MapDescriptor(field.Descriptor)
+		r.MapSignature(field.Signature)
+		r.annotations(field.RuntimeVisibleAnnotations)
+		r.annotations(field.RuntimeInvisibleAnnotations)
+	}
+	for i := range c.Methods {
+		r.method(&c.Methods[i])
+	}
+	delete(r.names, c.ThisClass)
+	names := make([]string, 0, len(r.names))
+	for name := range r.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// referenceCollector is a class.Remapper which records the types it maps, in order to reuse the
+// parsing of the descriptors, signatures and constants of the class package.
+type referenceCollector struct {
+	names map[string]bool
+}
+
+func (r *referenceCollector) MapType(internalName string) string {
+	name := strings.TrimLeft(internalName, "[")
+	if len(name) < len(internalName) {
+		// An array descriptor.
+		if !strings.HasPrefix(name, "L") {
+			return internalName
+		}
+		name = strings.TrimSuffix(name[1:], ";")
+	}
+	if name != "" {
+		r.names[name] = true
+	}
+	return internalName
+}
+
+func (r *referenceCollector) MapMethodName(owner string, name string, descriptor string) string {
+	return name
+}
+
+func (r *referenceCollector) MapFieldName(owner string, name string, descriptor string) string {
+	return name
+}
+
+func (r *referenceCollector) MapDescriptor(descriptor string) string {
+	return class.RemapDescriptor(r.MapType, descriptor)
+}
+
+func (r *referenceCollector) MapSignature(signature string) string {
+	return class.RemapSignature(r.MapType, signature)
+}
+
+func (r *referenceCollector) types(names []string) {
+	for _, name := range names {
+		r.MapType(name)
+	}
+}
+
+func (r *referenceCollector) value(value interface{}) {
+	class.RemapValue(r, value)
+}
+
+func (r *referenceCollector) annotations(annotations []class.Annotation) {
+	for _, annotation := range annotations {
+		r.MapDescriptor(annotation.Descriptor)
+		for _, pair := range annotation.ElementPairs {
+			r.elementValue(pair.Value)
+		}
+	}
+}
+
+func (r *referenceCollector) elementValue(value class.ElementValue) {
+	switch v := value.(type) {
+	case class.ElementEnumValue:
+		r.MapDescriptor(v.TypeName)
+	case class.ElementClassValue:
+		r.value(v.Value)
+	case class.ElementAnnotationValue:
+		r.annotations([]class.Annotation{v.Value})
+	case class.ElementArrayValue:
+		for _, element := range v.Values {
+			r.elementValue(element)
+		}
+	}
+}
+
+func (r *referenceCollector) method(method *class.Method) {
+	r.MapDescriptor(method.Descriptor)
+	r.MapSignature(method.Signature)
+	r.types(method.Exceptions)
+	r.annotations(method.RuntimeVisibleAnnotations)
+	r.annotations(method.RuntimeInvisibleAnnotations)
+	for _, parameter := range method.RuntimeVisibleParameterAnnotations {
+		r.annotations(parameter.Annotations)
+	}
+	for _, parameter := range method.RuntimeInvisibleParameterAnnotations {
+		r.annotations(parameter.Annotations)
+	}
+	if method.AnnotationDefault != nil {
+		r.elementValue(method.AnnotationDefault)
+	}
+	code := &method.Code
+	for _, exception := range code.ExceptionTable {
+		r.MapType(exception.CatchType)
+	}
+	for _, variable := range code.LocalVariables {
+		r.MapDescriptor(variable.Descriptor)
+		r.MapSignature(variable.Signature)
+	}
+	for _, instruction := range code.Instructions {
+		switch insn := instruction.(type) {
+		case *class.TypeInstruction:
+			r.MapType(insn.Type)
+		case *class.FieldInstruction:
+			r.MapType(insn.Owner)
+			r.MapDescriptor(insn.Descriptor)
+		case *class.MethodInstruction:
+			r.MapType(insn.Owner)
+			r.MapDescriptor(insn.Descriptor)
+		case *class.InvokeDynamicInstruction:
+			r.MapDescriptor(insn.Descriptor)
+			r.value(insn.BootstrapMethod)
+			for _, argument := range insn.BootstrapMethodArguments {
+				r.value(argument)
+			}
+		case *class.LdcInstruction:
+			r.value(insn.Value)
+		case *class.MultiANewArrayInstruction:
+			r.MapDescriptor(insn.Descriptor)
+		case *class.Frame:
+			for _, values := range [][]interface{}{insn.Locals, insn.Stack} {
+				for _, value := range values {
+					if name, ok := value.(string); ok {
+						r.MapType(name)
+					}
+				}
+			}
+		}
+	}
+}
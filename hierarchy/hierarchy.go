@@ -0,0 +1,349 @@
+// Package hierarchy indexes the superclass and interface relations between classes.
+package hierarchy
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+const objectName = "java/lang/Object"
+
+// Loader loads classes by internal name. A classpath.Classpath is a Loader.
+type Loader interface {
+	Find(internalName string) (*class.Class, error)
+}
+
+type typeInfo struct {
+	name        string
+	superName   string
+	interfaces  []string
+	isInterface bool
+}
+
+// Hierarchy records the superclass and interface edges of a set of classes. Types are designated
+// by their internal names, and array types by their descriptors. If a Loader is set, the types
+// which have not been added are loaded on demand; the subtype queries only consider the types
+// already added or loaded. A Hierarchy is safe for concurrent use.
+type Hierarchy struct {
+	loader   Loader
+	mutex    sync.Mutex
+	types    map[string]*typeInfo
+	subtypes map[string][]string
+	missing  map[string]bool
+}
+
+func New() *Hierarchy {
+	return NewWithLoader(nil)
+}
+
+func NewWithLoader(loader Loader) *Hierarchy {
+	return &Hierarchy{
+		loader:   loader,
+		types:    make(map[string]*typeInfo),
+		subtypes: make(map[string][]string),
+		missing:  make(map[string]bool),
+	}
+}
+
+// Add adds a class to the hierarchy. A class which is already in the hierarchy is not replaced.
+func (h *Hierarchy) Add(c *class.Class) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.add(c)
+}
+
+func (h *Hierarchy) add(c *class.Class) *typeInfo {
+	if info, ok := h.types[c.ThisClass]; ok {
+		return info
+	}
+	info := &typeInfo{
+		name:        c.ThisClass,
+		superName:   c.SuperClass,
+		interfaces:  c.Interfaces,
+		isInterface: c.AccessFlags&data.ACC_INTERFACE != 0,
+	}
+	h.types[info.name] = info
+	delete(h.missing, info.name)
+	if len(info.superName) > 0 {
+		h.subtypes[info.superName] = append(h.subtypes[info.superName], info.name)
+	}
+	for _, name := range info.interfaces {
+		h.subtypes[name] = append(h.subtypes[name], info.name)
+	}
+	return info
+}
+
+// lookup returns the given type, loading it if necessary, or nil if it cannot be found.
+func (h *Hierarchy) lookup(name string) *typeInfo {
+	return h.find(name, false)
+}
+
+// lookupSupertype returns a supertype referenced by a class of the hierarchy, like lookup, and
+// records it as missing if it cannot be found.
+func (h *Hierarchy) lookupSupertype(name string) *typeInfo {
+	return h.find(name, true)
+}
+
+func (h *Hierarchy) find(name string, supertype bool) *typeInfo {
+	h.mutex.Lock()
+	info, ok := h.types[name]
+	h.mutex.Unlock()
+	if ok {
+		return info
+	}
+	if name == objectName {
+		return &typeInfo{name: objectName}
+	}
+	var c *class.Class
+	if h.loader != nil {
+		c, _ = h.loader.Find(name)
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if c == nil {
+		if supertype {
+			h.missing[name] = true
+		}
+		return nil
+	}
+	return h.add(c)
+}
+
+// Contains returns true if the given type is in the hierarchy or can be loaded.
+func (h *Hierarchy) Contains(name string) bool {
+	return h.lookup(name) != nil
+}
+
+// SuperClass returns the superclass of the given type, and false if the type is unknown.
+func (h *Hierarchy) SuperClass(name string) (string, bool) {
+	info := h.lookup(name)
+	if info == nil {
+		return "", false
+	}
+	return info.superName, true
+}
+
+// Interfaces returns the interfaces directly implemented or extended by the given type.
+func (h *Hierarchy) Interfaces(name string) []string {
+	info := h.lookup(name)
+	if info == nil {
+		return nil
+	}
+	return info.interfaces
+}
+
+// IsInterface returns true if the given type is a known interface.
+func (h *Hierarchy) IsInterface(name string) bool {
+	info := h.lookup(name)
+	return info != nil && info.isInterface
+}
+
+// AllSupertypes returns the superclasses and the interfaces of the given type, transitively,
+// in breadth first order. The supertypes which cannot be found are included, but not their own
+// supertypes.
+func (h *Hierarchy) AllSupertypes(name string) []string {
+	if strings.HasPrefix(name, "[") {
+		return []string{objectName, "java/lang/Cloneable", "java/io/Serializable"}
+	}
+	supertypes := make([]string, 0)
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		var info *typeInfo
+		if queue[0] == name {
+			info = h.lookup(name)
+		} else {
+			info = h.lookupSupertype(queue[0])
+		}
+		queue = queue[1:]
+		if info == nil {
+			continue
+		}
+		names := info.interfaces
+		if len(info.superName) > 0 {
+			names = append([]string{info.superName}, names...)
+		}
+		for _, supertype := range names {
+			if !seen[supertype] {
+				seen[supertype] = true
+				supertypes = append(supertypes, supertype)
+				queue = append(queue, supertype)
+			}
+		}
+	}
+	return supertypes
+}
+
+// IsAssignableFrom returns true if a value of type from can be assigned to a variable of type
+// to, that is if to is from or one of its supertypes. Unlike with the JVM verifier, an
+// interface is only assignable from the types which implement it.
+func (h *Hierarchy) IsAssignableFrom(to string, from string) bool {
+	if to == from || to == objectName {
+		return true
+	}
+	if strings.HasPrefix(from, "[") {
+		if strings.HasPrefix(to, "[") {
+			toElement, fromElement := to[1:], from[1:]
+			if !isReference(toElement) || !isReference(fromElement) {
+				return false
+			}
+			return h.IsAssignableFrom(elementName(toElement), elementName(fromElement))
+		}
+		return to == "java/lang/Cloneable" || to == "java/io/Serializable"
+	}
+	if strings.HasPrefix(to, "[") {
+		return false
+	}
+	for _, supertype := range h.AllSupertypes(from) {
+		if supertype == to {
+			return true
+		}
+	}
+	return false
+}
+
+func isReference(descriptor string) bool {
+	return descriptor[0] == 'L' || descriptor[0] == '['
+}
+
+// elementName returns the internal name of an object type descriptor, or the array
+// descriptor itself.
+func elementName(descriptor string) string {
+	if descriptor[0] == 'L' {
+		return descriptor[1 : len(descriptor)-1]
+	}
+	return descriptor
+}
+
+// DirectSubtypes returns the classes and interfaces which directly extend or implement the
+// given type, sorted.
+func (h *Hierarchy) DirectSubtypes(name string) []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	subtypes := append([]string(nil), h.subtypes[name]...)
+	sort.Strings(subtypes)
+	return subtypes
+}
+
+// AllSubtypes returns the classes and interfaces which extend or implement the given type,
+// transitively, sorted.
+func (h *Hierarchy) AllSubtypes(name string) []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	seen := make(map[string]bool)
+	queue := []string{name}
+	for len(queue) > 0 {
+		for _, subtype := range h.subtypes[queue[0]] {
+			if !seen[subtype] {
+				seen[subtype] = true
+				queue = append(queue, subtype)
+			}
+		}
+		queue = queue[1:]
+	}
+	subtypes := make([]string, 0, len(seen))
+	for subtype := range seen {
+		subtypes = append(subtypes, subtype)
+	}
+	sort.Strings(subtypes)
+	return subtypes
+}
+
+// AllImplementors returns the classes, excluding interfaces, which implement the given
+// interface directly or through their supertypes, sorted.
+func (h *Hierarchy) AllImplementors(name string) []string {
+	implementors := make([]string, 0)
+	for _, subtype := range h.AllSubtypes(name) {
+		if !h.IsInterface(subtype) {
+			implementors = append(implementors, subtype)
+		}
+	}
+	return implementors
+}
+
+// CommonSuperClass returns the most specific common superclass of two classes. The result is
+// java/lang/Object if one of them is an interface or is unknown.
+func (h *Hierarchy) CommonSuperClass(a string, b string) string {
+	if a == b {
+		return a
+	}
+	if strings.HasPrefix(a, "[") || strings.HasPrefix(b, "[") {
+		if h.IsAssignableFrom(a, b) {
+			return a
+		}
+		if h.IsAssignableFrom(b, a) {
+			return b
+		}
+		return objectName
+	}
+	if h.IsInterface(a) || h.IsInterface(b) {
+		return objectName
+	}
+	superClasses := make(map[string]bool)
+	for name := b; len(name) > 0; {
+		superClasses[name] = true
+		info := h.lookupSuperClass(name, b)
+		if info == nil {
+			break
+		}
+		name = info.superName
+	}
+	for name := a; len(name) > 0; {
+		if superClasses[name] {
+			return name
+		}
+		info := h.lookupSuperClass(name, a)
+		if info == nil {
+			break
+		}
+		name = info.superName
+	}
+	return objectName
+}
+
+// lookupSuperClass looks up a type of the superclass chain of a class, which is a supertype
+// unless it is the class itself.
+func (h *Hierarchy) lookupSuperClass(name string, className string) *typeInfo {
+	if name == className {
+		return h.lookup(name)
+	}
+	return h.lookupSupertype(name)
+}
+
+// MissingSupertypes returns the supertypes of the classes of the hierarchy which were looked up
+// but could not be found, sorted. The types queried directly are not recorded.
+func (h *Hierarchy) MissingSupertypes() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	missing := make([]string, 0, len(h.missing))
+	for name := range h.missing {
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Check looks up the supertypes of all the classes of the hierarchy, transitively, and returns
+// the supertypes which cannot be found, with the classes which reference them.
+func (h *Hierarchy) Check() map[string][]string {
+	h.mutex.Lock()
+	names := make([]string, 0, len(h.types))
+	for name := range h.types {
+		names = append(names, name)
+	}
+	h.mutex.Unlock()
+	sort.Strings(names)
+	missing := make(map[string][]string)
+	for _, name := range names {
+		for _, supertype := range h.AllSupertypes(name) {
+			if h.lookupSupertype(supertype) == nil {
+				missing[supertype] = append(missing[supertype], name)
+			}
+		}
+	}
+	return missing
+}
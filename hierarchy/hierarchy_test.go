@@ -0,0 +1,73 @@
+package hierarchy
+
+import (
+	"testing"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/tools"
+)
+
+func newTestHierarchy() *Hierarchy {
+	h := New()
+	h.Add(&class.Class{ThisClass: "a/Shape", AccessFlags: data.ACC_INTERFACE | data.ACC_ABSTRACT, SuperClass: "java/lang/Object"})
+	h.Add(&class.Class{ThisClass: "a/Polygon", AccessFlags: data.ACC_INTERFACE | data.ACC_ABSTRACT, SuperClass: "java/lang/Object", Interfaces: []string{"a/Shape"}})
+	h.Add(&class.Class{ThisClass: "a/Base", SuperClass: "java/lang/Object", Interfaces: []string{"a/Polygon"}})
+	h.Add(&class.Class{ThisClass: "a/Square", SuperClass: "a/Base", Interfaces: []string{"ext/Drawable"}})
+	h.Add(&class.Class{ThisClass: "a/Triangle", SuperClass: "a/Base"})
+	h.Add(&class.Class{ThisClass: "a/Circle", SuperClass: "java/lang/Object", Interfaces: []string{"a/Shape"}})
+	return h
+}
+
+func TestHierarchy(t *testing.T) {
+	h := newTestHierarchy()
+	tools.AssertEqual(t, true, h.IsAssignableFrom("a/Shape", "a/Square"))
+	tools.AssertEqual(t, false, h.IsAssignableFrom("a/Polygon", "a/Circle"))
+	tools.AssertEqual(t, true, h.IsAssignableFrom("[La/Shape;", "[La/Triangle;"))
+	tools.AssertEqual(t, false, h.IsAssignableFrom("[I", "[La/Triangle;"))
+	tools.AssertEqual(t, true, h.IsAssignableFrom("java/lang/Cloneable", "[I"))
+
+	supertypes := h.AllSupertypes("a/Square")
+	tools.AssertEqual(t, 5, len(supertypes))
+	tools.AssertEqual(t, "a/Base", supertypes[0])
+	tools.AssertEqual(t, "a/Shape", supertypes[4])
+
+	subtypes := h.DirectSubtypes("a/Base")
+	tools.AssertEqual(t, 2, len(subtypes))
+	tools.AssertEqual(t, "a/Square", subtypes[0])
+	implementors := h.AllImplementors("a/Shape")
+	tools.AssertEqual(t, 4, len(implementors))
+	tools.AssertEqual(t, "a/Base", implementors[0])
+
+	tools.AssertEqual(t, "a/Base", h.CommonSuperClass("a/Square", "a/Triangle"))
+	tools.AssertEqual(t, "a/Base", h.CommonSuperClass("a/Base", "a/Triangle"))
+	tools.AssertEqual(t, "java/lang/Object", h.CommonSuperClass("a/Square", "a/Circle"))
+	tools.AssertEqual(t, "java/lang/Object", h.CommonSuperClass("a/Shape", "a/Circle"))
+
+	missing := h.Check()
+	tools.AssertEqual(t, 1, len(missing))
+	tools.AssertEqual(t, "a/Square", missing["ext/Drawable"][0])
+	tools.AssertEqual(t, "ext/Drawable", h.MissingSupertypes()[0])
+}
+
+type testLoader map[string]*class.Class
+
+func (l testLoader) Find(internalName string) (*class.Class, error) {
+	return l[internalName], nil
+}
+
+func TestLoader(t *testing.T) {
+	h := NewWithLoader(testLoader{
+		"b/A": &class.Class{ThisClass: "b/A", SuperClass: "java/lang/Object"},
+		"b/B": &class.Class{ThisClass: "b/B", SuperClass: "b/A"},
+	})
+	h.Add(&class.Class{ThisClass: "b/C", SuperClass: "b/B"})
+	tools.AssertEqual(t, true, h.IsAssignableFrom("b/A", "b/C"))
+	tools.AssertEqual(t, "b/B", h.DirectSubtypes("b/A")[0])
+	tools.AssertEqual(t, false, h.Contains("b/D"))
+	// The types queried directly are not missing supertypes.
+	tools.AssertEqual(t, 0, len(h.MissingSupertypes()))
+	h.Add(&class.Class{ThisClass: "b/E", SuperClass: "b/D"})
+	tools.AssertEqual(t, "b/D", h.AllSupertypes("b/E")[0])
+	tools.AssertEqual(t, "b/D", h.MissingSupertypes()[0])
+}
@@ -0,0 +1,32 @@
+// Package testutil provides the fixtures shared by the tests of the packages which analyze
+// classpaths. The classes of the fixtures are written in the jasm syntax.
+package testutil
+
+import (
+	"testing"
+
+	"github.com/tk103331/clazz/classpath"
+	"github.com/tk103331/clazz/jasm"
+)
+
+// Assemble assembles a class, and stops the test if it fails.
+func Assemble(t *testing.T, source string) []byte {
+	t.Helper()
+	content, err := jasm.Assemble([]byte(source))
+	if err != nil {
+		t.Fatalf("assembling %q: %v", source, err)
+	}
+	return content
+}
+
+// Classpath returns a classpath of the classes assembled from their sources, by internal name.
+func Classpath(t *testing.T, sources map[string]string) *classpath.Classpath {
+	t.Helper()
+	source := classpath.NewMemorySource()
+	for name, text := range sources {
+		source.Add(name, Assemble(t, text))
+	}
+	cp := classpath.New()
+	cp.Add(source)
+	return cp
+}
@@ -0,0 +1,898 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+// code is the prepared code of a method.
+type code struct {
+	instructions []class.Instruction
+	// labels are the indexes of the labels in the instructions.
+	labels   map[*class.Label]int
+	handlers []handler
+}
+
+// handler is an exception handler, for the instructions in [start, end).
+type handler struct {
+	start, end, handler int
+	catchType           string
+}
+
+func (in *Interpreter) code(method *class.Method) *code {
+	if c, ok := in.codes[method]; ok {
+		return c
+	}
+	c := &code{instructions: method.Code.Instructions, labels: make(map[*class.Label]int)}
+	for i, instruction := range c.instructions {
+		if label, ok := instruction.(*class.Label); ok {
+			c.labels[label] = i
+		}
+	}
+	for _, exception := range method.Code.ExceptionTable {
+		c.handlers = append(c.handlers, handler{c.labels[exception.Start], c.labels[exception.End],
+			c.labels[exception.Handler], exception.CatchType})
+	}
+	in.codes[method] = c
+	return c
+}
+
+// frame is the execution state of a method.
+type frame struct {
+	locals []Value
+	stack  []Value
+}
+
+// errMalformed is raised by the frame operations on malformed code.
+type errMalformed struct {
+	message string
+}
+
+func (f *frame) push(value Value) {
+	f.stack = append(f.stack, value)
+}
+
+func (f *frame) pop() Value {
+	if len(f.stack) == 0 {
+		panic(errMalformed{"operand stack underflow"})
+	}
+	value := f.stack[len(f.stack)-1]
+	f.stack = f.stack[:len(f.stack)-1]
+	return value
+}
+
+func (f *frame) popInt() int32 {
+	value, ok := f.pop().(int32)
+	if !ok {
+		panic(errMalformed{"expected an int"})
+	}
+	return value
+}
+
+func (f *frame) popLong() int64 {
+	value, ok := f.pop().(int64)
+	if !ok {
+		panic(errMalformed{"expected a long"})
+	}
+	return value
+}
+
+func (f *frame) popFloat() float32 {
+	value, ok := f.pop().(float32)
+	if !ok {
+		panic(errMalformed{"expected a float"})
+	}
+	return value
+}
+
+func (f *frame) popDouble() float64 {
+	value, ok := f.pop().(float64)
+	if !ok {
+		panic(errMalformed{"expected a double"})
+	}
+	return value
+}
+
+// popArray pops an array reference, and returns a NullPointerException if it is null.
+func (f *frame) popArray(in *Interpreter) (*Array, error) {
+	switch value := f.pop().(type) {
+	case nil:
+		return nil, in.NewException("java/lang/NullPointerException", "")
+	case *Array:
+		return value, nil
+	default:
+		panic(errMalformed{"expected an array"})
+	}
+}
+
+// isWide returns true for the long and double values, which take two slots.
+func isWide(value Value) bool {
+	switch value.(type) {
+	case int64, float64:
+		return true
+	}
+	return false
+}
+
+// run executes a method with its arguments, given as one value each.
+func (in *Interpreter) run(owner string, method *class.Method, args []Value) (result Value, err error) {
+	name := owner + "." + method.Name + method.Descriptor
+	if len(method.Code.Instructions) == 0 {
+		return nil, fmt.Errorf("%s: no code", name)
+	}
+	if in.depth >= in.MaxDepth {
+		return nil, in.NewException("java/lang/StackOverflowError", "")
+	}
+	in.depth++
+	defer func() { in.depth-- }()
+
+	c := in.code(method)
+	f := &frame{locals: make([]Value, int(method.Code.MaxLocal)+len(args))}
+	slot := 0
+	for _, arg := range args {
+		f.locals[slot] = arg
+		slot++
+		if isWide(arg) {
+			slot++
+		}
+	}
+	index := 0
+	defer func() {
+		if e := recover(); e != nil {
+			switch e := e.(type) {
+			case errMalformed:
+				err = fmt.Errorf("%s: instruction %d: %s", name, index, e.message)
+			case runtime.Error:
+				// Such as a local variable out of range, or of the wrong type.
+				err = fmt.Errorf("%s: instruction %d: %v", name, index, e)
+			default:
+				panic(e)
+			}
+		}
+	}()
+	for {
+		if index >= len(c.instructions) {
+			return nil, fmt.Errorf("%s: falling off the code", name)
+		}
+		instruction := c.instructions[index]
+		if instruction.OpCode() < 0 {
+			index++
+			continue
+		}
+		if in.Fuel <= 0 {
+			return nil, ErrOutOfFuel
+		}
+		in.Fuel--
+		next, returned, err := in.execute(c, f, index, instruction)
+		if err != nil {
+			exception, ok := err.(*Exception)
+			if !ok {
+				return nil, err
+			}
+			next = -1
+			for _, h := range c.handlers {
+				if index >= h.start && index < h.end &&
+					(h.catchType == "" || in.isSubclass(exception.Object.Class, h.catchType)) {
+					next = h.handler
+					break
+				}
+			}
+			if next < 0 {
+				return nil, exception
+			}
+			f.stack = append(f.stack[:0], exception.Object)
+		} else if returned {
+			if method.Descriptor[len(method.Descriptor)-1] == 'V' {
+				return nil, nil
+			}
+			return f.pop(), nil
+		}
+		index = next
+	}
+}
+
+// execute executes an instruction, and returns the index of the next one, or true if the method
+// returns.
+func (in *Interpreter) execute(c *code, f *frame, index int, instruction class.Instruction) (int, bool, error) {
+	opCode := instruction.OpCode()
+	switch insn := instruction.(type) {
+	case *class.VarInstruction:
+		switch opCode {
+		case data.ILOAD, data.LLOAD, data.FLOAD, data.DLOAD, data.ALOAD:
+			f.push(f.locals[insn.Var])
+		case data.ISTORE, data.LSTORE, data.FSTORE, data.DSTORE, data.ASTORE:
+			f.locals[insn.Var] = f.pop()
+		default:
+			return 0, false, fmt.Errorf("unsupported instruction %s", data.OPCODE_NAMES[opCode])
+		}
+	case *class.IincInstruction:
+		f.locals[insn.Var] = f.locals[insn.Var].(int32) + int32(insn.Increment)
+	case *class.IntInstruction:
+		if opCode == data.NEWARRAY {
+			array, err := in.newArray("["+primitiveDescriptors[insn.Operand], f.popInt())
+			if err != nil {
+				return 0, false, err
+			}
+			f.push(array)
+		} else {
+			f.push(insn.Operand)
+		}
+	case *class.LdcInstruction:
+		switch value := insn.Value.(type) {
+		case int32, int64, float32, float64, string:
+			f.push(value)
+		default:
+			return 0, false, fmt.Errorf("unsupported constant %v", insn.Value)
+		}
+	case *class.JumpInstruction:
+		if jump(f, opCode) {
+			return c.labels[insn.Label], false, nil
+		}
+	case *class.TableSwitchInstruction:
+		key := f.popInt()
+		if key >= insn.Min && key <= insn.Max {
+			return c.labels[insn.Labels[key-insn.Min]], false, nil
+		}
+		return c.labels[insn.Default], false, nil
+	case *class.LookupSwitchInstruction:
+		key := f.popInt()
+		for i, k := range insn.Keys {
+			if k == key {
+				return c.labels[insn.Labels[i]], false, nil
+			}
+		}
+		return c.labels[insn.Default], false, nil
+	case *class.TypeInstruction:
+		if err := in.executeType(f, opCode, insn.Type); err != nil {
+			return 0, false, err
+		}
+	case *class.MultiANewArrayInstruction:
+		lengths := make([]int32, insn.NumDimensions)
+		for i := insn.NumDimensions - 1; i >= 0; i-- {
+			lengths[i] = f.popInt()
+		}
+		array, err := in.multiANewArray(insn.Descriptor, lengths)
+		if err != nil {
+			return 0, false, err
+		}
+		f.push(array)
+	case *class.FieldInstruction:
+		if err := in.executeField(f, opCode, insn); err != nil {
+			return 0, false, err
+		}
+	case *class.MethodInstruction:
+		if err := in.executeMethod(f, opCode, insn.Owner, insn.Name, insn.Descriptor); err != nil {
+			return 0, false, err
+		}
+	case *class.InvokeDynamicInstruction:
+		if err := in.executeInvokeDynamic(f, insn); err != nil {
+			return 0, false, err
+		}
+	case *class.CodeInstruction:
+		return in.executeCode(f, index, opCode)
+	default:
+		return 0, false, fmt.Errorf("unsupported instruction %s", data.OPCODE_NAMES[opCode])
+	}
+	return index + 1, false, nil
+}
+
+var primitiveDescriptors = map[int32]string{
+	data.T_BOOLEAN: "Z", data.T_CHAR: "C", data.T_FLOAT: "F", data.T_DOUBLE: "D",
+	data.T_BYTE: "B", data.T_SHORT: "S", data.T_INT: "I", data.T_LONG: "J",
+}
+
+// jump pops the operands of a jump instruction, and returns true if the jump is taken.
+func jump(f *frame, opCode int) bool {
+	switch opCode {
+	case data.GOTO:
+		return true
+	case data.IFEQ:
+		return f.popInt() == 0
+	case data.IFNE:
+		return f.popInt() != 0
+	case data.IFLT:
+		return f.popInt() < 0
+	case data.IFGE:
+		return f.popInt() >= 0
+	case data.IFGT:
+		return f.popInt() > 0
+	case data.IFLE:
+		return f.popInt() <= 0
+	case data.IFNULL:
+		return f.pop() == nil
+	case data.IFNONNULL:
+		return f.pop() != nil
+	case data.IF_ACMPEQ, data.IF_ACMPNE:
+		b, a := f.pop(), f.pop()
+		return (a == b) == (opCode == data.IF_ACMPEQ)
+	}
+	b, a := f.popInt(), f.popInt()
+	switch opCode {
+	case data.IF_ICMPEQ:
+		return a == b
+	case data.IF_ICMPNE:
+		return a != b
+	case data.IF_ICMPLT:
+		return a < b
+	case data.IF_ICMPGE:
+		return a >= b
+	case data.IF_ICMPGT:
+		return a > b
+	case data.IF_ICMPLE:
+		return a <= b
+	}
+	panic(errMalformed{"unsupported jump instruction " + data.OPCODE_NAMES[opCode]})
+}
+
+func (in *Interpreter) executeType(f *frame, opCode int, typeName string) error {
+	switch opCode {
+	case data.NEW:
+		if in.class(typeName) != nil {
+			if err := in.Initialize(typeName); err != nil {
+				return err
+			}
+		}
+		f.push(in.NewObject(typeName))
+	case data.ANEWARRAY:
+		descriptor := "[L" + typeName + ";"
+		if typeName[0] == '[' {
+			descriptor = "[" + typeName
+		}
+		array, err := in.newArray(descriptor, f.popInt())
+		if err != nil {
+			return err
+		}
+		f.push(array)
+	case data.CHECKCAST:
+		value := f.pop()
+		if value != nil && !in.isInstance(value, typeName) {
+			return in.NewException("java/lang/ClassCastException", runtimeClass(value)+" cannot be cast to "+typeName)
+		}
+		f.push(value)
+	case data.INSTANCEOF:
+		value := f.pop()
+		f.push(boolean(value != nil && in.isInstance(value, typeName)))
+	}
+	return nil
+}
+
+func (in *Interpreter) multiANewArray(descriptor string, lengths []int32) (*Array, error) {
+	array, err := in.newArray(descriptor, lengths[0])
+	if err != nil || len(lengths) == 1 {
+		return array, err
+	}
+	for i := range array.Elements {
+		if array.Elements[i], err = in.multiANewArray(descriptor[1:], lengths[1:]); err != nil {
+			return nil, err
+		}
+	}
+	return array, nil
+}
+
+func (in *Interpreter) executeField(f *frame, opCode int, insn *class.FieldInstruction) error {
+	switch opCode {
+	case data.GETSTATIC, data.PUTSTATIC:
+		owner, err := in.staticField(insn.Owner, insn.Name)
+		if err != nil {
+			return err
+		}
+		if opCode == data.GETSTATIC {
+			f.push(in.statics[owner+"."+insn.Name])
+		} else {
+			in.statics[owner+"."+insn.Name] = f.pop()
+		}
+	case data.GETFIELD:
+		object, ok := f.pop().(*Object)
+		if !ok {
+			return in.NewException("java/lang/NullPointerException", "")
+		}
+		value, ok := object.Fields[insn.Name]
+		if !ok {
+			value = zero(insn.Descriptor)
+		}
+		f.push(value)
+	case data.PUTFIELD:
+		value := f.pop()
+		object, ok := f.pop().(*Object)
+		if !ok {
+			return in.NewException("java/lang/NullPointerException", "")
+		}
+		object.Fields[insn.Name] = value
+	}
+	return nil
+}
+
+func (in *Interpreter) executeMethod(f *frame, opCode int, owner string, name string, descriptor string) error {
+	argumentTypes := class.NewMethodType(descriptor).ArgumentTypes()
+	count := len(argumentTypes)
+	if opCode != data.INVOKESTATIC {
+		count++
+	}
+	if len(f.stack) < count {
+		panic(errMalformed{"operand stack underflow"})
+	}
+	args := append([]Value(nil), f.stack[len(f.stack)-count:]...)
+	f.stack = f.stack[:len(f.stack)-count]
+	result, err := in.invoke(opCode, owner, name, descriptor, args)
+	if err != nil {
+		return err
+	}
+	if name == "<init>" {
+		// Replaces the new object by the value built by a native constructor.
+		if result != nil {
+			for i, value := range f.stack {
+				if value == args[0] {
+					f.stack[i] = result
+				}
+			}
+			for i, value := range f.locals {
+				if value == args[0] {
+					f.locals[i] = result
+				}
+			}
+		}
+		return nil
+	}
+	if descriptor[len(descriptor)-1] != 'V' {
+		f.push(result)
+	}
+	return nil
+}
+
+func (in *Interpreter) executeInvokeDynamic(f *frame, insn *class.InvokeDynamicInstruction) error {
+	bootstrap := insn.BootstrapMethod
+	if bootstrap.Owner != "java/lang/invoke/StringConcatFactory" || bootstrap.Name != "makeConcatWithConstants" {
+		return fmt.Errorf("unsupported invokedynamic %s with bootstrap method %s.%s", insn.Name, bootstrap.Owner, bootstrap.Name)
+	}
+	argumentTypes := class.NewMethodType(insn.Descriptor).ArgumentTypes()
+	args := make([]Value, len(argumentTypes))
+	for i := len(args) - 1; i >= 0; i-- {
+		args[i] = f.pop()
+	}
+	recipe, _ := insn.BootstrapMethodArguments[0].(string)
+	constants := insn.BootstrapMethodArguments[1:]
+	var result []rune
+	for _, r := range recipe {
+		switch r {
+		case '\u0001':
+			s, err := in.toString(args[0], argumentTypes[0].Descriptor())
+			if err != nil {
+				return err
+			}
+			result = append(result, []rune(s)...)
+			args, argumentTypes = args[1:], argumentTypes[1:]
+		case '\u0002':
+			result = append(result, []rune(fmt.Sprint(constants[0]))...)
+			constants = constants[1:]
+		default:
+			result = append(result, r)
+		}
+	}
+	f.push(string(result))
+	return nil
+}
+
+func boolean(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// arrayIndex checks the index of an array element.
+func (in *Interpreter) arrayIndex(array *Array, index int32) error {
+	if index < 0 || int(index) >= len(array.Elements) {
+		return in.NewException("java/lang/ArrayIndexOutOfBoundsException",
+			fmt.Sprintf("Index %d out of bounds for length %d", index, len(array.Elements)))
+	}
+	return nil
+}
+
+func (in *Interpreter) executeCode(f *frame, index int, opCode int) (int, bool, error) {
+	switch {
+	case opCode == data.NOP:
+	case opCode == data.ACONST_NULL:
+		f.push(nil)
+	case opCode >= data.ICONST_M1 && opCode <= data.ICONST_5:
+		f.push(int32(opCode - data.ICONST_0))
+	case opCode == data.LCONST_0 || opCode == data.LCONST_1:
+		f.push(int64(opCode - data.LCONST_0))
+	case opCode >= data.FCONST_0 && opCode <= data.FCONST_2:
+		f.push(float32(opCode - data.FCONST_0))
+	case opCode == data.DCONST_0 || opCode == data.DCONST_1:
+		f.push(float64(opCode - data.DCONST_0))
+	case opCode >= data.IALOAD && opCode <= data.SALOAD:
+		i := f.popInt()
+		array, err := f.popArray(in)
+		if err == nil {
+			err = in.arrayIndex(array, i)
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		f.push(array.Elements[i])
+	case opCode >= data.IASTORE && opCode <= data.SASTORE:
+		value := f.pop()
+		i := f.popInt()
+		array, err := f.popArray(in)
+		if err == nil {
+			err = in.arrayIndex(array, i)
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		switch array.Descriptor {
+		case "[Z":
+			value = value.(int32) & 1
+		case "[B":
+			value = int32(int8(value.(int32)))
+		case "[C":
+			value = int32(uint16(value.(int32)))
+		case "[S":
+			value = int32(int16(value.(int32)))
+		}
+		if opCode == data.AASTORE && value != nil && !in.isInstance(value, array.Descriptor[1:]) &&
+			!in.isInstance(value, internalName(array.Descriptor[1:])) {
+			return 0, false, in.NewException("java/lang/ArrayStoreException", runtimeClass(value))
+		}
+		array.Elements[i] = value
+	case opCode >= data.POP && opCode <= data.SWAP:
+		executeStack(f, opCode)
+	case opCode >= data.IADD && opCode <= data.LXOR:
+		if err := in.executeArithmetic(f, opCode); err != nil {
+			return 0, false, err
+		}
+	case opCode >= data.I2L && opCode <= data.I2S:
+		executeConversion(f, opCode)
+	case opCode >= data.LCMP && opCode <= data.DCMPG:
+		executeComparison(f, opCode)
+	case opCode >= data.IRETURN && opCode <= data.RETURN:
+		return 0, true, nil
+	case opCode == data.ARRAYLENGTH:
+		array, err := f.popArray(in)
+		if err != nil {
+			return 0, false, err
+		}
+		f.push(int32(len(array.Elements)))
+	case opCode == data.ATHROW:
+		switch value := f.pop().(type) {
+		case *Object:
+			return 0, false, &Exception{Object: value}
+		default:
+			return 0, false, in.NewException("java/lang/NullPointerException", "")
+		}
+	case opCode == data.MONITORENTER || opCode == data.MONITOREXIT:
+		if f.pop() == nil {
+			return 0, false, in.NewException("java/lang/NullPointerException", "")
+		}
+	default:
+		return 0, false, fmt.Errorf("unsupported instruction %s", data.OPCODE_NAMES[opCode])
+	}
+	return index + 1, false, nil
+}
+
+// internalName returns the internal name of an object type descriptor, or the descriptor of an
+// array type.
+func internalName(descriptor string) string {
+	if descriptor[0] == 'L' {
+		return descriptor[1 : len(descriptor)-1]
+	}
+	return descriptor
+}
+
+func executeStack(f *frame, opCode int) {
+	switch opCode {
+	case data.POP:
+		f.pop()
+	case data.POP2:
+		if !isWide(f.pop()) {
+			f.pop()
+		}
+	case data.DUP:
+		v := f.pop()
+		f.push(v)
+		f.push(v)
+	case data.DUP_X1:
+		v1, v2 := f.pop(), f.pop()
+		f.push(v1)
+		f.push(v2)
+		f.push(v1)
+	case data.DUP_X2:
+		v1, v2 := f.pop(), f.pop()
+		if isWide(v2) {
+			f.push(v1)
+			f.push(v2)
+			f.push(v1)
+		} else {
+			v3 := f.pop()
+			f.push(v1)
+			f.push(v3)
+			f.push(v2)
+			f.push(v1)
+		}
+	case data.DUP2:
+		v1 := f.pop()
+		if isWide(v1) {
+			f.push(v1)
+			f.push(v1)
+		} else {
+			v2 := f.pop()
+			f.push(v2)
+			f.push(v1)
+			f.push(v2)
+			f.push(v1)
+		}
+	case data.DUP2_X1:
+		v1 := f.pop()
+		if isWide(v1) {
+			v2 := f.pop()
+			f.push(v1)
+			f.push(v2)
+			f.push(v1)
+		} else {
+			v2, v3 := f.pop(), f.pop()
+			f.push(v2)
+			f.push(v1)
+			f.push(v3)
+			f.push(v2)
+			f.push(v1)
+		}
+	case data.DUP2_X2:
+		v1 := f.pop()
+		if isWide(v1) {
+			v2 := f.pop()
+			if isWide(v2) {
+				f.push(v1)
+				f.push(v2)
+				f.push(v1)
+			} else {
+				v3 := f.pop()
+				f.push(v1)
+				f.push(v3)
+				f.push(v2)
+				f.push(v1)
+			}
+		} else {
+			v2, v3 := f.pop(), f.pop()
+			if isWide(v3) {
+				f.push(v2)
+				f.push(v1)
+				f.push(v3)
+				f.push(v2)
+				f.push(v1)
+			} else {
+				v4 := f.pop()
+				f.push(v2)
+				f.push(v1)
+				f.push(v4)
+				f.push(v3)
+				f.push(v2)
+				f.push(v1)
+			}
+		}
+	case data.SWAP:
+		v1, v2 := f.pop(), f.pop()
+		f.push(v1)
+		f.push(v2)
+	}
+}
+
+func (in *Interpreter) executeArithmetic(f *frame, opCode int) error {
+	switch opCode {
+	case data.INEG:
+		f.push(-f.popInt())
+		return nil
+	case data.LNEG:
+		f.push(-f.popLong())
+		return nil
+	case data.FNEG:
+		f.push(-f.popFloat())
+		return nil
+	case data.DNEG:
+		f.push(-f.popDouble())
+		return nil
+	case data.LSHL, data.LSHR, data.LUSHR:
+		shift := uint(f.popInt() & 63)
+		a := f.popLong()
+		switch opCode {
+		case data.LSHL:
+			f.push(a << shift)
+		case data.LSHR:
+			f.push(a >> shift)
+		default:
+			f.push(int64(uint64(a) >> shift))
+		}
+		return nil
+	}
+	// The operations are grouped by 4 types, int, long, float and double, except the shifts and
+	// the bitwise operations.
+	switch opCode {
+	case data.IADD, data.ISUB, data.IMUL, data.IDIV, data.IREM, data.ISHL, data.ISHR, data.IUSHR, data.IAND, data.IOR, data.IXOR:
+		b, a := f.popInt(), f.popInt()
+		switch opCode {
+		case data.IADD:
+			f.push(a + b)
+		case data.ISUB:
+			f.push(a - b)
+		case data.IMUL:
+			f.push(a * b)
+		case data.IDIV, data.IREM:
+			if b == 0 {
+				return in.NewException("java/lang/ArithmeticException", "/ by zero")
+			}
+			if b == -1 {
+				// Avoids the overflow of math.MinInt32 / -1.
+				if opCode == data.IDIV {
+					f.push(-a)
+				} else {
+					f.push(int32(0))
+				}
+			} else if opCode == data.IDIV {
+				f.push(a / b)
+			} else {
+				f.push(a % b)
+			}
+		case data.ISHL:
+			f.push(a << uint(b&31))
+		case data.ISHR:
+			f.push(a >> uint(b&31))
+		case data.IUSHR:
+			f.push(int32(uint32(a) >> uint(b&31)))
+		case data.IAND:
+			f.push(a & b)
+		case data.IOR:
+			f.push(a | b)
+		case data.IXOR:
+			f.push(a ^ b)
+		}
+	case data.LADD, data.LSUB, data.LMUL, data.LDIV, data.LREM, data.LAND, data.LOR, data.LXOR:
+		b, a := f.popLong(), f.popLong()
+		switch opCode {
+		case data.LADD:
+			f.push(a + b)
+		case data.LSUB:
+			f.push(a - b)
+		case data.LMUL:
+			f.push(a * b)
+		case data.LDIV, data.LREM:
+			if b == 0 {
+				return in.NewException("java/lang/ArithmeticException", "/ by zero")
+			}
+			if b == -1 {
+				if opCode == data.LDIV {
+					f.push(-a)
+				} else {
+					f.push(int64(0))
+				}
+			} else if opCode == data.LDIV {
+				f.push(a / b)
+			} else {
+				f.push(a % b)
+			}
+		case data.LAND:
+			f.push(a & b)
+		case data.LOR:
+			f.push(a | b)
+		case data.LXOR:
+			f.push(a ^ b)
+		}
+	case data.FADD, data.FSUB, data.FMUL, data.FDIV, data.FREM:
+		b, a := f.popFloat(), f.popFloat()
+		switch opCode {
+		case data.FADD:
+			f.push(a + b)
+		case data.FSUB:
+			f.push(a - b)
+		case data.FMUL:
+			f.push(a * b)
+		case data.FDIV:
+			f.push(a / b)
+		case data.FREM:
+			f.push(float32(math.Mod(float64(a), float64(b))))
+		}
+	case data.DADD, data.DSUB, data.DMUL, data.DDIV, data.DREM:
+		b, a := f.popDouble(), f.popDouble()
+		switch opCode {
+		case data.DADD:
+			f.push(a + b)
+		case data.DSUB:
+			f.push(a - b)
+		case data.DMUL:
+			f.push(a * b)
+		case data.DDIV:
+			f.push(a / b)
+		case data.DREM:
+			f.push(math.Mod(a, b))
+		}
+	}
+	return nil
+}
+
+func executeConversion(f *frame, opCode int) {
+	switch opCode {
+	case data.I2L:
+		f.push(int64(f.popInt()))
+	case data.I2F:
+		f.push(float32(f.popInt()))
+	case data.I2D:
+		f.push(float64(f.popInt()))
+	case data.L2I:
+		f.push(int32(f.popLong()))
+	case data.L2F:
+		f.push(float32(f.popLong()))
+	case data.L2D:
+		f.push(float64(f.popLong()))
+	case data.F2I:
+		f.push(int32(toInteger(float64(f.popFloat()), math.MinInt32, math.MaxInt32)))
+	case data.F2L:
+		f.push(toInteger(float64(f.popFloat()), math.MinInt64, math.MaxInt64))
+	case data.F2D:
+		f.push(float64(f.popFloat()))
+	case data.D2I:
+		f.push(int32(toInteger(f.popDouble(), math.MinInt32, math.MaxInt32)))
+	case data.D2L:
+		f.push(toInteger(f.popDouble(), math.MinInt64, math.MaxInt64))
+	case data.D2F:
+		f.push(float32(f.popDouble()))
+	case data.I2B:
+		f.push(int32(int8(f.popInt())))
+	case data.I2C:
+		f.push(int32(uint16(f.popInt())))
+	case data.I2S:
+		f.push(int32(int16(f.popInt())))
+	}
+}
+
+// toInteger converts a floating point value to an integer with the Java semantics: NaN is 0,
+// and the values out of range are clamped.
+func toInteger(value float64, min int64, max int64) int64 {
+	switch {
+	case math.IsNaN(value):
+		return 0
+	case value <= float64(min):
+		return min
+	case value >= float64(max):
+		return max
+	default:
+		return int64(value)
+	}
+}
+
+func executeComparison(f *frame, opCode int) {
+	var a, b float64
+	switch opCode {
+	case data.LCMP:
+		y, x := f.popLong(), f.popLong()
+		f.push(compare(x < y, x > y))
+		return
+	case data.FCMPL, data.FCMPG:
+		y, x := f.popFloat(), f.popFloat()
+		a, b = float64(x), float64(y)
+	default:
+		b, a = f.popDouble(), f.popDouble()
+	}
+	if math.IsNaN(a) || math.IsNaN(b) {
+		if opCode == data.FCMPG || opCode == data.DCMPG {
+			f.push(int32(1))
+		} else {
+			f.push(int32(-1))
+		}
+		return
+	}
+	f.push(compare(a < b, a > b))
+}
+
+func compare(less bool, greater bool) int32 {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,364 @@
+// Package interp executes a subset of the JVM bytecode: the int, long, float and double
+// arithmetic, the local variables, the arrays, the control flow and the exceptions, the static
+// and instance fields and methods of the loaded classes, the string constants, and the methods
+// of a native method table, which stubs java.lang.String and java.lang.StringBuilder by default.
+//
+// It is meant to evaluate the static initializers of classes, to test generated bytecode without
+// a JVM, or to decode obfuscated string constants, not to run whole programs: there are no
+// threads, no reflection, no garbage collection, and the execution is limited by a fuel.
+//
+// The values are represented by Go values: int32 for the boolean, byte, char, short and int
+// values, int64, float32, float64, string for the strings, *Array for the arrays, *Object for the
+// other objects, and nil for null.
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+// DefaultFuel is the default number of instructions which an interpreter can execute.
+const DefaultFuel = 10000000
+
+// DefaultMaxDepth is the default maximum depth of the method calls.
+const DefaultMaxDepth = 512
+
+// ErrOutOfFuel is returned when the fuel of an interpreter is exhausted.
+var ErrOutOfFuel = errors.New("out of fuel")
+
+// Value is an int32, int64, float32, float64, string, *Array or *Object value, or nil.
+type Value interface{}
+
+// Array is a Java array.
+type Array struct {
+	// Descriptor is the descriptor of the array type, such as "[I".
+	Descriptor string
+	Elements   []Value
+}
+
+// Object is a Java object which is not a string or an array.
+type Object struct {
+	// Class is the internal name of the class of the object.
+	Class string
+	// Fields are the instance fields of the object, by name.
+	Fields map[string]Value
+	// Native is the state of the objects implemented by native methods, such as the UTF-16
+	// characters of a StringBuilder.
+	Native interface{}
+}
+
+// Exception is the error of a Java exception which is thrown and not caught.
+type Exception struct {
+	Object *Object
+}
+
+func (e *Exception) Error() string {
+	if message, ok := e.Object.Fields["message"].(string); ok {
+		return "exception " + e.Object.Class + ": " + message
+	}
+	return "exception " + e.Object.Class
+}
+
+// Native is a method implemented in Go. The receiver of an instance method is the first
+// argument. A native constructor may return the value which replaces the new object, such as
+// the string built by a java.lang.String constructor.
+type Native func(in *Interpreter, args []Value) (Value, error)
+
+// Loader loads the classes executed by an interpreter. A classpath.Classpath is a Loader.
+type Loader interface {
+	Find(internalName string) (*class.Class, error)
+}
+
+// Interpreter executes the methods of the classes of a loader. It must not be used by several
+// goroutines at the same time.
+type Interpreter struct {
+	// Fuel is the number of instructions which can still be executed. The execution fails with
+	// ErrOutOfFuel when it is exhausted.
+	Fuel int64
+	// MaxDepth is the maximum depth of the method calls, after which a
+	// java/lang/StackOverflowError is thrown.
+	MaxDepth int
+	// Natives are the native methods, by "owner.name+descriptor". They take precedence over
+	// the methods of the loaded classes.
+	Natives map[string]Native
+
+	loader      Loader
+	classes     map[string]*class.Class
+	initialized map[string]bool
+	statics     map[string]Value
+	codes       map[*class.Method]*code
+	depth       int
+}
+
+// New returns an interpreter of the classes of a loader, with the default fuel, maximum depth
+// and native methods.
+func New(loader Loader) *Interpreter {
+	return &Interpreter{Fuel: DefaultFuel, MaxDepth: DefaultMaxDepth, Natives: DefaultNatives(), loader: loader,
+		classes: make(map[string]*class.Class), initialized: make(map[string]bool),
+		statics: make(map[string]Value), codes: make(map[*class.Method]*code)}
+}
+
+// class returns a loaded class, or nil if the loader cannot find it.
+func (in *Interpreter) class(name string) *class.Class {
+	c, ok := in.classes[name]
+	if !ok {
+		c, _ = in.loader.Find(name)
+		in.classes[name] = c
+	}
+	return c
+}
+
+// Initialize initializes a class, its superclasses and its static fields, and executes their
+// static initializers, if it is not already done.
+func (in *Interpreter) Initialize(className string) error {
+	if in.initialized[className] {
+		return nil
+	}
+	c := in.class(className)
+	if c == nil {
+		return fmt.Errorf("class %s not found", className)
+	}
+	in.initialized[className] = true
+	if c.SuperClass != "" && in.class(c.SuperClass) != nil {
+		if err := in.Initialize(c.SuperClass); err != nil {
+			return err
+		}
+	}
+	for _, field := range c.Fields {
+		if field.AccessFlags&data.ACC_STATIC != 0 {
+			value := zero(field.Descriptor)
+			if field.ConstantValue != nil {
+				value = field.ConstantValue
+			}
+			in.statics[className+"."+field.Name] = value
+		}
+	}
+	for i := range c.Methods {
+		if c.Methods[i].Name == "<clinit>" {
+			_, err := in.run(className, &c.Methods[i], nil)
+			return err
+		}
+	}
+	return nil
+}
+
+// StaticField returns the value of a static field, after the initialization of its class.
+func (in *Interpreter) StaticField(owner string, name string) (Value, error) {
+	declaringClass, err := in.staticField(owner, name)
+	if err != nil {
+		return nil, err
+	}
+	return in.statics[declaringClass+"."+name], nil
+}
+
+// staticField initializes the class which declares a static field, and returns its name.
+func (in *Interpreter) staticField(owner string, name string) (string, error) {
+	for c := in.class(owner); c != nil; c = in.class(c.SuperClass) {
+		for _, field := range c.Fields {
+			if field.Name == name && field.AccessFlags&data.ACC_STATIC != 0 {
+				return c.ThisClass, in.Initialize(c.ThisClass)
+			}
+		}
+	}
+	return "", fmt.Errorf("static field %s.%s not found", owner, name)
+}
+
+// Invoke executes a static method, with its arguments given as one value each.
+func (in *Interpreter) Invoke(owner string, name string, descriptor string, args ...Value) (Value, error) {
+	return in.invoke(data.INVOKESTATIC, owner, name, descriptor, args)
+}
+
+// NewObject returns a new object of a class, whose instance fields have their default values.
+func (in *Interpreter) NewObject(className string) *Object {
+	object := &Object{Class: className, Fields: make(map[string]Value)}
+	for c := in.class(className); c != nil; c = in.class(c.SuperClass) {
+		for _, field := range c.Fields {
+			if _, ok := object.Fields[field.Name]; !ok && field.AccessFlags&data.ACC_STATIC == 0 {
+				object.Fields[field.Name] = zero(field.Descriptor)
+			}
+		}
+	}
+	return object
+}
+
+// NewException returns a Java exception of the given class, with a message if it is not empty.
+func (in *Interpreter) NewException(className string, message string) *Exception {
+	object := in.NewObject(className)
+	if message != "" {
+		object.Fields["message"] = message
+	}
+	return &Exception{Object: object}
+}
+
+// invoke executes a method invocation, whose receiver is the first argument of the instance
+// methods.
+func (in *Interpreter) invoke(opCode int, owner string, name string, descriptor string, args []Value) (Value, error) {
+	key := owner + "." + name + descriptor
+	if opCode == data.INVOKEVIRTUAL || opCode == data.INVOKEINTERFACE {
+		if args[0] == nil {
+			return nil, in.NewException("java/lang/NullPointerException", "")
+		}
+		// Dispatches the call on the class of the receiver.
+		for className := runtimeClass(args[0]); className != ""; {
+			if native, ok := in.Natives[className+"."+name+descriptor]; ok {
+				return native(in, args)
+			}
+			c := in.class(className)
+			if c == nil {
+				className = builtinSuperClasses[className]
+				continue
+			}
+			if method := findMethod(c, name, descriptor); method != nil && method.AccessFlags&data.ACC_ABSTRACT == 0 {
+				return in.run(className, method, args)
+			}
+			className = c.SuperClass
+		}
+	}
+	if native, ok := in.Natives[key]; ok {
+		return native(in, args)
+	}
+	for c := in.class(owner); c != nil; c = in.class(c.SuperClass) {
+		if method := findMethod(c, name, descriptor); method != nil {
+			if opCode == data.INVOKESTATIC {
+				if err := in.Initialize(c.ThisClass); err != nil {
+					return nil, err
+				}
+			}
+			if method.AccessFlags&(data.ACC_ABSTRACT|data.ACC_NATIVE) != 0 {
+				break
+			}
+			return in.run(c.ThisClass, method, args)
+		}
+	}
+	return nil, fmt.Errorf("method %s not found", key)
+}
+
+func findMethod(c *class.Class, name string, descriptor string) *class.Method {
+	for i := range c.Methods {
+		if c.Methods[i].Name == name && c.Methods[i].Descriptor == descriptor {
+			return &c.Methods[i]
+		}
+	}
+	return nil
+}
+
+// runtimeClass returns the internal name of the class of a value.
+func runtimeClass(value Value) string {
+	switch v := value.(type) {
+	case string:
+		return "java/lang/String"
+	case *Object:
+		return v.Class
+	case *Array:
+		return "java/lang/Object"
+	default:
+		return ""
+	}
+}
+
+// builtinSuperClasses are the superclasses of the JDK classes known by the interpreter.
+var builtinSuperClasses = map[string]string{
+	"java/lang/String":                          "java/lang/Object",
+	"java/lang/StringBuilder":                   "java/lang/Object",
+	"java/lang/Throwable":                       "java/lang/Object",
+	"java/lang/Exception":                       "java/lang/Throwable",
+	"java/lang/Error":                           "java/lang/Throwable",
+	"java/lang/StackOverflowError":              "java/lang/Error",
+	"java/lang/RuntimeException":                "java/lang/Exception",
+	"java/lang/ArithmeticException":             "java/lang/RuntimeException",
+	"java/lang/ArrayStoreException":             "java/lang/RuntimeException",
+	"java/lang/ClassCastException":              "java/lang/RuntimeException",
+	"java/lang/IllegalArgumentException":        "java/lang/RuntimeException",
+	"java/lang/IllegalStateException":           "java/lang/RuntimeException",
+	"java/lang/IndexOutOfBoundsException":       "java/lang/RuntimeException",
+	"java/lang/ArrayIndexOutOfBoundsException":  "java/lang/IndexOutOfBoundsException",
+	"java/lang/StringIndexOutOfBoundsException": "java/lang/IndexOutOfBoundsException",
+	"java/lang/NegativeArraySizeException":      "java/lang/RuntimeException",
+	"java/lang/NullPointerException":            "java/lang/RuntimeException",
+	"java/lang/UnsupportedOperationException":   "java/lang/RuntimeException",
+}
+
+// builtinInterfaces are the interfaces of the JDK classes known by the interpreter.
+var builtinInterfaces = map[string][]string{
+	"java/lang/String":        {"java/io/Serializable", "java/lang/CharSequence", "java/lang/Comparable"},
+	"java/lang/StringBuilder": {"java/io/Serializable", "java/lang/CharSequence", "java/lang/Appendable"},
+	"java/lang/Throwable":     {"java/io/Serializable"},
+}
+
+// isSubclass returns true if a class is a subtype of another one, according to the loaded
+// classes and to the builtin JDK classes.
+func (in *Interpreter) isSubclass(name string, super string) bool {
+	if super == "java/lang/Object" {
+		return true
+	}
+	seen := make(map[string]bool)
+	queue := []string{name}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if name == super {
+			return true
+		}
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if c := in.class(name); c != nil {
+			queue = append(append(queue, c.SuperClass), c.Interfaces...)
+		} else {
+			queue = append(append(queue, builtinSuperClasses[name]), builtinInterfaces[name]...)
+		}
+	}
+	return false
+}
+
+// isInstance returns true if a non null value is an instance of a class or array type.
+func (in *Interpreter) isInstance(value Value, typeName string) bool {
+	if array, ok := value.(*Array); ok {
+		if !strings.HasPrefix(typeName, "[") {
+			return typeName == "java/lang/Object" || typeName == "java/lang/Cloneable" || typeName == "java/io/Serializable"
+		}
+		if array.Descriptor == typeName {
+			return true
+		}
+		element, target := array.Descriptor[1:], typeName[1:]
+		if strings.HasPrefix(element, "L") && strings.HasPrefix(target, "L") {
+			return in.isSubclass(element[1:len(element)-1], target[1:len(target)-1])
+		}
+		return strings.HasPrefix(element, "[") && target == "Ljava/lang/Object;"
+	}
+	return in.isSubclass(runtimeClass(value), typeName)
+}
+
+// zero returns the default value of a type.
+func zero(descriptor string) Value {
+	switch descriptor[0] {
+	case 'Z', 'B', 'C', 'S', 'I':
+		return int32(0)
+	case 'J':
+		return int64(0)
+	case 'F':
+		return float32(0)
+	case 'D':
+		return float64(0)
+	default:
+		return nil
+	}
+}
+
+// newArray returns a new array with the default values of its element type.
+func (in *Interpreter) newArray(descriptor string, length int32) (*Array, error) {
+	if length < 0 {
+		return nil, in.NewException("java/lang/NegativeArraySizeException", fmt.Sprint(length))
+	}
+	array := &Array{Descriptor: descriptor, Elements: make([]Value, length)}
+	value := zero(descriptor[1:])
+	for i := range array.Elements {
+		array.Elements[i] = value
+	}
+	return array, nil
+}
@@ -0,0 +1,229 @@
+package interp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tk103331/clazz/internal/testutil"
+	"github.com/tk103331/clazz/tools"
+)
+
+var sources = map[string]string{
+	"a/Math": `.class public super a/Math
+.super java/lang/Object
+.field public static final TABLE [I
+.field public static final NAME Ljava/lang/String;
+.method public static sum (I)J
+    lconst_0
+    lstore_1
+    iconst_1
+    istore_3
+L0:
+    iload_3
+    iload_0
+    if_icmpgt L1
+    lload_1
+    iload_3
+    i2l
+    ladd
+    lstore_1
+    iinc 3 1
+    goto L0
+L1:
+    lload_1
+    lreturn
+.end method
+.method public static square (I)I
+    iload_0
+    iload_0
+    invokestatic a/Math mul (II)I
+    ireturn
+.end method
+.method private static mul (II)I
+    iload_0
+    iload_1
+    imul
+    ireturn
+.end method
+.method public static safeDiv (II)I
+L0:
+    iload_0
+    iload_1
+    idiv
+L1:
+    ireturn
+L2:
+    pop
+    iconst_m1
+    ireturn
+    .catch java/lang/ArithmeticException from L0 to L1 using L2
+.end method
+.method public static div (II)I
+    iload_0
+    iload_1
+    idiv
+    ireturn
+.end method
+.method public static loop ()V
+L0:
+    goto L0
+.end method
+.method static <clinit> ()V
+    iconst_3
+    newarray int
+    dup
+    iconst_0
+    iconst_2
+    invokestatic a/Math square (I)I
+    iastore
+    dup
+    iconst_2
+    ldc 10
+    invokestatic a/Math square (I)I
+    iastore
+    putstatic a/Math TABLE [I
+    ldc 1.5f
+    f2d
+    ldc 2.0d
+    dmul
+    invokestatic java/lang/String valueOf (D)Ljava/lang/String;
+    putstatic a/Math NAME Ljava/lang/String;
+    return
+.end method
+`,
+	"a/Strings": `.class public super a/Strings
+.super java/lang/Object
+.method public static decode (Ljava/lang/String;I)Ljava/lang/String;
+    new java/lang/StringBuilder
+    dup
+    invokespecial java/lang/StringBuilder <init> ()V
+    astore_2
+    iconst_0
+    istore_3
+L0:
+    iload_3
+    aload_0
+    invokevirtual java/lang/String length ()I
+    if_icmpge L1
+    aload_2
+    aload_0
+    iload_3
+    invokevirtual java/lang/String charAt (I)C
+    iload_1
+    ixor
+    i2c
+    invokevirtual java/lang/StringBuilder append (C)Ljava/lang/StringBuilder;
+    pop
+    iinc 3 1
+    goto L0
+L1:
+    aload_2
+    invokevirtual java/lang/StringBuilder toString ()Ljava/lang/String;
+    areturn
+.end method
+.method public static chars ()Ljava/lang/String;
+    new java/lang/String
+    dup
+    iconst_2
+    newarray char
+    dup
+    iconst_0
+    bipush 104
+    castore
+    dup
+    iconst_1
+    bipush 105
+    castore
+    invokespecial java/lang/String <init> ([C)V
+    areturn
+.end method
+.method public static hash ()I
+    ldc "hello"
+    invokevirtual java/lang/String hashCode ()I
+    ireturn
+.end method
+.method public static native secret ()Ljava/lang/String;
+.end method
+`,
+}
+
+func TestInvoke(t *testing.T) {
+	in := New(testutil.Classpath(t, sources))
+	result, err := in.Invoke("a/Math", "sum", "(I)J", int32(100))
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, int64(5050), result)
+
+	result, err = in.Invoke("a/Math", "square", "(I)I", int32(-7))
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, int32(49), result)
+
+	result, err = in.Invoke("a/Math", "safeDiv", "(II)I", int32(7), int32(0))
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, int32(-1), result)
+
+	_, err = in.Invoke("a/Math", "div", "(II)I", int32(7), int32(0))
+	exception, ok := err.(*Exception)
+	tools.AssertEqual(t, true, ok)
+	tools.AssertEqual(t, "java/lang/ArithmeticException", exception.Object.Class)
+	tools.AssertEqual(t, "exception java/lang/ArithmeticException: / by zero", err.Error())
+}
+
+func TestStaticInitializer(t *testing.T) {
+	in := New(testutil.Classpath(t, sources))
+	table, err := in.StaticField("a/Math", "TABLE")
+	tools.AssertNoErr(t, err)
+	array := table.(*Array)
+	tools.AssertEqual(t, "[I", array.Descriptor)
+	tools.AssertEqual(t, 3, len(array.Elements))
+	tools.AssertEqual(t, int32(4), array.Elements[0])
+	tools.AssertEqual(t, int32(0), array.Elements[1])
+	tools.AssertEqual(t, int32(100), array.Elements[2])
+
+	name, err := in.StaticField("a/Math", "NAME")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "3.0", name)
+}
+
+func TestStrings(t *testing.T) {
+	in := New(testutil.Classpath(t, sources))
+	result, err := in.Invoke("a/Strings", "decode", "(Ljava/lang/String;I)Ljava/lang/String;", "KFOOL", int32(3))
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "HELLO", result)
+
+	result, err = in.Invoke("a/Strings", "chars", "()Ljava/lang/String;")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "hi", result)
+
+	result, err = in.Invoke("a/Strings", "hash", "()I")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, int32(99162322), result)
+}
+
+func TestNatives(t *testing.T) {
+	in := New(testutil.Classpath(t, sources))
+	_, err := in.Invoke("a/Strings", "secret", "()Ljava/lang/String;")
+	tools.AssertEqual(t, "method a/Strings.secret()Ljava/lang/String; not found", err.Error())
+
+	in.Natives["a/Strings.secret()Ljava/lang/String;"] = func(in *Interpreter, args []Value) (Value, error) {
+		return "secret", nil
+	}
+	result, err := in.Invoke("a/Strings", "secret", "()Ljava/lang/String;")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "secret", result)
+}
+
+func TestFuel(t *testing.T) {
+	in := New(testutil.Classpath(t, sources))
+	in.Fuel = 1000
+	_, err := in.Invoke("a/Math", "loop", "()V")
+	tools.AssertEqual(t, ErrOutOfFuel, err)
+	tools.AssertEqual(t, int64(0), in.Fuel)
+}
+
+func TestFormatFloat(t *testing.T) {
+	tools.AssertEqual(t, "1.0", formatFloat(1, 64))
+	tools.AssertEqual(t, "0.1", formatFloat(float64(float32(0.1)), 32))
+	tools.AssertEqual(t, "1.0E7", formatFloat(1e7, 64))
+	tools.AssertEqual(t, "1.5E-4", formatFloat(1.5e-4, 64))
+	tools.AssertEqual(t, "-0.0", formatFloat(math.Copysign(0, -1), 64))
+}
@@ -0,0 +1,358 @@
+package interp
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+// DefaultNatives returns the default native methods: the constructor of java.lang.Object, the
+// common methods of java.lang.String and java.lang.StringBuilder, and the constructors and
+// messages of the JDK exceptions known by the interpreter.
+func DefaultNatives() map[string]Native {
+	natives := map[string]Native{
+		"java/lang/Object.<init>()V": func(in *Interpreter, args []Value) (Value, error) {
+			return nil, nil
+		},
+
+		"java/lang/String.<init>()V": func(in *Interpreter, args []Value) (Value, error) {
+			return "", nil
+		},
+		"java/lang/String.<init>(Ljava/lang/String;)V": func(in *Interpreter, args []Value) (Value, error) {
+			return args[1].(string), nil
+		},
+		"java/lang/String.<init>([C)V": func(in *Interpreter, args []Value) (Value, error) {
+			chars, err := in.chars(args[1])
+			if err != nil {
+				return nil, err
+			}
+			return fromUTF16(chars), nil
+		},
+		"java/lang/String.<init>([CII)V": func(in *Interpreter, args []Value) (Value, error) {
+			chars, err := in.chars(args[1])
+			if err != nil {
+				return nil, err
+			}
+			start, end := int(args[2].(int32)), int(args[2].(int32))+int(args[3].(int32))
+			if start < 0 || end < start || end > len(chars) {
+				return nil, in.NewException("java/lang/StringIndexOutOfBoundsException", "")
+			}
+			return fromUTF16(chars[start:end]), nil
+		},
+		"java/lang/String.<init>([B)V": func(in *Interpreter, args []Value) (Value, error) {
+			array, ok := args[1].(*Array)
+			if !ok {
+				return nil, in.NewException("java/lang/NullPointerException", "")
+			}
+			bytes := make([]byte, len(array.Elements))
+			for i, b := range array.Elements {
+				bytes[i] = byte(b.(int32))
+			}
+			return strings.ToValidUTF8(string(bytes), "\uFFFD"), nil
+		},
+		"java/lang/String.length()I": func(in *Interpreter, args []Value) (Value, error) {
+			return int32(len(toUTF16(args[0].(string)))), nil
+		},
+		"java/lang/String.isEmpty()Z": func(in *Interpreter, args []Value) (Value, error) {
+			return boolean(args[0].(string) == ""), nil
+		},
+		"java/lang/String.charAt(I)C": func(in *Interpreter, args []Value) (Value, error) {
+			return in.charAt(toUTF16(args[0].(string)), args[1].(int32))
+		},
+		"java/lang/String.equals(Ljava/lang/Object;)Z": func(in *Interpreter, args []Value) (Value, error) {
+			other, ok := args[1].(string)
+			return boolean(ok && other == args[0].(string)), nil
+		},
+		"java/lang/String.hashCode()I": func(in *Interpreter, args []Value) (Value, error) {
+			var hash int32
+			for _, c := range toUTF16(args[0].(string)) {
+				hash = 31*hash + int32(c)
+			}
+			return hash, nil
+		},
+		"java/lang/String.concat(Ljava/lang/String;)Ljava/lang/String;": func(in *Interpreter, args []Value) (Value, error) {
+			other, ok := args[1].(string)
+			if !ok {
+				return nil, in.NewException("java/lang/NullPointerException", "")
+			}
+			return args[0].(string) + other, nil
+		},
+		"java/lang/String.substring(I)Ljava/lang/String;": func(in *Interpreter, args []Value) (Value, error) {
+			chars := toUTF16(args[0].(string))
+			return in.substring(chars, args[1].(int32), int32(len(chars)))
+		},
+		"java/lang/String.substring(II)Ljava/lang/String;": func(in *Interpreter, args []Value) (Value, error) {
+			return in.substring(toUTF16(args[0].(string)), args[1].(int32), args[2].(int32))
+		},
+		"java/lang/String.indexOf(I)I": func(in *Interpreter, args []Value) (Value, error) {
+			for i, c := range toUTF16(args[0].(string)) {
+				if int32(c) == args[1].(int32) {
+					return int32(i), nil
+				}
+			}
+			return int32(-1), nil
+		},
+		"java/lang/String.toCharArray()[C": func(in *Interpreter, args []Value) (Value, error) {
+			chars := toUTF16(args[0].(string))
+			array := &Array{Descriptor: "[C", Elements: make([]Value, len(chars))}
+			for i, c := range chars {
+				array.Elements[i] = int32(c)
+			}
+			return array, nil
+		},
+		"java/lang/String.getBytes()[B": func(in *Interpreter, args []Value) (Value, error) {
+			s := args[0].(string)
+			array := &Array{Descriptor: "[B", Elements: make([]Value, len(s))}
+			for i := 0; i < len(s); i++ {
+				array.Elements[i] = int32(int8(s[i]))
+			}
+			return array, nil
+		},
+		"java/lang/String.intern()Ljava/lang/String;": func(in *Interpreter, args []Value) (Value, error) {
+			return args[0], nil
+		},
+		"java/lang/String.toString()Ljava/lang/String;": func(in *Interpreter, args []Value) (Value, error) {
+			return args[0], nil
+		},
+		"java/lang/String.valueOf([C)Ljava/lang/String;": func(in *Interpreter, args []Value) (Value, error) {
+			chars, err := in.chars(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return fromUTF16(chars), nil
+		},
+
+		"java/lang/StringBuilder.<init>()V": func(in *Interpreter, args []Value) (Value, error) {
+			args[0].(*Object).Native = &[]uint16{}
+			return nil, nil
+		},
+		"java/lang/StringBuilder.<init>(I)V": func(in *Interpreter, args []Value) (Value, error) {
+			if args[1].(int32) < 0 {
+				return nil, in.NewException("java/lang/NegativeArraySizeException", strconv.Itoa(int(args[1].(int32))))
+			}
+			args[0].(*Object).Native = &[]uint16{}
+			return nil, nil
+		},
+		"java/lang/StringBuilder.<init>(Ljava/lang/String;)V": func(in *Interpreter, args []Value) (Value, error) {
+			s, ok := args[1].(string)
+			if !ok {
+				return nil, in.NewException("java/lang/NullPointerException", "")
+			}
+			chars := toUTF16(s)
+			args[0].(*Object).Native = &chars
+			return nil, nil
+		},
+		"java/lang/StringBuilder.toString()Ljava/lang/String;": func(in *Interpreter, args []Value) (Value, error) {
+			return fromUTF16(*builder(args[0])), nil
+		},
+		"java/lang/StringBuilder.length()I": func(in *Interpreter, args []Value) (Value, error) {
+			return int32(len(*builder(args[0]))), nil
+		},
+		"java/lang/StringBuilder.charAt(I)C": func(in *Interpreter, args []Value) (Value, error) {
+			return in.charAt(*builder(args[0]), args[1].(int32))
+		},
+		"java/lang/StringBuilder.setCharAt(IC)V": func(in *Interpreter, args []Value) (Value, error) {
+			chars := *builder(args[0])
+			if _, err := in.charAt(chars, args[1].(int32)); err != nil {
+				return nil, err
+			}
+			chars[args[1].(int32)] = uint16(args[2].(int32))
+			return nil, nil
+		},
+		"java/lang/StringBuilder.reverse()Ljava/lang/StringBuilder;": func(in *Interpreter, args []Value) (Value, error) {
+			// Reverses the code points, so that the surrogate pairs are preserved.
+			chars := builder(args[0])
+			runes := utf16.Decode(*chars)
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			*chars = utf16.Encode(runes)
+			return args[0], nil
+		},
+
+		"java/lang/Throwable.getMessage()Ljava/lang/String;": func(in *Interpreter, args []Value) (Value, error) {
+			return args[0].(*Object).Fields["message"], nil
+		},
+		"java/lang/Throwable.toString()Ljava/lang/String;": func(in *Interpreter, args []Value) (Value, error) {
+			object := args[0].(*Object)
+			name := strings.Replace(object.Class, "/", ".", -1)
+			if message, ok := object.Fields["message"].(string); ok {
+				return name + ": " + message, nil
+			}
+			return name, nil
+		},
+	}
+
+	for _, descriptor := range []string{"I", "J", "C", "Z", "F", "D", "Ljava/lang/Object;"} {
+		descriptor := descriptor
+		natives["java/lang/String.valueOf("+descriptor+")Ljava/lang/String;"] = func(in *Interpreter, args []Value) (Value, error) {
+			return in.toString(args[0], descriptor)
+		}
+	}
+	for _, descriptor := range []string{"I", "J", "C", "Z", "F", "D", "Ljava/lang/String;", "Ljava/lang/Object;",
+		"Ljava/lang/CharSequence;", "[C"} {
+		descriptor := descriptor
+		natives["java/lang/StringBuilder.append("+descriptor+")Ljava/lang/StringBuilder;"] = func(in *Interpreter, args []Value) (Value, error) {
+			var s string
+			if descriptor == "[C" {
+				chars, err := in.chars(args[1])
+				if err != nil {
+					return nil, err
+				}
+				s = fromUTF16(chars)
+			} else {
+				var err error
+				if s, err = in.toString(args[1], descriptor); err != nil {
+					return nil, err
+				}
+			}
+			chars := builder(args[0])
+			*chars = append(*chars, toUTF16(s)...)
+			return args[0], nil
+		}
+	}
+	for className := range builtinSuperClasses {
+		if !isThrowable(className) {
+			continue
+		}
+		natives[className+".<init>()V"] = func(in *Interpreter, args []Value) (Value, error) {
+			return nil, nil
+		}
+		natives[className+".<init>(Ljava/lang/String;)V"] = func(in *Interpreter, args []Value) (Value, error) {
+			args[0].(*Object).Fields["message"] = args[1]
+			return nil, nil
+		}
+	}
+	return natives
+}
+
+// isThrowable returns true if a builtin JDK class is a subclass of java.lang.Throwable.
+func isThrowable(className string) bool {
+	for ; className != ""; className = builtinSuperClasses[className] {
+		if className == "java/lang/Throwable" {
+			return true
+		}
+	}
+	return false
+}
+
+func toUTF16(s string) []uint16 {
+	return utf16.Encode([]rune(s))
+}
+
+func fromUTF16(chars []uint16) string {
+	return string(utf16.Decode(chars))
+}
+
+// builder returns the characters of a java.lang.StringBuilder.
+func builder(value Value) *[]uint16 {
+	object := value.(*Object)
+	if object.Native == nil {
+		object.Native = &[]uint16{}
+	}
+	return object.Native.(*[]uint16)
+}
+
+// chars returns the characters of a char array.
+func (in *Interpreter) chars(value Value) ([]uint16, error) {
+	array, ok := value.(*Array)
+	if !ok {
+		return nil, in.NewException("java/lang/NullPointerException", "")
+	}
+	chars := make([]uint16, len(array.Elements))
+	for i, c := range array.Elements {
+		chars[i] = uint16(c.(int32))
+	}
+	return chars, nil
+}
+
+func (in *Interpreter) charAt(chars []uint16, index int32) (Value, error) {
+	if index < 0 || int(index) >= len(chars) {
+		return nil, in.NewException("java/lang/StringIndexOutOfBoundsException",
+			"index "+strconv.Itoa(int(index))+", length "+strconv.Itoa(len(chars)))
+	}
+	return int32(chars[index]), nil
+}
+
+func (in *Interpreter) substring(chars []uint16, begin int32, end int32) (Value, error) {
+	if begin < 0 || end < begin || int(end) > len(chars) {
+		return nil, in.NewException("java/lang/StringIndexOutOfBoundsException",
+			"begin "+strconv.Itoa(int(begin))+", end "+strconv.Itoa(int(end))+", length "+strconv.Itoa(len(chars)))
+	}
+	return fromUTF16(chars[begin:end]), nil
+}
+
+// toString converts a value of the given type to a string, like String.valueOf. The objects
+// other than strings and string builders are converted by their toString method.
+func (in *Interpreter) toString(value Value, descriptor string) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case int32:
+		switch descriptor {
+		case "C":
+			return fromUTF16([]uint16{uint16(v)}), nil
+		case "Z":
+			return strconv.FormatBool(v != 0), nil
+		}
+		return strconv.Itoa(int(v)), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float32:
+		return formatFloat(float64(v), 32), nil
+	case float64:
+		return formatFloat(v, 64), nil
+	case string:
+		return v, nil
+	}
+	if object, ok := value.(*Object); ok {
+		if chars, ok := object.Native.(*[]uint16); ok {
+			return fromUTF16(*chars), nil
+		}
+	}
+	result, err := in.invoke(data.INVOKEVIRTUAL, "java/lang/Object", "toString", "()Ljava/lang/String;", []Value{value})
+	if err != nil {
+		return "", err
+	}
+	return in.toString(result, "Ljava/lang/String;")
+}
+
+// formatFloat formats a float or double like Float.toString and Double.toString: with at least
+// one digit after the decimal point, and in the computerized scientific notation outside of
+// [10^-3, 10^7).
+func formatFloat(value float64, bitSize int) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Infinity"
+	case math.IsInf(value, -1):
+		return "-Infinity"
+	}
+	abs := math.Abs(value)
+	if abs == 0 || abs >= 1e-3 && abs < 1e7 {
+		s := strconv.FormatFloat(value, 'f', -1, bitSize)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		if value == 0 && math.Signbit(value) {
+			return "-0.0"
+		}
+		return s
+	}
+	s := strconv.FormatFloat(value, 'E', -1, bitSize)
+	mantissa, exponent := s[:strings.IndexByte(s, 'E')], s[strings.IndexByte(s, 'E')+1:]
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	exponent = strings.TrimPrefix(exponent, "+")
+	if strings.HasPrefix(exponent, "-") {
+		exponent = "-" + strings.TrimLeft(exponent[1:], "0")
+	} else {
+		exponent = strings.TrimLeft(exponent, "0")
+	}
+	return mantissa + "E" + exponent
+}
@@ -0,0 +1,536 @@
+package jasm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+// Assemble returns the bytes of the class described by the given source.
+func Assemble(source []byte) ([]byte, error) {
+	c, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	writer := class.NewWriter()
+	c.Accept(writer)
+	return writer.Bytes()
+}
+
+// Parse returns the class described by the given source, with the max stack and max locals
+// values and the stack map frames of its methods computed as described in the package
+// documentation.
+func Parse(source []byte) (c *class.Class, err error) {
+	p := &parser{builder: class.NewBuilder(), version: 52}
+	for i, text := range strings.Split(string(source), "\n") {
+		l, err := tokenize(text, i+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(l.tokens) > 0 {
+			p.lines = append(p.lines, l)
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(syntaxError); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+	p.parseClass()
+	return p.finish()
+}
+
+type syntaxError struct {
+	line    int
+	message string
+}
+
+func (e syntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.message)
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+// line is a line of the source, split in tokens, with a position to read them.
+type line struct {
+	number int
+	tokens []token
+	pos    int
+}
+
+// tokenize splits a line in tokens.
+func tokenize(text string, number int) (*line, error) {
+	l := &line{number: number}
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == ';':
+			return l, nil
+		case c == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				return nil, syntaxError{number, "unterminated string"}
+			}
+			value, err := strconv.Unquote(text[i : end+1])
+			if err != nil {
+				return nil, syntaxError{number, fmt.Sprintf("invalid string %s", text[i:end+1])}
+			}
+			l.tokens = append(l.tokens, token{text: value, quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(text) && text[end] != ' ' && text[end] != '\t' && text[end] != '\r' {
+				end++
+			}
+			l.tokens = append(l.tokens, token{text: text[i:end]})
+			i = end
+		}
+	}
+	return l, nil
+}
+
+func (l *line) fail(format string, args ...interface{}) {
+	panic(syntaxError{l.number, fmt.Sprintf(format, args...)})
+}
+
+func (l *line) more() bool {
+	return l.pos < len(l.tokens)
+}
+
+// peek returns the next unquoted token, or an empty string.
+func (l *line) peek() string {
+	if !l.more() || l.tokens[l.pos].quoted {
+		return ""
+	}
+	return l.tokens[l.pos].text
+}
+
+func (l *line) next() token {
+	if !l.more() {
+		l.fail("unexpected end of line")
+	}
+	l.pos++
+	return l.tokens[l.pos-1]
+}
+
+func (l *line) word() string {
+	return l.next().text
+}
+
+func (l *line) expect(keyword string) {
+	if t := l.next(); t.quoted || t.text != keyword {
+		l.fail("expected %s instead of %q", keyword, t.text)
+	}
+}
+
+func (l *line) end() {
+	if l.more() {
+		l.fail("unexpected %q", l.tokens[l.pos].text)
+	}
+}
+
+func (l *line) int(bitSize int) int64 {
+	text := l.word()
+	value, err := strconv.ParseInt(text, 0, bitSize)
+	if err != nil {
+		l.fail("invalid integer %q", text)
+	}
+	return value
+}
+
+func (l *line) float(text string, bitSize int) float64 {
+	value, err := strconv.ParseFloat(text, bitSize)
+	if err != nil {
+		l.fail("invalid number %q", text)
+	}
+	return value
+}
+
+// access reads the access flag keywords of a kind of element, leaving at least required tokens
+// before the stop keyword or the end of the line.
+func (l *line) access(kind int, required int, stop string) uint16 {
+	end := l.pos
+	for end < len(l.tokens) && (l.tokens[end].quoted || l.tokens[end].text != stop) {
+		end++
+	}
+	access := uint16(0)
+	for l.pos < end-required && !l.tokens[l.pos].quoted {
+		flag, ok := accessFlag(l.tokens[l.pos].text, kind)
+		if !ok {
+			break
+		}
+		access |= flag
+		l.pos++
+	}
+	return access
+}
+
+// methodInfo records whether the max values and the frames of a method are given. The frames
+// are given by .stack directives, or are omitted with .noframes.
+type methodInfo struct {
+	code      bool
+	maxStack  int
+	maxLocals int
+	frames    bool
+}
+
+type parser struct {
+	lines   []*line
+	index   int
+	builder *class.Builder
+	version uint32
+	methods []methodInfo
+	labels  map[string]*class.Label
+}
+
+func (p *parser) next() *line {
+	l := p.lines[p.index]
+	p.index++
+	return l
+}
+
+// peek returns the first token of the next line, or an empty string.
+func (p *parser) peek() string {
+	if p.index >= len(p.lines) {
+		return ""
+	}
+	return p.lines[p.index].peek()
+}
+
+func (p *parser) parseClass() {
+	var access uint16
+	var name, signature, superName string
+	var interfaces []string
+	for p.index < len(p.lines) {
+		directive := p.peek()
+		if directive != ".version" && directive != ".class" && directive != ".super" &&
+			directive != ".implements" && directive != ".signature" {
+			break
+		}
+		l := p.next()
+		l.pos++
+		switch directive {
+		case ".version":
+			major := l.int(16)
+			minor := int64(0)
+			if l.more() {
+				minor = l.int(16)
+			}
+			p.version = uint32(minor)<<16 | uint32(major)
+		case ".class":
+			access = l.access(data.ACCESS_CLASS, 1, "")
+			name = l.word()
+		case ".super":
+			superName = l.word()
+		case ".implements":
+			interfaces = append(interfaces, l.word())
+		case ".signature":
+			signature = l.word()
+		}
+		l.end()
+	}
+	if name == "" {
+		panic(syntaxError{1, "missing .class directive"})
+	}
+	visitor := p.builder
+	visitor.Visit(p.version, access, name, signature, superName, interfaces)
+	for p.index < len(p.lines) {
+		l := p.next()
+		switch directive := l.word(); directive {
+		case ".source":
+			visitor.VisitSource(l.word(), p.builder.Class().SourceDebugExtension)
+		case ".debug":
+			visitor.VisitSource(p.builder.Class().SourceFile, l.word())
+		case ".deprecated":
+			visitor.VisitAttribute(class.Attribute{Name: data.DEPRECATED})
+		case ".module":
+			p.parseModule(l)
+		case ".nesthost":
+			visitor.VisitNestHost(l.word())
+		case ".nestmember":
+			visitor.VisitNestMember(l.word())
+		case ".enclosing":
+			l.expect("method")
+			owner := l.word()
+			methodName, descriptor := "", ""
+			if l.more() {
+				methodName, descriptor = l.word(), l.word()
+			}
+			visitor.VisitOuterClass(owner, methodName, descriptor)
+		case ".innerclass":
+			access := l.access(data.ACCESS_INNER_CLASS, 3, "")
+			visitor.VisitInnerClass(l.word(), l.word(), l.word(), access)
+		case ".annotation":
+			visible := p.visibility(l)
+			p.parseAnnotation(visitor.VisitAnnotation(l.word(), visible))
+		case ".attribute":
+			visitor.VisitAttribute(p.attribute(l))
+		case ".field":
+			p.parseField(l)
+		case ".method":
+			p.parseMethod(l)
+		default:
+			l.fail("unexpected %q", directive)
+		}
+		l.end()
+	}
+	visitor.VisitEnd()
+}
+
+func (p *parser) visibility(l *line) bool {
+	switch visibility := l.word(); visibility {
+	case "visible":
+		return true
+	case "invisible":
+		return false
+	default:
+		l.fail("expected visible or invisible instead of %q", visibility)
+		return false
+	}
+}
+
+func (p *parser) attribute(l *line) class.Attribute {
+	name := l.word()
+	content, err := hex.DecodeString(l.word())
+	if err != nil {
+		l.fail("invalid attribute content: %v", err)
+	}
+	return class.Attribute{Name: name, Content: content}
+}
+
+// block returns the lines up to the given end directive, which is consumed.
+func (p *parser) block(start *line, end string) []*line {
+	var lines []*line
+	// depth is the number of nested annotation blocks, which are kept in the lines.
+	depth := 0
+	for {
+		if p.index >= len(p.lines) {
+			start.fail("missing .end %s", end)
+		}
+		l := p.next()
+		switch l.peek() {
+		case ".annotation", ".parameterannotation":
+			depth++
+		case ".end":
+			if depth == 0 {
+				l.pos++
+				l.expect(end)
+				l.end()
+				return lines
+			}
+			depth--
+		}
+		lines = append(lines, l)
+	}
+}
+
+func (p *parser) parseModule(l *line) {
+	access := l.access(data.ACCESS_MODULE, 1, "version")
+	name := l.word()
+	version := ""
+	if l.peek() == "version" {
+		l.pos++
+		version = l.word()
+	}
+	visitor := p.builder.VisitModule(name, access, version)
+	for _, l := range p.block(l, "module") {
+		switch directive := l.word(); directive {
+		case ".requires":
+			access := l.access(data.ACCESS_MODULE_REQUIRES, 1, "version")
+			name := l.word()
+			version := ""
+			if l.peek() == "version" {
+				l.pos++
+				version = l.word()
+			}
+			visitor.VisitRequire(name, access, version)
+		case ".exports", ".opens":
+			access := l.access(data.ACCESS_MODULE_EXPORTS, 1, "to")
+			name := l.word()
+			var modules []string
+			if l.peek() == "to" {
+				l.pos++
+				for l.more() {
+					modules = append(modules, l.word())
+				}
+			}
+			if directive == ".exports" {
+				visitor.VisitExport(name, access, modules)
+			} else {
+				visitor.VisitOpen(name, access, modules)
+			}
+		case ".uses":
+			visitor.VisitUse(l.word())
+		case ".provides":
+			service := l.word()
+			l.expect("with")
+			var providers []string
+			for l.more() {
+				providers = append(providers, l.word())
+			}
+			visitor.VisitProvide(service, providers)
+		case ".mainclass":
+			visitor.VisitMainClass(l.word())
+		case ".package":
+			visitor.VisitPackage(l.word())
+		default:
+			l.fail("unexpected %q in module", directive)
+		}
+		l.end()
+	}
+	visitor.VisitEnd()
+}
+
+func (p *parser) parseField(l *line) {
+	access := l.access(data.ACCESS_FIELD, 2, "=")
+	name, descriptor := l.word(), l.word()
+	var value interface{}
+	if l.peek() == "=" {
+		l.pos++
+		value = p.constant(l)
+	}
+	var body []*line
+	switch p.peek() {
+	case ".signature", ".deprecated", ".annotation", ".attribute":
+		body = p.block(l, "field")
+	}
+	signature := ""
+	for _, l := range header(body, ".signature") {
+		signature = l.word()
+	}
+	visitor := p.builder.VisitField(access, name, descriptor, signature, value)
+	p.parseBody(body, func(l *line, directive string) {
+		switch directive {
+		case ".deprecated":
+			visitor.VisitAttribute(class.Attribute{Name: data.DEPRECATED})
+		case ".annotation":
+			visible := p.visibility(l)
+			p.parseAnnotation(visitor.VisitAnnotation(l.word(), visible))
+		case ".attribute":
+			visitor.VisitAttribute(p.attribute(l))
+		default:
+			l.fail("unexpected %q in field", directive)
+		}
+	})
+	visitor.VisitEnd()
+}
+
+// parseBody parses the lines of a field or method body, except the .signature and .throws
+// directives, and the lines of the annotations which are consumed by the parse function.
+func (p *parser) parseBody(body []*line, parse func(l *line, directive string)) {
+	lines, index := p.lines, p.index
+	p.lines, p.index = body, 0
+	for p.index < len(p.lines) {
+		l := p.next()
+		directive := l.peek()
+		if directive == ".signature" || directive == ".throws" {
+			continue
+		}
+		if directive != "" {
+			l.pos++
+		}
+		parse(l, directive)
+		l.end()
+	}
+	p.lines, p.index = lines, index
+}
+
+// header returns the lines of a body with the given directive, positioned after the directive.
+// The .signature and .throws directives are read before the others, which are visited after the
+// field or method.
+func header(body []*line, directive string) []*line {
+	var lines []*line
+	for _, l := range body {
+		if l.peek() == directive {
+			lines = append(lines, &line{number: l.number, tokens: l.tokens, pos: 1})
+		}
+	}
+	return lines
+}
+
+func (p *parser) parseAnnotation(visitor class.AnnotationVisitor) {
+	start := p.lines[p.index-1]
+	for _, l := range p.block(start, "annotation") {
+		name := l.word()
+		l.expect("=")
+		p.elementValue(l, visitor, name)
+		l.end()
+	}
+	visitor.VisitEnd()
+}
+
+func (p *parser) elementValue(l *line, visitor class.AnnotationVisitor, name string) {
+	tag := l.word()
+	switch tag {
+	case "Z":
+		switch value := l.word(); value {
+		case "true", "false":
+			visitor.Visit(name, value == "true")
+		default:
+			l.fail("invalid boolean %q", value)
+		}
+	case "B":
+		visitor.Visit(name, int8(l.int(8)))
+	case "C":
+		value := l.int(32)
+		visitor.Visit(name, uint16(value))
+	case "S":
+		visitor.Visit(name, int16(l.int(16)))
+	case "I":
+		visitor.Visit(name, int32(l.int(32)))
+	case "J":
+		text := strings.TrimSuffix(l.word(), "L")
+		value, err := strconv.ParseInt(text, 0, 64)
+		if err != nil {
+			l.fail("invalid long %q", text)
+		}
+		visitor.Visit(name, value)
+	case "F":
+		visitor.Visit(name, float32(l.float(l.word(), 32)))
+	case "D":
+		visitor.Visit(name, l.float(l.word(), 64))
+	case "s":
+		visitor.Visit(name, l.word())
+	case "e":
+		visitor.VisitEnum(name, l.word(), l.word())
+	case "c":
+		visitor.Visit(name, class.NewType(l.word()))
+	case "@":
+		annotation := visitor.VisitAnnotation(name, l.word())
+		l.expect("{")
+		for l.peek() != "}" {
+			elementName := l.word()
+			l.expect("=")
+			p.elementValue(l, annotation, elementName)
+		}
+		l.pos++
+		annotation.VisitEnd()
+	case "[":
+		array := visitor.VisitArray(name)
+		for l.peek() != "]" {
+			p.elementValue(l, array, "")
+		}
+		l.pos++
+		array.VisitEnd()
+	default:
+		l.fail("invalid element value tag %q", tag)
+	}
+}
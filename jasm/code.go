@@ -0,0 +1,403 @@
+package jasm
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+func (p *parser) parseMethod(l *line) {
+	access := l.access(data.ACCESS_METHOD, 2, "")
+	name, descriptor := l.word(), l.word()
+	body := p.block(l, "method")
+	signature := ""
+	for _, l := range header(body, ".signature") {
+		signature = l.word()
+	}
+	var exceptions []string
+	for _, l := range header(body, ".throws") {
+		exceptions = append(exceptions, l.word())
+	}
+	visitor := p.builder.VisitMethod(access, name, descriptor, signature, exceptions)
+	info := methodInfo{maxStack: -1, maxLocals: -1}
+	p.labels = make(map[string]*class.Label)
+	var lastLabel *class.Label
+	p.parseBody(body, func(l *line, directive string) {
+		if !info.code {
+			switch directive {
+			case ".deprecated":
+				visitor.VisitAttribute(class.Attribute{Name: data.DEPRECATED})
+				return
+			case ".parameter":
+				access := l.access(data.ACCESS_PARAMETER, 1, "")
+				visitor.VisitParameter(l.word(), access)
+				return
+			case ".annotation":
+				visible := p.visibility(l)
+				p.parseAnnotation(visitor.VisitAnnotation(l.word(), visible))
+				return
+			case ".annotableparameters":
+				visible := p.visibility(l)
+				visitor.VisitAnnotableParameterCount(int(l.int(8)), visible)
+				return
+			case ".parameterannotation":
+				parameter := int(l.int(8))
+				visible := p.visibility(l)
+				p.parseAnnotation(visitor.VisitParameterAnnotation(parameter, l.word(), visible))
+				return
+			case ".annotationdefault":
+				annotation := visitor.VisitAnnotationDefault()
+				p.elementValue(l, annotation, "")
+				annotation.VisitEnd()
+				return
+			case ".attribute":
+				visitor.VisitAttribute(p.attribute(l))
+				return
+			}
+			info.code = true
+			visitor.VisitCode()
+		}
+		label := lastLabel
+		lastLabel = nil
+		switch directive {
+		case ".limit":
+			switch limit := l.word(); limit {
+			case "stack":
+				info.maxStack = int(l.int(32))
+			case "locals":
+				info.maxLocals = int(l.int(32))
+			default:
+				l.fail("unknown limit %q", limit)
+			}
+			lastLabel = label
+		case ".line":
+			number := int(l.int(32))
+			if l.more() {
+				label = p.label(l, l.word())
+			} else if label == nil {
+				label = class.NewLabel()
+				visitor.VisitLabel(label)
+			}
+			visitor.VisitLineNumber(number, label)
+			lastLabel = label
+		case ".catch":
+			catchType := l.word()
+			if catchType == "any" {
+				catchType = ""
+			}
+			l.expect("from")
+			start := p.label(l, l.word())
+			l.expect("to")
+			end := p.label(l, l.word())
+			l.expect("using")
+			visitor.VisitTryCatchBlock(start, end, p.label(l, l.word()), catchType)
+			lastLabel = label
+		case ".var":
+			index := int(l.int(32))
+			l.expect("is")
+			name, descriptor, signature := l.word(), l.word(), ""
+			if l.peek() == "signature" {
+				l.pos++
+				signature = l.word()
+			}
+			l.expect("from")
+			start := p.label(l, l.word())
+			l.expect("to")
+			visitor.VisitLocalVariable(name, descriptor, signature, start, p.label(l, l.word()), index)
+			lastLabel = label
+		case ".stack":
+			info.frames = true
+			p.parseFrame(l, visitor)
+			lastLabel = label
+		case ".noframes":
+			info.frames = true
+			lastLabel = label
+		case ".attribute":
+			visitor.VisitAttribute(p.attribute(l))
+			lastLabel = label
+		default:
+			if strings.HasSuffix(directive, ":") && len(l.tokens) == 1 {
+				lastLabel = p.label(l, strings.TrimSuffix(directive, ":"))
+				visitor.VisitLabel(lastLabel)
+			} else {
+				p.parseInstruction(l, directive, visitor)
+			}
+		}
+	})
+	if info.code {
+		visitor.VisitMaxs(0, 0)
+	}
+	visitor.VisitEnd()
+	p.methods = append(p.methods, info)
+}
+
+// label returns the label with the given name in the current method.
+func (p *parser) label(l *line, name string) *class.Label {
+	if name == "" {
+		l.fail("invalid label")
+	}
+	label, ok := p.labels[name]
+	if !ok {
+		label = class.NewLabel()
+		p.labels[name] = label
+	}
+	return label
+}
+
+func (p *parser) parseFrame(l *line, visitor class.MethodVisitor) {
+	frameType := -2
+	text := l.word()
+	for t, name := range frameTypeNames {
+		if name == text {
+			frameType = t
+		}
+	}
+	var locals, stack []interface{}
+	switch frameType {
+	case data.F_SAME:
+	case data.F_SAME1:
+		stack = p.verificationTypes(l)
+	case data.F_APPEND:
+		locals = p.verificationTypes(l)
+	case data.F_CHOP:
+		locals = make([]interface{}, l.int(8))
+	case data.F_FULL, data.F_NEW:
+		l.expect("locals")
+		locals = p.verificationTypes(l)
+		l.expect("stack")
+		stack = p.verificationTypes(l)
+	default:
+		l.fail("unknown frame type %q", text)
+	}
+	visitor.VisitFrame(frameType, len(locals), locals, len(stack), stack)
+}
+
+// verificationTypes reads verification types up to the end of the line or the "stack" keyword.
+func (p *parser) verificationTypes(l *line) []interface{} {
+	values := make([]interface{}, 0)
+	for l.more() && l.peek() != "stack" {
+		switch name := l.word(); name {
+		case "Object":
+			values = append(values, l.word())
+		case "Uninitialized":
+			values = append(values, p.label(l, l.word()))
+		default:
+			found := false
+			for i, typeName := range verificationTypeNames {
+				if typeName == name {
+					values = append(values, uint8(i))
+					found = true
+				}
+			}
+			if !found {
+				l.fail("unknown verification type %q", name)
+			}
+		}
+	}
+	return values
+}
+
+func (p *parser) parseInstruction(l *line, name string, visitor class.MethodVisitor) {
+	opCode, ok := opCodes[name]
+	if !ok {
+		l.fail("unknown instruction %q", name)
+	}
+	op := uint16(opCode)
+	switch {
+	case opCode == data.BIPUSH:
+		visitor.VisitIntInstruction(op, int32(l.int(8)))
+	case opCode == data.SIPUSH:
+		visitor.VisitIntInstruction(op, int32(l.int(16)))
+	case opCode == data.NEWARRAY:
+		arrayType := l.word()
+		for t, typeName := range arrayTypeNames {
+			if typeName == arrayType {
+				visitor.VisitIntInstruction(op, t)
+				return
+			}
+		}
+		l.fail("unknown array type %q", arrayType)
+	case opCode >= data.ILOAD && opCode <= data.ALOAD, opCode >= data.ISTORE && opCode <= data.ASTORE,
+		opCode == data.RET:
+		visitor.VisitVarInstruction(op, int(l.int(32)))
+	case opCode >= data.ILOAD_0 && opCode < data.ILOAD_0+20:
+		visitor.VisitVarInstruction(uint16(data.ILOAD+(opCode-data.ILOAD_0)/4), (opCode-data.ILOAD_0)%4)
+	case opCode >= data.ISTORE_0 && opCode < data.ISTORE_0+20:
+		visitor.VisitVarInstruction(uint16(data.ISTORE+(opCode-data.ISTORE_0)/4), (opCode-data.ISTORE_0)%4)
+	case opCode == data.NEW, opCode == data.ANEWARRAY, opCode == data.CHECKCAST, opCode == data.INSTANCEOF:
+		visitor.VisitTypeInstruction(op, l.word())
+	case opCode >= data.GETSTATIC && opCode <= data.PUTFIELD:
+		visitor.VisitFieldInstruction(op, l.word(), l.word(), l.word())
+	case opCode >= data.INVOKEVIRTUAL && opCode <= data.INVOKEINTERFACE:
+		isInterface := opCode == data.INVOKEINTERFACE
+		if l.peek() == "interface" && len(l.tokens)-l.pos > 3 {
+			l.pos++
+			isInterface = true
+		}
+		visitor.VisitMethodInstruction(op, l.word(), l.word(), l.word(), isInterface)
+	case opCode == data.INVOKEDYNAMIC:
+		name, descriptor := l.word(), l.word()
+		l.expect("handle")
+		visitor.VisitInvokeDynamicInstruction(op, name, descriptor, p.handle(l), p.arguments(l))
+	case opCode >= data.IFEQ && opCode <= data.JSR, opCode == data.IFNULL, opCode == data.IFNONNULL:
+		visitor.VisitJumpInstruction(op, p.label(l, l.word()))
+	case opCode == data.GOTO_W:
+		visitor.VisitJumpInstruction(data.GOTO, p.label(l, l.word()))
+	case opCode == data.JSR_W:
+		visitor.VisitJumpInstruction(data.JSR, p.label(l, l.word()))
+	case opCode == data.LDC, opCode == data.LDC_W, opCode == data.LDC2_W:
+		visitor.VisitLdcInstruction(p.constant(l))
+	case opCode == data.IINC:
+		visitor.VisitIincInstruction(int(l.int(32)), int(l.int(16)))
+	case opCode == data.TABLESWITCH:
+		min, max := int32(l.int(32)), int32(l.int(32))
+		var labels []*class.Label
+		dflt := p.switchCases(l, func(c *line) {
+			labels = append(labels, p.label(c, c.word()))
+		})
+		if int64(len(labels)) != int64(max)-int64(min)+1 {
+			l.fail("expected %d labels instead of %d", int64(max)-int64(min)+1, len(labels))
+		}
+		visitor.VisitTableSwitchInstruction(min, max, dflt, labels)
+	case opCode == data.LOOKUPSWITCH:
+		var keys []int32
+		var labels []*class.Label
+		dflt := p.switchCases(l, func(c *line) {
+			keys = append(keys, int32(c.int(32)))
+			c.expect(":")
+			labels = append(labels, p.label(c, c.word()))
+		})
+		visitor.VisitLookupSwitchInstruction(dflt, keys, labels)
+	case opCode == data.MULTIANEWARRAY:
+		visitor.VisitMultiANewArrayInstruction(l.word(), int(l.int(16)))
+	case opCode == data.WIDE:
+		l.fail("wide is implied by the operands of the instructions")
+	default:
+		visitor.VisitInstruction(op)
+	}
+}
+
+// switchCases reads the lines of the cases of a switch instruction, up to the default case,
+// and returns the default label.
+func (p *parser) switchCases(l *line, parse func(c *line)) *class.Label {
+	for {
+		if p.index >= len(p.lines) {
+			l.fail("missing default case")
+		}
+		c := p.next()
+		if c.peek() == "default" {
+			c.pos++
+			c.expect(":")
+			label := p.label(c, c.word())
+			c.end()
+			return label
+		}
+		parse(c)
+		c.end()
+	}
+}
+
+// constant reads an ldc, field or bootstrap method argument constant.
+func (p *parser) constant(l *line) interface{} {
+	t := l.next()
+	if t.quoted {
+		return t.text
+	}
+	switch t.text {
+	case "class":
+		return class.NewObjectType(l.word())
+	case "methodtype":
+		return class.NewMethodType(l.word())
+	case "type":
+		return class.NewType(l.word())
+	case "handle":
+		return p.handle(l)
+	case "dynamic":
+		name, descriptor := l.word(), l.word()
+		l.expect("handle")
+		return class.ConstantDynamic{Name: name, Descriptor: descriptor, BootstrapMethod: p.handle(l),
+			BootstrapMethodArguments: p.arguments(l)}
+	}
+	text := t.text
+	if value, err := strconv.ParseInt(text, 0, 32); err == nil {
+		return int32(value)
+	}
+	number := text[:len(text)-1]
+	switch text[len(text)-1] {
+	case 'L', 'l':
+		value, err := strconv.ParseInt(number, 0, 64)
+		if err != nil {
+			l.fail("invalid long %q", text)
+		}
+		return value
+	case 'F', 'f':
+		return float32(l.float(number, 32))
+	case 'D', 'd':
+		return l.float(number, 64)
+	}
+	l.fail("invalid constant %q", text)
+	return nil
+}
+
+func (p *parser) handle(l *line) class.Handle {
+	kind := l.word()
+	handle := class.Handle{}
+	for tag, name := range handleKindNames {
+		if name == kind && name != "" {
+			handle.Tag = uint8(tag)
+		}
+	}
+	if handle.Tag == 0 {
+		l.fail("unknown handle kind %q", kind)
+	}
+	handle.IsInterface = handle.Tag == data.HANDLE_INVOKEINTERFACE
+	if l.peek() == "interface" && len(l.tokens)-l.pos > 3 {
+		l.pos++
+		handle.IsInterface = true
+	}
+	handle.Owner, handle.Name, handle.Descriptor = l.word(), l.word(), l.word()
+	return handle
+}
+
+// arguments reads bootstrap method arguments, between braces.
+func (p *parser) arguments(l *line) []interface{} {
+	l.expect("{")
+	arguments := make([]interface{}, 0)
+	for l.peek() != "}" {
+		arguments = append(arguments, p.constant(l))
+	}
+	l.pos++
+	return arguments
+}
+
+// finish computes the max values and the frames of the methods which don't give them. The
+// frames of a method with .limit directives are not computed, so that its code is written as
+// given even if it does not verify.
+func (p *parser) finish() (*class.Class, error) {
+	c := p.builder.Class()
+	for i, info := range p.methods {
+		method := &c.Methods[i]
+		if !info.code {
+			continue
+		}
+		var err error
+		limits := info.maxStack >= 0 || info.maxLocals >= 0
+		if !info.frames && !limits && p.version&0xffff >= 50 {
+			err = class.ComputeFrames(c.ThisClass, method, nil)
+		} else if info.maxStack < 0 || info.maxLocals < 0 {
+			err = class.ComputeMaxs(c.ThisClass, method)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.maxStack >= 0 {
+			method.Code.MaxStack = uint16(info.maxStack)
+		}
+		if info.maxLocals >= 0 {
+			method.Code.MaxLocal = uint16(info.maxLocals)
+		}
+	}
+	return c, nil
+}
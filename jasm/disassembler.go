@@ -0,0 +1,488 @@
+package jasm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+// Disassemble prints the class with the given content in the assembly language.
+func Disassemble(w io.Writer, content []byte) error {
+	reader := class.NewReader(bytes.NewReader(content))
+	if err := reader.Read(); err != nil {
+		return err
+	}
+	return DisassembleClass(w, reader.Class())
+}
+
+// DisassembleClass prints a class in the assembly language.
+func DisassembleClass(w io.Writer, c *class.Class) error {
+	d := &disassembler{writer: bufio.NewWriter(w)}
+	d.writeClass(c)
+	return d.writer.Flush()
+}
+
+type disassembler struct {
+	writer     *bufio.Writer
+	labelNames map[*class.Label]string
+}
+
+func (d *disassembler) printf(format string, args ...interface{}) {
+	fmt.Fprintf(d.writer, format, args...)
+}
+
+func (d *disassembler) writeClass(c *class.Class) {
+	d.printf(".version %d %d\n", c.Version&0xffff, c.Version>>16)
+	d.printf(".class %s%s\n", accessText(c.AccessFlags, data.ACCESS_CLASS), quote(c.ThisClass))
+	if c.SuperClass != "" {
+		d.printf(".super %s\n", quote(c.SuperClass))
+	}
+	for _, itf := range c.Interfaces {
+		d.printf(".implements %s\n", quote(itf))
+	}
+	if c.Signature != "" {
+		d.printf(".signature %s\n", strconv.Quote(c.Signature))
+	}
+	if c.SourceFile != "" {
+		d.printf(".source %s\n", strconv.Quote(c.SourceFile))
+	}
+	if c.SourceDebugExtension != "" {
+		d.printf(".debug %s\n", strconv.Quote(c.SourceDebugExtension))
+	}
+	if c.Deprecated {
+		d.printf(".deprecated\n")
+	}
+	if c.Module.Name != "" {
+		d.writeModule(c.Module)
+	}
+	if c.NestHost != "" {
+		d.printf(".nesthost %s\n", quote(c.NestHost))
+	}
+	if c.OuterClass.ClassName != "" {
+		d.printf(".enclosing method %s", quote(c.OuterClass.ClassName))
+		if c.OuterClass.MethodName != "" {
+			d.printf(" %s %s", quote(c.OuterClass.MethodName), quote(c.OuterClass.Descriptor))
+		}
+		d.printf("\n")
+	}
+	d.writeAnnotations("", c.RuntimeVisibleAnnotations, c.RuntimeInvisibleAnnotations)
+	d.writeAttributes("", c.Attributes)
+	for _, member := range c.NestMembers {
+		d.printf(".nestmember %s\n", quote(member))
+	}
+	for _, inner := range c.InnerClasses {
+		d.printf(".innerclass %s%s %s %s\n", accessText(inner.AccessFlags, data.ACCESS_INNER_CLASS),
+			quote(inner.Name), quote(inner.OuterName), quote(inner.InnerName))
+	}
+	for _, field := range c.Fields {
+		d.printf("\n")
+		d.writeField(field)
+	}
+	for _, method := range c.Methods {
+		d.printf("\n")
+		d.writeMethod(method)
+	}
+}
+
+func (d *disassembler) writeModule(module class.Module) {
+	d.printf(".module %s%s", accessText(module.AccessFlags, data.ACCESS_MODULE), quote(module.Name))
+	if module.Version != "" {
+		d.printf(" version %s", strconv.Quote(module.Version))
+	}
+	d.printf("\n")
+	for _, require := range module.Requires {
+		d.printf("    .requires %s%s", accessText(require.AccessFlags, data.ACCESS_MODULE_REQUIRES), quote(require.Name))
+		if require.Version != "" {
+			d.printf(" version %s", strconv.Quote(require.Version))
+		}
+		d.printf("\n")
+	}
+	for _, export := range module.Exports {
+		d.writeModulePackage(".exports", export.Name, export.AccessFlags, export.Modules)
+	}
+	for _, open := range module.Opens {
+		d.writeModulePackage(".opens", open.Name, open.AccessFlags, open.Modules)
+	}
+	for _, use := range module.Uses {
+		d.printf("    .uses %s\n", quote(use))
+	}
+	for _, provide := range module.Provides {
+		d.printf("    .provides %s with%s\n", quote(provide.Service), names(provide.Provides))
+	}
+	if module.MainClass != "" {
+		d.printf("    .mainclass %s\n", quote(module.MainClass))
+	}
+	for _, pkg := range module.Packages {
+		d.printf("    .package %s\n", quote(pkg))
+	}
+	d.printf(".end module\n")
+}
+
+func (d *disassembler) writeModulePackage(directive string, name string, access uint16, modules []string) {
+	d.printf("    %s %s%s", directive, accessText(access, data.ACCESS_MODULE_EXPORTS), quote(name))
+	if len(modules) > 0 {
+		d.printf(" to%s", names(modules))
+	}
+	d.printf("\n")
+}
+
+// names returns quoted names, each preceded by a space.
+func names(values []string) string {
+	text := ""
+	for _, value := range values {
+		text += " " + quote(value)
+	}
+	return text
+}
+
+func (d *disassembler) writeField(field class.Field) {
+	d.printf(".field %s%s %s", accessText(field.AccessFlags, data.ACCESS_FIELD), quote(field.Name), quote(field.Descriptor))
+	if field.ConstantValue != nil {
+		d.printf(" = %s", d.constant(field.ConstantValue))
+	}
+	d.printf("\n")
+	if field.Signature == "" && !field.Deprecated && len(field.RuntimeVisibleAnnotations) == 0 &&
+		len(field.RuntimeInvisibleAnnotations) == 0 && len(field.Attributes) == 0 {
+		return
+	}
+	if field.Signature != "" {
+		d.printf("    .signature %s\n", strconv.Quote(field.Signature))
+	}
+	if field.Deprecated {
+		d.printf("    .deprecated\n")
+	}
+	d.writeAnnotations("    ", field.RuntimeVisibleAnnotations, field.RuntimeInvisibleAnnotations)
+	d.writeAttributes("    ", field.Attributes)
+	d.printf(".end field\n")
+}
+
+func (d *disassembler) writeMethod(method class.Method) {
+	d.labelNames = make(map[*class.Label]string)
+	d.printf(".method %s%s %s\n", accessText(method.AccessFlags, data.ACCESS_METHOD), quote(method.Name), quote(method.Descriptor))
+	for _, exception := range method.Exceptions {
+		d.printf("    .throws %s\n", quote(exception))
+	}
+	if method.Signature != "" {
+		d.printf("    .signature %s\n", strconv.Quote(method.Signature))
+	}
+	if method.Deprecated {
+		d.printf("    .deprecated\n")
+	}
+	for _, parameter := range method.Parameters {
+		d.printf("    .parameter %s%s\n", accessText(parameter.AccessFlags, data.ACCESS_PARAMETER), strconv.Quote(parameter.ParameterName))
+	}
+	if method.AnnotationDefault != nil {
+		d.printf("    .annotationdefault %s\n", d.elementValue(method.AnnotationDefault))
+	}
+	d.writeAnnotations("    ", method.RuntimeVisibleAnnotations, method.RuntimeInvisibleAnnotations)
+	d.writeParameterAnnotations(method.RuntimeVisibleParameterAnnotations, true)
+	d.writeParameterAnnotations(method.RuntimeInvisibleParameterAnnotations, false)
+	d.writeAttributes("    ", method.Attributes)
+	if len(method.Code.Instructions) > 0 {
+		d.writeCode(method.Code)
+	}
+	d.printf(".end method\n")
+}
+
+func (d *disassembler) writeParameterAnnotations(parameters []class.ParameterAnnotation, visible bool) {
+	if len(parameters) == 0 {
+		return
+	}
+	d.printf("    .annotableparameters %s %d\n", visibility(visible), len(parameters))
+	for i, parameter := range parameters {
+		for _, annotation := range parameter.Annotations {
+			d.printf("    .parameterannotation %d %s %s\n", i, visibility(visible), quote(annotation.Descriptor))
+			d.writeElementPairs("        ", annotation.ElementPairs)
+			d.printf("    .end annotation\n")
+		}
+	}
+}
+
+func visibility(visible bool) string {
+	if visible {
+		return "visible"
+	}
+	return "invisible"
+}
+
+func (d *disassembler) writeAnnotations(indent string, visible []class.Annotation, invisible []class.Annotation) {
+	for i, annotations := range [][]class.Annotation{visible, invisible} {
+		for _, annotation := range annotations {
+			d.printf("%s.annotation %s %s\n", indent, visibility(i == 0), quote(annotation.Descriptor))
+			d.writeElementPairs(indent+"    ", annotation.ElementPairs)
+			d.printf("%s.end annotation\n", indent)
+		}
+	}
+}
+
+func (d *disassembler) writeElementPairs(indent string, pairs []class.ElementPair) {
+	for _, pair := range pairs {
+		d.printf("%s%s = %s\n", indent, quote(pair.Name), d.elementValue(pair.Value))
+	}
+}
+
+// elementValue returns an annotation element value, preceded by its tag.
+func (d *disassembler) elementValue(value class.ElementValue) string {
+	tag := string(rune(value.Tag()))
+	switch v := value.(type) {
+	case class.ElementBooleanValue:
+		return tag + " " + strconv.FormatBool(v.Value)
+	case class.ElementByteValue:
+		return tag + " " + strconv.Itoa(int(v.Value))
+	case class.ElementCharValue:
+		return tag + " " + strconv.Itoa(int(v.Value))
+	case class.ElementShortValue:
+		return tag + " " + strconv.Itoa(int(v.Value))
+	case class.ElementIntegerValue:
+		return tag + " " + strconv.Itoa(int(v.Value))
+	case class.ElementLongValue:
+		return tag + " " + strconv.FormatInt(v.Value, 10)
+	case class.ElementFloatValue:
+		return tag + " " + floatText(float64(v.Value), 32)
+	case class.ElementDoubleValue:
+		return tag + " " + floatText(v.Value, 64)
+	case class.ElementStringValue:
+		return tag + " " + strconv.Quote(v.Value)
+	case class.ElementEnumValue:
+		return tag + " " + quote(v.TypeName) + " " + quote(v.ConstName)
+	case class.ElementClassValue:
+		if t, ok := v.Value.(class.Type); ok {
+			return tag + " " + quote(t.Descriptor())
+		}
+		return tag + " " + quote(fmt.Sprint(v.Value))
+	case class.ElementAnnotationValue:
+		text := tag + " " + quote(v.Value.Descriptor) + " {"
+		for _, pair := range v.Value.ElementPairs {
+			text += " " + quote(pair.Name) + " = " + d.elementValue(pair.Value)
+		}
+		return text + " }"
+	case class.ElementArrayValue:
+		text := tag
+		for _, element := range v.Values {
+			text += " " + d.elementValue(element)
+		}
+		return text + " ]"
+	}
+	return tag
+}
+
+func (d *disassembler) writeAttributes(indent string, attributes []class.Attribute) {
+	for _, attribute := range attributes {
+		d.printf("%s.attribute %s %s\n", indent, strconv.Quote(attribute.Name), strconv.Quote(hex.EncodeToString(attribute.Content)))
+	}
+}
+
+func (d *disassembler) label(label *class.Label) string {
+	name, ok := d.labelNames[label]
+	if !ok {
+		name = "L" + strconv.Itoa(len(d.labelNames))
+		d.labelNames[label] = name
+	}
+	return name
+}
+
+func (d *disassembler) writeCode(code class.MethodCode) {
+	for _, instruction := range code.Instructions {
+		if label, ok := instruction.(*class.Label); ok {
+			d.label(label)
+		}
+	}
+	d.printf("    .limit stack %d\n", code.MaxStack)
+	d.printf("    .limit locals %d\n", code.MaxLocal)
+	var previous class.Instruction
+	for _, instruction := range code.Instructions {
+		d.writeInstruction(instruction, previous)
+		previous = instruction
+	}
+	for _, exception := range code.ExceptionTable {
+		catchType := "any"
+		if exception.CatchType != "" {
+			catchType = quote(exception.CatchType)
+		}
+		d.printf("    .catch %s from %s to %s using %s\n", catchType, d.label(exception.Start), d.label(exception.End),
+			d.label(exception.Handler))
+	}
+	for _, local := range code.LocalVariables {
+		d.printf("    .var %d is %s %s", local.Index, quote(local.Name), quote(local.Descriptor))
+		if local.Signature != "" {
+			d.printf(" signature %s", strconv.Quote(local.Signature))
+		}
+		d.printf(" from %s to %s\n", d.label(local.Start), d.label(local.End))
+	}
+	d.writeAttributes("    ", code.Attributes)
+}
+
+func (d *disassembler) writeInstruction(instruction class.Instruction, previous class.Instruction) {
+	switch insn := instruction.(type) {
+	case *class.Label:
+		d.printf("%s:\n", d.label(insn))
+		return
+	case *class.LineNumber:
+		if insn.Start == previous {
+			d.printf("    .line %d\n", insn.Line)
+		} else {
+			d.printf("    .line %d %s\n", insn.Line, d.label(insn.Start))
+		}
+		return
+	case *class.Frame:
+		d.printf("    .stack %s%s\n", frameTypeNames[insn.Type], d.frame(insn))
+		return
+	}
+	d.printf("    %s", data.OPCODE_NAMES[instruction.OpCode()])
+	switch insn := instruction.(type) {
+	case *class.IntInstruction:
+		if insn.Op == data.NEWARRAY {
+			d.printf(" %s", arrayTypeNames[insn.Operand])
+		} else {
+			d.printf(" %d", insn.Operand)
+		}
+	case *class.VarInstruction:
+		d.printf(" %d", insn.Var)
+	case *class.TypeInstruction:
+		d.printf(" %s", quote(insn.Type))
+	case *class.FieldInstruction:
+		d.printf(" %s %s %s", quote(insn.Owner), quote(insn.Name), quote(insn.Descriptor))
+	case *class.MethodInstruction:
+		if insn.IsInterface && insn.Op != data.INVOKEINTERFACE {
+			d.printf(" interface")
+		}
+		d.printf(" %s %s %s", quote(insn.Owner), quote(insn.Name), quote(insn.Descriptor))
+	case *class.InvokeDynamicInstruction:
+		d.printf(" %s %s %s%s", quote(insn.Name), quote(insn.Descriptor), d.handle(insn.BootstrapMethod),
+			d.arguments(insn.BootstrapMethodArguments))
+	case *class.JumpInstruction:
+		d.printf(" %s", d.label(insn.Label))
+	case *class.LdcInstruction:
+		d.printf(" %s", d.constant(insn.Value))
+	case *class.IincInstruction:
+		d.printf(" %d %d", insn.Var, insn.Increment)
+	case *class.TableSwitchInstruction:
+		d.printf(" %d %d\n", insn.Min, insn.Max)
+		for _, label := range insn.Labels {
+			d.printf("        %s\n", d.label(label))
+		}
+		d.printf("        default : %s", d.label(insn.Default))
+	case *class.LookupSwitchInstruction:
+		d.printf("\n")
+		for i, key := range insn.Keys {
+			d.printf("        %d : %s\n", key, d.label(insn.Labels[i]))
+		}
+		d.printf("        default : %s", d.label(insn.Default))
+	case *class.MultiANewArrayInstruction:
+		d.printf(" %s %d", quote(insn.Descriptor), insn.NumDimensions)
+	}
+	d.printf("\n")
+}
+
+// frame returns the verification types of a frame, each preceded by a space.
+func (d *disassembler) frame(frame *class.Frame) string {
+	switch frame.Type {
+	case data.F_SAME:
+		return ""
+	case data.F_SAME1:
+		return d.verificationTypes(frame.Stack)
+	case data.F_APPEND:
+		return d.verificationTypes(frame.Locals)
+	case data.F_CHOP:
+		return " " + strconv.Itoa(len(frame.Locals))
+	default:
+		return " locals" + d.verificationTypes(frame.Locals) + " stack" + d.verificationTypes(frame.Stack)
+	}
+}
+
+func (d *disassembler) verificationTypes(values []interface{}) string {
+	text := ""
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			text += " Object " + quote(v)
+		case *class.Label:
+			text += " Uninitialized " + d.label(v)
+		case uint8:
+			if int(v) < len(verificationTypeNames) {
+				text += " " + verificationTypeNames[v]
+			}
+		case int:
+			if v >= 0 && v < len(verificationTypeNames) {
+				text += " " + verificationTypeNames[v]
+			}
+		}
+	}
+	return text
+}
+
+// constant returns an ldc, field or bootstrap method argument constant.
+func (d *disassembler) constant(value interface{}) string {
+	switch v := value.(type) {
+	case int32:
+		return strconv.Itoa(int(v))
+	case int64:
+		return strconv.FormatInt(v, 10) + "L"
+	case float32:
+		return floatText(float64(v), 32) + "f"
+	case float64:
+		return floatText(v, 64) + "d"
+	case string:
+		return strconv.Quote(v)
+	case class.Type:
+		switch v.Sort() {
+		case data.TYPE_SORT_METHOD:
+			return "methodtype " + quote(v.Descriptor())
+		case data.TYPE_SORT_INTERNAL, data.TYPE_SORT_OBJECT, data.TYPE_SORT_ARRAY:
+			return "class " + quote(v.InternalName())
+		}
+		return "type " + quote(v.Descriptor())
+	case class.Handle:
+		return d.handle(v)
+	case class.ConstantDynamic:
+		return "dynamic " + quote(v.Name) + " " + quote(v.Descriptor) + " " + d.handle(v.BootstrapMethod) +
+			d.arguments(v.BootstrapMethodArguments)
+	}
+	return strconv.Quote(fmt.Sprint(value))
+}
+
+// handle returns a method handle constant, preceded by a space.
+func (d *disassembler) handle(handle class.Handle) string {
+	text := "handle "
+	if int(handle.Tag) < len(handleKindNames) {
+		text += handleKindNames[handle.Tag]
+	}
+	if handle.IsInterface && handle.Tag != data.HANDLE_INVOKEINTERFACE {
+		text += " interface"
+	}
+	return text + " " + quote(handle.Owner) + " " + quote(handle.Name) + " " + quote(handle.Descriptor)
+}
+
+// arguments returns bootstrap method arguments, preceded by a space.
+func (d *disassembler) arguments(arguments []interface{}) string {
+	text := " {"
+	for _, argument := range arguments {
+		text += " " + d.constant(argument)
+	}
+	return text + " }"
+}
+
+// floatText returns a float or double value, which is parsed back to the same value.
+func floatText(value float64, bitSize int) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Infinity"
+	case math.IsInf(value, -1):
+		return "-Infinity"
+	}
+	text := strconv.FormatFloat(value, 'g', -1, bitSize)
+	if !strings.ContainsAny(text, ".eIN") {
+		text += ".0"
+	}
+	return text
+}
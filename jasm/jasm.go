@@ -0,0 +1,116 @@
+// Package jasm assembles and disassembles class files in a textual assembly language, close to
+// the Jasmin and Krakatau syntaxes.
+//
+// A source file describes one class with one directive or instruction per line. The tokens are
+// separated by spaces, and can be quoted with the Go syntax. A ';' at the beginning of a token
+// starts a comment. For instance:
+//
+//	.version 52 0
+//	.class public super a/Hello
+//	.super java/lang/Object
+//	.source "Hello.java"
+//	.annotation invisible La/Ann;
+//	    value = s "x"
+//	    values = [ I 1 I 2 ]
+//	.end annotation
+//	.attribute "Custom" 0102
+//
+//	.field private static final F J = 5L
+//
+//	.method public static main ([Ljava/lang/String;)V
+//	    .limit stack 2
+//	    .limit locals 1
+//	L0:
+//	    .line 3
+//	    getstatic java/lang/System out Ljava/io/PrintStream;
+//	    ldc "Hello"
+//	    invokevirtual java/io/PrintStream println (Ljava/lang/String;)V
+//	    return
+//	.end method
+//
+// The class directives are .version, .class, .super, .implements, .signature, .source, .debug,
+// .deprecated, .nesthost, .nestmember, .innerclass, .enclosing method, .annotation, .attribute
+// and .module ... .end module. A field is declared with .field, followed by its .signature,
+// .deprecated, .annotation and .attribute directives and .end field if it has any. A method is
+// declared with .method and ends with .end method. It contains the .throws, .signature,
+// .deprecated, .parameter, .annotation, .annotableparameters, .parameterannotation,
+// .annotationdefault and .attribute directives, followed by its code: labels ("L0:"),
+// instructions, and the .limit, .line, .var, .catch, .stack and .noframes directives. In the code, the
+// .attribute directive adds an attribute to the Code attribute.
+//
+// The constants are ints (1), longs (1L), floats (1.5f), doubles (1.5d), strings ("s"), classes
+// (class java/lang/String), method types (methodtype (I)V), method handles (handle invokestatic
+// a/A m ()V) and dynamic constants (dynamic name I handle ... { arguments }). The annotation
+// element values are written with their element_value tag: Z true, B 1, C 97, S 1, I 1, J 1,
+// F 1.5, D 1.5, s "s", e La/E; NAME, c Ljava/lang/String;, @ La/Ann; { name = value }, or
+// [ value... ].
+//
+// The max stack and max locals values of a method are computed, unless it has .limit
+// directives. Its stack map frames are computed if the class version is 50 or more and the
+// method has no .limit, .stack or .noframes directives. A method with .limit directives or
+// .noframes is written as given, so that it may contain code which does not verify.
+package jasm
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+var verificationTypeNames = []string{"Top", "Integer", "Float", "Double", "Long", "Null", "UninitializedThis"}
+
+var frameTypeNames = map[int]string{data.F_NEW: "new", data.F_FULL: "full", data.F_APPEND: "append",
+	data.F_CHOP: "chop", data.F_SAME: "same", data.F_SAME1: "same1"}
+
+var handleKindNames = []string{"", "getfield", "getstatic", "putfield", "putstatic", "invokevirtual",
+	"invokestatic", "invokespecial", "newinvokespecial", "invokeinterface"}
+
+var arrayTypeNames = map[int32]string{data.T_BOOLEAN: "boolean", data.T_CHAR: "char", data.T_FLOAT: "float",
+	data.T_DOUBLE: "double", data.T_BYTE: "byte", data.T_SHORT: "short", data.T_INT: "int", data.T_LONG: "long"}
+
+var opCodes = make(map[string]int)
+
+func init() {
+	for opCode, name := range data.OPCODE_NAMES {
+		if name != "" {
+			opCodes[name] = opCode
+		}
+	}
+}
+
+// accessText returns the access flags of a kind of element (one of the data.ACCESS_* constants)
+// as lower case keywords, each followed by a space.
+func accessText(access uint16, kind int) string {
+	text := ""
+	for _, name := range data.AccessFlagNames(access, kind) {
+		text += strings.ToLower(strings.TrimPrefix(name, "ACC_")) + " "
+	}
+	return text
+}
+
+// accessFlag returns the access flag of a keyword returned by accessText.
+func accessFlag(keyword string, kind int) (uint16, bool) {
+	name := keyword
+	if !strings.HasPrefix(keyword, "0x") {
+		name = "ACC_" + strings.ToUpper(keyword)
+	}
+	access, err := data.ParseAccessFlags([]string{name}, kind)
+	return access, err == nil
+}
+
+// quote returns a name, descriptor or string token, quoted if it is empty or contains
+// characters that separate or start tokens.
+func quote(value string) string {
+	if value == "" || !utf8.ValidString(value) || strings.HasPrefix(value, ";") || strings.HasPrefix(value, "\"") {
+		return strconv.Quote(value)
+	}
+	for _, r := range value {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
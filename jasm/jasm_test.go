@@ -0,0 +1,174 @@
+package jasm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/tools"
+)
+
+func readClass(t *testing.T, content []byte) *class.Class {
+	t.Helper()
+	reader := class.NewReader(bytes.NewReader(content))
+	tools.AssertNoErr(t, reader.Read())
+	return reader.Class()
+}
+
+func writeClass(t *testing.T, c *class.Class) []byte {
+	t.Helper()
+	writer := class.NewWriter()
+	c.Accept(writer)
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+	return content
+}
+
+func disassemble(t *testing.T, content []byte) string {
+	t.Helper()
+	var out bytes.Buffer
+	tools.AssertNoErr(t, Disassemble(&out, content))
+	return out.String()
+}
+
+func assertLines(t *testing.T, output string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, output)
+		}
+	}
+}
+
+func TestRoundTripHello(t *testing.T) {
+	content, err := ioutil.ReadFile("../class/Hello.class")
+	tools.AssertNoErr(t, err)
+	source := disassemble(t, content)
+	assembled, err := Assemble([]byte(source))
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, string(writeClass(t, readClass(t, content))), string(assembled))
+	tools.AssertEqual(t, source, disassemble(t, assembled))
+}
+
+const source = `.version 52 0
+.class public super a/Switch
+.super java/lang/Object
+.source "Switch.java"
+.annotation invisible La/Ann;
+    value = s "x"
+    values = [ I 1 I 2 ]
+.end annotation
+.attribute "Custom" 0102
+
+.field private static final F J = 5L
+
+.method public static select (I)J ; computed limits and frames
+    iload 0
+    tableswitch 0 1
+        L0
+        L1
+        default : L2
+L0:
+    ldc 10L
+    lreturn
+L1:
+    ldc2_w 20L
+    lreturn
+L2:
+    lconst_0
+    lreturn
+.end method
+`
+
+func TestAssemble(t *testing.T) {
+	content, err := Assemble([]byte(source))
+	tools.AssertNoErr(t, err)
+	c := readClass(t, content)
+	tools.AssertEqual(t, "a/Switch", c.ThisClass)
+	tools.AssertEqual(t, 1, len(c.RuntimeInvisibleAnnotations))
+	tools.AssertEqual(t, "Custom", c.Attributes[0].Name)
+	tools.AssertEqual(t, int64(5), c.Fields[0].ConstantValue)
+	method := c.Methods[0]
+	tools.AssertEqual(t, uint16(2), method.Code.MaxStack)
+	tools.AssertEqual(t, uint16(1), method.Code.MaxLocal)
+	frames := 0
+	for _, insn := range method.Code.Instructions {
+		if _, ok := insn.(*class.Frame); ok {
+			frames++
+		}
+	}
+	tools.AssertEqual(t, 3, frames)
+	assertLines(t, disassemble(t, content),
+		".field private static final F J = 5L",
+		"    value = s \"x\"",
+		"    values = [ I 1 I 2 ]",
+		"    tableswitch 0 1",
+		"        default : L2",
+		"    .stack same",
+		"    ldc 10L",
+	)
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := Assemble([]byte(".class public a/A\n.super java/lang/Object\n.method m ()V\n    foo\n.end method\n"))
+	tools.AssertEqual(t, "line 4: unknown instruction \"foo\"", err.Error())
+}
+
+func TestMemberAnnotations(t *testing.T) {
+	content, err := Assemble([]byte(`.class public a/A
+.super java/lang/Object
+.field private f I
+    .annotation visible La/Ann;
+        value = I 1
+    .end annotation
+.end field
+.method m ()V
+    .annotation invisible La/Ann;
+    .end annotation
+    return
+.end method
+`))
+	tools.AssertNoErr(t, err)
+	c := readClass(t, content)
+	tools.AssertEqual(t, "La/Ann;", c.Fields[0].RuntimeVisibleAnnotations[0].Descriptor)
+	tools.AssertEqual(t, "La/Ann;", c.Methods[0].RuntimeInvisibleAnnotations[0].Descriptor)
+	tools.AssertEqual(t, 1, len(c.Methods[0].Code.Instructions))
+}
+
+func TestAssembleAsGiven(t *testing.T) {
+	content, err := Assemble([]byte(`.class public a/A
+.super java/lang/Object
+.method static underflow ()V
+    .limit stack 1
+    .limit locals 0
+    pop
+    return
+.end method
+.method static branch (I)I
+    .noframes
+    iload_0
+    ifeq L0
+    iconst_1
+    ireturn
+L0:
+    iconst_0
+    ireturn
+.end method
+`))
+	tools.AssertNoErr(t, err)
+	c := readClass(t, content)
+	underflow := c.Methods[0].Code
+	tools.AssertEqual(t, uint16(1), underflow.MaxStack)
+	tools.AssertEqual(t, uint16(0), underflow.MaxLocal)
+	tools.AssertEqual(t, 2, len(underflow.Instructions))
+	branch := c.Methods[1].Code
+	tools.AssertEqual(t, uint16(1), branch.MaxStack)
+	tools.AssertEqual(t, uint16(1), branch.MaxLocal)
+	for _, insn := range branch.Instructions {
+		if _, ok := insn.(*class.Frame); ok {
+			t.Errorf("unexpected frame in a method with .noframes")
+		}
+	}
+}
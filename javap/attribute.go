@@ -0,0 +1,527 @@
+package javap
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+// writeAttributes prints attributes in the order of the class file.
+func (d *disassembler) writeAttributes(attributes []data.AttributeData) {
+	for i := range attributes {
+		d.writeAttribute(&attributes[i])
+	}
+}
+
+func (d *disassembler) writeAttribute(attribute *data.AttributeData) {
+	p := d.printer
+	value := attribute.Value
+	reader := value.Reader()
+	switch name := d.utf8(attribute.NameIndex); name {
+	case data.CODE:
+		d.writeCode(value.Code())
+	case data.CONSTANT_VALUE:
+		p.print("ConstantValue: ")
+		d.writeConstant(value.Uint16())
+		p.println("")
+	case data.DEPRECATED:
+		p.println("Deprecated: true")
+	case data.SYNTHETIC:
+		p.println("Synthetic: true")
+	case data.SIGNATURE:
+		index := value.Uint16()
+		p.printf("Signature: #%d", index)
+		p.tab()
+		p.println("// " + d.stringValueAt(index))
+	case data.SOURCE_FILE:
+		p.println(`SourceFile: "` + d.utf8(value.Uint16()) + `"`)
+	case data.SOURCE_DEBUG_EXTENSION:
+		p.println("SourceDebugExtension:")
+		p.indent(+1)
+		for _, line := range regexp.MustCompile("[\r\n]+").Split(string(value), -1) {
+			p.println(line)
+		}
+		p.indent(-1)
+	case data.EXCEPTIONS:
+		p.println("Exceptions:")
+		p.indent(+1)
+		p.println("throws " + strings.Join(d.exceptions(attribute), ", "))
+		p.indent(-1)
+	case data.ENCLOSING_METHOD:
+		classIndex := reader.ReadUint16()
+		methodIndex := reader.ReadUint16()
+		p.printf("EnclosingMethod: #%d.#%d", classIndex, methodIndex)
+		p.tab()
+		p.print("// " + javaName(d.className(classIndex)))
+		if nameAndType, ok := d.constant(methodIndex).(data.ConstantNameAndTypeData); ok {
+			p.print("." + d.utf8(nameAndType.NameIndex))
+		}
+		p.println("")
+	case data.INNER_CLASSES:
+		d.writeInnerClasses(reader)
+	case data.NEST_HOST:
+		p.print("NestHost: ")
+		d.writeConstant(value.Uint16())
+		p.println("")
+	case data.NEST_MEMBERS:
+		p.println("NestMembers:")
+		p.indent(+1)
+		count := int(reader.ReadUint16())
+		for i := 0; i < count; i++ {
+			p.println(d.stringValueAt(reader.ReadUint16()))
+		}
+		p.indent(-1)
+	case data.BOOTSTRAP_METHODS:
+		d.writeBootstrapMethods(reader)
+	case data.METHOD_PARAMETERS:
+		p.println("MethodParameters:")
+		p.indent(+1)
+		p.printf("%-31s%s\n", "Name", "Flags")
+		count := int(reader.ReadUint8())
+		for i := 0; i < count; i++ {
+			nameIndex := reader.ReadUint16()
+			access := reader.ReadUint16()
+			name := "<no name>"
+			if nameIndex != 0 {
+				name = d.stringValueAt(nameIndex)
+			}
+			flags := ""
+			if access&data.ACC_FINAL != 0 {
+				flags += "final "
+			}
+			if access&data.ACC_MANDATED != 0 {
+				flags += "mandated "
+			}
+			if access&data.ACC_SYNTHETIC != 0 {
+				flags += "synthetic"
+			}
+			p.printf("%-31s%s\n", name, flags)
+		}
+		p.indent(-1)
+	case data.LINE_NUMBER_TABLE:
+		p.println("LineNumberTable:")
+		p.indent(+1)
+		count := int(reader.ReadUint16())
+		for i := 0; i < count; i++ {
+			startPC := reader.ReadUint16()
+			p.printf("line %d: %d\n", reader.ReadUint16(), startPC)
+		}
+		p.indent(-1)
+	case data.LOCAL_VARIABLE_TABLE, data.LOCAL_VARIABLE_TYPE_TABLE:
+		p.println(name + ":")
+		p.indent(+1)
+		p.println("Start  Length  Slot  Name   Signature")
+		count := int(reader.ReadUint16())
+		for i := 0; i < count; i++ {
+			startPC := reader.ReadUint16()
+			length := reader.ReadUint16()
+			nameIndex := reader.ReadUint16()
+			descriptorIndex := reader.ReadUint16()
+			index := reader.ReadUint16()
+			p.printf("%5d %7d %5d %5s   %s\n", startPC, length, index, d.stringValueAt(nameIndex), d.stringValueAt(descriptorIndex))
+		}
+		p.indent(-1)
+	case data.STACK_MAP_TABLE:
+		d.writeStackMapTable(reader)
+	case data.RUNTIME_VISIBLE_ANNOTATIONS, data.RUNTIME_INVISIBLE_ANNOTATIONS:
+		p.println(name + ":")
+		p.indent(+1)
+		count := int(reader.ReadUint16())
+		for i := 0; i < count; i++ {
+			p.printf("%d: ", i)
+			d.writeAnnotation(readAnnotation(reader))
+			p.println("")
+		}
+		p.indent(-1)
+	case data.RUNTIME_VISIBLE_PARAMETER_ANNOTATIONS, data.RUNTIME_INVISIBLE_PARAMETER_ANNOTATIONS:
+		p.println(name + ":")
+		p.indent(+1)
+		parameters := int(reader.ReadUint8())
+		for parameter := 0; parameter < parameters; parameter++ {
+			p.printf("parameter %d: \n", parameter)
+			p.indent(+1)
+			count := int(reader.ReadUint16())
+			for i := 0; i < count; i++ {
+				p.printf("%d: ", i)
+				d.writeAnnotation(readAnnotation(reader))
+				p.println("")
+			}
+			p.indent(-1)
+		}
+		p.indent(-1)
+	case data.ANNOTATION_DEFAULT:
+		p.println("AnnotationDefault:")
+		p.indent(+1)
+		p.print("default_value: ")
+		element := readElementValue(reader)
+		d.writeElementValue(element, false)
+		p.println("")
+		p.indent(+1)
+		d.writeElementValue(element, true)
+		p.indent(-1)
+		p.indent(-1)
+		p.println("")
+	case data.MODULE:
+		d.writeModule(reader)
+	case data.MODULE_PACKAGES:
+		p.println("ModulePackages: ")
+		count := int(reader.ReadUint16())
+		for i := 0; i < count; i++ {
+			index := reader.ReadUint16()
+			p.printf("\t#%d", index)
+			p.tab()
+			p.println("// " + javaName(d.stringValueAt(index)))
+		}
+	case data.MODULE_MAIN_CLASS:
+		index := value.Uint16()
+		p.printf("ModuleMainClass: #%d", index)
+		p.tab()
+		p.println("// " + javaName(d.className(index)))
+	default:
+		p.printf("%s: length = 0x%x (unknown attribute)\n", name, len(value))
+		p.print("  ")
+		for i, b := range value {
+			p.printf(" %02x", b)
+			if i%16 == 15 && i < len(value)-1 {
+				p.println("")
+				p.print("  ")
+			}
+		}
+		p.println("")
+	}
+}
+
+func (d *disassembler) writeInnerClasses(reader *data.AttributeValueReader) {
+	p := d.printer
+	count := int(reader.ReadUint16())
+	if count == 0 {
+		return
+	}
+	p.println("InnerClasses:")
+	p.indent(+1)
+	for i := 0; i < count; i++ {
+		innerClass := reader.ReadUint16()
+		outerClass := reader.ReadUint16()
+		innerName := reader.ReadUint16()
+		access := reader.ReadUint16()
+		if access&data.ACC_INTERFACE != 0 {
+			access &^= data.ACC_ABSTRACT
+		}
+		d.writeModifiers(access, innerClassModifiers)
+		if innerName != 0 {
+			p.printf("#%d= ", innerName)
+		}
+		p.printf("#%d", innerClass)
+		if outerClass != 0 {
+			p.printf(" of #%d", outerClass)
+		}
+		p.print(";")
+		p.tab()
+		p.print("// ")
+		if innerName != 0 {
+			p.print(d.utf8(innerName) + "=")
+		}
+		d.writeConstant(innerClass)
+		if outerClass != 0 {
+			p.print(" of ")
+			d.writeConstant(outerClass)
+		}
+		p.println("")
+	}
+	p.indent(-1)
+}
+
+func (d *disassembler) writeBootstrapMethods(reader *data.AttributeValueReader) {
+	p := d.printer
+	p.println("BootstrapMethods:")
+	count := int(reader.ReadUint16())
+	for i := 0; i < count; i++ {
+		p.indent(+1)
+		methodRef := reader.ReadUint16()
+		p.printf("%d: #%d ", i, methodRef)
+		p.println(d.stringValueAt(methodRef))
+		p.indent(+1)
+		p.println("Method arguments:")
+		p.indent(+1)
+		arguments := int(reader.ReadUint16())
+		for j := 0; j < arguments; j++ {
+			argument := reader.ReadUint16()
+			p.printf("#%d ", argument)
+			p.println(d.stringValueAt(argument))
+		}
+		p.indent(-3)
+	}
+}
+
+func (d *disassembler) writeModule(reader *data.AttributeValueReader) {
+	p := d.printer
+	p.println("Module:")
+	p.indent(+1)
+	name := reader.ReadUint16()
+	access := reader.ReadUint16()
+	p.printf("#%d,%x", name, access)
+	p.tab()
+	p.print("// " + d.stringValueAt(name))
+	d.writeModuleFlags(access)
+	p.println("")
+	d.writeOptionalIndex(reader.ReadUint16())
+
+	count := int(reader.ReadUint16())
+	p.printf("%d", count)
+	p.tab()
+	p.println("// requires")
+	p.indent(+1)
+	for i := 0; i < count; i++ {
+		index := reader.ReadUint16()
+		access := reader.ReadUint16()
+		p.printf("#%d,%x", index, access)
+		p.tab()
+		p.print("// " + d.stringValueAt(index))
+		if access&data.ACC_TRANSITIVE != 0 {
+			p.print(" ACC_TRANSITIVE")
+		}
+		if access&data.ACC_STATIC_PHASE != 0 {
+			p.print(" ACC_STATIC_PHASE")
+		}
+		d.writeModuleFlags(access &^ data.ACC_OPEN)
+		p.println("")
+		d.writeOptionalIndex(reader.ReadUint16())
+	}
+	p.indent(-1)
+
+	for _, kind := range []string{"exports", "opens"} {
+		count = int(reader.ReadUint16())
+		p.printf("%d", count)
+		p.tab()
+		p.println("// " + kind)
+		p.indent(+1)
+		for i := 0; i < count; i++ {
+			index := reader.ReadUint16()
+			access := reader.ReadUint16()
+			p.printf("#%d,%x", index, access)
+			p.tab()
+			p.print("// " + d.stringValueAt(index))
+			d.writeModuleFlags(access &^ data.ACC_OPEN)
+			to := int(reader.ReadUint16())
+			if to == 0 {
+				p.println("")
+				continue
+			}
+			p.printf(" to ... %d\n", to)
+			p.indent(+1)
+			for j := 0; j < to; j++ {
+				module := reader.ReadUint16()
+				p.printf("#%d", module)
+				p.tab()
+				p.println("// ... to " + d.stringValueAt(module))
+			}
+			p.indent(-1)
+		}
+		p.indent(-1)
+	}
+
+	count = int(reader.ReadUint16())
+	p.printf("%d", count)
+	p.tab()
+	p.println("// uses")
+	p.indent(+1)
+	for i := 0; i < count; i++ {
+		index := reader.ReadUint16()
+		p.printf("#%d", index)
+		p.tab()
+		p.println("// " + d.stringValueAt(index))
+	}
+	p.indent(-1)
+
+	count = int(reader.ReadUint16())
+	p.printf("%d", count)
+	p.tab()
+	p.println("// provides")
+	p.indent(+1)
+	for i := 0; i < count; i++ {
+		index := reader.ReadUint16()
+		with := int(reader.ReadUint16())
+		p.printf("#%d", index)
+		p.tab()
+		p.printf("// %s with ... %d\n", d.stringValueAt(index), with)
+		p.indent(+1)
+		for j := 0; j < with; j++ {
+			provider := reader.ReadUint16()
+			p.printf("#%d", provider)
+			p.tab()
+			p.println("// ... with " + d.stringValueAt(provider))
+		}
+		p.indent(-1)
+	}
+	p.indent(-1)
+	p.indent(-1)
+}
+
+func (d *disassembler) writeModuleFlags(access uint16) {
+	if access&data.ACC_OPEN != 0 {
+		d.printer.print(" ACC_OPEN")
+	}
+	if access&data.ACC_MANDATED != 0 {
+		d.printer.print(" ACC_MANDATED")
+	}
+	if access&data.ACC_SYNTHETIC != 0 {
+		d.printer.print(" ACC_SYNTHETIC")
+	}
+}
+
+func (d *disassembler) writeOptionalIndex(index uint16) {
+	d.printer.printf("#%d", index)
+	if index != 0 {
+		d.printer.tab()
+		d.printer.print("// " + d.stringValueAt(index))
+	}
+	d.printer.println("")
+}
+
+type annotation struct {
+	typeIndex uint16
+	names     []uint16
+	values    []elementValue
+}
+
+type elementValue struct {
+	tag        byte
+	index      uint16
+	constIndex uint16
+	annotation *annotation
+	values     []elementValue
+}
+
+func readAnnotation(reader *data.AttributeValueReader) *annotation {
+	a := &annotation{typeIndex: reader.ReadUint16()}
+	count := int(reader.ReadUint16())
+	for i := 0; i < count; i++ {
+		a.names = append(a.names, reader.ReadUint16())
+		a.values = append(a.values, readElementValue(reader))
+	}
+	return a
+}
+
+func readElementValue(reader *data.AttributeValueReader) elementValue {
+	value := elementValue{tag: reader.ReadUint8()}
+	switch value.tag {
+	case data.ELEMENT_TAG_ENUM:
+		value.index = reader.ReadUint16()
+		value.constIndex = reader.ReadUint16()
+	case data.ELEMENT_TAG_ANNOTATION:
+		value.annotation = readAnnotation(reader)
+	case data.ELEMENT_TAG_ARRAY:
+		count := int(reader.ReadUint16())
+		for i := 0; i < count; i++ {
+			value.values = append(value.values, readElementValue(reader))
+		}
+	default:
+		value.index = reader.ReadUint16()
+	}
+	return value
+}
+
+// writeAnnotation prints an annotation with constant pool indices, and then resolved on the
+// next lines.
+func (d *disassembler) writeAnnotation(a *annotation) {
+	d.writeAnnotationValue(a, false)
+	d.printer.println("")
+	d.printer.indent(+1)
+	d.writeAnnotationValue(a, true)
+	d.printer.indent(-1)
+}
+
+func (d *disassembler) writeAnnotationValue(a *annotation, resolve bool) {
+	p := d.printer
+	d.writeDescriptor(a.typeIndex, resolve)
+	if !resolve {
+		p.print("(")
+		for i, name := range a.names {
+			if i > 0 {
+				p.print(",")
+			}
+			p.printf("#%d=", name)
+			d.writeElementValue(a.values[i], false)
+		}
+		p.print(")")
+		return
+	}
+	if len(a.names) > 0 {
+		p.println("(")
+		p.indent(+1)
+	}
+	for i, name := range a.names {
+		p.print(d.stringValueAt(name) + "=")
+		d.writeElementValue(a.values[i], true)
+		p.println("")
+	}
+	if len(a.names) > 0 {
+		p.indent(-1)
+		p.print(")")
+	}
+}
+
+func (d *disassembler) writeDescriptor(index uint16, resolve bool) {
+	if resolve {
+		d.printer.print(fieldType(d.utf8(index)))
+	} else {
+		d.printer.printf("#%d", index)
+	}
+}
+
+func (d *disassembler) writeElementValue(value elementValue, resolve bool) {
+	p := d.printer
+	switch value.tag {
+	case data.ELEMENT_TAG_ENUM:
+		if resolve {
+			d.writeDescriptor(value.index, true)
+			p.print("." + d.stringValueAt(value.constIndex))
+		} else {
+			p.printf("%c#%d.#%d", value.tag, value.index, value.constIndex)
+		}
+	case data.ELEMENT_TAG_CLASS:
+		if resolve {
+			p.print("class ")
+			d.writeDescriptor(value.index, true)
+		} else {
+			p.printf("%c#%d", value.tag, value.index)
+		}
+	case data.ELEMENT_TAG_ANNOTATION:
+		p.print("@")
+		d.writeAnnotationValue(value.annotation, resolve)
+	case data.ELEMENT_TAG_ARRAY:
+		p.print("[")
+		for i, element := range value.values {
+			if i > 0 {
+				p.print(",")
+			}
+			d.writeElementValue(element, resolve)
+		}
+		p.print("]")
+	default:
+		if !resolve {
+			p.printf("%c#%d", value.tag, value.index)
+			return
+		}
+		switch value.tag {
+		case data.ELEMENT_TAG_BYTE:
+			p.print("(byte) " + d.stringValueAt(value.index))
+		case data.ELEMENT_TAG_SHORT:
+			p.print("(short) " + d.stringValueAt(value.index))
+		case data.ELEMENT_TAG_CHAR:
+			if constant, ok := d.constant(value.index).(data.ConstantIntegerData); ok {
+				p.print("'" + escape(string(rune(constant.IntegerValue))) + "'")
+			}
+		case data.ELEMENT_TAG_BOOLEAN:
+			if constant, ok := d.constant(value.index).(data.ConstantIntegerData); ok {
+				p.print(fmt.Sprint(constant.IntegerValue != 0))
+			}
+		case data.ELEMENT_TAG_STRING:
+			p.print(`"` + d.stringValueAt(value.index) + `"`)
+		default:
+			p.print(d.stringValueAt(value.index))
+		}
+	}
+}
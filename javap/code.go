@@ -0,0 +1,182 @@
+package javap
+
+import (
+	"github.com/tk103331/clazz/class/data"
+)
+
+var arrayTypeNames = map[int32]string{4: "boolean", 5: "char", 6: "float", 7: "double", 8: "byte", 9: "short",
+	10: "int", 11: "long"}
+
+var verificationTypeNames = []string{"top", "int", "float", "double", "long", "null", "this", "uninitialized"}
+
+// writeCode prints the Code attribute of the current method: its instructions, its exception
+// table and its own attributes.
+func (d *disassembler) writeCode(code data.CodeData) {
+	p := d.printer
+	p.println("Code:")
+	p.indent(+1)
+	argsSize := 0
+	if d.method != nil {
+		argsSize = len(parseMethodType(d.utf8(d.method.DescriptorIndex)).parameters)
+		if d.method.AccessFlags&data.ACC_STATIC == 0 {
+			argsSize++
+		}
+	}
+	p.printf("stack=%d, locals=%d, args_size=%d\n", code.MaxStack, code.MaxLocals, argsSize)
+	for _, instruction := range code.Instructions() {
+		d.writeInstruction(instruction)
+	}
+	if len(code.ExceptionTable) > 0 {
+		p.println("Exception table:")
+		p.indent(+1)
+		p.println(" from    to  target type")
+		for _, handler := range code.ExceptionTable {
+			p.printf(" %5d %5d %5d   ", handler.StartPC, handler.EndPC, handler.HandlerPC)
+			if handler.CatchType == 0 {
+				p.println("any")
+			} else {
+				p.println("Class " + d.stringValueAt(handler.CatchType))
+			}
+		}
+		p.indent(-1)
+	}
+	d.writeAttributes(code.Attributes)
+	p.indent(-1)
+}
+
+func (d *disassembler) writeInstruction(instruction data.InstructionData) {
+	p := d.printer
+	opCode := instruction.OpCode
+	name := data.OPCODE_NAMES[opCode]
+	if instruction.Wide {
+		name += "_w"
+	}
+	p.printf("%4d: %-13s ", instruction.Offset, name)
+	switch {
+	case opCode == data.BIPUSH, opCode == data.SIPUSH:
+		p.printf("%d", instruction.Value)
+	case opCode == data.NEWARRAY:
+		p.print(" " + arrayTypeNames[instruction.Value])
+	case opCode == data.IINC:
+		p.printf("%d, %d", instruction.Index, instruction.Value)
+	case opCode >= data.ILOAD && opCode <= data.ALOAD, opCode >= data.ISTORE && opCode <= data.ASTORE,
+		opCode == data.RET:
+		p.printf("%d", instruction.Index)
+	case opCode >= data.IFEQ && opCode <= data.JSR, opCode == data.IFNULL, opCode == data.IFNONNULL,
+		opCode == data.GOTO_W, opCode == data.JSR_W:
+		p.printf("%d", instruction.Offset+int(instruction.Value))
+	case opCode == data.TABLESWITCH:
+		p.printf("{ // %d to %d", instruction.Low, instruction.High)
+		p.indent(+3)
+		for i, offset := range instruction.Offsets {
+			p.printf("\n%12d: %d", instruction.Low+int32(i), instruction.Offset+int(offset))
+		}
+		p.printf("\n     default: %d\n}", instruction.Offset+int(instruction.Value))
+		p.indent(-3)
+	case opCode == data.LOOKUPSWITCH:
+		p.printf("{ // %d", len(instruction.Keys))
+		p.indent(+3)
+		for i, key := range instruction.Keys {
+			p.printf("\n%12d: %d", key, instruction.Offset+int(instruction.Offsets[i]))
+		}
+		p.printf("\n     default: %d\n}", instruction.Offset+int(instruction.Value))
+		p.indent(-3)
+	case opCode == data.INVOKEINTERFACE, opCode == data.MULTIANEWARRAY:
+		p.printf("#%d,  %d", instruction.Index, instruction.Count)
+		d.writeOperandComment(instruction.Index)
+	case opCode == data.INVOKEDYNAMIC:
+		p.printf("#%d,  0", instruction.Index)
+		d.writeOperandComment(instruction.Index)
+	case opCode == data.LDC, opCode == data.LDC_W, opCode == data.LDC2_W,
+		opCode >= data.GETSTATIC && opCode <= data.INVOKESTATIC, opCode == data.NEW, opCode == data.ANEWARRAY,
+		opCode == data.CHECKCAST, opCode == data.INSTANCEOF:
+		p.printf("#%d", instruction.Index)
+		d.writeOperandComment(instruction.Index)
+	}
+	p.println("")
+}
+
+func (d *disassembler) writeOperandComment(index uint16) {
+	d.printer.tab()
+	d.printer.print("// ")
+	d.writeConstant(index)
+}
+
+// writeStackMapTable prints the frames of a StackMapTable attribute.
+func (d *disassembler) writeStackMapTable(reader *data.AttributeValueReader) {
+	p := d.printer
+	count := int(reader.ReadUint16())
+	p.printf("StackMapTable: number_of_entries = %d\n", count)
+	p.indent(+1)
+	for i := 0; i < count; i++ {
+		frameType := int(reader.ReadUint8())
+		switch {
+		case frameType < 64:
+			p.printf("frame_type = %d /* same */\n", frameType)
+		case frameType < 128:
+			p.printf("frame_type = %d /* same_locals_1_stack_item */\n", frameType)
+			p.indent(+1)
+			d.writeVerificationTypes(reader, "stack", 1)
+			p.indent(-1)
+		case frameType < 247:
+			p.printf("frame_type = %d /* unknown */\n", frameType)
+		case frameType == 247:
+			p.printf("frame_type = %d /* same_locals_1_stack_item_frame_extended */\n", frameType)
+			p.indent(+1)
+			p.printf("offset_delta = %d\n", reader.ReadUint16())
+			d.writeVerificationTypes(reader, "stack", 1)
+			p.indent(-1)
+		case frameType < 251:
+			p.printf("frame_type = %d /* chop */\n", frameType)
+			p.indent(+1)
+			p.printf("offset_delta = %d\n", reader.ReadUint16())
+			p.indent(-1)
+		case frameType == 251:
+			p.printf("frame_type = %d /* same_frame_extended */\n", frameType)
+			p.indent(+1)
+			p.printf("offset_delta = %d\n", reader.ReadUint16())
+			p.indent(-1)
+		case frameType < 255:
+			p.printf("frame_type = %d /* append */\n", frameType)
+			p.indent(+1)
+			p.printf("offset_delta = %d\n", reader.ReadUint16())
+			d.writeVerificationTypes(reader, "locals", frameType-251)
+			p.indent(-1)
+		default:
+			p.printf("frame_type = %d /* full_frame */\n", frameType)
+			p.indent(+1)
+			p.printf("offset_delta = %d\n", reader.ReadUint16())
+			d.writeVerificationTypes(reader, "locals", int(reader.ReadUint16()))
+			d.writeVerificationTypes(reader, "stack", int(reader.ReadUint16()))
+			p.indent(-1)
+		}
+	}
+	p.indent(-1)
+}
+
+func (d *disassembler) writeVerificationTypes(reader *data.AttributeValueReader, name string, count int) {
+	p := d.printer
+	p.print(name + " = [")
+	for i := 0; i < count; i++ {
+		tag := reader.ReadUint8()
+		switch tag {
+		case data.ITEM_OBJECT:
+			p.print(" ")
+			d.writeConstant(reader.ReadUint16())
+		case data.ITEM_UNINITIALIZED:
+			p.printf(" uninitialized %d", reader.ReadUint16())
+		default:
+			if int(tag) < len(verificationTypeNames) {
+				p.print(" " + verificationTypeNames[tag])
+			} else {
+				p.printf(" unknown %d", tag)
+			}
+		}
+		if i == count-1 {
+			p.print(" ")
+		} else {
+			p.print(",")
+		}
+	}
+	p.println("]")
+}
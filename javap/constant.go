@@ -0,0 +1,291 @@
+package javap
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+var constantTagNames = map[uint8]string{
+	data.TAG_CONSTANT_UTF8:                "Utf8",
+	data.TAG_CONSTANT_INTEGER:             "Integer",
+	data.TAG_CONSTANT_FLOAT:               "Float",
+	data.TAG_CONSTANT_LONG:                "Long",
+	data.TAG_CONSTANT_DOUBLE:              "Double",
+	data.TAG_CONSTANT_CLASS:               "Class",
+	data.TAG_CONSTANT_STRING:              "String",
+	data.TAG_CONSTANT_FIELDREF:            "Fieldref",
+	data.TAG_CONSTANT_METHODREF:           "Methodref",
+	data.TAG_CONSTANT_INTERFACE_METHODREF: "InterfaceMethodref",
+	data.TAG_CONSTANT_NAME_AND_TYPE:       "NameAndType",
+	data.TAG_CONSTANT_METHOD_HANDLE:       "MethodHandle",
+	data.TAG_CONSTANT_METHOD_TYPE:         "MethodType",
+	data.TAG_CONSTANT_DYNAMIC:             "Dynamic",
+	data.TAG_CONSTANT_INVOKE_DYNAMIC:      "InvokeDynamic",
+	data.TAG_CONSTANT_MODULE:              "Module",
+	data.TAG_CONSTANT_PACKAGE:             "Package",
+}
+
+// constantKinds are the kinds printed before the values of the constants referenced by
+// instructions and attributes.
+var constantKinds = map[uint8]string{
+	data.TAG_CONSTANT_UTF8:                "Utf8",
+	data.TAG_CONSTANT_INTEGER:             "int",
+	data.TAG_CONSTANT_FLOAT:               "float",
+	data.TAG_CONSTANT_LONG:                "long",
+	data.TAG_CONSTANT_DOUBLE:              "double",
+	data.TAG_CONSTANT_CLASS:               "class",
+	data.TAG_CONSTANT_STRING:              "String",
+	data.TAG_CONSTANT_FIELDREF:            "Field",
+	data.TAG_CONSTANT_METHODREF:           "Method",
+	data.TAG_CONSTANT_INTERFACE_METHODREF: "InterfaceMethod",
+	data.TAG_CONSTANT_NAME_AND_TYPE:       "NameAndType",
+	data.TAG_CONSTANT_METHOD_HANDLE:       "MethodHandle",
+	data.TAG_CONSTANT_METHOD_TYPE:         "MethodType",
+	data.TAG_CONSTANT_DYNAMIC:             "Dynamic",
+	data.TAG_CONSTANT_INVOKE_DYNAMIC:      "InvokeDynamic",
+}
+
+var referenceKindNames = []string{"", "REF_getField", "REF_getStatic", "REF_putField", "REF_putStatic",
+	"REF_invokeVirtual", "REF_invokeStatic", "REF_invokeSpecial", "REF_newInvokeSpecial", "REF_invokeInterface"}
+
+func (d *disassembler) constant(index uint16) data.ConstantData {
+	if int(index) >= len(d.class.ConstantPool) {
+		return nil
+	}
+	return d.class.ConstantPool[index]
+}
+
+func (d *disassembler) utf8(index uint16) string {
+	if constant, ok := d.constant(index).(data.ConstantUTF8Data); ok {
+		return constant.UTF8Value
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+func (d *disassembler) className(index uint16) string {
+	if constant, ok := d.constant(index).(data.ConstantClassData); ok {
+		return d.utf8(constant.NameIndex)
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// writeConstantPool prints the constant pool, with an entry per line.
+func (d *disassembler) writeConstantPool() {
+	p := d.printer
+	pool := d.class.ConstantPool
+	p.println("Constant pool:")
+	p.indent(+1)
+	width := len(strconv.Itoa(len(pool))) + 1
+	for i := 1; i < len(pool); i++ {
+		constant := pool[i]
+		if constant == nil {
+			continue
+		}
+		p.printf("%*s", width, "#"+strconv.Itoa(i))
+		p.printf(" = %-18s ", constantTagNames[constant.Tag()])
+		switch c := constant.(type) {
+		case data.ConstantClassData:
+			d.printComment(fmt.Sprintf("#%d", c.NameIndex), d.stringValue(c))
+		case data.ConstantStringData:
+			d.printComment(fmt.Sprintf("#%d", c.ValueIndex), d.stringValue(c))
+		case data.ConstantModuleData:
+			d.printComment(fmt.Sprintf("#%d", c.NameIndex), d.stringValue(c))
+		case data.ConstantPackageData:
+			d.printComment(fmt.Sprintf("#%d", c.NameIndex), d.stringValue(c))
+		case data.ConstantFieldRefData:
+			d.printComment(fmt.Sprintf("#%d.#%d", c.ClassIndex, c.NameAndTypeIndex), d.stringValue(c))
+		case data.ConstantMethodRefData:
+			d.printComment(fmt.Sprintf("#%d.#%d", c.ClassIndex, c.NameAndTypeIndex), d.stringValue(c))
+		case data.ConstantInterfaceMethodRefData:
+			d.printComment(fmt.Sprintf("#%d.#%d", c.ClassIndex, c.NameAndTypeIndex), d.stringValue(c))
+		case data.ConstantNameAndTypeData:
+			d.printComment(fmt.Sprintf("#%d:#%d", c.NameIndex, c.DescriptorIndex), d.stringValue(c))
+		case data.ConstantMethodHandleData:
+			d.printComment(fmt.Sprintf("%d:#%d", c.ReferenceKind, c.ReferenceIndex), d.stringValue(c))
+		case data.ConstantMethodTypeData:
+			d.printComment(fmt.Sprintf("#%d", c.DescriptorIndex), " "+d.stringValue(c))
+		case data.ConstantDynamicData:
+			d.printComment(fmt.Sprintf("#%d:#%d", c.BootstrapMethodIndex, c.NameAndTypeIndex), d.stringValue(c))
+		case data.ConstantInvokeDynamicData:
+			d.printComment(fmt.Sprintf("#%d:#%d", c.BootstrapMethodIndex, c.NameAndTypeIndex), d.stringValue(c))
+		default:
+			p.println(d.stringValue(constant))
+		}
+	}
+	p.indent(-1)
+}
+
+func (d *disassembler) printComment(value string, comment string) {
+	d.printer.print(value)
+	d.printer.tab()
+	d.printer.println("// " + comment)
+}
+
+// writeConstant prints a constant referenced by an instruction or an attribute, preceded by
+// its kind. The references to the members of this class are printed without class name.
+func (d *disassembler) writeConstant(index uint16) {
+	if index == 0 {
+		d.printer.print("#0")
+		return
+	}
+	constant := d.constant(index)
+	if constant == nil {
+		d.printer.printf("#%d", index)
+		return
+	}
+	kind := constantKinds[constant.Tag()]
+	if reference, ok := constant.(data.ConstantReferenceData); ok && reference.OwnerIndex() == d.class.ThisClass {
+		constant = d.constant(reference.DescriptorIndex())
+	}
+	d.printer.print(kind + " " + d.stringValue(constant))
+}
+
+func (d *disassembler) stringValueAt(index uint16) string {
+	constant := d.constant(index)
+	if constant == nil {
+		return fmt.Sprintf("#%d", index)
+	}
+	return d.stringValue(constant)
+}
+
+// stringValue returns the value of a constant as printed by javap.
+func (d *disassembler) stringValue(constant data.ConstantData) string {
+	switch c := constant.(type) {
+	case data.ConstantUTF8Data:
+		return escape(c.UTF8Value)
+	case data.ConstantIntegerData:
+		return strconv.Itoa(int(c.IntegerValue))
+	case data.ConstantFloatData:
+		return javaFloat(float64(c.FloatValue), 32) + "f"
+	case data.ConstantLongData:
+		return strconv.FormatInt(c.LongValue, 10) + "l"
+	case data.ConstantDoubleData:
+		return javaFloat(c.DoubleValue, 64) + "d"
+	case data.ConstantClassData:
+		return checkName(d.utf8(c.NameIndex))
+	case data.ConstantStringData:
+		return d.stringValueAt(c.ValueIndex)
+	case data.ConstantModuleData:
+		return checkName(d.utf8(c.NameIndex))
+	case data.ConstantPackageData:
+		return checkName(d.utf8(c.NameIndex))
+	case data.ConstantFieldRefData:
+		return d.referenceValue(c.ClassIndex, c.NameAndTypeIndex)
+	case data.ConstantMethodRefData:
+		return d.referenceValue(c.ClassIndex, c.NameAndTypeIndex)
+	case data.ConstantInterfaceMethodRefData:
+		return d.referenceValue(c.ClassIndex, c.NameAndTypeIndex)
+	case data.ConstantNameAndTypeData:
+		return checkName(d.utf8(c.NameIndex)) + ":" + d.stringValueAt(c.DescriptorIndex)
+	case data.ConstantMethodHandleData:
+		kind := fmt.Sprint(c.ReferenceKind)
+		if int(c.ReferenceKind) < len(referenceKindNames) {
+			kind = referenceKindNames[c.ReferenceKind]
+		}
+		return kind + " " + d.stringValueAt(c.ReferenceIndex)
+	case data.ConstantMethodTypeData:
+		return d.stringValueAt(c.DescriptorIndex)
+	case data.ConstantDynamicData:
+		return fmt.Sprintf("#%d:%s", c.BootstrapMethodIndex, d.stringValueAt(c.NameAndTypeIndex))
+	case data.ConstantInvokeDynamicData:
+		return fmt.Sprintf("#%d:%s", c.BootstrapMethodIndex, d.stringValueAt(c.NameAndTypeIndex))
+	default:
+		return "<unknown>"
+	}
+}
+
+func (d *disassembler) referenceValue(classIndex uint16, nameAndTypeIndex uint16) string {
+	return d.stringValueAt(classIndex) + "." + d.stringValueAt(nameAndTypeIndex)
+}
+
+// checkName returns a name unchanged if it is a valid binary name, or quoted otherwise.
+func checkName(name string) string {
+	if len(name) == 0 {
+		return `""`
+	}
+	previous := '/'
+	for _, c := range name {
+		if (previous == '/' && !isJavaIdentifierStart(c)) || (c != '/' && !isJavaIdentifierPart(c)) {
+			return `"` + escape(name) + `"`
+		}
+		previous = c
+	}
+	return name
+}
+
+func isJavaIdentifierStart(c rune) bool {
+	return unicode.IsLetter(c) || c == '$' || c == '_' || unicode.Is(unicode.Sc, c)
+}
+
+func isJavaIdentifierPart(c rune) bool {
+	return isJavaIdentifierStart(c) || unicode.IsDigit(c)
+}
+
+// escape escapes a string as javap does for the Utf8 constants.
+func escape(s string) string {
+	var builder strings.Builder
+	for _, c := range s {
+		switch c {
+		case '\t':
+			builder.WriteString(`\t`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\b':
+			builder.WriteString(`\b`)
+		case '\f':
+			builder.WriteString(`\f`)
+		case '"':
+			builder.WriteString(`\"`)
+		case '\'':
+			builder.WriteString(`\'`)
+		case '\\':
+			builder.WriteString(`\\`)
+		default:
+			if unicode.IsControl(c) {
+				fmt.Fprintf(&builder, `\u%04x`, c)
+			} else {
+				builder.WriteRune(c)
+			}
+		}
+	}
+	return builder.String()
+}
+
+// javaFloat formats a floating point value as Java's Double.toString and Float.toString.
+func javaFloat(v float64, bitSize int) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	case v == 0:
+		if math.Signbit(v) {
+			return "-0.0"
+		}
+		return "0.0"
+	}
+	if abs := math.Abs(v); abs >= 1e-3 && abs < 1e7 {
+		s := strconv.FormatFloat(v, 'f', -1, bitSize)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	}
+	s := strconv.FormatFloat(v, 'e', -1, bitSize)
+	i := strings.IndexByte(s, 'e')
+	mantissa := s[:i]
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	exponent, _ := strconv.Atoi(s[i+1:])
+	return mantissa + "E" + strconv.Itoa(exponent)
+}
@@ -0,0 +1,330 @@
+// Package javap disassembles class files with the output format of "javap -v -c -p".
+package javap
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tk103331/clazz/class/data"
+)
+
+// Options are the information about the class file printed in the header of the output.
+type Options struct {
+	// Path is the path of the class file, printed in the "Classfile" line if not empty.
+	Path string
+	// LastModified is the modification time of the class file, printed if not zero.
+	LastModified time.Time
+	// Checksum is the name of the checksum algorithm, "SHA-256" by default, or "MD5" as in the
+	// javap versions before 17.
+	Checksum string
+}
+
+type flag struct {
+	mask uint16
+	name string
+}
+
+var classFlags = []flag{{data.ACC_PUBLIC, "ACC_PUBLIC"}, {data.ACC_FINAL, "ACC_FINAL"},
+	{data.ACC_SUPER, "ACC_SUPER"}, {data.ACC_INTERFACE, "ACC_INTERFACE"}, {data.ACC_ABSTRACT, "ACC_ABSTRACT"},
+	{data.ACC_SYNTHETIC, "ACC_SYNTHETIC"}, {data.ACC_ANNOTATION, "ACC_ANNOTATION"}, {data.ACC_ENUM, "ACC_ENUM"},
+	{data.ACC_MODULE, "ACC_MODULE"}}
+
+var fieldFlags = []flag{{data.ACC_PUBLIC, "ACC_PUBLIC"}, {data.ACC_PRIVATE, "ACC_PRIVATE"},
+	{data.ACC_PROTECTED, "ACC_PROTECTED"}, {data.ACC_STATIC, "ACC_STATIC"}, {data.ACC_FINAL, "ACC_FINAL"},
+	{data.ACC_VOLATILE, "ACC_VOLATILE"}, {data.ACC_TRANSIENT, "ACC_TRANSIENT"}, {data.ACC_SYNTHETIC, "ACC_SYNTHETIC"},
+	{data.ACC_ENUM, "ACC_ENUM"}}
+
+var methodFlags = []flag{{data.ACC_PUBLIC, "ACC_PUBLIC"}, {data.ACC_PRIVATE, "ACC_PRIVATE"},
+	{data.ACC_PROTECTED, "ACC_PROTECTED"}, {data.ACC_STATIC, "ACC_STATIC"}, {data.ACC_FINAL, "ACC_FINAL"},
+	{data.ACC_SYNCHRONIZED, "ACC_SYNCHRONIZED"}, {data.ACC_BRIDGE, "ACC_BRIDGE"}, {data.ACC_VARARGS, "ACC_VARARGS"},
+	{data.ACC_NATIVE, "ACC_NATIVE"}, {data.ACC_ABSTRACT, "ACC_ABSTRACT"}, {data.ACC_STRICT, "ACC_STRICT"},
+	{data.ACC_SYNTHETIC, "ACC_SYNTHETIC"}}
+
+var classModifiers = []flag{{data.ACC_PUBLIC, "public"}, {data.ACC_FINAL, "final"}, {data.ACC_ABSTRACT, "abstract"}}
+
+var innerClassModifiers = []flag{{data.ACC_PUBLIC, "public"}, {data.ACC_PRIVATE, "private"},
+	{data.ACC_PROTECTED, "protected"}, {data.ACC_STATIC, "static"}, {data.ACC_FINAL, "final"},
+	{data.ACC_ABSTRACT, "abstract"}}
+
+var fieldModifiers = []flag{{data.ACC_PUBLIC, "public"}, {data.ACC_PRIVATE, "private"},
+	{data.ACC_PROTECTED, "protected"}, {data.ACC_STATIC, "static"}, {data.ACC_FINAL, "final"},
+	{data.ACC_VOLATILE, "volatile"}, {data.ACC_TRANSIENT, "transient"}}
+
+var methodModifiers = []flag{{data.ACC_PUBLIC, "public"}, {data.ACC_PRIVATE, "private"},
+	{data.ACC_PROTECTED, "protected"}, {data.ACC_STATIC, "static"}, {data.ACC_FINAL, "final"},
+	{data.ACC_SYNCHRONIZED, "synchronized"}, {data.ACC_NATIVE, "native"}, {data.ACC_ABSTRACT, "abstract"},
+	{data.ACC_STRICT, "strictfp"}}
+
+func flagNames(access uint16, flags []flag) []string {
+	names := make([]string, 0)
+	for _, f := range flags {
+		if access&f.mask != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+type disassembler struct {
+	printer *printer
+	class   *data.ClassData
+	method  *data.MethodData
+}
+
+// Disassemble prints the class file with the given content.
+func Disassemble(w io.Writer, content []byte, options Options) error {
+	reader := data.NewReader(bytes.NewReader(content))
+	if err := reader.Read(); err != nil {
+		return err
+	}
+	d := &disassembler{printer: &printer{out: w}, class: reader.Data()}
+	if err := d.write(content, options); err != nil {
+		return err
+	}
+	return d.printer.err
+}
+
+// DisassembleFile prints the class file with the given path.
+func DisassembleFile(w io.Writer, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	options := Options{Path: path}
+	if absolutePath, err := filepath.Abs(path); err == nil {
+		options.Path = absolutePath
+	}
+	if info, err := os.Stat(path); err == nil {
+		options.LastModified = info.ModTime()
+	}
+	return Disassemble(w, content, options)
+}
+
+func (d *disassembler) write(content []byte, options Options) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("malformed class: %v", e)
+		}
+	}()
+	p := d.printer
+	c := d.class
+	if len(options.Path) > 0 {
+		p.println("Classfile " + options.Path)
+	}
+	p.indent(+1)
+	if !options.LastModified.IsZero() {
+		p.printf("Last modified %s; size %d bytes\n", options.LastModified.Format("Jan 2, 2006"), len(content))
+	} else {
+		p.printf("Size %d bytes\n", len(content))
+	}
+	var digest hash.Hash
+	if options.Checksum == "MD5" {
+		digest = md5.New()
+	} else {
+		options.Checksum = "SHA-256"
+		digest = sha256.New()
+	}
+	digest.Write(content)
+	p.printf("%s checksum %x\n", options.Checksum, digest.Sum(nil))
+	if attribute := d.findAttribute(c.Attributes, data.SOURCE_FILE); attribute != nil {
+		p.println(`Compiled from "` + d.utf8(attribute.Value.Uint16()) + `"`)
+	}
+	p.indent(-1)
+
+	d.writeClassHeader()
+	p.println("")
+	p.indent(+1)
+	p.printf("minor version: %d\n", c.MinorVersion)
+	p.printf("major version: %d\n", c.MajorVersion)
+	d.writeFlags(c.AccessFlags, classFlags)
+	p.printf("this_class: #%d", c.ThisClass)
+	if c.ThisClass != 0 {
+		p.tab()
+		p.print("// " + d.stringValueAt(c.ThisClass))
+	}
+	p.println("")
+	p.printf("super_class: #%d", c.SuperClass)
+	if c.SuperClass != 0 {
+		p.tab()
+		p.print("// " + d.stringValueAt(c.SuperClass))
+	}
+	p.println("")
+	p.printf("interfaces: %d, fields: %d, methods: %d, attributes: %d\n",
+		len(c.Interfaces), len(c.Fields), len(c.Methods), len(c.Attributes))
+	p.indent(-1)
+	d.writeConstantPool()
+	p.println("{")
+	p.indent(+1)
+	for i := range c.Fields {
+		d.writeField(&c.Fields[i])
+	}
+	for i := range c.Methods {
+		d.writeMethod(&c.Methods[i])
+	}
+	p.pendingNewline = false
+	p.indent(-1)
+	p.println("}")
+	d.writeAttributes(c.Attributes)
+	return nil
+}
+
+func (d *disassembler) findAttribute(attributes []data.AttributeData, name string) *data.AttributeData {
+	for i := range attributes {
+		if d.utf8(attributes[i].NameIndex) == name {
+			return &attributes[i]
+		}
+	}
+	return nil
+}
+
+func (d *disassembler) writeModifiers(access uint16, modifiers []flag) {
+	for _, name := range flagNames(access, modifiers) {
+		d.printer.print(name + " ")
+	}
+}
+
+func (d *disassembler) writeFlags(access uint16, flags []flag) {
+	d.printer.printf("flags: (0x%04x) ", access)
+	d.printer.println(strings.Join(flagNames(access, flags), ", "))
+}
+
+func (d *disassembler) writeClassHeader() {
+	p := d.printer
+	c := d.class
+	access := c.AccessFlags
+	isInterface := access&data.ACC_INTERFACE != 0
+	if isInterface {
+		access &^= data.ACC_ABSTRACT
+	}
+	d.writeModifiers(access, classModifiers)
+	if c.AccessFlags&data.ACC_MODULE != 0 {
+		p.print("module ")
+		if attribute := d.findAttribute(c.Attributes, data.MODULE); attribute != nil {
+			reader := attribute.Value.Reader()
+			p.print(d.stringValueAt(reader.ReadUint16()))
+			reader.ReadUint16()
+			if version := reader.ReadUint16(); version != 0 {
+				p.print("@" + d.utf8(version))
+			}
+		}
+		return
+	}
+	if isInterface {
+		p.print("interface ")
+	} else {
+		p.print("class ")
+	}
+	p.print(javaName(d.className(c.ThisClass)))
+	if attribute := d.findAttribute(c.Attributes, data.SIGNATURE); attribute != nil {
+		signature := parseClassType(d.utf8(attribute.Value.Uint16()))
+		p.print(typeParameters(signature.typeParameters))
+		if isInterface {
+			if len(signature.interfaces) > 0 {
+				p.print(" extends " + strings.Join(signature.interfaces, ", "))
+			}
+		} else {
+			p.print(" extends " + signature.superClass)
+			if len(signature.interfaces) > 0 {
+				p.print(" implements " + strings.Join(signature.interfaces, ", "))
+			}
+		}
+		return
+	}
+	if !isInterface && c.SuperClass != 0 {
+		if superName := javaName(d.className(c.SuperClass)); superName != "java.lang.Object" {
+			p.print(" extends " + superName)
+		}
+	}
+	for i, itf := range c.Interfaces {
+		if i > 0 {
+			p.print(",")
+		} else if isInterface {
+			p.print(" extends ")
+		} else {
+			p.print(" implements ")
+		}
+		p.print(javaName(d.className(itf.Index)))
+	}
+}
+
+func (d *disassembler) writeField(field *data.FieldData) {
+	p := d.printer
+	d.writeModifiers(field.AccessFlags, fieldModifiers)
+	if attribute := d.findAttribute(field.Attributes, data.SIGNATURE); attribute != nil {
+		p.print(fieldType(d.utf8(attribute.Value.Uint16())))
+	} else {
+		p.print(fieldType(d.utf8(field.DescriptorIndex)))
+	}
+	p.print(" " + d.utf8(field.NameIndex) + ";")
+	p.println("")
+	p.indent(+1)
+	p.println("descriptor: " + d.utf8(field.DescriptorIndex))
+	d.writeFlags(field.AccessFlags, fieldFlags)
+	d.writeAttributes(field.Attributes)
+	p.indent(-1)
+	p.println("")
+}
+
+func (d *disassembler) writeMethod(method *data.MethodData) {
+	p := d.printer
+	d.method = method
+	descriptor := d.utf8(method.DescriptorIndex)
+	m := parseMethodType(descriptor)
+	var genericExceptions []string
+	if attribute := d.findAttribute(method.Attributes, data.SIGNATURE); attribute != nil {
+		m = parseMethodType(d.utf8(attribute.Value.Uint16()))
+		genericExceptions = m.exceptions
+	}
+	d.writeModifiers(method.AccessFlags, methodModifiers)
+	if len(m.typeParameters) > 0 {
+		p.print(typeParameters(m.typeParameters) + " ")
+	}
+	parameters := "(" + strings.Join(m.parameters, ", ") + ")"
+	if method.AccessFlags&data.ACC_VARARGS != 0 {
+		if i := strings.LastIndex(parameters, "[]"); i > 0 {
+			parameters = parameters[:i] + "..." + parameters[i+2:]
+		}
+	}
+	switch name := d.utf8(method.NameIndex); name {
+	case "<clinit>":
+		p.print("{}")
+	case "<init>":
+		p.print(javaName(d.className(d.class.ThisClass)) + parameters)
+	default:
+		p.print(m.returnType + " " + name + parameters)
+	}
+	if attribute := d.findAttribute(method.Attributes, data.EXCEPTIONS); attribute != nil {
+		p.print(" throws ")
+		if len(genericExceptions) > 0 {
+			p.print(strings.Join(genericExceptions, ", "))
+		} else {
+			p.print(strings.Join(d.exceptions(attribute), ", "))
+		}
+	}
+	p.println(";")
+	p.indent(+1)
+	p.println("descriptor: " + descriptor)
+	d.writeFlags(method.AccessFlags, methodFlags)
+	d.writeAttributes(method.Attributes)
+	p.indent(-1)
+	p.pendingNewline = true
+	d.method = nil
+}
+
+func (d *disassembler) exceptions(attribute *data.AttributeData) []string {
+	reader := attribute.Value.Reader()
+	exceptions := make([]string, reader.ReadUint16())
+	for i := range exceptions {
+		exceptions[i] = javaName(d.className(reader.ReadUint16()))
+	}
+	return exceptions
+}
@@ -0,0 +1,100 @@
+package javap
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/tools"
+)
+
+func disassemble(t *testing.T, content []byte) string {
+	t.Helper()
+	var out bytes.Buffer
+	tools.AssertNoErr(t, Disassemble(&out, content, Options{}))
+	return out.String()
+}
+
+func assertLines(t *testing.T, output string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, output)
+		}
+	}
+}
+
+func TestDisassembleHello(t *testing.T) {
+	content, err := ioutil.ReadFile("../class/Hello.class")
+	tools.AssertNoErr(t, err)
+	assertLines(t, disassemble(t, content),
+		`  Compiled from "Hello.java"`,
+		"public class com.example.demo.Hello",
+		"  major version: 52",
+		"  flags: (0x0021) ACC_PUBLIC, ACC_SUPER",
+		"  this_class: #4                          // com/example/demo/Hello",
+		"   #1 = Methodref          #5.#26         // java/lang/Object.\"<init>\":()V",
+		"  #26 = NameAndType        #14:#15        // \"<init>\":()V",
+		"  private int x;",
+		"    flags: (0x0002) ACC_PRIVATE",
+		"      stack=2, locals=1, args_size=1",
+		"         1: invokespecial #1                  // Method java/lang/Object.\"<init>\":()V",
+		"         5: sipush        233",
+		"         8: putfield      #2                  // Field x:I",
+		"        line 11: 0",
+		"  public int method3(int);",
+		"}",
+		`SourceFile: "Hello.java"`,
+		"  public static #7= #6 of #4;             // User=class com/example/demo/Hello$User of class com/example/demo/Hello")
+}
+
+func TestDisassembleCode(t *testing.T) {
+	writer := class.NewWriter()
+	writer.Visit(52, data.ACC_PUBLIC|data.ACC_SUPER, "a/A", "", "java/lang/Object", nil)
+	method := writer.VisitMethod(data.ACC_PUBLIC|data.ACC_STATIC, "m", "(I)I", "", nil)
+	method.VisitCode()
+	start, end, handler := class.NewLabel(), class.NewLabel(), class.NewLabel()
+	one, dflt := class.NewLabel(), class.NewLabel()
+	method.VisitTryCatchBlock(start, end, handler, "java/lang/Exception")
+	method.VisitLabel(start)
+	method.VisitVarInstruction(data.ILOAD, 0)
+	method.VisitLookupSwitchInstruction(dflt, []int32{10}, []*class.Label{one})
+	method.VisitLabel(one)
+	method.VisitFrame(data.F_SAME, 0, nil, 0, nil)
+	method.VisitLdcInstruction(2.5)
+	method.VisitInstruction(data.POP2)
+	method.VisitLabel(dflt)
+	method.VisitFrame(data.F_SAME, 0, nil, 0, nil)
+	method.VisitInstruction(data.ICONST_1)
+	method.VisitLabel(end)
+	method.VisitInstruction(data.IRETURN)
+	method.VisitLabel(handler)
+	method.VisitFrame(data.F_SAME1, 0, nil, 1, []interface{}{"java/lang/Exception"})
+	method.VisitInstruction(data.POP)
+	method.VisitInstruction(data.ICONST_0)
+	method.VisitInstruction(data.IRETURN)
+	method.VisitMaxs(2, 1)
+	method.VisitEnd()
+	writer.VisitEnd()
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+
+	assertLines(t, disassemble(t, content),
+		"  public static int m(int);",
+		"    flags: (0x0009) ACC_PUBLIC, ACC_STATIC",
+		"         1: lookupswitch  { // 1",
+		"                      10: 20",
+		"                 default: 24",
+		"            }",
+		"        20: ldc2_w        #7                  // double 2.5d",
+		"      Exception table:",
+		"         from    to  target type",
+		"             0    25    26   Class java/lang/Exception",
+		"      StackMapTable: number_of_entries = 3",
+		"        frame_type = 20 /* same */",
+		"        frame_type = 65 /* same_locals_1_stack_item */",
+		"          stack = [ class java/lang/Exception ]")
+}
@@ -0,0 +1,83 @@
+package javap
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	indentWidth = 2
+	tabColumn   = 40
+)
+
+// printer reproduces the line layout of javap: spaces are only written when followed by another
+// character on the same line, lines are indented by indentWidth spaces per level, and tab
+// aligns the comments at tabColumn after the indentation.
+type printer struct {
+	out            io.Writer
+	buffer         strings.Builder
+	column         int
+	indentCount    int
+	pendingSpaces  int
+	pendingNewline bool
+	err            error
+}
+
+func (p *printer) print(s string) {
+	if p.pendingNewline {
+		p.pendingNewline = false
+		p.println("")
+	}
+	for _, c := range s {
+		switch c {
+		case ' ':
+			p.pendingSpaces++
+		case '\n':
+			p.println("")
+		default:
+			if p.column == 0 {
+				p.pad(p.indentCount * indentWidth)
+			}
+			p.pad(p.pendingSpaces)
+			p.pendingSpaces = 0
+			p.buffer.WriteRune(c)
+			p.column++
+		}
+	}
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	p.print(fmt.Sprintf(format, args...))
+}
+
+func (p *printer) println(s string) {
+	p.print(s)
+	p.pendingSpaces = 0
+	p.buffer.WriteByte('\n')
+	if p.err == nil {
+		_, p.err = io.WriteString(p.out, p.buffer.String())
+	}
+	p.buffer.Reset()
+	p.column = 0
+}
+
+func (p *printer) pad(n int) {
+	for i := 0; i < n; i++ {
+		p.buffer.WriteByte(' ')
+	}
+	p.column += n
+}
+
+func (p *printer) tab() {
+	column := p.indentCount*indentWidth + tabColumn
+	if column <= p.column {
+		p.pendingSpaces++
+	} else {
+		p.pendingSpaces += column - p.column
+	}
+}
+
+func (p *printer) indent(delta int) {
+	p.indentCount += delta
+}
@@ -0,0 +1,193 @@
+package javap
+
+import (
+	"strings"
+)
+
+var primitiveNames = map[byte]string{
+	'B': "byte", 'C': "char", 'D': "double", 'F': "float", 'I': "int", 'J': "long", 'S': "short",
+	'Z': "boolean", 'V': "void",
+}
+
+func javaName(internalName string) string {
+	return strings.ReplaceAll(internalName, "/", ".")
+}
+
+// typeParser parses descriptors and generic signatures into Java source types.
+type typeParser struct {
+	s   string
+	pos int
+}
+
+func (t *typeParser) more() bool {
+	return t.pos < len(t.s)
+}
+
+func (t *typeParser) peek() byte {
+	if t.pos < len(t.s) {
+		return t.s[t.pos]
+	}
+	return 0
+}
+
+func (t *typeParser) next() byte {
+	c := t.peek()
+	t.pos++
+	return c
+}
+
+// parseType parses a field descriptor or a type signature.
+func (t *typeParser) parseType() string {
+	c := t.next()
+	switch c {
+	case '[':
+		return t.parseType() + "[]"
+	case 'L':
+		return t.parseClassType()
+	case 'T':
+		end := strings.IndexByte(t.s[t.pos:], ';')
+		if end < 0 {
+			end = len(t.s) - t.pos - 1
+		}
+		name := t.s[t.pos : t.pos+end]
+		t.pos += end + 1
+		return name
+	default:
+		if name, ok := primitiveNames[c]; ok {
+			return name
+		}
+		return string(c)
+	}
+}
+
+func (t *typeParser) parseClassType() string {
+	var builder strings.Builder
+	for t.more() {
+		c := t.next()
+		switch c {
+		case ';':
+			return builder.String()
+		case '/':
+			builder.WriteByte('.')
+		case '<':
+			builder.WriteByte('<')
+			separator := ""
+			for t.more() && t.peek() != '>' {
+				builder.WriteString(separator)
+				switch t.peek() {
+				case '*':
+					t.pos++
+					builder.WriteString("?")
+				case '+':
+					t.pos++
+					builder.WriteString("? extends " + t.parseType())
+				case '-':
+					t.pos++
+					builder.WriteString("? super " + t.parseType())
+				default:
+					builder.WriteString(t.parseType())
+				}
+				separator = ", "
+			}
+			t.pos++
+			builder.WriteByte('>')
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// parseTypeParameters parses the optional formal type parameters of a signature.
+func (t *typeParser) parseTypeParameters() []string {
+	if t.peek() != '<' {
+		return nil
+	}
+	t.pos++
+	parameters := make([]string, 0)
+	for t.more() && t.peek() != '>' {
+		end := strings.IndexByte(t.s[t.pos:], ':')
+		if end < 0 {
+			break
+		}
+		name := t.s[t.pos : t.pos+end]
+		t.pos += end
+		separator := " extends "
+		for t.peek() == ':' {
+			t.pos++
+			if t.peek() == ':' {
+				continue
+			}
+			name += separator + t.parseType()
+			separator = " & "
+		}
+		parameters = append(parameters, name)
+	}
+	t.pos++
+	return parameters
+}
+
+// parameterTypes returns the parameter types of a method descriptor or signature, and moves
+// after the closing parenthesis.
+func (t *typeParser) parameterTypes() []string {
+	types := make([]string, 0)
+	if t.peek() != '(' {
+		return types
+	}
+	t.pos++
+	for t.more() && t.peek() != ')' {
+		types = append(types, t.parseType())
+	}
+	t.pos++
+	return types
+}
+
+// fieldType returns the Java type of a field descriptor or signature.
+func fieldType(descriptor string) string {
+	parser := &typeParser{s: descriptor}
+	return parser.parseType()
+}
+
+type methodType struct {
+	typeParameters []string
+	parameters     []string
+	returnType     string
+	exceptions     []string
+}
+
+func parseMethodType(signature string) methodType {
+	parser := &typeParser{s: signature}
+	m := methodType{}
+	m.typeParameters = parser.parseTypeParameters()
+	m.parameters = parser.parameterTypes()
+	m.returnType = parser.parseType()
+	for parser.peek() == '^' {
+		parser.pos++
+		m.exceptions = append(m.exceptions, parser.parseType())
+	}
+	return m
+}
+
+type classType struct {
+	typeParameters []string
+	superClass     string
+	interfaces     []string
+}
+
+func parseClassType(signature string) classType {
+	parser := &typeParser{s: signature}
+	c := classType{}
+	c.typeParameters = parser.parseTypeParameters()
+	c.superClass = parser.parseType()
+	for parser.more() {
+		c.interfaces = append(c.interfaces, parser.parseType())
+	}
+	return c
+}
+
+func typeParameters(parameters []string) string {
+	if len(parameters) == 0 {
+		return ""
+	}
+	return "<" + strings.Join(parameters, ", ") + ">"
+}
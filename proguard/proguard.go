@@ -0,0 +1,371 @@
+// Package proguard reads the mapping files written by ProGuard and R8, which give the original
+// names of the obfuscated classes, fields and methods, and the original lines of the
+// obfuscated line numbers, including the frames of the inlined methods.
+//
+// A mapping is converted to a Remapper, to de-obfuscate the classes with a
+// class.RemappingVisitor, and is used by Retrace to de-obfuscate stack traces.
+// The names and types of a mapping are internal names and descriptors, such as
+// "com/example/Foo" and "(ILjava/lang/String;)V".
+package proguard
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/hierarchy"
+)
+
+// Mapping is the content of a mapping file.
+type Mapping struct {
+	// Classes are the classes of the mapping, in the order of the file.
+	Classes      []*Class
+	byOriginal   map[string]*Class
+	byObfuscated map[string]*Class
+}
+
+// Class is the mapping of a class and of its members.
+type Class struct {
+	Original   string
+	Obfuscated string
+	// SourceFile is the original source file of the class, given by the R8 metadata, or an
+	// empty string.
+	SourceFile string
+	Fields     []Field
+	Methods    []Method
+}
+
+// Field is the mapping of a field.
+type Field struct {
+	Original   string
+	Obfuscated string
+	// Descriptor is the original descriptor of the field.
+	Descriptor string
+}
+
+// Method is the mapping of a method, or of a range of its obfuscated lines.
+type Method struct {
+	Obfuscated string
+	// StartLine and EndLine are the range of obfuscated lines of the mapping, or 0 if it has
+	// no range and applies to all the lines of the method.
+	StartLine int
+	EndLine   int
+	// Frames are the original methods of the obfuscated lines, from the innermost inlined
+	// method to the outermost one, which is the method declared in the obfuscated class. A
+	// method without inlined methods has one frame.
+	Frames []Frame
+}
+
+// Frame is an original method, and the original lines of a range of obfuscated lines.
+type Frame struct {
+	// Class is the original class which declares the method.
+	Class      string
+	Name       string
+	Descriptor string
+	// StartLine and EndLine are the original lines, or 0 if they are not given.
+	StartLine int
+	EndLine   int
+}
+
+// Line returns the original line of an obfuscated line of a method mapping.
+func (f Frame) Line(m *Method, line int) int {
+	switch {
+	case f.StartLine == 0:
+		return line
+	case f.EndLine-f.StartLine == m.EndLine-m.StartLine && m.StartLine > 0:
+		return f.StartLine + line - m.StartLine
+	default:
+		return f.StartLine
+	}
+}
+
+// Class returns the mapping of a class given by its original name, or nil.
+func (m *Mapping) Class(original string) *Class {
+	return m.byOriginal[original]
+}
+
+// ObfuscatedClass returns the mapping of a class given by its obfuscated name, or nil.
+func (m *Mapping) ObfuscatedClass(obfuscated string) *Class {
+	return m.byObfuscated[obfuscated]
+}
+
+// Parse reads a ProGuard or R8 mapping file.
+func Parse(r io.Reader) (*Mapping, error) {
+	m := &Mapping{byOriginal: make(map[string]*Class), byObfuscated: make(map[string]*Class)}
+	var current *Class
+	// last is the previous method mapping, to group the inlined frames.
+	var last *Method
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "#") {
+			if current != nil {
+				current.parseMetadata(text[1:])
+			}
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			if !strings.HasSuffix(text, ":") {
+				return nil, fmt.Errorf("line %d: invalid class mapping %q", lineNumber, text)
+			}
+			original, obfuscated, ok := split(text[:len(text)-1])
+			if !ok {
+				return nil, fmt.Errorf("line %d: invalid class mapping %q", lineNumber, text)
+			}
+			current = &Class{Original: internalName(original), Obfuscated: internalName(obfuscated)}
+			m.Classes = append(m.Classes, current)
+			m.byOriginal[current.Original] = current
+			m.byObfuscated[current.Obfuscated] = current
+			last = nil
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: member mapping outside of a class", lineNumber)
+		}
+		if i := strings.Index(text, " #"); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		member, obfuscated, ok := split(text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid member mapping %q", lineNumber, text)
+		}
+		if !strings.Contains(member, "(") {
+			space := strings.LastIndexByte(member, ' ')
+			if space < 0 {
+				return nil, fmt.Errorf("line %d: invalid field mapping %q", lineNumber, text)
+			}
+			current.Fields = append(current.Fields, Field{Original: member[space+1:], Obfuscated: obfuscated,
+				Descriptor: descriptor(member[:space])})
+			last = nil
+			continue
+		}
+		method, frame, err := parseMethod(member)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		if frame.Class == "" {
+			frame.Class = current.Original
+		}
+		method.Obfuscated = obfuscated
+		// The frames of the inlined methods share the obfuscated name and lines of the
+		// outermost method, which is the last one.
+		if last != nil && last.StartLine > 0 && last.Obfuscated == method.Obfuscated &&
+			last.StartLine == method.StartLine && last.EndLine == method.EndLine {
+			last.Frames = append(last.Frames, frame)
+			continue
+		}
+		method.Frames = []Frame{frame}
+		current.Methods = append(current.Methods, method)
+		last = &current.Methods[len(current.Methods)-1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseMetadata reads the R8 metadata of a class, given as JSON in a comment.
+func (c *Class) parseMetadata(text string) {
+	var metadata struct {
+		ID       string `json:"id"`
+		FileName string `json:"fileName"`
+	}
+	if json.Unmarshal([]byte(strings.TrimSpace(text)), &metadata) == nil && metadata.ID == "sourceFile" {
+		c.SourceFile = metadata.FileName
+	}
+}
+
+// split splits "original -> obfuscated".
+func split(text string) (string, string, bool) {
+	arrow := strings.Index(text, " -> ")
+	if arrow < 0 {
+		return "", "", false
+	}
+	original, obfuscated := strings.TrimSpace(text[:arrow]), strings.TrimSpace(text[arrow+4:])
+	return original, obfuscated, original != "" && obfuscated != ""
+}
+
+// parseMethod parses "[start:end:]type [class.]name(arguments)[:originalStart[:originalEnd]]".
+func parseMethod(text string) (Method, Frame, error) {
+	var method Method
+	var frame Frame
+	lparen, rparen := strings.IndexByte(text, '('), strings.LastIndexByte(text, ')')
+	if lparen < 0 || rparen < lparen {
+		return method, frame, fmt.Errorf("invalid method mapping %q", text)
+	}
+	head, arguments, tail := text[:lparen], text[lparen+1:rparen], text[rparen+1:]
+	// The obfuscated line range precedes the return type.
+	if colon := strings.IndexByte(head, ':'); colon >= 0 {
+		parts := strings.SplitN(head, ":", 3)
+		if len(parts) != 3 {
+			return method, frame, fmt.Errorf("invalid line range in %q", text)
+		}
+		var err error
+		if method.StartLine, err = strconv.Atoi(parts[0]); err != nil {
+			return method, frame, fmt.Errorf("invalid line range in %q", text)
+		}
+		if method.EndLine, err = strconv.Atoi(parts[1]); err != nil {
+			return method, frame, fmt.Errorf("invalid line range in %q", text)
+		}
+		head = parts[2]
+	}
+	space := strings.LastIndexByte(head, ' ')
+	if space < 0 {
+		return method, frame, fmt.Errorf("invalid method mapping %q", text)
+	}
+	returnType, name := head[:space], head[space+1:]
+	if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+		frame.Class, name = internalName(name[:dot]), name[dot+1:]
+	}
+	frame.Name = name
+	var b strings.Builder
+	b.WriteByte('(')
+	if arguments != "" {
+		for _, argument := range strings.Split(arguments, ",") {
+			b.WriteString(descriptor(strings.TrimSpace(argument)))
+		}
+	}
+	b.WriteByte(')')
+	b.WriteString(descriptor(returnType))
+	frame.Descriptor = b.String()
+	if tail != "" {
+		parts := strings.Split(tail[1:], ":")
+		if tail[0] != ':' || len(parts) > 2 {
+			return method, frame, fmt.Errorf("invalid original lines in %q", text)
+		}
+		var err error
+		if frame.StartLine, err = strconv.Atoi(parts[0]); err != nil {
+			return method, frame, fmt.Errorf("invalid original lines in %q", text)
+		}
+		frame.EndLine = frame.StartLine
+		if len(parts) == 2 {
+			if frame.EndLine, err = strconv.Atoi(parts[1]); err != nil {
+				return method, frame, fmt.Errorf("invalid original lines in %q", text)
+			}
+		}
+	}
+	return method, frame, nil
+}
+
+func internalName(javaName string) string {
+	return strings.Replace(javaName, ".", "/", -1)
+}
+
+var primitiveDescriptors = map[string]string{"boolean": "Z", "byte": "B", "char": "C", "short": "S", "int": "I",
+	"long": "J", "float": "F", "double": "D", "void": "V"}
+
+// descriptor returns the descriptor of a Java type, such as "java.lang.String[]".
+func descriptor(javaType string) string {
+	dimensions := ""
+	for strings.HasSuffix(javaType, "[]") {
+		dimensions += "["
+		javaType = javaType[:len(javaType)-2]
+	}
+	if d, ok := primitiveDescriptors[javaType]; ok {
+		return dimensions + d
+	}
+	return dimensions + "L" + internalName(javaType) + ";"
+}
+
+// obfuscate returns the obfuscated name of a class, or the original name if it is not in the
+// mapping.
+func (m *Mapping) obfuscate(original string) string {
+	if c := m.byOriginal[original]; c != nil {
+		return c.Obfuscated
+	}
+	return original
+}
+
+// RenamingMap returns the mapping from the obfuscated names to the original ones, in the format
+// of class.NewSimpleRemapper: the internal names of the classes, "owner.name" for the fields,
+// and "owner.name" followed by the descriptor for the methods, with the obfuscated owners and
+// descriptors. It de-obfuscates the classes, but not the references to the members through
+// the subtypes of their declaring classes, which are renamed by a Remapper.
+func (m *Mapping) RenamingMap() map[string]string {
+	mapping := make(map[string]string)
+	for _, c := range m.Classes {
+		if c.Obfuscated != c.Original {
+			mapping[c.Obfuscated] = c.Original
+		}
+		for _, field := range c.Fields {
+			if field.Obfuscated != field.Original {
+				mapping[c.Obfuscated+"."+field.Obfuscated] = field.Original
+			}
+		}
+		for _, method := range c.Methods {
+			frame := method.Frames[len(method.Frames)-1]
+			if method.Obfuscated != frame.Name {
+				mapping[c.Obfuscated+"."+method.Obfuscated+class.RemapDescriptor(m.obfuscate, frame.Descriptor)] = frame.Name
+			}
+		}
+	}
+	return mapping
+}
+
+// Remapper is a class.Remapper which de-obfuscates classes with a mapping. The fields and
+// methods referenced through a subtype of their declaring class are resolved with the
+// hierarchy of the obfuscated classes: the owner first, then its superclasses, then its
+// interfaces.
+type Remapper struct {
+	*class.SimpleRemapper
+	hierarchy *hierarchy.Hierarchy
+}
+
+// Remapper returns a Remapper with the renaming map of the mapping. The hierarchy contains, or
+// loads, the obfuscated classes.
+func (m *Mapping) Remapper(h *hierarchy.Hierarchy) *Remapper {
+	return &Remapper{SimpleRemapper: class.NewSimpleRemapper(m.RenamingMap()), hierarchy: h}
+}
+
+func (r *Remapper) MapMethodName(owner string, name string, descriptor string) string {
+	for _, declaringClass := range r.declaringClasses(owner) {
+		if newName := r.SimpleRemapper.MapMethodName(declaringClass, name, descriptor); newName != name {
+			return newName
+		}
+	}
+	return name
+}
+
+func (r *Remapper) MapFieldName(owner string, name string, descriptor string) string {
+	for _, declaringClass := range r.declaringClasses(owner) {
+		if newName := r.SimpleRemapper.MapFieldName(declaringClass, name, descriptor); newName != name {
+			return newName
+		}
+	}
+	return name
+}
+
+// declaringClasses returns the types which may declare a member referenced through owner, in
+// the order of resolution.
+func (r *Remapper) declaringClasses(owner string) []string {
+	if owner == "" {
+		return []string{owner}
+	}
+	types := []string{owner}
+	seen := map[string]bool{owner: true}
+	for name := owner; ; {
+		superClass, ok := r.hierarchy.SuperClass(name)
+		if !ok || superClass == "" || seen[superClass] {
+			break
+		}
+		types = append(types, superClass)
+		seen[superClass] = true
+		name = superClass
+	}
+	for _, supertype := range r.hierarchy.AllSupertypes(owner) {
+		if !seen[supertype] {
+			types = append(types, supertype)
+			seen[supertype] = true
+		}
+	}
+	return types
+}
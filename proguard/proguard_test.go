@@ -0,0 +1,151 @@
+package proguard
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/hierarchy"
+	"github.com/tk103331/clazz/internal/testutil"
+	"github.com/tk103331/clazz/tools"
+)
+
+const mappingText = `# compiler: R8
+com.example.Main -> a.a:
+# {"id":"sourceFile","fileName":"Main.kt"}
+    java.lang.String name -> a
+    com.example.Util util -> b
+    1:3:void <init>() -> <init>
+    4:4:int com.example.Util.twice(int):20:20 -> a
+    4:4:void run(java.lang.String[]):10:10 -> a
+    5:8:void run(java.lang.String[]):11:14 -> a
+    void log(com.example.Util,int[]) -> b
+    9:9:void first():30:30 -> c
+    9:9:void second():40:40 -> c
+com.example.Util -> a.b:
+    int twice(int) -> a
+com.example.Kept -> com.example.Kept:
+`
+
+func parse(t *testing.T) *Mapping {
+	m, err := Parse(strings.NewReader(mappingText))
+	tools.AssertNoErr(t, err)
+	return m
+}
+
+func TestParse(t *testing.T) {
+	m := parse(t)
+	tools.AssertEqual(t, 3, len(m.Classes))
+	main := m.ObfuscatedClass("a/a")
+	tools.AssertEqual(t, main, m.Class("com/example/Main"))
+	tools.AssertEqual(t, "Main.kt", main.SourceFile)
+	tools.AssertEqual(t, Field{Original: "util", Obfuscated: "b", Descriptor: "Lcom/example/Util;"}, main.Fields[1])
+	tools.AssertEqual(t, 5, len(main.Methods))
+
+	inlined := main.Methods[1]
+	tools.AssertEqual(t, 4, inlined.StartLine)
+	tools.AssertEqual(t, 2, len(inlined.Frames))
+	tools.AssertEqual(t, Frame{Class: "com/example/Util", Name: "twice", Descriptor: "(I)I", StartLine: 20, EndLine: 20}, inlined.Frames[0])
+	tools.AssertEqual(t, Frame{Class: "com/example/Main", Name: "run", Descriptor: "([Ljava/lang/String;)V", StartLine: 10, EndLine: 10}, inlined.Frames[1])
+	tools.AssertEqual(t, 13, main.Methods[2].Frames[0].Line(&main.Methods[2], 7))
+	tools.AssertEqual(t, "(Lcom/example/Util;[I)V", main.Methods[3].Frames[0].Descriptor)
+	tools.AssertEqual(t, 0, main.Methods[3].StartLine)
+
+	_, err := Parse(strings.NewReader("    int a -> b\n"))
+	tools.AssertEqual(t, "line 1: member mapping outside of a class", err.Error())
+	_, err = Parse(strings.NewReader("a.A -> b:\n    1:x:void m() -> a\n"))
+	tools.AssertEqual(t, "line 2: invalid line range in \"1:x:void m()\"", err.Error())
+}
+
+func TestRenamingMap(t *testing.T) {
+	remapper := class.NewSimpleRemapper(parse(t).RenamingMap())
+	tools.AssertEqual(t, "com/example/Main", remapper.MapType("a/a"))
+	tools.AssertEqual(t, "com/example/Kept", remapper.MapType("com/example/Kept"))
+	tools.AssertEqual(t, "util", remapper.MapFieldName("a/a", "b", "La/b;"))
+	tools.AssertEqual(t, "run", remapper.MapMethodName("a/a", "a", "([Ljava/lang/String;)V"))
+	tools.AssertEqual(t, "log", remapper.MapMethodName("a/a", "b", "(La/b;[I)V"))
+	tools.AssertEqual(t, "twice", remapper.MapMethodName("a/b", "a", "(I)I"))
+	tools.AssertEqual(t, "(Lcom/example/Util;)V", remapper.MapDescriptor("(La/b;)V"))
+}
+
+func TestRemapper(t *testing.T) {
+	m, err := Parse(strings.NewReader(`com.example.Base -> a.c:
+    int count -> b
+    void run() -> a
+com.example.Named -> a.e:
+    java.lang.String name() -> c
+com.example.Sub -> a.d:
+`))
+	tools.AssertNoErr(t, err)
+	h := hierarchy.New()
+	var sub *class.Class
+	for _, source := range []string{
+		".class public super a/c\n.super java/lang/Object\n",
+		".class public interface abstract a/e\n.super java/lang/Object\n",
+		`.class public super a/d
+.super a/c
+.implements a/e
+.method public m ()V
+    aload_0
+    invokevirtual a/d a ()V
+    aload_0
+    getfield a/d b I
+    pop
+    aload_0
+    invokeinterface a/d c ()Ljava/lang/String;
+    pop
+    return
+.end method
+`,
+	} {
+		reader := class.NewReader(bytes.NewReader(testutil.Assemble(t, source)))
+		tools.AssertNoErr(t, reader.Read())
+		sub = reader.Class()
+		h.Add(sub)
+	}
+
+	builder := class.NewBuilder()
+	sub.Accept(class.NewRemappingVisitor(builder, m.Remapper(h)))
+	c := builder.Class()
+	tools.AssertEqual(t, "com/example/Sub", c.ThisClass)
+	tools.AssertEqual(t, "com/example/Base", c.SuperClass)
+	var names []string
+	for _, instruction := range c.Methods[0].Code.Instructions {
+		switch instruction := instruction.(type) {
+		case *class.MethodInstruction:
+			names = append(names, instruction.Owner+"."+instruction.Name)
+		case *class.FieldInstruction:
+			names = append(names, instruction.Owner+"."+instruction.Name)
+		}
+	}
+	tools.AssertEqual(t, "[com/example/Sub.run com/example/Sub.count com/example/Sub.name]", fmt.Sprint(names))
+}
+
+func TestRetrace(t *testing.T) {
+	trace := `Exception in thread "main" a.b: boom
+	at a.b.a(SourceFile:1)
+	at a.a.a(SourceFile:4)
+	at a.a.a(SourceFile:6)
+	at a.a.b(Unknown Source)
+	at a.a.c(SourceFile)
+	at app//a.b.a(SourceFile:1) ~[app.jar:1.0]
+	at java.base/java.lang.Thread.run(Thread.java:833)
+Caused by: a.a
+`
+	var b bytes.Buffer
+	tools.AssertNoErr(t, parse(t).Retrace(strings.NewReader(trace), &b))
+	tools.AssertEqual(t, `Exception in thread "main" com.example.Util: boom
+	at com.example.Util.twice(Util.java:1)
+	at com.example.Util.twice(Util.java:20)
+	at com.example.Main.run(Main.kt:10)
+	at com.example.Main.run(Main.kt:12)
+	at com.example.Main.log(Unknown Source)
+	at com.example.Main.first(Main.kt)
+	at com.example.Main.second(Main.kt)
+	at app//com.example.Util.twice(Util.java:1) ~[app.jar:1.0]
+	at java.base/java.lang.Thread.run(Thread.java:833)
+Caused by: com.example.Main
+`, b.String())
+}
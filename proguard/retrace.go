@@ -0,0 +1,130 @@
+package proguard
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/stacktrace"
+)
+
+// exceptionRegexp matches the first line of an exception, such as "Caused by: a.b: message".
+var exceptionRegexp = regexp.MustCompile(`^(\s*(?:Caused by: |Suppressed: |Exception in thread "[^"]*" )?)([\w$.]+)(:.*)?$`)
+
+// Retrace rewrites the obfuscated class names and frames of a Java stack trace with their
+// original names and lines. An obfuscated frame is replaced by the frames of the original
+// methods, the inlined ones first. When its line does not identify the original method, the
+// frames of the other candidates follow, prefixed with "<OR> ". The other lines are copied.
+func (m *Mapping) Retrace(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	writer := bufio.NewWriter(w)
+	for scanner.Scan() {
+		for _, line := range m.RetraceLine(scanner.Text()) {
+			writer.WriteString(line)
+			writer.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// RetraceLine rewrites a line of a stack trace, and returns the resulting lines.
+func (m *Mapping) RetraceLine(line string) []string {
+	if prefix, frame, suffix, ok := stacktrace.SplitFrame(line); ok {
+		return m.retraceFrame(line, prefix, frame, suffix)
+	}
+	if match := exceptionRegexp.FindStringSubmatch(line); match != nil {
+		if c := m.byObfuscated[internalName(match[2])]; c != nil {
+			return []string{match[1] + javaName(c.Original) + match[3]}
+		}
+	}
+	return []string{line}
+}
+
+func (m *Mapping) retraceFrame(text string, prefix string, frame stacktrace.Frame, suffix string) []string {
+	c := m.byObfuscated[frame.Class]
+	if c == nil {
+		return []string{text}
+	}
+	module, methodName, location, line := frame.Module, frame.Method, frame.Location(), frame.Line
+	// The candidates are the method mappings whose range contains the line, or which have no
+	// range, or all the mappings of the method if none matches.
+	var candidates []*Method
+	for i := range c.Methods {
+		method := &c.Methods[i]
+		if method.Obfuscated == methodName && line > 0 && method.StartLine <= line && line <= method.EndLine {
+			candidates = append(candidates, method)
+		}
+	}
+	if len(candidates) == 0 {
+		for i := range c.Methods {
+			if c.Methods[i].Obfuscated == methodName && (line == 0 || c.Methods[i].StartLine == 0) {
+				candidates = append(candidates, &c.Methods[i])
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		for i := range c.Methods {
+			if c.Methods[i].Obfuscated == methodName {
+				candidates = append(candidates, &c.Methods[i])
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return []string{prefix + module + javaName(c.Original) + "." + methodName + "(" + m.location(c.Original, location, line, 0) + ")" + suffix}
+	}
+	var lines []string
+	seen := make(map[string]bool)
+	for i, method := range candidates {
+		for _, frame := range method.Frames {
+			frameLine := 0
+			if line > 0 {
+				frameLine = frame.Line(method, line)
+			}
+			text := prefix + module + javaName(frame.Class) + "." + frame.Name +
+				"(" + m.location(frame.Class, location, line, frameLine) + ")" + suffix
+			if seen[text] {
+				continue
+			}
+			seen[text] = true
+			if i > 0 {
+				indent := len(prefix) - len(strings.TrimLeft(prefix, " \t"))
+				text = text[:indent] + "<OR> " + text[indent:]
+			}
+			lines = append(lines, text)
+		}
+	}
+	return lines
+}
+
+// location returns the original location of a frame, with the source file of its class and
+// its original line.
+func (m *Mapping) location(original string, location string, line int, originalLine int) string {
+	if location == "Native Method" || location == "Unknown Source" && line == 0 {
+		return location
+	}
+	sourceFile := ""
+	if c := m.byOriginal[original]; c != nil {
+		sourceFile = c.SourceFile
+	}
+	if sourceFile == "" {
+		name := original[strings.LastIndexByte(original, '/')+1:]
+		if dollar := strings.IndexByte(name, '$'); dollar > 0 {
+			name = name[:dollar]
+		}
+		sourceFile = name + ".java"
+	}
+	if originalLine > 0 {
+		return sourceFile + ":" + strconv.Itoa(originalLine)
+	}
+	return sourceFile
+}
+
+func javaName(internalName string) string {
+	return strings.Replace(internalName, "/", ".", -1)
+}
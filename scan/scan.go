@@ -0,0 +1,366 @@
+// Package scan indexes the annotations of the classes of large classpaths with a pool of
+// goroutines, and finds the classes, fields and methods which carry an annotation: directly,
+// through meta-annotations, or inherited from their superclasses.
+//
+// The classes are read with class.Reader.ReadAnnotations, which only resolves their names and
+// annotations. An annotation is inherited when its type is annotated with
+// java.lang.annotation.Inherited, and is in the classpath.
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/classpath"
+)
+
+const inheritedDescriptor = "Ljava/lang/annotation/Inherited;"
+
+// Kind is the kind of an annotated element.
+type Kind int
+
+const (
+	Class Kind = iota
+	Field
+	Method
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Class:
+		return "class"
+	case Field:
+		return "field"
+	default:
+		return "method"
+	}
+}
+
+// Options configure the scan.
+type Options struct {
+	// Workers is the number of goroutines which read the classes. By default, the number of
+	// CPUs.
+	Workers int
+}
+
+// Index is the index of the annotations of the classes of a classpath.
+type Index struct {
+	classes map[string]*class.Class
+}
+
+// Scan reads the annotations of all the classes of a classpath concurrently. When several
+// sources contain a class, the first one wins. It stops at the first error, or when the context
+// is done.
+func Scan(ctx context.Context, cp *classpath.Classpath, options Options) (*Index, error) {
+	type job struct {
+		source classpath.Source
+		name   string
+	}
+	var jobs []job
+	seen := make(map[string]bool)
+	for _, source := range cp.Sources() {
+		names, err := source.ClassNames()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if name != "module-info" && !seen[name] {
+				seen[name] = true
+				jobs = append(jobs, job{source, name})
+			}
+		}
+	}
+
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	index := &Index{classes: make(map[string]*class.Class, len(jobs))}
+	var mutex sync.Mutex
+	var firstErr error
+	queue := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				c, err := read(j.source, j.name)
+				mutex.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					cancel()
+				} else {
+					index.classes[j.name] = c
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, j := range jobs {
+		select {
+		case queue <- j:
+		case <-workerCtx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func read(source classpath.Source, name string) (*class.Class, error) {
+	content, err := source.ReadClass(name)
+	if err != nil {
+		return nil, err
+	}
+	reader := class.NewReader(bytes.NewReader(content))
+	if err := reader.ReadAnnotations(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return reader.Class(), nil
+}
+
+// Class returns a class of the index, with only its names and annotations, or nil.
+func (i *Index) Class(internalName string) *class.Class {
+	return i.classes[internalName]
+}
+
+// ClassNames returns the internal names of the classes of the index, sorted.
+func (i *Index) ClassNames() []string {
+	names := make([]string, 0, len(i.classes))
+	for name := range i.classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Match is an element which carries a searched annotation.
+type Match struct {
+	Kind Kind
+	// Class is the internal name of the class of the element.
+	Class string
+	// Name and Descriptor are the name and descriptor of the field or method, or empty strings.
+	Name       string
+	Descriptor string
+	// Annotation is the annotation of the element: the searched one, or one which is
+	// meta-annotated with it.
+	Annotation class.Annotation
+	// Via are the internal names of the annotation types between the annotation of the element
+	// and the searched annotation, for a meta-annotation.
+	Via []string
+	// InheritedFrom is the superclass which declares the annotation, for an inherited
+	// annotation.
+	InheritedFrom string
+	// Values are the element values of the searched annotation, including the default values
+	// of its type if it is in the index.
+	Values map[string]class.ElementValue
+}
+
+// String returns the kind and name of the element, and how the annotation was found.
+func (m Match) String() string {
+	var b strings.Builder
+	b.WriteString(m.Kind.String() + " " + m.Class)
+	switch m.Kind {
+	case Field:
+		b.WriteString("." + m.Name + ":" + m.Descriptor)
+	case Method:
+		b.WriteString("." + m.Name + m.Descriptor)
+	}
+	if len(m.Via) > 0 {
+		b.WriteString(" via " + strings.Join(m.Via, " "))
+	}
+	if m.InheritedFrom != "" {
+		b.WriteString(" inherited from " + m.InheritedFrom)
+	}
+	return b.String()
+}
+
+// Find returns the classes, fields and methods which carry an annotation, given by its
+// internal name, sorted by class, kind, name and descriptor.
+func (i *Index) Find(annotation string) []Match {
+	f := &finder{index: i, target: "L" + annotation + ";", paths: make(map[string][]*class.Annotation),
+		searching: make(map[string]bool)}
+	var matches []Match
+	for _, name := range i.ClassNames() {
+		c := i.classes[name]
+		classMatches := f.matches(Match{Kind: Class, Class: name}, c.RuntimeVisibleAnnotations, c.RuntimeInvisibleAnnotations)
+		if len(classMatches) == 0 {
+			classMatches = f.inherited(c)
+		}
+		matches = append(matches, classMatches...)
+		for _, field := range c.Fields {
+			matches = append(matches, f.matches(Match{Kind: Field, Class: name, Name: field.Name, Descriptor: field.Descriptor},
+				field.RuntimeVisibleAnnotations, field.RuntimeInvisibleAnnotations)...)
+		}
+		for _, method := range c.Methods {
+			matches = append(matches, f.matches(Match{Kind: Method, Class: name, Name: method.Name, Descriptor: method.Descriptor},
+				method.RuntimeVisibleAnnotations, method.RuntimeInvisibleAnnotations)...)
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		x, y := matches[a], matches[b]
+		if x.Class != y.Class {
+			return x.Class < y.Class
+		}
+		if x.Kind != y.Kind {
+			return x.Kind < y.Kind
+		}
+		if x.Name != y.Name {
+			return x.Name < y.Name
+		}
+		return x.Descriptor < y.Descriptor
+	})
+	return matches
+}
+
+type finder struct {
+	index *Index
+	// target is the descriptor of the searched annotation.
+	target string
+	// paths are the meta-annotation paths of the annotation types, by descriptor: the
+	// annotations from one on the annotation type to the searched one, nil if it is not
+	// meta-annotated with it.
+	paths map[string][]*class.Annotation
+	// searching are the annotation types whose path is being searched, for the cycles such as
+	// java.lang.annotation.Documented.
+	searching map[string]bool
+}
+
+// path returns the meta-annotations which lead from an annotation type to the searched
+// annotation, or nil.
+func (f *finder) path(descriptor string) []*class.Annotation {
+	path, _ := f.search(descriptor)
+	return path
+}
+
+// search returns the path of an annotation type, and false if it found none only because of a
+// type whose path is being searched. Such a result is not cached, since the path may go
+// through this type once its search is complete.
+func (f *finder) search(descriptor string) ([]*class.Annotation, bool) {
+	if path, ok := f.paths[descriptor]; ok {
+		return path, true
+	}
+	if f.searching[descriptor] {
+		return nil, false
+	}
+	c := f.index.classes[class.NewType(descriptor).InternalName()]
+	if c == nil {
+		f.paths[descriptor] = nil
+		return nil, true
+	}
+	f.searching[descriptor] = true
+	defer delete(f.searching, descriptor)
+	complete := true
+	for _, annotations := range [][]class.Annotation{c.RuntimeVisibleAnnotations, c.RuntimeInvisibleAnnotations} {
+		for i := range annotations {
+			annotation := &annotations[i]
+			if annotation.Descriptor == f.target {
+				f.paths[descriptor] = []*class.Annotation{annotation}
+				return f.paths[descriptor], true
+			}
+			path, ok := f.search(annotation.Descriptor)
+			if path != nil {
+				f.paths[descriptor] = append([]*class.Annotation{annotation}, path...)
+				return f.paths[descriptor], true
+			}
+			complete = complete && ok
+		}
+	}
+	if complete {
+		f.paths[descriptor] = nil
+	}
+	return nil, complete
+}
+
+// matches returns the matches of an element with the given annotations.
+func (f *finder) matches(element Match, annotations ...[]class.Annotation) []Match {
+	var matches []Match
+	for _, list := range annotations {
+		for _, annotation := range list {
+			match := element
+			match.Annotation = annotation
+			if annotation.Descriptor == f.target {
+				match.Values = f.values(&annotation)
+			} else if path := f.path(annotation.Descriptor); path != nil {
+				match.Via = []string{class.NewType(annotation.Descriptor).InternalName()}
+				for _, meta := range path[:len(path)-1] {
+					match.Via = append(match.Via, class.NewType(meta.Descriptor).InternalName())
+				}
+				match.Values = f.values(path[len(path)-1])
+			} else {
+				continue
+			}
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// inherited returns the matches of the inherited annotations of the first superclass which has
+// some.
+func (f *finder) inherited(c *class.Class) []Match {
+	for superClass := f.index.classes[c.SuperClass]; superClass != nil; superClass = f.index.classes[superClass.SuperClass] {
+		var matches []Match
+		for _, match := range f.matches(Match{Kind: Class, Class: c.ThisClass},
+			superClass.RuntimeVisibleAnnotations, superClass.RuntimeInvisibleAnnotations) {
+			if f.isInherited(match.Annotation.Descriptor) {
+				match.InheritedFrom = superClass.ThisClass
+				matches = append(matches, match)
+			}
+		}
+		if len(matches) > 0 {
+			return matches
+		}
+	}
+	return nil
+}
+
+func (f *finder) isInherited(descriptor string) bool {
+	c := f.index.classes[class.NewType(descriptor).InternalName()]
+	if c == nil {
+		return false
+	}
+	for _, annotations := range [][]class.Annotation{c.RuntimeVisibleAnnotations, c.RuntimeInvisibleAnnotations} {
+		for _, annotation := range annotations {
+			if annotation.Descriptor == inheritedDescriptor {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// values returns the element values of an annotation, with the default values of its type.
+func (f *finder) values(annotation *class.Annotation) map[string]class.ElementValue {
+	values := make(map[string]class.ElementValue)
+	if c := f.index.classes[class.NewType(annotation.Descriptor).InternalName()]; c != nil {
+		for _, method := range c.Methods {
+			if method.AnnotationDefault != nil {
+				values[method.Name] = method.AnnotationDefault
+			}
+		}
+	}
+	for _, pair := range annotation.ElementPairs {
+		values[pair.Name] = pair.Value
+	}
+	return values
+}
@@ -0,0 +1,109 @@
+package scan
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/internal/testutil"
+	"github.com/tk103331/clazz/tools"
+)
+
+var sources = map[string]string{
+	"a/Component": `.class public interface abstract annotation a/Component
+.super java/lang/Object
+.implements java/lang/annotation/Annotation
+.method public abstract value ()Ljava/lang/String;
+    .annotationdefault s "default"
+.end method
+`,
+	"a/Service": `.class public interface abstract annotation a/Service
+.super java/lang/Object
+.implements java/lang/annotation/Annotation
+.annotation visible Ljava/lang/annotation/Inherited;
+.end annotation
+.annotation visible La/Component;
+    value = s "service"
+.end annotation
+`,
+	"a/Base": `.class public super a/Base
+.super java/lang/Object
+.annotation visible La/Service;
+.end annotation
+`,
+	"a/Impl": `.class public super a/Impl
+.super a/Base
+`,
+	"a/Controller": `.class public super a/Controller
+.super java/lang/Object
+.field private service La/Base;
+    .annotation visible La/Component;
+        value = s "field"
+    .end annotation
+.end field
+.method public handle ()V
+    .annotation invisible La/Component;
+    .end annotation
+    return
+.end method
+`,
+}
+
+func TestFind(t *testing.T) {
+	index, err := Scan(context.Background(), testutil.Classpath(t, sources), Options{Workers: 2})
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, 5, len(index.ClassNames()))
+
+	matches := index.Find("a/Component")
+	tools.AssertEqual(t, 5, len(matches))
+	tools.AssertEqual(t, "class a/Base via a/Service", matches[0].String())
+	tools.AssertEqual(t, class.ElementStringValue{Value: "service"}, matches[0].Values["value"])
+	tools.AssertEqual(t, "field a/Controller.service:La/Base;", matches[1].String())
+	tools.AssertEqual(t, class.ElementStringValue{Value: "field"}, matches[1].Values["value"])
+	tools.AssertEqual(t, "method a/Controller.handle()V", matches[2].String())
+	tools.AssertEqual(t, class.ElementStringValue{Value: "default"}, matches[2].Values["value"])
+	tools.AssertEqual(t, "class a/Impl via a/Service inherited from a/Base", matches[3].String())
+	tools.AssertEqual(t, "class a/Service", matches[4].String())
+
+	matches = index.Find("a/Service")
+	tools.AssertEqual(t, 2, len(matches))
+	tools.AssertEqual(t, "class a/Impl inherited from a/Base", matches[1].String())
+}
+
+func TestFindCycle(t *testing.T) {
+	annotationType := func(name string, annotations ...string) string {
+		text := ".class public interface abstract annotation " + name + "\n.super java/lang/Object\n"
+		for _, annotation := range annotations {
+			text += ".annotation visible L" + annotation + ";\n.end annotation\n"
+		}
+		return text
+	}
+	cp := testutil.Classpath(t, map[string]string{
+		"z/A": annotationType("z/A", "z/B", "z/C"),
+		"z/B": annotationType("z/B", "z/A"),
+		"z/C": annotationType("z/C", "z/T"),
+		"z/T": annotationType("z/T"),
+		"a/X": ".class public super a/X\n.super java/lang/Object\n.annotation visible Lz/A;\n.end annotation\n",
+		"a/Y": ".class public super a/Y\n.super java/lang/Object\n.annotation visible Lz/B;\n.end annotation\n",
+	})
+	index, err := Scan(context.Background(), cp, Options{Workers: 2})
+	tools.AssertNoErr(t, err)
+	var text []string
+	for _, match := range index.Find("z/T") {
+		text = append(text, match.String())
+	}
+	tools.AssertEqual(t, "class a/X via z/A z/C\n"+
+		"class a/Y via z/B z/A z/C\n"+
+		"class z/A via z/B z/A z/C\n"+
+		"class z/A via z/C\n"+
+		"class z/B via z/A z/C\n"+
+		"class z/C", strings.Join(text, "\n"))
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Scan(ctx, testutil.Classpath(t, sources), Options{})
+	tools.AssertEqual(t, context.Canceled, err)
+}
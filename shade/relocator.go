@@ -0,0 +1,198 @@
+// Package shade relocates the packages of the classes and resources of JAR files, so that a
+// copy of a library can be embedded in an application without conflicting with other copies.
+package shade
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/tk103331/clazz/class"
+)
+
+// Relocation moves the classes of a package and of its subpackages to another package. The
+// packages are designated by their dotted names, for example "com.google.common". Excludes are
+// class names which are not relocated. An exclude can contain "*", which matches a part of a
+// simple name, or end with ".**", which matches all the classes of a package and its
+// subpackages.
+type Relocation struct {
+	Pattern       string
+	ShadedPattern string
+	Excludes      []string
+}
+
+type relocation struct {
+	pattern       string
+	shadedPattern string
+	excludes      []string
+}
+
+// Relocator is a class.StringRemapper which applies a list of relocations. The first
+// relocation matching a name is applied.
+type Relocator struct {
+	relocations []relocation
+}
+
+func NewRelocator(relocations ...Relocation) *Relocator {
+	r := &Relocator{}
+	for _, rel := range relocations {
+		excludes := make([]string, len(rel.Excludes))
+		for i, exclude := range rel.Excludes {
+			excludes[i] = strings.ReplaceAll(exclude, ".", "/")
+		}
+		r.relocations = append(r.relocations, relocation{
+			pattern:       strings.ReplaceAll(rel.Pattern, ".", "/") + "/",
+			shadedPattern: strings.ReplaceAll(rel.ShadedPattern, ".", "/") + "/",
+			excludes:      excludes,
+		})
+	}
+	return r
+}
+
+func (r *relocation) excluded(name string) bool {
+	for _, exclude := range r.excludes {
+		if strings.HasSuffix(exclude, "/**") {
+			if strings.HasPrefix(name, strings.TrimSuffix(exclude, "**")) {
+				return true
+			}
+		} else if matched, _ := path.Match(exclude, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// relocate relocates a name using '/' as separator. It returns false if no relocation
+// applies.
+func (r *Relocator) relocate(name string) (string, bool) {
+	for _, rel := range r.relocations {
+		if strings.HasPrefix(name, rel.pattern) && !rel.excluded(strings.TrimSuffix(name, ".class")) {
+			return rel.shadedPattern + name[len(rel.pattern):], true
+		}
+	}
+	return name, false
+}
+
+func (r *Relocator) MapType(internalName string) string {
+	if strings.HasPrefix(internalName, "[") {
+		return r.MapDescriptor(internalName)
+	}
+	newName, _ := r.relocate(internalName)
+	return newName
+}
+
+func (r *Relocator) MapMethodName(owner string, name string, descriptor string) string {
+	return name
+}
+
+func (r *Relocator) MapFieldName(owner string, name string, descriptor string) string {
+	return name
+}
+
+func (r *Relocator) MapDescriptor(descriptor string) string {
+	return class.RemapDescriptor(r.MapType, descriptor)
+}
+
+func (r *Relocator) MapSignature(signature string) string {
+	return class.RemapSignature(r.MapType, signature)
+}
+
+// MapString relocates a string constant which looks like a class name, in dotted or internal
+// form, or like a resource path, with an optional leading '/'.
+func (r *Relocator) MapString(value string) string {
+	if strings.IndexFunc(value, unicode.IsSpace) >= 0 {
+		return value
+	}
+	prefix := ""
+	name := value
+	if strings.HasPrefix(name, "/") {
+		prefix = "/"
+		name = name[1:]
+	}
+	if strings.Contains(name, "/") {
+		newName, _ := r.relocate(name)
+		return prefix + newName
+	}
+	if prefix != "" || strings.IndexFunc(name, isNotClassNameChar) >= 0 {
+		return value
+	}
+	newName, ok := r.relocate(strings.ReplaceAll(name, ".", "/"))
+	if !ok {
+		return value
+	}
+	return strings.ReplaceAll(newName, "/", ".")
+}
+
+func isNotClassNameChar(c rune) bool {
+	return c != '.' && c != '$' && c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c)
+}
+
+// RelocatePath relocates the path of a JAR entry. The "META-INF/versions/N/" prefix of the
+// entries of multi-release JARs is kept.
+func (r *Relocator) RelocatePath(entryPath string) string {
+	prefix := ""
+	if strings.HasPrefix(entryPath, versionsDirectory) {
+		if i := strings.Index(entryPath[len(versionsDirectory):], "/"); i >= 0 {
+			prefix = entryPath[:len(versionsDirectory)+i+1]
+		}
+	}
+	newPath, _ := r.relocate(entryPath[len(prefix):])
+	return prefix + newPath
+}
+
+// RelocateClass relocates the classes referenced by the given class bytes. The constant pool is
+// rebuilt, so it returns an error if the class has a non empty attribute unknown to the reader,
+// whose constant pool indexes could not be kept valid.
+func (r *Relocator) RelocateClass(content []byte) ([]byte, error) {
+	reader := class.NewReader(bytes.NewReader(content))
+	if err := reader.Read(); err != nil {
+		return nil, err
+	}
+	if name := unknownAttribute(reader.Class()); name != "" {
+		return nil, fmt.Errorf("cannot relocate class %s: unsupported attribute %s", reader.Class().ThisClass, name)
+	}
+	writer := class.NewWriter()
+	reader.Accept(class.NewRemappingVisitor(writer, r))
+	return writer.Bytes()
+}
+
+// unknownAttribute returns the name of the first non empty attribute of a class, record
+// component, field, method or code which is kept as is by the reader, or "" if there is none.
+func unknownAttribute(c *class.Class) string {
+	attributes := [][]class.Attribute{c.Attributes}
+	for _, component := range c.RecordComponents {
+		attributes = append(attributes, component.Attributes)
+	}
+	for _, field := range c.Fields {
+		attributes = append(attributes, field.Attributes)
+	}
+	for _, method := range c.Methods {
+		attributes = append(attributes, method.Attributes, method.Code.Attributes)
+	}
+	for _, list := range attributes {
+		for _, attribute := range list {
+			if len(attribute.Content) > 0 {
+				return attribute.Name
+			}
+		}
+	}
+	return ""
+}
+
+// relocateServices relocates the provider class names of a META-INF/services file.
+func (r *Relocator) relocateServices(content []byte) []byte {
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, line := range lines {
+		end := len(line)
+		if j := strings.IndexByte(line, '#'); j >= 0 {
+			end = j
+		}
+		name := strings.TrimSpace(line[:end])
+		if len(name) > 0 {
+			lines[i] = strings.Replace(line, name, r.MapString(name), 1)
+		}
+	}
+	return []byte(strings.Join(lines, ""))
+}
@@ -0,0 +1,102 @@
+package shade
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	versionsDirectory = "META-INF/versions/"
+	servicesDirectory = "META-INF/services/"
+)
+
+// Shade copies the JAR read by reader to writer, relocating its classes, the classes they
+// reference, its resources and its service provider files.
+// The signature files are not copied, since they do not match the relocated classes.
+func (r *Relocator) Shade(reader *zip.Reader, writer io.Writer) error {
+	zipWriter := zip.NewWriter(writer)
+	written := make(map[string]bool)
+	for _, file := range reader.File {
+		if isSignatureFile(file.Name) {
+			continue
+		}
+		content, err := readFile(file)
+		if err != nil {
+			return err
+		}
+		name := file.Name
+		switch {
+		case strings.HasSuffix(name, ".class") && !file.FileInfo().IsDir():
+			content, err = r.RelocateClass(content)
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			name = r.RelocatePath(name)
+		case strings.HasPrefix(name, servicesDirectory) && len(name) > len(servicesDirectory):
+			name = servicesDirectory + r.MapString(name[len(servicesDirectory):])
+			content = r.relocateServices(content)
+		default:
+			name = r.RelocatePath(name)
+		}
+		// The first entry wins when several entries are relocated to the same path.
+		if written[name] {
+			continue
+		}
+		written[name] = true
+
+		header := file.FileHeader
+		header.Name = name
+		entry, err := zipWriter.CreateHeader(&header)
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(content); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// ShadeFile relocates the JAR file input with the given relocations, and writes the result to
+// the JAR file output.
+func ShadeFile(input string, output string, relocations ...Relocation) error {
+	reader, err := zip.OpenReader(input)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := NewRelocator(relocations...).Shade(&reader.Reader, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+func isSignatureFile(name string) bool {
+	if !strings.HasPrefix(name, "META-INF/") || strings.Count(name, "/") != 1 {
+		return false
+	}
+	for _, suffix := range []string{".SF", ".DSA", ".RSA", ".EC"} {
+		if strings.HasSuffix(strings.ToUpper(name), suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package shade
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/tools"
+)
+
+func newTestClass(t *testing.T, name string) []byte {
+	writer := class.NewWriter()
+	writer.Visit(52, data.ACC_PUBLIC, name, "", "java/lang/Object", nil)
+	writer.VisitField(data.ACC_PRIVATE, "foo", "Lcom/google/common/Foo;", "", nil).VisitEnd()
+	method := writer.VisitMethod(data.ACC_PUBLIC|data.ACC_STATIC, "run", "()Ljava/lang/Object;", "", nil)
+	method.VisitCode()
+	method.VisitLdcInstruction("com.google.common.Foo")
+	method.VisitMethodInstruction(data.INVOKESTATIC, "com/google/common/Foo", "of", "(Ljava/lang/String;)Lcom/google/common/Foo;", false)
+	method.VisitInstruction(data.ARETURN)
+	method.VisitMaxs(1, 0)
+	method.VisitEnd()
+	writer.VisitEnd()
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+	return content
+}
+
+func TestRelocator(t *testing.T) {
+	relocator := NewRelocator(Relocation{
+		Pattern:       "com.google.common",
+		ShadedPattern: "myapp.shaded.guava",
+		Excludes:      []string{"com.google.common.Keep*", "com.google.common.internal.**"},
+	})
+	tools.AssertEqual(t, "myapp/shaded/guava/Foo", relocator.MapType("com/google/common/Foo"))
+	tools.AssertEqual(t, "com/google/common/KeepMe", relocator.MapType("com/google/common/KeepMe"))
+	tools.AssertEqual(t, "com/google/common/internal/a/B", relocator.MapType("com/google/common/internal/a/B"))
+	tools.AssertEqual(t, "com/google/commons/Foo", relocator.MapType("com/google/commons/Foo"))
+	tools.AssertEqual(t, "[Lmyapp/shaded/guava/Foo;", relocator.MapType("[Lcom/google/common/Foo;"))
+	tools.AssertEqual(t, "myapp.shaded.guava.base.Foo$1", relocator.MapString("com.google.common.base.Foo$1"))
+	tools.AssertEqual(t, "/myapp/shaded/guava/data.txt", relocator.MapString("/com/google/common/data.txt"))
+	tools.AssertEqual(t, "com.google.common is great", relocator.MapString("com.google.common is great"))
+	tools.AssertEqual(t, "META-INF/versions/11/myapp/shaded/guava/Foo.class", relocator.RelocatePath("META-INF/versions/11/com/google/common/Foo.class"))
+}
+
+func TestShade(t *testing.T) {
+	var input bytes.Buffer
+	zipWriter := zip.NewWriter(&input)
+	entries := map[string][]byte{
+		"a/Main.class":                           newTestClass(t, "a/Main"),
+		"com/google/common/Foo.class":            newTestClass(t, "com/google/common/Foo"),
+		"com/google/common/data.txt":             []byte("data"),
+		"META-INF/services/com.google.common.Fn": []byte("# providers\ncom.google.common.Foo # default\n"),
+		"META-INF/SIGNER.SF":                     []byte("signature"),
+	}
+	for name, content := range entries {
+		w, err := zipWriter.Create(name)
+		tools.AssertNoErr(t, err)
+		w.Write(content)
+	}
+	tools.AssertNoErr(t, zipWriter.Close())
+	reader, err := zip.NewReader(bytes.NewReader(input.Bytes()), int64(input.Len()))
+	tools.AssertNoErr(t, err)
+
+	var output bytes.Buffer
+	relocator := NewRelocator(Relocation{Pattern: "com.google.common", ShadedPattern: "myapp.shaded.guava"})
+	tools.AssertNoErr(t, relocator.Shade(reader, &output))
+	shaded, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	tools.AssertNoErr(t, err)
+
+	files := make(map[string][]byte)
+	for _, file := range shaded.File {
+		files[file.Name], err = readFile(file)
+		tools.AssertNoErr(t, err)
+	}
+	tools.AssertEqual(t, 4, len(files))
+	tools.AssertEqual(t, "data", string(files["myapp/shaded/guava/data.txt"]))
+	tools.AssertEqual(t, "# providers\nmyapp.shaded.guava.Foo # default\n", string(files["META-INF/services/myapp.shaded.guava.Fn"]))
+
+	classReader := class.NewReader(bytes.NewReader(files["myapp/shaded/guava/Foo.class"]))
+	tools.AssertNoErr(t, classReader.Read())
+	tools.AssertEqual(t, "myapp/shaded/guava/Foo", classReader.Class().ThisClass)
+
+	classReader = class.NewReader(bytes.NewReader(files["a/Main.class"]))
+	tools.AssertNoErr(t, classReader.Read())
+	main := classReader.Class()
+	tools.AssertEqual(t, "Lmyapp/shaded/guava/Foo;", main.Fields[0].Descriptor)
+	for _, instruction := range main.Methods[0].Code.Instructions {
+		switch insn := instruction.(type) {
+		case *class.LdcInstruction:
+			tools.AssertEqual(t, "myapp.shaded.guava.Foo", insn.Value)
+		case *class.MethodInstruction:
+			tools.AssertEqual(t, "myapp/shaded/guava/Foo", insn.Owner)
+			tools.AssertEqual(t, "(Ljava/lang/String;)Lmyapp/shaded/guava/Foo;", insn.Descriptor)
+		}
+	}
+}
+
+func TestRelocateClassAttributes(t *testing.T) {
+	relocator := NewRelocator(Relocation{Pattern: "com.google.common", ShadedPattern: "myapp.shaded.guava"})
+	writer := class.NewWriter()
+	writer.Visit(52, data.ACC_PUBLIC, "a/Main", "", "java/lang/Object", nil)
+	field := writer.VisitField(data.ACC_PRIVATE, "foo", "Ljava/lang/Object;", "", nil)
+	field.VisitTypeAnnotation(class.NewTypeReference(data.TYPE_REF_FIELD), nil, "Lcom/google/common/NonNull;", true).VisitEnd()
+	field.VisitEnd()
+	writer.VisitEnd()
+	content, err := writer.Bytes()
+	tools.AssertNoErr(t, err)
+	content, err = relocator.RelocateClass(content)
+	tools.AssertNoErr(t, err)
+	classReader := class.NewReader(bytes.NewReader(content))
+	tools.AssertNoErr(t, classReader.Read())
+	tools.AssertEqual(t, "Lmyapp/shaded/guava/NonNull;", classReader.Class().Fields[0].RuntimeVisibleTypeAnnotations[0].Descriptor)
+
+	// The content of an unknown attribute can reference the constant pool, which is rebuilt.
+	writer = class.NewWriter()
+	writer.Visit(52, data.ACC_PUBLIC, "a/Main", "", "java/lang/Object", nil)
+	method := writer.VisitMethod(data.ACC_STATIC, "run", "()V", "", nil)
+	method.VisitCode()
+	method.VisitInstruction(data.RETURN)
+	method.VisitAttribute(class.Attribute{Name: "Custom", Content: []byte{0, 1}})
+	method.VisitMaxs(0, 0)
+	method.VisitEnd()
+	writer.VisitEnd()
+	content, err = writer.Bytes()
+	tools.AssertNoErr(t, err)
+	if _, err := relocator.RelocateClass(content); err == nil {
+		t.Errorf("a class with an unknown code attribute is relocated")
+	} else {
+		tools.AssertEqual(t, "cannot relocate class a/Main: unsupported attribute Custom", err.Error())
+	}
+}
@@ -0,0 +1,214 @@
+package stacktrace
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SMAP is a source map of the JSR-45 SourceDebugExtension attribute, which maps the lines of a
+// class to the lines of other source files, such as the JSP pages, or the Kotlin inline
+// functions.
+type SMAP struct {
+	// OutputFile is the name of the generated source file.
+	OutputFile string
+	// DefaultStratum is the name of the stratum used by Map.
+	DefaultStratum string
+	Strata         []Stratum
+}
+
+// Stratum is a stratum of a SMAP, such as "JSP" or "Kotlin".
+type Stratum struct {
+	Name  string
+	Files []SourceFile
+	Lines []LineInfo
+}
+
+// SourceFile is a source file of a stratum.
+type SourceFile struct {
+	ID   int
+	Name string
+	// Path is the path of the file, or an empty string.
+	Path string
+}
+
+// LineInfo maps RepeatCount input lines from InputStartLine to the output lines from
+// OutputStartLine, OutputLineIncrement output lines per input line.
+type LineInfo struct {
+	InputStartLine      int
+	FileID              int
+	RepeatCount         int
+	OutputStartLine     int
+	OutputLineIncrement int
+}
+
+// SourceLine is a line of a source file.
+type SourceLine struct {
+	File SourceFile
+	Line int
+}
+
+func (l SourceLine) String() string {
+	if l.File.Path != "" {
+		return l.File.Name + ":" + strconv.Itoa(l.Line) + " (" + l.File.Path + ")"
+	}
+	return l.File.Name + ":" + strconv.Itoa(l.Line)
+}
+
+// ParseSMAP parses the content of a SourceDebugExtension attribute. The embedded source maps
+// are not supported.
+func ParseSMAP(text string) (*SMAP, error) {
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	if len(lines) < 3 || strings.TrimSpace(lines[0]) != "SMAP" {
+		return nil, errors.New("invalid SMAP header")
+	}
+	smap := &SMAP{OutputFile: strings.TrimSpace(lines[1]), DefaultStratum: strings.TrimSpace(lines[2])}
+	var stratum *Stratum
+	section := ""
+	fileID := 0
+	for i := 3; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "*") {
+			switch {
+			case line == "*E":
+				return smap, nil
+			case strings.HasPrefix(line, "*S "):
+				smap.Strata = append(smap.Strata, Stratum{Name: strings.TrimSpace(line[3:])})
+				stratum = &smap.Strata[len(smap.Strata)-1]
+				section, fileID = "", 0
+			case line == "*F" || line == "*L" || line == "*V":
+				if stratum == nil {
+					return nil, fmt.Errorf("line %d: %s section outside of a stratum", i+1, line)
+				}
+				section = line
+			case line == "*O" || line == "*C":
+				return nil, errors.New("embedded SMAPs are not supported")
+			default:
+				// Unknown sections are ignored.
+				section = line
+			}
+			continue
+		}
+		switch section {
+		case "*F":
+			file, hasPath, err := parseFile(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			if hasPath {
+				i++
+				if i == len(lines) {
+					return nil, fmt.Errorf("line %d: missing file path", i)
+				}
+				file.Path = strings.TrimSpace(lines[i])
+			}
+			stratum.Files = append(stratum.Files, file)
+		case "*L":
+			info, err := parseLineInfo(line, fileID)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			fileID = info.FileID
+			stratum.Lines = append(stratum.Lines, info)
+		}
+	}
+	return nil, errors.New("missing *E at the end of the SMAP")
+}
+
+// parseFile parses "id name" or "+ id name", which is followed by the path of the file.
+func parseFile(line string) (SourceFile, bool, error) {
+	hasPath := strings.HasPrefix(line, "+")
+	if hasPath {
+		line = strings.TrimSpace(line[1:])
+	}
+	fields := strings.SplitN(line, " ", 2)
+	if len(fields) != 2 {
+		return SourceFile{}, false, fmt.Errorf("invalid file %q", line)
+	}
+	id, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return SourceFile{}, false, fmt.Errorf("invalid file %q", line)
+	}
+	return SourceFile{ID: id, Name: strings.TrimSpace(fields[1])}, hasPath, nil
+}
+
+// parseLineInfo parses "InputStartLine[#LineFileID][,RepeatCount]:OutputStartLine[,OutputLineIncrement]".
+func parseLineInfo(line string, fileID int) (LineInfo, error) {
+	info := LineInfo{FileID: fileID, RepeatCount: 1, OutputLineIncrement: 1}
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return info, fmt.Errorf("invalid line info %q", line)
+	}
+	input, output := line[:colon], line[colon+1:]
+	var err error
+	number := func(s string) int {
+		n, e := strconv.Atoi(s)
+		if e != nil && err == nil {
+			err = fmt.Errorf("invalid line info %q", line)
+		}
+		return n
+	}
+	if comma := strings.IndexByte(input, ','); comma >= 0 {
+		info.RepeatCount = number(input[comma+1:])
+		input = input[:comma]
+	}
+	if hash := strings.IndexByte(input, '#'); hash >= 0 {
+		info.FileID = number(input[hash+1:])
+		input = input[:hash]
+	}
+	info.InputStartLine = number(input)
+	if comma := strings.IndexByte(output, ','); comma >= 0 {
+		info.OutputLineIncrement = number(output[comma+1:])
+		output = output[:comma]
+	}
+	info.OutputStartLine = number(output)
+	return info, err
+}
+
+// Stratum returns a stratum given by its name, or nil.
+func (s *SMAP) Stratum(name string) *Stratum {
+	for i := range s.Strata {
+		if s.Strata[i].Name == name {
+			return &s.Strata[i]
+		}
+	}
+	return nil
+}
+
+// Map returns the source line of an output line, in the default stratum.
+func (s *SMAP) Map(outputLine int) (SourceLine, bool) {
+	stratum := s.Stratum(s.DefaultStratum)
+	if stratum == nil {
+		return SourceLine{}, false
+	}
+	return stratum.Map(outputLine)
+}
+
+// Map returns the source line of an output line.
+func (s *Stratum) Map(outputLine int) (SourceLine, bool) {
+	for _, info := range s.Lines {
+		delta := outputLine - info.OutputStartLine
+		if delta < 0 {
+			continue
+		}
+		index := 0
+		if info.OutputLineIncrement > 0 {
+			index = delta / info.OutputLineIncrement
+		} else if delta > 0 {
+			continue
+		}
+		if index >= info.RepeatCount {
+			continue
+		}
+		for _, file := range s.Files {
+			if file.ID == info.FileID {
+				return SourceLine{File: file, Line: info.InputStartLine + index}, true
+			}
+		}
+	}
+	return SourceLine{}, false
+}
@@ -0,0 +1,315 @@
+// Package stacktrace symbolicates the frames of Java stack traces with the classes which threw
+// them: it checks that the source file and line of a frame match its class and method, finds
+// the bytecode ranges of the line, and maps the line with the SourceDebugExtension of the
+// class, for the JSP pages and the Kotlin inline functions.
+//
+// A line which is outside of the method of a frame, or a source file which differs from the
+// one of the class, reveals that the classpath does not contain the build which produced the
+// stack trace.
+package stacktrace
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/classpath"
+)
+
+// frameRegexp matches a frame of a stack trace, such as "\tat app//a.b.C.m(C.java:12)". The
+// class may be prefixed by a class loader and a module, either of which may be empty.
+var frameRegexp = regexp.MustCompile(`^(\s*at )((?:[^\s(/]*/){0,2})([^\s(/]+)\.([^\s(.]+)\(([^)]*)\)(.*)$`)
+
+// Frame is a frame of a stack trace.
+type Frame struct {
+	// Module is the class loader and module prefix of the frame, such as "java.base/" or
+	// "app//", or an empty string.
+	Module string
+	// Class is the internal name of the class of the frame.
+	Class  string
+	Method string
+	// File is the source file of the frame, or an empty string.
+	File string
+	// Line is the line of the frame, or 0 if it is unknown.
+	Line   int
+	Native bool
+}
+
+// ParseFrame parses a frame of a stack trace, such as "at a.b.C.m(C.java:12)", and returns
+// false if the line is not a frame.
+func ParseFrame(line string) (Frame, bool) {
+	_, frame, _, ok := SplitFrame(line)
+	return frame, ok
+}
+
+// SplitFrame parses a frame of a stack trace like ParseFrame, and also returns the text before
+// the class of the frame, such as "\tat ", and the text after its closing parenthesis, such as
+// " ~[app.jar:1.0]".
+func SplitFrame(line string) (prefix string, frame Frame, suffix string, ok bool) {
+	match := frameRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return "", Frame{}, "", false
+	}
+	frame = Frame{Module: match[2], Class: strings.Replace(match[3], ".", "/", -1), Method: match[4]}
+	location := match[5]
+	switch location {
+	case "Native Method":
+		frame.Native = true
+	case "Unknown Source":
+	default:
+		frame.File = location
+		if colon := strings.LastIndexByte(location, ':'); colon >= 0 {
+			if line, err := strconv.Atoi(location[colon+1:]); err == nil {
+				frame.File, frame.Line = location[:colon], line
+			}
+		}
+	}
+	return match[1], frame, match[6], true
+}
+
+// Location returns the location of a frame as written in a stack trace, such as "C.java:12",
+// "Native Method" or "Unknown Source".
+func (f Frame) Location() string {
+	switch {
+	case f.Native:
+		return "Native Method"
+	case f.File == "":
+		return "Unknown Source"
+	case f.Line > 0:
+		return f.File + ":" + strconv.Itoa(f.Line)
+	}
+	return f.File
+}
+
+// Status is the result of the symbolication of a frame.
+type Status int
+
+const (
+	// Verified is a frame whose line is in its method.
+	Verified Status = iota
+	// Unverified is a frame without line, or in a native method.
+	Unverified
+	// ClassNotFound is a frame whose class is not in the classpath.
+	ClassNotFound
+	// MethodNotFound is a frame whose method is not declared in its class.
+	MethodNotFound
+	// NoLineNumbers is a frame whose method has no line numbers.
+	NoLineNumbers
+	// SourceFileMismatch is a frame whose source file differs from the one of its class.
+	SourceFileMismatch
+	// LineInOtherMethod is a frame whose line is in another method of its class.
+	LineInOtherMethod
+	// LineOutsideMethods is a frame whose line is in none of the methods of its class.
+	LineOutsideMethods
+)
+
+var statusNames = []string{"verified", "unverified", "class not found", "method not found", "no line numbers",
+	"source file mismatch", "line in another method", "line outside of the methods"}
+
+func (s Status) String() string {
+	return statusNames[s]
+}
+
+// Mismatch returns true if the status reveals a different build.
+func (s Status) Mismatch() bool {
+	return s == MethodNotFound || s >= SourceFileMismatch
+}
+
+// Range is a range [Start, End) of bytecode offsets.
+type Range struct {
+	Start int
+	End   int
+}
+
+// Symbol is a symbolicated frame.
+type Symbol struct {
+	Frame  Frame
+	Status Status
+	// SourceFile is the source file of the class, or an empty string.
+	SourceFile string
+	// Descriptors are the descriptors of the methods which contain the line, or of all the
+	// methods with the name of the frame if the line is not verified.
+	Descriptors []string
+	// Ranges are the bytecode ranges of the line in the methods which contain it, sorted.
+	Ranges []Range
+	// OtherMethods are the methods which contain the line, for LineInOtherMethod, as
+	// name+descriptor.
+	OtherMethods []string
+	// Source is the line mapped by the SourceDebugExtension of the class, if it differs from
+	// the line of the frame.
+	Source *SourceLine
+}
+
+// String returns a short description of the symbol, such as "run()V bytecode 4-9".
+func (s *Symbol) String() string {
+	var b strings.Builder
+	switch s.Status {
+	case Verified:
+		b.WriteString(s.Frame.Method + strings.Join(s.Descriptors, "|") + " bytecode ")
+		for i, r := range s.Ranges {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(strconv.Itoa(r.Start) + "-" + strconv.Itoa(r.End-1))
+		}
+	case SourceFileMismatch:
+		fmt.Fprintf(&b, "mismatch: %s is not the source file %s of %s", s.Frame.File, s.SourceFile, s.Frame.Class)
+	case LineInOtherMethod:
+		fmt.Fprintf(&b, "mismatch: line %d is in %s, not in %s", s.Frame.Line, strings.Join(s.OtherMethods, ", "), s.Frame.Method)
+	case LineOutsideMethods:
+		fmt.Fprintf(&b, "mismatch: line %d is outside of the methods of %s", s.Frame.Line, s.Frame.Class)
+	case MethodNotFound:
+		fmt.Fprintf(&b, "mismatch: method %s not found in %s", s.Frame.Method, s.Frame.Class)
+	default:
+		b.WriteString(s.Status.String())
+	}
+	if s.Source != nil {
+		b.WriteString(", source " + s.Source.String())
+	}
+	return b.String()
+}
+
+// Symbolicate symbolicates a frame with its class in a classpath.
+func Symbolicate(cp *classpath.Classpath, frame Frame) (*Symbol, error) {
+	symbol := &Symbol{Frame: frame}
+	c, err := cp.Find(frame.Class)
+	if err != nil {
+		if errors.Is(err, classpath.ErrNotFound) {
+			symbol.Status = ClassNotFound
+			return symbol, nil
+		}
+		return nil, err
+	}
+	symbol.SourceFile = c.SourceFile
+	if frame.Line > 0 && c.SourceDebugExtension != "" {
+		if smap, err := ParseSMAP(c.SourceDebugExtension); err == nil {
+			if source, ok := smap.Map(frame.Line); ok && (source.File.Name != c.SourceFile || source.Line != frame.Line) {
+				symbol.Source = &source
+			}
+		}
+	}
+	if frame.File != "" && c.SourceFile != "" && frame.File != c.SourceFile {
+		symbol.Status = SourceFileMismatch
+		return symbol, nil
+	}
+	var methods []*class.Method
+	for i := range c.Methods {
+		if c.Methods[i].Name == frame.Method {
+			methods = append(methods, &c.Methods[i])
+		}
+	}
+	if len(methods) == 0 {
+		symbol.Status = MethodNotFound
+		return symbol, nil
+	}
+	if frame.Native || frame.Line <= 0 {
+		symbol.Status = Unverified
+		for _, method := range methods {
+			symbol.Descriptors = append(symbol.Descriptors, method.Descriptor)
+		}
+		return symbol, nil
+	}
+	hasLines := false
+	for _, method := range methods {
+		ranges, ok := lineRanges(method, frame.Line)
+		hasLines = hasLines || ok
+		if len(ranges) > 0 {
+			symbol.Descriptors = append(symbol.Descriptors, method.Descriptor)
+			symbol.Ranges = append(symbol.Ranges, ranges...)
+		}
+	}
+	if len(symbol.Ranges) > 0 {
+		sort.Slice(symbol.Ranges, func(i, j int) bool { return symbol.Ranges[i].Start < symbol.Ranges[j].Start })
+		return symbol, nil
+	}
+	if !hasLines {
+		symbol.Status = NoLineNumbers
+		return symbol, nil
+	}
+	for i := range c.Methods {
+		method := &c.Methods[i]
+		if ranges, _ := lineRanges(method, frame.Line); method.Name != frame.Method && len(ranges) > 0 {
+			symbol.OtherMethods = append(symbol.OtherMethods, method.Name+method.Descriptor)
+		}
+	}
+	if len(symbol.OtherMethods) > 0 {
+		symbol.Status = LineInOtherMethod
+	} else {
+		symbol.Status = LineOutsideMethods
+	}
+	return symbol, nil
+}
+
+// lineRanges returns the bytecode ranges of a line in a method, and false if the method has no
+// line numbers.
+func lineRanges(method *class.Method, line int) ([]Range, bool) {
+	offsets := method.Code.Offsets()
+	codeLength := offsets[len(offsets)-1]
+	type entry struct {
+		offset int
+		line   int
+	}
+	var entries []entry
+	for i, instruction := range method.Code.Instructions {
+		if lineNumber, ok := instruction.(*class.LineNumber); ok {
+			entries = append(entries, entry{offsets[i], lineNumber.Line})
+		}
+	}
+	if len(entries) == 0 {
+		return nil, false
+	}
+	var ranges []Range
+	for i, e := range entries {
+		if e.line != line {
+			continue
+		}
+		end := codeLength
+		// The range ends at the next entry with a greater offset.
+		for _, next := range entries[i+1:] {
+			if next.offset > e.offset {
+				end = next.offset
+				break
+			}
+		}
+		if end > e.offset {
+			if n := len(ranges); n > 0 && ranges[n-1].End == e.offset {
+				ranges[n-1].End = end
+			} else {
+				ranges = append(ranges, Range{e.offset, end})
+			}
+		}
+	}
+	return ranges, true
+}
+
+// Annotate copies a stack trace, and appends the description of its symbol to each frame whose
+// class is in the classpath, such as "\tat a.B.run(B.java:4) [run()V bytecode 4-9]".
+func Annotate(cp *classpath.Classpath, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	writer := bufio.NewWriter(w)
+	for scanner.Scan() {
+		line := scanner.Text()
+		writer.WriteString(line)
+		if frame, ok := ParseFrame(line); ok {
+			symbol, err := Symbolicate(cp, frame)
+			if err != nil {
+				return err
+			}
+			if symbol.Status != ClassNotFound {
+				writer.WriteString(" [" + symbol.String() + "]")
+			}
+		}
+		writer.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,106 @@
+package stacktrace
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/internal/testutil"
+	"github.com/tk103331/clazz/tools"
+)
+
+const smapText = "SMAP\nFoo.kt\nKotlin\n*S Kotlin\n*F\n+ 1 Foo.kt\na/Foo.kt\n+ 2 Inline.kt\na/InlineKt\n*L\n1#1,20:1\n5#2,3:21\n*S KotlinDebug\n*F\n+ 1 Foo.kt\na/Foo.kt\n*L\n22#1:3\n*E\n"
+
+var sources = map[string]string{
+	"a/Foo": `.class public super a/Foo
+.super java/lang/Object
+.source "Foo.kt"
+.debug ` + `"` + strings.Replace(smapText, "\n", `\n`, -1) + `"` + `
+.method public static run ()V
+L0:
+    .line 3
+    iconst_0
+    pop
+L1:
+    .line 22
+    iconst_1
+    pop
+L2:
+    .line 4
+    return
+.end method
+.method public static other ()V
+L0:
+    .line 10
+    return
+.end method
+.method public static nolines ()V
+    return
+.end method
+`,
+}
+
+func TestParseFrame(t *testing.T) {
+	frame, ok := ParseFrame("\tat java.base/java.lang.Thread.run(Thread.java:833)")
+	tools.AssertEqual(t, true, ok)
+	tools.AssertEqual(t, Frame{Module: "java.base/", Class: "java/lang/Thread", Method: "run", File: "Thread.java", Line: 833}, frame)
+	frame, ok = ParseFrame("  at a.B$C.<init>(Native Method)")
+	tools.AssertEqual(t, true, ok)
+	tools.AssertEqual(t, Frame{Class: "a/B$C", Method: "<init>", Native: true}, frame)
+	frame, ok = ParseFrame("\tat app//a.b.C.m(C.java:12)")
+	tools.AssertEqual(t, true, ok)
+	tools.AssertEqual(t, Frame{Module: "app//", Class: "a/b/C", Method: "m", File: "C.java", Line: 12}, frame)
+	frame, ok = ParseFrame("\tat com.foo.loader/foo@9.0/com.foo.Main.run(Main.java:101)")
+	tools.AssertEqual(t, true, ok)
+	tools.AssertEqual(t, Frame{Module: "com.foo.loader/foo@9.0/", Class: "com/foo/Main", Method: "run", File: "Main.java", Line: 101}, frame)
+	frame, ok = ParseFrame("\tat com.foo.loader//com.foo.bar.App.run(App.java:12)")
+	tools.AssertEqual(t, true, ok)
+	tools.AssertEqual(t, Frame{Module: "com.foo.loader//", Class: "com/foo/bar/App", Method: "run", File: "App.java", Line: 12}, frame)
+	_, ok = ParseFrame("Caused by: a.B: at x")
+	tools.AssertEqual(t, false, ok)
+}
+
+func TestSMAP(t *testing.T) {
+	smap, err := ParseSMAP(smapText)
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "Kotlin", smap.DefaultStratum)
+	tools.AssertEqual(t, 2, len(smap.Strata))
+	source, ok := smap.Map(23)
+	tools.AssertEqual(t, true, ok)
+	tools.AssertEqual(t, "Inline.kt:7 (a/InlineKt)", source.String())
+	source, ok = smap.Stratum("KotlinDebug").Map(3)
+	tools.AssertEqual(t, true, ok)
+	tools.AssertEqual(t, 22, source.Line)
+	_, ok = smap.Map(30)
+	tools.AssertEqual(t, false, ok)
+
+	_, err = ParseSMAP("SMAP\nFoo.kt\nKotlin\n*S Kotlin\n*L\n1#1,x:1\n*E\n")
+	tools.AssertEqual(t, "line 6: invalid line info \"1#1,x:1\"", err.Error())
+}
+
+func TestAnnotate(t *testing.T) {
+	trace := `java.lang.IllegalStateException: boom
+	at a.Foo.run(Foo.kt:3)
+	at a.Foo.run(Foo.kt:22)
+	at a.Foo.run(Foo.kt:10)
+	at a.Foo.run(Foo.kt:99)
+	at a.Foo.run(Bar.java:3)
+	at a.Foo.missing(Foo.kt:3)
+	at a.Foo.nolines(Foo.kt:3)
+	at a.Foo.run(Unknown Source)
+	at java.base/java.lang.Thread.run(Thread.java:833)
+`
+	var b bytes.Buffer
+	tools.AssertNoErr(t, Annotate(testutil.Classpath(t, sources), strings.NewReader(trace), &b))
+	tools.AssertEqual(t, `java.lang.IllegalStateException: boom
+	at a.Foo.run(Foo.kt:3) [run()V bytecode 0-1]
+	at a.Foo.run(Foo.kt:22) [run()V bytecode 2-3, source Inline.kt:6 (a/InlineKt)]
+	at a.Foo.run(Foo.kt:10) [mismatch: line 10 is in other()V, not in run]
+	at a.Foo.run(Foo.kt:99) [mismatch: line 99 is outside of the methods of a/Foo]
+	at a.Foo.run(Bar.java:3) [mismatch: Bar.java is not the source file Foo.kt of a/Foo]
+	at a.Foo.missing(Foo.kt:3) [mismatch: method missing not found in a/Foo]
+	at a.Foo.nolines(Foo.kt:3) [no line numbers]
+	at a.Foo.run(Unknown Source) [unverified]
+	at java.base/java.lang.Thread.run(Thread.java:833)
+`, b.String())
+}
@@ -0,0 +1,407 @@
+// Package unused finds the private fields and methods which are not used by their nest, the
+// package private fields and methods which are not used by their package, and the classes which
+// are not reachable from the entry points of a classpath.
+//
+// The members are used by the field and method instructions, and by the method handles of the
+// constants and of the bootstrap method arguments, which reference the implementation methods of
+// the lambda expressions. The references from the private and package private synthetic methods,
+// such as the accessors generated for the nest mates before Java 11 and the lambda bodies, only
+// count when these methods are used themselves.
+package unused
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/classpath"
+	"github.com/tk103331/clazz/deps"
+	"github.com/tk103331/clazz/hierarchy"
+)
+
+// Kind is the kind of a member.
+type Kind int
+
+const (
+	Field Kind = iota
+	Method
+)
+
+func (k Kind) String() string {
+	if k == Field {
+		return "field"
+	}
+	return "method"
+}
+
+// Member is a field or a method of a class.
+type Member struct {
+	Kind        Kind
+	Owner       string
+	Name        string
+	Descriptor  string
+	AccessFlags uint16
+}
+
+// String returns "owner.name:descriptor" for a field and "owner.name(arguments)return" for a
+// method.
+func (m Member) String() string {
+	if m.Kind == Field {
+		return m.Owner + "." + m.Name + ":" + m.Descriptor
+	}
+	return m.Owner + "." + m.Name + m.Descriptor
+}
+
+// Options are the entry points of the analysis.
+type Options struct {
+	// EntryPoints are the internal names of the entry point classes. By default, the classes
+	// which declare a main method.
+	EntryPoints []string
+	// EntryAnnotations are the internal names of the annotations which mark their classes and
+	// members as entry points, such as the annotations of a test or dependency injection
+	// framework. The annotated members are used.
+	EntryAnnotations []string
+}
+
+// Result is the result of the analysis, sorted.
+type Result struct {
+	// UnusedMembers are the unused private and package private members, excluding the synthetic
+	// members, the static initializers, the private constructors without arguments and the
+	// members of the serialization.
+	UnusedMembers []Member
+	// DeadClasses are the classes which are not reachable from the entry points.
+	DeadClasses []string
+}
+
+// key identifies a member, without its access flags.
+type key struct {
+	kind       Kind
+	owner      string
+	name       string
+	descriptor string
+}
+
+// reference is the use of a member by a method.
+type reference struct {
+	from   string
+	member key
+}
+
+type analyzer struct {
+	classes   map[string]*class.Class
+	hierarchy *hierarchy.Hierarchy
+	// references are the members referenced by each method.
+	references map[key][]reference
+	used       map[key]bool
+	// analyzed are the methods whose references have been marked as used.
+	analyzed map[key]bool
+	queue    []key
+}
+
+// Analyze returns the unused members and the dead classes of a classpath.
+func Analyze(cp *classpath.Classpath, options Options) (*Result, error) {
+	names, err := cp.ClassNames()
+	if err != nil {
+		return nil, err
+	}
+	a := &analyzer{classes: make(map[string]*class.Class), hierarchy: hierarchy.NewWithLoader(cp),
+		references: make(map[key][]reference), used: make(map[key]bool), analyzed: make(map[key]bool)}
+	var classNames []string
+	for _, name := range names {
+		if name == "module-info" || name == "package-info" || strings.HasSuffix(name, "/package-info") {
+			continue
+		}
+		c, err := cp.Find(name)
+		if err != nil {
+			return nil, err
+		}
+		a.classes[name] = c
+		a.hierarchy.Add(c)
+		classNames = append(classNames, name)
+	}
+	entryAnnotations := make(map[string]bool)
+	for _, annotation := range options.EntryAnnotations {
+		entryAnnotations["L"+annotation+";"] = true
+	}
+	annotated := func(annotations ...[]class.Annotation) bool {
+		for _, list := range annotations {
+			for _, annotation := range list {
+				if entryAnnotations[annotation.Descriptor] {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	entryPoints := options.EntryPoints
+	defaultEntryPoints := entryPoints == nil
+	for _, name := range classNames {
+		c := a.classes[name]
+		entryPoint := annotated(c.RuntimeVisibleAnnotations, c.RuntimeInvisibleAnnotations)
+		for _, field := range c.Fields {
+			if annotated(field.RuntimeVisibleAnnotations, field.RuntimeInvisibleAnnotations) {
+				a.use(key{Field, name, field.Name, field.Descriptor})
+				entryPoint = true
+			}
+		}
+		for i := range c.Methods {
+			method := &c.Methods[i]
+			m := key{Method, name, method.Name, method.Descriptor}
+			a.references[m] = methodReferences(name, method)
+			if annotated(method.RuntimeVisibleAnnotations, method.RuntimeInvisibleAnnotations) {
+				a.use(m)
+				entryPoint = true
+			} else if defaultEntryPoints && isMain(method) {
+				entryPoint = true
+			}
+			if method.AccessFlags&data.ACC_SYNTHETIC == 0 || !isHidden(method.AccessFlags) {
+				a.queue = append(a.queue, m)
+			}
+		}
+		if entryPoint {
+			entryPoints = append(entryPoints, name)
+		}
+	}
+	for len(a.queue) > 0 {
+		m := a.queue[0]
+		a.queue = a.queue[1:]
+		if a.analyzed[m] {
+			continue
+		}
+		a.analyzed[m] = true
+		for _, reference := range a.references[m] {
+			a.reference(reference)
+		}
+	}
+
+	result := &Result{}
+	for _, name := range classNames {
+		c := a.classes[name]
+		for _, field := range c.Fields {
+			if isHidden(field.AccessFlags) && field.AccessFlags&data.ACC_SYNTHETIC == 0 &&
+				!a.used[key{Field, name, field.Name, field.Descriptor}] && !serializationFields[field.Name] {
+				result.UnusedMembers = append(result.UnusedMembers,
+					Member{Field, name, field.Name, field.Descriptor, field.AccessFlags})
+			}
+		}
+		for _, method := range c.Methods {
+			if isHidden(method.AccessFlags) && method.AccessFlags&data.ACC_SYNTHETIC == 0 &&
+				!a.used[key{Method, name, method.Name, method.Descriptor}] && !isIgnored(&method) {
+				result.UnusedMembers = append(result.UnusedMembers,
+					Member{Method, name, method.Name, method.Descriptor, method.AccessFlags})
+			}
+		}
+	}
+	result.DeadClasses = a.deadClasses(classNames, entryPoints)
+	return result, nil
+}
+
+// isHidden returns true for the private and package private members.
+func isHidden(accessFlags uint16) bool {
+	return accessFlags&(data.ACC_PUBLIC|data.ACC_PROTECTED) == 0
+}
+
+func isMain(method *class.Method) bool {
+	return method.Name == "main" && method.Descriptor == "([Ljava/lang/String;)V" &&
+		method.AccessFlags&(data.ACC_PUBLIC|data.ACC_STATIC) == data.ACC_PUBLIC|data.ACC_STATIC
+}
+
+var serializationFields = map[string]bool{"serialVersionUID": true, "serialPersistentFields": true}
+
+var serializationMethods = map[string]bool{
+	"writeObject(Ljava/io/ObjectOutputStream;)V": true,
+	"readObject(Ljava/io/ObjectInputStream;)V":   true,
+	"readObjectNoData()V":                        true,
+	"writeReplace()Ljava/lang/Object;":           true,
+	"readResolve()Ljava/lang/Object;":            true,
+}
+
+// isIgnored returns true for the methods which are used implicitly.
+func isIgnored(method *class.Method) bool {
+	return method.Name == "<clinit>" || serializationMethods[method.Name+method.Descriptor] ||
+		method.Name == "<init>" && method.Descriptor == "()V" && method.AccessFlags&data.ACC_PRIVATE != 0
+}
+
+// methodReferences returns the members referenced by the instructions of a method.
+func methodReferences(owner string, method *class.Method) []reference {
+	var references []reference
+	handle := func(value interface{}) {
+		if h, ok := value.(class.Handle); ok {
+			kind := Method
+			if h.Tag <= data.HANDLE_PUTSTATIC {
+				kind = Field
+			}
+			references = append(references, reference{owner, key{kind, h.Owner, h.Name, h.Descriptor}})
+		}
+	}
+	for _, instruction := range method.Code.Instructions {
+		switch insn := instruction.(type) {
+		case *class.FieldInstruction:
+			references = append(references, reference{owner, key{Field, insn.Owner, insn.Name, insn.Descriptor}})
+		case *class.MethodInstruction:
+			references = append(references, reference{owner, key{Method, insn.Owner, insn.Name, insn.Descriptor}})
+		case *class.InvokeDynamicInstruction:
+			handle(insn.BootstrapMethod)
+			for _, argument := range insn.BootstrapMethodArguments {
+				handle(argument)
+			}
+		case *class.LdcInstruction:
+			handle(insn.Value)
+		}
+	}
+	return references
+}
+
+// use marks a member as used, and queues the references of a method.
+func (a *analyzer) use(m key) {
+	if !a.used[m] {
+		a.used[m] = true
+		if m.kind == Method {
+			a.queue = append(a.queue, m)
+		}
+	}
+}
+
+// reference marks the declarations of a referenced member as used, if it is accessible from the
+// class of the reference: the declaration found in the superclasses of the owner and, for the
+// methods, the non private declarations which it overrides or which override it.
+func (a *analyzer) reference(r reference) {
+	declaration, flags, ok := a.resolve(r.member)
+	if !ok || !a.accessible(r.from, declaration.owner, flags) {
+		return
+	}
+	a.use(declaration)
+	if declaration.kind == Field || flags&data.ACC_PRIVATE != 0 {
+		return
+	}
+	related := a.hierarchy.AllSubtypes(declaration.owner)
+	for name := declaration.owner; ; {
+		superName, ok := a.hierarchy.SuperClass(name)
+		if !ok || superName == "" {
+			break
+		}
+		related = append(related, superName)
+		name = superName
+	}
+	for _, name := range related {
+		m := key{Method, name, declaration.name, declaration.descriptor}
+		if flags, ok := a.declared(m); ok && flags&data.ACC_PRIVATE == 0 {
+			a.use(m)
+		}
+	}
+}
+
+// resolve returns the declaration of a member in the superclasses of its owner.
+func (a *analyzer) resolve(m key) (key, uint16, bool) {
+	for name := m.owner; name != ""; {
+		m.owner = name
+		if flags, ok := a.declared(m); ok {
+			return m, flags, true
+		}
+		superName, ok := a.hierarchy.SuperClass(name)
+		if !ok {
+			break
+		}
+		name = superName
+	}
+	return key{}, 0, false
+}
+
+// declared returns the access flags of a member declared in the classpath.
+func (a *analyzer) declared(m key) (uint16, bool) {
+	c := a.classes[m.owner]
+	if c == nil {
+		return 0, false
+	}
+	if m.kind == Field {
+		for _, field := range c.Fields {
+			if field.Name == m.name && field.Descriptor == m.descriptor {
+				return field.AccessFlags, true
+			}
+		}
+	} else {
+		for _, method := range c.Methods {
+			if method.Name == m.name && method.Descriptor == m.descriptor {
+				return method.AccessFlags, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// accessible returns true if a class can use a member of a class with the given access flags:
+// the private members are accessible to the nest of their class, and the package private members
+// to its package.
+func (a *analyzer) accessible(from string, owner string, flags uint16) bool {
+	switch {
+	case flags&data.ACC_PRIVATE != 0:
+		return a.nestHost(from) == a.nestHost(owner)
+	case isHidden(flags):
+		return packageName(from) == packageName(owner)
+	default:
+		return true
+	}
+}
+
+func (a *analyzer) nestHost(name string) string {
+	if c := a.classes[name]; c != nil && c.NestHost != "" {
+		return c.NestHost
+	}
+	return name
+}
+
+func packageName(internalName string) string {
+	if index := strings.LastIndexByte(internalName, '/'); index >= 0 {
+		return internalName[:index]
+	}
+	return ""
+}
+
+// deadClasses returns the classes which are not reachable from the entry points through the
+// references of the classes. The inner classes and nest members declared by a class are not
+// references in themselves.
+func (a *analyzer) deadClasses(names []string, entryPoints []string) []string {
+	reached := make(map[string]bool)
+	var queue []string
+	for _, entryPoint := range entryPoints {
+		if a.classes[entryPoint] != nil && !reached[entryPoint] {
+			reached[entryPoint] = true
+			queue = append(queue, entryPoint)
+		}
+	}
+	for len(queue) > 0 {
+		c := *a.classes[queue[0]]
+		queue = queue[1:]
+		c.InnerClasses = nil
+		c.NestMembers = nil
+		for _, name := range deps.References(&c) {
+			if a.classes[name] != nil && !reached[name] {
+				reached[name] = true
+				queue = append(queue, name)
+			}
+		}
+	}
+	var dead []string
+	for _, name := range names {
+		if !reached[name] {
+			dead = append(dead, name)
+		}
+	}
+	sort.Strings(dead)
+	return dead
+}
+
+// String returns the unused members and the dead classes, one per line.
+func (r *Result) String() string {
+	var b strings.Builder
+	for _, member := range r.UnusedMembers {
+		fmt.Fprintf(&b, "unused %s %s\n", member.Kind, member)
+	}
+	for _, name := range r.DeadClasses {
+		fmt.Fprintf(&b, "dead class %s\n", name)
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package unused
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/internal/testutil"
+	"github.com/tk103331/clazz/tools"
+)
+
+var sources = map[string]string{
+	"a/Main": `.version 55 0
+.class public super a/Main
+.super java/lang/Object
+.nestmember a/Main$Inner
+.field private static secret I
+.field private static hidden I
+.field private static nested I
+.field private static unread I
+.method public static main ([Ljava/lang/String;)V
+    invokestatic a/Util helper ()V
+    invokedynamic run ()Ljava/lang/Runnable; handle invokestatic java/lang/invoke/LambdaMetafactory metafactory (Ljava/lang/invoke/MethodHandles$Lookup;Ljava/lang/String;Ljava/lang/invoke/MethodType;Ljava/lang/invoke/MethodType;Ljava/lang/invoke/MethodHandle;Ljava/lang/invoke/MethodType;)Ljava/lang/invoke/CallSite; { methodtype ()V handle invokestatic a/Main lambda$main$0 ()V methodtype ()V }
+    pop
+    return
+.end method
+.method private static synthetic lambda$main$0 ()V
+    getstatic a/Main secret I
+    pop
+    return
+.end method
+.method static synthetic access$000 ()I
+    getstatic a/Main hidden I
+    ireturn
+.end method
+.method private unusedMethod ()V
+    return
+.end method
+`,
+	"a/Main$Inner": `.version 55 0
+.class super a/Main$Inner
+.super java/lang/Object
+.nesthost a/Main
+.method static read ()I
+    getstatic a/Main nested I
+    ireturn
+.end method
+`,
+	"a/Util": `.version 55 0
+.class super a/Util
+.super java/lang/Object
+.method private <init> ()V
+    aload 0
+    invokespecial java/lang/Object <init> ()V
+    return
+.end method
+.method static helper ()V
+    return
+.end method
+.method static orphan ()V
+    return
+.end method
+`,
+	"b/Other": `.version 55 0
+.class public super b/Other
+.super java/lang/Object
+.method public static run ()V
+    iconst_0
+    putstatic a/Main unread I
+    return
+.end method
+`,
+	"b/Service": `.version 55 0
+.class public super b/Service
+.super java/lang/Object
+.field private injected Lb/Other;
+    .annotation visible Lb/Inject;
+    .end annotation
+.end field
+`,
+}
+
+func TestAnalyze(t *testing.T) {
+	result, err := Analyze(testutil.Classpath(t, sources), Options{EntryAnnotations: []string{"b/Inject"}})
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "unused field a/Main.hidden:I\n"+
+		"unused field a/Main.unread:I\n"+
+		"unused method a/Main.unusedMethod()V\n"+
+		"unused method a/Main$Inner.read()I\n"+
+		"unused method a/Util.orphan()V\n"+
+		"dead class a/Main$Inner\n",
+		result.String())
+
+	result, err = Analyze(testutil.Classpath(t, sources), Options{EntryPoints: []string{"a/Main"}})
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, "a/Main$Inner b/Other b/Service", strings.Join(result.DeadClasses, " "))
+	tools.AssertEqual(t, "a/Main.hidden:I", result.UnusedMembers[0].String())
+	tools.AssertEqual(t, 6, len(result.UnusedMembers))
+}
@@ -0,0 +1,387 @@
+// Package usage finds the usages of a method, a field, a class or a string literal in the code
+// of the classes of a classpath: the instructions which call the method, read or write the
+// field, instantiate the class or load the string, including through method handles.
+//
+// The member references are resolved like the JVM does, through the superclasses and
+// interfaces of the classpath, so that a call to an inherited method through a subclass is a
+// usage of the inherited method. The references to the classes which are not in the classpath
+// are only matched by their owner.
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+	"github.com/tk103331/clazz/classpath"
+)
+
+// Target is the kind of a searched element.
+type Target int
+
+const (
+	// Method searches the invocations of a method, and the method handles of it.
+	Method Target = iota
+	// Field searches the reads and writes of a field.
+	Field
+	// New searches the instantiations of a class.
+	New
+	// String searches the loads of a string literal.
+	String
+)
+
+// Query designates the searched element.
+type Query struct {
+	Target Target
+	// Owner is the internal name of the class of the method or field, or of the instantiated
+	// class.
+	Owner string
+	// Name and Descriptor are the name and descriptor of the method or field. An empty field
+	// descriptor matches all the fields with the name.
+	Name       string
+	Descriptor string
+	// Literal is the searched string literal.
+	Literal string
+}
+
+// ParseQuery parses a query: "method:owner.name(descriptor)", "field:owner.name",
+// "field:owner.name:descriptor", "new:owner" or "string:literal".
+func ParseQuery(s string) (Query, error) {
+	colon := strings.IndexByte(s, ':')
+	if colon < 0 {
+		return Query{}, fmt.Errorf("invalid query %q, expected method:, field:, new: or string:", s)
+	}
+	kind, value := s[:colon], s[colon+1:]
+	switch kind {
+	case "method":
+		paren := strings.IndexByte(value, '(')
+		dot := -1
+		if paren > 0 {
+			dot = strings.LastIndexByte(value[:paren], '.')
+		}
+		if dot <= 0 {
+			return Query{}, fmt.Errorf("invalid method %q, expected owner.name(descriptor)", value)
+		}
+		return Query{Target: Method, Owner: value[:dot], Name: value[dot+1 : paren], Descriptor: value[paren:]}, nil
+	case "field":
+		query := Query{Target: Field}
+		if colon := strings.IndexByte(value, ':'); colon >= 0 {
+			value, query.Descriptor = value[:colon], value[colon+1:]
+		}
+		dot := strings.LastIndexByte(value, '.')
+		if dot <= 0 || dot == len(value)-1 {
+			return Query{}, fmt.Errorf("invalid field %q, expected owner.name or owner.name:descriptor", value)
+		}
+		query.Owner, query.Name = value[:dot], value[dot+1:]
+		return query, nil
+	case "new":
+		if value == "" {
+			return Query{}, errors.New("invalid query \"new:\", expected a class name")
+		}
+		return Query{Target: New, Owner: value}, nil
+	case "string":
+		return Query{Target: String, Literal: value}, nil
+	}
+	return Query{}, fmt.Errorf("invalid query %q, expected method:, field:, new: or string:", s)
+}
+
+func (q Query) String() string {
+	switch q.Target {
+	case Method:
+		return "method:" + q.Owner + "." + q.Name + q.Descriptor
+	case Field:
+		if q.Descriptor != "" {
+			return "field:" + q.Owner + "." + q.Name + ":" + q.Descriptor
+		}
+		return "field:" + q.Owner + "." + q.Name
+	case New:
+		return "new:" + q.Owner
+	default:
+		return "string:" + q.Literal
+	}
+}
+
+// Kind is the kind of a usage.
+type Kind int
+
+const (
+	// Call is an invoke instruction.
+	Call Kind = iota
+	// Reference is a method handle of a method or constructor, such as a method reference.
+	Reference
+	// Read is a getfield or getstatic instruction, or a getter method handle.
+	Read
+	// Write is a putfield or putstatic instruction, or a setter method handle.
+	Write
+	// Instantiation is a new instruction.
+	Instantiation
+	// Literal is a ldc instruction.
+	Literal
+)
+
+var kindNames = []string{"call", "reference", "read", "write", "new", "literal"}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// MarshalText encodes the kind as "call", "reference", "read", "write", "new" or "literal".
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Usage is an instruction which uses the searched element.
+type Usage struct {
+	Kind Kind `json:"kind"`
+	// Class, Method and Descriptor designate the method which contains the instruction.
+	Class      string `json:"class"`
+	Method     string `json:"method"`
+	Descriptor string `json:"descriptor"`
+	// Offset is the bytecode offset of the instruction.
+	Offset int `json:"offset"`
+	// Line is the source line of the instruction, or 0 if the method has no line numbers.
+	Line int `json:"line,omitempty"`
+	// SourceFile is the source file of the class, or an empty string.
+	SourceFile string `json:"sourceFile,omitempty"`
+	// Reference is the element as referenced by the instruction, such as
+	// "a/Sub.run()V" for a call to an inherited method through a subclass.
+	Reference string `json:"reference"`
+}
+
+// String returns the location of the usage, followed by its kind and reference.
+func (u Usage) String() string {
+	var b strings.Builder
+	b.WriteString(u.Class + "." + u.Method + u.Descriptor + " @" + strconv.Itoa(u.Offset))
+	if u.Line > 0 {
+		b.WriteString(" (")
+		if u.SourceFile != "" {
+			b.WriteString(u.SourceFile + ":")
+		} else {
+			b.WriteString("line ")
+		}
+		b.WriteString(strconv.Itoa(u.Line) + ")")
+	}
+	b.WriteString(": " + u.Kind.String() + " " + u.Reference)
+	return b.String()
+}
+
+// Search returns the usages of an element in all the classes of a classpath, sorted by class,
+// method and offset.
+func Search(cp *classpath.Classpath, query Query) ([]Usage, error) {
+	names, err := cp.ClassNames()
+	if err != nil {
+		return nil, err
+	}
+	s := &searcher{cp: cp, query: query, resolved: make(map[string]string)}
+	if query.Target == Method || query.Target == Field {
+		s.declaringClass = s.resolve(query.Owner, query.Name, query.Descriptor, query.Target == Field)
+	}
+	usages := make([]Usage, 0)
+	for _, name := range names {
+		if name == "module-info" || strings.HasSuffix(name, "/module-info") {
+			continue
+		}
+		c, err := cp.Find(name)
+		if err != nil {
+			return nil, err
+		}
+		for i := range c.Methods {
+			usages = append(usages, s.search(c, &c.Methods[i])...)
+		}
+	}
+	return usages, nil
+}
+
+type searcher struct {
+	cp    *classpath.Classpath
+	query Query
+	// declaringClass is the class which declares the searched method or field.
+	declaringClass string
+	// resolved are the declaring classes of the referenced members, by reference.
+	resolved map[string]string
+}
+
+// search returns the usages in the code of a method.
+func (s *searcher) search(c *class.Class, method *class.Method) []Usage {
+	var usages []Usage
+	var offsets []int
+	line := 0
+	for i, instruction := range method.Code.Instructions {
+		var kind Kind
+		var reference string
+		switch insn := instruction.(type) {
+		case *class.LineNumber:
+			line = insn.Line
+			continue
+		case *class.MethodInstruction:
+			kind, reference = Call, insn.Owner+"."+insn.Name+insn.Descriptor
+			if !s.matchMethod(insn.Owner, insn.Name, insn.Descriptor) {
+				continue
+			}
+		case *class.FieldInstruction:
+			kind, reference = Read, insn.Owner+"."+insn.Name+":"+insn.Descriptor
+			if insn.Op == data.PUTFIELD || insn.Op == data.PUTSTATIC {
+				kind = Write
+			}
+			if !s.matchField(insn.Owner, insn.Name, insn.Descriptor) {
+				continue
+			}
+		case *class.TypeInstruction:
+			kind, reference = Instantiation, insn.Type
+			if insn.Op != data.NEW || s.query.Target != New || insn.Type != s.query.Owner {
+				continue
+			}
+		case *class.LdcInstruction:
+			if value, ok := insn.Value.(string); ok {
+				kind, reference = Literal, strconv.Quote(value)
+				if s.query.Target != String || value != s.query.Literal {
+					continue
+				}
+			} else if handle, ok := insn.Value.(class.Handle); !ok || !s.matchHandle(handle, &kind, &reference) {
+				continue
+			}
+		case *class.InvokeDynamicInstruction:
+			found := s.matchHandle(insn.BootstrapMethod, &kind, &reference)
+			for _, argument := range insn.BootstrapMethodArguments {
+				if found {
+					break
+				}
+				if handle, ok := argument.(class.Handle); ok {
+					found = s.matchHandle(handle, &kind, &reference)
+				}
+			}
+			if !found {
+				continue
+			}
+		default:
+			continue
+		}
+		if offsets == nil {
+			offsets = method.Code.Offsets()
+		}
+		usages = append(usages, Usage{Kind: kind, Class: c.ThisClass, Method: method.Name, Descriptor: method.Descriptor,
+			Offset: offsets[i], Line: line, SourceFile: c.SourceFile, Reference: reference})
+	}
+	return usages
+}
+
+// matchHandle returns true if a method handle references the searched element, and sets the
+// kind and reference of its usage.
+func (s *searcher) matchHandle(handle class.Handle, kind *Kind, reference *string) bool {
+	switch handle.Tag {
+	case data.HANDLE_GETFIELD, data.HANDLE_GETSTATIC, data.HANDLE_PUTFIELD, data.HANDLE_PUTSTATIC:
+		*kind, *reference = Read, handle.Owner+"."+handle.Name+":"+handle.Descriptor
+		if handle.Tag == data.HANDLE_PUTFIELD || handle.Tag == data.HANDLE_PUTSTATIC {
+			*kind = Write
+		}
+		return s.matchField(handle.Owner, handle.Name, handle.Descriptor)
+	case data.HANDLE_NEWINVOKESPECIAL:
+		*kind, *reference = Reference, handle.Owner+"."+handle.Name+handle.Descriptor
+		if s.query.Target == New {
+			return handle.Owner == s.query.Owner
+		}
+		return s.matchMethod(handle.Owner, handle.Name, handle.Descriptor)
+	default:
+		*kind, *reference = Reference, handle.Owner+"."+handle.Name+handle.Descriptor
+		return s.matchMethod(handle.Owner, handle.Name, handle.Descriptor)
+	}
+}
+
+func (s *searcher) matchMethod(owner string, name string, descriptor string) bool {
+	return s.query.Target == Method && name == s.query.Name && descriptor == s.query.Descriptor &&
+		s.resolve(owner, name, descriptor, false) == s.declaringClass
+}
+
+func (s *searcher) matchField(owner string, name string, descriptor string) bool {
+	return s.query.Target == Field && name == s.query.Name &&
+		(s.query.Descriptor == "" || descriptor == s.query.Descriptor) &&
+		s.resolve(owner, name, descriptor, true) == s.declaringClass
+}
+
+// resolve returns the class which declares a referenced method or field, or the owner of the
+// reference if it is not found in the classpath. An empty field descriptor matches all the
+// fields with the name.
+func (s *searcher) resolve(owner string, name string, descriptor string, field bool) string {
+	key := owner + "." + name + ":" + descriptor
+	if field {
+		key = "field " + key
+	}
+	if declaringClass, ok := s.resolved[key]; ok {
+		return declaringClass
+	}
+	var declaringClass string
+	if field {
+		declaringClass = s.resolveField(owner, name, descriptor, make(map[string]bool))
+	} else {
+		declaringClass = s.resolveMethod(owner, name, descriptor)
+	}
+	if declaringClass == "" {
+		declaringClass = owner
+	}
+	s.resolved[key] = declaringClass
+	return declaringClass
+}
+
+// find returns a class of the classpath, or nil.
+func (s *searcher) find(name string) *class.Class {
+	if name == "" {
+		return nil
+	}
+	c, err := s.cp.Find(name)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// resolveMethod looks up a method in a class and its superclasses, then in their interfaces.
+func (s *searcher) resolveMethod(owner string, name string, descriptor string) string {
+	var interfaces []string
+	for c := s.find(owner); c != nil; c = s.find(c.SuperClass) {
+		for _, method := range c.Methods {
+			if method.Name == name && method.Descriptor == descriptor {
+				return c.ThisClass
+			}
+		}
+		interfaces = append(interfaces, c.Interfaces...)
+	}
+	seen := make(map[string]bool)
+	for len(interfaces) > 0 {
+		c := s.find(interfaces[0])
+		interfaces = interfaces[1:]
+		if c == nil || seen[c.ThisClass] {
+			continue
+		}
+		seen[c.ThisClass] = true
+		for _, method := range c.Methods {
+			if method.Name == name && method.Descriptor == descriptor && method.AccessFlags&data.ACC_STATIC == 0 {
+				return c.ThisClass
+			}
+		}
+		interfaces = append(interfaces, c.Interfaces...)
+	}
+	return ""
+}
+
+// resolveField looks up a field in a class, then in its interfaces, then in its superclass.
+func (s *searcher) resolveField(owner string, name string, descriptor string, seen map[string]bool) string {
+	c := s.find(owner)
+	if c == nil || seen[owner] {
+		return ""
+	}
+	seen[owner] = true
+	for _, field := range c.Fields {
+		if field.Name == name && (descriptor == "" || field.Descriptor == descriptor) {
+			return owner
+		}
+	}
+	for _, itf := range c.Interfaces {
+		if declaringClass := s.resolveField(itf, name, descriptor, seen); declaringClass != "" {
+			return declaringClass
+		}
+	}
+	return s.resolveField(c.SuperClass, name, descriptor, seen)
+}
@@ -0,0 +1,134 @@
+package usage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/classpath"
+	"github.com/tk103331/clazz/internal/testutil"
+	"github.com/tk103331/clazz/tools"
+)
+
+var sources = map[string]string{
+	"a/Base": `.class public super a/Base
+.super java/lang/Object
+.field protected count I
+.method public <init> ()V
+    aload_0
+    invokespecial java/lang/Object <init> ()V
+    return
+.end method
+.method public run ()V
+    return
+.end method
+`,
+	"a/Sub": `.class public super a/Sub
+.super a/Base
+.method public <init> ()V
+    aload_0
+    invokespecial a/Base <init> ()V
+    return
+.end method
+`,
+	"a/Other": `.class public super a/Other
+.super java/lang/Object
+.field protected count I
+.method public run ()V
+    return
+.end method
+`,
+	"a/Caller": `.class public super a/Caller
+.super java/lang/Object
+.source "Caller.java"
+.method public static main ([Ljava/lang/String;)V
+L0:
+    .line 3
+    new a/Sub
+    dup
+    invokespecial a/Sub <init> ()V
+    astore_1
+L1:
+    .line 4
+    aload_1
+    invokevirtual a/Sub run ()V
+    aload_1
+    dup
+    getfield a/Sub count I
+    iconst_1
+    iadd
+    putfield a/Base count I
+    ldc "hello"
+    pop
+    ldc handle invokevirtual a/Base run ()V
+    pop
+    new a/Other
+    dup
+    invokevirtual a/Other run ()V
+    getfield a/Other count I
+    pop
+    return
+.end method
+`,
+}
+
+func search(t *testing.T, cp *classpath.Classpath, query string) []string {
+	q, err := ParseQuery(query)
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, query, q.String())
+	usages, err := Search(cp, q)
+	tools.AssertNoErr(t, err)
+	var lines []string
+	for _, usage := range usages {
+		lines = append(lines, usage.String())
+	}
+	return lines
+}
+
+func TestSearch(t *testing.T) {
+	cp := testutil.Classpath(t, sources)
+	lines := search(t, cp, "method:a/Base.run()V")
+	tools.AssertEqual(t, 2, len(lines))
+	tools.AssertEqual(t, "a/Caller.main([Ljava/lang/String;)V @9 (Caller.java:4): call a/Sub.run()V", lines[0])
+	tools.AssertEqual(t, "a/Caller.main([Ljava/lang/String;)V @25 (Caller.java:4): reference a/Base.run()V", lines[1])
+
+	lines = search(t, cp, "field:a/Sub.count")
+	tools.AssertEqual(t, 2, len(lines))
+	tools.AssertEqual(t, "a/Caller.main([Ljava/lang/String;)V @14 (Caller.java:4): read a/Sub.count:I", lines[0])
+	tools.AssertEqual(t, "a/Caller.main([Ljava/lang/String;)V @19 (Caller.java:4): write a/Base.count:I", lines[1])
+
+	lines = search(t, cp, "new:a/Sub")
+	tools.AssertEqual(t, 1, len(lines))
+	tools.AssertEqual(t, "a/Caller.main([Ljava/lang/String;)V @0 (Caller.java:3): new a/Sub", lines[0])
+
+	lines = search(t, cp, "string:hello")
+	tools.AssertEqual(t, 1, len(lines))
+	tools.AssertEqual(t, "a/Caller.main([Ljava/lang/String;)V @22 (Caller.java:4): literal \"hello\"", lines[0])
+
+	lines = search(t, cp, "method:a/Base.<init>()V")
+	tools.AssertEqual(t, 1, len(lines))
+	tools.AssertEqual(t, "a/Sub.<init>()V @1: call a/Base.<init>()V", lines[0])
+}
+
+func TestSearchOffsets(t *testing.T) {
+	// The fields fill the constant pool, so that the string is loaded with ldc_w.
+	var source strings.Builder
+	source.WriteString(".class public super a/Wide\n.super java/lang/Object\n")
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&source, ".field public static f%d I\n", i)
+	}
+	source.WriteString(".method public static m ()V\n    ldc \"x\"\n    pop\n    getstatic a/Wide f0 I\n    pop\n    return\n.end method\n")
+	lines := search(t, testutil.Classpath(t, map[string]string{"a/Wide": source.String()}), "field:a/Wide.f0")
+	tools.AssertEqual(t, 1, len(lines))
+	tools.AssertEqual(t, "a/Wide.m()V @4: read a/Wide.f0:I", lines[0])
+}
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery("field:a/B.f:I")
+	tools.AssertNoErr(t, err)
+	tools.AssertEqual(t, Query{Target: Field, Owner: "a/B", Name: "f", Descriptor: "I"}, q)
+	for _, query := range []string{"a/B.m()V", "method:a/B.m", "field:f", "new:", "class:a/B"} {
+		_, err := ParseQuery(query)
+		tools.AssertEqual(t, true, err != nil)
+	}
+}
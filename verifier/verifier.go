@@ -0,0 +1,484 @@
+// Package verifier checks the bytecode of methods like the JVM verifier (JVMS 4.10), without a
+// JVM. The methods of the classes with a version of 51 or more, and of the classes with version
+// 50 which have StackMapTable frames, are type checked against their frames. The other methods
+// are verified by type inference, except the methods with JSR or RET instructions, since the
+// subroutines are not supported: they are reported with an unverified error.
+package verifier
+
+import (
+	"fmt"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/class/data"
+)
+
+// Hierarchy gives the class hierarchy used to check the assignability of the reference types.
+// A *hierarchy.Hierarchy is a Hierarchy. The reference types which are not in the hierarchy are
+// assumed to be assignable.
+type Hierarchy interface {
+	Contains(name string) bool
+	IsInterface(name string) bool
+	IsAssignableFrom(to string, from string) bool
+	CommonSuperClass(a string, b string) string
+}
+
+// Error is a verification error, found at the instruction at the given bytecode offset of a
+// method. Expected and Actual are set for the type errors. Unverified is set if the method was
+// not verified, because it uses subroutines, and the offset is the one of its first JSR or RET
+// instruction.
+type Error struct {
+	Class       string
+	Method      string
+	Descriptor  string
+	Offset      int
+	Instruction string
+	Message     string
+	Expected    string
+	Actual      string
+	Unverified  bool
+}
+
+func (e *Error) Error() string {
+	text := fmt.Sprintf("%s.%s%s: offset %d", e.Class, e.Method, e.Descriptor, e.Offset)
+	if e.Instruction != "" {
+		text += ": " + e.Instruction
+	}
+	text += ": " + e.Message
+	if e.Expected != "" {
+		text += fmt.Sprintf(" (expected %s, found %s)", e.Expected, e.Actual)
+	}
+	return text
+}
+
+const throwableName = "java/lang/Throwable"
+
+// failure is the panic value of the verification errors.
+type failure struct {
+	message    string
+	expected   string
+	actual     string
+	unverified bool
+}
+
+type Verifier struct {
+	hierarchy Hierarchy
+}
+
+// New returns a verifier which checks the assignability of the reference types with the given
+// hierarchy, which may be nil.
+func New(hierarchy Hierarchy) *Verifier {
+	return &Verifier{hierarchy: hierarchy}
+}
+
+// Verify verifies the methods of a class, and returns the first error of each invalid method,
+// and an unverified error for each method with subroutines.
+func (v *Verifier) Verify(c *class.Class) []*Error {
+	var errors []*Error
+	for i := range c.Methods {
+		if err := v.VerifyMethod(c, &c.Methods[i]); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	return errors
+}
+
+// VerifyMethod verifies a method of a class, and returns its first error, or nil.
+func (v *Verifier) VerifyMethod(c *class.Class, method *class.Method) (err *Error) {
+	m := &methodVerifier{hierarchy: v.hierarchy, class: c, method: method}
+	m.simulator = class.NewSimulator(c.ThisClass, method)
+	m.simulator.SuperClass = c.SuperClass
+	m.simulator.Check = true
+	m.simulator.IsAssignable = m.isAssignable
+	if v.hierarchy != nil {
+		m.simulator.CommonSuperClass = v.hierarchy.CommonSuperClass
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			f, ok := r.(failure)
+			if !ok {
+				panic(r)
+			}
+			err = &Error{Class: c.ThisClass, Method: method.Name, Descriptor: method.Descriptor,
+				Message: f.message, Expected: f.expected, Actual: f.actual, Unverified: f.unverified}
+			if m.index < len(m.instructions) {
+				err.Offset = m.offsets[m.index]
+				err.Instruction = instructionText(m.instructions[m.index], m)
+			}
+		}
+	}()
+	m.verify()
+	return nil
+}
+
+type methodVerifier struct {
+	hierarchy    Hierarchy
+	class        *class.Class
+	method       *class.Method
+	instructions []class.Instruction
+	offsets      []int
+	labels       map[*class.Label]int
+	simulator    *class.Simulator
+	typeChecking bool
+	// frames gives the stack map frame of the instruction at each index, when type checking.
+	frames    []*class.FrameState
+	states    []*class.FrameState
+	queue     []int
+	index     int
+	maxStack  int
+	maxLocals int
+}
+
+func (m *methodVerifier) fail(format string, args ...interface{}) {
+	panic(failure{message: fmt.Sprintf(format, args...)})
+}
+
+func (m *methodVerifier) mismatch(message string, expected interface{}, actual interface{}) {
+	panic(failure{message: message, expected: m.simulator.ValueString(expected), actual: m.simulator.ValueString(actual)})
+}
+
+// isAssignable returns whether a class is assignable to another one in the hierarchy. The
+// classes which are not in the hierarchy are assumed to be assignable.
+func (m *methodVerifier) isAssignable(from string, to string) bool {
+	h := m.hierarchy
+	if h == nil || !h.Contains(from) || !h.Contains(to) {
+		return true
+	}
+	return h.IsInterface(to) || h.IsAssignableFrom(to, from)
+}
+
+func (m *methodVerifier) verify() {
+	code := m.method.Code
+	if m.method.AccessFlags&(data.ACC_ABSTRACT|data.ACC_NATIVE) != 0 {
+		if len(code.Instructions) > 0 {
+			m.fail("abstract and native methods can't have code")
+		}
+		return
+	}
+	if len(code.Instructions) == 0 {
+		m.fail("missing code")
+	}
+	m.maxStack, m.maxLocals = int(code.MaxStack), int(code.MaxLocal)
+	m.prepare()
+	major := m.class.Version & 0xffff
+	m.typeChecking = major >= 51
+	for i, instruction := range m.instructions {
+		if _, ok := instruction.(*class.Frame); ok && major >= 50 {
+			m.typeChecking = true
+		}
+		if op := instruction.OpCode(); op == data.JSR || op == data.RET {
+			m.index = i
+			if major >= 51 {
+				m.fail("jsr and ret instructions are not allowed in class files of version 51 or more")
+			}
+			panic(failure{message: "not verified, subroutines are not supported", unverified: true})
+		}
+	}
+	initial := m.simulator.InitialState()
+	if m.typeChecking {
+		m.expandFrames(initial.Locals)
+	}
+	m.states = make([]*class.FrameState, len(m.instructions))
+	m.index = 0
+	m.mergeInto(0, m.padLocals(initial))
+	for len(m.queue) > 0 {
+		index := m.queue[len(m.queue)-1]
+		m.queue = m.queue[:len(m.queue)-1]
+		m.index = index
+		m.step(index)
+	}
+}
+
+// prepare copies the instructions of the method, with a label before each NEW instruction,
+// which designates its uninitialized type, and their bytecode offsets.
+func (m *methodVerifier) prepare() {
+	m.labels = make(map[*class.Label]int)
+	var previous *class.Label
+	offsets := m.method.Code.Offsets()
+	for i, instruction := range m.method.Code.Instructions {
+		switch insn := instruction.(type) {
+		case *class.Label:
+			previous = insn
+		case *class.LineNumber, *class.Frame:
+		case *class.TypeInstruction:
+			if insn.Op == data.NEW {
+				if previous == nil {
+					previous = class.NewLabel()
+					m.instructions = append(m.instructions, previous)
+					m.offsets = append(m.offsets, offsets[i])
+				}
+				m.simulator.NewTypes[previous] = insn.Type
+				m.simulator.NewLabels[insn] = previous
+			}
+			previous = nil
+		default:
+			previous = nil
+		}
+		m.instructions = append(m.instructions, instruction)
+		m.offsets = append(m.offsets, offsets[i])
+	}
+	for i, instruction := range m.instructions {
+		if label, ok := instruction.(*class.Label); ok {
+			m.labels[label] = i
+		}
+	}
+}
+
+// target returns the index of a label.
+func (m *methodVerifier) target(label *class.Label) int {
+	index, ok := m.labels[label]
+	if !ok {
+		m.fail("label not found in the code")
+	}
+	return index
+}
+
+// nextInstruction returns the index of the first real instruction at or after an index, or -1.
+func (m *methodVerifier) nextInstruction(index int) int {
+	for i := index; i < len(m.instructions); i++ {
+		if m.instructions[i].OpCode() >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// padLocals completes the local variables of a state with top values, up to max locals.
+func (m *methodVerifier) padLocals(s *class.FrameState) *class.FrameState {
+	if len(s.Locals) > m.maxLocals {
+		m.fail("%d local variables, more than max locals %d", len(s.Locals), m.maxLocals)
+	}
+	for len(s.Locals) < m.maxLocals {
+		s.Locals = append(s.Locals, data.ITEM_TOP)
+	}
+	return s
+}
+
+// expandFrames computes the stack map frame of each instruction from the compressed frames,
+// starting from the initial locals. The frame of an instruction also applies to the pseudo
+// instructions before it.
+func (m *methodVerifier) expandFrames(initial []interface{}) {
+	m.frames = make([]*class.FrameState, len(m.instructions))
+	locals := append([]interface{}{}, initial...)
+	var current *class.FrameState
+	start := 0
+	for i, instruction := range m.instructions {
+		m.index = i
+		if instruction.OpCode() >= 0 {
+			for j := start; current != nil && j <= i; j++ {
+				m.frames[j] = current
+			}
+			current = nil
+			start = i + 1
+			continue
+		}
+		frame, ok := instruction.(*class.Frame)
+		if !ok || current != nil {
+			continue
+		}
+		var stack []interface{}
+		switch frame.Type {
+		case data.F_SAME:
+		case data.F_SAME1:
+			stack = m.frameValues(frame.Stack)
+		case data.F_CHOP:
+			for n := len(frame.Locals); n > 0; n-- {
+				if len(locals) == 0 {
+					m.fail("invalid chop frame")
+				}
+				size := 1
+				if len(locals) > 1 && locals[len(locals)-1] == data.ITEM_TOP &&
+					(locals[len(locals)-2] == data.ITEM_LONG || locals[len(locals)-2] == data.ITEM_DOUBLE) {
+					size = 2
+				}
+				locals = locals[:len(locals)-size]
+			}
+		case data.F_APPEND:
+			locals = append(locals, m.frameValues(frame.Locals)...)
+		case data.F_FULL, data.F_NEW:
+			locals = m.frameValues(frame.Locals)
+			stack = m.frameValues(frame.Stack)
+		default:
+			m.fail("invalid frame type %d", frame.Type)
+		}
+		if len(stack) > m.maxStack {
+			m.fail("the stack map frame stack is larger than max stack %d", m.maxStack)
+		}
+		current = m.padLocals(&class.FrameState{Locals: append([]interface{}{}, locals...), Stack: stack})
+	}
+}
+
+// frameValues returns the values of the verification types of a frame, with two slots for the
+// long and double values, and the label of the NEW instruction of the uninitialized values.
+func (m *methodVerifier) frameValues(items []interface{}) []interface{} {
+	var result []interface{}
+	for _, item := range items {
+		switch v := item.(type) {
+		case uint8:
+			switch v {
+			case data.ITEM_TOP, data.ITEM_INTEGER, data.ITEM_FLOAT, data.ITEM_NULL, data.ITEM_UNINITIALIZED_THIS:
+				result = append(result, v)
+			case data.ITEM_LONG, data.ITEM_DOUBLE:
+				result = append(result, v, data.ITEM_TOP)
+			default:
+				m.fail("invalid verification type %d", v)
+			}
+		case string:
+			result = append(result, v)
+		case *class.Label:
+			index := m.nextInstruction(m.target(v))
+			insn, ok := m.instructions[max(index, 0)].(*class.TypeInstruction)
+			if index < 0 || !ok || insn.Op != data.NEW {
+				m.fail("uninitialized verification type without NEW instruction")
+			}
+			result = append(result, m.simulator.NewLabels[insn])
+		default:
+			m.fail("invalid verification type %v", item)
+		}
+	}
+	return result
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// mergeInto merges a state into the state of an instruction. When type checking, the state
+// must be assignable to the stack map frame of the instruction.
+func (m *methodVerifier) mergeInto(index int, s *class.FrameState) {
+	if m.typeChecking && m.frames[index] != nil {
+		frame := m.frames[index]
+		if len(s.Stack) != len(frame.Stack) {
+			m.fail("stack height %d does not match the stack map frame height %d", len(s.Stack), len(frame.Stack))
+		}
+		for i, v := range s.Locals {
+			if !m.simulator.Assignable(v, frame.Locals[i]) {
+				m.mismatch(fmt.Sprintf("local variable %d does not match the stack map frame", i), frame.Locals[i], v)
+			}
+		}
+		for i, v := range s.Stack {
+			if !m.simulator.Assignable(v, frame.Stack[i]) {
+				m.mismatch(fmt.Sprintf("stack value %d does not match the stack map frame", i), frame.Stack[i], v)
+			}
+		}
+		if m.states[index] == nil {
+			m.states[index] = frame
+			m.queue = append(m.queue, index)
+		}
+		return
+	}
+	current := m.states[index]
+	if current == nil {
+		m.states[index] = s.Copy()
+		m.queue = append(m.queue, index)
+		return
+	}
+	if len(current.Stack) != len(s.Stack) {
+		m.fail("inconsistent stack heights %d and %d", len(current.Stack), len(s.Stack))
+	}
+	changed := false
+	for _, values := range [][2][]interface{}{{current.Locals, s.Locals}, {current.Stack, s.Stack}} {
+		for i, v := range values[1] {
+			if merged := m.simulator.Merge(values[0][i], v); merged != values[0][i] {
+				values[0][i] = merged
+				changed = true
+			}
+		}
+	}
+	if changed {
+		m.queue = append(m.queue, index)
+	}
+}
+
+// step simulates an instruction, and merges the resulting state into its successors.
+func (m *methodVerifier) step(index int) {
+	before := m.states[index]
+	instruction := m.instructions[index]
+	if instruction.OpCode() < 0 {
+		if index+1 >= len(m.instructions) {
+			m.fail("execution falls off the end of the code")
+		}
+		m.mergeInto(index+1, before)
+		return
+	}
+	after := before.Copy()
+	if err := m.simulator.Execute(instruction, after); err != nil {
+		e := err.(*class.SimulationError)
+		panic(failure{message: e.Message, expected: e.Expected, actual: e.Actual})
+	}
+	for _, exception := range m.method.Code.ExceptionTable {
+		if index < m.target(exception.Start) || index >= m.target(exception.End) {
+			continue
+		}
+		catchType := throwableName
+		if exception.CatchType != "" {
+			catchType = exception.CatchType
+			if !m.simulator.Assignable(catchType, throwableName) {
+				m.mismatch("invalid exception handler type", throwableName, catchType)
+			}
+		}
+		handler := m.target(exception.Handler)
+		m.branch(handler)
+		if m.maxStack < 1 {
+			m.fail("operand stack overflow")
+		}
+		m.mergeInto(handler, &class.FrameState{Locals: before.Locals, Stack: []interface{}{catchType}})
+		m.mergeInto(handler, &class.FrameState{Locals: after.Locals, Stack: []interface{}{catchType}})
+	}
+	targets, next := class.Successors(instruction)
+	for _, target := range targets {
+		index := m.target(target)
+		m.branch(index)
+		m.mergeInto(index, after)
+	}
+	if next {
+		if index+1 >= len(m.instructions) {
+			m.fail("execution falls off the end of the code")
+		}
+		m.mergeInto(index+1, after)
+	} else if m.typeChecking && m.nextInstruction(index+1) >= 0 && m.frames[index+1] == nil {
+		m.fail("missing stack map frame after an unconditional branch")
+	}
+}
+
+// branch checks that a branch target has a stack map frame when type checking.
+func (m *methodVerifier) branch(index int) {
+	if m.typeChecking && m.frames[index] == nil {
+		m.fail("missing stack map frame at branch target offset %d", m.offsets[index])
+	}
+}
+
+// instructionText returns the mnemonic and the main operands of an instruction.
+func instructionText(instruction class.Instruction, m *methodVerifier) string {
+	if instruction.OpCode() < 0 {
+		return ""
+	}
+	name := data.OPCODE_NAMES[instruction.OpCode()]
+	switch insn := instruction.(type) {
+	case *class.IntInstruction:
+		return fmt.Sprintf("%s %d", name, insn.Operand)
+	case *class.VarInstruction:
+		return fmt.Sprintf("%s %d", name, insn.Var)
+	case *class.IincInstruction:
+		return fmt.Sprintf("%s %d %d", name, insn.Var, insn.Increment)
+	case *class.TypeInstruction:
+		return name + " " + insn.Type
+	case *class.FieldInstruction:
+		return name + " " + insn.Owner + "." + insn.Name + " : " + insn.Descriptor
+	case *class.MethodInstruction:
+		return name + " " + insn.Owner + "." + insn.Name + insn.Descriptor
+	case *class.InvokeDynamicInstruction:
+		return name + " " + insn.Name + insn.Descriptor
+	case *class.JumpInstruction:
+		if index, ok := m.labels[insn.Label]; ok {
+			return fmt.Sprintf("%s %d", name, m.offsets[index])
+		}
+	case *class.LdcInstruction:
+		return fmt.Sprintf("%s %v", name, insn.Value)
+	case *class.MultiANewArrayInstruction:
+		return fmt.Sprintf("%s %s %d", name, insn.Descriptor, insn.NumDimensions)
+	}
+	return name
+}
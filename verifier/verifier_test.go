@@ -0,0 +1,217 @@
+package verifier
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tk103331/clazz/class"
+	"github.com/tk103331/clazz/hierarchy"
+	"github.com/tk103331/clazz/jasm"
+	"github.com/tk103331/clazz/tools"
+)
+
+func parse(t *testing.T, source string) *class.Class {
+	t.Helper()
+	c, err := jasm.Parse([]byte(source))
+	tools.AssertNoErr(t, err)
+	return c
+}
+
+func TestVerifyHello(t *testing.T) {
+	f, err := os.Open("../class/Hello.class")
+	tools.AssertNoErr(t, err)
+	defer f.Close()
+	reader := class.NewReader(f)
+	tools.AssertNoErr(t, reader.Read())
+	tools.AssertEqual(t, 0, len(New(hierarchy.New()).Verify(reader.Class())))
+}
+
+const valid = `.version 52 0
+.class public super a/T
+.super java/lang/Object
+
+.method public <init> ()V
+    aload 0
+    invokespecial java/lang/Object <init> ()V
+    return
+.end method
+
+.method public static sum ([I)J
+    lconst_0
+    lstore 1
+    iconst_0
+    istore 3
+L0:
+    iload 3
+    aload 0
+    arraylength
+    if_icmpge L1
+    lload 1
+    aload 0
+    iload 3
+    iaload
+    i2l
+    ladd
+    lstore 1
+    iinc 3 1
+    goto L0
+L1:
+    lload 1
+    lreturn
+.end method
+
+.method public static create ()Ljava/lang/Object;
+L0:
+    new java/lang/StringBuilder
+    dup
+    ldc "x"
+    invokespecial java/lang/StringBuilder <init> (Ljava/lang/String;)V
+L1:
+    areturn
+L2:
+    pop
+    aconst_null
+    areturn
+    .catch java/lang/RuntimeException from L0 to L1 using L2
+.end method
+`
+
+func TestVerifyValid(t *testing.T) {
+	c := parse(t, valid)
+	tools.AssertEqual(t, 0, len(New(nil).Verify(c)))
+
+	// Without frames, the methods with branches are rejected, unless they are verified by inference.
+	for i := range c.Methods {
+		var instructions []class.Instruction
+		for _, instruction := range c.Methods[i].Code.Instructions {
+			if _, ok := instruction.(*class.Frame); !ok {
+				instructions = append(instructions, instruction)
+			}
+		}
+		c.Methods[i].Code.Instructions = instructions
+	}
+	errors := New(nil).Verify(c)
+	tools.AssertEqual(t, 2, len(errors))
+	tools.AssertEqual(t, "a/T.sum([I)J: offset 7: if_icmpge 23: missing stack map frame at branch target offset 23", errors[0].Error())
+	c.Version = 49
+	tools.AssertEqual(t, 0, len(New(nil).Verify(c)))
+}
+
+func TestVerifyErrors(t *testing.T) {
+	h := hierarchy.New()
+	h.Add(&class.Class{ThisClass: "a/A", SuperClass: "java/lang/Object"})
+	h.Add(&class.Class{ThisClass: "a/B", SuperClass: "java/lang/Object"})
+	c := parse(t, `.version 52 0
+.class public super a/T
+.super java/lang/Object
+
+.method public static store ()V
+    fconst_1
+    istore 0
+    return
+.end method
+
+.method public static call (La/B;)V
+    aload 0
+    invokestatic a/T take (La/A;)V
+    return
+.end method
+
+.method public static uninitialized ()V
+    new a/A
+    dup
+    invokevirtual a/A m ()V
+    return
+.end method
+
+.method public static frame (I)I
+    .limit stack 1
+    .limit locals 1
+    iload 0
+    ifeq L1
+    iconst_1
+    ireturn
+L1:
+    .stack full locals Float stack
+    iconst_0
+    ireturn
+.end method
+`)
+	errors := New(h).Verify(c)
+	tools.AssertEqual(t, 4, len(errors))
+	err := errors[0]
+	tools.AssertEqual(t, 1, err.Offset)
+	tools.AssertEqual(t, "istore 0", err.Instruction)
+	tools.AssertEqual(t, "int", err.Expected)
+	tools.AssertEqual(t, "float", err.Actual)
+	tools.AssertEqual(t, "a/T.call(La/B;)V: offset 1: invokestatic a/T.take(La/A;)V: bad type on operand stack (expected a/A, found a/B)",
+		errors[1].Error())
+	tools.AssertEqual(t, "a/T.uninitialized()V: offset 4: invokevirtual a/A.m()V: bad type on operand stack (expected a/A, found uninitialized a/A)",
+		errors[2].Error())
+	tools.AssertEqual(t, "a/T.frame(I)I: offset 1: ifeq 6: local variable 0 does not match the stack map frame (expected float, found int)",
+		errors[3].Error())
+}
+
+func TestVerifyOffsets(t *testing.T) {
+	// The fields fill the constant pool, so that the string is loaded with ldc_w.
+	var source strings.Builder
+	source.WriteString(".version 52 0\n.class public super a/T\n.super java/lang/Object\n")
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&source, ".field public f%d I\n", i)
+	}
+	source.WriteString(".method public static m ()V\n    .limit stack 1\n    .limit locals 0\n" +
+		"    ldc \"x\"\n    pop\n    pop\n    return\n.end method\n")
+	content, err := jasm.Assemble([]byte(source.String()))
+	tools.AssertNoErr(t, err)
+	reader := class.NewReader(bytes.NewReader(content))
+	tools.AssertNoErr(t, reader.Read())
+	c := reader.Class()
+	tools.AssertEqual(t, "[0 3 4 5 6]", fmt.Sprint(c.Methods[0].Code.Offsets()))
+
+	errors := New(nil).Verify(c)
+	tools.AssertEqual(t, 1, len(errors))
+	tools.AssertEqual(t, 4, errors[0].Offset)
+	tools.AssertEqual(t, "pop", errors[0].Instruction)
+}
+
+func TestVerifySubroutines(t *testing.T) {
+	c := parse(t, `.version 49 0
+.class public super a/T
+.super java/lang/Object
+
+.method public static m ()V
+    .limit stack 1
+    .limit locals 1
+    jsr L0
+    return
+L0:
+    astore 0
+    ret 0
+.end method
+
+.method public static n ()V
+    .limit stack 0
+    .limit locals 0
+    return
+.end method
+`)
+	errors := New(nil).Verify(c)
+	tools.AssertEqual(t, 1, len(errors))
+	tools.AssertEqual(t, 0, errors[0].Offset)
+	tools.AssertEqual(t, "jsr 4", errors[0].Instruction)
+	if !errors[0].Unverified {
+		t.Errorf("unverified method expected: %v", errors[0])
+	}
+	tools.AssertEqual(t, "a/T.m()V: offset 0: jsr 4: not verified, subroutines are not supported", errors[0].Error())
+
+	c.Version = 51
+	errors = New(nil).Verify(c)
+	tools.AssertEqual(t, 1, len(errors))
+	if errors[0].Unverified {
+		t.Errorf("verification error expected: %v", errors[0])
+	}
+	tools.AssertEqual(t, "jsr and ret instructions are not allowed in class files of version 51 or more", errors[0].Message)
+}